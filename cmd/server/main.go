@@ -1,11 +1,31 @@
 // Package main is an entry point for a server.
+//
+// Usage:
+//
+//	gophkeeper-server                        runs the server
+//	gophkeeper-server rotate-key [-batch N]  re-encrypts the records with the active key
+//	gophkeeper-server migrate-legacy [-batch N]
+//	                                         marks the records encrypted in the legacy format
+//	gophkeeper-server reindex [-batch N]     rebuilds the search tokens of the records
+//	gophkeeper-server backup -out FILE (-user NAME | -all) [-snapshot=false]
+//	                                         writes the encrypted backup of the user or of all the users
+//	gophkeeper-server restore -in FILE (-user NAME | -all) [-overwrite] [-dry-run]
+//	                                         checks and restores the backup
+//	gophkeeper-server unlock (-user NAME | -ip ADDR)
+//	                                         removes the lockout of the logins after the failures
+//
+// The password of the backup is set by -password or GOPHKEEPER_BACKUP_PASSWORD.
 package main
 
 import (
+	"flag"
+	"os"
+
 	"github.com/joho/godotenv"
 
 	"github.com/blokhinnv/gophkeeper/internal/server"
 	"github.com/blokhinnv/gophkeeper/internal/server/config"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
 	"github.com/blokhinnv/gophkeeper/pkg/log"
 )
 
@@ -15,5 +35,136 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if len(os.Args) > 1 && os.Args[1] == "rotate-key" {
+		rotateKey(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate-legacy" {
+		migrateLegacy(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		reindex(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backup" {
+		backup(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		restore(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "unlock" {
+		unlock(cfg, os.Args[2:])
+		return
+	}
 	server.RunServer(cfg)
 }
+
+// rotateKey runs the rotate-key subcommand.
+func rotateKey(cfg *config.ServerConfig, args []string) {
+	flags := flag.NewFlagSet("rotate-key", flag.ExitOnError)
+	batchSize := flags.Int("batch", 100, "number of records re-encrypted at once")
+	flags.Parse(args)
+	if *batchSize <= 0 {
+		log.Fatalf("batch size must be positive: %v", *batchSize)
+	}
+	if err := server.RunKeyRotation(cfg, *batchSize); err != nil {
+		log.Fatalf("key rotation failed: %v", err)
+	}
+}
+
+// migrateLegacy runs the migrate-legacy subcommand.
+func migrateLegacy(cfg *config.ServerConfig, args []string) {
+	flags := flag.NewFlagSet("migrate-legacy", flag.ExitOnError)
+	batchSize := flags.Int("batch", 100, "number of records checked at once")
+	flags.Parse(args)
+	if *batchSize <= 0 {
+		log.Fatalf("batch size must be positive: %v", *batchSize)
+	}
+	if err := server.RunLegacyMigration(cfg, *batchSize); err != nil {
+		log.Fatalf("legacy migration failed: %v", err)
+	}
+}
+
+// reindex runs the reindex subcommand.
+func reindex(cfg *config.ServerConfig, args []string) {
+	flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+	batchSize := flags.Int("batch", 100, "number of records reindexed at once")
+	flags.Parse(args)
+	if *batchSize <= 0 {
+		log.Fatalf("batch size must be positive: %v", *batchSize)
+	}
+	if err := server.RunReindex(cfg, *batchSize); err != nil {
+		log.Fatalf("reindex failed: %v", err)
+	}
+}
+
+// backupFlags adds the flags shared by the backup and restore subcommands
+// and returns the parsed username and password.
+func backupFlags(flags *flag.FlagSet, args []string) (string, string) {
+	username := flags.String("user", "", "name of the user whose data is backed up or restored")
+	all := flags.Bool("all", false, "back up or restore the data of all the users")
+	password := flags.String(
+		"password",
+		os.Getenv("GOPHKEEPER_BACKUP_PASSWORD"),
+		"password of the backup (default: $GOPHKEEPER_BACKUP_PASSWORD)",
+	)
+	flags.Parse(args)
+	if (*username == "") == !*all {
+		log.Fatal("either -user or -all must be set")
+	}
+	if *password == "" {
+		log.Fatal("the password of the backup is not set")
+	}
+	return *username, *password
+}
+
+// backup runs the backup subcommand.
+func backup(cfg *config.ServerConfig, args []string) {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	file := flags.String("out", "", "file the backup is written to")
+	snapshot := flags.Bool(
+		"snapshot",
+		true,
+		"read the data from a single snapshot of the DB; needs a replica set",
+	)
+	username, password := backupFlags(flags, args)
+	if *file == "" {
+		log.Fatal("the file of the backup is not set")
+	}
+	if err := server.RunBackup(cfg, *file, username, password, *snapshot); err != nil {
+		log.Fatalf("backup failed: %v", err)
+	}
+}
+
+// restore runs the restore subcommand.
+func restore(cfg *config.ServerConfig, args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	file := flags.String("in", "", "file the backup is read from")
+	overwrite := flags.Bool("overwrite", false, "replace the data of the users which already exist")
+	dryRun := flags.Bool("dry-run", false, "check the backup without restoring it")
+	username, password := backupFlags(flags, args)
+	if *file == "" {
+		log.Fatal("the file of the backup is not set")
+	}
+	opts := service.RestoreOptions{Username: username, Overwrite: *overwrite, DryRun: *dryRun}
+	if err := server.RunRestore(cfg, *file, password, opts); err != nil {
+		log.Fatalf("restore failed: %v", err)
+	}
+}
+
+// unlock runs the unlock subcommand.
+func unlock(cfg *config.ServerConfig, args []string) {
+	flags := flag.NewFlagSet("unlock", flag.ExitOnError)
+	username := flags.String("user", "", "name of the user whose logins are unlocked")
+	ip := flags.String("ip", "", "address whose logins are unlocked")
+	flags.Parse(args)
+	if *username == "" && *ip == "" {
+		log.Fatal("either -user or -ip must be set")
+	}
+	if err := server.RunUnlock(cfg, *username, *ip); err != nil {
+		log.Fatalf("unlock failed: %v", err)
+	}
+}
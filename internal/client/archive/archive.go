@@ -0,0 +1,459 @@
+// Package archive writes and reads the encrypted portable archives of the records.
+//
+// An archive is a zip file with the entries:
+//
+//	header.json          the format, the version, the creation time, the Argon2id
+//	                     parameters of the key derivation and the key check
+//	manifest.enc         the manifest: the files, the numbers of the records,
+//	                     the sizes and the SHA-256 checksums of the content
+//	collections/<name>   the records of a collection as a JSON array
+//	blobs/<id>           the content of a file uploaded as a blob
+//
+// The key is derived from the password of the archive with the parameters of
+// the header. The manifest and the collections are encrypted with AES-256-GCM
+// as a whole, the blobs are encrypted in chunks of 1 MiB each prefixed with
+// the big-endian uint32 length of the sealed chunk. The checksums are
+// computed over the decrypted content, so the archive doesn't depend on the
+// server or the account it was made of.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+const (
+	// Format is the name of the format of the archives.
+	Format = "gophkeeper-archive"
+	// Version is the version of the format written by Writer.
+	Version = 1
+
+	headerFile   = "header.json"
+	manifestFile = "manifest.enc"
+	// chunkSize is the size of the plaintext chunks of the blobs.
+	chunkSize = 1 << 20
+)
+
+// keyCheckPlaintext is the known value encrypted as the key check of the archive.
+var keyCheckPlaintext = []byte("gophkeeper archive key check")
+
+// newKDFParams returns the parameters of the key derivation of a new archive.
+var newKDFParams = encrypt.NewKDFParams
+
+// KDF holds the parameters of the key derivation.
+type KDF struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	Time      uint32 `json:"time"`
+	Memory    uint32 `json:"memory"` // Memory is the memory usage in KiB.
+	Threads   uint8  `json:"threads"`
+}
+
+// Header is the unencrypted header of the archive.
+type Header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	KDF       KDF       `json:"kdf"`
+	KeyCheck  []byte    `json:"key_check"` // KeyCheck is the known value encrypted with the key.
+}
+
+// CollectionEntry describes the records of a collection.
+type CollectionEntry struct {
+	Name    models.CollectionName `json:"name"`
+	File    string                `json:"file"`
+	Records int                   `json:"records"`
+	SHA256  string                `json:"sha256"`
+}
+
+// BlobEntry describes the content of a blob.
+type BlobEntry struct {
+	ID     string `json:"id"`
+	File   string `json:"file"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists the content of the archive.
+type Manifest struct {
+	Collections []CollectionEntry `json:"collections"`
+	Blobs       []BlobEntry       `json:"blobs"`
+}
+
+// deriveKey derives the key of the archive from the password.
+func deriveKey(password string, kdf KDF) ([]byte, error) {
+	if kdf.Algorithm != models.KDFArgon2id {
+		return nil, errors.New("unsupported key derivation function: " + kdf.Algorithm)
+	}
+	return encrypt.DeriveKey(password, encrypt.KDFParams{
+		Salt:    kdf.Salt,
+		Time:    kdf.Time,
+		Memory:  kdf.Memory,
+		Threads: kdf.Threads,
+	})
+}
+
+// collectionRecords returns the pointer to the records of the collection.
+func collectionRecords(
+	data *clientModels.SyncResponse,
+	collectionName models.CollectionName,
+) any {
+	switch collectionName {
+	case models.TextCollection:
+		return &data.Text
+	case models.BinaryCollection:
+		return &data.Binary
+	case models.CardCollection:
+		return &data.Card
+	case models.CredentialsCollection:
+		return &data.Credential
+	case models.OTPCollection:
+		return &data.OTP
+	default:
+		return nil
+	}
+}
+
+// Writer writes an archive.
+type Writer struct {
+	zw       *zip.Writer
+	key      []byte
+	manifest Manifest
+}
+
+// NewWriter writes the header of a new archive encrypted with the key
+// derived from the password.
+func NewWriter(w io.Writer, password string) (*Writer, error) {
+	params, err := newKDFParams()
+	if err != nil {
+		return nil, err
+	}
+	kdf := KDF{
+		Algorithm: models.KDFArgon2id,
+		Salt:      params.Salt,
+		Time:      params.Time,
+		Memory:    params.Memory,
+		Threads:   params.Threads,
+	}
+	key, err := deriveKey(password, kdf)
+	if err != nil {
+		return nil, err
+	}
+	keyCheck, err := encrypt.SealBytes(keyCheckPlaintext, key)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.MarshalIndent(Header{
+		Format:    Format,
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+		KDF:       kdf,
+		KeyCheck:  keyCheck,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	aw := &Writer{zw: zip.NewWriter(w), key: key}
+	f, err := aw.zw.Create(headerFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(header); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+// create adds the file of the encrypted content to the archive.
+// The content is not compressed since it can't be.
+func (w *Writer) create(name string) (io.Writer, error) {
+	return w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+}
+
+// writeSealed writes the encrypted content to the file.
+func (w *Writer) writeSealed(name string, content []byte) error {
+	sealed, err := encrypt.SealBytes(content, w.key)
+	if err != nil {
+		return err
+	}
+	f, err := w.create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(sealed)
+	return err
+}
+
+// WriteRecords writes the records of all the collections. The end-to-end
+// encrypted records which are not decrypted are not written.
+func (w *Writer) WriteRecords(data *clientModels.SyncResponse) error {
+	plain := *data
+	plain.Encrypted = nil
+	for _, collectionName := range models.AllowedCollectionNames {
+		content, err := json.Marshal(collectionRecords(&plain, collectionName))
+		if err != nil {
+			return err
+		}
+		entry := CollectionEntry{
+			Name:    collectionName,
+			File:    "collections/" + string(collectionName),
+			Records: plain.Count(collectionName),
+			SHA256:  checksum(sha256.New(), content),
+		}
+		if err := w.writeSealed(entry.File, content); err != nil {
+			return err
+		}
+		w.manifest.Collections = append(w.manifest.Collections, entry)
+	}
+	return nil
+}
+
+// WriteBlob writes the content of the blob written by write.
+func (w *Writer) WriteBlob(id string, write func(w io.Writer) error) error {
+	entry := BlobEntry{ID: id, File: "blobs/" + id}
+	f, err := w.create(entry.File)
+	if err != nil {
+		return err
+	}
+	cw := &chunkWriter{w: f, key: w.key, hash: sha256.New()}
+	if err := write(cw); err != nil {
+		return err
+	}
+	if err := cw.flush(); err != nil {
+		return err
+	}
+	entry.Size, entry.SHA256 = cw.size, checksum(cw.hash, nil)
+	w.manifest.Blobs = append(w.manifest.Blobs, entry)
+	return nil
+}
+
+// Close writes the manifest and finishes the archive.
+// It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	manifest, err := json.Marshal(w.manifest)
+	if err != nil {
+		return err
+	}
+	if err := w.writeSealed(manifestFile, manifest); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+// chunkWriter encrypts the content in chunks.
+type chunkWriter struct {
+	w    io.Writer
+	key  []byte
+	buf  []byte
+	hash hash.Hash
+	size int64
+}
+
+// Write encrypts the full chunks of the content.
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.hash.Write(p)
+	c.size += int64(len(p))
+	c.buf = append(c.buf, p...)
+	for len(c.buf) >= chunkSize {
+		if err := c.writeChunk(c.buf[:chunkSize]); err != nil {
+			return 0, err
+		}
+		c.buf = c.buf[chunkSize:]
+	}
+	return len(p), nil
+}
+
+// flush encrypts the rest of the content.
+func (c *chunkWriter) flush() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	err := c.writeChunk(c.buf)
+	c.buf = nil
+	return err
+}
+
+// writeChunk encrypts the chunk and writes it with its length.
+func (c *chunkWriter) writeChunk(chunk []byte) error {
+	sealed, err := encrypt.SealBytes(chunk, c.key)
+	if err != nil {
+		return err
+	}
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(sealed)))
+	if _, err := c.w.Write(length[:]); err != nil {
+		return err
+	}
+	_, err = c.w.Write(sealed)
+	return err
+}
+
+// checksum returns the hex encoded sum of the hash with the data appended.
+func checksum(h hash.Hash, data []byte) string {
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Reader reads an archive.
+type Reader struct {
+	Header   Header
+	Manifest Manifest
+	zr       *zip.Reader
+	key      []byte
+}
+
+// NewReader reads the header and the manifest of the archive.
+// ErrWrongArchivePassword is returned if the password is wrong.
+func NewReader(r io.ReaderAt, size int64, password string) (*Reader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrCorruptedArchive, err)
+	}
+	ar := &Reader{zr: zr}
+	header, err := ar.readFile(headerFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(header, &ar.Header); err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrCorruptedArchive, err)
+	}
+	if ar.Header.Format != Format {
+		return nil, fmt.Errorf("%w: not a %v", clientErr.ErrCorruptedArchive, Format)
+	}
+	if ar.Header.Version != Version {
+		return nil, fmt.Errorf("unsupported archive version: %v", ar.Header.Version)
+	}
+	if ar.key, err = deriveKey(password, ar.Header.KDF); err != nil {
+		return nil, err
+	}
+	check, err := encrypt.OpenBytes(ar.Header.KeyCheck, ar.key)
+	if err != nil || !bytes.Equal(check, keyCheckPlaintext) {
+		return nil, clientErr.ErrWrongArchivePassword
+	}
+	manifest, err := ar.readSealed(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(manifest, &ar.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrCorruptedArchive, err)
+	}
+	return ar, nil
+}
+
+// readFile returns the content of the file of the archive.
+func (r *Reader) readFile(name string) ([]byte, error) {
+	f, err := r.zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: no %v", clientErr.ErrCorruptedArchive, name)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// readSealed returns the decrypted content of the file of the archive.
+func (r *Reader) readSealed(name string) ([]byte, error) {
+	sealed, err := r.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	content, err := encrypt.OpenBytes(sealed, r.key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v: %v", clientErr.ErrCorruptedArchive, name, err)
+	}
+	return content, nil
+}
+
+// ReadRecords returns the records of all the collections of the manifest
+// checking their checksums.
+func (r *Reader) ReadRecords() (*clientModels.SyncResponse, error) {
+	data := &clientModels.SyncResponse{}
+	for _, entry := range r.Manifest.Collections {
+		content, err := r.readSealed(entry.File)
+		if err != nil {
+			return nil, err
+		}
+		if checksum(sha256.New(), content) != entry.SHA256 {
+			return nil, fmt.Errorf("%w: bad checksum of %v", clientErr.ErrCorruptedArchive, entry.File)
+		}
+		records := collectionRecords(data, entry.Name)
+		if records == nil {
+			return nil, fmt.Errorf("%w: unknown collection %v", clientErr.ErrCorruptedArchive, entry.Name)
+		}
+		if err := json.Unmarshal(content, records); err != nil {
+			return nil, fmt.Errorf("%w: %v", clientErr.ErrCorruptedArchive, err)
+		}
+		if data.Count(entry.Name) != entry.Records {
+			return nil, fmt.Errorf("%w: bad number of records of %v", clientErr.ErrCorruptedArchive, entry.Name)
+		}
+	}
+	return data, nil
+}
+
+// Blob returns the manifest entry of the blob.
+func (r *Reader) Blob(id string) (BlobEntry, bool) {
+	for _, b := range r.Manifest.Blobs {
+		if b.ID == id {
+			return b, true
+		}
+	}
+	return BlobEntry{}, false
+}
+
+// ReadBlob writes the decrypted content of the blob to w checking its size
+// and checksum. The content is written before it is checked.
+func (r *Reader) ReadBlob(id string, w io.Writer) error {
+	entry, ok := r.Blob(id)
+	if !ok {
+		return fmt.Errorf("%w: no blob %v", clientErr.ErrCorruptedArchive, id)
+	}
+	f, err := r.zr.Open(entry.File)
+	if err != nil {
+		return fmt.Errorf("%w: no %v", clientErr.ErrCorruptedArchive, entry.File)
+	}
+	defer f.Close()
+	h := sha256.New()
+	size := int64(0)
+	for {
+		var length [4]byte
+		if _, err := io.ReadFull(f, length[:]); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%w: %v: %v", clientErr.ErrCorruptedArchive, entry.File, err)
+		}
+		n := binary.BigEndian.Uint32(length[:])
+		if n > chunkSize+encrypt.SealOverhead {
+			return fmt.Errorf("%w: %v: bad chunk", clientErr.ErrCorruptedArchive, entry.File)
+		}
+		sealed := make([]byte, n)
+		if _, err := io.ReadFull(f, sealed); err != nil {
+			return fmt.Errorf("%w: %v: %v", clientErr.ErrCorruptedArchive, entry.File, err)
+		}
+		chunk, err := encrypt.OpenBytes(sealed, r.key)
+		if err != nil {
+			return fmt.Errorf("%w: %v: %v", clientErr.ErrCorruptedArchive, entry.File, err)
+		}
+		h.Write(chunk)
+		size += int64(len(chunk))
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	if size != entry.Size || checksum(h, nil) != entry.SHA256 {
+		return fmt.Errorf("%w: bad checksum of %v", clientErr.ErrCorruptedArchive, entry.File)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+func init() {
+	// The tests don't need the memory-hard key derivation.
+	newKDFParams = func() (encrypt.KDFParams, error) {
+		params, err := encrypt.NewKDFParams()
+		params.Time, params.Memory, params.Threads = 1, 64, 1
+		return params, err
+	}
+}
+
+// testRecords are the records written to the archives of the tests.
+var testRecords = &clientModels.SyncResponse{
+	Text: []models.TextRecord{
+		{RecordID: models.NewRandomObjectID(), Data: "note", Metadata: models.Metadata{"title": "a"}},
+	},
+	Binary: []models.BinaryRecord{
+		{RecordID: models.NewRandomObjectID(), Data: models.BinaryInfo{FileName: "big.bin", BlobID: "b1"}},
+	},
+	Credential: []models.CredentialRecord{
+		{RecordID: models.NewRandomObjectID(), Data: models.CredentialInfo{Login: "john", Password: "secret"}},
+	},
+}
+
+// newArchive returns an archive with testRecords and the blob.
+func newArchive(t *testing.T, password string, blob []byte) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, password)
+	require.NoError(t, err)
+	data := *testRecords
+	data.Encrypted = []clientModels.EncryptedRecord{
+		{Collection: models.TextCollection, RecordID: models.NewRandomObjectID()},
+	}
+	require.NoError(t, w.WriteRecords(&data))
+	require.NoError(t, w.WriteBlob("b1", func(w io.Writer) error {
+		_, err := w.Write(blob)
+		return err
+	}))
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestArchive(t *testing.T) {
+	blob := make([]byte, 2*chunkSize+123)
+	_, err := rand.Read(blob)
+	require.NoError(t, err)
+	data := newArchive(t, "pass", blob)
+
+	t.Run("ok", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(data), int64(len(data)), "pass")
+		require.NoError(t, err)
+		assert.Equal(t, Format, r.Header.Format)
+		assert.Equal(t, Version, r.Header.Version)
+		assert.Equal(t, models.KDFArgon2id, r.Header.KDF.Algorithm)
+		assert.Len(t, r.Manifest.Collections, len(models.AllowedCollectionNames))
+
+		records, err := r.ReadRecords()
+		require.NoError(t, err)
+		assert.Equal(t, testRecords.Text, records.Text)
+		assert.Equal(t, testRecords.Binary, records.Binary)
+		assert.Equal(t, testRecords.Credential, records.Credential)
+		assert.Empty(t, records.Card)
+		assert.Empty(t, records.Encrypted)
+
+		entry, ok := r.Blob("b1")
+		require.True(t, ok)
+		assert.Equal(t, int64(len(blob)), entry.Size)
+		var content bytes.Buffer
+		require.NoError(t, r.ReadBlob("b1", &content))
+		assert.Equal(t, blob, content.Bytes())
+
+		err = r.ReadBlob("b2", io.Discard)
+		assert.ErrorIs(t, err, clientErr.ErrCorruptedArchive)
+	})
+	t.Run("wrong_password", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader(data), int64(len(data)), "wrong")
+		assert.ErrorIs(t, err, clientErr.ErrWrongArchivePassword)
+	})
+	t.Run("not_archive", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader([]byte("abc")), 3, "pass")
+		assert.ErrorIs(t, err, clientErr.ErrCorruptedArchive)
+	})
+	t.Run("tampered", func(t *testing.T) {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			if f.Name == "collections/text" || f.Name == "blobs/b1" {
+				content[len(content)-1] ^= 1
+			}
+			fw, err := zw.Create(f.Name)
+			require.NoError(t, err)
+			_, err = fw.Write(content)
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+
+		r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "pass")
+		require.NoError(t, err)
+		_, err = r.ReadRecords()
+		assert.ErrorIs(t, err, clientErr.ErrCorruptedArchive)
+		err = r.ReadBlob("b1", io.Discard)
+		assert.ErrorIs(t, err, clientErr.ErrCorruptedArchive)
+	})
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+)
+
+// passwdCmd represents the passwd command
+var passwdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "change the password",
+	Long: `The passwd command asks for the current password and the new one twice
+and changes the password of the user. All the sessions of the user are
+finished, so the other clients have to log in again. The tokens of the new
+session are saved to the profile.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		oldPassword, err := readPassword(cmd, "Current password: ")
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		newPassword, err := readNewPassword(cmd, "New password: ", "Repeat new password: ")
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		tokens, err := authService.ChangePassword(token, oldPassword, newPassword)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Println("Password changed")
+		printTokens(tokens)
+		p, err := profile.Load(profile.Name(cmd))
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		p.SetTokens(tokens)
+		if err := profile.Save(profile.Name(cmd), p); err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Printf("Saved to profile %v\n", profile.Name(cmd))
+		return nil
+	},
+}
+
+// deleteAccountCmd represents the delete-account command
+var deleteAccountCmd = &cobra.Command{
+	Use:   "delete-account",
+	Short: "delete the account with all the data",
+	Long: `The delete-account command asks for the password twice and deletes
+the user with all the records, their history and the files on the server.
+The deletion can't be undone. The local copies made by sync and backup
+are kept. The tokens are removed from the profile.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		password, err := readNewPassword(cmd, "Password: ", "Repeat password: ")
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		if err := authService.DeleteAccount(token, password); err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Println("Account deleted")
+		p, err := profile.Load(profile.Name(cmd))
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		p.Username = ""
+		p.ClearTokens()
+		if err := profile.Save(profile.Name(cmd), p); err != nil {
+			fmt.Println(err)
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	addTokenFlag(passwdCmd)
+	addTokenFlag(deleteAccountCmd)
+	AuthCmd.AddCommand(passwdCmd, deleteAccountCmd)
+}
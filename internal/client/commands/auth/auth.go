@@ -4,7 +4,9 @@ package auth
 import (
 	"github.com/spf13/cobra"
 
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
 	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
 )
 
 var (
@@ -13,20 +15,35 @@ var (
 	// AuthCmd represents the auth command.
 	AuthCmd = &cobra.Command{
 		Use:   "auth",
-		Short: "authorization and registration commands",
-		Long:  "A parent command for login and register.",
+		Short: "authorization, registration and session commands",
+		Long:  "A parent command for login, register, refresh, logout, sessions, revoke, devices, mfa, passwd and delete-account.",
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if _, err := profile.Apply(cmd); err != nil {
+				log.Fatalf("Error while loading the profile: %v", err)
+			}
 			baseURL := cmd.Flag("server").Value.String()
-			authService = service.NewAuthService(baseURL)
+			transport := cmd.Flag("transport").Value.String()
+			var err error
+			authService, err = service.NewAuthServiceWithTransport(transport, baseURL)
+			if err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
 		},
 	}
 )
 
-func init() {
-	AuthCmd.PersistentFlags().StringP("username", "u", "", "username to authorize")
-	AuthCmd.PersistentFlags().StringP("password", "p", "", "password to authorize")
+// addCredentialsFlags adds the required username and password flags to the command.
+func addCredentialsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("username", "u", "", "username to authorize")
+	cmd.Flags().StringP("password", "p", "", "password to authorize")
 
 	for _, flag := range []string{"username", "password"} {
-		AuthCmd.MarkPersistentFlagRequired(flag)
+		cmd.MarkFlagRequired(flag)
 	}
 }
+
+// addTokenFlag adds the required access token flag to the command.
+func addTokenFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("token", "t", "", "access token (default: from the profile)")
+	cmd.MarkFlagRequired("token")
+}
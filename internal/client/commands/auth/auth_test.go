@@ -2,14 +2,19 @@ package auth
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/blokhinnv/gophkeeper/internal/client/commands/cotesting"
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
 	"github.com/blokhinnv/gophkeeper/internal/client/service/mock"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
 )
 
 func init() {
@@ -17,18 +22,19 @@ func init() {
 }
 
 func TestLoginCommand(t *testing.T) {
+	t.Setenv(profile.DirEnv, t.TempDir())
 	AuthCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		authService = mock.NewMockAuthService(mockCtrl)
 		authService.(*mock.MockAuthService).EXPECT().
-			Auth(gomock.Eq("someuser"), gomock.Eq("correctpwd")).
+			Auth(gomock.Eq("someuser"), gomock.Eq("correctpwd"), gomock.Not(gomock.Nil())).
 			AnyTimes().
-			Return("some token", nil)
+			Return(&models.TokenPair{AccessToken: "some token", RefreshToken: "refresh"}, nil)
 		authService.(*mock.MockAuthService).EXPECT().
-			Auth(gomock.Eq("someuser"), gomock.Eq("wrongpwd")).
+			Auth(gomock.Eq("someuser"), gomock.Eq("wrongpwd"), gomock.Any()).
 			AnyTimes().
-			Return("", fmt.Errorf("Bad credentials"))
+			Return(nil, fmt.Errorf("Bad credentials"))
 	}
 
 	rootCmd := AuthCmd
@@ -40,6 +46,13 @@ func TestLoginCommand(t *testing.T) {
 			"--password=correctpwd",
 		)
 		assert.NoError(t, err)
+
+		p, err := profile.Load(profile.DefaultName)
+		require.NoError(t, err)
+		assert.Equal(t, "someuser", p.Username)
+		assert.Equal(t, "some token", p.Token)
+		assert.Equal(t, "https://localhost:8080", p.Server)
+		assert.NotEmpty(t, p.DeviceKey, "the key of the device should be kept in the profile")
 	})
 	t.Run("bad", func(t *testing.T) {
 		err := cotesting.ExecuteCommandC(
@@ -86,3 +99,374 @@ func TestRegisterCommand(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestRefreshCommand(t *testing.T) {
+	t.Setenv(profile.DirEnv, t.TempDir())
+	AuthCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		authService = mock.NewMockAuthService(mockCtrl)
+		authService.(*mock.MockAuthService).EXPECT().
+			Refresh(gomock.Eq("refresh"), gomock.Any()).
+			AnyTimes().
+			Return(&models.TokenPair{AccessToken: "some token", RefreshToken: "new-refresh"}, nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			Refresh(gomock.Eq("used"), gomock.Any()).
+			AnyTimes().
+			Return(nil, srvErrors.ErrBadRefreshToken)
+	}
+	rootCmd := AuthCmd
+	t.Run("ok", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "refresh", "--refresh-token=refresh")
+		assert.NoError(t, err)
+	})
+	t.Run("bad", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "refresh", "--refresh-token=used")
+		assert.Error(t, err)
+	})
+	t.Run("profile", func(t *testing.T) {
+		require.NoError(t, profile.Save(profile.DefaultName, &profile.Profile{RefreshToken: "refresh"}))
+		refreshCmd.Flags().Set("refresh-token", "")
+		err := cotesting.ExecuteCommandC(rootCmd, "refresh")
+		assert.NoError(t, err)
+
+		p, err := profile.Load(profile.DefaultName)
+		require.NoError(t, err)
+		assert.Equal(t, "some token", p.Token)
+		assert.Equal(t, "new-refresh", p.RefreshToken)
+	})
+}
+
+func TestLogoutCommand(t *testing.T) {
+	t.Setenv(profile.DirEnv, t.TempDir())
+	AuthCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		authService = mock.NewMockAuthService(mockCtrl)
+		authService.(*mock.MockAuthService).EXPECT().
+			Logout(gomock.Eq("token")).
+			AnyTimes().
+			Return(nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			Logout(gomock.Eq("bad")).
+			AnyTimes().
+			Return(srvErrors.ErrUnauthorized)
+	}
+	rootCmd := AuthCmd
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, profile.Save(profile.DefaultName, &profile.Profile{Token: "token"}))
+		err := cotesting.ExecuteCommandC(rootCmd, "logout", "--token=token")
+		assert.NoError(t, err)
+
+		p, err := profile.Load(profile.DefaultName)
+		require.NoError(t, err)
+		assert.Empty(t, p.Token)
+	})
+	t.Run("bad", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "logout", "--token=bad")
+		assert.Error(t, err)
+	})
+}
+
+func TestSessionsCommand(t *testing.T) {
+	id := models.NewRandomObjectID()
+	AuthCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		authService = mock.NewMockAuthService(mockCtrl)
+		authService.(*mock.MockAuthService).EXPECT().
+			Sessions(gomock.Eq("token")).
+			AnyTimes().
+			Return([]models.Session{{ID: id, Current: true}}, nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			Sessions(gomock.Eq("bad")).
+			AnyTimes().
+			Return(nil, srvErrors.ErrUnauthorized)
+		authService.(*mock.MockAuthService).EXPECT().
+			RevokeSession(gomock.Eq("token"), gomock.Eq(id.Hex())).
+			AnyTimes().
+			Return(nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			RevokeSession(gomock.Eq("token"), gomock.Eq("unknown")).
+			AnyTimes().
+			Return(srvErrors.ErrSessionNotFound)
+	}
+	rootCmd := AuthCmd
+	t.Run("list", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "sessions", "--token=token")
+		assert.NoError(t, err)
+	})
+	t.Run("list_bad", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "sessions", "--token=bad")
+		assert.Error(t, err)
+	})
+	t.Run("revoke", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "revoke", id.Hex(), "--token=token")
+		assert.NoError(t, err)
+	})
+	t.Run("revoke_unknown", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "revoke", "unknown", "--token=token")
+		assert.Error(t, err)
+	})
+}
+
+func TestDevicesCommand(t *testing.T) {
+	id := models.NewRandomObjectID()
+	AuthCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		authService = mock.NewMockAuthService(mockCtrl)
+		authService.(*mock.MockAuthService).EXPECT().
+			Devices(gomock.Eq("token")).
+			AnyTimes().
+			Return([]models.Device{{ID: id, Current: true}}, nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			Devices(gomock.Eq("bad")).
+			AnyTimes().
+			Return(nil, srvErrors.ErrUnauthorized)
+		authService.(*mock.MockAuthService).EXPECT().
+			DeleteDevice(gomock.Eq("token"), gomock.Eq(id.Hex())).
+			AnyTimes().
+			Return(nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			DeleteDevice(gomock.Eq("token"), gomock.Eq("unknown")).
+			AnyTimes().
+			Return(srvErrors.ErrDeviceNotFound)
+	}
+	rootCmd := AuthCmd
+	t.Run("list", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "devices", "--token=token")
+		assert.NoError(t, err)
+	})
+	t.Run("list_bad", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "devices", "--token=bad")
+		assert.Error(t, err)
+	})
+	t.Run("delete", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "devices", "delete", id.Hex(), "--token=token")
+		assert.NoError(t, err)
+	})
+	t.Run("delete_unknown", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "devices", "delete", "unknown", "--token=token")
+		assert.ErrorIs(t, err, srvErrors.ErrDeviceNotFound)
+	})
+}
+
+func TestLoginMFACommand(t *testing.T) {
+	t.Setenv(profile.DirEnv, t.TempDir())
+	AuthCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		authService = mock.NewMockAuthService(mockCtrl)
+		authService.(*mock.MockAuthService).EXPECT().
+			Auth(gomock.Eq("someuser"), gomock.Eq("correctpwd"), gomock.Any()).
+			AnyTimes().
+			Return(&models.TokenPair{MFARequired: true, MFAToken: "challenge"}, nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			AuthMFA(gomock.Eq("challenge"), gomock.Eq("123456"), gomock.Not(gomock.Nil())).
+			AnyTimes().
+			Return(&models.TokenPair{AccessToken: "some token", RefreshToken: "refresh"}, nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			AuthMFA(gomock.Eq("challenge"), gomock.Eq("000000"), gomock.Any()).
+			AnyTimes().
+			Return(nil, srvErrors.ErrBadMFACode)
+	}
+
+	rootCmd := AuthCmd
+	defer rootCmd.SetIn(nil)
+	t.Run("flag", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"login",
+			"--username=someuser",
+			"--password=correctpwd",
+			"--code=123456",
+		)
+		assert.NoError(t, err)
+
+		p, err := profile.Load(profile.DefaultName)
+		require.NoError(t, err)
+		assert.Equal(t, "some token", p.Token)
+	})
+	t.Run("prompt", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader("123456\n"))
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"login",
+			"--username=someuser",
+			"--password=correctpwd",
+			"--code=",
+		)
+		assert.NoError(t, err)
+	})
+	t.Run("no_code", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader(""))
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"login",
+			"--username=someuser",
+			"--password=correctpwd",
+			"--code=",
+		)
+		assert.Error(t, err)
+	})
+	t.Run("bad_code", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"login",
+			"--username=someuser",
+			"--password=correctpwd",
+			"--code=000000",
+		)
+		assert.ErrorIs(t, err, srvErrors.ErrBadMFACode)
+	})
+}
+
+func TestMFACommand(t *testing.T) {
+	AuthCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		authService = mock.NewMockAuthService(mockCtrl)
+		authService.(*mock.MockAuthService).EXPECT().
+			EnrollMFA(gomock.Eq("token"), gomock.Eq("pwd")).
+			AnyTimes().
+			Return(&models.MFAEnrollment{Secret: "SECRET", URI: "otpauth://totp/GophKeeper:someuser"}, nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			EnrollMFA(gomock.Eq("token"), gomock.Eq("wrong")).
+			AnyTimes().
+			Return(nil, srvErrors.ErrBadCredentials)
+		authService.(*mock.MockAuthService).EXPECT().
+			ConfirmMFA(gomock.Eq("token"), gomock.Eq("123456")).
+			AnyTimes().
+			Return([]string{"abcd-efgh"}, nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			ConfirmMFA(gomock.Eq("token"), gomock.Eq("000000")).
+			AnyTimes().
+			Return(nil, srvErrors.ErrBadMFACode)
+		authService.(*mock.MockAuthService).EXPECT().
+			DisableMFA(gomock.Eq("token"), gomock.Eq("pwd"), gomock.Eq("123456")).
+			AnyTimes().
+			Return(nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			DisableMFA(gomock.Eq("token"), gomock.Eq("pwd"), gomock.Eq("000000")).
+			AnyTimes().
+			Return(srvErrors.ErrBadMFACode)
+	}
+	rootCmd := AuthCmd
+	defer rootCmd.SetIn(nil)
+	t.Run("enroll", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader("pwd\n"))
+		err := cotesting.ExecuteCommandC(rootCmd, "mfa", "enroll", "--token=token")
+		assert.NoError(t, err)
+	})
+	t.Run("enroll_wrong_password", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader("wrong\n"))
+		err := cotesting.ExecuteCommandC(rootCmd, "mfa", "enroll", "--token=token")
+		assert.ErrorIs(t, err, srvErrors.ErrBadCredentials)
+	})
+	t.Run("confirm", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "mfa", "confirm", "123456", "--token=token")
+		assert.NoError(t, err)
+	})
+	t.Run("confirm_bad", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "mfa", "confirm", "000000", "--token=token")
+		assert.Error(t, err)
+	})
+	t.Run("disable", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader("pwd\n"))
+		err := cotesting.ExecuteCommandC(rootCmd, "mfa", "disable", "123456", "--token=token")
+		assert.NoError(t, err)
+	})
+	t.Run("disable_bad", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader("pwd\n"))
+		err := cotesting.ExecuteCommandC(rootCmd, "mfa", "disable", "000000", "--token=token")
+		assert.Error(t, err)
+	})
+}
+
+func TestPasswdCommand(t *testing.T) {
+	t.Setenv(profile.DirEnv, t.TempDir())
+	AuthCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		authService = mock.NewMockAuthService(mockCtrl)
+		authService.(*mock.MockAuthService).EXPECT().
+			ChangePassword(gomock.Eq("token"), gomock.Eq("old pwd"), gomock.Eq("new pwd")).
+			AnyTimes().
+			Return(&models.TokenPair{AccessToken: "new token", RefreshToken: "new refresh"}, nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			ChangePassword(gomock.Eq("token"), gomock.Eq("wrong"), gomock.Eq("new pwd")).
+			AnyTimes().
+			Return(nil, srvErrors.ErrBadCredentials)
+	}
+	rootCmd := AuthCmd
+	defer rootCmd.SetIn(nil)
+	t.Run("ok", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader("old pwd\nnew pwd\nnew pwd\n"))
+		err := cotesting.ExecuteCommandC(rootCmd, "passwd", "--token=token")
+		require.NoError(t, err)
+		p, err := profile.Load(profile.DefaultName)
+		require.NoError(t, err)
+		assert.Equal(t, "new token", p.Token)
+		assert.Equal(t, "new refresh", p.RefreshToken)
+	})
+	t.Run("wrong_password", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader("wrong\nnew pwd\nnew pwd\n"))
+		err := cotesting.ExecuteCommandC(rootCmd, "passwd", "--token=token")
+		assert.ErrorIs(t, err, srvErrors.ErrBadCredentials)
+	})
+	t.Run("mismatch", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader("old pwd\nnew pwd\nnew pwd2\n"))
+		err := cotesting.ExecuteCommandC(rootCmd, "passwd", "--token=token")
+		assert.EqualError(t, err, "passwords do not match")
+	})
+	t.Run("no_password", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader(""))
+		err := cotesting.ExecuteCommandC(rootCmd, "passwd", "--token=token")
+		assert.EqualError(t, err, "no password provided")
+	})
+}
+
+func TestDeleteAccountCommand(t *testing.T) {
+	t.Setenv(profile.DirEnv, t.TempDir())
+	require.NoError(t, profile.Save(profile.DefaultName, &profile.Profile{
+		Username:     "someuser",
+		Token:        "token",
+		RefreshToken: "refresh",
+	}))
+	AuthCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		authService = mock.NewMockAuthService(mockCtrl)
+		authService.(*mock.MockAuthService).EXPECT().
+			DeleteAccount(gomock.Eq("token"), gomock.Eq("pwd")).
+			AnyTimes().
+			Return(nil)
+		authService.(*mock.MockAuthService).EXPECT().
+			DeleteAccount(gomock.Eq("token"), gomock.Eq("wrong")).
+			AnyTimes().
+			Return(srvErrors.ErrBadCredentials)
+	}
+	rootCmd := AuthCmd
+	defer rootCmd.SetIn(nil)
+	t.Run("wrong_password", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader("wrong\nwrong\n"))
+		err := cotesting.ExecuteCommandC(rootCmd, "delete-account", "--token=token")
+		assert.ErrorIs(t, err, srvErrors.ErrBadCredentials)
+	})
+	t.Run("mismatch", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader("pwd\nwrong\n"))
+		err := cotesting.ExecuteCommandC(rootCmd, "delete-account", "--token=token")
+		assert.EqualError(t, err, "passwords do not match")
+	})
+	t.Run("ok", func(t *testing.T) {
+		rootCmd.SetIn(strings.NewReader("pwd\r\npwd\r\n"))
+		err := cotesting.ExecuteCommandC(rootCmd, "delete-account", "--token=token")
+		require.NoError(t, err)
+		p, err := profile.Load(profile.DefaultName)
+		require.NoError(t, err)
+		assert.Empty(t, p.Username)
+		assert.Empty(t, p.Token)
+		assert.Empty(t, p.RefreshToken)
+	})
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// devicesCmd represents the devices command
+var devicesCmd = &cobra.Command{
+	Use:   "devices",
+	Short: "list the devices",
+	Long: `The devices command lists the devices registered by the logins of the user.
+The device of the token provided is marked with an asterisk.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		devices, err := authService.Devices(token)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		for _, d := range devices {
+			mark := " "
+			if d.Current {
+				mark = "*"
+			}
+			fmt.Printf(
+				"%v %v %q platform=%v created=%v last_login=%v\n",
+				mark,
+				d.ID.Hex(),
+				d.Name,
+				d.Platform,
+				d.CreatedAt.Local().Format(time.RFC3339),
+				d.LastLoginAt.Local().Format(time.RFC3339),
+			)
+		}
+		return nil
+	},
+}
+
+// deleteDeviceCmd represents the devices delete command
+var deleteDeviceCmd = &cobra.Command{
+	Use:   "delete <device id>",
+	Short: "delete a device",
+	Long: `The delete command removes a device of the user, for example, a lost one.
+All the sessions of the device are finished at once: their refresh tokens can't be
+used anymore, their access tokens are revoked and the device stops receiving
+the sync events. The device is registered again with a new id on its next login.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		if err := authService.DeleteDevice(token, args[0]); err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Println("Device deleted")
+		return nil
+	},
+}
+
+func init() {
+	addTokenFlag(devicesCmd)
+	addTokenFlag(deleteDeviceCmd)
+	devicesCmd.AddCommand(deleteDeviceCmd)
+	AuthCmd.AddCommand(devicesCmd)
+}
@@ -1,9 +1,15 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
 )
 
 // loginCmd represents the login command
@@ -11,21 +17,94 @@ var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "login",
 	Long: `The loginCmd command represents the login functionality, used for user authorization.
-The command takes a username and password as arguments and returns a token,
-which can be used for subsequent authenticated requests.`,
+The command takes a username and password as arguments and returns an access token,
+which can be used for subsequent authenticated requests, and a refresh token,
+which exchanges the expired access token for a new one.
+The tokens and the server are saved to the profile, so the other commands
+use them when the flags are omitted.
+If the second factor is enabled, the one-time code of the authenticator app
+or a recovery code is taken from the --code flag or asked for.
+The login registers the device of the profile: its key is generated on the first
+login and kept in the profile, the name is the hostname unless --device-name is set.
+The session is bound to the device, so deleting the device signs it out.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		username := cmd.Flag("username").Value.String()
 		password := cmd.Flag("password").Value.String()
-		tok, err := authService.Auth(username, password)
+		device, err := profile.LoadDevice(
+			profile.Name(cmd),
+			cmd.Flag("device-name").Value.String(),
+		)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		tokens, err := authService.Auth(username, password, device)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		if tokens.MFARequired {
+			code, err := readCode(cmd)
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			tokens, err = authService.AuthMFA(tokens.MFAToken, code, device)
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+		}
+		printTokens(tokens)
+		p, err := profile.Load(profile.Name(cmd))
 		if err != nil {
 			fmt.Println(err)
 			return err
 		}
-		fmt.Println("Token: ", tok)
+		p.Server = cmd.Flag("server").Value.String()
+		if f := cmd.Flag("transport"); f != nil {
+			p.Transport = f.Value.String()
+		}
+		p.Username = username
+		p.SetTokens(tokens)
+		if err := profile.Save(profile.Name(cmd), p); err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Printf("Saved to profile %v\n", profile.Name(cmd))
 		return nil
 	},
 }
 
+// printTokens prints the tokens of the session.
+func printTokens(tokens *models.TokenPair) {
+	fmt.Println("Token: ", tokens.AccessToken)
+	fmt.Println("Refresh token: ", tokens.RefreshToken)
+	fmt.Println("Expires at: ", tokens.ExpiresAt.Local().Format(time.RFC3339))
+	if tokens.DeviceID != "" {
+		fmt.Println("Device: ", tokens.DeviceID)
+	}
+}
+
+// readCode returns the code of the second factor from the flag
+// or reads it from the input.
+func readCode(cmd *cobra.Command) (string, error) {
+	if code := cmd.Flag("code").Value.String(); code != "" {
+		return code, nil
+	}
+	code, err := readLine(cmd, "One-time code: ")
+	if err != nil {
+		return "", err
+	}
+	if code = strings.TrimSpace(code); code == "" {
+		return "", errors.New("no one-time code provided")
+	}
+	return code, nil
+}
+
 func init() {
+	addCredentialsFlags(loginCmd)
+	loginCmd.Flags().StringP("code", "c", "", "one-time or recovery code of the second factor")
+	loginCmd.Flags().String("device-name", "", "name of the device (default: the saved name or the hostname)")
 	AuthCmd.AddCommand(loginCmd)
 }
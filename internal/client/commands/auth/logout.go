@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+)
+
+// logoutCmd represents the logout command
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "logout",
+	Long: `The logout command revokes the access token and finishes its session,
+so the refresh token of the session can't be used anymore.
+The tokens of the session are removed from the profile.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		if err := authService.Logout(token); err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Println("Logged out")
+		p, err := profile.Load(profile.Name(cmd))
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		if p.Token != token {
+			return nil
+		}
+		p.ClearTokens()
+		if err := profile.Save(profile.Name(cmd), p); err != nil {
+			fmt.Println(err)
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	addTokenFlag(logoutCmd)
+	AuthCmd.AddCommand(logoutCmd)
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// mfaCmd represents the mfa command
+var mfaCmd = &cobra.Command{
+	Use:   "mfa",
+	Short: "second factor commands",
+	Long:  "A parent command for enroll, confirm and disable of the TOTP second factor.",
+}
+
+// mfaEnrollCmd represents the mfa enroll command
+var mfaEnrollCmd = &cobra.Command{
+	Use:   "enroll",
+	Short: "enroll the second factor",
+	Long: `The enroll command asks for the password and generates a new TOTP secret
+of the user. Add the secret or the otpauth:// URI to an authenticator app and
+enable the second factor with the confirm command.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		password, err := readPassword(cmd, "Password: ")
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		enrollment, err := authService.EnrollMFA(token, password)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Println("Secret: ", enrollment.Secret)
+		fmt.Println("URI: ", enrollment.URI)
+		return nil
+	},
+}
+
+// mfaConfirmCmd represents the mfa confirm command
+var mfaConfirmCmd = &cobra.Command{
+	Use:   "confirm <code>",
+	Short: "enable the second factor",
+	Long: `The confirm command checks the one-time code of the enrolled secret and
+enables the second factor. The recovery codes are printed only once:
+keep them safe, every code replaces the one-time code once.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		codes, err := authService.ConfirmMFA(token, args[0])
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Println("Second factor enabled. Recovery codes:")
+		for _, code := range codes {
+			fmt.Println(code)
+		}
+		return nil
+	},
+}
+
+// mfaDisableCmd represents the mfa disable command
+var mfaDisableCmd = &cobra.Command{
+	Use:   "disable <code>",
+	Short: "disable the second factor",
+	Long: `The disable command asks for the password, checks it together with
+the one-time code or a recovery code and turns off the second factor.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		password, err := readPassword(cmd, "Password: ")
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		if err := authService.DisableMFA(token, password, args[0]); err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Println("Second factor disabled")
+		return nil
+	},
+}
+
+func init() {
+	addTokenFlag(mfaEnrollCmd)
+	addTokenFlag(mfaConfirmCmd)
+	addTokenFlag(mfaDisableCmd)
+	mfaCmd.AddCommand(mfaEnrollCmd, mfaConfirmCmd, mfaDisableCmd)
+	AuthCmd.AddCommand(mfaCmd)
+}
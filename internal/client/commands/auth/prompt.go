@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// readLine prints the prompt and reads a line from the input of the command.
+// The input is read byte by byte, so the next prompts get the next lines.
+// The line is returned as is, so the passwords may have spaces.
+func readLine(cmd *cobra.Command, prompt string) (string, error) {
+	fmt.Print(prompt)
+	var sb strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := cmd.InOrStdin().Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			sb.WriteByte(buf[0])
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\r"), nil
+}
+
+// readPassword prints the prompt and reads a password from the input of the command.
+// The password isn't echoed if the input is a terminal. Returns an error if the password is empty.
+func readPassword(cmd *cobra.Command, prompt string) (string, error) {
+	var password string
+	if f, ok := cmd.InOrStdin().(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Print(prompt)
+		b, err := term.ReadPassword(int(f.Fd()))
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		password = string(b)
+	} else {
+		var err error
+		if password, err = readLine(cmd, prompt); err != nil {
+			return "", err
+		}
+	}
+	if password == "" {
+		return "", errors.New("no password provided")
+	}
+	return password, nil
+}
+
+// readNewPassword reads a password twice and returns it if both are the same.
+func readNewPassword(cmd *cobra.Command, prompt, repeatPrompt string) (string, error) {
+	password, err := readPassword(cmd, prompt)
+	if err != nil {
+		return "", err
+	}
+	repeated, err := readPassword(cmd, repeatPrompt)
+	if err != nil {
+		return "", err
+	}
+	if password != repeated {
+		return "", errors.New("passwords do not match")
+	}
+	return password, nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+)
+
+// errNoRefreshToken is returned when the refresh token is neither provided nor saved to the profile.
+var errNoRefreshToken = errors.New("no refresh token: provide --refresh-token or login first")
+
+// refreshCmd represents the refresh command
+var refreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "refresh the tokens",
+	Long: `The refresh command exchanges the refresh token for a new access token
+and a new refresh token. Every refresh token can be used only once.
+If the refresh token is omitted, the one saved to the profile is used,
+and the new tokens are saved to the profile. The session bound to the device
+is refreshed only with the key of the device kept in the profile.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Load(profile.Name(cmd))
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		refreshToken := cmd.Flag("refresh-token").Value.String()
+		if refreshToken == "" {
+			refreshToken = p.RefreshToken
+		}
+		if refreshToken == "" {
+			fmt.Println(errNoRefreshToken)
+			return errNoRefreshToken
+		}
+		device, err := p.RefreshDevice()
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		tokens, err := authService.Refresh(refreshToken, device)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		printTokens(tokens)
+		if refreshToken != p.RefreshToken {
+			return nil
+		}
+		p.SetTokens(tokens)
+		if err := profile.Save(profile.Name(cmd), p); err != nil {
+			fmt.Println(err)
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	refreshCmd.Flags().StringP("refresh-token", "r", "", "refresh token (default: from the profile)")
+	AuthCmd.AddCommand(refreshCmd)
+}
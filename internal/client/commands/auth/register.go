@@ -27,5 +27,6 @@ If the registration fails, an error message will be printed to the console.`,
 }
 
 func init() {
+	addCredentialsFlags(registerCmd)
 	AuthCmd.AddCommand(registerCmd)
 }
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// sessionsCmd represents the sessions command
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "list the active sessions",
+	Long: `The sessions command lists the active sessions of the user.
+The session of the token provided is marked with an asterisk.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		sessions, err := authService.Sessions(token)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		for _, s := range sessions {
+			mark := " "
+			if s.Current {
+				mark = "*"
+			}
+			fmt.Printf(
+				"%v %v created=%v last_used=%v expires=%v\n",
+				mark,
+				s.ID.Hex(),
+				s.CreatedAt.Local().Format(time.RFC3339),
+				s.LastUsedAt.Local().Format(time.RFC3339),
+				s.ExpiresAt.Local().Format(time.RFC3339),
+			)
+		}
+		return nil
+	},
+}
+
+// revokeCmd represents the revoke command
+var revokeCmd = &cobra.Command{
+	Use:   "revoke <session id>",
+	Short: "revoke a session",
+	Long: `The revoke command finishes a session of the user, for example, on a lost device.
+The refresh token of the session can't be used anymore, and its access token is revoked.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		if err := authService.RevokeSession(token, args[0]); err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Println("Session revoked")
+		return nil
+	},
+}
+
+func init() {
+	addTokenFlag(sessionsCmd)
+	addTokenFlag(revokeCmd)
+	AuthCmd.AddCommand(sessionsCmd, revokeCmd)
+}
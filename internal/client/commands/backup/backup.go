@@ -0,0 +1,66 @@
+// Package backup provides implementation of the export and restore CLI-commands.
+package backup
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
+)
+
+var (
+	// storageService is a service used to restore the records.
+	storageService service.StorageService
+	// blobService is a service used to download and upload the blobs.
+	blobService service.BlobService
+	// syncService is a service used to read all the records.
+	syncService service.SyncService
+)
+
+// preRun creates the services used by the commands.
+func preRun(cmd *cobra.Command, args []string) {
+	if _, err := profile.Apply(cmd); err != nil {
+		log.Fatalf("Error while loading the profile: %v", err)
+	}
+	baseURL := cmd.Flag("server").Value.String()
+	transport := cmd.Flag("transport").Value.String()
+	var err error
+	storageService, err = service.NewStorageServiceWithTransport(transport, baseURL)
+	if err != nil {
+		log.Fatalf("Error while creating a service: %v", err)
+	}
+	blobService, err = service.NewBlobServiceWithTransport(transport, baseURL)
+	if err != nil {
+		log.Fatalf("Error while creating a service: %v", err)
+	}
+	syncService, err = service.NewSyncServiceWithTransport(transport, baseURL)
+	if err != nil {
+		log.Fatalf("Error while creating a service: %v", err)
+	}
+	if password := cmd.Flag("master-password").Value.String(); password != "" {
+		vault, err := service.NewVaultWithTransport(transport, baseURL, password)
+		if err != nil {
+			log.Fatalf("Error while creating a service: %v", err)
+		}
+		storageService = service.NewE2EStorageService(storageService, vault)
+		blobService = service.NewE2EBlobService(blobService, vault)
+		syncService = service.NewE2ESyncService(syncService, vault)
+	}
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{ExportCmd, RestoreCmd} {
+		cmd.PersistentPreRun = preRun
+		cmd.PersistentFlags().StringP("token", "t", "", "jwt token (default: from the profile)")
+		cmd.MarkPersistentFlagRequired("token")
+		cmd.Flags().StringP("archive-password", "p", "", "password of the archive")
+		cmd.MarkFlagRequired("archive-password")
+	}
+	RestoreCmd.Flags().String(
+		"on-conflict",
+		string(service.KeepTheirs),
+		"what to do with the records changed since the export: mine, theirs or merge",
+	)
+	RestoreCmd.Flags().Bool("dry-run", false, "report what would be restored without saving it")
+}
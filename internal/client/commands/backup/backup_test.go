@@ -0,0 +1,173 @@
+package backup
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/commands/cotesting"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/client/service/mock"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestExportRestore(t *testing.T) {
+	t.Setenv(profile.DirEnv, t.TempDir())
+	var (
+		oldBlobID = models.NewRandomObjectID()
+		newBlobID = models.NewRandomObjectID()
+		note      = models.NewRandomObjectID()
+		login     = models.NewRandomObjectID()
+		file      = models.NewRandomObjectID()
+		content   = []byte("hello, go")
+	)
+	exported := &clientModels.SyncResponse{
+		Text: []models.TextRecord{{RecordID: note, Data: "note", Metadata: models.Metadata{"a": "1"}}},
+		Credential: []models.CredentialRecord{{
+			RecordID: login,
+			Data:     models.CredentialInfo{Login: "john", Password: "secret"},
+			Metadata: models.Metadata{"url": "https://github.com"},
+		}},
+		Binary: []models.BinaryRecord{{
+			RecordID: file,
+			Data:     models.BinaryInfo{FileName: "hello.txt", BlobID: oldBlobID.Hex(), Size: "9"},
+		}},
+		Encrypted: []clientModels.EncryptedRecord{
+			{Collection: models.TextCollection, RecordID: models.NewRandomObjectID()},
+		},
+	}
+	var (
+		synced  *clientModels.SyncResponse
+		added   map[models.CollectionName][]string
+		updated []string
+	)
+	setUp := func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		sync := mock.NewMockSyncService(mockCtrl)
+		sync.EXPECT().
+			Sync("sometoken", models.AllowedCollectionNames).
+			DoAndReturn(func(string, []models.CollectionName) (*clientModels.SyncResponse, error) {
+				return synced, nil
+			}).
+			AnyTimes()
+		syncService = sync
+		storage := mock.NewMockStorageService(mockCtrl)
+		storage.EXPECT().
+			Add(gomock.Any(), gomock.Any(), "sometoken").
+			DoAndReturn(func(body string, c models.CollectionName, _ string) (string, error) {
+				added[c] = append(added[c], body)
+				return "ok", nil
+			}).
+			AnyTimes()
+		storage.EXPECT().
+			Update(gomock.Any(), models.TextCollection, "sometoken").
+			DoAndReturn(func(body string, _ models.CollectionName, _ string) (string, error) {
+				updated = append(updated, body)
+				return "ok", nil
+			}).
+			AnyTimes()
+		storageService = storage
+		blobs := mock.NewMockBlobService(mockCtrl)
+		blobs.EXPECT().
+			Download(oldBlobID, gomock.Any(), "sometoken").
+			DoAndReturn(func(_ models.ObjectID, w io.Writer, _ string) error {
+				_, err := w.Write(content)
+				return err
+			}).
+			AnyTimes()
+		blobs.EXPECT().ChunkSize().Return(4).AnyTimes()
+		blobs.EXPECT().
+			Create(int64(len(content)), "sometoken").
+			Return(&models.Blob{BlobID: newBlobID, Size: 9}, nil).
+			AnyTimes()
+		blobs.EXPECT().
+			Append(newBlobID, gomock.Any(), gomock.Any(), "sometoken").
+			DoAndReturn(func(_ models.ObjectID, offset int64, chunk []byte, _ string) (*models.Blob, error) {
+				return &models.Blob{BlobID: newBlobID, Size: 9, Received: offset + int64(len(chunk))}, nil
+			}).
+			AnyTimes()
+		blobService = blobs
+	}
+	ExportCmd.PersistentPreRun = setUp
+	RestoreCmd.PersistentPreRun = setUp
+	reset := func(existing *clientModels.SyncResponse) {
+		synced = existing
+		added = make(map[models.CollectionName][]string)
+		updated = nil
+	}
+	restore := func(args ...string) error {
+		return cotesting.ExecuteCommandC(
+			RestoreCmd,
+			append([]string{"--token=sometoken", "--archive-password=pass", "--dry-run=false"}, args...)...,
+		)
+	}
+
+	archive := filepath.Join(t.TempDir(), "backup.gka")
+	reset(exported)
+	err := cotesting.ExecuteCommandC(ExportCmd, "--token=sometoken", "--archive-password=pass", archive)
+	require.NoError(t, err)
+
+	t.Run("fresh_account", func(t *testing.T) {
+		reset(&clientModels.SyncResponse{})
+		require.NoError(t, restore("--on-conflict=theirs", archive))
+		require.Len(t, added[models.TextCollection], 1)
+		assert.Contains(t, added[models.TextCollection][0], `"data":"note"`)
+		require.Len(t, added[models.CredentialsCollection], 1)
+		assert.Contains(t, added[models.CredentialsCollection][0], `"url":"https://github.com"`)
+		require.Len(t, added[models.BinaryCollection], 1)
+		assert.Contains(t, added[models.BinaryCollection][0], `"BlobID":"`+newBlobID.Hex()+`"`)
+		assert.Empty(t, updated)
+	})
+	t.Run("existing", func(t *testing.T) {
+		existing := &clientModels.SyncResponse{
+			Text: []models.TextRecord{{
+				RecordID: note,
+				Data:     "changed",
+				Metadata: models.Metadata{"b": "2"},
+				Version:  3,
+			}},
+			Credential: exported.Credential,
+			Binary: []models.BinaryRecord{{
+				RecordID: models.NewRandomObjectID(),
+				Data:     models.BinaryInfo{FileName: "hello.txt", BlobID: newBlobID.Hex(), Size: "9"},
+			}},
+		}
+		reset(existing)
+		require.NoError(t, restore("--on-conflict=theirs", archive))
+		assert.Empty(t, added)
+		assert.Empty(t, updated)
+
+		reset(existing)
+		require.NoError(t, restore("--on-conflict=merge", archive))
+		assert.Empty(t, added)
+		require.Len(t, updated, 1)
+		assert.Contains(t, updated[0], `"data":"note"`)
+		assert.Contains(t, updated[0], `"expected_version":3`)
+		assert.Contains(t, updated[0], `"metadata":{"a":"1","b":"2"}`)
+	})
+	t.Run("dry_run", func(t *testing.T) {
+		reset(&clientModels.SyncResponse{})
+		require.NoError(t, restore("--dry-run", archive))
+		assert.Empty(t, added)
+	})
+	t.Run("wrong_password", func(t *testing.T) {
+		reset(&clientModels.SyncResponse{})
+		err := restore("--archive-password=wrong", archive)
+		assert.Error(t, err)
+		assert.Empty(t, added)
+	})
+	t.Run("unknown_resolution", func(t *testing.T) {
+		err := restore("--on-conflict=both", archive)
+		assert.Error(t, err)
+	})
+	t.Run("no_file", func(t *testing.T) {
+		err := restore(filepath.Join(t.TempDir(), "nonexistent.gka"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/archive"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// ExportCmd represents the export command
+var ExportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "export command",
+	Long: `The export command writes all the records and the content of the uploaded
+files to an archive encrypted with the password of the archive. The archive
+doesn't depend on the server or the account and is restored with the restore
+command. The end-to-end encrypted records are exported only with the master password.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		password := cmd.Flag("archive-password").Value.String()
+		if err := export(args[0], password, token); err != nil {
+			os.Remove(args[0])
+			fmt.Println(err)
+			return err
+		}
+		return nil
+	},
+}
+
+// export writes the archive of all the records to the file.
+func export(file, password, token string) (err error) {
+	data, err := syncService.Sync(token, models.AllowedCollectionNames)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	w, err := archive.NewWriter(f, password)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteRecords(data); err != nil {
+		return err
+	}
+	blobs := 0
+	for _, r := range data.Binary {
+		if r.Data.BlobID == "" {
+			continue
+		}
+		blobID, err := models.ObjectIDFromString(r.Data.BlobID)
+		if err != nil {
+			return err
+		}
+		err = w.WriteBlob(r.Data.BlobID, func(w io.Writer) error {
+			return blobService.Download(blobID, w, token)
+		})
+		if err != nil {
+			return fmt.Errorf("%v: %w", r.Data.FileName, err)
+		}
+		blobs++
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	plain := *data
+	plain.Encrypted = nil
+	for _, collectionName := range models.AllowedCollectionNames {
+		fmt.Printf("%-11s %d\n", collectionName, plain.Count(collectionName))
+	}
+	fmt.Printf("Exported files: %d\n", blobs)
+	if len(data.Encrypted) > 0 {
+		fmt.Printf(
+			"Skipped %d end-to-end encrypted records: use --master-password to export them\n",
+			len(data.Encrypted),
+		)
+	}
+	return nil
+}
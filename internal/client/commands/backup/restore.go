@@ -0,0 +1,276 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/archive"
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// RestoreCmd represents the restore command
+var RestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "restore command",
+	Long: `The restore command adds the records of the archive made by the export command.
+The content of the files is uploaded again. The records which are already saved
+are skipped. If a record with the same id was changed since the export, the
+conflict is resolved with --on-conflict: theirs keeps the saved copy, mine
+overwrites it with the copy of the archive, merge overwrites the data and merges
+the metadata. With --dry-run nothing is saved, and the report shows what would
+be restored.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		resolution, err := service.NewConflictResolution(cmd.Flag("on-conflict").Value.String())
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		f, err := os.Open(args[0])
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		r, err := archive.NewReader(f, info.Size(), cmd.Flag("archive-password").Value.String())
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		records, err := r.ReadRecords()
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		existing, err := syncService.Sync(token, models.AllowedCollectionNames)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		return restore(r, records, existing, resolution, token, dryRun)
+	},
+}
+
+// restoreStatus is the outcome of the restore of a record.
+type restoreStatus string
+
+// Statuses of the restored records.
+const (
+	statusRestored restoreStatus = "restored" // the record is added
+	statusUpdated  restoreStatus = "updated"  // the saved copy is overwritten
+	statusExists   restoreStatus = "exists"   // the same record is already saved
+	statusKept     restoreStatus = "kept"     // the saved copy is changed and kept
+	statusFailed   restoreStatus = "failed"   // the record is not saved
+)
+
+// untypedRecords returns the records of the collection as untyped ones.
+func untypedRecords(
+	data *clientModels.SyncResponse,
+	collectionName models.CollectionName,
+) ([]models.UntypedRecord, error) {
+	var records any
+	switch collectionName {
+	case models.TextCollection:
+		records = data.Text
+	case models.BinaryCollection:
+		records = data.Binary
+	case models.CardCollection:
+		records = data.Card
+	case models.CredentialsCollection:
+		records = data.Credential
+	case models.OTPCollection:
+		records = data.OTP
+	}
+	b, err := json.Marshal(records)
+	if err != nil {
+		return nil, err
+	}
+	var untyped []models.UntypedRecord
+	err = json.Unmarshal(b, &untyped)
+	return untyped, err
+}
+
+// contentKey returns the content of the record which doesn't depend on
+// the server. The blob of a binary record gets another id on every upload.
+func contentKey(record models.UntypedRecord) string {
+	content := record.UntypedRecordContent
+	if data, ok := content.Data.(map[string]any); ok {
+		copied := make(map[string]any, len(data))
+		for k, v := range data {
+			copied[k] = v
+		}
+		delete(copied, "BlobID")
+		content.Data = copied
+	}
+	b, _ := json.Marshal(content)
+	return string(b)
+}
+
+// restore saves the records of the archive which are not saved yet and prints
+// the report. An error is returned if any of the records is not saved.
+func restore(
+	r *archive.Reader,
+	records, existing *clientModels.SyncResponse,
+	resolution service.ConflictResolution,
+	token string,
+	dryRun bool,
+) error {
+	counts := make(map[restoreStatus]int)
+	for _, collectionName := range models.AllowedCollectionNames {
+		archived, err := untypedRecords(records, collectionName)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		saved, err := untypedRecords(existing, collectionName)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		byID := make(map[models.ObjectID]models.UntypedRecord, len(saved))
+		contents := make(map[string]bool, len(saved))
+		for _, s := range saved {
+			byID[s.RecordID] = s
+			contents[contentKey(s)] = true
+		}
+		for _, record := range archived {
+			status, reason := restoreRecord(
+				r, record, byID, contents, collectionName, resolution, token, dryRun,
+			)
+			counts[status]++
+			if reason != "" {
+				reason = ": " + reason
+			}
+			fmt.Printf("%-11s %-11s %v%v\n", status, collectionName, record.RecordID.Hex(), reason)
+		}
+	}
+	restored, updated := counts[statusRestored], counts[statusUpdated]
+	skipped, failed := counts[statusExists]+counts[statusKept], counts[statusFailed]
+	if dryRun {
+		fmt.Printf("Dry run: %d to restore, %d to update, %d to skip\n", restored, updated, skipped)
+		return nil
+	}
+	fmt.Printf("Restored: %d, updated: %d, skipped: %d, failed: %d\n", restored, updated, skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d records are not restored", failed)
+	}
+	return nil
+}
+
+// restoreRecord saves the record of the archive unless the same record is
+// saved. The record saved with the same id is resolved with the resolution.
+func restoreRecord(
+	r *archive.Reader,
+	record models.UntypedRecord,
+	byID map[models.ObjectID]models.UntypedRecord,
+	contents map[string]bool,
+	collectionName models.CollectionName,
+	resolution service.ConflictResolution,
+	token string,
+	dryRun bool,
+) (restoreStatus, string) {
+	if contents[contentKey(record)] {
+		return statusExists, ""
+	}
+	current, conflict := byID[record.RecordID]
+	status := statusRestored
+	if conflict {
+		if resolution == service.KeepTheirs {
+			return statusKept, fmt.Sprintf("changed since the export, version %v", current.Version)
+		}
+		status = statusUpdated
+	}
+	if dryRun {
+		return status, ""
+	}
+	content, err := uploadContent(r, record.UntypedRecordContent, token)
+	if err != nil {
+		return statusFailed, err.Error()
+	}
+	if conflict {
+		b, err := json.Marshal(map[string]any{
+			"record_id": record.RecordID,
+			"data":      content.Data,
+			"metadata":  content.Metadata,
+		})
+		if err != nil {
+			return statusFailed, err.Error()
+		}
+		_, err = service.ResolveConflict(
+			storageService,
+			string(b),
+			collectionName,
+			token,
+			&clientErr.ConflictError{Current: current},
+			resolution,
+		)
+		if err != nil {
+			return statusFailed, err.Error()
+		}
+		return status, ""
+	}
+	b, err := json.Marshal(content)
+	if err != nil {
+		return statusFailed, err.Error()
+	}
+	if _, err := storageService.Add(string(b), collectionName, token); err != nil {
+		return statusFailed, err.Error()
+	}
+	return status, ""
+}
+
+// uploadContent uploads the content of the blob of the binary record from
+// the archive and returns the content of the record with the new blob id.
+func uploadContent(
+	r *archive.Reader,
+	content models.UntypedRecordContent,
+	token string,
+) (models.UntypedRecordContent, error) {
+	data, ok := content.Data.(map[string]any)
+	if !ok {
+		return content, nil
+	}
+	blobID, _ := data["BlobID"].(string)
+	if blobID == "" {
+		return content, nil
+	}
+	entry, ok := r.Blob(blobID)
+	if !ok {
+		return content, fmt.Errorf("%w: no content of the file", clientErr.ErrCorruptedArchive)
+	}
+	tmp, err := os.CreateTemp("", "gophkeeper-restore-*")
+	if err != nil {
+		return content, err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+	if err := r.ReadBlob(blobID, tmp); err != nil {
+		return content, err
+	}
+	blob, err := service.UploadBlob(blobService, tmp, entry.Size, token, nil)
+	if err != nil {
+		return content, err
+	}
+	copied := make(map[string]any, len(data))
+	for k, v := range data {
+		copied[k] = v
+	}
+	copied["BlobID"] = blob.BlobID.Hex()
+	content.Data = copied
+	return content, nil
+}
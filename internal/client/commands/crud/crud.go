@@ -2,26 +2,64 @@
 package crud
 
 import (
+	"errors"
+
 	"github.com/spf13/cobra"
 
 	"github.com/blokhinnv/gophkeeper/internal/client/commands/crud/upsert"
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
 	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
 )
 
 var (
 	// storageService is a storage service used for a command implementation.
 	storageService service.StorageService
+	// blobService is a service used to download the files of the binary records.
+	blobService service.BlobService
 	// storageService is a encryption service used for a command implementation.
 	encryptService service.EncryptService
+	// localStore is a local store used for a command implementation;
+	// nil if the key is not provided.
+	localStore service.LocalStore
 	// CRUDCmd represents the CRUD command.
 	CRUDCmd = &cobra.Command{
 		Use:   "crud",
 		Short: "a command for crud operations",
-		Long:  `A parent command for a add, delete and upsert.`,
+		Long: `A parent command for a add, delete and upsert.
+With the master password the revisions listed by history are decrypted as well,
+and so are the files saved by download.`,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if _, err := profile.Apply(cmd); err != nil {
+				log.Fatalf("Error while loading the profile: %v", err)
+			}
 			baseURL := cmd.Flag("server").Value.String()
-			storageService = service.NewStorageService(baseURL)
+			transport := cmd.Flag("transport").Value.String()
+			var err error
+			storageService, err = service.NewStorageServiceWithTransport(transport, baseURL)
+			if err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			blobService, err = service.NewBlobServiceWithTransport(transport, baseURL)
+			if err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			if password := cmd.Flag("master-password"); password != nil && password.Value.String() != "" {
+				vault, err := service.NewVaultWithTransport(transport, baseURL, password.Value.String())
+				if err != nil {
+					log.Fatalf("Error while creating a service: %v", err)
+				}
+				storageService = service.NewE2EStorageService(storageService, vault)
+				blobService = service.NewE2EBlobService(blobService, vault)
+			}
 			encryptService = service.NewEncryptService()
+			localStore = nil
+			if key := cmd.Flag("key"); key != nil && key.Value.String() != "" {
+				localStore = service.NewLocalStore(cmd.Flag("file").Value.String(), key.Value.String())
+				storageService = service.NewOfflineStorageService(storageService, localStore)
+			}
 		},
 	}
 )
@@ -29,5 +67,24 @@ var (
 func init() {
 	CRUDCmd.PersistentFlags().StringP("collection", "c", "", "a collection to work with")
 	CRUDCmd.MarkPersistentFlagRequired("collection")
-	CRUDCmd.AddCommand(readCmd, deleteCmd, upsert.UpsertCmd)
+	CRUDCmd.AddCommand(
+		readCmd,
+		deleteCmd,
+		trashCmd,
+		historyCmd,
+		restoreCmd,
+		otpCmd,
+		downloadCmd,
+		upsert.UpsertCmd,
+	)
+}
+
+// loadData loads the synced data from the local store. The file written
+// by the sync command of older clients is read as well.
+func loadData(file, key string) (*clientModels.SyncResponse, error) {
+	data, err := localStore.Load()
+	if errors.Is(err, clientErr.ErrNotLocalStore) {
+		return encryptService.FromEncryptedFile(file, key)
+	}
+	return data, err
 }
@@ -1,15 +1,23 @@
 package crud
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/blokhinnv/gophkeeper/internal/client/commands/cotesting"
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
 	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
 	"github.com/blokhinnv/gophkeeper/internal/client/service/mock"
 	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
 )
@@ -18,33 +26,60 @@ func init() {
 	CRUDCmd.PersistentFlags().StringP("server", "s", "https://localhost:8080", "server addr")
 }
 
+// mockLocalStore returns a local store which keeps the data in the file fname.
+// The file legacy is not a local store, so the encrypt service reads it.
+func mockLocalStore(
+	mockCtrl *gomock.Controller,
+	file string,
+	r *clientModels.SyncResponse,
+) service.LocalStore {
+	store := mock.NewMockLocalStore(mockCtrl)
+	switch file {
+	case "fname":
+		store.EXPECT().Load().AnyTimes().Return(r, nil)
+	case "legacy":
+		store.EXPECT().Load().AnyTimes().Return(nil, clientErr.ErrNotLocalStore)
+	default:
+		store.EXPECT().Load().AnyTimes().Return(nil, fmt.Errorf("bad file"))
+	}
+	encryptService = mock.NewMockEncryptService(mockCtrl)
+	encryptService.(*mock.MockEncryptService).EXPECT().
+		FromEncryptedFile(gomock.Eq("legacy"), gomock.Eq("correctkey")).
+		AnyTimes().
+		Return(r, nil)
+	return store
+}
+
 func TestReadCommand(t *testing.T) {
 	CRUDCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		storageService = mock.NewMockStorageService(mockCtrl)
-		encryptService = mock.NewMockEncryptService(mockCtrl)
 
 		r := &clientModels.SyncResponse{
 			Text: []srvrModels.TextRecord{
 				{Data: "some text"},
 			},
 		}
+		localStore = mockLocalStore(mockCtrl, cmd.Flag("file").Value.String(), r)
 
-		encryptService.(*mock.MockEncryptService).EXPECT().
-			FromEncryptedFile(gomock.Eq("badfname"), gomock.Eq("badkey")).
+		storageService.(*mock.MockStorageService).EXPECT().
+			GetAll(srvrModels.CollectionName("text"), r, srvrModels.GetAllOptions{}).
 			AnyTimes().
-			Return(r, fmt.Errorf("bad file"))
-
-		encryptService.(*mock.MockEncryptService).EXPECT().
-			FromEncryptedFile(gomock.Eq("fname"), gomock.Eq("correctkey")).
+			Return(r.Text, "", nil)
+		storageService.(*mock.MockStorageService).EXPECT().
+			GetAll(srvrModels.CollectionName("text"), r, srvrModels.GetAllOptions{
+				Limit:    1,
+				Cursor:   "cursor",
+				Sort:     "-updated",
+				Metadata: srvrModels.Metadata{"site": "github.com"},
+			}).
 			AnyTimes().
-			Return(r, nil)
-
+			Return(r.Text, "next", nil)
 		storageService.(*mock.MockStorageService).EXPECT().
-			GetAll(srvrModels.CollectionName("text"), r).
+			GetAll(srvrModels.CollectionName("text"), r, srvrModels.GetAllOptions{Sort: "name"}).
 			AnyTimes().
-			Return(r.Text)
+			Return(nil, "", fmt.Errorf("bad list options"))
 	}
 
 	rootCmd := CRUDCmd
@@ -78,7 +113,43 @@ func TestReadCommand(t *testing.T) {
 		)
 		assert.NoError(t, err)
 	})
+	t.Run("legacy_file", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"read",
+			"--key=correctkey",
+			"--file=legacy",
+			"--collection=text",
+		)
+		assert.NoError(t, err)
+	})
+	t.Run("bad_sort", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"read",
+			"--key=correctkey",
+			"--file=fname",
+			"--collection=text",
+			"--sort=name",
+		)
+		assert.Error(t, err)
+	})
+	t.Run("page", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"read",
+			"--key=correctkey",
+			"--file=fname",
+			"--collection=text",
+			"--limit=1",
+			"--cursor=cursor",
+			"--sort=-updated",
+			"--meta=site=github.com",
+		)
+		assert.NoError(t, err)
+	})
 }
+
 func TestDeleteCommand(t *testing.T) {
 	CRUDCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		mockCtrl := gomock.NewController(t)
@@ -129,3 +200,453 @@ func TestDeleteCommand(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestOTPCodeCommand(t *testing.T) {
+	id := srvrModels.NewRandomObjectID()
+	badID := srvrModels.NewRandomObjectID()
+	hotpID := srvrModels.NewRandomObjectID()
+	conflictID := srvrModels.NewRandomObjectID()
+	r := &clientModels.SyncResponse{
+		OTP: []srvrModels.OTPRecord{
+			{RecordID: id, Data: srvrModels.OTPInfo{Secret: "JBSWY3DPEHPK3PXP"}},
+			{RecordID: badID, Data: srvrModels.OTPInfo{Secret: "JBSWY3DPEHPK3PXP", Digits: "4"}},
+			{
+				RecordID: hotpID,
+				Data:     srvrModels.OTPInfo{Type: "hotp", Secret: "JBSWY3DPEHPK3PXP", Counter: "0"},
+				Version:  1,
+			},
+			{
+				RecordID: conflictID,
+				Data:     srvrModels.OTPInfo{Type: "hotp", Secret: "JBSWY3DPEHPK3PXP"},
+				Version:  1,
+			},
+		},
+	}
+	// the issued codes of the HOTP record and the versions of the records on the server
+	var issued []string
+	versions := map[srvrModels.ObjectID]int64{hotpID: 1, conflictID: 1}
+	CRUDCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		storageService = mock.NewMockStorageService(mockCtrl)
+		storageService.(*mock.MockStorageService).EXPECT().
+			Update(gomock.Any(), srvrModels.OTPCollection, "sometoken").
+			AnyTimes().
+			DoAndReturn(func(body string, _ srvrModels.CollectionName, _ string) (string, error) {
+				var upd struct {
+					srvrModels.OTPRecord
+					ExpectedVersion int64 `json:"expected_version"`
+				}
+				if err := json.Unmarshal([]byte(body), &upd); err != nil {
+					return "", err
+				}
+				// the server accepts the update of the current version only
+				if upd.RecordID == conflictID || versions[upd.RecordID] != upd.ExpectedVersion {
+					return "", &clientErr.ConflictError{}
+				}
+				versions[upd.RecordID]++
+				return "ok", nil
+			})
+		localStore = mockLocalStore(mockCtrl, cmd.Flag("file").Value.String(), r)
+		// the local store gets the advanced counter
+		localStore.(*mock.MockLocalStore).EXPECT().
+			Merge(gomock.Any()).
+			AnyTimes().
+			DoAndReturn(func(data *clientModels.SyncResponse) error {
+				for _, rec := range data.OTP {
+					for i := range r.OTP {
+						if r.OTP[i].RecordID == rec.RecordID {
+							code, _ := r.OTP[i].Data.Code(time.Now())
+							issued = append(issued, code.Code)
+							r.OTP[i] = rec
+						}
+					}
+				}
+				return nil
+			})
+	}
+
+	rootCmd := CRUDCmd
+	t.Run("bad_file", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"otp",
+			"code",
+			"--key=badkey",
+			"--file=badfname",
+			"--token=sometoken",
+			"--id="+id.Hex(),
+		)
+		assert.Error(t, err)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"otp",
+			"code",
+			"--key=correctkey",
+			"--file=fname",
+			"--token=sometoken",
+			"--id="+srvrModels.NewRandomObjectID().Hex(),
+		)
+		assert.Error(t, err)
+	})
+	t.Run("bad_record", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"otp",
+			"code",
+			"--key=correctkey",
+			"--file=fname",
+			"--token=sometoken",
+			"--id="+badID.Hex(),
+		)
+		assert.Error(t, err)
+	})
+	t.Run("ok", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"otp",
+			"code",
+			"--key=correctkey",
+			"--file=fname",
+			"--token=sometoken",
+			"--id="+id.Hex(),
+		)
+		assert.NoError(t, err)
+	})
+	t.Run("hotp_advances_counter", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			err := cotesting.ExecuteCommandC(
+				rootCmd,
+				"otp",
+				"code",
+				"--key=correctkey",
+				"--file=fname",
+				"--token=sometoken",
+				"--id="+hotpID.Hex(),
+			)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, "2", r.OTP[2].Data.Counter)
+		assert.Equal(t, int64(3), r.OTP[2].Version)
+		require.Len(t, issued, 2)
+		assert.NotEqual(t, issued[0], issued[1])
+	})
+	t.Run("hotp_conflict", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"otp",
+			"code",
+			"--key=correctkey",
+			"--file=fname",
+			"--token=sometoken",
+			"--id="+conflictID.Hex(),
+		)
+		assert.Error(t, err)
+	})
+}
+
+func TestDownloadCommand(t *testing.T) {
+	inlineID := srvrModels.NewRandomObjectID()
+	uploadedID := srvrModels.NewRandomObjectID()
+	brokenID := srvrModels.NewRandomObjectID()
+	blobID := srvrModels.NewRandomObjectID()
+	brokenBlobID := srvrModels.NewRandomObjectID()
+	CRUDCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		storageService = mock.NewMockStorageService(mockCtrl)
+
+		r := &clientModels.SyncResponse{
+			Binary: []srvrModels.BinaryRecord{
+				{
+					RecordID: inlineID,
+					Data:     srvrModels.BinaryInfo{FileName: "notes.txt", Content: "aGVsbG8sIGdv"},
+				},
+				{
+					RecordID: uploadedID,
+					Data: srvrModels.BinaryInfo{
+						FileName: "dir/movie.mkv",
+						BlobID:   blobID.Hex(),
+						Size:     "9",
+					},
+				},
+				{
+					RecordID: brokenID,
+					Data: srvrModels.BinaryInfo{
+						FileName: "broken.mkv",
+						BlobID:   brokenBlobID.Hex(),
+						Size:     "9",
+					},
+				},
+			},
+		}
+		localStore = mockLocalStore(mockCtrl, cmd.Flag("file").Value.String(), r)
+		blobs := mock.NewMockBlobService(mockCtrl)
+		blobs.EXPECT().
+			Download(blobID, gomock.Any(), "sometoken").
+			DoAndReturn(func(_ srvrModels.ObjectID, w io.Writer, _ string) error {
+				_, err := w.Write([]byte("hello, go"))
+				return err
+			}).
+			AnyTimes()
+		blobs.EXPECT().
+			Download(brokenBlobID, gomock.Any(), "sometoken").
+			DoAndReturn(func(_ srvrModels.ObjectID, w io.Writer, _ string) error {
+				w.Write([]byte("hell"))
+				return fmt.Errorf("the content is truncated")
+			}).
+			AnyTimes()
+		blobService = blobs
+	}
+
+	rootCmd := CRUDCmd
+	dir := t.TempDir()
+	download := func(id srvrModels.ObjectID, output string) error {
+		return cotesting.ExecuteCommandC(
+			rootCmd,
+			"download",
+			id.Hex(),
+			"--key=correctkey",
+			"--file=fname",
+			"--token=sometoken",
+			"--output="+output,
+		)
+	}
+	t.Run("no_id", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"download",
+			"--key=correctkey",
+			"--file=fname",
+			"--token=sometoken",
+		)
+		assert.Error(t, err)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		err := download(srvrModels.NewRandomObjectID(), filepath.Join(dir, "missing"))
+		assert.Error(t, err)
+	})
+	t.Run("inline", func(t *testing.T) {
+		output := filepath.Join(dir, "notes.txt")
+		require.NoError(t, download(inlineID, output))
+		content, err := os.ReadFile(output)
+		require.NoError(t, err)
+		assert.Equal(t, "hello, go", string(content))
+	})
+	t.Run("uploaded", func(t *testing.T) {
+		output := filepath.Join(dir, "movie.mkv")
+		require.NoError(t, download(uploadedID, output))
+		content, err := os.ReadFile(output)
+		require.NoError(t, err)
+		assert.Equal(t, "hello, go", string(content))
+	})
+	t.Run("truncated", func(t *testing.T) {
+		output := filepath.Join(dir, "broken.mkv")
+		assert.Error(t, download(brokenID, output))
+		assert.NoFileExists(t, output)
+	})
+}
+
+func TestHistoryCommand(t *testing.T) {
+	id := srvrModels.NewRandomObjectID()
+	CRUDCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		storageService = mock.NewMockStorageService(mockCtrl)
+
+		storageService.(*mock.MockStorageService).EXPECT().
+			History(srvrModels.TextCollection, id, "sometoken").
+			AnyTimes().
+			Return([]srvrModels.Revision{{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "old text"},
+				RecordID:             id,
+				Rev:                  1,
+				Op:                   srvrModels.OpUpdate,
+			}}, nil)
+		storageService.(*mock.MockStorageService).EXPECT().
+			History(srvrModels.TextCollection, gomock.Not(id), "sometoken").
+			AnyTimes().
+			Return(nil, fmt.Errorf("server error"))
+	}
+
+	rootCmd := CRUDCmd
+	t.Run("bad_id", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"history",
+			"--token=sometoken",
+			"--id=1234",
+			"--collection=text",
+		)
+		assert.Error(t, err)
+	})
+	t.Run("service_error", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"history",
+			"--token=sometoken",
+			"--id="+srvrModels.NewRandomObjectID().Hex(),
+			"--collection=text",
+		)
+		assert.Error(t, err)
+	})
+	t.Run("ok", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"history",
+			"--token=sometoken",
+			"--id="+id.Hex(),
+			"--collection=text",
+		)
+		assert.NoError(t, err)
+	})
+}
+
+func TestRestoreCommand(t *testing.T) {
+	id := srvrModels.NewRandomObjectID()
+	CRUDCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		storageService = mock.NewMockStorageService(mockCtrl)
+
+		storageService.(*mock.MockStorageService).EXPECT().
+			Restore(srvrModels.TextCollection, id, int64(2), "sometoken").
+			AnyTimes().
+			Return("restored", nil)
+		storageService.(*mock.MockStorageService).EXPECT().
+			Restore(srvrModels.TextCollection, id, int64(7), "sometoken").
+			AnyTimes().
+			Return("", fmt.Errorf("revision was not found"))
+	}
+
+	rootCmd := CRUDCmd
+	t.Run("bad_collection", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"restore",
+			"--token=sometoken",
+			"--id="+id.Hex(),
+			"--rev=2",
+			"--collection=badcollection",
+		)
+		assert.Error(t, err)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"restore",
+			"--token=sometoken",
+			"--id="+id.Hex(),
+			"--rev=7",
+			"--collection=text",
+		)
+		assert.Error(t, err)
+	})
+	t.Run("ok", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"restore",
+			"--token=sometoken",
+			"--id="+id.Hex(),
+			"--rev=2",
+			"--collection=text",
+		)
+		assert.NoError(t, err)
+	})
+}
+
+func TestTrashCommand(t *testing.T) {
+	id := srvrModels.NewRandomObjectID()
+	CRUDCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		storageService = mock.NewMockStorageService(mockCtrl)
+
+		storageService.(*mock.MockStorageService).EXPECT().
+			Trash(srvrModels.TextCollection, "sometoken").
+			AnyTimes().
+			Return([]srvrModels.UntypedRecord{{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "deleted text"},
+				RecordID:             id,
+			}}, nil)
+		storageService.(*mock.MockStorageService).EXPECT().
+			Undelete(srvrModels.TextCollection, id, "sometoken").
+			AnyTimes().
+			Return("restored", nil)
+		storageService.(*mock.MockStorageService).EXPECT().
+			Undelete(srvrModels.TextCollection, gomock.Not(id), "sometoken").
+			AnyTimes().
+			Return("", fmt.Errorf("record was not found"))
+		storageService.(*mock.MockStorageService).EXPECT().
+			Purge(srvrModels.TextCollection, id, "sometoken").
+			AnyTimes().
+			Return("purged", nil)
+	}
+
+	rootCmd := CRUDCmd
+	t.Run("list", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"trash",
+			"list",
+			"--token=sometoken",
+			"--collection=text",
+		)
+		assert.NoError(t, err)
+	})
+	t.Run("list_bad_collection", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"trash",
+			"list",
+			"--token=sometoken",
+			"--collection=badcollection",
+		)
+		assert.Error(t, err)
+	})
+	t.Run("restore", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"trash",
+			"restore",
+			"--token=sometoken",
+			"--id="+id.Hex(),
+			"--collection=text",
+		)
+		assert.NoError(t, err)
+	})
+	t.Run("restore_not_found", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"trash",
+			"restore",
+			"--token=sometoken",
+			"--id="+srvrModels.NewRandomObjectID().Hex(),
+			"--collection=text",
+		)
+		assert.Error(t, err)
+	})
+	t.Run("purge", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"trash",
+			"purge",
+			"--token=sometoken",
+			"--id="+id.Hex(),
+			"--collection=text",
+		)
+		assert.NoError(t, err)
+	})
+	t.Run("purge_bad_id", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"trash",
+			"purge",
+			"--token=sometoken",
+			"--id=1234",
+			"--collection=text",
+		)
+		assert.Error(t, err)
+	})
+}
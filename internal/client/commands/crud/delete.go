@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
 )
 
@@ -12,10 +13,13 @@ import (
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "delete command",
-	Long: `The 'delete' command deletes a single record from the specified collection in the remote storage service.
+	Long: `The 'delete' command moves a single record of the specified collection to the trash of the remote storage service.
+Use the 'trash' commands to restore or purge it.
 It requires a valid authentication token and the record ID to be deleted as a flag.
 Provide the name of the collection to delete the record from as an argument.
-The command will construct a request body using the provided record ID, and send the DELETE request to the remote service.`,
+The command will construct a request body using the provided record ID, and send the DELETE request to the remote service.
+With the key of the local store the record is removed from it as well, and the removal
+is queued if the server is unavailable.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		token := cmd.Flag("token").Value.String()
 		id := cmd.Flag("id").Value.String()
@@ -37,7 +41,12 @@ The command will construct a request body using the provided record ID, and send
 
 func init() {
 	deleteCmd.PersistentFlags().String("id", "", "id of a record to delete")
-	deleteCmd.PersistentFlags().String("token", "t", "user's jwt token")
+	deleteCmd.PersistentFlags().String("token", "", "user's jwt token (default: from the profile)")
+	deleteCmd.PersistentFlags().
+		StringP("file", "f", "", "filename of the local store (default: from the profile)")
+	deleteCmd.PersistentFlags().
+		StringP("key", "k", "", "key of the local store which enables the offline mode")
+	profile.MarkFileFlag(deleteCmd, "file")
 	for _, flag := range []string{"id", "token"} {
 		deleteCmd.MarkPersistentFlagRequired(flag)
 	}
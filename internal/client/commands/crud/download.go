@@ -0,0 +1,133 @@
+package crud
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/client/progress"
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// downloadCmd represents the download command
+var downloadCmd = &cobra.Command{
+	Use:   "download <id>",
+	Short: "download command",
+	Long: `The download command saves the file of a record of the binary collection.
+It accepts flags to decrypt the data from the local store kept by the sync command.
+The uploaded file is streamed from the server to the disk with its progress shown,
+while the small files kept inline in the record are saved without the server.
+By default the file is saved to the current directory under its original name.`,
+	Args: cobra.ExactArgs(1),
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// the command always works with the binary collection
+		cmd.Flags().Set("collection", string(models.BinaryCollection))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := cmd.Flag("key").Value.String()
+		file := cmd.Flag("file").Value.String()
+		token := cmd.Flag("token").Value.String()
+		output := cmd.Flag("output").Value.String()
+		id := args[0]
+
+		decrypted, err := loadData(file, key)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		for _, r := range decrypted.Binary {
+			if r.RecordID.Hex() != id {
+				continue
+			}
+			if output == "" {
+				output = filepath.Base(r.Data.FileName)
+			}
+			if err := saveFile(r.Data, output, token); err != nil {
+				fmt.Println(err)
+				return err
+			}
+			fmt.Printf("Saved to %v\n", output)
+			return nil
+		}
+		err = fmt.Errorf("%w: id=%v", errors.ErrRecordNotFound, id)
+		fmt.Println(err)
+		return err
+	},
+}
+
+// saveFile writes the content of the binary record to the file. The partially
+// written file is removed on failure.
+func saveFile(data models.BinaryInfo, output, token string) (err error) {
+	var content []byte
+	if data.BlobID == "" {
+		if content, err = base64.StdEncoding.DecodeString(data.Content); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(output)
+		}
+	}()
+	if data.BlobID == "" {
+		_, err = f.Write(content)
+		return err
+	}
+	blobID, err := models.ObjectIDFromString(data.BlobID)
+	if err != nil {
+		return err
+	}
+	size, err := strconv.ParseInt(data.Size, 10, 64)
+	if err != nil {
+		return fmt.Errorf("bad size of the file: %w", err)
+	}
+	w := &progressWriter{
+		w:     f,
+		bar:   progress.NewBar(os.Stdout, filepath.Base(data.FileName)),
+		total: size,
+	}
+	return blobService.Download(blobID, w, token)
+}
+
+// progressWriter reports the progress of the writes to the bar.
+type progressWriter struct {
+	w       io.Writer
+	bar     *progress.Bar
+	written int64
+	total   int64
+}
+
+// Write writes the data and updates the bar.
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.written += int64(n)
+	w.bar.Update(w.written, w.total)
+	return n, err
+}
+
+func init() {
+	downloadCmd.PersistentFlags().
+		StringP("file", "f", "", "filename of the local store (default: from the profile)")
+	downloadCmd.PersistentFlags().StringP("key", "k", "", "key of the local store")
+	downloadCmd.PersistentFlags().String("token", "", "user's jwt token (default: from the profile)")
+	downloadCmd.PersistentFlags().
+		StringP("output", "o", "", "path to save the file to (default: the name of the file)")
+	profile.MarkFileFlag(downloadCmd, "file")
+	for _, flag := range []string{"file", "key", "token"} {
+		downloadCmd.MarkPersistentFlagRequired(flag)
+	}
+}
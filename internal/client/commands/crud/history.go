@@ -0,0 +1,53 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "history command",
+	Long: `The 'history' command lists the previous revisions of a record from the specified collection, the latest first.
+The server keeps a revision every time the record is updated or deleted for the retention period set on the server.
+The rev field of a revision is the version of the record it holds; pass it to the 'restore' command to bring the revision back.
+The result is returned as a JSON string.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		collectionName, err := models.NewCollectionName(cmd.Flag("collection").Value.String())
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		id, err := models.ObjectIDFromString(cmd.Flag("id").Value.String())
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		revisions, err := storageService.History(collectionName, id, token)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		resJSON, err := json.MarshalIndent(revisions, "", "  ")
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Printf("Result: %s\n", resJSON)
+		return nil
+	},
+}
+
+func init() {
+	historyCmd.PersistentFlags().String("id", "", "id of a record")
+	historyCmd.PersistentFlags().String("token", "", "user's jwt token (default: from the profile)")
+	for _, flag := range []string{"id", "token"} {
+		historyCmd.MarkPersistentFlagRequired(flag)
+	}
+}
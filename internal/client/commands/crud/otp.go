@@ -0,0 +1,112 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+var (
+	// otpCmd represents the otp command
+	otpCmd = &cobra.Command{
+		Use:   "otp",
+		Short: "one-time passwords commands",
+		Long:  "A parent command for operations with the otp collection.",
+	}
+	// otpCodeCmd represents the otp code command
+	otpCodeCmd = &cobra.Command{
+		Use:   "code",
+		Short: "code command",
+		Long: `The code command generates the current one-time password for a record of the otp collection.
+It accepts flags to decrypt the data from the local store kept by the sync command.
+TOTP codes are printed together with the number of seconds they remain valid.
+The counter of a HOTP record is advanced on the server and in the local store
+before the code is printed, so the same code is never issued twice.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			// the command always works with the otp collection
+			cmd.Flags().Set("collection", string(models.OTPCollection))
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token := cmd.Flag("token").Value.String()
+			key := cmd.Flag("key").Value.String()
+			file := cmd.Flag("file").Value.String()
+			id := cmd.Flag("id").Value.String()
+
+			decrypted, err := loadData(file, key)
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			for _, r := range decrypted.OTP {
+				if r.RecordID.Hex() != id {
+					continue
+				}
+				code, err := r.Data.Code(time.Now())
+				if err != nil {
+					fmt.Println(err)
+					return err
+				}
+				if r.Data.IsHOTP() {
+					if err := advanceCounter(r, token); err != nil {
+						fmt.Println(err)
+						return err
+					}
+				}
+				fmt.Printf("Code: %s\n", code.Code)
+				if code.RemainingSeconds > 0 {
+					fmt.Printf("Valid for: %ds\n", code.RemainingSeconds)
+				}
+				return nil
+			}
+			err = fmt.Errorf("%w: id=%v", errors.ErrRecordNotFound, id)
+			fmt.Println(err)
+			return err
+		},
+	}
+)
+
+// advanceCounter saves the incremented counter of the HOTP record on the server
+// and then in the local store, so the next code is generated from the new counter
+// even before the next sync. The record isn't updated if it has been changed
+// since the last sync.
+func advanceCounter(r models.OTPRecord, token string) error {
+	next, err := r.Data.Next()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]any{
+		"record_id":        r.RecordID,
+		"data":             next,
+		"metadata":         r.Metadata,
+		"expected_version": r.Version,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := storageService.Update(string(body), models.OTPCollection, token); err != nil {
+		return err
+	}
+	r.Data = next
+	r.Version++
+	return localStore.Merge(&clientModels.SyncResponse{OTP: []models.OTPRecord{r}})
+}
+
+func init() {
+	otpCodeCmd.PersistentFlags().
+		StringP("file", "f", "", "filename of the local store (default: from the profile)")
+	otpCodeCmd.PersistentFlags().StringP("key", "k", "", "key of the local store")
+	otpCodeCmd.PersistentFlags().String("id", "", "id of an otp record")
+	otpCodeCmd.PersistentFlags().String("token", "", "user's jwt token (default: from the profile)")
+	profile.MarkFileFlag(otpCodeCmd, "file")
+	for _, flag := range []string{"file", "key", "id", "token"} {
+		otpCodeCmd.MarkPersistentFlagRequired(flag)
+	}
+	otpCmd.AddCommand(otpCodeCmd)
+}
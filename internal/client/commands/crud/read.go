@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
 )
 
@@ -14,7 +15,11 @@ var readCmd = &cobra.Command{
 	Use:   "read",
 	Short: "read command",
 	Long: `The readCmd command retrieves all documents from a specified collection.
-It accepts flags to decrypt the data from an encrypted file.
+It accepts flags to decrypt the data from the local store kept by the sync command,
+so the records are available while the server is unavailable.
+The records are filtered by the metadata values (--meta key=value), sorted by the time
+they were created or updated (--sort updated, -updated for the newest first) and
+listed in pages of --limit records; the cursor printed after a page starts the next one.
 The result is returned as a JSON string.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		key := cmd.Flag("key").Value.String()
@@ -25,27 +30,74 @@ The result is returned as a JSON string.`,
 			return err
 		}
 
-		decrypted, err := encryptService.FromEncryptedFile(file, key)
+		decrypted, err := loadData(file, key)
 		if err != nil {
 			fmt.Println(err)
 			return err
 		}
 
-		res := storageService.GetAll(collectionName, decrypted)
+		options, err := readOptions(cmd)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+
+		res, cursor, err := storageService.GetAll(collectionName, decrypted, options)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
 		resJSON, err := json.MarshalIndent(res, "", "  ")
 		if err != nil {
 			fmt.Println(err)
 			return err
 		}
 		fmt.Printf("Result: %s\n", resJSON)
+		if cursor != "" {
+			fmt.Printf("Next page: --cursor=%s\n", cursor)
+		}
 		return nil
 	},
 }
 
+// readOptions returns the options of the listing set by the flags.
+func readOptions(cmd *cobra.Command) (models.GetAllOptions, error) {
+	flags := cmd.Flags()
+	options := models.GetAllOptions{
+		Cursor: cmd.Flag("cursor").Value.String(),
+		Sort:   cmd.Flag("sort").Value.String(),
+	}
+	var err error
+	if options.Limit, err = flags.GetInt64("limit"); err != nil {
+		return options, err
+	}
+	if options.OmitContent, err = flags.GetBool("omit-content"); err != nil {
+		return options, err
+	}
+	meta, err := flags.GetStringToString("meta")
+	if err != nil {
+		return options, err
+	}
+	if len(meta) > 0 {
+		options.Metadata = models.Metadata(meta)
+	}
+	return options, nil
+}
+
 func init() {
-	readCmd.PersistentFlags().StringP("file", "f", "", "filename to load synced data from")
-	readCmd.PersistentFlags().StringP("key", "k", "", "key for data decryption")
+	readCmd.PersistentFlags().
+		StringP("file", "f", "", "filename of the local store (default: from the profile)")
+	readCmd.PersistentFlags().StringP("key", "k", "", "key of the local store")
+	readCmd.PersistentFlags().
+		Int64("limit", 0, "maximum number of the records in the page (default: all the records)")
+	readCmd.PersistentFlags().String("cursor", "", "cursor of the page printed with the previous one")
+	readCmd.PersistentFlags().
+		String("sort", "", "created or updated, prefixed with - for the descending order (default: created)")
+	readCmd.PersistentFlags().
+		StringToStringP("meta", "m", nil, "metadata values the records must have, e.g. site=github.com")
+	readCmd.PersistentFlags().Bool("omit-content", false, "omit the content of the binary records")
 
+	profile.MarkFileFlag(readCmd, "file")
 	for _, flag := range []string{"file", "key"} {
 		readCmd.MarkPersistentFlagRequired(flag)
 	}
@@ -0,0 +1,53 @@
+package crud
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "restore command",
+	Long: `The 'restore' command replaces a record from the specified collection with one of its previous revisions.
+It requires a valid authentication token, the record ID and the revision listed by the 'history' command as flags.
+The replaced state is kept in the history as well, so the restore can be undone.
+A deleted record is recreated with the same ID.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := cmd.Flag("token").Value.String()
+		collectionName, err := models.NewCollectionName(cmd.Flag("collection").Value.String())
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		id, err := models.ObjectIDFromString(cmd.Flag("id").Value.String())
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		rev, err := cmd.Flags().GetInt64("rev")
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		msg, err := storageService.Restore(collectionName, id, rev, token)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		fmt.Println(msg)
+		return nil
+	},
+}
+
+func init() {
+	restoreCmd.PersistentFlags().String("id", "", "id of a record to restore")
+	restoreCmd.PersistentFlags().Int64("rev", 0, "revision to restore")
+	restoreCmd.PersistentFlags().String("token", "", "user's jwt token (default: from the profile)")
+	for _, flag := range []string{"id", "rev", "token"} {
+		restoreCmd.MarkPersistentFlagRequired(flag)
+	}
+}
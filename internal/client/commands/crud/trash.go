@@ -0,0 +1,103 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+var (
+	// trashCmd represents the trash command
+	trashCmd = &cobra.Command{
+		Use:   "trash",
+		Short: "trash commands",
+		Long: `A parent command for operations with the deleted records of a collection.
+The server keeps the deleted records in the trash for the period set on the server and purges them afterwards.`,
+	}
+	// trashListCmd represents the trash list command
+	trashListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list command",
+		Long: `The list command lists the deleted records of the specified collection which have not been purged yet.
+The result is returned as a JSON string.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token := cmd.Flag("token").Value.String()
+			collectionName, err := models.NewCollectionName(cmd.Flag("collection").Value.String())
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			records, err := storageService.Trash(collectionName, token)
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			resJSON, err := json.MarshalIndent(records, "", "  ")
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			fmt.Printf("Result: %s\n", resJSON)
+			return nil
+		},
+	}
+	// trashRestoreCmd represents the trash restore command
+	trashRestoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "restore command",
+		Long: `The restore command moves a deleted record of the specified collection back from the trash.
+The record keeps its ID and data.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTrashCommand(cmd, storageService.Undelete)
+		},
+	}
+	// trashPurgeCmd represents the trash purge command
+	trashPurgeCmd = &cobra.Command{
+		Use:   "purge",
+		Short: "purge command",
+		Long: `The purge command permanently deletes a record of the specified collection from the trash.
+The purged record can't be moved back from the trash, but its revisions can be restored
+from the history for the retention period set on the server.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTrashCommand(cmd, storageService.Purge)
+		},
+	}
+)
+
+// runTrashCommand applies an action to a record of the trash and prints the result.
+func runTrashCommand(
+	cmd *cobra.Command,
+	action func(models.CollectionName, models.ObjectID, string) (string, error),
+) error {
+	token := cmd.Flag("token").Value.String()
+	collectionName, err := models.NewCollectionName(cmd.Flag("collection").Value.String())
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	id, err := models.ObjectIDFromString(cmd.Flag("id").Value.String())
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	msg, err := action(collectionName, id, token)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	fmt.Println(msg)
+	return nil
+}
+
+func init() {
+	trashCmd.PersistentFlags().String("token", "", "user's jwt token (default: from the profile)")
+	trashCmd.MarkPersistentFlagRequired("token")
+	for _, c := range []*cobra.Command{trashRestoreCmd, trashPurgeCmd} {
+		c.PersistentFlags().String("id", "", "id of a deleted record")
+		c.MarkPersistentFlagRequired("id")
+	}
+	trashCmd.AddCommand(trashListCmd, trashRestoreCmd, trashPurgeCmd)
+}
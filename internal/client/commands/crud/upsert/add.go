@@ -15,7 +15,8 @@ var addCmd = &cobra.Command{
 	Long: `The add command allows users to add new records to a specified collection.
 It requires a valid JWT token for authorization and accepts various flags for
 different types of data. The command constructs the record body and sends it
-to the server for storage.`,
+to the server for storage. The file of a binary record is uploaded first,
+and the progress of the upload is shown.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		token := cmd.Flag("token").Value.String()
 		collectionName, err := models.NewCollectionName(cmd.Flag("collection").Value.String())
@@ -24,7 +25,12 @@ to the server for storage.`,
 			return err
 		}
 
-		body, err := getBody(&cmdFlags, collectionName, "000000000000000000000000")
+		flags, err := withUploadedFile(cmdFlags, collectionName, token)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		body, err := getBody(flags, collectionName, "000000000000000000000000")
 		if err != nil {
 			fmt.Println(err)
 			return err
@@ -0,0 +1,53 @@
+package upsert
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/progress"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// uploadFile streams the file from the disk to the blob store and returns
+// the binary data which refers to the uploaded blob.
+func uploadFile(fileName, token string) (models.BinaryInfo, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return models.BinaryInfo{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return models.BinaryInfo{}, err
+	}
+	bar := progress.NewBar(os.Stdout, filepath.Base(fileName))
+	blob, err := service.UploadBlob(blobService, f, info.Size(), token, bar.Update)
+	if err != nil {
+		return models.BinaryInfo{}, err
+	}
+	return models.BinaryInfo{
+		FileName: fileName,
+		BlobID:   blob.BlobID.Hex(),
+		Size:     strconv.FormatInt(info.Size(), 10),
+	}, nil
+}
+
+// withUploadedFile returns a copy of the flags. For a binary record the file
+// is uploaded, and the copy refers to the uploaded blob.
+func withUploadedFile(
+	flags UpsertFlags,
+	collectionName models.CollectionName,
+	token string,
+) (*UpsertFlags, error) {
+	if collectionName != models.BinaryCollection {
+		return &flags, nil
+	}
+	data, err := uploadFile(flags.BinaryInfo.FileName, token)
+	if err != nil {
+		return nil, err
+	}
+	flags.BinaryInfo = data
+	return &flags, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/blokhinnv/gophkeeper/internal/server/errors"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/otp"
 )
 
 // metadataFromFlags converts metadata from flags to models.Metadata
@@ -59,14 +60,17 @@ func getBody(
 			RecordID: recordID,
 		}
 	case models.BinaryCollection:
-		fname := flags.BinaryInfo.FileName
-		content, err := fileToBase64(fname)
-		if err != nil {
-			return "", err
-		}
-		data := models.BinaryInfo{
-			FileName: fname,
-			Content:  content,
+		data := flags.BinaryInfo
+		// the content of a file which isn't uploaded as a blob is kept inline
+		if data.BlobID == "" {
+			content, err := fileToBase64(data.FileName)
+			if err != nil {
+				return "", err
+			}
+			data = models.BinaryInfo{
+				FileName: data.FileName,
+				Content:  content,
+			}
 		}
 		body = &models.BinaryRecord{Data: data, Metadata: md, RecordID: recordID}
 	case models.CardCollection:
@@ -77,6 +81,16 @@ func getBody(
 			Metadata: md,
 			RecordID: recordID,
 		}
+	case models.OTPCollection:
+		data := flags.OTPInfo
+		if flags.OTPURI != "" {
+			key, err := otp.ParseURI(flags.OTPURI)
+			if err != nil {
+				return "", err
+			}
+			data = models.NewOTPInfo(key)
+		}
+		body = &models.OTPRecord{Data: data, Metadata: md, RecordID: recordID}
 	default:
 		return "", fmt.Errorf("%w: %v", errors.ErrUnknownCollection, collectionName)
 	}
@@ -87,3 +101,17 @@ func getBody(
 	}
 	return string(bodyEncoded), nil
 }
+
+// withExpectedVersion adds the version the record is expected to have on the server to the body.
+func withExpectedVersion(body string, version int64) (string, error) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return "", err
+	}
+	fields["expected_version"] = version
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
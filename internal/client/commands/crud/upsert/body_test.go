@@ -159,6 +159,20 @@ func TestGetBody(t *testing.T) {
 			expectedBody:   `{"Data":{"FileName":"sample.txt","Content":"aGVsbG8sIGdv"},"Metadata":{"key1":"value1","key2":"value2"},"record_id":"1234567890abcdef12345678"}`,
 			expectedError:  nil,
 		},
+		{
+			name: "uploaded binary collection",
+			flags: &UpsertFlags{
+				BinaryInfo: models.BinaryInfo{
+					FileName: "movie.mkv",
+					BlobID:   "6457e99ec51d35bd689f2f5b",
+					Size:     "1048577",
+				},
+			},
+			collectionName: models.BinaryCollection,
+			recordIDHex:    "1234567890abcdef12345678",
+			expectedBody:   `{"Data":{"FileName":"movie.mkv","BlobID":"6457e99ec51d35bd689f2f5b","Size":"1048577"},"Metadata":{},"record_id":"1234567890abcdef12345678"}`,
+			expectedError:  nil,
+		},
 		{
 			name: "valid card collection",
 			flags: &UpsertFlags{
@@ -188,6 +202,40 @@ func TestGetBody(t *testing.T) {
 			expectedBody:   `{"Data":{"Login":"user1","Password":"password1"},"Metadata":{"key1":"value1","key2":"value2"},"record_id":"1234567890abcdef12345678"}`,
 			expectedError:  nil,
 		},
+		{
+			name: "valid otp collection",
+			flags: &UpsertFlags{
+				OTPInfo: models.OTPInfo{
+					Secret: "JBSWY3DPEHPK3PXP",
+					Issuer: "Example",
+				},
+				Metadata: MetadataSlice{"key1;value1"},
+			},
+			collectionName: models.OTPCollection,
+			recordIDHex:    "1234567890abcdef12345678",
+			expectedBody:   `{"Data":{"Type":"","Secret":"JBSWY3DPEHPK3PXP","Issuer":"Example","Account":"","Algorithm":"","Digits":"","Period":"","Counter":""},"Metadata":{"key1":"value1"},"record_id":"1234567890abcdef12345678"}`,
+			expectedError:  nil,
+		},
+		{
+			name: "valid otp uri",
+			flags: &UpsertFlags{
+				OTPURI: "otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&issuer=Example",
+			},
+			collectionName: models.OTPCollection,
+			recordIDHex:    "1234567890abcdef12345678",
+			expectedBody:   `{"Data":{"Type":"totp","Secret":"JBSWY3DPEHPK3PXP","Issuer":"Example","Account":"alice","Algorithm":"SHA1","Digits":"6","Period":"30","Counter":""},"Metadata":{},"record_id":"1234567890abcdef12345678"}`,
+			expectedError:  nil,
+		},
+		{
+			name: "invalid otp uri",
+			flags: &UpsertFlags{
+				OTPURI: "https://example.com",
+			},
+			collectionName: models.OTPCollection,
+			recordIDHex:    "1234567890abcdef12345678",
+			expectedBody:   "",
+			expectedError:  fmt.Errorf("uri error"),
+		},
 		{
 			name: "unknown collection",
 			flags: &UpsertFlags{
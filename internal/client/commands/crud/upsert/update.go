@@ -1,10 +1,14 @@
 package upsert
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
 
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
 )
 
@@ -14,7 +18,14 @@ var updateCmd = &cobra.Command{
 	Short: "update command",
 	Long: `The update command updates an existing record in a specified collection.
 It requires a valid token, a collection name, and the id of the record to be updated.
-It also expects a valid JSON body that contains the updated information for the record.`,
+It also expects a valid JSON body that contains the updated information for the record.
+With --expected-version the record is updated only if nobody changed it since
+that version. With the key of the local store the version of the local copy
+is expected by default, and --force updates the record without the check.
+On a conflict the current copy on the server is printed, unless
+--on-conflict tells how to resolve the conflict: mine overwrites the copy,
+theirs discards the local changes and merge keeps the local data and merges
+the metadata of both copies.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		token := cmd.Flag("token").Value.String()
 		id := cmd.Flag("id").Value.String()
@@ -23,13 +34,62 @@ It also expects a valid JSON body that contains the updated information for the
 			fmt.Println(err)
 			return err
 		}
+		var resolution service.ConflictResolution
+		if name := cmd.Flag("on-conflict").Value.String(); name != "" {
+			if resolution, err = service.NewConflictResolution(name); err != nil {
+				fmt.Println(err)
+				return err
+			}
+		}
 
-		body, err := getBody(&cmdFlags, collectionName, id)
+		flags, err := withUploadedFile(cmdFlags, collectionName, token)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		body, err := getBody(flags, collectionName, id)
 		if err != nil {
 			fmt.Println(err)
 			return err
 		}
+		expectedVersion, err := cmd.Flags().GetInt64("expected-version")
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		if expectedVersion == 0 && !force {
+			if expectedVersion, err = cachedVersion(collectionName, id); err != nil {
+				fmt.Println(err)
+				return err
+			}
+		}
+		if expectedVersion != 0 {
+			if body, err = withExpectedVersion(body, expectedVersion); err != nil {
+				fmt.Println(err)
+				return err
+			}
+		}
 		msg, err := storageService.Update(body, collectionName, token)
+		var conflict *clientErr.ConflictError
+		if errors.As(err, &conflict) && resolution != "" {
+			msg, err = service.ResolveConflict(
+				storageService,
+				body,
+				collectionName,
+				token,
+				conflict,
+				resolution,
+			)
+		} else if errors.As(err, &conflict) {
+			current, _ := json.MarshalIndent(conflict.Current, "", "  ")
+			fmt.Printf("%v\nThe current record:\n%s\n", err, current)
+			return err
+		}
 		if err != nil {
 			fmt.Println(err)
 			return err
@@ -40,9 +100,33 @@ It also expects a valid JSON body that contains the updated information for the
 	},
 }
 
+// cachedVersion returns the version of the local copy of the record;
+// zero if there is no local store or the record is not in it.
+func cachedVersion(collectionName models.CollectionName, id string) (int64, error) {
+	if localStore == nil {
+		return 0, nil
+	}
+	recordID, err := models.ObjectIDFromString(id)
+	if err != nil {
+		return 0, err
+	}
+	data, err := localStore.Load()
+	if err != nil {
+		return 0, err
+	}
+	return data.Version(collectionName, recordID), nil
+}
+
 func init() {
 	UpsertCmd.AddCommand(updateCmd)
 	updateCmd.PersistentFlags().String("id", "", "id of a record to update")
 	updateCmd.MarkPersistentFlagRequired("id")
+	updateCmd.PersistentFlags().
+		Int64("expected-version", 0, "version the record must have to be updated (default: the version of the local copy)")
+	updateCmd.PersistentFlags().
+		Bool("force", false, "update the record without checking its version")
+	updateCmd.MarkFlagsMutuallyExclusive("expected-version", "force")
+	updateCmd.PersistentFlags().
+		String("on-conflict", "", "resolution of a version conflict: mine, theirs or merge (default: print the current record)")
 
 }
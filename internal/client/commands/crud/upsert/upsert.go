@@ -4,8 +4,10 @@ package upsert
 import (
 	"github.com/spf13/cobra"
 
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
 	"github.com/blokhinnv/gophkeeper/internal/client/service"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
 )
 
 // MetadataSlice is a slice of strings to store metadata
@@ -18,6 +20,8 @@ type UpsertFlags struct {
 	models.BinaryInfo
 	models.CredentialInfo
 	models.CardInfo
+	models.OTPInfo
+	OTPURI   string
 	Metadata MetadataSlice
 }
 
@@ -26,21 +30,60 @@ var (
 	cmdFlags = UpsertFlags{}
 	// storageService is a storage service used for a command implementation.
 	storageService service.StorageService
+	// blobService is a service used to upload the files of the binary records.
+	blobService service.BlobService
+	// localStore is the local store used for a command implementation;
+	// nil without the key of the store.
+	localStore service.LocalStore
 	// UpsertCmd represents the upsert command.
 	UpsertCmd = &cobra.Command{
 		Use:   "upsert",
 		Short: "upsert command",
-		Long:  "A parent command for add and update.",
+		Long: `A parent command for add and update.
+With the key of the local store the records are saved to it as well,
+and the writes are queued while the server is unavailable.
+The files of the binary records are streamed from the disk to the server
+in chunks, so the upload requires the server to be available.`,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if _, err := profile.Apply(cmd); err != nil {
+				log.Fatalf("Error while loading the profile: %v", err)
+			}
 			baseURL := cmd.Flag("server").Value.String()
-			storageService = service.NewStorageService(baseURL)
+			transport := cmd.Flag("transport").Value.String()
+			var err error
+			storageService, err = service.NewStorageServiceWithTransport(transport, baseURL)
+			if err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			blobService, err = service.NewBlobServiceWithTransport(transport, baseURL)
+			if err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			if password := cmd.Flag("master-password").Value.String(); password != "" {
+				vault, err := service.NewVaultWithTransport(transport, baseURL, password)
+				if err != nil {
+					log.Fatalf("Error while creating a service: %v", err)
+				}
+				storageService = service.NewE2EStorageService(storageService, vault)
+				blobService = service.NewE2EBlobService(blobService, vault)
+			}
+			localStore = nil
+			if key := cmd.Flag("key").Value.String(); key != "" {
+				localStore = service.NewLocalStore(cmd.Flag("store").Value.String(), key)
+				storageService = service.NewOfflineStorageService(storageService, localStore)
+			}
 		},
 	}
 )
 
 func init() {
-	UpsertCmd.PersistentFlags().String("token", "", "user's jwt token")
+	UpsertCmd.PersistentFlags().String("token", "", "user's jwt token (default: from the profile)")
 	UpsertCmd.MarkPersistentFlagRequired("token")
+	UpsertCmd.PersistentFlags().
+		String("store", "", "filename of the local store (default: from the profile)")
+	UpsertCmd.PersistentFlags().
+		StringP("key", "k", "", "key of the local store which enables the offline mode")
+	profile.MarkFileFlag(UpsertCmd, "store")
 
 	UpsertCmd.PersistentFlags().
 		StringVar(&cmdFlags.TextInfo, "text", "", "data for a text record")
@@ -62,6 +105,26 @@ func init() {
 		StringVar(&cmdFlags.CardInfo.ExpirationDate, "expiration-date", "", "data for a credentials record")
 	UpsertCmd.MarkFlagsRequiredTogether("card-number", "cvv", "expiration-date")
 
+	UpsertCmd.PersistentFlags().
+		StringVar(&cmdFlags.OTPURI, "otp-uri", "", "otpauth:// URI of an otp record")
+	UpsertCmd.PersistentFlags().
+		StringVar(&cmdFlags.OTPInfo.Type, "otp-type", "", "data for an otp record: totp or hotp")
+	UpsertCmd.PersistentFlags().
+		StringVar(&cmdFlags.OTPInfo.Secret, "otp-secret", "", "data for an otp record: base32 secret")
+	UpsertCmd.PersistentFlags().
+		StringVar(&cmdFlags.OTPInfo.Issuer, "otp-issuer", "", "data for an otp record")
+	UpsertCmd.PersistentFlags().
+		StringVar(&cmdFlags.OTPInfo.Account, "otp-account", "", "data for an otp record")
+	UpsertCmd.PersistentFlags().
+		StringVar(&cmdFlags.OTPInfo.Algorithm, "otp-algorithm", "", "data for an otp record: SHA1, SHA256 or SHA512")
+	UpsertCmd.PersistentFlags().
+		StringVar(&cmdFlags.OTPInfo.Digits, "otp-digits", "", "data for an otp record")
+	UpsertCmd.PersistentFlags().
+		StringVar(&cmdFlags.OTPInfo.Period, "otp-period", "", "data for an otp record")
+	UpsertCmd.PersistentFlags().
+		StringVar(&cmdFlags.OTPInfo.Counter, "otp-counter", "", "data for an otp record")
+	UpsertCmd.MarkFlagsMutuallyExclusive("otp-uri", "otp-secret")
+
 	UpsertCmd.PersistentFlags().
 		StringSliceVarP(&cmdFlags.Metadata, "meta", "m", []string{}, "semicolor separated metadata values")
 
@@ -1,14 +1,22 @@
 package upsert
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/blokhinnv/gophkeeper/internal/client/commands/cotesting"
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
 	"github.com/blokhinnv/gophkeeper/internal/client/service/mock"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
 )
 
@@ -107,3 +115,176 @@ func TestUpdateCommand(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestUpdateCommand_Conflict(t *testing.T) {
+	current := models.UntypedRecord{
+		UntypedRecordContent: models.UntypedRecordContent{
+			Data:     "their text",
+			Metadata: models.Metadata{"site": "example.com"},
+		},
+		Version: 3,
+	}
+	var resolved string
+	UpsertCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		storageService = mock.NewMockStorageService(mockCtrl)
+		storageService.(*mock.MockStorageService).EXPECT().
+			Update(gomock.Any(), models.TextCollection, "sometoken").
+			DoAndReturn(func(body string, _ models.CollectionName, _ string) (string, error) {
+				if strings.Contains(body, `"expected_version":3`) {
+					resolved = body
+					return "ok", nil
+				}
+				return "", &clientErr.ConflictError{Current: current}
+			}).
+			AnyTimes()
+	}
+
+	rootCmd := UpsertCmd
+	args := []string{
+		"update",
+		"--token=sometoken",
+		"--collection=text",
+		"--text=my text",
+		"--meta=tag;new",
+		"--id=6457e99ec51d35bd689f2f5b",
+		"--expected-version=2",
+	}
+	t.Run("no_resolution", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, args...)
+		assert.ErrorIs(t, err, srvErrors.ErrVersionConflict)
+	})
+	t.Run("bad_resolution", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, append(args, "--on-conflict=ask")...)
+		assert.Error(t, err)
+	})
+	t.Run("merge", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, append(args, "--on-conflict=merge")...)
+		require.NoError(t, err)
+		assert.Contains(t, resolved, `"metadata":{"site":"example.com","tag":"new"}`)
+	})
+	t.Run("theirs", func(t *testing.T) {
+		resolved = ""
+		err := cotesting.ExecuteCommandC(rootCmd, append(args, "--on-conflict=theirs")...)
+		require.NoError(t, err)
+		assert.Empty(t, resolved)
+	})
+}
+
+// resetUpdateFlags restores the defaults of the update flags set by the previous runs.
+func resetUpdateFlags(t *testing.T) {
+	updateCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		require.NoError(t, f.Value.Set(f.DefValue))
+		f.Changed = false
+	})
+}
+
+func TestUpdateCommand_CachedVersion(t *testing.T) {
+	id := "6457e99ec51d35bd689f2f5b"
+	recordID, err := models.ObjectIDFromString(id)
+	require.NoError(t, err)
+	var sent string
+	UpsertCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		storageService = mock.NewMockStorageService(mockCtrl)
+		storageService.(*mock.MockStorageService).EXPECT().
+			Update(gomock.Any(), models.TextCollection, "sometoken").
+			DoAndReturn(func(body string, _ models.CollectionName, _ string) (string, error) {
+				sent = body
+				return "ok", nil
+			})
+		localStore = mock.NewMockLocalStore(mockCtrl)
+		localStore.(*mock.MockLocalStore).EXPECT().
+			Load().
+			Return(&clientModels.SyncResponse{
+				Text: []models.TextRecord{{RecordID: recordID, Version: 5}},
+			}, nil).
+			MaxTimes(1)
+	}
+	t.Cleanup(func() { localStore = nil })
+
+	rootCmd := UpsertCmd
+	args := []string{
+		"update",
+		"--token=sometoken",
+		"--collection=text",
+		"--text=my text",
+		"--id=" + id,
+	}
+	t.Run("cached", func(t *testing.T) {
+		resetUpdateFlags(t)
+		err := cotesting.ExecuteCommandC(rootCmd, args...)
+		require.NoError(t, err)
+		assert.Contains(t, sent, `"expected_version":5`)
+	})
+	t.Run("expected_version", func(t *testing.T) {
+		resetUpdateFlags(t)
+		err := cotesting.ExecuteCommandC(rootCmd, append(args, "--expected-version=4")...)
+		require.NoError(t, err)
+		assert.Contains(t, sent, `"expected_version":4`)
+	})
+	t.Run("force", func(t *testing.T) {
+		resetUpdateFlags(t)
+		err := cotesting.ExecuteCommandC(rootCmd, append(args, "--force")...)
+		require.NoError(t, err)
+		assert.NotContains(t, sent, "expected_version")
+	})
+}
+
+func TestAddCommand_Binary(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sample.txt")
+	require.NoError(t, os.WriteFile(file, []byte("hello, go"), 0644))
+	blobID := models.NewRandomObjectID()
+	var added string
+	UpsertCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		storageService = mock.NewMockStorageService(mockCtrl)
+		storageService.(*mock.MockStorageService).EXPECT().
+			Add(gomock.Any(), models.BinaryCollection, "sometoken").
+			DoAndReturn(func(body string, _ models.CollectionName, _ string) (string, error) {
+				added = body
+				return "ok", nil
+			}).
+			AnyTimes()
+		blobs := mock.NewMockBlobService(mockCtrl)
+		blobs.EXPECT().ChunkSize().Return(4).AnyTimes()
+		blobs.EXPECT().
+			Create(int64(9), "sometoken").
+			Return(&models.Blob{BlobID: blobID, Size: 9}, nil).
+			AnyTimes()
+		received := int64(0)
+		blobs.EXPECT().
+			Append(blobID, gomock.Any(), gomock.Any(), "sometoken").
+			DoAndReturn(func(_ models.ObjectID, offset int64, chunk []byte, _ string) (*models.Blob, error) {
+				received = offset + int64(len(chunk))
+				return &models.Blob{BlobID: blobID, Size: 9, Received: received}, nil
+			}).
+			AnyTimes()
+		blobService = blobs
+	}
+
+	rootCmd := UpsertCmd
+	t.Run("ok", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"add",
+			"--token=sometoken",
+			"--collection=binary",
+			"--file="+file,
+		)
+		require.NoError(t, err)
+		assert.Contains(t, added, `"BlobID":"`+blobID.Hex()+`"`)
+		assert.Contains(t, added, `"Size":"9"`)
+		assert.NotContains(t, added, "Content")
+	})
+	t.Run("no_file", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(
+			rootCmd,
+			"add",
+			"--token=sometoken",
+			"--collection=binary",
+			"--file="+filepath.Join(t.TempDir(), "nonexistent.txt"),
+		)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,179 @@
+// Package imports provides implementation of the import CLI-command.
+package imports
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/importer"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
+)
+
+var (
+	// storageService is a storage service used for a command implementation.
+	storageService service.StorageService
+	// blobService is a service used to upload the attachments.
+	blobService service.BlobService
+	// syncService is a service used to find the existing records.
+	syncService service.SyncService
+	// ImportCmd represents the import command
+	ImportCmd = &cobra.Command{
+		Use:   "import <file>",
+		Short: "import command",
+		Long: `The import command adds the records exported by another password manager.
+The format of the export is one of: ` + strings.Join(importer.Formats(), ", ") + `.
+The logins become credentials, the cards become cards, the secure notes become
+texts and the attachments become binary records. The URLs, the folders, the notes
+and the tags of the entries are saved to the metadata. The KeePass database is
+decrypted with the password of the export.
+With --skip-existing the records already saved on the server and the repeated
+entries of the export are skipped. With --dry-run nothing is saved, and the report
+shows what would be imported.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token := cmd.Flag("token").Value.String()
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			skipExisting, _ := cmd.Flags().GetBool("skip-existing")
+			parser, err := importer.NewParser(
+				cmd.Flag("format").Value.String(),
+				importer.Options{Password: cmd.Flag("export-password").Value.String()},
+			)
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			entries, err := parser.Parse(data)
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			var existing *clientModels.SyncResponse
+			if skipExisting {
+				if existing, err = syncService.Sync(token, models.AllowedCollectionNames); err != nil {
+					fmt.Println(err)
+					return err
+				}
+			}
+			return importItems(importer.Plan(entries, existing), token, dryRun)
+		},
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if _, err := profile.Apply(cmd); err != nil {
+				log.Fatalf("Error while loading the profile: %v", err)
+			}
+			baseURL := cmd.Flag("server").Value.String()
+			transport := cmd.Flag("transport").Value.String()
+			var err error
+			storageService, err = service.NewStorageServiceWithTransport(transport, baseURL)
+			if err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			blobService, err = service.NewBlobServiceWithTransport(transport, baseURL)
+			if err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			syncService, err = service.NewSyncServiceWithTransport(transport, baseURL)
+			if err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			if password := cmd.Flag("master-password").Value.String(); password != "" {
+				vault, err := service.NewVaultWithTransport(transport, baseURL, password)
+				if err != nil {
+					log.Fatalf("Error while creating a service: %v", err)
+				}
+				storageService = service.NewE2EStorageService(storageService, vault)
+				blobService = service.NewE2EBlobService(blobService, vault)
+				syncService = service.NewE2ESyncService(syncService, vault)
+			}
+		},
+	}
+)
+
+func init() {
+	ImportCmd.PersistentFlags().StringP("token", "t", "", "jwt token (default: from the profile)")
+	ImportCmd.MarkPersistentFlagRequired("token")
+	ImportCmd.Flags().StringP("format", "f", "", "format of the export")
+	ImportCmd.MarkFlagRequired("format")
+	ImportCmd.Flags().String("export-password", "", "password of the encrypted export")
+	ImportCmd.Flags().Bool("dry-run", false, "report what would be imported without saving it")
+	ImportCmd.Flags().Bool("skip-existing", false, "skip the records which are already saved")
+}
+
+// importItems saves the new entries and prints the report. An error is
+// returned if any of the entries is not saved.
+func importItems(items []importer.Item, token string, dryRun bool) error {
+	imported, skipped, failed := 0, 0, 0
+	for _, item := range items {
+		status, reason := string(item.Status), item.Reason
+		switch {
+		case item.Status != importer.StatusNew:
+			skipped++
+		case dryRun:
+			imported++
+		default:
+			if err := importItem(item, token); err != nil {
+				status, reason = "failed", err.Error()
+				failed++
+			} else {
+				status = "imported"
+				imported++
+			}
+		}
+		collection := string(item.Collection)
+		if collection == "" {
+			collection = "-"
+		}
+		if reason != "" {
+			reason = ": " + reason
+		}
+		fmt.Printf("%-11s %-11s %v%v\n", status, collection, item.Title, reason)
+	}
+	if dryRun {
+		fmt.Printf("Dry run: %d to import, %d to skip\n", imported, skipped)
+		return nil
+	}
+	fmt.Printf("Imported: %d, skipped: %d, failed: %d\n", imported, skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d entries are not imported", failed)
+	}
+	return nil
+}
+
+// importItem saves the record of the entry. The attachment is uploaded first.
+func importItem(item importer.Item, token string) error {
+	data := item.Data
+	if info, ok := data.(models.BinaryInfo); ok {
+		blob, err := service.UploadBlob(
+			blobService,
+			bytes.NewReader(item.Content),
+			int64(len(item.Content)),
+			token,
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+		info.BlobID = blob.BlobID.Hex()
+		info.Size = strconv.Itoa(len(item.Content))
+		data = info
+	}
+	body, err := importer.Body(item.Entry, data)
+	if err != nil {
+		return err
+	}
+	_, err = storageService.Add(body, item.Collection, token)
+	return err
+}
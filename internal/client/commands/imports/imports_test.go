@@ -0,0 +1,177 @@
+package imports
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/commands/cotesting"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/client/service/mock"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// bitwardenExport has a login, a card and an identity.
+const bitwardenExport = `{
+	"encrypted": false,
+	"items": [
+		{
+			"type": 1, "name": "GitHub",
+			"login": {"username": "john", "password": "secret", "uris": [{"uri": "https://github.com"}]}
+		},
+		{
+			"type": 3, "name": "Visa",
+			"card": {"number": "4111111111111111", "expMonth": "7", "expYear": "2027", "code": "123"}
+		},
+		{"type": 4, "name": "Passport"}
+	]
+}`
+
+// keepassExport has an entry with an attachment.
+const keepassExport = `<KeePassFile>
+	<Meta><Binaries><Binary ID="0">aGVsbG8sIGdv</Binary></Binaries></Meta>
+	<Root><Group><Name>Database</Name><Entry>
+		<String><Key>Title</Key><Value>Server</Value></String>
+		<String><Key>UserName</Key><Value>root</Value></String>
+		<String><Key>Password</Key><Value>toor</Value></String>
+		<Binary><Key>id_rsa.pub</Key><Value Ref="0"/></Binary>
+	</Entry></Group></Root>
+</KeePassFile>`
+
+// writeExport writes the export to a temporary file.
+func writeExport(t *testing.T, name, content string) string {
+	file := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func TestImportCommand(t *testing.T) {
+	t.Setenv(profile.DirEnv, t.TempDir())
+	bitwarden := writeExport(t, "bitwarden.json", bitwardenExport)
+	keepass := writeExport(t, "keepass.xml", keepassExport)
+	blobID := models.NewRandomObjectID()
+	var (
+		added   map[models.CollectionName][]string
+		addErr  error
+		synced  int
+		syncErr error
+	)
+	ImportCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		storage := mock.NewMockStorageService(mockCtrl)
+		storage.EXPECT().
+			Add(gomock.Any(), gomock.Any(), "sometoken").
+			DoAndReturn(func(body string, c models.CollectionName, _ string) (string, error) {
+				if addErr != nil {
+					return "", addErr
+				}
+				added[c] = append(added[c], body)
+				return "ok", nil
+			}).
+			AnyTimes()
+		storageService = storage
+		sync := mock.NewMockSyncService(mockCtrl)
+		sync.EXPECT().
+			Sync("sometoken", models.AllowedCollectionNames).
+			DoAndReturn(func(string, []models.CollectionName) (*clientModels.SyncResponse, error) {
+				synced++
+				return &clientModels.SyncResponse{
+					Credential: []models.CredentialRecord{{
+						Data:     models.CredentialInfo{Login: "john", Password: "secret"},
+						Metadata: models.Metadata{"url": "https://github.com"},
+					}},
+				}, syncErr
+			}).
+			AnyTimes()
+		syncService = sync
+		blobs := mock.NewMockBlobService(mockCtrl)
+		blobs.EXPECT().ChunkSize().Return(4).AnyTimes()
+		blobs.EXPECT().
+			Create(int64(9), "sometoken").
+			Return(&models.Blob{BlobID: blobID, Size: 9}, nil).
+			AnyTimes()
+		blobs.EXPECT().
+			Append(blobID, gomock.Any(), gomock.Any(), "sometoken").
+			DoAndReturn(func(_ models.ObjectID, offset int64, chunk []byte, _ string) (*models.Blob, error) {
+				return &models.Blob{BlobID: blobID, Size: 9, Received: offset + int64(len(chunk))}, nil
+			}).
+			AnyTimes()
+		blobService = blobs
+	}
+	rootCmd := ImportCmd
+	run := func(args ...string) error {
+		return cotesting.ExecuteCommandC(
+			rootCmd,
+			append([]string{"--token=sometoken", "--dry-run=false", "--skip-existing=false"}, args...)...,
+		)
+	}
+	reset := func() {
+		added = make(map[models.CollectionName][]string)
+		addErr, syncErr, synced = nil, nil, 0
+	}
+	t.Run("ok", func(t *testing.T) {
+		reset()
+		err := run(bitwarden, "--format=bitwarden-json")
+		require.NoError(t, err)
+		require.Len(t, added[models.CredentialsCollection], 1)
+		assert.Contains(t, added[models.CredentialsCollection][0], `"Login":"john"`)
+		assert.Contains(t, added[models.CredentialsCollection][0], `"url":"https://github.com"`)
+		require.Len(t, added[models.CardCollection], 1)
+		assert.Contains(t, added[models.CardCollection][0], `"ExpirationDate":"07/27"`)
+		assert.Zero(t, synced)
+	})
+	t.Run("skip_existing", func(t *testing.T) {
+		reset()
+		err := run(bitwarden, "--format=bitwarden-json", "--skip-existing")
+		require.NoError(t, err)
+		assert.Empty(t, added[models.CredentialsCollection])
+		assert.Len(t, added[models.CardCollection], 1)
+		assert.Equal(t, 1, synced)
+	})
+	t.Run("dry_run", func(t *testing.T) {
+		reset()
+		err := run(bitwarden, "--format=bitwarden-json", "--dry-run")
+		require.NoError(t, err)
+		assert.Empty(t, added)
+	})
+	t.Run("attachment", func(t *testing.T) {
+		reset()
+		err := run(keepass, "--format=keepass-xml")
+		require.NoError(t, err)
+		require.Len(t, added[models.BinaryCollection], 1)
+		assert.Contains(t, added[models.BinaryCollection][0], `"BlobID":"`+blobID.Hex()+`"`)
+		assert.Contains(t, added[models.BinaryCollection][0], `"FileName":"id_rsa.pub"`)
+		assert.Len(t, added[models.CredentialsCollection], 1)
+	})
+	t.Run("add_error", func(t *testing.T) {
+		reset()
+		addErr = errors.New("server error")
+		err := run(bitwarden, "--format=bitwarden-json")
+		assert.Error(t, err)
+	})
+	t.Run("sync_error", func(t *testing.T) {
+		reset()
+		syncErr = errors.New("server error")
+		err := run(bitwarden, "--format=bitwarden-json", "--skip-existing")
+		assert.Error(t, err)
+	})
+	t.Run("unknown_format", func(t *testing.T) {
+		err := run(bitwarden, "--format=lastpass")
+		assert.Error(t, err)
+	})
+	t.Run("no_file", func(t *testing.T) {
+		err := run(filepath.Join(t.TempDir(), "nonexistent.json"), "--format=bitwarden-json")
+		assert.Error(t, err)
+	})
+	t.Run("bad_export", func(t *testing.T) {
+		err := run(keepass, "--format=bitwarden-json")
+		assert.Error(t, err)
+	})
+}
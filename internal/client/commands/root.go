@@ -7,9 +7,15 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/blokhinnv/gophkeeper/internal/client/commands/auth"
+	"github.com/blokhinnv/gophkeeper/internal/client/commands/backup"
 	"github.com/blokhinnv/gophkeeper/internal/client/commands/crud"
+	"github.com/blokhinnv/gophkeeper/internal/client/commands/imports"
+	"github.com/blokhinnv/gophkeeper/internal/client/commands/search"
 	"github.com/blokhinnv/gophkeeper/internal/client/commands/shell"
+	"github.com/blokhinnv/gophkeeper/internal/client/commands/status"
 	"github.com/blokhinnv/gophkeeper/internal/client/commands/sync"
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -28,6 +34,22 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.AddCommand(auth.AuthCmd, crud.CRUDCmd, shell.ShellCmd, sync.SyncCmd)
+	rootCmd.AddCommand(
+		auth.AuthCmd,
+		crud.CRUDCmd,
+		backup.ExportCmd,
+		imports.ImportCmd,
+		backup.RestoreCmd,
+		search.SearchCmd,
+		shell.ShellCmd,
+		status.StatusCmd,
+		sync.SyncCmd,
+	)
 	rootCmd.PersistentFlags().StringP("server", "s", "https://localhost:8080", "server addr")
+	rootCmd.PersistentFlags().
+		String("transport", service.TransportHTTP, "transport to talk to the server: http or grpc")
+	rootCmd.PersistentFlags().
+		String("master-password", "", "master password which enables the end-to-end encryption")
+	rootCmd.PersistentFlags().
+		String("profile", profile.DefaultName, "profile which keeps the server, the token and the local store path")
 }
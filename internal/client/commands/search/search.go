@@ -0,0 +1,64 @@
+// Package search provides implementation of the search CLI-command.
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
+)
+
+var (
+	// storageService is a storage service used for a command implementation.
+	storageService service.StorageService
+	// SearchCmd represents the search command
+	SearchCmd = &cobra.Command{
+		Use:   "search <query>",
+		Short: "search command",
+		Long: `The search command finds the records of all the collections on the server
+whose metadata values, credential logins, file names or last four digits of the
+card numbers have all the words of the query. A word matches a word of the record
+it is equal to or is a prefix of, e.g. "git" matches "github.com". Secrets and
+the end-to-end encrypted records are not searched. The content of the binary
+records is omitted. The result is returned as a JSON string.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token := cmd.Flag("token").Value.String()
+			results, err := storageService.Search(strings.Join(args, " "), token)
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			resJSON, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			fmt.Printf("Result: %s\n", resJSON)
+			return nil
+		},
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if _, err := profile.Apply(cmd); err != nil {
+				log.Fatalf("Error while loading the profile: %v", err)
+			}
+			var err error
+			storageService, err = service.NewStorageServiceWithTransport(
+				cmd.Flag("transport").Value.String(),
+				cmd.Flag("server").Value.String(),
+			)
+			if err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+		},
+	}
+)
+
+func init() {
+	SearchCmd.PersistentFlags().StringP("token", "t", "", "jwt token (default: from the profile)")
+	SearchCmd.MarkPersistentFlagRequired("token")
+}
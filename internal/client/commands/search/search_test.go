@@ -0,0 +1,55 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/commands/cotesting"
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/client/service/mock"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestSearchCommand(t *testing.T) {
+	t.Setenv(profile.DirEnv, t.TempDir())
+	SearchCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		storage := mock.NewMockStorageService(mockCtrl)
+		storageService = storage
+		storage.EXPECT().
+			Search("john github", "sometoken").
+			AnyTimes().
+			Return([]models.SearchResult{{
+				Collection: models.CredentialsCollection,
+				Record: models.UntypedRecord{
+					UntypedRecordContent: models.UntypedRecordContent{
+						Data: map[string]any{"Login": "john@github.com", "Password": "secret"},
+					},
+					RecordID: models.NewRandomObjectID(),
+				},
+			}}, nil)
+		storage.EXPECT().
+			Search("nothing", "sometoken").
+			AnyTimes().
+			Return(nil, fmt.Errorf("unable to search"))
+	}
+
+	rootCmd := SearchCmd
+	t.Run("ok", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "john", "github", "--token=sometoken")
+		assert.NoError(t, err)
+	})
+	t.Run("no_query", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "--token=sometoken")
+		assert.Error(t, err)
+	})
+	t.Run("service_err", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "nothing", "--token=sometoken")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,205 @@
+package shell
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/otp"
+)
+
+// formField describes an input of the record form.
+type formField struct {
+	key    string
+	label  string
+	secret bool
+}
+
+// metadataField is an input for metadata available for every collection.
+var metadataField = formField{key: "metadata", label: "Metadata (key=value; ...)"}
+
+// collectionFields contains the inputs of the record form for every collection.
+var collectionFields = map[models.CollectionName][]formField{
+	models.TextCollection: {
+		{key: "text", label: "Text"},
+	},
+	models.CredentialsCollection: {
+		{key: "login", label: "Login"},
+		{key: "password", label: "Password", secret: true},
+	},
+	models.CardCollection: {
+		{key: "number", label: "Card number", secret: true},
+		{key: "cvv", label: "CVV", secret: true},
+		{key: "expiration", label: "Expiration date"},
+	},
+	models.BinaryCollection: {
+		{key: "file", label: "File path"},
+	},
+	models.OTPCollection: {
+		{key: "uri", label: "otpauth URI (overrides the fields below)"},
+		{key: "type", label: "Type (totp or hotp)"},
+		{key: "secret", label: "Secret", secret: true},
+		{key: "issuer", label: "Issuer"},
+		{key: "account", label: "Account"},
+		{key: "algorithm", label: "Algorithm (SHA1, SHA256 or SHA512)"},
+		{key: "digits", label: "Digits"},
+		{key: "period", label: "Period"},
+		{key: "counter", label: "Counter"},
+	},
+}
+
+// newInput creates a text input with a static cursor.
+func newInput(secret bool) textinput.Model {
+	input := textinput.New()
+	input.Prompt = ""
+	input.Cursor.SetMode(cursor.CursorStatic)
+	if secret {
+		input.EchoMode = textinput.EchoPassword
+		input.EchoCharacter = '•'
+	}
+	return input
+}
+
+// recordForm is a form to add a new record or to edit an existing one.
+type recordForm struct {
+	collection models.CollectionName
+	recordID   models.ObjectID
+	edit       bool
+	fields     []formField
+	inputs     []textinput.Model
+	focused    int
+	err        error
+	// version is the version of the edited record the update is expected to apply to.
+	version int64
+}
+
+// newRecordForm creates a form for the collection. If e is not nil,
+// the form edits the record and its inputs contain the current values.
+func newRecordForm(collection models.CollectionName, e *entry) *recordForm {
+	f := &recordForm{collection: collection}
+	f.fields = append(f.fields, collectionFields[collection]...)
+	f.fields = append(f.fields, metadataField)
+	for _, ff := range f.fields {
+		input := newInput(ff.secret)
+		if e != nil {
+			input.SetValue(e.values[ff.key])
+		}
+		f.inputs = append(f.inputs, input)
+	}
+	if e != nil {
+		f.recordID = e.id
+		f.version = e.version
+		f.edit = true
+	}
+	f.inputs[0].Focus()
+	return f
+}
+
+// focus moves the focus to the i-th input.
+func (f *recordForm) focus(i int) {
+	f.inputs[f.focused].Blur()
+	f.focused = (i + len(f.inputs)) % len(f.inputs)
+	f.inputs[f.focused].Focus()
+}
+
+// next moves the focus to the next input.
+func (f *recordForm) next() {
+	f.focus(f.focused + 1)
+}
+
+// prev moves the focus to the previous input.
+func (f *recordForm) prev() {
+	f.focus(f.focused - 1)
+}
+
+// last checks if the last input is focused.
+func (f *recordForm) last() bool {
+	return f.focused == len(f.inputs)-1
+}
+
+// values returns the entered values by the field keys.
+func (f *recordForm) values() map[string]string {
+	res := make(map[string]string, len(f.fields))
+	for i, ff := range f.fields {
+		res[ff.key] = f.inputs[i].Value()
+	}
+	return res
+}
+
+// data builds the record data from the entered values.
+func (f *recordForm) data(v map[string]string) (any, error) {
+	switch f.collection {
+	case models.CredentialsCollection:
+		return models.CredentialInfo{Login: v["login"], Password: v["password"]}, nil
+	case models.CardCollection:
+		return models.CardInfo{
+			CardNumber:     v["number"],
+			CVV:            v["cvv"],
+			ExpirationDate: v["expiration"],
+		}, nil
+	case models.BinaryCollection:
+		bytes, err := os.ReadFile(v["file"])
+		if err != nil {
+			return nil, err
+		}
+		return models.BinaryInfo{
+			FileName: v["file"],
+			Content:  base64.StdEncoding.EncodeToString(bytes),
+		}, nil
+	case models.OTPCollection:
+		if v["uri"] != "" {
+			key, err := otp.ParseURI(v["uri"])
+			if err != nil {
+				return nil, err
+			}
+			return models.NewOTPInfo(key), nil
+		}
+		return models.OTPInfo{
+			Type:      v["type"],
+			Secret:    v["secret"],
+			Issuer:    v["issuer"],
+			Account:   v["account"],
+			Algorithm: v["algorithm"],
+			Digits:    v["digits"],
+			Period:    v["period"],
+			Counter:   v["counter"],
+		}, nil
+	default:
+		return v["text"], nil
+	}
+}
+
+// body returns the request body built from the entered values.
+func (f *recordForm) body() (string, error) {
+	v := f.values()
+	data, err := f.data(v)
+	if err != nil {
+		return "", err
+	}
+	md, err := parseMetadata(v["metadata"])
+	if err != nil {
+		return "", err
+	}
+	body := struct {
+		models.UntypedRecord
+		ExpectedVersion int64 `json:"expected_version,omitempty"`
+	}{
+		UntypedRecord: models.UntypedRecord{
+			UntypedRecordContent: models.UntypedRecordContent{
+				Data:     data,
+				Metadata: md,
+			},
+			RecordID: f.recordID,
+		},
+		ExpectedVersion: f.version,
+	}
+	bodyEncoded, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	return string(bodyEncoded), nil
+}
@@ -0,0 +1,804 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// errEmptyCredentials is shown when the username or the password is not entered.
+var errEmptyCredentials = errors.New("username and password are required")
+
+// screen is a kind of the view shown to the user.
+type screen int
+
+// loginScreen, mainScreen, formScreen, confirmScreen and conflictScreen are
+// constants representing the available screens.
+const (
+	loginScreen screen = iota
+	mainScreen
+	formScreen
+	confirmScreen
+	conflictScreen
+)
+
+// pane is a part of the main screen which receives the navigation keys.
+type pane int
+
+// sidebarPane and listPane are constants representing the focusable panes.
+const (
+	sidebarPane pane = iota
+	listPane
+)
+
+// authMsg is sent when the authentication is finished.
+type authMsg struct {
+	username string
+	tokens   *models.TokenPair
+	err      error
+}
+
+// renewMsg is sent when it is time to refresh the tokens of the session.
+type renewMsg struct{}
+
+// tokensMsg is sent when the tokens of the session are refreshed.
+type tokensMsg struct {
+	tokens *models.TokenPair
+	err    error
+}
+
+// renewMargin is how long before the expiration the access token is refreshed.
+const renewMargin = time.Minute
+
+// eventsMsg is sent when the stream of the change events is opened.
+type eventsMsg struct {
+	events <-chan models.ChangeEvent
+	err    error
+}
+
+// changeMsg is sent when the server pushes a change of a record.
+// If ok is false, the stream of the change events is closed.
+type changeMsg struct {
+	event models.ChangeEvent
+	ok    bool
+}
+
+// reconnectMsg is sent when it is time to reopen the stream of the change events.
+type reconnectMsg struct{}
+
+// syncMsg is sent when the data is retrieved from the server
+// or from the local store while the server is unavailable.
+type syncMsg struct {
+	data *clientModels.SyncResponse
+	err  error
+	// offline is set if the data is loaded from the local store.
+	offline bool
+	// pending is a number of the writes waiting for the server.
+	pending int
+}
+
+// recordMsg is sent when the changed record is retrieved from the server.
+type recordMsg struct {
+	event models.ChangeEvent
+	data  *clientModels.SyncResponse
+	err   error
+}
+
+// storageMsg is sent when a record is added, updated or deleted.
+type storageMsg struct {
+	msg string
+	err error
+	// conflict is set if the update is rejected since the record was modified by another client.
+	conflict *updateConflict
+}
+
+// updateConflict is an update rejected since the record was modified by another client.
+type updateConflict struct {
+	body       string
+	collection models.CollectionName
+	err        *clientErr.ConflictError
+}
+
+// tickMsg is sent periodically to redraw the time-dependent data.
+type tickMsg time.Time
+
+// model is a state of the terminal user interface.
+type model struct {
+	authService    service.AuthService
+	syncService    service.SyncService
+	storageService service.StorageService
+	// store serves the data while the server is unavailable; nil disables the offline mode.
+	store service.LocalStore
+	// device is registered by the login and its key refreshes the session;
+	// nil logs in without a device.
+	device *service.Device
+
+	// server is an address of the server shown in the status bar.
+	server string
+	// ctx cancels the stream of the change events when the program is finished.
+	ctx context.Context
+	// events receives the changes pushed by the server; nil if the stream is closed.
+	events <-chan models.ChangeEvent
+	// version is a version of the last change event received.
+	version int64
+	// refresh is an interval of redrawing the time-dependent data
+	// such as otp codes; zero disables redrawing.
+	refresh time.Duration
+	// reconnect is a delay before reopening the closed stream of the change
+	// events; zero disables reconnecting.
+	reconnect time.Duration
+	now       func() time.Time
+
+	screen        screen
+	width, height int
+
+	loginInputs  []textinput.Model
+	loginFocused int
+	// mfaToken is the challenge token of the login while the one-time code is asked for.
+	mfaToken     string
+	codeInput    textinput.Model
+	username     string
+	token        string
+	refreshToken string
+	// tokenExpiry is the expiration time of the access token; zero disables refreshing.
+	tokenExpiry time.Time
+
+	data       *clientModels.SyncResponse
+	collection int
+	cursor     int
+	pane       pane
+	search     textinput.Model
+	searching  bool
+	reveal     bool
+	form       *recordForm
+	conflict   *updateConflict
+	// find is the query of the search of all the collections on the server.
+	find    textinput.Model
+	finding bool
+	// found is set while the records found by the server are shown.
+	found *searchResults
+
+	status    string
+	statusErr bool
+	syncState string
+}
+
+// newModel creates the initial state of the user interface.
+func newModel(
+	authService service.AuthService,
+	syncService service.SyncService,
+	storageService service.StorageService,
+	server string,
+	ctx context.Context,
+) model {
+	username := newInput(false)
+	username.Placeholder = "username"
+	username.Focus()
+	password := newInput(true)
+	password.Placeholder = "password"
+	code := newInput(false)
+	code.Placeholder = "one-time or recovery code"
+	search := newInput(false)
+	search.Prompt = "/"
+	find := newInput(false)
+	find.Prompt = "?"
+	return model{
+		authService:    authService,
+		syncService:    syncService,
+		storageService: storageService,
+		server:         server,
+		ctx:            ctx,
+		refresh:        time.Second,
+		reconnect:      5 * time.Second,
+		now:            time.Now,
+		width:          100,
+		height:         30,
+		loginInputs:    []textinput.Model{username, password},
+		codeInput:      code,
+		search:         search,
+		find:           find,
+		syncState:      "not synced",
+	}
+}
+
+// Init returns the initial command of the program.
+func (m model) Init() tea.Cmd {
+	return nil
+}
+
+// collectionName returns the selected collection.
+func (m model) collectionName() models.CollectionName {
+	return models.AllowedCollectionNames[m.collection]
+}
+
+// visibleEntries returns the entries of the selected collection matching the search query.
+func (m model) visibleEntries() []entry {
+	return filterEntries(entries(m.data, m.collectionName()), m.search.Value())
+}
+
+// selected returns the entry under the cursor
+// or the found record while the records found by the server are shown.
+func (m model) selected() (entry, bool) {
+	if m.found != nil {
+		f, ok := m.selectedFound()
+		return f.entry, ok
+	}
+	es := m.visibleEntries()
+	if m.cursor < 0 || m.cursor >= len(es) {
+		return entry{}, false
+	}
+	return es[m.cursor], true
+}
+
+// clampCursor keeps the cursor inside the list.
+func (m *model) clampCursor() {
+	n := len(m.visibleEntries())
+	if m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// setStatus shows the message in the status bar.
+func (m *model) setStatus(msg string, err error) {
+	m.status = msg
+	m.statusErr = err != nil
+	if err != nil {
+		m.status = fmt.Sprintf("%v: %v", msg, err)
+	}
+}
+
+// authCmd authenticates the user. If register is true, the user is registered first.
+func (m model) authCmd(username, password string, register bool) tea.Cmd {
+	return func() tea.Msg {
+		if register {
+			if err := m.authService.Register(username, password); err != nil {
+				return authMsg{err: fmt.Errorf("unable to register: %w", err)}
+			}
+		}
+		tokens, err := m.authService.Auth(username, password, m.device)
+		if err != nil {
+			return authMsg{err: fmt.Errorf("unable to login: %w", err)}
+		}
+		return authMsg{username: username, tokens: tokens}
+	}
+}
+
+// mfaCmd exchanges the challenge token of the login and the code of the second factor
+// for the tokens of the session.
+func (m model) mfaCmd(code string) tea.Cmd {
+	username, mfaToken := m.username, m.mfaToken
+	return func() tea.Msg {
+		tokens, err := m.authService.AuthMFA(mfaToken, code, m.device)
+		if err != nil {
+			return authMsg{username: username, err: fmt.Errorf("unable to login: %w", err)}
+		}
+		return authMsg{username: username, tokens: tokens}
+	}
+}
+
+// setTokens keeps the tokens of the session.
+func (m *model) setTokens(tokens *models.TokenPair) {
+	m.token, m.refreshToken, m.tokenExpiry = tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresAt
+}
+
+// renewCmd schedules refreshing of the tokens shortly before the access token expires.
+func (m model) renewCmd() tea.Cmd {
+	if m.tokenExpiry.IsZero() || m.refreshToken == "" {
+		return nil
+	}
+	delay := m.tokenExpiry.Sub(m.now()) - renewMargin
+	if delay < 0 {
+		delay = 0
+	}
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return renewMsg{}
+	})
+}
+
+// refreshCmd exchanges the refresh token for the new tokens of the session.
+func (m model) refreshCmd() tea.Cmd {
+	refreshToken := m.refreshToken
+	return func() tea.Msg {
+		tokens, err := m.authService.Refresh(refreshToken, m.device)
+		return tokensMsg{tokens: tokens, err: err}
+	}
+}
+
+// eventsCmd opens the stream of the change events.
+func (m model) eventsCmd() tea.Cmd {
+	return func() tea.Msg {
+		events, err := m.syncService.Events(m.ctx, m.token)
+		return eventsMsg{events: events, err: err}
+	}
+}
+
+// waitForEvent waits for the next change event.
+func (m model) waitForEvent() tea.Cmd {
+	if m.events == nil {
+		return nil
+	}
+	events := m.events
+	return func() tea.Msg {
+		event, ok := <-events
+		return changeMsg{event: event, ok: ok}
+	}
+}
+
+// reconnectCmd schedules reopening of the stream of the change events.
+func (m model) reconnectCmd() tea.Cmd {
+	if m.reconnect == 0 {
+		return nil
+	}
+	return tea.Tick(m.reconnect, func(time.Time) tea.Msg {
+		return reconnectMsg{}
+	})
+}
+
+// syncCmd retrieves the data of all the collections from the server.
+// The data is loaded from the local store if the server is unavailable.
+func (m model) syncCmd() tea.Cmd {
+	return func() tea.Msg {
+		data, err := m.syncService.Sync(m.token, models.AllowedCollectionNames)
+		if !errors.Is(err, clientErr.ErrServerUnavailable) || m.store == nil {
+			return syncMsg{data: data, err: err}
+		}
+		if data, err = m.store.Load(); err != nil {
+			return syncMsg{err: err}
+		}
+		ops, err := m.store.Pending()
+		return syncMsg{data: data, err: err, offline: true, pending: len(ops)}
+	}
+}
+
+// recordCmd retrieves the record mentioned in the change event.
+func (m model) recordCmd(event models.ChangeEvent) tea.Cmd {
+	return func() tea.Msg {
+		data, err := m.syncService.SyncRecord(m.token, event.Collection, event.RecordID)
+		return recordMsg{event: event, data: data, err: err}
+	}
+}
+
+// syncedState returns the sync state after the data is updated.
+func (m model) syncedState(push bool) string {
+	state := fmt.Sprintf("synced at %v", m.now().Format("15:04:05"))
+	if push {
+		state += " (push)"
+	}
+	return state
+}
+
+// tickCmd schedules the next redrawing of the time-dependent data.
+func (m model) tickCmd() tea.Cmd {
+	if m.refresh == 0 {
+		return nil
+	}
+	return tea.Tick(m.refresh, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// storageCmd sends the record to the storage.
+func (m model) storageCmd(
+	op func(string, models.CollectionName, string) (string, error),
+	body string,
+	collection models.CollectionName,
+) tea.Cmd {
+	return func() tea.Msg {
+		msg, err := op(body, collection, m.token)
+		return newStorageMsg(msg, err, body, collection)
+	}
+}
+
+// newStorageMsg creates the message about the result of the request with the body.
+func newStorageMsg(msg string, err error, body string, collection models.CollectionName) storageMsg {
+	var conflict *clientErr.ConflictError
+	if errors.As(err, &conflict) {
+		return storageMsg{
+			err:      err,
+			conflict: &updateConflict{body: body, collection: collection, err: conflict},
+		}
+	}
+	return storageMsg{msg: msg, err: err}
+}
+
+// resolveCmd resolves the conflict of the rejected update and sends the resolved update.
+func (m model) resolveCmd(conflict *updateConflict, resolution service.ConflictResolution) tea.Cmd {
+	return func() tea.Msg {
+		msg, err := service.ResolveConflict(
+			m.storageService,
+			conflict.body,
+			conflict.collection,
+			m.token,
+			conflict.err,
+			resolution,
+		)
+		return newStorageMsg(msg, err, conflict.body, conflict.collection)
+	}
+}
+
+// Update handles the messages and updates the state.
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+	case authMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = msg.err.Error(), true
+			return m, nil
+		}
+		m.username = msg.username
+		if msg.tokens.MFARequired {
+			m.mfaToken = msg.tokens.MFAToken
+			m.loginInputs[m.loginFocused].Blur()
+			m.codeInput.Reset()
+			m.codeInput.Focus()
+			m.setStatus("enter the one-time code", nil)
+			return m, nil
+		}
+		m.mfaToken = ""
+		m.setTokens(msg.tokens)
+		m.screen = mainScreen
+		m.setStatus(fmt.Sprintf("logged in as %v", m.username), nil)
+		m.syncState = "syncing..."
+		return m, tea.Batch(m.syncCmd(), m.eventsCmd(), m.tickCmd(), m.renewCmd())
+	case renewMsg:
+		return m, m.refreshCmd()
+	case tokensMsg:
+		if msg.err != nil {
+			m.setStatus("unable to refresh the session, log in again", msg.err)
+			return m, nil
+		}
+		m.setTokens(msg.tokens)
+		return m, m.renewCmd()
+	case eventsMsg:
+		if msg.err != nil {
+			m.setStatus("live updates are unavailable", msg.err)
+			return m, m.reconnectCmd()
+		}
+		m.events, m.version = msg.events, 0
+		return m, m.waitForEvent()
+	case changeMsg:
+		return m.updateChange(msg)
+	case reconnectMsg:
+		// the changes made while the stream was closed are synced as well
+		return m, tea.Batch(m.syncCmd(), m.eventsCmd())
+	case syncMsg:
+		if msg.err != nil {
+			m.syncState = fmt.Sprintf("sync failed: %v", msg.err)
+			return m, nil
+		}
+		m.data = msg.data
+		m.clampCursor()
+		m.syncState = m.syncedState(false)
+		if msg.offline {
+			m.syncState = fmt.Sprintf("offline, %d pending", msg.pending)
+		}
+		return m, nil
+	case recordMsg:
+		if errors.Is(msg.err, srvErrors.ErrRecordNotFound) {
+			// the record has been deleted after the change
+			msg.event.Op, msg.err = models.OpDelete, nil
+		}
+		if msg.err != nil {
+			m.syncState = fmt.Sprintf("sync failed: %v", msg.err)
+			return m, nil
+		}
+		m.applyChange(msg.event, msg.data)
+		return m, nil
+	case storageMsg:
+		if msg.conflict != nil {
+			m.conflict = msg.conflict
+			m.screen = conflictScreen
+			m.setStatus("update rejected", msg.err)
+			return m, nil
+		}
+		if msg.err != nil {
+			m.setStatus("request failed", msg.err)
+			return m, nil
+		}
+		m.setStatus(msg.msg, nil)
+		if m.events != nil {
+			// the change is pushed back by the server
+			return m, nil
+		}
+		m.syncState = "syncing..."
+		return m, m.syncCmd()
+	case foundMsg:
+		return m.updateFound(msg)
+	case tickMsg:
+		return m, m.tickCmd()
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		switch m.screen {
+		case loginScreen:
+			return m.updateLogin(msg)
+		case formScreen:
+			return m.updateForm(msg)
+		case confirmScreen:
+			return m.updateConfirm(msg)
+		case conflictScreen:
+			return m.updateConflict(msg)
+		default:
+			return m.updateMain(msg)
+		}
+	}
+	return m, nil
+}
+
+// applyChange updates the record mentioned in the change event with the data retrieved.
+func (m *model) applyChange(event models.ChangeEvent, data *clientModels.SyncResponse) {
+	if m.data == nil {
+		m.data = &clientModels.SyncResponse{}
+	}
+	if event.Op == models.OpDelete {
+		m.data.Remove(event.Collection, event.RecordID)
+	} else {
+		m.data.Merge(data)
+	}
+	m.clampCursor()
+	m.syncState = m.syncedState(true)
+}
+
+// updateChange applies the change pushed by the server. Only the changed record
+// is retrieved unless some events have been missed.
+func (m model) updateChange(msg changeMsg) (tea.Model, tea.Cmd) {
+	if !msg.ok {
+		m.events = nil
+		m.syncState = "live updates lost"
+		if m.reconnect != 0 {
+			m.syncState += ", reconnecting..."
+		}
+		return m, m.reconnectCmd()
+	}
+	missed := m.version != 0 && msg.event.Version != m.version+1
+	m.version = msg.event.Version
+	if missed {
+		m.syncState = "changes missed, syncing..."
+		return m, tea.Batch(m.syncCmd(), m.waitForEvent())
+	}
+	if msg.event.Op == models.OpDelete {
+		m.applyChange(msg.event, nil)
+		return m, m.waitForEvent()
+	}
+	m.syncState = "change pushed, syncing..."
+	return m, tea.Batch(m.recordCmd(msg.event), m.waitForEvent())
+}
+
+// updateLogin handles the keys on the login screen.
+func (m model) updateLogin(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.mfaToken != "" {
+		return m.updateCode(msg)
+	}
+	switch msg.String() {
+	case "tab", "shift+tab", "up", "down":
+		m.loginInputs[m.loginFocused].Blur()
+		m.loginFocused = (m.loginFocused + 1) % len(m.loginInputs)
+		m.loginInputs[m.loginFocused].Focus()
+		return m, nil
+	case "enter", "ctrl+r":
+		if msg.String() == "enter" && m.loginFocused == 0 {
+			m.loginInputs[0].Blur()
+			m.loginFocused = 1
+			m.loginInputs[1].Focus()
+			return m, nil
+		}
+		username, password := m.loginInputs[0].Value(), m.loginInputs[1].Value()
+		if username == "" || password == "" {
+			m.setStatus("unable to authenticate", errEmptyCredentials)
+			return m, nil
+		}
+		m.setStatus("authenticating...", nil)
+		return m, m.authCmd(username, password, msg.String() == "ctrl+r")
+	}
+	var cmd tea.Cmd
+	m.loginInputs[m.loginFocused], cmd = m.loginInputs[m.loginFocused].Update(msg)
+	return m, cmd
+}
+
+// updateCode handles the keys while the code of the second factor is asked for.
+func (m model) updateCode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mfaToken = ""
+		m.codeInput.Blur()
+		m.loginInputs[m.loginFocused].Focus()
+		m.setStatus("login canceled", nil)
+		return m, nil
+	case "enter":
+		code := strings.TrimSpace(m.codeInput.Value())
+		if code == "" {
+			return m, nil
+		}
+		m.setStatus("checking the code...", nil)
+		return m, m.mfaCmd(code)
+	}
+	var cmd tea.Cmd
+	m.codeInput, cmd = m.codeInput.Update(msg)
+	return m, cmd
+}
+
+// updateMain handles the keys on the main screen.
+func (m model) updateMain(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.finding {
+		return m.updateFind(msg)
+	}
+	if m.found != nil {
+		return m.updateResults(msg)
+	}
+	if m.searching {
+		switch msg.String() {
+		case "enter":
+			m.searching = false
+			m.search.Blur()
+			m.pane = listPane
+			return m, nil
+		case "esc":
+			m.searching = false
+			m.search.Blur()
+			m.search.SetValue("")
+			m.clampCursor()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.search, cmd = m.search.Update(msg)
+		m.cursor = 0
+		return m, cmd
+	}
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "tab":
+		if m.pane == sidebarPane {
+			m.pane = listPane
+		} else {
+			m.pane = sidebarPane
+		}
+	case "left", "h":
+		m.pane = sidebarPane
+	case "right", "l", "enter":
+		m.pane = listPane
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "/":
+		m.searching = true
+		m.search.Focus()
+	case "f":
+		m.finding = true
+		m.find.SetValue("")
+		m.find.Focus()
+	case "esc":
+		m.search.SetValue("")
+		m.clampCursor()
+	case "s":
+		m.reveal = !m.reveal
+	case "r":
+		m.syncState = "syncing..."
+		return m, m.syncCmd()
+	case "a":
+		m.form = newRecordForm(m.collectionName(), nil)
+		m.screen = formScreen
+	case "e":
+		if e, ok := m.selected(); ok {
+			m.form = newRecordForm(m.collectionName(), &e)
+			m.screen = formScreen
+		}
+	case "d":
+		if _, ok := m.selected(); ok {
+			m.screen = confirmScreen
+		}
+	}
+	return m, nil
+}
+
+// move moves the cursor of the focused pane.
+func (m *model) move(delta int) {
+	if m.pane == sidebarPane {
+		n := len(models.AllowedCollectionNames)
+		m.collection = (m.collection + delta + n) % n
+		m.cursor = 0
+		return
+	}
+	m.cursor += delta
+	m.clampCursor()
+}
+
+// updateForm handles the keys on the record form.
+func (m model) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.form = nil
+		m.screen = mainScreen
+		return m, nil
+	case "tab", "down":
+		m.form.next()
+		return m, nil
+	case "shift+tab", "up":
+		m.form.prev()
+		return m, nil
+	case "enter", "ctrl+s":
+		if msg.String() == "enter" && !m.form.last() {
+			m.form.next()
+			return m, nil
+		}
+		body, err := m.form.body()
+		if err != nil {
+			m.form.err = err
+			return m, nil
+		}
+		op := m.storageService.Add
+		if m.form.edit {
+			op = m.storageService.Update
+		}
+		cmd := m.storageCmd(op, body, m.form.collection)
+		m.form = nil
+		m.screen = mainScreen
+		m.setStatus("saving...", nil)
+		return m, cmd
+	}
+	var cmd tea.Cmd
+	i := m.form.focused
+	m.form.inputs[i], cmd = m.form.inputs[i].Update(msg)
+	return m, cmd
+}
+
+// updateConfirm handles the keys on the delete confirmation.
+func (m model) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		m.screen = mainScreen
+		e, ok := m.selected()
+		if !ok {
+			return m, nil
+		}
+		body := fmt.Sprintf(`{"record_id": "%v"}`, e.id.Hex())
+		m.setStatus("deleting...", nil)
+		return m, m.storageCmd(m.storageService.Delete, body, m.collectionName())
+	case "n", "N", "esc":
+		m.screen = mainScreen
+	}
+	return m, nil
+}
+
+// updateConflict handles the keys on the conflict resolution screen.
+func (m model) updateConflict(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	resolutions := map[string]service.ConflictResolution{
+		"m": service.KeepMine,
+		"t": service.KeepTheirs,
+		"b": service.MergeMetadata,
+	}
+	if resolution, ok := resolutions[msg.String()]; ok {
+		conflict := m.conflict
+		m.conflict = nil
+		m.screen = mainScreen
+		m.setStatus("resolving the conflict...", nil)
+		return m, m.resolveCmd(conflict, resolution)
+	}
+	switch msg.String() {
+	case "esc":
+		m.conflict = nil
+		m.screen = mainScreen
+		m.setStatus("update canceled", nil)
+	case "s":
+		m.reveal = !m.reveal
+	}
+	return m, nil
+}
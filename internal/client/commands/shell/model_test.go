@@ -0,0 +1,643 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	"github.com/blokhinnv/gophkeeper/internal/client/service/mock"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+type testModel struct {
+	t       *testing.T
+	m       model
+	auth    *mock.MockAuthService
+	sync    *mock.MockSyncService
+	storage *mock.MockStorageService
+	events  chan models.ChangeEvent
+}
+
+func newTestModel(t *testing.T) *testModel {
+	mockCtrl := gomock.NewController(t)
+	tm := &testModel{
+		t:       t,
+		auth:    mock.NewMockAuthService(mockCtrl),
+		sync:    mock.NewMockSyncService(mockCtrl),
+		storage: mock.NewMockStorageService(mockCtrl),
+		events:  make(chan models.ChangeEvent, 10),
+	}
+	tm.m = newModel(tm.auth, tm.sync, tm.storage, "localhost:8080", context.Background())
+	tm.m.refresh = 0
+	tm.m.reconnect = 0
+	tm.m.now = func() time.Time { return time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC) }
+	return tm
+}
+
+// send passes the message to the model and runs all the resulting commands.
+func (tm *testModel) send(msg tea.Msg) {
+	next, cmd := tm.m.Update(msg)
+	tm.m = next.(model)
+	tm.run(cmd)
+}
+
+// run executes the command and passes the resulting messages to the model.
+func (tm *testModel) run(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	switch msg := cmd().(type) {
+	case nil:
+	case tea.BatchMsg:
+		for _, c := range msg {
+			tm.run(c)
+		}
+	default:
+		tm.send(msg)
+	}
+}
+
+func (tm *testModel) typeText(s string) {
+	tm.send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+}
+
+func (tm *testModel) press(keys ...tea.KeyType) {
+	for _, k := range keys {
+		tm.send(tea.KeyMsg{Type: k})
+	}
+}
+
+func testData() *clientModels.SyncResponse {
+	return &clientModels.SyncResponse{
+		Credential: []models.CredentialRecord{
+			{
+				RecordID: models.NewRandomObjectID(),
+				Data:     models.CredentialInfo{Login: "alice@github", Password: "s3cr3t"},
+				Metadata: models.Metadata{"site": "github.com"},
+			},
+			{
+				RecordID: models.NewRandomObjectID(),
+				Data:     models.CredentialInfo{Login: "bob@gitlab", Password: "hunter2"},
+			},
+		},
+	}
+}
+
+// expectEvents returns the events channel of the test model when the stream is opened.
+// The events sent before the channel is closed are received after the initial sync.
+func (tm *testModel) expectEvents(events ...models.ChangeEvent) {
+	for _, e := range events {
+		tm.events <- e
+	}
+	close(tm.events)
+	tm.sync.EXPECT().
+		Events(gomock.Any(), "token").
+		Return((<-chan models.ChangeEvent)(tm.events), nil)
+}
+
+// login logs in and selects the credentials collection.
+// The events are pushed after the initial sync.
+func (tm *testModel) login(data *clientModels.SyncResponse, events ...models.ChangeEvent) {
+	tm.auth.EXPECT().Auth("user", "pwd", tm.m.device).Return(&models.TokenPair{AccessToken: "token"}, nil)
+	tm.sync.EXPECT().Sync("token", models.AllowedCollectionNames).Return(data, nil)
+	tm.expectEvents(events...)
+
+	tm.typeText("user")
+	tm.press(tea.KeyEnter)
+	tm.typeText("pwd")
+	tm.press(tea.KeyEnter)
+
+	for tm.m.collectionName() != models.CredentialsCollection {
+		tm.press(tea.KeyDown)
+	}
+	tm.press(tea.KeyTab)
+}
+
+func TestLogin(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		tm := newTestModel(t)
+		tm.login(testData())
+		assert.Equal(t, mainScreen, tm.m.screen)
+		assert.Equal(t, "token", tm.m.token)
+		// the stream of the test model is closed right after the initial sync
+		assert.Equal(t, "live updates lost", tm.m.syncState)
+		assert.Contains(t, tm.m.View(), "alice@github")
+	})
+	t.Run("device", func(t *testing.T) {
+		tm := newTestModel(t)
+		tm.m.device = service.NewDevice(
+			"laptop",
+			"linux/amd64",
+			ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize)),
+		)
+		tm.login(testData())
+		assert.Equal(t, mainScreen, tm.m.screen)
+	})
+	t.Run("register", func(t *testing.T) {
+		tm := newTestModel(t)
+		tm.auth.EXPECT().Register("user", "pwd").Return(nil)
+		tm.auth.EXPECT().Auth("user", "pwd", gomock.Nil()).Return(&models.TokenPair{AccessToken: "token"}, nil)
+		tm.sync.EXPECT().Sync("token", models.AllowedCollectionNames).Return(testData(), nil)
+		tm.expectEvents()
+
+		tm.typeText("user")
+		tm.press(tea.KeyTab)
+		tm.typeText("pwd")
+		tm.press(tea.KeyCtrlR)
+		assert.Equal(t, mainScreen, tm.m.screen)
+	})
+	t.Run("error", func(t *testing.T) {
+		tm := newTestModel(t)
+		tm.auth.EXPECT().Auth("user", "pwd", gomock.Nil()).Return(nil, errors.New("wrong password"))
+
+		tm.typeText("user")
+		tm.press(tea.KeyEnter)
+		tm.typeText("pwd")
+		tm.press(tea.KeyEnter)
+		assert.Equal(t, loginScreen, tm.m.screen)
+		assert.True(t, tm.m.statusErr)
+		assert.Contains(t, tm.m.View(), "wrong password")
+	})
+	t.Run("second_factor", func(t *testing.T) {
+		tm := newTestModel(t)
+		tm.auth.EXPECT().Auth("user", "pwd", gomock.Nil()).
+			Return(&models.TokenPair{MFARequired: true, MFAToken: "challenge"}, nil)
+		tm.auth.EXPECT().AuthMFA("challenge", "000000", gomock.Nil()).Return(nil, srvErrors.ErrBadMFACode)
+		tm.auth.EXPECT().AuthMFA("challenge", "123456", gomock.Nil()).Return(&models.TokenPair{AccessToken: "token"}, nil)
+		tm.sync.EXPECT().Sync("token", models.AllowedCollectionNames).Return(testData(), nil)
+		tm.expectEvents()
+
+		tm.typeText("user")
+		tm.press(tea.KeyEnter)
+		tm.typeText("pwd")
+		tm.press(tea.KeyEnter)
+		assert.Equal(t, loginScreen, tm.m.screen)
+		assert.Contains(t, tm.m.View(), "One-time code")
+
+		// the code can be entered again after the mistake
+		tm.typeText("000000")
+		tm.press(tea.KeyEnter)
+		assert.Equal(t, loginScreen, tm.m.screen)
+		assert.True(t, tm.m.statusErr)
+		tm.m.codeInput.Reset()
+		tm.typeText("123456")
+		tm.press(tea.KeyEnter)
+		assert.Equal(t, mainScreen, tm.m.screen)
+		assert.Equal(t, "user", tm.m.username)
+		assert.Equal(t, "token", tm.m.token)
+	})
+	t.Run("second_factor_canceled", func(t *testing.T) {
+		tm := newTestModel(t)
+		tm.auth.EXPECT().Auth("user", "pwd", gomock.Nil()).
+			Return(&models.TokenPair{MFARequired: true, MFAToken: "challenge"}, nil)
+
+		tm.typeText("user")
+		tm.press(tea.KeyEnter)
+		tm.typeText("pwd")
+		tm.press(tea.KeyEnter, tea.KeyEsc)
+		assert.Equal(t, loginScreen, tm.m.screen)
+		assert.Empty(t, tm.m.mfaToken)
+		assert.NotContains(t, tm.m.View(), "One-time code")
+	})
+	t.Run("empty", func(t *testing.T) {
+		tm := newTestModel(t)
+		tm.press(tea.KeyEnter, tea.KeyEnter)
+		assert.Equal(t, loginScreen, tm.m.screen)
+		assert.Contains(t, tm.m.status, errEmptyCredentials.Error())
+	})
+}
+
+func TestRefreshTokens(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		tm := newTestModel(t)
+		tm.login(testData())
+		tm.m.refreshToken = "refresh"
+		tm.m.tokenExpiry = tm.m.now().Add(10 * time.Minute)
+		assert.NotNil(t, tm.m.renewCmd())
+
+		tm.auth.EXPECT().
+			Refresh("refresh", gomock.Nil()).
+			Return(&models.TokenPair{AccessToken: "new-token", RefreshToken: "new-refresh"}, nil)
+		tm.send(renewMsg{})
+		assert.Equal(t, "new-token", tm.m.token)
+		assert.Equal(t, "new-refresh", tm.m.refreshToken)
+	})
+	t.Run("error", func(t *testing.T) {
+		tm := newTestModel(t)
+		tm.login(testData())
+		tm.m.refreshToken = "refresh"
+
+		tm.auth.EXPECT().Refresh("refresh", gomock.Nil()).Return(nil, srvErrors.ErrBadRefreshToken)
+		tm.send(renewMsg{})
+		assert.Equal(t, "token", tm.m.token)
+		assert.True(t, tm.m.statusErr)
+	})
+}
+
+func TestChangeEvents(t *testing.T) {
+	data := testData()
+	added := models.CredentialRecord{
+		RecordID: models.NewRandomObjectID(),
+		Data:     models.CredentialInfo{Login: "carol@bitbucket", Password: "pwd"},
+	}
+	updated := data.Credential[1]
+	updated.Data.Login = "bob@gitlab.com"
+
+	tm := newTestModel(t)
+	tm.sync.EXPECT().
+		SyncRecord("token", models.CredentialsCollection, added.RecordID).
+		Return(&clientModels.SyncResponse{Credential: []models.CredentialRecord{added}}, nil)
+	tm.sync.EXPECT().
+		SyncRecord("token", models.CredentialsCollection, updated.RecordID).
+		Return(&clientModels.SyncResponse{Credential: []models.CredentialRecord{updated}}, nil)
+	deleted := data.Credential[0].RecordID
+	tm.login(
+		data,
+		models.ChangeEvent{
+			Collection: models.CredentialsCollection,
+			RecordID:   added.RecordID,
+			Op:         models.OpCreate,
+			Version:    1,
+		},
+		models.ChangeEvent{
+			Collection: models.CredentialsCollection,
+			RecordID:   deleted,
+			Op:         models.OpDelete,
+			Version:    2,
+		},
+		models.ChangeEvent{
+			Collection: models.CredentialsCollection,
+			RecordID:   updated.RecordID,
+			Op:         models.OpUpdate,
+			Version:    3,
+		},
+	)
+	assert.Equal(t, []models.CredentialRecord{updated, added}, tm.m.data.Credential)
+	assert.Equal(t, int64(3), tm.m.version)
+	// the stream is closed after the events
+	assert.Nil(t, tm.m.events)
+	assert.Equal(t, "live updates lost", tm.m.syncState)
+}
+
+func TestChangeEventsMissed(t *testing.T) {
+	tm := newTestModel(t)
+	id := models.NewRandomObjectID()
+	tm.sync.EXPECT().
+		SyncRecord("token", models.TextCollection, id).
+		Return(&clientModels.SyncResponse{}, nil)
+	// the second full sync is caused by the missed event
+	tm.sync.EXPECT().Sync("token", models.AllowedCollectionNames).Return(testData(), nil)
+	tm.login(
+		testData(),
+		models.ChangeEvent{Collection: models.TextCollection, RecordID: id, Op: models.OpCreate, Version: 4},
+		models.ChangeEvent{Collection: models.TextCollection, RecordID: id, Op: models.OpUpdate, Version: 6},
+	)
+	assert.Equal(t, int64(6), tm.m.version)
+}
+
+func TestChangeRecordNotFound(t *testing.T) {
+	data := testData()
+	tm := newTestModel(t)
+	tm.sync.EXPECT().
+		SyncRecord("token", models.CredentialsCollection, data.Credential[0].RecordID).
+		Return(nil, srvErrors.ErrRecordNotFound)
+	tm.login(data, models.ChangeEvent{
+		Collection: models.CredentialsCollection,
+		RecordID:   data.Credential[0].RecordID,
+		Op:         models.OpUpdate,
+		Version:    1,
+	})
+	// the record deleted after the change is removed
+	assert.Len(t, tm.m.data.Credential, 1)
+}
+
+func TestEventsUnavailable(t *testing.T) {
+	tm := newTestModel(t)
+	tm.auth.EXPECT().Auth("user", "pwd", gomock.Nil()).Return(&models.TokenPair{AccessToken: "token"}, nil)
+	tm.sync.EXPECT().Sync("token", models.AllowedCollectionNames).Return(testData(), nil)
+	tm.sync.EXPECT().Events(gomock.Any(), "token").Return(nil, errors.New("boom"))
+
+	tm.typeText("user")
+	tm.press(tea.KeyEnter)
+	tm.typeText("pwd")
+	tm.press(tea.KeyEnter)
+	assert.Equal(t, mainScreen, tm.m.screen)
+	assert.Equal(t, "live updates are unavailable: boom", tm.m.status)
+
+	// a change made by the user is synced without the stream
+	tm.sync.EXPECT().Sync("token", models.AllowedCollectionNames).Return(testData(), nil)
+	tm.send(storageMsg{msg: "added"})
+	assert.Contains(t, tm.m.syncState, "synced at")
+}
+
+func TestStorageWithEvents(t *testing.T) {
+	tm := newTestModel(t)
+	tm.login(testData())
+	// the change made by the user is pushed back by the server, so no sync is needed
+	tm.m.events = make(chan models.ChangeEvent)
+	tm.send(storageMsg{msg: "added"})
+	assert.Equal(t, "added", tm.m.status)
+}
+
+func TestSyncError(t *testing.T) {
+	tm := newTestModel(t)
+	tm.login(testData())
+	tm.sync.EXPECT().Sync("token", models.AllowedCollectionNames).Return(nil, errors.New("boom"))
+	tm.typeText("r")
+	assert.Equal(t, "sync failed: boom", tm.m.syncState)
+	// the previous data is kept
+	assert.Len(t, tm.m.visibleEntries(), 2)
+}
+
+func TestSyncOffline(t *testing.T) {
+	tm := newTestModel(t)
+	tm.login(testData())
+	store := mock.NewMockLocalStore(gomock.NewController(t))
+	tm.m.store = store
+	tm.sync.EXPECT().
+		Sync("token", models.AllowedCollectionNames).
+		Return(nil, fmt.Errorf("%w: connection refused", clientErr.ErrServerUnavailable))
+	local := testData()
+	local.Credential = local.Credential[:1]
+	store.EXPECT().Load().Return(local, nil)
+	store.EXPECT().Pending().Return(make([]clientModels.PendingOp, 2), nil)
+	tm.typeText("r")
+	assert.Equal(t, "offline, 2 pending", tm.m.syncState)
+	// the records are shown from the local store
+	assert.Len(t, tm.m.visibleEntries(), 1)
+}
+
+func TestSearch(t *testing.T) {
+	tm := newTestModel(t)
+	tm.login(testData())
+	require.Len(t, tm.m.visibleEntries(), 2)
+
+	tm.typeText("/")
+	tm.typeText("gitlab")
+	require.Len(t, tm.m.visibleEntries(), 1)
+	assert.Equal(t, "bob@gitlab", tm.m.visibleEntries()[0].title)
+	tm.press(tea.KeyEnter)
+	assert.False(t, tm.m.searching)
+	assert.Len(t, tm.m.visibleEntries(), 1)
+
+	// secrets are not searchable
+	tm.typeText("/")
+	tm.press(tea.KeyEsc)
+	tm.typeText("/")
+	tm.typeText("hunter2")
+	assert.Len(t, tm.m.visibleEntries(), 0)
+	tm.press(tea.KeyEsc)
+	assert.Len(t, tm.m.visibleEntries(), 2)
+}
+
+func TestSearchAll(t *testing.T) {
+	tm := newTestModel(t)
+	data := testData()
+	tm.login(data)
+	tm.m.collection = 0
+	found := []models.SearchResult{
+		{
+			Collection: models.TextCollection,
+			Record: models.UntypedRecord{
+				UntypedRecordContent: models.UntypedRecordContent{
+					Data:     "not synced text",
+					Metadata: models.Metadata{"note": "github"},
+				},
+				RecordID: models.NewRandomObjectID(),
+			},
+		},
+		{
+			Collection: models.CredentialsCollection,
+			Record: models.UntypedRecord{
+				UntypedRecordContent: models.UntypedRecordContent{
+					Data: map[string]any{"Login": "alice@github", "Password": "s3cr3t"},
+				},
+				RecordID: data.Credential[0].RecordID,
+			},
+		},
+	}
+	tm.storage.EXPECT().Search("github", "token").Return(found, nil)
+
+	tm.typeText("f")
+	require.True(t, tm.m.finding)
+	tm.typeText("github")
+	tm.press(tea.KeyEnter)
+	require.NotNil(t, tm.m.found)
+	require.Len(t, tm.m.found.entries, 2)
+	assert.Equal(t, "2 records found", tm.m.status)
+	assert.Contains(t, tm.m.View(), "credentials: alice@github")
+	e, ok := tm.m.selected()
+	require.True(t, ok)
+	assert.Equal(t, "not synced text", e.title)
+
+	// the found record is missing from the synced data
+	tm.press(tea.KeyEnter)
+	assert.NotNil(t, tm.m.found)
+	assert.Contains(t, tm.m.status, "not synced")
+
+	// the found record is selected in its collection
+	tm.press(tea.KeyDown)
+	tm.press(tea.KeyEnter)
+	assert.Nil(t, tm.m.found)
+	assert.Equal(t, models.CredentialsCollection, tm.m.collectionName())
+	e, ok = tm.m.selected()
+	require.True(t, ok)
+	assert.Equal(t, data.Credential[0].RecordID, e.id)
+
+	tm.storage.EXPECT().Search("nothing", "token").Return(nil, errors.New("empty search query"))
+	tm.typeText("f")
+	tm.typeText("nothing")
+	tm.press(tea.KeyEnter)
+	assert.Nil(t, tm.m.found)
+	assert.True(t, tm.m.statusErr)
+
+	// the canceled search is not sent
+	tm.typeText("f")
+	tm.typeText("github")
+	tm.press(tea.KeyEsc)
+	assert.False(t, tm.m.finding)
+	assert.Nil(t, tm.m.found)
+}
+
+func TestRevealSecrets(t *testing.T) {
+	tm := newTestModel(t)
+	tm.login(testData())
+	view := tm.m.View()
+	assert.Contains(t, view, "github.com")
+	assert.NotContains(t, view, "s3cr3t")
+
+	tm.typeText("s")
+	assert.Contains(t, tm.m.View(), "s3cr3t")
+}
+
+func TestAddRecord(t *testing.T) {
+	tm := newTestModel(t)
+	tm.login(testData())
+	tm.storage.EXPECT().
+		Add(
+			`{"data":{"Login":"carol","Password":"pwd"},"metadata":{"site":"example.com"},`+
+				`"record_id":"000000000000000000000000"}`,
+			models.CredentialsCollection,
+			"token",
+		).
+		Return("added", nil)
+	tm.sync.EXPECT().Sync("token", models.AllowedCollectionNames).Return(testData(), nil)
+
+	tm.typeText("a")
+	require.Equal(t, formScreen, tm.m.screen)
+	tm.typeText("carol")
+	tm.press(tea.KeyTab)
+	tm.typeText("pwd")
+	tm.press(tea.KeyEnter)
+	tm.typeText("site=example.com")
+	tm.press(tea.KeyEnter)
+	assert.Equal(t, mainScreen, tm.m.screen)
+	assert.Equal(t, "added", tm.m.status)
+}
+
+func TestEditRecord(t *testing.T) {
+	data := testData()
+	tm := newTestModel(t)
+	tm.login(data)
+	tm.storage.EXPECT().
+		Update(
+			`{"data":{"Login":"alice@github","Password":"s3cr3t!"},"metadata":{"site":"github.com"},`+
+				`"record_id":"`+data.Credential[0].RecordID.Hex()+`"}`,
+			models.CredentialsCollection,
+			"token",
+		).
+		Return("", errors.New("bad request"))
+
+	tm.typeText("e")
+	require.Equal(t, formScreen, tm.m.screen)
+	tm.press(tea.KeyTab)
+	tm.typeText("!")
+	tm.press(tea.KeyCtrlS)
+	assert.Equal(t, mainScreen, tm.m.screen)
+	assert.True(t, tm.m.statusErr)
+	assert.Contains(t, tm.m.status, "bad request")
+}
+
+func TestEditRecordConflict(t *testing.T) {
+	data := testData()
+	data.Credential[0].Version = 2
+	id := data.Credential[0].RecordID
+	tm := newTestModel(t)
+	tm.login(data)
+	current := models.UntypedRecord{
+		UntypedRecordContent: models.UntypedRecordContent{
+			Data:     map[string]any{"Login": "alice@github", "Password": "changed"},
+			Metadata: models.Metadata{"team": "dev"},
+		},
+		RecordID: id,
+		Version:  3,
+	}
+	gomock.InOrder(
+		tm.storage.EXPECT().
+			Update(
+				`{"data":{"Login":"alice@github","Password":"s3cr3t!"},"metadata":{"site":"github.com"},`+
+					`"record_id":"`+id.Hex()+`","expected_version":2}`,
+				models.CredentialsCollection,
+				"token",
+			).
+			Return("", &clientErr.ConflictError{Current: current}),
+		tm.storage.EXPECT().
+			Update(
+				`{"data":{"Login":"alice@github","Password":"s3cr3t!"},`+
+					`"expected_version":3,"metadata":{"site":"github.com","team":"dev"},`+
+					`"record_id":"`+id.Hex()+`"}`,
+				models.CredentialsCollection,
+				"token",
+			).
+			Return("updated", nil),
+	)
+	tm.sync.EXPECT().Sync("token", models.AllowedCollectionNames).Return(testData(), nil)
+
+	tm.typeText("e")
+	tm.press(tea.KeyTab)
+	tm.typeText("!")
+	tm.press(tea.KeyCtrlS)
+	require.Equal(t, conflictScreen, tm.m.screen)
+	view := tm.m.View()
+	assert.Contains(t, view, "modified by another client")
+	assert.Contains(t, view, "team=dev")
+	assert.NotContains(t, view, "changed")
+
+	tm.typeText("b")
+	assert.Equal(t, mainScreen, tm.m.screen)
+	assert.Equal(t, "updated", tm.m.status)
+}
+
+func TestEditRecordConflictCanceled(t *testing.T) {
+	data := testData()
+	tm := newTestModel(t)
+	tm.login(data)
+	tm.storage.EXPECT().
+		Update(gomock.Any(), models.CredentialsCollection, "token").
+		Return("", &clientErr.ConflictError{Current: models.UntypedRecord{
+			UntypedRecordContent: models.UntypedRecordContent{Data: map[string]any{"Login": "x"}},
+		}})
+
+	tm.typeText("e")
+	tm.press(tea.KeyCtrlS)
+	require.Equal(t, conflictScreen, tm.m.screen)
+	tm.press(tea.KeyEsc)
+	assert.Equal(t, mainScreen, tm.m.screen)
+	assert.Nil(t, tm.m.conflict)
+	assert.Equal(t, "update canceled", tm.m.status)
+}
+
+func TestFormValidation(t *testing.T) {
+	tm := newTestModel(t)
+	tm.login(testData())
+	tm.typeText("a")
+	tm.press(tea.KeyTab, tea.KeyTab)
+	tm.typeText("novalue")
+	tm.press(tea.KeyCtrlS)
+	assert.Equal(t, formScreen, tm.m.screen)
+	assert.Error(t, tm.m.form.err)
+	assert.Contains(t, tm.m.View(), "expected key=value")
+
+	tm.press(tea.KeyEsc)
+	assert.Equal(t, mainScreen, tm.m.screen)
+	assert.Nil(t, tm.m.form)
+}
+
+func TestDeleteRecord(t *testing.T) {
+	data := testData()
+	tm := newTestModel(t)
+	tm.login(data)
+	tm.storage.EXPECT().
+		Delete(
+			`{"record_id": "`+data.Credential[1].RecordID.Hex()+`"}`,
+			models.CredentialsCollection,
+			"token",
+		).
+		Return("deleted", nil)
+	tm.sync.EXPECT().Sync("token", models.AllowedCollectionNames).Return(testData(), nil)
+
+	tm.press(tea.KeyDown)
+	tm.typeText("d")
+	require.Equal(t, confirmScreen, tm.m.screen)
+	assert.Contains(t, tm.m.View(), "(y/n)")
+	tm.typeText("n")
+	assert.Equal(t, mainScreen, tm.m.screen)
+
+	tm.typeText("d")
+	tm.typeText("y")
+	assert.Equal(t, mainScreen, tm.m.screen)
+	assert.Equal(t, "deleted", tm.m.status)
+}
@@ -0,0 +1,244 @@
+package shell
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// field is a single named value of a record shown in the detail pane.
+type field struct {
+	name   string
+	value  string
+	secret bool
+}
+
+// entry is a record of any collection prepared for displaying.
+type entry struct {
+	id       models.ObjectID
+	title    string
+	fields   []field
+	metadata models.Metadata
+	// version is the version of the record on the server; zero if unknown.
+	version int64
+	// values contains the initial values of the edit form.
+	values map[string]string
+	// otp is set for the otp records to generate the current code.
+	otp *models.OTPInfo
+}
+
+// mask replaces a secret value with placeholder symbols.
+func mask(s string) string {
+	if s == "" {
+		return ""
+	}
+	return strings.Repeat("•", 8)
+}
+
+// firstLine returns the first line of the text.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// lastDigits returns the last n digits of the card number.
+func lastDigits(number string, n int) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+	if len(digits) > n {
+		digits = digits[len(digits)-n:]
+	}
+	return digits
+}
+
+// formatMetadata converts metadata into the "key=value; key=value" form.
+func formatMetadata(md models.Metadata) string {
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", k, md[k]))
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// parseMetadata parses metadata in the "key=value; key=value" form.
+func parseMetadata(s string) (models.Metadata, error) {
+	md := make(models.Metadata)
+	for _, pair := range strings.Split(s, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("wrong metadata %q: expected key=value", pair)
+		}
+		md[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return md, nil
+}
+
+// entries converts the records of the collection into entries.
+func entries(data *clientModels.SyncResponse, collection models.CollectionName) []entry {
+	if data == nil {
+		return nil
+	}
+	var res []entry
+	switch collection {
+	case models.TextCollection:
+		for _, r := range data.Text {
+			res = append(res, entry{
+				id:       r.RecordID,
+				title:    firstLine(r.Data),
+				fields:   []field{{name: "Text", value: r.Data}},
+				metadata: r.Metadata,
+				version:  r.Version,
+				values:   map[string]string{"text": r.Data},
+			})
+		}
+	case models.CredentialsCollection:
+		for _, r := range data.Credential {
+			res = append(res, entry{
+				id:    r.RecordID,
+				title: r.Data.Login,
+				fields: []field{
+					{name: "Login", value: r.Data.Login},
+					{name: "Password", value: r.Data.Password, secret: true},
+				},
+				metadata: r.Metadata,
+				version:  r.Version,
+				values: map[string]string{
+					"login":    r.Data.Login,
+					"password": r.Data.Password,
+				},
+			})
+		}
+	case models.CardCollection:
+		for _, r := range data.Card {
+			res = append(res, entry{
+				id:    r.RecordID,
+				title: "•••• " + lastDigits(r.Data.CardNumber, 4),
+				fields: []field{
+					{name: "Card number", value: r.Data.CardNumber, secret: true},
+					{name: "CVV", value: r.Data.CVV, secret: true},
+					{name: "Expiration date", value: r.Data.ExpirationDate},
+				},
+				metadata: r.Metadata,
+				version:  r.Version,
+				values: map[string]string{
+					"number":     r.Data.CardNumber,
+					"cvv":        r.Data.CVV,
+					"expiration": r.Data.ExpirationDate,
+				},
+			})
+		}
+	case models.BinaryCollection:
+		for _, r := range data.Binary {
+			size := "unknown"
+			if r.Data.Size != "" {
+				size = r.Data.Size + " bytes"
+			} else if b, err := base64.StdEncoding.DecodeString(r.Data.Content); err == nil {
+				size = fmt.Sprintf("%d bytes", len(b))
+			}
+			res = append(res, entry{
+				id:    r.RecordID,
+				title: r.Data.FileName,
+				fields: []field{
+					{name: "File name", value: r.Data.FileName},
+					{name: "Size", value: size},
+				},
+				metadata: r.Metadata,
+				version:  r.Version,
+				values:   map[string]string{"file": r.Data.FileName},
+			})
+		}
+	case models.OTPCollection:
+		for _, r := range data.OTP {
+			info := r.Data
+			title := info.Account
+			if info.Issuer != "" {
+				title = info.Issuer + ":" + info.Account
+			}
+			res = append(res, entry{
+				id:    r.RecordID,
+				title: title,
+				fields: []field{
+					{name: "Type", value: info.Type},
+					{name: "Issuer", value: info.Issuer},
+					{name: "Account", value: info.Account},
+					{name: "Secret", value: info.Secret, secret: true},
+					{name: "Algorithm", value: info.Algorithm},
+					{name: "Digits", value: info.Digits},
+					{name: "Period", value: info.Period},
+					{name: "Counter", value: info.Counter},
+				},
+				metadata: r.Metadata,
+				version:  r.Version,
+				values: map[string]string{
+					"type":      info.Type,
+					"secret":    info.Secret,
+					"issuer":    info.Issuer,
+					"account":   info.Account,
+					"algorithm": info.Algorithm,
+					"digits":    info.Digits,
+					"period":    info.Period,
+					"counter":   info.Counter,
+				},
+				otp: &info,
+			})
+		}
+	}
+	for i := range res {
+		if res[i].title == "" {
+			res[i].title = res[i].id.Hex()
+		}
+		res[i].values["metadata"] = formatMetadata(res[i].metadata)
+	}
+	return res
+}
+
+// matches checks if the entry contains the query. Secret values are not searched.
+func (e entry) matches(query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return true
+	}
+	candidates := []string{e.title, e.id.Hex()}
+	for _, f := range e.fields {
+		if !f.secret {
+			candidates = append(candidates, f.value)
+		}
+	}
+	for k, v := range e.metadata {
+		candidates = append(candidates, k, v)
+	}
+	for _, c := range candidates {
+		if strings.Contains(strings.ToLower(c), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterEntries returns the entries matching the query.
+func filterEntries(es []entry, query string) []entry {
+	var res []entry
+	for _, e := range es {
+		if e.matches(query) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
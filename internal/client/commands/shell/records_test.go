@@ -0,0 +1,95 @@
+package shell
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestMetadata(t *testing.T) {
+	md, err := parseMetadata(" site = github.com; ;note=a=b")
+	require.NoError(t, err)
+	assert.Equal(t, models.Metadata{"site": "github.com", "note": "a=b"}, md)
+	assert.Equal(t, "note=a=b; site=github.com", formatMetadata(md))
+
+	_, err = parseMetadata("novalue")
+	assert.Error(t, err)
+	_, err = parseMetadata("=value")
+	assert.Error(t, err)
+}
+
+func TestEntries(t *testing.T) {
+	data := &clientModels.SyncResponse{
+		Text: []models.TextRecord{{Data: "first line\nsecond line"}},
+		Card: []models.CardRecord{{Data: models.CardInfo{
+			CardNumber:     "4111 1111 1111 1234",
+			CVV:            "123",
+			ExpirationDate: "12/30",
+		}}},
+		Binary: []models.BinaryRecord{{Data: models.BinaryInfo{
+			FileName: "notes.txt",
+			Content:  "aGVsbG8=",
+		}}},
+		OTP: []models.OTPRecord{{Data: models.OTPInfo{
+			Secret:  "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			Issuer:  "Example",
+			Account: "alice",
+		}}},
+	}
+	tests := []struct {
+		collection models.CollectionName
+		title      string
+		field      field
+	}{
+		{models.TextCollection, "first line", field{name: "Text", value: "first line\nsecond line"}},
+		{models.CardCollection, "•••• 1234", field{name: "CVV", value: "123", secret: true}},
+		{models.BinaryCollection, "notes.txt", field{name: "Size", value: "5 bytes"}},
+		{models.OTPCollection, "Example:alice", field{name: "Issuer", value: "Example"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.collection), func(t *testing.T) {
+			es := entries(data, tt.collection)
+			require.Len(t, es, 1)
+			assert.Equal(t, tt.title, es[0].title)
+			assert.Contains(t, es[0].fields, tt.field)
+		})
+	}
+	assert.Empty(t, entries(data, models.CredentialsCollection))
+
+	data.Binary[0].Data = models.BinaryInfo{
+		FileName: "movie.mkv",
+		BlobID:   models.NewRandomObjectID().Hex(),
+		Size:     "1048577",
+	}
+	assert.Contains(
+		t,
+		entries(data, models.BinaryCollection)[0].fields,
+		field{name: "Size", value: "1048577 bytes"},
+	)
+	assert.Empty(t, entries(nil, models.TextCollection))
+}
+
+func TestOTPCodeInDetails(t *testing.T) {
+	tm := newTestModel(t)
+	tm.m.screen = mainScreen
+	tm.m.pane = listPane
+	tm.m.data = &clientModels.SyncResponse{
+		OTP: []models.OTPRecord{{Data: models.OTPInfo{
+			Secret:  "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			Account: "alice",
+		}}},
+	}
+	for tm.m.collectionName() != models.OTPCollection {
+		tm.m.collection++
+	}
+	tm.m.now = func() time.Time { return time.Unix(59, 0) }
+	view := tm.m.View()
+	// RFC 6238 test vector
+	assert.Contains(t, view, "287082")
+	assert.NotContains(t, view, "GEZDGNBVGY3TQOJQ")
+}
@@ -0,0 +1,141 @@
+package shell
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// foundMsg is sent when the server finds the records of all the collections.
+type foundMsg struct {
+	query   string
+	results []models.SearchResult
+	err     error
+}
+
+// foundEntry is a record found by the server prepared for displaying.
+type foundEntry struct {
+	collection models.CollectionName
+	entry
+}
+
+// searchResults are the records found by the server and the cursor among them.
+type searchResults struct {
+	query   string
+	entries []foundEntry
+	cursor  int
+}
+
+// foundEntries converts the found records into entries. The records which
+// can't be shown are skipped.
+func foundEntries(results []models.SearchResult) []foundEntry {
+	res := make([]foundEntry, 0, len(results))
+	for _, r := range results {
+		data := &clientModels.SyncResponse{}
+		if err := data.AppendRecord(r.Collection, r.Record); err != nil {
+			continue
+		}
+		for _, e := range entries(data, r.Collection) {
+			res = append(res, foundEntry{collection: r.Collection, entry: e})
+		}
+	}
+	return res
+}
+
+// findCmd searches the records of all the collections on the server.
+func (m model) findCmd(query string) tea.Cmd {
+	return func() tea.Msg {
+		results, err := m.storageService.Search(query, m.token)
+		return foundMsg{query: query, results: results, err: err}
+	}
+}
+
+// updateFound shows the records found by the server.
+func (m model) updateFound(msg foundMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.setStatus("search failed", msg.err)
+		return m, nil
+	}
+	m.found = &searchResults{query: msg.query, entries: foundEntries(msg.results)}
+	m.pane = listPane
+	m.setStatus(fmt.Sprintf("%d records found", len(m.found.entries)), nil)
+	return m, nil
+}
+
+// updateFind handles the keys while the query of the search on the server is typed.
+func (m model) updateFind(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.finding = false
+		m.find.Blur()
+		if m.find.Value() == "" {
+			return m, nil
+		}
+		m.setStatus("searching...", nil)
+		return m, m.findCmd(m.find.Value())
+	case "esc":
+		m.finding = false
+		m.find.Blur()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.find, cmd = m.find.Update(msg)
+	return m, cmd
+}
+
+// updateResults handles the keys while the found records are shown.
+func (m model) updateResults(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "up", "k":
+		if m.found.cursor > 0 {
+			m.found.cursor--
+		}
+	case "down", "j":
+		if m.found.cursor < len(m.found.entries)-1 {
+			m.found.cursor++
+		}
+	case "s":
+		m.reveal = !m.reveal
+	case "enter":
+		m.openFound()
+	case "esc":
+		m.found = nil
+	}
+	return m, nil
+}
+
+// selectedFound returns the found record under the cursor.
+func (m model) selectedFound() (foundEntry, bool) {
+	if m.found == nil || m.found.cursor >= len(m.found.entries) {
+		return foundEntry{}, false
+	}
+	return m.found.entries[m.found.cursor], true
+}
+
+// openFound selects the found record in the list of its collection,
+// so it can be edited or deleted.
+func (m *model) openFound() {
+	f, ok := m.selectedFound()
+	if !ok {
+		return
+	}
+	for i, c := range models.AllowedCollectionNames {
+		if c != f.collection {
+			continue
+		}
+		for j, e := range entries(m.data, c) {
+			if e.id == f.id {
+				m.collection, m.cursor, m.pane = i, j, listPane
+				m.search.SetValue("")
+				m.found = nil
+				return
+			}
+		}
+	}
+	m.setStatus("the record is not synced yet, press r to sync", nil)
+}
@@ -2,30 +2,102 @@
 package shell
 
 import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
 )
 
 var (
-	// shellCtrl is a controller to interact with different services.
-	shellCtrl ShellController
+	// authService is a service to authenticate the user.
+	authService service.AuthService
+	// syncService is a service to retrieve the data and to receive the notifications.
+	syncService service.SyncService
+	// storageService is a service to modify the records.
+	storageService service.StorageService
+	// localStore is a local copy of the records; nil if the key is not provided.
+	localStore service.LocalStore
+	// device is the device of the profile registered by the login.
+	device *service.Device
 	// ShellCmd represents the shell command.
 	ShellCmd = &cobra.Command{
 		Use:   "shell",
-		Short: "Runs the shell with a persistent menu.",
-		Long: `Runs the shell and presents a persistent menu for the user to interact with.
-The user is presented with a menu that persists throughout the duration of the shell, a
-llowing them to navigate through different features of the shell.`,
+		Short: "Runs the full-screen terminal user interface.",
+		Long: `Runs the full-screen terminal user interface.
+The interface contains a collection sidebar, a searchable record list, a detail
+pane with masked secrets and inline forms to add, edit and delete records. The
+status bar shows the sync state which is updated when the server pushes changes
+made by any client of the same user. With the key of the local store the records
+are shown from it and the changes are queued while the server is unavailable.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			for {
-				shellCtrl.ShowMenu()
+			// the context closes the stream of the change events on exit
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			m := newModel(
+				authService,
+				syncService,
+				storageService,
+				cmd.Flag("server").Value.String(),
+				ctx,
+			)
+			m.store = localStore
+			m.device = device
+			p := tea.NewProgram(
+				m,
+				tea.WithAltScreen(),
+				tea.WithInput(cmd.InOrStdin()),
+				tea.WithOutput(cmd.OutOrStdout()),
+			)
+			if _, err := p.Run(); err != nil {
+				log.Fatalf("Error while running the interface: %v", err)
 			}
 		},
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if _, err := profile.Apply(cmd); err != nil {
+				log.Fatalf("Error while loading the profile: %v", err)
+			}
+			var err error
+			if device, err = profile.LoadDevice(profile.Name(cmd), ""); err != nil {
+				log.Fatalf("Error while loading the device of the profile: %v", err)
+			}
 			baseURL := cmd.Flag("server").Value.String()
-			shellCtrl = NewShellController(baseURL)
+			transport := cmd.Flag("transport").Value.String()
+			if authService, err = service.NewAuthServiceWithTransport(transport, baseURL); err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			if syncService, err = service.NewSyncServiceWithTransport(transport, baseURL); err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			if storageService, err = service.NewStorageServiceWithTransport(transport, baseURL); err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			// the vault is unlocked with the first sync after the login
+			if password := cmd.Flag("master-password").Value.String(); password != "" {
+				vault, err := service.NewVaultWithTransport(transport, baseURL, password)
+				if err != nil {
+					log.Fatalf("Error while creating a service: %v", err)
+				}
+				syncService = service.NewE2ESyncService(syncService, vault)
+				storageService = service.NewE2EStorageService(storageService, vault)
+			}
+			localStore = nil
+			if key := cmd.Flag("key").Value.String(); key != "" {
+				localStore = service.NewLocalStore(cmd.Flag("file").Value.String(), key)
+				syncService = service.NewOfflineSyncService(syncService, storageService, localStore)
+				storageService = service.NewOfflineStorageService(storageService, localStore)
+			}
 		},
 	}
 )
 
 func init() {
+	ShellCmd.PersistentFlags().
+		StringP("file", "f", "", "filename of the local store (default: from the profile)")
+	ShellCmd.PersistentFlags().
+		StringP("key", "k", "", "key of the local store which enables the offline mode")
+	profile.MarkFileFlag(ShellCmd, "file")
 }
@@ -0,0 +1,314 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// sidebarWidth and listWidth are the widths of the sidebar and the record list.
+const (
+	sidebarWidth = 18
+	listWidth    = 32
+)
+
+var (
+	paneStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("240")).
+			Padding(0, 1)
+	focusedPaneStyle = paneStyle.Copy().BorderForeground(lipgloss.Color("62"))
+	selectedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("229")).
+				Background(lipgloss.Color("62"))
+	titleStyle  = lipgloss.NewStyle().Bold(true)
+	labelStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	helpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("230")).
+			Background(lipgloss.Color("236"))
+)
+
+// truncate cuts the string to fit the width.
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 {
+		return ""
+	}
+	if len(r) <= width {
+		return s
+	}
+	return string(r[:width-1]) + "…"
+}
+
+// View renders the current screen.
+func (m model) View() string {
+	var body string
+	switch m.screen {
+	case loginScreen:
+		body = m.loginView()
+	case formScreen:
+		body = m.formView()
+	case conflictScreen:
+		body = m.conflictView()
+	default:
+		body = m.mainView()
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, body, m.statusBar())
+}
+
+// loginView renders the login screen.
+func (m model) loginView() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("GophKeeper") + "\n\n")
+	labels := []string{"Username", "Password"}
+	for i, input := range m.loginInputs {
+		b.WriteString(labelStyle.Render(labels[i]) + "\n")
+		b.WriteString(input.View() + "\n\n")
+	}
+	if m.mfaToken != "" {
+		b.WriteString(labelStyle.Render("One-time code") + "\n")
+		b.WriteString(m.codeInput.View() + "\n\n")
+		b.WriteString(helpStyle.Render("enter: check the code • esc: cancel • ctrl+c: quit"))
+	} else {
+		b.WriteString(helpStyle.Render("enter: login • ctrl+r: register • tab: next field • ctrl+c: quit"))
+	}
+	return paneStyle.Copy().Width(m.width - 2).Height(m.paneHeight()).Render(b.String())
+}
+
+// paneHeight returns the height available for the panes.
+func (m model) paneHeight() int {
+	h := m.height - 3
+	if h < 5 {
+		h = 5
+	}
+	return h
+}
+
+// mainView renders the sidebar, the record list and the detail pane.
+func (m model) mainView() string {
+	h := m.paneHeight() - 1
+	sidebar, list := paneStyle, paneStyle
+	if m.pane == sidebarPane {
+		sidebar = focusedPaneStyle
+	} else {
+		list = focusedPaneStyle
+	}
+	detailWidth := m.width - sidebarWidth - listWidth - 6
+	if detailWidth < 20 {
+		detailWidth = 20
+	}
+	panes := lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		sidebar.Copy().Width(sidebarWidth).Height(h).Render(m.sidebarView()),
+		list.Copy().Width(listWidth).Height(h).Render(m.listView(h)),
+		paneStyle.Copy().Width(detailWidth).Height(h).Render(m.detailView(detailWidth-2)),
+	)
+	return lipgloss.JoinVertical(lipgloss.Left, panes, m.helpView())
+}
+
+// sidebarView renders the list of collections.
+func (m model) sidebarView() string {
+	lines := []string{titleStyle.Render("Collections")}
+	for i, c := range models.AllowedCollectionNames {
+		line := fmt.Sprintf("%-12v %3d", c, len(entries(m.data, c)))
+		if i == m.collection {
+			line = selectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// listView renders the records of the selected collection
+// or the records found by the server.
+func (m model) listView(height int) string {
+	if m.found != nil {
+		return m.foundView(height)
+	}
+	lines := []string{titleStyle.Render(string(m.collectionName()))}
+	if m.finding {
+		lines = append(lines, m.find.View())
+	} else if m.searching || m.search.Value() != "" {
+		lines = append(lines, m.search.View())
+	}
+	es := m.visibleEntries()
+	titles := make([]string, 0, len(es))
+	for _, e := range es {
+		titles = append(titles, e.title)
+	}
+	return strings.Join(append(lines, rowsView(titles, m.cursor, height-len(lines))...), "\n")
+}
+
+// foundView renders the records of all the collections found by the server.
+func (m model) foundView(height int) string {
+	lines := []string{titleStyle.Render(truncate("found: "+m.found.query, listWidth-2))}
+	titles := make([]string, 0, len(m.found.entries))
+	for _, f := range m.found.entries {
+		titles = append(titles, fmt.Sprintf("%v: %v", f.collection, f.title))
+	}
+	return strings.Join(append(lines, rowsView(titles, m.found.cursor, height-len(lines))...), "\n")
+}
+
+// rowsView renders the titles which fit the rows with the one under the cursor selected.
+func rowsView(titles []string, cursor, rows int) []string {
+	if len(titles) == 0 {
+		return []string{helpStyle.Render("no records")}
+	}
+	start := 0
+	if cursor >= rows {
+		start = cursor - rows + 1
+	}
+	var lines []string
+	for i := start; i < len(titles) && i < start+rows; i++ {
+		line := truncate(titles[i], listWidth-2)
+		if i == cursor {
+			line = selectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// detailView renders the selected record. Secret values are masked
+// unless the user reveals them.
+func (m model) detailView(width int) string {
+	e, ok := m.selected()
+	if !ok {
+		return helpStyle.Render("select a record")
+	}
+	if m.screen == confirmScreen {
+		return errorStyle.Render(fmt.Sprintf("Delete %q? (y/n)", e.title))
+	}
+	lines := []string{
+		titleStyle.Render(truncate(e.title, width)),
+		labelStyle.Render("ID: ") + e.id.Hex(),
+	}
+	if e.version != 0 {
+		lines = append(lines, labelStyle.Render("Version: ")+fmt.Sprint(e.version))
+	}
+	lines = append(lines, "")
+	lines = append(lines, m.fieldLines(e)...)
+	return lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+}
+
+// fieldLines renders the fields, the otp code and the metadata of the record.
+func (m model) fieldLines(e entry) []string {
+	var lines []string
+	for _, f := range e.fields {
+		if f.value == "" {
+			continue
+		}
+		value := f.value
+		if f.secret && !m.reveal {
+			value = mask(value)
+		}
+		lines = append(lines, labelStyle.Render(f.name+": ")+value)
+	}
+	if e.otp != nil {
+		code, err := e.otp.Code(m.now())
+		if err != nil {
+			lines = append(lines, errorStyle.Render(fmt.Sprintf("unable to generate the code: %v", err)))
+		} else {
+			line := labelStyle.Render("Code: ") + code.Code
+			if code.RemainingSeconds > 0 {
+				line += fmt.Sprintf(" (%ds)", code.RemainingSeconds)
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(e.metadata) > 0 {
+		lines = append(lines, "", titleStyle.Render("Metadata"))
+		for _, kv := range strings.Split(formatMetadata(e.metadata), "; ") {
+			lines = append(lines, kv)
+		}
+	}
+	return lines
+}
+
+// helpView renders the key bindings of the main screen.
+func (m model) helpView() string {
+	if m.searching {
+		return helpStyle.Render("enter: apply • esc: clear")
+	}
+	if m.finding {
+		return helpStyle.Render("enter: search all collections • esc: cancel")
+	}
+	if m.found != nil {
+		return helpStyle.Render("↑/↓: move • enter: open • s: show secrets • esc: back • q: quit")
+	}
+	return helpStyle.Render(
+		"↑/↓: move • tab: switch pane • /: search • f: search all • a: add • e: edit • " +
+			"d: delete • s: show secrets • r: sync • q: quit",
+	)
+}
+
+// formView renders the record form.
+func (m model) formView() string {
+	f := m.form
+	title := fmt.Sprintf("New %v record", f.collection)
+	if f.edit {
+		title = fmt.Sprintf("Edit %v record %v", f.collection, f.recordID.Hex())
+	}
+	lines := []string{titleStyle.Render(title), ""}
+	for i, ff := range f.fields {
+		label := labelStyle.Render(ff.label)
+		if i == f.focused {
+			label = selectedStyle.Render(ff.label)
+		}
+		lines = append(lines, label, f.inputs[i].View())
+	}
+	if f.err != nil {
+		lines = append(lines, "", errorStyle.Render(f.err.Error()))
+	}
+	lines = append(lines, "", helpStyle.Render("tab: next field • ctrl+s: save • esc: cancel"))
+	return focusedPaneStyle.Copy().Width(m.width - 2).Height(m.paneHeight()).
+		Render(strings.Join(lines, "\n"))
+}
+
+// conflictView renders the current copy of the record whose update is rejected
+// and the ways to resolve the conflict.
+func (m model) conflictView() string {
+	c := m.conflict
+	lines := []string{
+		titleStyle.Render(fmt.Sprintf("Record %v was modified by another client", c.err.Current.RecordID.Hex())),
+		"",
+		labelStyle.Render("Current version: ") + fmt.Sprint(c.err.Current.Version),
+		"",
+	}
+	current := &clientModels.SyncResponse{}
+	if err := current.AppendRecord(c.collection, c.err.Current); err != nil {
+		lines = append(lines, errorStyle.Render(fmt.Sprintf("unable to show the record: %v", err)))
+	}
+	for _, e := range entries(current, c.collection) {
+		lines = append(lines, m.fieldLines(e)...)
+	}
+	lines = append(lines, "", helpStyle.Render(
+		"m: keep mine • t: keep theirs • b: keep my data and merge metadata • "+
+			"s: show secrets • esc: cancel",
+	))
+	return focusedPaneStyle.Copy().Width(m.width - 2).Height(m.paneHeight()).
+		Render(strings.Join(lines, "\n"))
+}
+
+// statusBar renders the user, the last action result and the sync state.
+func (m model) statusBar() string {
+	user := "not logged in"
+	if m.username != "" {
+		user = m.username
+	}
+	left := fmt.Sprintf(" %v@%v ", user, m.server)
+	right := fmt.Sprintf(" %v ", m.syncState)
+	status := m.status
+	if m.statusErr {
+		status = errorStyle.Copy().Background(lipgloss.Color("236")).Render(status)
+	}
+	gap := m.width - lipgloss.Width(left) - lipgloss.Width(right) - lipgloss.Width(status)
+	if gap < 1 {
+		gap = 1
+	}
+	return statusStyle.Render(left + status + strings.Repeat(" ", gap) + right)
+}
@@ -0,0 +1,89 @@
+// Package status provides implementation of the status CLI-command.
+package status
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
+)
+
+// timeFormat is a format of the times printed by the command.
+const timeFormat = "2006-01-02 15:04:05"
+
+var (
+	// localStore is a local store used for a command implementation.
+	localStore service.LocalStore
+	// StatusCmd represents the status command
+	StatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "status command",
+		Long: `The status command shows the state of the local store: the time of the last sync,
+the number of records in every collection and the writes made while the server
+was unavailable. The pending writes are sent with the next sync or write.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			syncedAt, err := localStore.SyncedAt()
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			data, err := localStore.Load()
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			ops, err := localStore.Pending()
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			fmt.Printf("Local store: %v\n", cmd.Flag("file").Value.String())
+			if syncedAt.IsZero() {
+				fmt.Println("Synced at: never")
+			} else {
+				fmt.Printf("Synced at: %v\n", syncedAt.Local().Format(timeFormat))
+			}
+			counts := make([]string, 0, len(models.AllowedCollectionNames))
+			for _, collectionName := range models.AllowedCollectionNames {
+				counts = append(counts, fmt.Sprintf("%v=%d", collectionName, data.Count(collectionName)))
+			}
+			fmt.Printf("Records: %v\n", strings.Join(counts, " "))
+			fmt.Printf("Pending operations: %d\n", len(ops))
+			for i, op := range ops {
+				fmt.Printf(
+					"  %d. %v %v %v id=%v\n",
+					i+1,
+					op.QueuedAt.Local().Format(timeFormat),
+					op.Op,
+					op.Collection,
+					op.RecordID.Hex(),
+				)
+			}
+			return nil
+		},
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if _, err := profile.Apply(cmd); err != nil {
+				log.Fatalf("Error while loading the profile: %v", err)
+			}
+			localStore = service.NewLocalStore(
+				cmd.Flag("file").Value.String(),
+				cmd.Flag("key").Value.String(),
+			)
+		},
+	}
+)
+
+func init() {
+	StatusCmd.PersistentFlags().
+		StringP("file", "f", "", "filename of the local store (default: from the profile)")
+	StatusCmd.PersistentFlags().StringP("key", "k", "", "key of the local store")
+	profile.MarkFileFlag(StatusCmd, "file")
+	for _, flag := range []string{"file", "key"} {
+		StatusCmd.MarkPersistentFlagRequired(flag)
+	}
+}
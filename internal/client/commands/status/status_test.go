@@ -0,0 +1,52 @@
+package status
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/commands/cotesting"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
+	"github.com/blokhinnv/gophkeeper/internal/client/service/mock"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestStatusCommand(t *testing.T) {
+	t.Setenv(profile.DirEnv, t.TempDir())
+	StatusCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		store := mock.NewMockLocalStore(mockCtrl)
+		localStore = store
+		if cmd.Flag("key").Value.String() != "correctkey" {
+			store.EXPECT().SyncedAt().AnyTimes().Return(time.Time{}, fmt.Errorf("wrong key"))
+			return
+		}
+		store.EXPECT().SyncedAt().AnyTimes().Return(time.Now(), nil)
+		store.EXPECT().Load().AnyTimes().Return(&clientModels.SyncResponse{
+			Text: []models.TextRecord{{RecordID: models.NewRandomObjectID(), Data: "some text"}},
+		}, nil)
+		store.EXPECT().Pending().AnyTimes().Return([]clientModels.PendingOp{{
+			Seq:        1,
+			Op:         models.OpCreate,
+			Collection: models.TextCollection,
+			RecordID:   models.NewRandomObjectID(),
+			QueuedAt:   time.Now(),
+		}}, nil)
+	}
+
+	rootCmd := StatusCmd
+	t.Run("ok", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "--file=fname", "--key=correctkey")
+		assert.NoError(t, err)
+	})
+	t.Run("bad_key", func(t *testing.T) {
+		err := cotesting.ExecuteCommandC(rootCmd, "--file=fname", "--key=badkey")
+		assert.Error(t, err)
+	})
+}
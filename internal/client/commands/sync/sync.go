@@ -3,18 +3,19 @@ package sync
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
 	"github.com/blokhinnv/gophkeeper/internal/client/service"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
 )
 
 var (
 	// syncService is a sync service used for a command implementation.
 	syncService service.SyncService
-	// encryptService is a encrypt service used for a command implementation.
-	encryptService service.EncryptService
 	// SyncCmd represents the sync command
 	SyncCmd = &cobra.Command{
 		Use:   "sync",
@@ -22,10 +23,14 @@ var (
 		Long: `The SyncCmd command performs a synchronization operation between
 the client and the remote storage service. It accepts the "token", "key",
 and "file" flags to authenticate and encrypt the data, respectively.
-It also requires the "collection" flag to be set to a list of collections to sync.`,
+It also requires the "collection" flag to be set to a list of collections to sync.
+The data is saved to the local store in the file which also keeps the writes
+made while the server was unavailable; they are sent to the server first.
+All the collections are synced with the changes made since the previous sync.
+The token and the file are taken from the profile when omitted, and the file
+is saved to the profile for the other commands.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			token := cmd.Flag("token").Value.String()
-			key := cmd.Flag("key").Value.String()
 			file := cmd.Flag("file").Value.String()
 			collectionStringSlice, err := cmd.Flags().GetStringSlice("collection")
 			if err != nil {
@@ -42,30 +47,54 @@ It also requires the "collection" flag to be set to a list of collections to syn
 				}
 				collections = append(collections, c)
 			}
-			resp, err := syncService.Sync(token, collections)
-			if err != nil {
+			// the synced data is saved to the local store by the service
+			if _, err := syncService.Sync(token, collections); err != nil {
 				fmt.Println(err)
 				return err
 			}
-			err = encryptService.ToEncryptedFile(resp, file, key)
-			if err != nil {
+			if err := rememberFile(cmd, file); err != nil {
 				fmt.Println(err)
 				return err
 			}
-			return err
+			return nil
 		},
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if _, err := profile.Apply(cmd); err != nil {
+				log.Fatalf("Error while loading the profile: %v", err)
+			}
 			baseURL := cmd.Flag("server").Value.String()
-			syncService = service.NewSyncService(baseURL)
-			encryptService = service.NewEncryptService()
+			transport := cmd.Flag("transport").Value.String()
+			var err error
+			syncService, err = service.NewSyncServiceWithTransport(transport, baseURL)
+			if err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			storageService, err := service.NewStorageServiceWithTransport(transport, baseURL)
+			if err != nil {
+				log.Fatalf("Error while creating a service: %v", err)
+			}
+			if password := cmd.Flag("master-password").Value.String(); password != "" {
+				vault, err := service.NewVaultWithTransport(transport, baseURL, password)
+				if err != nil {
+					log.Fatalf("Error while creating a service: %v", err)
+				}
+				syncService = service.NewE2ESyncService(syncService, vault)
+				storageService = service.NewE2EStorageService(storageService, vault)
+			}
+			localStore := service.NewLocalStore(
+				cmd.Flag("file").Value.String(),
+				cmd.Flag("key").Value.String(),
+			)
+			syncService = service.NewOfflineSyncService(syncService, storageService, localStore)
 		},
 	}
 )
 
 func init() {
-	SyncCmd.PersistentFlags().StringP("token", "t", "", "jwt token")
-	SyncCmd.PersistentFlags().StringP("file", "f", "", "filename to save synced data")
-	SyncCmd.PersistentFlags().StringP("key", "k", "", "key for data encryption")
+	SyncCmd.PersistentFlags().StringP("token", "t", "", "jwt token (default: from the profile)")
+	SyncCmd.PersistentFlags().
+		StringP("file", "f", "", "filename of the local store (default: from the profile)")
+	SyncCmd.PersistentFlags().StringP("key", "k", "", "key of the local store")
 	SyncCmd.PersistentFlags().
 		StringSliceP(
 			"collection",
@@ -75,10 +104,29 @@ func init() {
 				string(models.TextCollection),
 				string(models.CredentialsCollection),
 				string(models.BinaryCollection),
+				string(models.OTPCollection),
 			},
 			"collections to sync",
 		)
+	profile.MarkFileFlag(SyncCmd, "file")
 	for _, flag := range []string{"token", "file", "key"} {
 		SyncCmd.MarkPersistentFlagRequired(flag)
 	}
 }
+
+// rememberFile saves the absolute path of the local store to the profile.
+func rememberFile(cmd *cobra.Command, file string) error {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	p, err := profile.Load(profile.Name(cmd))
+	if err != nil {
+		return err
+	}
+	if p.File == path {
+		return nil
+	}
+	p.File = path
+	return profile.Save(profile.Name(cmd), p)
+}
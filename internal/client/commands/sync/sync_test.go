@@ -2,13 +2,16 @@ package sync
 
 import (
 	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/blokhinnv/gophkeeper/internal/client/commands/cotesting"
+	"github.com/blokhinnv/gophkeeper/internal/client/profile"
 	"github.com/blokhinnv/gophkeeper/internal/client/service/mock"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
 )
@@ -18,11 +21,12 @@ func init() {
 }
 
 func TestSyncCommand(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(profile.DirEnv, dir)
 	SyncCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		syncService = mock.NewMockSyncService(mockCtrl)
-		encryptService = mock.NewMockEncryptService(mockCtrl)
 		syncService.(*mock.MockSyncService).EXPECT().
 			Sync(gomock.Eq("sometoken"), gomock.Eq([]models.CollectionName{"text"})).
 			AnyTimes().
@@ -32,11 +36,6 @@ func TestSyncCommand(t *testing.T) {
 			Sync(gomock.Eq("sometoken"), gomock.Eq([]models.CollectionName{"binary"})).
 			AnyTimes().
 			Return(nil, fmt.Errorf("unable to sync"))
-
-		encryptService.(*mock.MockEncryptService).EXPECT().
-			ToEncryptedFile(gomock.Any(), gomock.Eq("fname"), gomock.Eq("somekey")).
-			AnyTimes().
-			Return(nil)
 	}
 
 	rootCmd := SyncCmd
@@ -51,6 +50,12 @@ func TestSyncCommand(t *testing.T) {
 			"--collection=text",
 		)
 		assert.NoError(t, err)
+
+		// the local store is remembered for the other commands
+		p, err := profile.Load(profile.DefaultName)
+		require.NoError(t, err)
+		assert.True(t, filepath.IsAbs(p.File))
+		assert.Equal(t, "fname", filepath.Base(p.File))
 	})
 	t.Run("no_sync", func(t *testing.T) {
 		defer rootCmd.ResetFlags()
@@ -1,8 +1,56 @@
 // Package errors contains predefined client errors.
 package errors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
 
 // ErrServerUnavailable is an error variable that represents a situation where
 // the server is not currently available to handle a request.
 var ErrServerUnavailable = errors.New("server unavailable")
+
+// ErrWrongMasterPassword is returned when the key derived from the master
+// password can't decrypt the key check of the vault.
+var ErrWrongMasterPassword = errors.New("wrong master password")
+
+// ErrWrongKey is returned when the local store can't be decrypted with the key.
+var ErrWrongKey = errors.New("wrong key of the local store")
+
+// ErrNotLocalStore is returned when the file is not a local store,
+// e.g. it is a dump written by the sync command of older clients.
+var ErrNotLocalStore = errors.New("file is not a local store")
+
+// ErrWrongExportPassword is returned when the imported export can't be
+// decrypted with the password.
+var ErrWrongExportPassword = errors.New("wrong password of the export")
+
+// ErrWrongArchivePassword is returned when the key derived from the password
+// can't decrypt the key check of the archive.
+var ErrWrongArchivePassword = errors.New("wrong password of the archive")
+
+// ErrCorruptedArchive is returned when the content of the archive doesn't
+// match its manifest.
+var ErrCorruptedArchive = errors.New("corrupted archive")
+
+// ErrOperationRejected is returned when the server rejects a queued operation.
+var ErrOperationRejected = errors.New("queued operation rejected by the server")
+
+// ConflictError is returned when the record was modified by another client
+// after the expected version. It holds the current copy of the record on the server.
+type ConflictError struct {
+	Current models.UntypedRecord
+}
+
+// Error returns the message with the current version of the record.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: the current version is %v", srvErrors.ErrVersionConflict, e.Current.Version)
+}
+
+// Unwrap returns ErrVersionConflict of the server.
+func (e *ConflictError) Unwrap() error {
+	return srvErrors.ErrVersionConflict
+}
@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// Types of the Bitwarden items.
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+)
+
+// bitwardenExport is the unencrypted JSON export of Bitwarden.
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []struct {
+		Type     int    `json:"type"`
+		Name     string `json:"name"`
+		Notes    string `json:"notes"`
+		FolderID string `json:"folderId"`
+		Login    struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			URIs     []struct {
+				URI string `json:"uri"`
+			} `json:"uris"`
+		} `json:"login"`
+		Card struct {
+			CardholderName string `json:"cardholderName"`
+			Number         string `json:"number"`
+			ExpMonth       string `json:"expMonth"`
+			ExpYear        string `json:"expYear"`
+			Code           string `json:"code"`
+		} `json:"card"`
+	} `json:"items"`
+}
+
+// bitwardenParser reads the items of the unencrypted JSON export of Bitwarden.
+type bitwardenParser struct{}
+
+// Parse returns the items of the export. The identities can't be imported.
+func (p *bitwardenParser) Parse(data []byte) ([]Entry, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+	if export.Encrypted {
+		return nil, errors.New("the encrypted Bitwarden exports are not supported")
+	}
+	folders := make(map[string]string, len(export.Folders))
+	for _, f := range export.Folders {
+		folders[f.ID] = f.Name
+	}
+	entries := make([]Entry, 0, len(export.Items))
+	for _, item := range export.Items {
+		f := entryFields{title: item.Name, folder: folders[item.FolderID], notes: item.Notes}
+		switch item.Type {
+		case bitwardenLogin:
+			f.username, f.password = item.Login.Username, item.Login.Password
+			if len(item.Login.URIs) > 0 {
+				f.url = item.Login.URIs[0].URI
+			}
+			entries = append(entries, loginEntry(f))
+		case bitwardenSecureNote:
+			entries = append(entries, noteEntry(f))
+		case bitwardenCard:
+			month, _ := strconv.Atoi(item.Card.ExpMonth)
+			year, _ := strconv.Atoi(item.Card.ExpYear)
+			md := f.metadata()
+			if item.Card.CardholderName != "" {
+				md[CardholderKey] = item.Card.CardholderName
+			}
+			entries = append(entries, Entry{
+				Title:      item.Name,
+				Collection: models.CardCollection,
+				Data: models.CardInfo{
+					CardNumber:     item.Card.Number,
+					CVV:            item.Card.Code,
+					ExpirationDate: expirationDate(month, year),
+				},
+				Metadata: md,
+			})
+		case bitwardenIdentity:
+			entries = append(entries, unsupportedEntry(item.Name, "identities are not supported"))
+		default:
+			entries = append(entries, unsupportedEntry(item.Name, "unknown item type"))
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestBitwardenParser(t *testing.T) {
+	data := `{
+		"encrypted": false,
+		"folders": [{"id": "f1", "name": "Work"}],
+		"items": [
+			{
+				"type": 1, "name": "GitHub", "folderId": "f1", "notes": "2FA is on",
+				"login": {
+					"username": "john", "password": "secret",
+					"uris": [{"match": null, "uri": "https://github.com"}]
+				}
+			},
+			{"type": 2, "name": "Wi-Fi", "notes": "office password", "secureNote": {"type": 0}},
+			{
+				"type": 3, "name": "Visa",
+				"card": {
+					"cardholderName": "John Doe", "brand": "Visa", "number": "4111111111111111",
+					"expMonth": "7", "expYear": "2027", "code": "123"
+				}
+			},
+			{"type": 4, "name": "Passport", "identity": {"firstName": "John"}}
+		]
+	}`
+	entries, err := (&bitwardenParser{}).Parse([]byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{
+			Title:      "GitHub",
+			Collection: models.CredentialsCollection,
+			Data:       models.CredentialInfo{Login: "john", Password: "secret"},
+			Metadata: models.Metadata{
+				TitleKey:  "GitHub",
+				URLKey:    "https://github.com",
+				FolderKey: "Work",
+				NotesKey:  "2FA is on",
+			},
+		},
+		{
+			Title:      "Wi-Fi",
+			Collection: models.TextCollection,
+			Data:       "office password",
+			Metadata:   models.Metadata{TitleKey: "Wi-Fi"},
+		},
+		{
+			Title:      "Visa",
+			Collection: models.CardCollection,
+			Data: models.CardInfo{
+				CardNumber:     "4111111111111111",
+				CVV:            "123",
+				ExpirationDate: "07/27",
+			},
+			Metadata: models.Metadata{TitleKey: "Visa", CardholderKey: "John Doe"},
+		},
+		{Title: "Passport", Unsupported: "identities are not supported"},
+	}, entries)
+
+	t.Run("encrypted", func(t *testing.T) {
+		_, err := (&bitwardenParser{}).Parse([]byte(`{"encrypted": true, "items": []}`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// csvParser reads the logins from a CSV export with a header. The columns
+// are found by their names; a field may have several names.
+type csvParser struct {
+	title, username, password, url, folder, notes, tags []string
+}
+
+var (
+	// chromeCSVParser reads the passwords exported by Chrome.
+	chromeCSVParser = &csvParser{
+		title:    []string{"name"},
+		username: []string{"username"},
+		password: []string{"password"},
+		url:      []string{"url"},
+		notes:    []string{"note"},
+	}
+	// firefoxCSVParser reads the logins exported by Firefox. The logins have
+	// no names, so the host of the URL is the title.
+	firefoxCSVParser = &csvParser{
+		username: []string{"username"},
+		password: []string{"password"},
+		url:      []string{"url"},
+	}
+	// onePasswordCSVParser reads the items exported by 1Password in CSV.
+	onePasswordCSVParser = &csvParser{
+		title:    []string{"title"},
+		username: []string{"username"},
+		password: []string{"password"},
+		url:      []string{"url", "website"},
+		notes:    []string{"notes", "notesplain"},
+		tags:     []string{"tags"},
+	}
+)
+
+// Parse returns the logins of the CSV export.
+func (p *csvParser) Parse(data []byte) ([]Entry, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no header in the CSV export")
+	}
+	columns := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := p.column(columns, p.password); !ok {
+		return nil, fmt.Errorf("no password column in the CSV header: %v", rows[0])
+	}
+	entries := make([]Entry, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		value := func(names []string) string {
+			if i, ok := p.column(columns, names); ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		f := entryFields{
+			title:    value(p.title),
+			username: value(p.username),
+			password: value(p.password),
+			url:      value(p.url),
+			folder:   value(p.folder),
+			notes:    value(p.notes),
+			tags:     value(p.tags),
+		}
+		if f.title == "" {
+			f.title = hostname(f.url)
+		}
+		entries = append(entries, loginEntry(f))
+	}
+	return entries, nil
+}
+
+// column returns the index of the first found column with one of the names.
+func (p *csvParser) column(columns map[string]int, names []string) (int, bool) {
+	for _, name := range names {
+		if i, ok := columns[name]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// hostname returns the host of the URL; the URL itself if it can't be parsed.
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestCSVParsers(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser Parser
+		data   string
+		want   []Entry
+	}{
+		{
+			name:   "chrome",
+			parser: chromeCSVParser,
+			data: "\ufeffname,url,username,password,note\n" +
+				"github.com,https://github.com/login,john,secret,2FA is on\n",
+			want: []Entry{{
+				Title:      "github.com",
+				Collection: models.CredentialsCollection,
+				Data:       models.CredentialInfo{Login: "john", Password: "secret"},
+				Metadata: models.Metadata{
+					TitleKey: "github.com",
+					URLKey:   "https://github.com/login",
+					NotesKey: "2FA is on",
+				},
+			}},
+		},
+		{
+			name:   "firefox",
+			parser: firefoxCSVParser,
+			data: `"url","username","password","httpRealm","formActionOrigin","guid","timeCreated"` + "\n" +
+				`"https://accounts.google.com","john@gmail.com","secret",,"https://accounts.google.com","{1}","1683700000000"` + "\n",
+			want: []Entry{{
+				Title:      "accounts.google.com",
+				Collection: models.CredentialsCollection,
+				Data:       models.CredentialInfo{Login: "john@gmail.com", Password: "secret"},
+				Metadata: models.Metadata{
+					TitleKey: "accounts.google.com",
+					URLKey:   "https://accounts.google.com",
+				},
+			}},
+		},
+		{
+			name:   "1password",
+			parser: onePasswordCSVParser,
+			data: "Title,Url,Username,Password,OTPAuth,Favorite,Archived,Tags,Notes\n" +
+				"GitHub,https://github.com,john,secret,,false,false,dev,\n" +
+				"Door code,,,,,false,false,,1234\n",
+			want: []Entry{
+				{
+					Title:      "GitHub",
+					Collection: models.CredentialsCollection,
+					Data:       models.CredentialInfo{Login: "john", Password: "secret"},
+					Metadata: models.Metadata{
+						TitleKey: "GitHub",
+						URLKey:   "https://github.com",
+						TagsKey:  "dev",
+					},
+				},
+				{
+					Title:      "Door code",
+					Collection: models.TextCollection,
+					Data:       "1234",
+					Metadata:   models.Metadata{TitleKey: "Door code"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := tt.parser.Parse([]byte(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, entries)
+		})
+	}
+	t.Run("no_password_column", func(t *testing.T) {
+		_, err := chromeCSVParser.Parse([]byte("name,url\ngithub,https://github.com\n"))
+		assert.Error(t, err)
+	})
+	t.Run("empty", func(t *testing.T) {
+		_, err := chromeCSVParser.Parse([]byte(""))
+		assert.Error(t, err)
+	})
+}
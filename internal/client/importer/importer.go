@@ -0,0 +1,152 @@
+// Package importer converts the exports of other password managers into records.
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// Metadata keys the imported fields are saved to.
+const (
+	TitleKey  = "title"
+	URLKey    = "url"
+	FolderKey = "folder"
+	NotesKey  = "notes"
+	TagsKey   = "tags"
+	// CardholderKey is the key of the name of the card owner.
+	CardholderKey = "cardholder"
+)
+
+// Entry is a record read from an export.
+type Entry struct {
+	Title      string                // Title is a name of the entry in the export.
+	Collection models.CollectionName // Collection is the collection the record is saved to.
+	// Data is models.TextInfo, models.CredentialInfo, models.CardInfo or
+	// models.BinaryInfo with the file name only.
+	Data     any
+	Content  []byte          // Content is the content of an attachment.
+	Metadata models.Metadata // Metadata keeps the URL, the folder, the notes and the tags of the entry.
+	// Unsupported explains why the entry can't be imported; empty if it can.
+	Unsupported string
+}
+
+// Options are the options of the parsers.
+type Options struct {
+	Password string // Password is the password of the encrypted exports.
+}
+
+// Parser reads the entries from an export of a password manager.
+type Parser interface {
+	// Parse returns the entries of the export in the order they are found.
+	Parse(data []byte) ([]Entry, error)
+}
+
+// parsers are the constructors of the parsers by the names of the formats.
+var parsers = map[string]func(opts Options) Parser{
+	"keepass-kdbx":   func(opts Options) Parser { return &kdbxParser{password: opts.Password} },
+	"keepass-xml":    func(opts Options) Parser { return &keepassXMLParser{} },
+	"bitwarden-json": func(opts Options) Parser { return &bitwardenParser{} },
+	"1password-1pux": func(opts Options) Parser { return &onePUXParser{} },
+	"1password-csv":  func(opts Options) Parser { return onePasswordCSVParser },
+	"chrome-csv":     func(opts Options) Parser { return chromeCSVParser },
+	"firefox-csv":    func(opts Options) Parser { return firefoxCSVParser },
+}
+
+// NewParser returns the parser of the format.
+func NewParser(format string, opts Options) (Parser, error) {
+	newParser, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf(
+			"unknown format %q, expected one of: %v",
+			format,
+			strings.Join(Formats(), ", "),
+		)
+	}
+	return newParser(opts), nil
+}
+
+// Formats returns the names of the supported formats.
+func Formats() []string {
+	formats := make([]string, 0, len(parsers))
+	for f := range parsers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// entryFields are the common fields of the logins of the password managers.
+type entryFields struct {
+	title, username, password, url, folder, notes, tags string
+}
+
+// metadata returns the metadata of the entry without the empty values.
+func (f entryFields) metadata() models.Metadata {
+	md := make(models.Metadata)
+	for k, v := range map[string]string{
+		TitleKey:  f.title,
+		URLKey:    f.url,
+		FolderKey: f.folder,
+		NotesKey:  f.notes,
+		TagsKey:   f.tags,
+	} {
+		if v = strings.TrimSpace(v); v != "" {
+			md[k] = v
+		}
+	}
+	return md
+}
+
+// loginEntry returns a credentials entry of the login. A login with neither
+// a username nor a password is a secure note.
+func loginEntry(f entryFields) Entry {
+	if f.username == "" && f.password == "" {
+		return noteEntry(f)
+	}
+	return Entry{
+		Title:      f.title,
+		Collection: models.CredentialsCollection,
+		Data:       models.CredentialInfo{Login: f.username, Password: f.password},
+		Metadata:   f.metadata(),
+	}
+}
+
+// noteEntry returns a text entry which keeps the notes.
+func noteEntry(f entryFields) Entry {
+	notes := f.notes
+	f.notes = ""
+	return Entry{
+		Title:      f.title,
+		Collection: models.TextCollection,
+		Data:       notes,
+		Metadata:   f.metadata(),
+	}
+}
+
+// attachmentEntry returns a binary entry of the attachment of the entry.
+func attachmentEntry(f entryFields, fileName string, content []byte) Entry {
+	return Entry{
+		Title:      f.title,
+		Collection: models.BinaryCollection,
+		Data:       models.BinaryInfo{FileName: fileName},
+		Content:    content,
+		Metadata:   entryFields{title: f.title, folder: f.folder}.metadata(),
+	}
+}
+
+// unsupportedEntry returns an entry which can't be imported.
+func unsupportedEntry(title, reason string) Entry {
+	return Entry{Title: title, Unsupported: reason}
+}
+
+// expirationDate returns the expiration date of a card in the MM/YY format.
+// An empty string is returned if the month or the year is unknown.
+func expirationDate(month, year int) string {
+	if month < 1 || month > 12 || year <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%02d/%02d", month, year%100)
+}
@@ -0,0 +1,599 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/salsa20/salsa"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+)
+
+// Signatures of a KeePass database file.
+const (
+	kdbxSignature1 = 0x9AA2D903
+	kdbxSignature2 = 0xB54BFB67
+)
+
+// IDs of the fields of the outer header.
+const (
+	kdbxEndOfHeader         = 0
+	kdbxCipherID            = 2
+	kdbxCompressionFlags    = 3
+	kdbxMasterSeed          = 4
+	kdbxTransformSeed       = 5
+	kdbxTransformRounds     = 6
+	kdbxEncryptionIV        = 7
+	kdbxProtectedStreamKey  = 8
+	kdbxStreamStartBytes    = 9
+	kdbxInnerRandomStreamID = 10
+	kdbxKdfParameters       = 11
+)
+
+// IDs of the fields of the inner header of KDBX 4.
+const (
+	kdbxInnerStreamID  = 1
+	kdbxInnerStreamKey = 2
+	kdbxInnerBinary    = 3
+)
+
+// IDs of the key streams which protect the values of the document.
+const (
+	kdbxSalsa20InnerStream  = 2
+	kdbxChaCha20InnerStream = 3
+)
+
+// kdbxVariantDictionaryEnd is the type of the last item of a variant dictionary.
+const kdbxVariantDictionaryEnd = 0
+
+// UUIDs of the ciphers and the key derivation functions.
+var (
+	kdbxAES256   = []byte{0x31, 0xc1, 0xf2, 0xe6, 0xbf, 0x71, 0x43, 0x50, 0xbe, 0x58, 0x05, 0x21, 0x6a, 0xfc, 0x5a, 0xff}
+	kdbxChaCha20 = []byte{0xd6, 0x03, 0x8a, 0x2b, 0x8b, 0x6f, 0x4c, 0xb5, 0xa5, 0x24, 0x33, 0x9a, 0x31, 0xdb, 0xb5, 0x9a}
+	kdbxAESKDF   = []byte{0xc9, 0xd9, 0xf3, 0x9a, 0x62, 0x8a, 0x44, 0x60, 0xbf, 0x74, 0x0d, 0x08, 0xc1, 0x8a, 0x4f, 0xea}
+	kdbxArgon2d  = []byte{0xef, 0x63, 0x6d, 0xdf, 0x8c, 0x29, 0x44, 0x4b, 0x91, 0xf7, 0xa9, 0xa4, 0x03, 0xe3, 0x0c, 0x0a}
+	kdbxArgon2id = []byte{0x9e, 0x29, 0x8b, 0x19, 0x56, 0xdb, 0x47, 0x73, 0xb2, 0x3d, 0xfc, 0x3e, 0xc6, 0xf0, 0xa1, 0xe6}
+	// kdbxSalsa20Nonce is the nonce of the Salsa20 inner stream.
+	kdbxSalsa20Nonce = []byte{0xe8, 0x30, 0x09, 0x4b, 0x97, 0x20, 0x5d, 0x2a}
+)
+
+// errBadKDBX is returned when the file is not a KeePass database.
+var errBadKDBX = errors.New("not a KeePass database")
+
+// kdbxHeader is the outer header of a KeePass database.
+type kdbxHeader struct {
+	major      uint16
+	fields     map[byte][]byte
+	kdf        map[string][]byte // kdf holds the parameters of the key derivation of KDBX 4.
+	raw        []byte            // raw is the header as it is in the file.
+	compressed bool
+}
+
+// kdbxParser reads the entries of a KeePass database of KDBX 3.1 or KDBX 4
+// encrypted with the password. The databases protected with a key file are
+// not supported, and neither is the Argon2d key derivation.
+type kdbxParser struct {
+	password string
+}
+
+// Parse decrypts the database and returns its entries.
+func (p *kdbxParser) Parse(data []byte) ([]Entry, error) {
+	r := bytes.NewReader(data)
+	h, err := readKDBXHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	passwordHash := sha256.Sum256([]byte(p.password))
+	compositeKey := sha256.Sum256(passwordHash[:])
+	transformedKey, err := h.transformKey(compositeKey[:])
+	if err != nil {
+		return nil, err
+	}
+	var (
+		payload   []byte
+		streamID  uint32
+		streamKey []byte
+		binaries  [][]byte
+	)
+	if h.major == 3 {
+		payload, err = h.readV3Payload(r, transformedKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(h.fields[kdbxInnerRandomStreamID]) != 4 {
+			return nil, fmt.Errorf("%w: no inner stream id", errBadKDBX)
+		}
+		streamID = binary.LittleEndian.Uint32(h.fields[kdbxInnerRandomStreamID])
+		streamKey = h.fields[kdbxProtectedStreamKey]
+	} else {
+		payload, err = h.readV4Payload(r, transformedKey)
+		if err != nil {
+			return nil, err
+		}
+		var n int
+		streamID, streamKey, binaries, n, err = readKDBXInnerHeader(payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = payload[n:]
+		if binaries == nil {
+			binaries = [][]byte{}
+		}
+	}
+	stream, err := kdbxInnerStream(streamID, streamKey)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := unprotect(payload, stream)
+	if err != nil {
+		return nil, err
+	}
+	return (&keepassXMLParser{binaries: binaries}).Parse(doc)
+}
+
+// readKDBXHeader reads the outer header.
+func readKDBXHeader(r *bytes.Reader) (*kdbxHeader, error) {
+	var prefix struct {
+		Signature1, Signature2 uint32
+		Minor, Major           uint16
+	}
+	if err := binary.Read(r, binary.LittleEndian, &prefix); err != nil {
+		return nil, errBadKDBX
+	}
+	if prefix.Signature1 != kdbxSignature1 || prefix.Signature2 != kdbxSignature2 {
+		return nil, errBadKDBX
+	}
+	if prefix.Major != 3 && prefix.Major != 4 {
+		return nil, fmt.Errorf("KDBX %v is not supported", prefix.Major)
+	}
+	h := &kdbxHeader{major: prefix.Major, fields: make(map[byte][]byte)}
+	for {
+		id, err := r.ReadByte()
+		if err != nil {
+			return nil, errBadKDBX
+		}
+		var size uint32
+		if h.major == 3 {
+			var size16 uint16
+			err = binary.Read(r, binary.LittleEndian, &size16)
+			size = uint32(size16)
+		} else {
+			err = binary.Read(r, binary.LittleEndian, &size)
+		}
+		if err != nil || int64(size) > int64(r.Len()) {
+			return nil, errBadKDBX
+		}
+		value := make([]byte, size)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, errBadKDBX
+		}
+		if id == kdbxEndOfHeader {
+			break
+		}
+		h.fields[id] = value
+	}
+	h.raw = make([]byte, int(r.Size())-r.Len())
+	if _, err := r.ReadAt(h.raw, 0); err != nil {
+		return nil, errBadKDBX
+	}
+	if flags := h.fields[kdbxCompressionFlags]; len(flags) == 4 {
+		h.compressed = binary.LittleEndian.Uint32(flags) == 1
+	}
+	for _, id := range []byte{kdbxCipherID, kdbxMasterSeed, kdbxEncryptionIV} {
+		if len(h.fields[id]) == 0 {
+			return nil, fmt.Errorf("%w: no header field %v", errBadKDBX, id)
+		}
+	}
+	if h.major == 4 {
+		kdf, err := readVariantDictionary(h.fields[kdbxKdfParameters])
+		if err != nil {
+			return nil, err
+		}
+		h.kdf = kdf
+	}
+	return h, nil
+}
+
+// readVariantDictionary reads the dictionary of the parameters of KDBX 4.
+// The values are kept as they are in the file.
+func readVariantDictionary(data []byte) (map[string][]byte, error) {
+	r := bytes.NewReader(data)
+	var version uint16
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil || version>>8 != 1 {
+		return nil, fmt.Errorf("%w: bad kdf parameters", errBadKDBX)
+	}
+	dict := make(map[string][]byte)
+	for {
+		valueType, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad kdf parameters", errBadKDBX)
+		}
+		if valueType == kdbxVariantDictionaryEnd {
+			return dict, nil
+		}
+		var item [2][]byte
+		for i := range item {
+			var size int32
+			if err := binary.Read(r, binary.LittleEndian, &size); err != nil ||
+				size < 0 || int64(size) > int64(r.Len()) {
+				return nil, fmt.Errorf("%w: bad kdf parameters", errBadKDBX)
+			}
+			item[i] = make([]byte, size)
+			if _, err := io.ReadFull(r, item[i]); err != nil {
+				return nil, fmt.Errorf("%w: bad kdf parameters", errBadKDBX)
+			}
+		}
+		dict[string(item[0])] = item[1]
+	}
+}
+
+// kdfUint returns the unsigned integer parameter of the key derivation.
+func (h *kdbxHeader) kdfUint(name string) (uint64, error) {
+	switch v := h.kdf[name]; len(v) {
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(v)), nil
+	case 8:
+		return binary.LittleEndian.Uint64(v), nil
+	default:
+		return 0, fmt.Errorf("%w: bad kdf parameter %v", errBadKDBX, name)
+	}
+}
+
+// transformKey derives the key from the composite key of the credentials.
+func (h *kdbxHeader) transformKey(compositeKey []byte) ([]byte, error) {
+	if h.major == 3 {
+		rounds := h.fields[kdbxTransformRounds]
+		if len(rounds) != 8 {
+			return nil, fmt.Errorf("%w: no transform rounds", errBadKDBX)
+		}
+		return aesKDF(compositeKey, h.fields[kdbxTransformSeed], binary.LittleEndian.Uint64(rounds))
+	}
+	kdfID := h.kdf["$UUID"]
+	switch {
+	case bytes.Equal(kdfID, kdbxAESKDF):
+		rounds, err := h.kdfUint("R")
+		if err != nil {
+			return nil, err
+		}
+		return aesKDF(compositeKey, h.kdf["S"], rounds)
+	case bytes.Equal(kdfID, kdbxArgon2id):
+		iterations, err := h.kdfUint("I")
+		if err != nil {
+			return nil, err
+		}
+		memory, err := h.kdfUint("M")
+		if err != nil {
+			return nil, err
+		}
+		parallelism, err := h.kdfUint("P")
+		if err != nil {
+			return nil, err
+		}
+		if iterations > math.MaxUint32 || memory/1024 > math.MaxUint32 || parallelism > math.MaxUint8 {
+			return nil, fmt.Errorf("%w: bad argon2 parameters", errBadKDBX)
+		}
+		return argon2.IDKey(
+			compositeKey,
+			h.kdf["S"],
+			uint32(iterations),
+			uint32(memory/1024),
+			uint8(parallelism),
+			32,
+		), nil
+	case bytes.Equal(kdfID, kdbxArgon2d):
+		return nil, errors.New(
+			"the Argon2d key derivation is not supported, change it to Argon2id or AES-KDF or export the database to XML",
+		)
+	default:
+		return nil, fmt.Errorf("%w: unknown key derivation function", errBadKDBX)
+	}
+}
+
+// aesKDF transforms the key by encrypting it with AES the number of rounds.
+func aesKDF(key, seed []byte, rounds uint64) ([]byte, error) {
+	block, err := aes.NewCipher(seed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad transform seed", errBadKDBX)
+	}
+	k := make([]byte, len(key))
+	copy(k, key)
+	for i := uint64(0); i < rounds; i++ {
+		block.Encrypt(k[:16], k[:16])
+		block.Encrypt(k[16:], k[16:])
+	}
+	sum := sha256.Sum256(k)
+	return sum[:], nil
+}
+
+// decrypt decrypts the payload with the cipher of the header.
+func (h *kdbxHeader) decrypt(ciphertext, transformedKey []byte) ([]byte, error) {
+	key := sha256.Sum256(append(append([]byte{}, h.fields[kdbxMasterSeed]...), transformedKey...))
+	iv := h.fields[kdbxEncryptionIV]
+	switch cipherID := h.fields[kdbxCipherID]; {
+	case bytes.Equal(cipherID, kdbxAES256):
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return nil, err
+		}
+		if len(iv) != block.BlockSize() || len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+			return nil, fmt.Errorf("%w: bad encrypted payload", errBadKDBX)
+		}
+		plaintext := make([]byte, len(ciphertext))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+		padding := int(plaintext[len(plaintext)-1])
+		if padding == 0 || padding > block.BlockSize() {
+			return nil, clientErr.ErrWrongExportPassword
+		}
+		return plaintext[:len(plaintext)-padding], nil
+	case bytes.Equal(cipherID, kdbxChaCha20):
+		stream, err := chacha20.NewUnauthenticatedCipher(key[:], iv)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errBadKDBX, err)
+		}
+		plaintext := make([]byte, len(ciphertext))
+		stream.XORKeyStream(plaintext, ciphertext)
+		return plaintext, nil
+	default:
+		return nil, errors.New("the cipher of the database is not supported")
+	}
+}
+
+// decompress decompresses the payload if it is compressed.
+func (h *kdbxHeader) decompress(payload []byte) ([]byte, error) {
+	if !h.compressed {
+		return payload, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// readV3Payload decrypts the payload of KDBX 3.1 and joins its hashed blocks.
+func (h *kdbxHeader) readV3Payload(r *bytes.Reader, transformedKey []byte) ([]byte, error) {
+	ciphertext := make([]byte, r.Len())
+	if _, err := io.ReadFull(r, ciphertext); err != nil {
+		return nil, err
+	}
+	plaintext, err := h.decrypt(ciphertext, transformedKey)
+	if err != nil {
+		return nil, err
+	}
+	startBytes := h.fields[kdbxStreamStartBytes]
+	if len(startBytes) == 0 || !bytes.HasPrefix(plaintext, startBytes) {
+		return nil, clientErr.ErrWrongExportPassword
+	}
+	blocks := bytes.NewReader(plaintext[len(startBytes):])
+	var payload []byte
+	for {
+		var block struct {
+			Index uint32
+			Hash  [32]byte
+			Size  uint32
+		}
+		if err := binary.Read(blocks, binary.LittleEndian, &block); err != nil ||
+			int64(block.Size) > int64(blocks.Len()) {
+			return nil, fmt.Errorf("%w: bad block", errBadKDBX)
+		}
+		if block.Size == 0 {
+			break
+		}
+		data := make([]byte, block.Size)
+		if _, err := io.ReadFull(blocks, data); err != nil {
+			return nil, err
+		}
+		if sha256.Sum256(data) != block.Hash {
+			return nil, fmt.Errorf("%w: bad block hash", errBadKDBX)
+		}
+		payload = append(payload, data...)
+	}
+	return h.decompress(payload)
+}
+
+// kdbxBlockHMAC returns the HMAC of the block of KDBX 4 with the index.
+func kdbxBlockHMAC(hmacKey []byte, index uint64, data ...[]byte) []byte {
+	var indexBytes [8]byte
+	binary.LittleEndian.PutUint64(indexBytes[:], index)
+	blockKey := sha512.Sum512(append(indexBytes[:], hmacKey...))
+	mac := hmac.New(sha256.New, blockKey[:])
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// readV4Payload checks the header and the blocks of KDBX 4 with their HMACs
+// and decrypts the payload.
+func (h *kdbxHeader) readV4Payload(r *bytes.Reader, transformedKey []byte) ([]byte, error) {
+	var hash, headerHMAC [32]byte
+	if _, err := io.ReadFull(r, hash[:]); err != nil {
+		return nil, errBadKDBX
+	}
+	if _, err := io.ReadFull(r, headerHMAC[:]); err != nil {
+		return nil, errBadKDBX
+	}
+	if sha256.Sum256(h.raw) != hash {
+		return nil, fmt.Errorf("%w: bad header hash", errBadKDBX)
+	}
+	hmacKey := sha512.Sum512(
+		append(append(append([]byte{}, h.fields[kdbxMasterSeed]...), transformedKey...), 1),
+	)
+	if !hmac.Equal(kdbxBlockHMAC(hmacKey[:], math.MaxUint64, h.raw), headerHMAC[:]) {
+		return nil, clientErr.ErrWrongExportPassword
+	}
+	var ciphertext []byte
+	for index := uint64(0); ; index++ {
+		var block struct {
+			HMAC [32]byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &block); err != nil ||
+			int64(block.Size) > int64(r.Len()) {
+			return nil, fmt.Errorf("%w: bad block", errBadKDBX)
+		}
+		data := make([]byte, block.Size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		var size [4]byte
+		binary.LittleEndian.PutUint32(size[:], block.Size)
+		if !hmac.Equal(kdbxBlockHMAC(hmacKey[:], index, size[:], data), block.HMAC[:]) {
+			return nil, fmt.Errorf("%w: bad block hmac", errBadKDBX)
+		}
+		if block.Size == 0 {
+			break
+		}
+		ciphertext = append(ciphertext, data...)
+	}
+	plaintext, err := h.decrypt(ciphertext, transformedKey)
+	if err != nil {
+		return nil, err
+	}
+	return h.decompress(plaintext)
+}
+
+// readKDBXInnerHeader reads the inner header of KDBX 4 which precedes the XML
+// document. The length of the header is returned.
+func readKDBXInnerHeader(
+	payload []byte,
+) (streamID uint32, streamKey []byte, binaries [][]byte, n int, err error) {
+	r := bytes.NewReader(payload)
+	for {
+		var field struct {
+			ID   byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &field); err != nil ||
+			int64(field.Size) > int64(r.Len()) {
+			return 0, nil, nil, 0, fmt.Errorf("%w: bad inner header", errBadKDBX)
+		}
+		value := make([]byte, field.Size)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return 0, nil, nil, 0, err
+		}
+		switch field.ID {
+		case kdbxEndOfHeader:
+			return streamID, streamKey, binaries, len(payload) - r.Len(), nil
+		case kdbxInnerStreamID:
+			if len(value) != 4 {
+				return 0, nil, nil, 0, fmt.Errorf("%w: bad inner stream id", errBadKDBX)
+			}
+			streamID = binary.LittleEndian.Uint32(value)
+		case kdbxInnerStreamKey:
+			streamKey = value
+		case kdbxInnerBinary:
+			// the first byte holds the flags of the attachment
+			if len(value) == 0 {
+				return 0, nil, nil, 0, fmt.Errorf("%w: bad attachment", errBadKDBX)
+			}
+			binaries = append(binaries, value[1:])
+		}
+	}
+}
+
+// salsa20Stream is the Salsa20 key stream of the protected values of KDBX 3.1.
+type salsa20Stream struct {
+	key     [32]byte
+	counter [16]byte // counter holds the nonce and the number of the block.
+	block   [64]byte
+	used    int
+}
+
+// XORKeyStream XORs each byte in src with a byte from the key stream.
+func (s *salsa20Stream) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if s.used == len(s.block) {
+			var zeros [64]byte
+			salsa.XORKeyStream(s.block[:], zeros[:], &s.counter, &s.key)
+			binary.LittleEndian.PutUint64(
+				s.counter[8:],
+				binary.LittleEndian.Uint64(s.counter[8:])+1,
+			)
+			s.used = 0
+		}
+		dst[i] = src[i] ^ s.block[s.used]
+		s.used++
+	}
+}
+
+// kdbxInnerStream returns the key stream which protects the values of the document.
+func kdbxInnerStream(id uint32, key []byte) (cipher.Stream, error) {
+	switch id {
+	case kdbxSalsa20InnerStream:
+		s := &salsa20Stream{key: sha256.Sum256(key), used: 64}
+		copy(s.counter[:8], kdbxSalsa20Nonce)
+		return s, nil
+	case kdbxChaCha20InnerStream:
+		h := sha512.Sum512(key)
+		return chacha20.NewUnauthenticatedCipher(h[:32], h[32:44])
+	default:
+		return nil, fmt.Errorf("inner stream %v is not supported", id)
+	}
+}
+
+// unprotect returns the document with the protected values decrypted
+// with the key stream in the order they are found.
+func unprotect(doc []byte, stream cipher.Stream) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	var buf bytes.Buffer
+	e := xml.NewEncoder(&buf)
+	protected := false
+	for {
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.ProcInst:
+			continue
+		case xml.StartElement:
+			protected = false
+			attrs := make([]xml.Attr, 0, len(t.Attr))
+			for _, a := range t.Attr {
+				if a.Name.Local == "Protected" && a.Value == "True" {
+					protected = true
+					continue
+				}
+				attrs = append(attrs, a)
+			}
+			t.Attr = attrs
+			tok = t
+		case xml.CharData:
+			if protected {
+				value, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(t)))
+				if err != nil {
+					return nil, fmt.Errorf("%w: bad protected value", errBadKDBX)
+				}
+				stream.XORKeyStream(value, value)
+				tok = xml.CharData(value)
+			}
+		case xml.EndElement:
+			protected = false
+		}
+		if err := e.EncodeToken(xml.CopyToken(tok)); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,277 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/salsa20"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// kdbxDocument is a KeePass document with the protected values %s: the current
+// and the previous passwords of GitHub and the password of Jira. The attachment
+// of GitHub is the first binary of the database.
+const kdbxDocument = `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta>%s</Meta>
+	<Root>
+		<Group>
+			<UUID>cm9vdA==</UUID>
+			<Name>Database</Name>
+			<Entry>
+				<String><Key>Title</Key><Value>GitHub</Value></String>
+				<String><Key>UserName</Key><Value>john</Value></String>
+				<String><Key>Password</Key><Value Protected="True">%s</Value></String>
+				<String><Key>URL</Key><Value>https://github.com</Value></String>
+				<Binary><Key>id_rsa.pub</Key><Value Ref="0"/></Binary>
+				<History>
+					<Entry>
+						<String><Key>Password</Key><Value Protected="True">%s</Value></String>
+					</Entry>
+				</History>
+			</Entry>
+			<Group>
+				<UUID>d29yaw==</UUID>
+				<Name>Work</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>Jira</Value></String>
+					<String><Key>UserName</Key><Value>jdoe</Value></String>
+					<String><Key>Password</Key><Value Protected="True">%s</Value></String>
+				</Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`
+
+// kdbxPasswords are the protected values of kdbxDocument.
+var kdbxPasswords = []string{"secret", "old secret", "jira-secret"}
+
+// kdbxEntries are the entries of kdbxDocument.
+var kdbxEntries = []Entry{
+	{
+		Title:      "GitHub",
+		Collection: models.CredentialsCollection,
+		Data:       models.CredentialInfo{Login: "john", Password: "secret"},
+		Metadata:   models.Metadata{TitleKey: "GitHub", URLKey: "https://github.com"},
+	},
+	{
+		Title:      "GitHub",
+		Collection: models.BinaryCollection,
+		Data:       models.BinaryInfo{FileName: "id_rsa.pub"},
+		Content:    []byte("ssh-ed25519 AAAA"),
+		Metadata:   models.Metadata{TitleKey: "GitHub"},
+	},
+	{
+		Title:      "Jira",
+		Collection: models.CredentialsCollection,
+		Data:       models.CredentialInfo{Login: "jdoe", Password: "jira-secret"},
+		Metadata:   models.Metadata{TitleKey: "Jira", FolderKey: "Work"},
+	},
+}
+
+// protectedDocument returns kdbxDocument with the passwords encrypted by xor
+// which is called once for all of them.
+func protectedDocument(meta string, xor func(dst, src []byte)) string {
+	plaintext := []byte(strings.Join(kdbxPasswords, ""))
+	ciphertext := make([]byte, len(plaintext))
+	xor(ciphertext, plaintext)
+	args := []any{meta}
+	for _, p := range kdbxPasswords {
+		args = append(args, base64.StdEncoding.EncodeToString(ciphertext[:len(p)]))
+		ciphertext = ciphertext[len(p):]
+	}
+	return fmt.Sprintf(kdbxDocument, args...)
+}
+
+// randomBytes returns n random bytes.
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+// encryptAES encrypts the plaintext with AES-CBC and the PKCS#7 padding.
+func encryptAES(t *testing.T, key, iv, plaintext []byte) []byte {
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+	return ciphertext
+}
+
+// writeLE writes the values in the little-endian order.
+func writeLE(buf *bytes.Buffer, values ...any) {
+	for _, v := range values {
+		if b, ok := v.([]byte); ok {
+			buf.Write(b)
+			continue
+		}
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+}
+
+// newKDBX4 returns a KDBX 4 database with kdbxDocument encrypted with AES
+// and the key derived with AES-KDF. The document is compressed, and its values
+// are protected with ChaCha20.
+func newKDBX4(t *testing.T, password string) []byte {
+	masterSeed, iv, kdfSeed := randomBytes(t, 32), randomBytes(t, 16), randomBytes(t, 32)
+	var kdf bytes.Buffer
+	writeLE(&kdf, uint16(0x0100))
+	writeLE(&kdf, byte(0x42), int32(5), []byte("$UUID"), int32(len(kdbxAESKDF)), kdbxAESKDF)
+	writeLE(&kdf, byte(0x05), int32(1), []byte("R"), int32(8), uint64(100))
+	writeLE(&kdf, byte(0x42), int32(1), []byte("S"), int32(len(kdfSeed)), kdfSeed)
+	writeLE(&kdf, byte(0))
+
+	var header bytes.Buffer
+	writeLE(&header, uint32(kdbxSignature1), uint32(kdbxSignature2), uint16(1), uint16(4))
+	for _, f := range []struct {
+		id    byte
+		value []byte
+	}{
+		{kdbxCipherID, kdbxAES256},
+		{kdbxCompressionFlags, []byte{1, 0, 0, 0}},
+		{kdbxMasterSeed, masterSeed},
+		{kdbxEncryptionIV, iv},
+		{kdbxKdfParameters, kdf.Bytes()},
+		{kdbxEndOfHeader, []byte("\r\n\r\n")},
+	} {
+		writeLE(&header, f.id, uint32(len(f.value)), f.value)
+	}
+
+	streamKey := randomBytes(t, 64)
+	var payload bytes.Buffer
+	writeLE(&payload, byte(kdbxInnerStreamID), uint32(4), uint32(kdbxChaCha20InnerStream))
+	writeLE(&payload, byte(kdbxInnerStreamKey), uint32(len(streamKey)), streamKey)
+	attachment := append([]byte{1}, kdbxEntries[1].Content...)
+	writeLE(&payload, byte(kdbxInnerBinary), uint32(len(attachment)), attachment)
+	writeLE(&payload, byte(kdbxEndOfHeader), uint32(0))
+	stream, err := kdbxInnerStream(kdbxChaCha20InnerStream, streamKey)
+	require.NoError(t, err)
+	payload.WriteString(protectedDocument("", stream.XORKeyStream))
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, err = zw.Write(payload.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	compositeKey := sha256.Sum256(sha256Sum([]byte(password)))
+	transformedKey, err := aesKDF(compositeKey[:], kdfSeed, 100)
+	require.NoError(t, err)
+	key := sha256.Sum256(append(append([]byte{}, masterSeed...), transformedKey...))
+	ciphertext := encryptAES(t, key[:], iv, compressed.Bytes())
+	hmacKey := sha512.Sum512(append(append(append([]byte{}, masterSeed...), transformedKey...), 1))
+
+	var file bytes.Buffer
+	headerHash := sha256.Sum256(header.Bytes())
+	writeLE(
+		&file,
+		header.Bytes(),
+		headerHash[:],
+		kdbxBlockHMAC(hmacKey[:], math.MaxUint64, header.Bytes()),
+	)
+	for i, block := range [][]byte{ciphertext, {}} {
+		var size [4]byte
+		binary.LittleEndian.PutUint32(size[:], uint32(len(block)))
+		writeLE(&file, kdbxBlockHMAC(hmacKey[:], uint64(i), size[:], block), size[:], block)
+	}
+	return file.Bytes()
+}
+
+// newKDBX3 returns a KDBX 3.1 database with kdbxDocument encrypted with AES.
+// The values of the document are protected with Salsa20, and the attachment
+// is kept in the document.
+func newKDBX3(t *testing.T, password string) []byte {
+	masterSeed, iv, transformSeed := randomBytes(t, 32), randomBytes(t, 16), randomBytes(t, 32)
+	streamKey, startBytes := randomBytes(t, 32), randomBytes(t, 32)
+	rounds := make([]byte, 8)
+	binary.LittleEndian.PutUint64(rounds, 100)
+
+	var header bytes.Buffer
+	writeLE(&header, uint32(kdbxSignature1), uint32(kdbxSignature2), uint16(1), uint16(3))
+	for _, f := range []struct {
+		id    byte
+		value []byte
+	}{
+		{kdbxCipherID, kdbxAES256},
+		{kdbxCompressionFlags, []byte{0, 0, 0, 0}},
+		{kdbxMasterSeed, masterSeed},
+		{kdbxTransformSeed, transformSeed},
+		{kdbxTransformRounds, rounds},
+		{kdbxEncryptionIV, iv},
+		{kdbxProtectedStreamKey, streamKey},
+		{kdbxStreamStartBytes, startBytes},
+		{kdbxInnerRandomStreamID, []byte{kdbxSalsa20InnerStream, 0, 0, 0}},
+		{kdbxEndOfHeader, []byte("\r\n\r\n")},
+	} {
+		writeLE(&header, f.id, uint16(len(f.value)), f.value)
+	}
+
+	meta := fmt.Sprintf(
+		`<Binaries><Binary ID="0">%s</Binary></Binaries>`,
+		base64.StdEncoding.EncodeToString(kdbxEntries[1].Content),
+	)
+	salsaKey := sha256.Sum256(streamKey)
+	doc := []byte(protectedDocument(meta, func(dst, src []byte) {
+		salsa20.XORKeyStream(dst, src, kdbxSalsa20Nonce, &salsaKey)
+	}))
+	var plaintext bytes.Buffer
+	docHash := sha256.Sum256(doc)
+	writeLE(&plaintext, startBytes)
+	writeLE(&plaintext, uint32(0), docHash[:], uint32(len(doc)), doc)
+	writeLE(&plaintext, uint32(1), make([]byte, 32), uint32(0))
+
+	compositeKey := sha256.Sum256(sha256Sum([]byte(password)))
+	transformedKey, err := aesKDF(compositeKey[:], transformSeed, 100)
+	require.NoError(t, err)
+	key := sha256.Sum256(append(append([]byte{}, masterSeed...), transformedKey...))
+	return append(header.Bytes(), encryptAES(t, key[:], iv, plaintext.Bytes())...)
+}
+
+// sha256Sum returns the SHA-256 hash of the data as a slice.
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func TestKDBXParser(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		file func(t *testing.T, password string) []byte
+	}{
+		{name: "kdbx4", file: newKDBX4},
+		{name: "kdbx3", file: newKDBX3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.file(t, "master")
+			entries, err := (&kdbxParser{password: "master"}).Parse(data)
+			require.NoError(t, err)
+			assert.Equal(t, kdbxEntries, entries)
+
+			_, err = (&kdbxParser{password: "wrong"}).Parse(data)
+			assert.ErrorIs(t, err, clientErr.ErrWrongExportPassword)
+		})
+	}
+	t.Run("not_kdbx", func(t *testing.T) {
+		_, err := (&kdbxParser{password: "master"}).Parse([]byte("<KeePassFile/>"))
+		assert.ErrorIs(t, err, errBadKDBX)
+	})
+}
@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// keepassFile is the XML document of a KeePass database.
+type keepassFile struct {
+	Meta struct {
+		RecycleBinEnabled string `xml:"RecycleBinEnabled"`
+		RecycleBinUUID    string `xml:"RecycleBinUUID"`
+		Binaries          []struct {
+			ID         string `xml:"ID,attr"`
+			Compressed string `xml:"Compressed,attr"`
+			Value      string `xml:",chardata"`
+		} `xml:"Binaries>Binary"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keepassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+// keepassGroup is a group of the entries and the nested groups.
+type keepassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keepassEntry `xml:"Entry"`
+	Groups  []keepassGroup `xml:"Group"`
+}
+
+// keepassEntry is an entry of a group. The history of the entry is not read.
+type keepassEntry struct {
+	Tags    string `xml:"Tags"`
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"String"`
+	Binaries []struct {
+		Key   string `xml:"Key"`
+		Value struct {
+			Ref string `xml:"Ref,attr"`
+		} `xml:"Value"`
+	} `xml:"Binary"`
+}
+
+// field returns the value of the string field of the entry.
+func (e keepassEntry) field(key string) string {
+	for _, s := range e.Strings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// keepassXMLParser reads the entries of the XML export of KeePass.
+type keepassXMLParser struct {
+	// binaries are the attachments kept outside of the XML document; if nil,
+	// the attachments are read from the document.
+	binaries [][]byte
+}
+
+// Parse returns the entries of the groups followed by their attachments.
+// The path of the group is the folder of an entry. The entries of the recycle
+// bin are skipped.
+func (p *keepassXMLParser) Parse(data []byte) ([]Entry, error) {
+	var doc keepassFile
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	binaries := p.binaries
+	if binaries == nil {
+		binaries = make([][]byte, len(doc.Meta.Binaries))
+		for _, b := range doc.Meta.Binaries {
+			i, err := strconv.Atoi(b.ID)
+			if err != nil || i < 0 || i >= len(binaries) {
+				return nil, fmt.Errorf("bad attachment id %q", b.ID)
+			}
+			if binaries[i], err = keepassBinary(b.Value, b.Compressed == "True"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	recycleBin := ""
+	if doc.Meta.RecycleBinEnabled == "True" {
+		recycleBin = doc.Meta.RecycleBinUUID
+	}
+	var (
+		entries []Entry
+		walk    func(g keepassGroup, folder string) error
+	)
+	walk = func(g keepassGroup, folder string) error {
+		if recycleBin != "" && g.UUID == recycleBin {
+			return nil
+		}
+		for _, e := range g.Entries {
+			f := entryFields{
+				title:    e.field("Title"),
+				username: e.field("UserName"),
+				password: e.field("Password"),
+				url:      e.field("URL"),
+				folder:   folder,
+				notes:    e.field("Notes"),
+				tags:     e.Tags,
+			}
+			entries = append(entries, loginEntry(f))
+			for _, b := range e.Binaries {
+				i, err := strconv.Atoi(b.Value.Ref)
+				if err != nil || i < 0 || i >= len(binaries) {
+					return fmt.Errorf("bad attachment reference %q", b.Value.Ref)
+				}
+				entries = append(entries, attachmentEntry(f, b.Key, binaries[i]))
+			}
+		}
+		for _, sub := range g.Groups {
+			path := sub.Name
+			if folder != "" {
+				path = folder + "/" + sub.Name
+			}
+			if err := walk(sub, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// the root group is the database itself, not a folder
+	for _, g := range doc.Root.Groups {
+		if err := walk(g, ""); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// keepassBinary decodes the attachment kept in the document.
+func keepassBinary(value string, compressed bool) ([]byte, error) {
+	content, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || !compressed {
+		return content, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
@@ -0,0 +1,102 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestKeepassXMLParser(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, err := zw.Write([]byte("ssh-ed25519 AAAA"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	doc := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta>
+		<RecycleBinEnabled>True</RecycleBinEnabled>
+		<RecycleBinUUID>Ymlu</RecycleBinUUID>
+		<Binaries><Binary ID="0" Compressed="True">%s</Binary></Binaries>
+	</Meta>
+	<Root>
+		<Group>
+			<UUID>cm9vdA==</UUID>
+			<Name>Database</Name>
+			<Group>
+				<UUID>d29yaw==</UUID>
+				<Name>Work</Name>
+				<Group>
+					<UUID>Z2l0</UUID>
+					<Name>Git</Name>
+					<Entry>
+						<Tags>dev;work</Tags>
+						<String><Key>Title</Key><Value>GitHub</Value></String>
+						<String><Key>UserName</Key><Value>john</Value></String>
+						<String><Key>Password</Key><Value ProtectInMemory="True">secret</Value></String>
+						<String><Key>URL</Key><Value>https://github.com</Value></String>
+						<String><Key>Notes</Key><Value>2FA is on</Value></String>
+						<Binary><Key>id_rsa.pub</Key><Value Ref="0"/></Binary>
+					</Entry>
+				</Group>
+				<Entry>
+					<String><Key>Title</Key><Value>Wi-Fi</Value></String>
+					<String><Key>Notes</Key><Value>office password</Value></String>
+				</Entry>
+			</Group>
+			<Group>
+				<UUID>Ymlu</UUID>
+				<Name>Recycle Bin</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>Deleted</Value></String>
+					<String><Key>UserName</Key><Value>deleted</Value></String>
+				</Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`, base64.StdEncoding.EncodeToString(compressed.Bytes()))
+
+	entries, err := (&keepassXMLParser{}).Parse([]byte(doc))
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{
+			Title:      "Wi-Fi",
+			Collection: models.TextCollection,
+			Data:       "office password",
+			Metadata:   models.Metadata{TitleKey: "Wi-Fi", FolderKey: "Work"},
+		},
+		{
+			Title:      "GitHub",
+			Collection: models.CredentialsCollection,
+			Data:       models.CredentialInfo{Login: "john", Password: "secret"},
+			Metadata: models.Metadata{
+				TitleKey:  "GitHub",
+				URLKey:    "https://github.com",
+				FolderKey: "Work/Git",
+				NotesKey:  "2FA is on",
+				TagsKey:   "dev;work",
+			},
+		},
+		{
+			Title:      "GitHub",
+			Collection: models.BinaryCollection,
+			Data:       models.BinaryInfo{FileName: "id_rsa.pub"},
+			Content:    []byte("ssh-ed25519 AAAA"),
+			Metadata:   models.Metadata{TitleKey: "GitHub", FolderKey: "Work/Git"},
+		},
+	}, entries)
+
+	t.Run("bad_reference", func(t *testing.T) {
+		_, err := (&keepassXMLParser{}).Parse([]byte(`<KeePassFile><Root><Group><Entry>
+			<Binary><Key>a.txt</Key><Value Ref="1"/></Binary>
+		</Entry></Group></Root></KeePassFile>`))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,217 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// Categories of the 1Password items.
+const (
+	onePasswordLogin      = "001"
+	onePasswordCard       = "002"
+	onePasswordSecureNote = "003"
+	onePasswordPassword   = "005"
+	onePasswordDocument   = "006"
+)
+
+// onePUXField is a field of a section of a 1Password item.
+type onePUXField struct {
+	ID    string `json:"id"`
+	Value struct {
+		String           *string     `json:"string"`
+		Concealed        *string     `json:"concealed"`
+		CreditCardNumber *string     `json:"creditCardNumber"`
+		MonthYear        *int        `json:"monthYear"`
+		File             *onePUXFile `json:"file"`
+	} `json:"value"`
+}
+
+// onePUXFile refers to a file of the files directory of the export.
+type onePUXFile struct {
+	FileName   string `json:"fileName"`
+	DocumentID string `json:"documentId"`
+}
+
+// path returns the path of the file in the archive.
+func (f onePUXFile) path() string {
+	return fmt.Sprintf("files/%v__%v", f.DocumentID, f.FileName)
+}
+
+// text returns the value of the field as a string.
+func (f onePUXField) text() string {
+	for _, v := range []*string{f.Value.String, f.Value.Concealed, f.Value.CreditCardNumber} {
+		if v != nil {
+			return *v
+		}
+	}
+	return ""
+}
+
+// onePUXItem is an item of a 1Password vault.
+type onePUXItem struct {
+	CategoryUUID string `json:"categoryUuid"`
+	Overview     struct {
+		Title string   `json:"title"`
+		URL   string   `json:"url"`
+		Tags  []string `json:"tags"`
+	} `json:"overview"`
+	Details struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Fields []onePUXField `json:"fields"`
+		} `json:"sections"`
+		DocumentAttributes *onePUXFile `json:"documentAttributes"`
+	} `json:"details"`
+}
+
+// field returns the field of the sections of the item with the ID.
+func (item onePUXItem) field(id string) onePUXField {
+	for _, s := range item.Details.Sections {
+		for _, f := range s.Fields {
+			if f.ID == id {
+				return f
+			}
+		}
+	}
+	return onePUXField{}
+}
+
+// onePUXExport is the data of the 1PUX export of 1Password.
+type onePUXExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePUXItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+// onePUXParser reads the items of the 1PUX export of 1Password: a zip archive
+// with the items in export.data and the documents in the files directory.
+type onePUXParser struct{}
+
+// Parse returns the items of the export followed by their attachments.
+// The names of the vaults are the folders. The archived items are imported as well.
+func (p *onePUXParser) Parse(data []byte) ([]Entry, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	content, err := readZipFile(archive, "export.data")
+	if err != nil {
+		return nil, err
+	}
+	var export onePUXExport
+	if err := json.Unmarshal(content, &export); err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, account := range export.Accounts {
+		for _, vault := range account.Vaults {
+			for _, item := range vault.Items {
+				f := entryFields{
+					title:  item.Overview.Title,
+					url:    item.Overview.URL,
+					folder: vault.Attrs.Name,
+					notes:  item.Details.NotesPlain,
+					tags:   strings.Join(item.Overview.Tags, ","),
+				}
+				entry, err := onePUXEntry(archive, f, item)
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, entry)
+				for _, s := range item.Details.Sections {
+					for _, field := range s.Fields {
+						if field.Value.File == nil {
+							continue
+						}
+						content, err := readZipFile(archive, field.Value.File.path())
+						if err != nil {
+							return nil, err
+						}
+						entries = append(entries, attachmentEntry(f, field.Value.File.FileName, content))
+					}
+				}
+			}
+		}
+	}
+	return entries, nil
+}
+
+// onePUXEntry converts the item with the common fields f.
+func onePUXEntry(archive *zip.Reader, f entryFields, item onePUXItem) (Entry, error) {
+	switch item.CategoryUUID {
+	case onePasswordLogin:
+		for _, lf := range item.Details.LoginFields {
+			switch lf.Designation {
+			case "username":
+				f.username = lf.Value
+			case "password":
+				f.password = lf.Value
+			}
+		}
+		return loginEntry(f), nil
+	case onePasswordPassword:
+		f.password = item.Details.Password
+		return loginEntry(f), nil
+	case onePasswordSecureNote:
+		return noteEntry(f), nil
+	case onePasswordCard:
+		expiry := item.field("expiry").Value.MonthYear
+		date := ""
+		if expiry != nil {
+			date = expirationDate(*expiry%100, *expiry/100)
+		}
+		md := f.metadata()
+		if holder := item.field("cardholder").text(); holder != "" {
+			md[CardholderKey] = holder
+		}
+		return Entry{
+			Title:      f.title,
+			Collection: models.CardCollection,
+			Data: models.CardInfo{
+				CardNumber:     item.field("ccnum").text(),
+				CVV:            item.field("cvv").text(),
+				ExpirationDate: date,
+			},
+			Metadata: md,
+		}, nil
+	case onePasswordDocument:
+		doc := item.Details.DocumentAttributes
+		if doc == nil {
+			return unsupportedEntry(f.title, "no document"), nil
+		}
+		content, err := readZipFile(archive, doc.path())
+		if err != nil {
+			return Entry{}, err
+		}
+		return attachmentEntry(f, doc.FileName, content), nil
+	default:
+		return unsupportedEntry(f.title, fmt.Sprintf("category %v is not supported", item.CategoryUUID)), nil
+	}
+}
+
+// readZipFile returns the content of the file of the archive.
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+	f, err := archive.Open(name)
+	if err != nil {
+		return nil, errors.New("no " + name + " in the archive")
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// newOnePUX returns a 1PUX archive with the files.
+func newOnePUX(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestOnePUXParser(t *testing.T) {
+	data := newOnePUX(t, map[string]string{
+		"export.attributes": `{"version": 3}`,
+		"export.data": `{"accounts": [{"vaults": [{"attrs": {"name": "Personal"}, "items": [
+			{
+				"categoryUuid": "001",
+				"overview": {"title": "GitHub", "url": "https://github.com", "tags": ["dev", "work"]},
+				"details": {
+					"loginFields": [
+						{"value": "john", "designation": "username"},
+						{"value": "secret", "designation": "password"}
+					],
+					"notesPlain": "2FA is on",
+					"sections": [{"fields": [
+						{"id": "key", "value": {"file": {"fileName": "id_rsa.pub", "documentId": "d1"}}}
+					]}]
+				}
+			},
+			{
+				"categoryUuid": "002",
+				"overview": {"title": "Visa"},
+				"details": {"sections": [{"fields": [
+					{"id": "cardholder", "value": {"string": "John Doe"}},
+					{"id": "ccnum", "value": {"creditCardNumber": "4111111111111111"}},
+					{"id": "cvv", "value": {"concealed": "123"}},
+					{"id": "expiry", "value": {"monthYear": 202707}}
+				]}]}
+			},
+			{"categoryUuid": "003", "overview": {"title": "Wi-Fi"}, "details": {"notesPlain": "office password"}},
+			{
+				"categoryUuid": "006",
+				"overview": {"title": "Contract"},
+				"details": {"documentAttributes": {"fileName": "contract.pdf", "documentId": "d2"}}
+			},
+			{"categoryUuid": "004", "overview": {"title": "Passport"}, "details": {}}
+		]}]}]}`,
+		"files/d1__id_rsa.pub":   "ssh-ed25519 AAAA",
+		"files/d2__contract.pdf": "%PDF-1.4",
+	})
+	entries, err := (&onePUXParser{}).Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{
+			Title:      "GitHub",
+			Collection: models.CredentialsCollection,
+			Data:       models.CredentialInfo{Login: "john", Password: "secret"},
+			Metadata: models.Metadata{
+				TitleKey:  "GitHub",
+				URLKey:    "https://github.com",
+				FolderKey: "Personal",
+				NotesKey:  "2FA is on",
+				TagsKey:   "dev,work",
+			},
+		},
+		{
+			Title:      "GitHub",
+			Collection: models.BinaryCollection,
+			Data:       models.BinaryInfo{FileName: "id_rsa.pub"},
+			Content:    []byte("ssh-ed25519 AAAA"),
+			Metadata:   models.Metadata{TitleKey: "GitHub", FolderKey: "Personal"},
+		},
+		{
+			Title:      "Visa",
+			Collection: models.CardCollection,
+			Data: models.CardInfo{
+				CardNumber:     "4111111111111111",
+				CVV:            "123",
+				ExpirationDate: "07/27",
+			},
+			Metadata: models.Metadata{TitleKey: "Visa", FolderKey: "Personal", CardholderKey: "John Doe"},
+		},
+		{
+			Title:      "Wi-Fi",
+			Collection: models.TextCollection,
+			Data:       "office password",
+			Metadata:   models.Metadata{TitleKey: "Wi-Fi", FolderKey: "Personal"},
+		},
+		{
+			Title:      "Contract",
+			Collection: models.BinaryCollection,
+			Data:       models.BinaryInfo{FileName: "contract.pdf"},
+			Content:    []byte("%PDF-1.4"),
+			Metadata:   models.Metadata{TitleKey: "Contract", FolderKey: "Personal"},
+		},
+		{Title: "Passport", Unsupported: "category 004 is not supported"},
+	}, entries)
+
+	t.Run("no_export_data", func(t *testing.T) {
+		_, err := (&onePUXParser{}).Parse(newOnePUX(t, map[string]string{"export.attributes": "{}"}))
+		assert.Error(t, err)
+	})
+	t.Run("not_zip", func(t *testing.T) {
+		_, err := (&onePUXParser{}).Parse([]byte("{}"))
+		assert.Error(t, err)
+	})
+}
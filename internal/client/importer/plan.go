@@ -0,0 +1,155 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/validation"
+)
+
+// Status is the outcome of the import of an entry.
+type Status string
+
+// Statuses of the entries.
+const (
+	StatusNew         Status = "new"         // the entry is imported
+	StatusExists      Status = "exists"      // the same record is already saved
+	StatusDuplicate   Status = "duplicate"   // the same entry is found earlier in the export
+	StatusInvalid     Status = "invalid"     // the entry doesn't make a valid record
+	StatusUnsupported Status = "unsupported" // the entry has a type which can't be imported
+)
+
+// Item is an entry with the outcome of its import.
+type Item struct {
+	Entry
+	Status Status
+	Reason string // Reason explains why the entry is not imported.
+}
+
+// Plan decides which entries are imported. The entries which are the same
+// as the existing records or the entries found earlier in the export are skipped.
+// Two records are the same if they have the same login and URL, the same card
+// number, the same note and title or the same file name and title. If existing
+// is nil, the entries are not compared at all.
+func Plan(entries []Entry, existing *clientModels.SyncResponse) []Item {
+	seen := existingKeys(existing)
+	planned := make(map[string]bool)
+	items := make([]Item, 0, len(entries))
+	for _, e := range entries {
+		item := Item{Entry: e, Status: StatusNew}
+		if e.Unsupported != "" {
+			item.Status, item.Reason = StatusUnsupported, e.Unsupported
+			items = append(items, item)
+			continue
+		}
+		if err := validate(e); err != nil {
+			item.Status, item.Reason = StatusInvalid, err.Error()
+			items = append(items, item)
+			continue
+		}
+		if existing != nil {
+			k := key(e.Collection, e.Data, e.Metadata)
+			switch {
+			case seen[k]:
+				item.Status = StatusExists
+			case planned[k]:
+				item.Status = StatusDuplicate
+			default:
+				planned[k] = true
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// existingKeys returns the keys of the records.
+func existingKeys(data *clientModels.SyncResponse) map[string]bool {
+	keys := make(map[string]bool)
+	if data == nil {
+		return keys
+	}
+	for _, r := range data.Text {
+		keys[key(models.TextCollection, r.Data, r.Metadata)] = true
+	}
+	for _, r := range data.Binary {
+		keys[key(models.BinaryCollection, r.Data, r.Metadata)] = true
+	}
+	for _, r := range data.Card {
+		keys[key(models.CardCollection, r.Data, r.Metadata)] = true
+	}
+	for _, r := range data.Credential {
+		keys[key(models.CredentialsCollection, r.Data, r.Metadata)] = true
+	}
+	return keys
+}
+
+// key returns the fields which identify the record joined in a string.
+func key(collectionName models.CollectionName, data any, md models.Metadata) string {
+	fields := []string{string(collectionName)}
+	switch d := data.(type) {
+	case models.TextInfo:
+		fields = append(fields, md[TitleKey], d)
+	case models.BinaryInfo:
+		fields = append(fields, md[TitleKey], d.FileName)
+	case models.CardInfo:
+		fields = append(fields, strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, d.CardNumber))
+	case models.CredentialInfo:
+		fields = append(fields, d.Login, md[URLKey])
+	}
+	return strings.Join(fields, "\x00")
+}
+
+// validate checks that the entry makes a valid record.
+func validate(e Entry) error {
+	switch d := e.Data.(type) {
+	case models.TextInfo:
+		if strings.TrimSpace(d) == "" {
+			return errors.New("empty note")
+		}
+		return nil
+	case models.BinaryInfo:
+		if d.FileName == "" || len(e.Content) == 0 {
+			return errors.New("empty attachment")
+		}
+		return nil
+	case models.CardInfo, models.CredentialInfo:
+		return validation.Validate.Struct(d)
+	default:
+		return fmt.Errorf("%w: %v", srvErrors.ErrUnknownCollection, e.Collection)
+	}
+}
+
+// Body returns the body of the request which adds the record of the entry.
+// The data of a binary record is passed after the attachment is uploaded.
+func Body(e Entry, data any) (string, error) {
+	var body any
+	switch d := data.(type) {
+	case models.TextInfo:
+		body = &models.TextRecord{Data: d, Metadata: e.Metadata}
+	case models.BinaryInfo:
+		body = &models.BinaryRecord{Data: d, Metadata: e.Metadata}
+	case models.CardInfo:
+		body = &models.CardRecord{Data: d, Metadata: e.Metadata}
+	case models.CredentialInfo:
+		body = &models.CredentialRecord{Data: d, Metadata: e.Metadata}
+	default:
+		return "", fmt.Errorf("%w: %v", srvErrors.ErrUnknownCollection, e.Collection)
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
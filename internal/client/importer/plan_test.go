@@ -0,0 +1,114 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestNewParser(t *testing.T) {
+	for _, format := range Formats() {
+		p, err := NewParser(format, Options{})
+		require.NoError(t, err)
+		assert.NotNil(t, p)
+	}
+	_, err := NewParser("lastpass", Options{})
+	assert.Error(t, err)
+}
+
+func TestPlan(t *testing.T) {
+	github := Entry{
+		Title:      "GitHub",
+		Collection: models.CredentialsCollection,
+		Data:       models.CredentialInfo{Login: "john", Password: "secret"},
+		Metadata:   models.Metadata{TitleKey: "GitHub", URLKey: "https://github.com"},
+	}
+	gitlab := Entry{
+		Title:      "GitLab",
+		Collection: models.CredentialsCollection,
+		Data:       models.CredentialInfo{Login: "john", Password: "secret"},
+		Metadata:   models.Metadata{TitleKey: "GitLab", URLKey: "https://gitlab.com"},
+	}
+	card := Entry{
+		Title:      "Visa",
+		Collection: models.CardCollection,
+		Data:       models.CardInfo{CardNumber: "4111 1111 1111 1111", CVV: "123", ExpirationDate: "07/27"},
+	}
+	entries := []Entry{
+		github,
+		gitlab,
+		gitlab,
+		card,
+		{Title: "Bad card", Collection: models.CardCollection, Data: models.CardInfo{CardNumber: "1234"}},
+		{Title: "No login", Collection: models.CredentialsCollection, Data: models.CredentialInfo{Password: "secret"}},
+		{Title: "Empty", Collection: models.TextCollection, Data: " "},
+		{Title: "Passport", Unsupported: "identities are not supported"},
+	}
+	existing := &clientModels.SyncResponse{
+		Credential: []models.CredentialRecord{{
+			Data:     models.CredentialInfo{Login: "john", Password: "changed"},
+			Metadata: models.Metadata{URLKey: "https://github.com"},
+		}},
+		Card: []models.CardRecord{{Data: models.CardInfo{CardNumber: "4111111111111111"}}},
+	}
+	statuses := func(items []Item) []Status {
+		result := make([]Status, 0, len(items))
+		for _, item := range items {
+			result = append(result, item.Status)
+		}
+		return result
+	}
+
+	items := Plan(entries, existing)
+	assert.Equal(t, []Status{
+		StatusExists,
+		StatusNew,
+		StatusDuplicate,
+		StatusExists,
+		StatusInvalid,
+		StatusInvalid,
+		StatusInvalid,
+		StatusUnsupported,
+	}, statuses(items))
+	assert.Equal(t, "identities are not supported", items[7].Reason)
+	assert.Equal(t, "empty note", items[6].Reason)
+
+	items = Plan(entries, nil)
+	assert.Equal(t, []Status{
+		StatusNew,
+		StatusNew,
+		StatusNew,
+		StatusNew,
+		StatusInvalid,
+		StatusInvalid,
+		StatusInvalid,
+		StatusUnsupported,
+	}, statuses(items))
+}
+
+func TestBody(t *testing.T) {
+	e := Entry{
+		Collection: models.BinaryCollection,
+		Data:       models.BinaryInfo{FileName: "id_rsa.pub"},
+		Content:    []byte("ssh-ed25519 AAAA"),
+		Metadata:   models.Metadata{TitleKey: "GitHub"},
+	}
+	body, err := Body(e, models.BinaryInfo{
+		FileName: "id_rsa.pub",
+		BlobID:   "645b34a19affed5a60fcfadd",
+		Size:     "16",
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"record_id": "000000000000000000000000",
+		"Data": {"FileName": "id_rsa.pub", "BlobID": "645b34a19affed5a60fcfadd", "Size": "16"},
+		"Metadata": {"title": "GitHub"}
+	}`, body)
+
+	_, err = Body(Entry{Collection: models.OTPCollection}, models.OTPInfo{})
+	assert.Error(t, err)
+}
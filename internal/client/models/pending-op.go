@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// PendingOp is a write made while the server was unavailable. The operations
+// are kept in the journal of the local store until they are sent to the server.
+type PendingOp struct {
+	Seq        uint64                `json:"-"` // Seq is the position of the operation in the journal.
+	Op         models.ChangeOp       `json:"op"`
+	Collection models.CollectionName `json:"collection"`
+	RecordID   models.ObjectID       `json:"record_id"`
+	Body       string                `json:"body"` // Body is the request body sent to the server.
+	QueuedAt   time.Time             `json:"queued_at"`
+}
@@ -1,7 +1,24 @@
 // Package models provides common data structures used by the client.
 package models
 
-import "github.com/blokhinnv/gophkeeper/internal/server/models"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// EncryptedRecord is an end-to-end encrypted record which can't be read
+// without the key derived from the master password.
+type EncryptedRecord struct {
+	Collection models.CollectionName
+	RecordID   models.ObjectID
+	Ciphertext []byte
+	Version    int64      `json:",omitempty"`
+	UpdatedAt  *time.Time `json:",omitempty"`
+}
 
 // SyncResponse defines the response from the SyncService Sync method.
 type SyncResponse struct {
@@ -9,4 +26,223 @@ type SyncResponse struct {
 	Binary     []models.BinaryRecord
 	Card       []models.CardRecord
 	Credential []models.CredentialRecord
+	OTP        []models.OTPRecord
+	Encrypted  []EncryptedRecord `json:",omitempty"` // Encrypted holds the records which are not decrypted yet.
+}
+
+// AppendRecord converts the untyped record received from the server and appends
+// it to the collection. An end-to-end encrypted record is appended to Encrypted.
+func (r *SyncResponse) AppendRecord(
+	collectionName models.CollectionName,
+	record models.UntypedRecord,
+) error {
+	if models.IsEncryptedData(record.Data) {
+		ciphertext, err := models.EncryptedCiphertext(record.Data)
+		if err != nil {
+			return err
+		}
+		r.Encrypted = append(r.Encrypted, EncryptedRecord{
+			Collection: collectionName,
+			RecordID:   record.RecordID,
+			Ciphertext: ciphertext,
+			Version:    record.Version,
+			UpdatedAt:  record.UpdatedAt,
+		})
+		return nil
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	switch collectionName {
+	case models.TextCollection:
+		return appendRecord(&r.Text, b)
+	case models.BinaryCollection:
+		return appendRecord(&r.Binary, b)
+	case models.CardCollection:
+		return appendRecord(&r.Card, b)
+	case models.CredentialsCollection:
+		return appendRecord(&r.Credential, b)
+	case models.OTPCollection:
+		return appendRecord(&r.OTP, b)
+	default:
+		return fmt.Errorf("%w: %v", errors.ErrUnknownCollection, collectionName)
+	}
+}
+
+// appendRecord decodes the record and appends it to the records.
+func appendRecord[T any](records *[]T, b []byte) error {
+	var record T
+	if err := json.Unmarshal(b, &record); err != nil {
+		return err
+	}
+	*records = append(*records, record)
+	return nil
+}
+
+// Merge adds the records of the other response replacing the records with the same IDs.
+func (r *SyncResponse) Merge(other *SyncResponse) {
+	r.Text = mergeRecords(r.Text, other.Text, func(rec models.TextRecord) models.ObjectID {
+		return rec.RecordID
+	})
+	r.Binary = mergeRecords(r.Binary, other.Binary, func(rec models.BinaryRecord) models.ObjectID {
+		return rec.RecordID
+	})
+	r.Card = mergeRecords(r.Card, other.Card, func(rec models.CardRecord) models.ObjectID {
+		return rec.RecordID
+	})
+	r.Credential = mergeRecords(
+		r.Credential,
+		other.Credential,
+		func(rec models.CredentialRecord) models.ObjectID {
+			return rec.RecordID
+		},
+	)
+	r.OTP = mergeRecords(r.OTP, other.OTP, func(rec models.OTPRecord) models.ObjectID {
+		return rec.RecordID
+	})
+	r.Encrypted = mergeRecords(
+		r.Encrypted,
+		other.Encrypted,
+		func(rec EncryptedRecord) models.ObjectID {
+			return rec.RecordID
+		},
+	)
+}
+
+// Remove removes the record with the ID from the collection.
+func (r *SyncResponse) Remove(collectionName models.CollectionName, id models.ObjectID) {
+	r.Encrypted = removeRecord(r.Encrypted, id, func(rec EncryptedRecord) models.ObjectID {
+		return rec.RecordID
+	})
+	switch collectionName {
+	case models.TextCollection:
+		r.Text = removeRecord(r.Text, id, func(rec models.TextRecord) models.ObjectID {
+			return rec.RecordID
+		})
+	case models.BinaryCollection:
+		r.Binary = removeRecord(r.Binary, id, func(rec models.BinaryRecord) models.ObjectID {
+			return rec.RecordID
+		})
+	case models.CardCollection:
+		r.Card = removeRecord(r.Card, id, func(rec models.CardRecord) models.ObjectID {
+			return rec.RecordID
+		})
+	case models.CredentialsCollection:
+		r.Credential = removeRecord(
+			r.Credential,
+			id,
+			func(rec models.CredentialRecord) models.ObjectID {
+				return rec.RecordID
+			},
+		)
+	case models.OTPCollection:
+		r.OTP = removeRecord(r.OTP, id, func(rec models.OTPRecord) models.ObjectID {
+			return rec.RecordID
+		})
+	}
+}
+
+// Count returns the number of the records of the collection
+// including the encrypted ones.
+func (r *SyncResponse) Count(collectionName models.CollectionName) int {
+	count := 0
+	for _, rec := range r.Encrypted {
+		if rec.Collection == collectionName {
+			count++
+		}
+	}
+	switch collectionName {
+	case models.TextCollection:
+		count += len(r.Text)
+	case models.BinaryCollection:
+		count += len(r.Binary)
+	case models.CardCollection:
+		count += len(r.Card)
+	case models.CredentialsCollection:
+		count += len(r.Credential)
+	case models.OTPCollection:
+		count += len(r.OTP)
+	}
+	return count
+}
+
+// Version returns the version of the record with the ID from the collection
+// including the encrypted ones; zero if the record is not found.
+func (r *SyncResponse) Version(collectionName models.CollectionName, id models.ObjectID) int64 {
+	for _, rec := range r.Encrypted {
+		if rec.Collection == collectionName && rec.RecordID == id {
+			return rec.Version
+		}
+	}
+	switch collectionName {
+	case models.TextCollection:
+		return findVersion(r.Text, id, func(rec models.TextRecord) (models.ObjectID, int64) {
+			return rec.RecordID, rec.Version
+		})
+	case models.BinaryCollection:
+		return findVersion(r.Binary, id, func(rec models.BinaryRecord) (models.ObjectID, int64) {
+			return rec.RecordID, rec.Version
+		})
+	case models.CardCollection:
+		return findVersion(r.Card, id, func(rec models.CardRecord) (models.ObjectID, int64) {
+			return rec.RecordID, rec.Version
+		})
+	case models.CredentialsCollection:
+		return findVersion(
+			r.Credential,
+			id,
+			func(rec models.CredentialRecord) (models.ObjectID, int64) {
+				return rec.RecordID, rec.Version
+			},
+		)
+	case models.OTPCollection:
+		return findVersion(r.OTP, id, func(rec models.OTPRecord) (models.ObjectID, int64) {
+			return rec.RecordID, rec.Version
+		})
+	}
+	return 0
+}
+
+// findVersion returns the version of the record with the ID; zero if it's not found.
+func findVersion[T any](
+	records []T,
+	id models.ObjectID,
+	version func(T) (models.ObjectID, int64),
+) int64 {
+	for _, r := range records {
+		if recordID, v := version(r); recordID == id {
+			return v
+		}
+	}
+	return 0
+}
+
+// mergeRecords replaces the records with the same IDs and appends the new ones.
+func mergeRecords[T any](records, added []T, id func(T) models.ObjectID) []T {
+	for _, a := range added {
+		replaced := false
+		for i, r := range records {
+			if id(r) == id(a) {
+				records[i] = a
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			records = append(records, a)
+		}
+	}
+	return records
+}
+
+// removeRecord removes the record with the ID.
+func removeRecord[T any](records []T, removed models.ObjectID, id func(T) models.ObjectID) []T {
+	result := records[:0]
+	for _, r := range records {
+		if id(r) != removed {
+			result = append(result, r)
+		}
+	}
+	return result
 }
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestSyncResponse_Merge(t *testing.T) {
+	first, second := models.NewRandomObjectID(), models.NewRandomObjectID()
+	r := &SyncResponse{
+		Text: []models.TextRecord{
+			{RecordID: first, Data: "first"},
+			{RecordID: second, Data: "second"},
+		},
+	}
+	added := models.NewRandomObjectID()
+	r.Merge(&SyncResponse{
+		Text: []models.TextRecord{{RecordID: second, Data: "updated"}},
+		OTP:  []models.OTPRecord{{RecordID: added, Data: models.OTPInfo{Secret: "JBSWY3DPEHPK3PXP"}}},
+	})
+	assert.Equal(t, []models.TextRecord{
+		{RecordID: first, Data: "first"},
+		{RecordID: second, Data: "updated"},
+	}, r.Text)
+	assert.Equal(t, added, r.OTP[0].RecordID)
+}
+
+func TestSyncResponse_Remove(t *testing.T) {
+	first, second := models.NewRandomObjectID(), models.NewRandomObjectID()
+	r := &SyncResponse{
+		Card:       []models.CardRecord{{RecordID: first}, {RecordID: second}},
+		Credential: []models.CredentialRecord{{RecordID: first}},
+	}
+	r.Remove(models.CardCollection, first)
+	assert.Equal(t, []models.CardRecord{{RecordID: second}}, r.Card)
+	// other collections are not affected
+	assert.Len(t, r.Credential, 1)
+	r.Remove(models.CardCollection, models.NewRandomObjectID())
+	assert.Len(t, r.Card, 1)
+}
+
+func TestSyncResponse_Count(t *testing.T) {
+	r := &SyncResponse{
+		Card: []models.CardRecord{{RecordID: models.NewRandomObjectID()}},
+		Encrypted: []EncryptedRecord{
+			{Collection: models.CardCollection, RecordID: models.NewRandomObjectID()},
+			{Collection: models.TextCollection, RecordID: models.NewRandomObjectID()},
+		},
+	}
+	assert.Equal(t, 2, r.Count(models.CardCollection))
+	assert.Equal(t, 1, r.Count(models.TextCollection))
+	assert.Equal(t, 0, r.Count(models.OTPCollection))
+}
+
+func TestSyncResponse_Version(t *testing.T) {
+	card, encrypted := models.NewRandomObjectID(), models.NewRandomObjectID()
+	r := &SyncResponse{
+		Card: []models.CardRecord{{RecordID: card, Version: 3}},
+		Encrypted: []EncryptedRecord{
+			{Collection: models.TextCollection, RecordID: encrypted, Version: 7},
+		},
+	}
+	assert.Equal(t, int64(3), r.Version(models.CardCollection, card))
+	assert.Equal(t, int64(7), r.Version(models.TextCollection, encrypted))
+	assert.Equal(t, int64(0), r.Version(models.TextCollection, card))
+	assert.Equal(t, int64(0), r.Version(models.CardCollection, models.NewRandomObjectID()))
+}
+
+func TestSyncResponse_AppendRecord(t *testing.T) {
+	id := models.NewRandomObjectID()
+	r := &SyncResponse{}
+	require.NoError(t, r.AppendRecord(models.CredentialsCollection, models.UntypedRecord{
+		RecordID: id,
+		UntypedRecordContent: models.UntypedRecordContent{
+			Data:     map[string]any{"Login": "login", "Password": "pwd"},
+			Metadata: models.Metadata{"site": "example.com"},
+		},
+	}))
+	assert.Equal(t, []models.CredentialRecord{{
+		RecordID: id,
+		Data:     models.CredentialInfo{Login: "login", Password: "pwd"},
+		Metadata: models.Metadata{"site": "example.com"},
+	}}, r.Credential)
+
+	require.NoError(t, r.AppendRecord(models.TextCollection, models.UntypedRecord{
+		RecordID: id,
+		UntypedRecordContent: models.UntypedRecordContent{
+			Data: models.NewEncryptedData([]byte("ciphertext")),
+		},
+	}))
+	assert.Empty(t, r.Text)
+	assert.Equal(t, []EncryptedRecord{{
+		Collection: models.TextCollection,
+		RecordID:   id,
+		Ciphertext: []byte("ciphertext"),
+	}}, r.Encrypted)
+
+	err := r.AppendRecord("unknown", models.UntypedRecord{})
+	assert.ErrorIs(t, err, errors.ErrUnknownCollection)
+	err = r.AppendRecord(models.TextCollection, models.UntypedRecord{
+		UntypedRecordContent: models.UntypedRecordContent{
+			Data: map[string]any{models.EncryptedField: 42},
+		},
+	})
+	assert.ErrorIs(t, err, errors.ErrBadEnvelope)
+	err = r.AppendRecord(models.TextCollection, models.UntypedRecord{
+		UntypedRecordContent: models.UntypedRecordContent{Data: map[string]any{"a": "b"}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,111 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+)
+
+// newAuthService creates the service which refreshes the expired tokens.
+var newAuthService = service.NewAuthServiceWithTransport
+
+// fileAnnotation marks the flags of the local store path.
+const fileAnnotation = "gophkeeper_profile_file"
+
+// fileFlags are the names of the flags which may be marked with MarkFileFlag.
+var fileFlags = []string{"file", "store"}
+
+// MarkFileFlag marks the persistent flag of the command as a path of the local
+// store kept by the sync command, so Apply uses the path from the profile for it.
+func MarkFileFlag(cmd *cobra.Command, name string) error {
+	return cmd.PersistentFlags().SetAnnotation(name, fileAnnotation, []string{"true"})
+}
+
+// Name returns the name of the profile chosen with the --profile flag.
+func Name(cmd *cobra.Command) string {
+	if f := cmd.Flag("profile"); f != nil && f.Value.String() != "" {
+		return f.Value.String()
+	}
+	return DefaultName
+}
+
+// flagValue returns the value of the flag or an empty string if there is no such flag.
+func flagValue(cmd *cobra.Command, flag string) string {
+	if f := cmd.Flag(flag); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
+
+// setDefault sets the flag to the value if the flag exists and is not set explicitly.
+func setDefault(cmd *cobra.Command, flag, value string) error {
+	f := cmd.Flag(flag)
+	if f == nil || f.Changed || value == "" {
+		return nil
+	}
+	if err := f.Value.Set(value); err != nil {
+		return err
+	}
+	// the flag is set as if it was provided, so the required flags are satisfied
+	f.Changed = true
+	return nil
+}
+
+// Apply loads the profile chosen with the --profile flag and uses its values
+// for the server, transport, token and marked file flags of the command which
+// are not set explicitly. The expired token is refreshed first if possible.
+func Apply(cmd *cobra.Command) (*Profile, error) {
+	name := Name(cmd)
+	p, err := Load(name)
+	if err != nil {
+		return nil, err
+	}
+	for flag, value := range map[string]string{
+		"server":    p.Server,
+		"transport": p.Transport,
+	} {
+		if err := setDefault(cmd, flag, value); err != nil {
+			return nil, err
+		}
+	}
+	for _, flag := range fileFlags {
+		if f := cmd.Flag(flag); f != nil && len(f.Annotations[fileAnnotation]) > 0 {
+			if err := setDefault(cmd, flag, p.File); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if f := cmd.Flag("token"); f == nil || f.Changed || p.Token == "" {
+		return p, nil
+	}
+	if p.Expired(time.Now()) && p.RefreshToken != "" {
+		if err := refresh(cmd, name, p); err != nil {
+			// the server rejects the expired token with a clear message anyway
+			fmt.Fprintf(os.Stderr, "unable to refresh the token of profile %v: %v\n", name, err)
+		}
+	}
+	return p, setDefault(cmd, "token", p.Token)
+}
+
+// refresh exchanges the refresh token of the profile for the new tokens and saves them.
+// The session of the device is refreshed with the key of the device of the profile.
+func refresh(cmd *cobra.Command, name string, p *Profile) error {
+	authService, err := newAuthService(flagValue(cmd, "transport"), flagValue(cmd, "server"))
+	if err != nil {
+		return err
+	}
+	device, err := p.RefreshDevice()
+	if err != nil {
+		return err
+	}
+	tokens, err := authService.Refresh(p.RefreshToken, device)
+	if err != nil {
+		return err
+	}
+	p.SetTokens(tokens)
+	return Save(name, p)
+}
@@ -0,0 +1,82 @@
+package profile
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+)
+
+// defaultDeviceName is the name of the device used when the hostname is unknown.
+const defaultDeviceName = "gophkeeper"
+
+// ensureDeviceKey generates the key of the device if the profile has none.
+// Returns true if the key has been generated.
+func (p *Profile) ensureDeviceKey() (bool, error) {
+	if p.DeviceKey != "" {
+		return false, nil
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return false, err
+	}
+	p.DeviceKey = base64.StdEncoding.EncodeToString(key.Seed())
+	return true, nil
+}
+
+// Device returns the device which the profile registers on login with its key.
+// The name of the device defaults to the hostname; the platform is the OS and
+// the architecture of the client.
+func (p *Profile) Device() (*service.Device, error) {
+	seed, err := base64.StdEncoding.DecodeString(p.DeviceKey)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("bad device key in the profile")
+	}
+	name := p.DeviceName
+	if name == "" {
+		if name, err = os.Hostname(); err != nil || name == "" {
+			name = defaultDeviceName
+		}
+	}
+	return service.NewDevice(
+		name,
+		runtime.GOOS+"/"+runtime.GOARCH,
+		ed25519.NewKeyFromSeed(seed),
+	), nil
+}
+
+// RefreshDevice returns the device whose key refreshes the sessions of the profile
+// or nil if the profile has never registered a device.
+func (p *Profile) RefreshDevice() (*service.Device, error) {
+	if p.DeviceKey == "" {
+		return nil, nil
+	}
+	return p.Device()
+}
+
+// LoadDevice returns the device of the profile. The key of the device is
+// generated and saved on the first use, so the server recognizes the device
+// on the next logins. A non-empty name renames the device.
+func LoadDevice(name, deviceName string) (*service.Device, error) {
+	p, err := Load(name)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := p.ensureDeviceKey()
+	if err != nil {
+		return nil, err
+	}
+	if deviceName != "" && deviceName != p.DeviceName {
+		p.DeviceName, changed = deviceName, true
+	}
+	if changed {
+		if err := Save(name, p); err != nil {
+			return nil, err
+		}
+	}
+	return p.Device()
+}
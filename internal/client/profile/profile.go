@@ -0,0 +1,140 @@
+// Package profile keeps the settings of the client between the commands:
+// the server, the tokens of the session, the key of the device and the path
+// of the local store.
+// Every profile is a JSON file readable only by its owner.
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// DefaultName is the name of the profile used when the --profile flag is omitted.
+const DefaultName = "default"
+
+// DirEnv is the environment variable which overrides the directory of the profiles.
+const DirEnv = "GOPHKEEPER_CONFIG_DIR"
+
+// ErrBadName is returned when the name of the profile can't be used as a file name.
+var ErrBadName = errors.New("profile name may contain only letters, digits, '-' and '_'")
+
+// nameRe matches the allowed names of the profiles.
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Profile is a set of settings remembered between the commands.
+type Profile struct {
+	Server       string    `json:"server,omitempty"`
+	Transport    string    `json:"transport,omitempty"`
+	Username     string    `json:"username,omitempty"`
+	Token        string    `json:"token,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"` // ExpiresAt is the expiration time of the token.
+	File         string    `json:"file,omitempty"`       // File is the path of the local store kept by the sync command.
+	DeviceName   string    `json:"device_name,omitempty"`
+	// DeviceKey is the seed of the Ed25519 key of the device in base64.
+	// Its public key identifies the device on the server.
+	DeviceKey string `json:"device_key,omitempty"`
+}
+
+// SetTokens remembers the tokens of the session.
+func (p *Profile) SetTokens(tokens *models.TokenPair) {
+	p.Token, p.RefreshToken, p.ExpiresAt = tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresAt
+}
+
+// ClearTokens forgets the tokens of the session.
+func (p *Profile) ClearTokens() {
+	p.Token, p.RefreshToken, p.ExpiresAt = "", "", time.Time{}
+}
+
+// Expired reports whether the token has expired. Tokens without
+// the expiration time are never considered expired.
+func (p *Profile) Expired(now time.Time) bool {
+	return !p.ExpiresAt.IsZero() && !now.Before(p.ExpiresAt)
+}
+
+// Dir returns the directory of the profiles: the value of GOPHKEEPER_CONFIG_DIR
+// or gophkeeper/profiles inside the user's config directory.
+func Dir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gophkeeper", "profiles"), nil
+}
+
+// Path returns the path of the profile file.
+func Path(name string) (string, error) {
+	if !nameRe.MatchString(name) {
+		return "", fmt.Errorf("%w: %q", ErrBadName, name)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// Load reads the profile. A profile which has not been saved yet is empty.
+func Load(name string) (*Profile, error) {
+	path, err := Path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Profile{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	p := &Profile{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("bad profile %v: %w", path, err)
+	}
+	return p, nil
+}
+
+// Save writes the profile. The file is replaced atomically and
+// is readable only by the user since it contains the tokens.
+func Save(name string, p *Profile) error {
+	path, err := Path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	// CreateTemp creates the file with 0600 already, but umask-independent
+	// permissions are set explicitly
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
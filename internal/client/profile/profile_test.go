@@ -0,0 +1,204 @@
+package profile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/client/service"
+	"github.com/blokhinnv/gophkeeper/internal/client/service/mock"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(DirEnv, filepath.Join(dir, "profiles"))
+
+	t.Run("missing", func(t *testing.T) {
+		p, err := Load("work")
+		require.NoError(t, err)
+		assert.Equal(t, &Profile{}, p)
+	})
+	t.Run("ok", func(t *testing.T) {
+		p := &Profile{
+			Server:    "https://staging:8080",
+			Username:  "alice",
+			Token:     "token",
+			ExpiresAt: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+			File:      "/tmp/alice.sync",
+		}
+		require.NoError(t, Save("work", p))
+		got, err := Load("work")
+		require.NoError(t, err)
+		assert.Equal(t, p, got)
+
+		path, err := Path("work")
+		require.NoError(t, err)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		info, err = os.Stat(filepath.Dir(path))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	})
+	t.Run("bad_name", func(t *testing.T) {
+		_, err := Load("../work")
+		assert.ErrorIs(t, err, ErrBadName)
+		assert.ErrorIs(t, Save("", &Profile{}), ErrBadName)
+	})
+	t.Run("bad_file", func(t *testing.T) {
+		path, err := Path("broken")
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+		_, err = Load("broken")
+		assert.Error(t, err)
+	})
+}
+
+func TestProfile_Tokens(t *testing.T) {
+	now := time.Now()
+	p := &Profile{}
+	assert.False(t, p.Expired(now))
+
+	p.SetTokens(&models.TokenPair{AccessToken: "a", RefreshToken: "r", ExpiresAt: now})
+	assert.True(t, p.Expired(now))
+	assert.False(t, p.Expired(now.Add(-time.Second)))
+
+	p.ClearTokens()
+	assert.Equal(t, &Profile{}, p)
+}
+
+// newTestCommand returns a command with the flags the profile applies to.
+func newTestCommand(profileName string) *cobra.Command {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.PersistentFlags().String("server", "https://localhost:8080", "")
+	cmd.PersistentFlags().String("transport", "http", "")
+	cmd.PersistentFlags().String("profile", profileName, "")
+	cmd.PersistentFlags().String("token", "", "")
+	cmd.PersistentFlags().String("file", "", "")
+	cmd.PersistentFlags().String("store", "", "")
+	return cmd
+}
+
+func TestApply(t *testing.T) {
+	t.Setenv(DirEnv, t.TempDir())
+	require.NoError(t, Save("work", &Profile{
+		Server:    "https://staging:8080",
+		Transport: "grpc",
+		Token:     "token",
+		ExpiresAt: time.Now().Add(time.Hour),
+		File:      "/tmp/work.sync",
+	}))
+
+	t.Run("defaults", func(t *testing.T) {
+		cmd := newTestCommand("work")
+		require.NoError(t, MarkFileFlag(cmd, "file"))
+		require.NoError(t, MarkFileFlag(cmd, "store"))
+		_, err := Apply(cmd)
+		require.NoError(t, err)
+		assert.Equal(t, "https://staging:8080", cmd.Flag("server").Value.String())
+		assert.Equal(t, "grpc", cmd.Flag("transport").Value.String())
+		assert.Equal(t, "token", cmd.Flag("token").Value.String())
+		assert.Equal(t, "/tmp/work.sync", cmd.Flag("file").Value.String())
+		assert.Equal(t, "/tmp/work.sync", cmd.Flag("store").Value.String())
+	})
+	t.Run("explicit_flags", func(t *testing.T) {
+		cmd := newTestCommand("work")
+		require.NoError(t, cmd.ParseFlags([]string{"--server=https://prod:8080", "--token=mine"}))
+		_, err := Apply(cmd)
+		require.NoError(t, err)
+		assert.Equal(t, "https://prod:8080", cmd.Flag("server").Value.String())
+		assert.Equal(t, "mine", cmd.Flag("token").Value.String())
+		// the file flag is not marked
+		assert.Equal(t, "", cmd.Flag("file").Value.String())
+	})
+	t.Run("another_profile", func(t *testing.T) {
+		cmd := newTestCommand(DefaultName)
+		_, err := Apply(cmd)
+		require.NoError(t, err)
+		assert.Equal(t, "https://localhost:8080", cmd.Flag("server").Value.String())
+		assert.False(t, cmd.Flag("token").Changed)
+	})
+}
+
+func TestApply_Refresh(t *testing.T) {
+	t.Setenv(DirEnv, t.TempDir())
+	mockCtrl := gomock.NewController(t)
+	authService := mock.NewMockAuthService(mockCtrl)
+	defer func(f func(string, string) (service.AuthService, error)) { newAuthService = f }(newAuthService)
+	newAuthService = func(transport, addr string) (service.AuthService, error) {
+		assert.Equal(t, "https://staging:8080", addr)
+		return authService, nil
+	}
+	expired := &Profile{
+		Server:       "https://staging:8080",
+		Token:        "old",
+		RefreshToken: "refresh",
+		ExpiresAt:    time.Now().Add(-time.Minute),
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, Save("work", expired))
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		authService.EXPECT().Refresh("refresh", gomock.Nil()).Return(&models.TokenPair{
+			AccessToken:  "new",
+			RefreshToken: "new-refresh",
+			ExpiresAt:    expiresAt,
+		}, nil)
+		cmd := newTestCommand("work")
+		_, err := Apply(cmd)
+		require.NoError(t, err)
+		assert.Equal(t, "new", cmd.Flag("token").Value.String())
+
+		p, err := Load("work")
+		require.NoError(t, err)
+		assert.Equal(t, "new-refresh", p.RefreshToken)
+		assert.Equal(t, expiresAt, p.ExpiresAt)
+	})
+	t.Run("error", func(t *testing.T) {
+		require.NoError(t, Save("work", expired))
+		authService.EXPECT().Refresh("refresh", gomock.Nil()).Return(nil, errors.New("session revoked"))
+		cmd := newTestCommand("work")
+		_, err := Apply(cmd)
+		require.NoError(t, err)
+		assert.Equal(t, "old", cmd.Flag("token").Value.String())
+	})
+}
+
+func TestLoadDevice(t *testing.T) {
+	t.Setenv(DirEnv, t.TempDir())
+
+	device, err := LoadDevice("work", "")
+	require.NoError(t, err)
+	info := device.Info
+	info.Proof = device.Prove("nonce")
+	require.NoError(t, info.Validate())
+	hostname, _ := os.Hostname()
+	if hostname != "" {
+		assert.Equal(t, hostname, device.Info.Name)
+	}
+
+	// the key is kept in the profile, so the device is the same on the next login
+	again, err := LoadDevice("work", "laptop")
+	require.NoError(t, err)
+	assert.Equal(t, device.Info.PublicKey, again.Info.PublicKey)
+	assert.Equal(t, device.Key, again.Key)
+	assert.Equal(t, "laptop", again.Info.Name)
+	p, err := Load("work")
+	require.NoError(t, err)
+	assert.Equal(t, "laptop", p.DeviceName)
+
+	other, err := LoadDevice("home", "")
+	require.NoError(t, err)
+	assert.NotEqual(t, device.Info.PublicKey, other.Info.PublicKey)
+
+	_, err = (&Profile{DeviceKey: "bad"}).Device()
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+// Package progress draws the progress of the long transfers in the terminal.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// barWidth is the number of the cells of the bar.
+const barWidth = 30
+
+// Bar draws a single line progress bar, redrawing it in place on every update.
+type Bar struct {
+	w     io.Writer
+	label string
+	done  bool
+}
+
+// NewBar returns a new progress bar which writes to w.
+func NewBar(w io.Writer, label string) *Bar {
+	return &Bar{w: w, label: label}
+}
+
+// Update redraws the bar. The line is finished once all the bytes are transferred.
+func (b *Bar) Update(sent, total int64) {
+	if b.done {
+		return
+	}
+	ratio := 1.0
+	if total > 0 {
+		ratio = float64(sent) / float64(total)
+	}
+	filled := int(ratio * barWidth)
+	fmt.Fprintf(
+		b.w,
+		"\r%s [%s%s] %3d%% %s/%s",
+		b.label,
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", barWidth-filled),
+		int(ratio*100),
+		formatBytes(sent),
+		formatBytes(total),
+	)
+	if sent >= total {
+		b.done = true
+		fmt.Fprintln(b.w)
+	}
+}
+
+// formatBytes formats the number of bytes with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,40 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBar(t *testing.T) {
+	var buf bytes.Buffer
+	bar := NewBar(&buf, "movie.mkv")
+
+	bar.Update(512*1024, 2*1024*1024)
+	assert.Equal(
+		t,
+		"\rmovie.mkv [=======                       ]  25% 512.0 KiB/2.0 MiB",
+		buf.String(),
+	)
+	bar.Update(2*1024*1024, 2*1024*1024)
+	assert.True(t, strings.HasSuffix(buf.String(), "100% 2.0 MiB/2.0 MiB\n"))
+
+	// the finished bar isn't redrawn
+	n := buf.Len()
+	bar.Update(2*1024*1024, 2*1024*1024)
+	assert.Equal(t, n, buf.Len())
+}
+
+func TestBar_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	NewBar(&buf, "empty.txt").Update(0, 0)
+	assert.Equal(t, "\rempty.txt [==============================] 100% 0 B/0 B\n", buf.String())
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "1023 B", formatBytes(1023))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "3.0 GiB", formatBytes(3<<30))
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+
+	"github.com/go-resty/resty/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// grpcAuthService is an implementation of AuthService over gRPC.
+type grpcAuthService struct {
+	client pb.AuthClient
+}
+
+// NewGRPCAuthService creates a new instance of AuthService which uses the gRPC server addr.
+func NewGRPCAuthService(addr string, opts ...grpc.DialOption) (AuthService, error) {
+	conn, err := newGRPCConn(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcAuthService{client: pb.NewAuthClient(conn)}, nil
+}
+
+// deviceNonce returns a new nonce which the device signs with its key.
+func (s *grpcAuthService) deviceNonce() (string, error) {
+	resp, err := s.client.DeviceNonce(context.Background(), &pb.DeviceNonceRequest{})
+	if err != nil {
+		return "", grpcError(err)
+	}
+	return resp.GetNonce(), nil
+}
+
+// Auth authenticates a user with the given username and password and returns the tokens of the new session if successful.
+func (s *grpcAuthService) Auth(
+	username, password string,
+	device *Device,
+) (*srvrModels.TokenPair, error) {
+	info, err := proveDevice(device, s.deviceNonce)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Login(
+		context.Background(),
+		&pb.Credentials{Username: username, Password: password, Device: pb.NewDeviceInfo(info)},
+	)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	tokens := resp.ModelTokens()
+	return &tokens, nil
+}
+
+// AuthMFA exchanges the challenge token and the one-time or the recovery code for the tokens of the new session.
+func (s *grpcAuthService) AuthMFA(
+	mfaToken, code string,
+	device *Device,
+) (*srvrModels.TokenPair, error) {
+	info, err := proveDevice(device, s.deviceNonce)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.LoginMFA(
+		context.Background(),
+		&pb.LoginMFARequest{MfaToken: mfaToken, Code: code, Device: pb.NewDeviceInfo(info)},
+	)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	tokens := resp.ModelTokens()
+	return &tokens, nil
+}
+
+// Register creates a new user with the given username and password.
+func (s *grpcAuthService) Register(username, password string) error {
+	_, err := s.client.Register(
+		context.Background(),
+		&pb.Credentials{Username: username, Password: password},
+	)
+	if err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+// Refresh exchanges the refresh token for the new tokens of the session.
+func (s *grpcAuthService) Refresh(
+	refreshToken string,
+	device *Device,
+) (*srvrModels.TokenPair, error) {
+	info, err := proveDevice(device, s.deviceNonce)
+	if err != nil {
+		return nil, err
+	}
+	req := &pb.RefreshRequest{RefreshToken: refreshToken}
+	if info != nil {
+		req.Proof = pb.NewDeviceProof(info.Proof)
+	}
+	resp, err := s.client.Refresh(context.Background(), req)
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Unauthenticated:
+		if status.Convert(err).Message() == srvErrors.ErrBadDeviceProof.Error() {
+			return nil, srvErrors.ErrBadDeviceProof
+		}
+		return nil, srvErrors.ErrBadRefreshToken
+	default:
+		return nil, grpcError(err)
+	}
+	tokens := resp.ModelTokens()
+	return &tokens, nil
+}
+
+// Logout revokes the access token and finishes its session.
+func (s *grpcAuthService) Logout(token string) error {
+	_, err := s.client.Logout(tokenContext(context.Background(), token), &pb.LogoutRequest{})
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.Unauthenticated:
+		return srvErrors.ErrUnauthorized
+	default:
+		return grpcError(err)
+	}
+}
+
+// Sessions returns the active sessions of the user.
+func (s *grpcAuthService) Sessions(token string) ([]srvrModels.Session, error) {
+	resp, err := s.client.ListSessions(
+		tokenContext(context.Background(), token),
+		&pb.ListSessionsRequest{},
+	)
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Unauthenticated:
+		return nil, srvErrors.ErrUnauthorized
+	default:
+		return nil, grpcError(err)
+	}
+	sessions := make([]srvrModels.Session, 0, len(resp.GetSessions()))
+	for _, session := range resp.GetSessions() {
+		m, err := session.ModelSession()
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, m)
+	}
+	return sessions, nil
+}
+
+// RevokeSession finishes a session of the user.
+func (s *grpcAuthService) RevokeSession(token, sessionID string) error {
+	_, err := s.client.RevokeSession(
+		tokenContext(context.Background(), token),
+		&pb.RevokeSessionRequest{SessionId: sessionID},
+	)
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.Unauthenticated:
+		return srvErrors.ErrUnauthorized
+	case codes.NotFound:
+		return srvErrors.ErrSessionNotFound
+	default:
+		return grpcError(err)
+	}
+}
+
+// Devices returns the devices of the user.
+func (s *grpcAuthService) Devices(token string) ([]srvrModels.Device, error) {
+	resp, err := s.client.ListDevices(
+		tokenContext(context.Background(), token),
+		&pb.ListDevicesRequest{},
+	)
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Unauthenticated:
+		return nil, srvErrors.ErrUnauthorized
+	default:
+		return nil, grpcError(err)
+	}
+	devices := make([]srvrModels.Device, 0, len(resp.GetDevices()))
+	for _, device := range resp.GetDevices() {
+		m, err := device.ModelDevice()
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, m)
+	}
+	return devices, nil
+}
+
+// DeleteDevice removes a device of the user and signs it out.
+func (s *grpcAuthService) DeleteDevice(token, deviceID string) error {
+	_, err := s.client.DeleteDevice(
+		tokenContext(context.Background(), token),
+		&pb.DeleteDeviceRequest{DeviceId: deviceID},
+	)
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.Unauthenticated:
+		return srvErrors.ErrUnauthorized
+	case codes.NotFound:
+		return srvErrors.ErrDeviceNotFound
+	default:
+		return grpcError(err)
+	}
+}
+
+// grpcMFAError converts the error of the requests of the second factor.
+// The wrong password and the wrong code are told apart by the message.
+func grpcMFAError(err error) error {
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.Unauthenticated:
+		return srvErrors.ErrUnauthorized
+	case codes.PermissionDenied:
+		if status.Convert(err).Message() == srvErrors.ErrBadCredentials.Error() {
+			return srvErrors.ErrBadCredentials
+		}
+		return srvErrors.ErrBadMFACode
+	default:
+		return grpcError(err)
+	}
+}
+
+// EnrollMFA checks the password and generates a new secret of the second factor of the user.
+func (s *grpcAuthService) EnrollMFA(token, password string) (*srvrModels.MFAEnrollment, error) {
+	resp, err := s.client.EnrollMFA(
+		tokenContext(context.Background(), token),
+		&pb.EnrollMFARequest{Password: password},
+	)
+	if err := grpcMFAError(err); err != nil {
+		return nil, err
+	}
+	return &srvrModels.MFAEnrollment{Secret: resp.GetSecret(), URI: resp.GetUri()}, nil
+}
+
+// ConfirmMFA enables the second factor with the code of the enrolled secret and returns the recovery codes.
+func (s *grpcAuthService) ConfirmMFA(token, code string) ([]string, error) {
+	resp, err := s.client.ConfirmMFA(
+		tokenContext(context.Background(), token),
+		&pb.MFACodeRequest{Code: code},
+	)
+	if err := grpcMFAError(err); err != nil {
+		return nil, err
+	}
+	return resp.GetRecoveryCodes(), nil
+}
+
+// DisableMFA checks the password and the code and turns off the second factor of the user.
+func (s *grpcAuthService) DisableMFA(token, password, code string) error {
+	_, err := s.client.DisableMFA(
+		tokenContext(context.Background(), token),
+		&pb.DisableMFARequest{Password: password, Code: code},
+	)
+	return grpcMFAError(err)
+}
+
+// grpcPasswordError converts the error of the requests confirmed by the password.
+func grpcPasswordError(err error) error {
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.Unauthenticated:
+		return srvErrors.ErrUnauthorized
+	case codes.PermissionDenied:
+		return srvErrors.ErrBadCredentials
+	default:
+		return grpcError(err)
+	}
+}
+
+// ChangePassword replaces the password of the user and returns the tokens of the new session.
+func (s *grpcAuthService) ChangePassword(
+	token, oldPassword, newPassword string,
+) (*srvrModels.TokenPair, error) {
+	resp, err := s.client.ChangePassword(
+		tokenContext(context.Background(), token),
+		&pb.ChangePasswordRequest{OldPassword: oldPassword, NewPassword: newPassword},
+	)
+	if err := grpcPasswordError(err); err != nil {
+		return nil, err
+	}
+	tokens := resp.ModelTokens()
+	return &tokens, nil
+}
+
+// DeleteAccount deletes the user with all the data.
+func (s *grpcAuthService) DeleteAccount(token, password string) error {
+	_, err := s.client.DeleteAccount(
+		tokenContext(context.Background(), token),
+		&pb.DeleteAccountRequest{Password: password},
+	)
+	return grpcPasswordError(err)
+}
+
+// GetClient returns nil since the service does not use the REST API.
+func (s *grpcAuthService) GetClient() *resty.Client {
+	return nil
+}
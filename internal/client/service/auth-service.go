@@ -11,14 +11,46 @@ import (
 	"github.com/go-resty/resty/v2"
 
 	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
 )
 
 // AuthService is an interface that provides methods for authentication and registration.
 type AuthService interface {
-	// Auth authenticates a user with the given username and password and returns an authentication token if successful.
-	Auth(username, password string) (string, error)
+	// Auth authenticates a user with the given username and password and returns the tokens of the new session if successful.
+	// The session is bound to the device unless it is nil;
+	// the key of the device signs a nonce of the server.
+	// If the user has the second factor enabled, only the challenge token is returned with MFARequired set.
+	Auth(username, password string, device *Device) (*srvrModels.TokenPair, error)
+	// AuthMFA exchanges the challenge token and the one-time or the recovery code for the tokens of the new session.
+	AuthMFA(mfaToken, code string, device *Device) (*srvrModels.TokenPair, error)
 	// Register creates a new user with the given username and password.
 	Register(username, password string) error
+	// Refresh exchanges the refresh token for the new tokens of the session.
+	// The session bound to a device is refreshed with the key of the device,
+	// which may be nil otherwise.
+	Refresh(refreshToken string, device *Device) (*srvrModels.TokenPair, error)
+	// Logout revokes the access token and finishes its session.
+	Logout(token string) error
+	// Sessions returns the active sessions of the user.
+	Sessions(token string) ([]srvrModels.Session, error)
+	// RevokeSession finishes a session of the user.
+	RevokeSession(token, sessionID string) error
+	// Devices returns the devices of the user.
+	Devices(token string) ([]srvrModels.Device, error)
+	// DeleteDevice removes a device of the user and signs it out.
+	DeleteDevice(token, deviceID string) error
+	// EnrollMFA checks the password and generates a new secret of the second factor of the user.
+	EnrollMFA(token, password string) (*srvrModels.MFAEnrollment, error)
+	// ConfirmMFA enables the second factor with the code of the enrolled secret and returns the recovery codes.
+	ConfirmMFA(token, code string) ([]string, error)
+	// DisableMFA checks the password and the code and turns off the second factor of the user.
+	DisableMFA(token, password, code string) error
+	// ChangePassword replaces the password of the user and returns the tokens of the new session.
+	// All the other sessions of the user are finished.
+	ChangePassword(token, oldPassword, newPassword string) (*srvrModels.TokenPair, error)
+	// DeleteAccount deletes the user with all the data.
+	DeleteAccount(token, password string) error
 	// GetClient returns the service's client.
 	GetClient() *resty.Client
 }
@@ -49,24 +81,70 @@ type authResult struct {
 	Error string `json:"error"`
 }
 
-// Auth authenticates a user with the given username and password and returns an authentication token if successful.
-func (s *authService) Auth(username, password string) (string, error) {
-	r := &authResult{}
+// deviceNonce returns a new nonce which the device signs with its key.
+func (s *authService) deviceNonce() (string, error) {
+	nonce := &srvrModels.DeviceNonce{}
+	resp, err := s.client.R().
+		SetResult(nonce).
+		Post("/api/user/devices/nonce")
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return "", errors.New(resp.String())
+	}
+	return nonce.Nonce, nil
+}
+
+// Auth authenticates a user with the given username and password and returns the tokens of the new session if successful.
+func (s *authService) Auth(
+	username, password string,
+	device *Device,
+) (*srvrModels.TokenPair, error) {
+	info, err := proveDevice(device, s.deviceNonce)
+	if err != nil {
+		return nil, err
+	}
+	tokens := &srvrModels.TokenPair{}
 	resp, err := s.client.R().
 		SetHeader("Content-Type", "application/json").
-		SetBody(fmt.Sprintf(`{"username":"%s","password":"%s"}`, username, password)).
+		SetBody(srvrModels.LoginRequest{
+			UserCredentials: srvrModels.UserCredentials{Username: username, Password: password},
+			Device:          info,
+		}).
+		SetResult(tokens).
 		Put("/api/user/login")
 	if err != nil {
-		return "", fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
 	}
 	if resp.StatusCode() >= http.StatusBadRequest {
-		r.Error = resp.String()
+		return nil, errors.New(resp.String())
 	}
-	r.Token = resp.String()
-	if r.Error != "" {
-		return "", errors.New(r.Error)
+	return tokens, nil
+}
+
+// AuthMFA exchanges the challenge token and the one-time or the recovery code for the tokens of the new session.
+func (s *authService) AuthMFA(
+	mfaToken, code string,
+	device *Device,
+) (*srvrModels.TokenPair, error) {
+	info, err := proveDevice(device, s.deviceNonce)
+	if err != nil {
+		return nil, err
+	}
+	tokens := &srvrModels.TokenPair{}
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(srvrModels.MFALoginRequest{MFAToken: mfaToken, Code: code, Device: info}).
+		SetResult(tokens).
+		Put("/api/user/login/mfa")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return nil, errors.New(resp.String())
 	}
-	return r.Token, nil
+	return tokens, nil
 }
 
 // Register creates a new user with the given username and password.
@@ -89,6 +167,251 @@ func (s *authService) Register(username, password string) error {
 	return nil
 }
 
+// Refresh exchanges the refresh token for the new tokens of the session.
+// Returns ErrBadRefreshToken if the session has expired or has been revoked
+// and ErrBadDeviceProof if the device is not the one of the session.
+func (s *authService) Refresh(refreshToken string, device *Device) (*srvrModels.TokenPair, error) {
+	info, err := proveDevice(device, s.deviceNonce)
+	if err != nil {
+		return nil, err
+	}
+	req := srvrModels.RefreshRequest{RefreshToken: refreshToken}
+	if info != nil {
+		req.Proof = info.Proof
+	}
+	tokens := &srvrModels.TokenPair{}
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(req).
+		SetResult(tokens).
+		Post("/api/user/refresh")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	switch {
+	case resp.StatusCode() == http.StatusUnauthorized &&
+		resp.String() == srvErrors.ErrBadDeviceProof.Error():
+		return nil, srvErrors.ErrBadDeviceProof
+	case resp.StatusCode() == http.StatusUnauthorized:
+		return nil, srvErrors.ErrBadRefreshToken
+	case resp.StatusCode() >= http.StatusBadRequest:
+		return nil, errors.New(resp.String())
+	}
+	return tokens, nil
+}
+
+// Logout revokes the access token and finishes its session.
+func (s *authService) Logout(token string) error {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		Post("/api/user/logout")
+	if err != nil {
+		return fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	switch {
+	case resp.StatusCode() == http.StatusUnauthorized:
+		return srvErrors.ErrUnauthorized
+	case resp.StatusCode() >= http.StatusBadRequest:
+		return errors.New(resp.String())
+	}
+	return nil
+}
+
+// Sessions returns the active sessions of the user.
+func (s *authService) Sessions(token string) ([]srvrModels.Session, error) {
+	var sessions []srvrModels.Session
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetResult(&sessions).
+		Get("/api/user/sessions")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	switch {
+	case resp.StatusCode() == http.StatusUnauthorized:
+		return nil, srvErrors.ErrUnauthorized
+	case resp.StatusCode() >= http.StatusBadRequest:
+		return nil, errors.New(resp.String())
+	}
+	return sessions, nil
+}
+
+// RevokeSession finishes a session of the user.
+// Returns ErrSessionNotFound if there is no such active session.
+func (s *authService) RevokeSession(token, sessionID string) error {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetPathParam("sessionID", sessionID).
+		Delete("/api/user/sessions/{sessionID}")
+	if err != nil {
+		return fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	switch {
+	case resp.StatusCode() == http.StatusUnauthorized:
+		return srvErrors.ErrUnauthorized
+	case resp.StatusCode() == http.StatusNotFound:
+		return srvErrors.ErrSessionNotFound
+	case resp.StatusCode() >= http.StatusBadRequest:
+		return errors.New(resp.String())
+	}
+	return nil
+}
+
+// Devices returns the devices of the user.
+func (s *authService) Devices(token string) ([]srvrModels.Device, error) {
+	var devices []srvrModels.Device
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetResult(&devices).
+		Get("/api/user/devices")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	switch {
+	case resp.StatusCode() == http.StatusUnauthorized:
+		return nil, srvErrors.ErrUnauthorized
+	case resp.StatusCode() >= http.StatusBadRequest:
+		return nil, errors.New(resp.String())
+	}
+	return devices, nil
+}
+
+// DeleteDevice removes a device of the user and signs it out.
+// Returns ErrDeviceNotFound if the user has no such device.
+func (s *authService) DeleteDevice(token, deviceID string) error {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetPathParam("deviceID", deviceID).
+		Delete("/api/user/devices/{deviceID}")
+	if err != nil {
+		return fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	switch {
+	case resp.StatusCode() == http.StatusUnauthorized:
+		return srvErrors.ErrUnauthorized
+	case resp.StatusCode() == http.StatusNotFound:
+		return srvErrors.ErrDeviceNotFound
+	case resp.StatusCode() >= http.StatusBadRequest:
+		return errors.New(resp.String())
+	}
+	return nil
+}
+
+// mfaError converts the error response of the requests of the second factor.
+// The wrong password and the wrong code are told apart by the message.
+func mfaError(resp *resty.Response) error {
+	switch {
+	case resp.StatusCode() == http.StatusUnauthorized:
+		return srvErrors.ErrUnauthorized
+	case resp.StatusCode() == http.StatusForbidden &&
+		resp.String() == srvErrors.ErrBadCredentials.Error():
+		return srvErrors.ErrBadCredentials
+	case resp.StatusCode() == http.StatusForbidden:
+		return srvErrors.ErrBadMFACode
+	case resp.StatusCode() >= http.StatusBadRequest:
+		return errors.New(resp.String())
+	}
+	return nil
+}
+
+// EnrollMFA checks the password and generates a new secret of the second factor of the user.
+// Returns ErrBadCredentials if the password is incorrect.
+func (s *authService) EnrollMFA(token, password string) (*srvrModels.MFAEnrollment, error) {
+	enrollment := &srvrModels.MFAEnrollment{}
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetHeader("Content-Type", "application/json").
+		SetBody(srvrModels.MFAEnrollRequest{Password: password}).
+		SetResult(enrollment).
+		Post("/api/user/mfa")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if err := mfaError(resp); err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// ConfirmMFA enables the second factor with the code of the enrolled secret and returns the recovery codes.
+// Returns ErrBadMFACode if the code is incorrect.
+func (s *authService) ConfirmMFA(token, code string) ([]string, error) {
+	codes := &srvrModels.MFARecoveryCodes{}
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetHeader("Content-Type", "application/json").
+		SetBody(srvrModels.MFACodeRequest{Code: code}).
+		SetResult(codes).
+		Post("/api/user/mfa/confirm")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if err := mfaError(resp); err != nil {
+		return nil, err
+	}
+	return codes.RecoveryCodes, nil
+}
+
+// DisableMFA checks the password and the code and turns off the second factor of the user.
+// Returns ErrBadCredentials if the password is incorrect and ErrBadMFACode if the code is.
+func (s *authService) DisableMFA(token, password, code string) error {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetHeader("Content-Type", "application/json").
+		SetBody(srvrModels.MFADisableRequest{Password: password, Code: code}).
+		Delete("/api/user/mfa")
+	if err != nil {
+		return fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	return mfaError(resp)
+}
+
+// passwordError converts the error response of the requests confirmed by the password.
+func passwordError(resp *resty.Response) error {
+	switch {
+	case resp.StatusCode() == http.StatusUnauthorized:
+		return srvErrors.ErrUnauthorized
+	case resp.StatusCode() == http.StatusForbidden:
+		return srvErrors.ErrBadCredentials
+	case resp.StatusCode() >= http.StatusBadRequest:
+		return errors.New(resp.String())
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of the user and returns the tokens of the new session.
+// Returns ErrBadCredentials if the current password is incorrect.
+func (s *authService) ChangePassword(token, oldPassword, newPassword string) (*srvrModels.TokenPair, error) {
+	tokens := &srvrModels.TokenPair{}
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetHeader("Content-Type", "application/json").
+		SetBody(srvrModels.PasswordChangeRequest{OldPassword: oldPassword, NewPassword: newPassword}).
+		SetResult(tokens).
+		Post("/api/user/password")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if err := passwordError(resp); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// DeleteAccount deletes the user with all the data.
+// Returns ErrBadCredentials if the password is incorrect.
+func (s *authService) DeleteAccount(token, password string) error {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetHeader("Content-Type", "application/json").
+		SetBody(srvrModels.AccountDeleteRequest{Password: password}).
+		Delete("/api/user")
+	if err != nil {
+		return fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	return passwordError(resp)
+}
+
 // GetClient returns the service's client.
 func (s *authService) GetClient() *resty.Client {
 	return s.client
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
@@ -8,6 +9,10 @@ import (
 	"github.com/go-resty/resty/v2"
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
 )
 
 func TestNewConfiguredClient(t *testing.T) {
@@ -43,15 +48,53 @@ func TestAuthService_Auth(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
 		httpmock.Reset()
 
+		responder, err := httpmock.NewJsonResponder(200, srvrModels.TokenPair{
+			AccessToken:  "some token...",
+			RefreshToken: "refresh",
+			SessionID:    "1",
+		})
+		require.NoError(t, err)
 		httpmock.RegisterResponder(
 			http.MethodPut,
 			fmt.Sprintf("%v/api/user/login", baseURL),
-			httpmock.NewStringResponder(200, "some token..."),
+			responder,
 		)
 
-		resp, err := service.Auth("testuser", "testpassword")
-		assert.Equal(t, "some token...", resp)
+		resp, err := service.Auth("testuser", "testpassword", nil)
 		assert.Nil(t, err)
+		assert.Equal(t, "some token...", resp.AccessToken)
+		assert.Equal(t, "refresh", resp.RefreshToken)
+	})
+	t.Run("device", func(t *testing.T) {
+		httpmock.Reset()
+
+		device := newTestDevice()
+		nonce, err := httpmock.NewJsonResponder(200, srvrModels.DeviceNonce{Nonce: "nonce"})
+		require.NoError(t, err)
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			fmt.Sprintf("%v/api/user/devices/nonce", baseURL),
+			nonce,
+		)
+		httpmock.RegisterResponder(
+			http.MethodPut,
+			fmt.Sprintf("%v/api/user/login", baseURL),
+			func(req *http.Request) (*http.Response, error) {
+				var body srvrModels.LoginRequest
+				if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+					return nil, err
+				}
+				assert.Equal(t, "testuser", body.Username)
+				require.NotNil(t, body.Device)
+				assert.Equal(t, device.Info.PublicKey, body.Device.PublicKey)
+				assert.Equal(t, device.Prove("nonce"), body.Device.Proof)
+				return httpmock.NewJsonResponse(200, srvrModels.TokenPair{DeviceID: "device"})
+			},
+		)
+
+		resp, err := service.Auth("testuser", "testpassword", device)
+		require.NoError(t, err)
+		assert.Equal(t, "device", resp.DeviceID)
 	})
 	t.Run("fail", func(t *testing.T) {
 		httpmock.Reset()
@@ -62,10 +105,10 @@ func TestAuthService_Auth(t *testing.T) {
 			httpmock.NewStringResponder(400, "some error..."),
 		)
 
-		resp, err := service.Auth("testuser", "testpassword")
-		assert.Equal(t, "some error...", err.Error())
+		resp, err := service.Auth("testuser", "testpassword", nil)
 		assert.NotNil(t, err)
-		assert.Equal(t, "", resp)
+		assert.Equal(t, "some error...", err.Error())
+		assert.Nil(t, resp)
 	})
 }
 
@@ -104,3 +147,374 @@ func TestAuthService_Register(t *testing.T) {
 		assert.NotNil(t, err)
 	})
 }
+
+func TestAuthService_Refresh(t *testing.T) {
+	baseURL := "https://example.com"
+	service := NewAuthService(baseURL)
+	httpmock.ActivateNonDefault(service.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+	t.Run("ok", func(t *testing.T) {
+		httpmock.Reset()
+		responder, err := httpmock.NewJsonResponder(
+			200,
+			srvrModels.TokenPair{AccessToken: "token", RefreshToken: "new"},
+		)
+		require.NoError(t, err)
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			fmt.Sprintf("%v/api/user/refresh", baseURL),
+			responder,
+		)
+		resp, err := service.Refresh("refresh", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "new", resp.RefreshToken)
+	})
+	t.Run("device", func(t *testing.T) {
+		httpmock.Reset()
+		device := newTestDevice()
+		nonce, err := httpmock.NewJsonResponder(200, srvrModels.DeviceNonce{Nonce: "nonce"})
+		require.NoError(t, err)
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			fmt.Sprintf("%v/api/user/devices/nonce", baseURL),
+			nonce,
+		)
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			fmt.Sprintf("%v/api/user/refresh", baseURL),
+			func(req *http.Request) (*http.Response, error) {
+				var body srvrModels.RefreshRequest
+				if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+					return nil, err
+				}
+				assert.Equal(t, "refresh", body.RefreshToken)
+				assert.Equal(t, device.Prove("nonce"), body.Proof)
+				return httpmock.NewJsonResponse(200, srvrModels.TokenPair{RefreshToken: "new"})
+			},
+		)
+		resp, err := service.Refresh("refresh", device)
+		require.NoError(t, err)
+		assert.Equal(t, "new", resp.RefreshToken)
+	})
+	t.Run("bad_proof", func(t *testing.T) {
+		httpmock.Reset()
+		nonce, err := httpmock.NewJsonResponder(200, srvrModels.DeviceNonce{Nonce: "nonce"})
+		require.NoError(t, err)
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			fmt.Sprintf("%v/api/user/devices/nonce", baseURL),
+			nonce,
+		)
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			fmt.Sprintf("%v/api/user/refresh", baseURL),
+			httpmock.NewStringResponder(401, srvErrors.ErrBadDeviceProof.Error()),
+		)
+		_, err = service.Refresh("refresh", newTestDevice())
+		assert.ErrorIs(t, err, srvErrors.ErrBadDeviceProof)
+	})
+	t.Run("bad_token", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			fmt.Sprintf("%v/api/user/refresh", baseURL),
+			httpmock.NewStringResponder(401, "refresh token is invalid or expired"),
+		)
+		_, err := service.Refresh("refresh", nil)
+		assert.ErrorIs(t, err, srvErrors.ErrBadRefreshToken)
+	})
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	baseURL := "https://example.com"
+	service := NewAuthService(baseURL)
+	httpmock.ActivateNonDefault(service.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+	id := srvrModels.NewRandomObjectID()
+
+	t.Run("list", func(t *testing.T) {
+		httpmock.Reset()
+		responder, err := httpmock.NewJsonResponder(
+			200,
+			[]srvrModels.Session{{ID: id, Current: true}},
+		)
+		require.NoError(t, err)
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			fmt.Sprintf("%v/api/user/sessions", baseURL),
+			responder,
+		)
+		sessions, err := service.Sessions("token")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, id, sessions[0].ID)
+		assert.True(t, sessions[0].Current)
+	})
+	t.Run("list_unauthorized", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			fmt.Sprintf("%v/api/user/sessions", baseURL),
+			httpmock.NewStringResponder(401, "Unauthorized"),
+		)
+		_, err := service.Sessions("token")
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
+	})
+	t.Run("revoke", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodDelete,
+			fmt.Sprintf("%v/api/user/sessions/%v", baseURL, id.Hex()),
+			httpmock.NewStringResponder(200, "Session revoked"),
+		)
+		assert.NoError(t, service.RevokeSession("token", id.Hex()))
+	})
+	t.Run("revoke_not_found", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodDelete,
+			fmt.Sprintf("%v/api/user/sessions/%v", baseURL, id.Hex()),
+			httpmock.NewStringResponder(404, "session was not found"),
+		)
+		assert.ErrorIs(t, service.RevokeSession("token", id.Hex()), srvErrors.ErrSessionNotFound)
+	})
+	t.Run("logout", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			fmt.Sprintf("%v/api/user/logout", baseURL),
+			httpmock.NewStringResponder(200, "Logged out"),
+		)
+		assert.NoError(t, service.Logout("token"))
+	})
+	t.Run("logout_unauthorized", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			fmt.Sprintf("%v/api/user/logout", baseURL),
+			httpmock.NewStringResponder(401, "Unauthorized"),
+		)
+		assert.ErrorIs(t, service.Logout("token"), srvErrors.ErrUnauthorized)
+	})
+}
+
+func TestAuthService_Devices(t *testing.T) {
+	baseURL := "https://example.com"
+	service := NewAuthService(baseURL)
+	httpmock.ActivateNonDefault(service.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+	id := srvrModels.NewRandomObjectID()
+
+	t.Run("list", func(t *testing.T) {
+		httpmock.Reset()
+		responder, err := httpmock.NewJsonResponder(
+			200,
+			[]srvrModels.Device{{ID: id, DeviceInfo: srvrModels.DeviceInfo{Name: "laptop"}, Current: true}},
+		)
+		require.NoError(t, err)
+		httpmock.RegisterResponder(http.MethodGet, baseURL+"/api/user/devices", responder)
+		devices, err := service.Devices("token")
+		require.NoError(t, err)
+		require.Len(t, devices, 1)
+		assert.Equal(t, id, devices[0].ID)
+		assert.Equal(t, "laptop", devices[0].Name)
+		assert.True(t, devices[0].Current)
+	})
+	t.Run("list_unauthorized", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			baseURL+"/api/user/devices",
+			httpmock.NewStringResponder(401, "Unauthorized"),
+		)
+		_, err := service.Devices("token")
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
+	})
+	t.Run("delete", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodDelete,
+			fmt.Sprintf("%v/api/user/devices/%v", baseURL, id.Hex()),
+			httpmock.NewStringResponder(200, "Device deleted"),
+		)
+		assert.NoError(t, service.DeleteDevice("token", id.Hex()))
+	})
+	t.Run("delete_not_found", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodDelete,
+			fmt.Sprintf("%v/api/user/devices/%v", baseURL, id.Hex()),
+			httpmock.NewStringResponder(404, "device was not found"),
+		)
+		assert.ErrorIs(t, service.DeleteDevice("token", id.Hex()), srvErrors.ErrDeviceNotFound)
+	})
+}
+
+func TestAuthService_MFA(t *testing.T) {
+	baseURL := "https://example.com"
+	service := NewAuthService(baseURL)
+	httpmock.ActivateNonDefault(service.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	t.Run("login", func(t *testing.T) {
+		httpmock.Reset()
+		challenge, err := httpmock.NewJsonResponder(200, srvrModels.TokenPair{
+			MFARequired: true,
+			MFAToken:    "challenge",
+		})
+		require.NoError(t, err)
+		httpmock.RegisterResponder(http.MethodPut, baseURL+"/api/user/login", challenge)
+		tokens, err := service.Auth("testuser", "testpassword", nil)
+		require.NoError(t, err)
+		assert.True(t, tokens.MFARequired)
+		assert.Equal(t, "challenge", tokens.MFAToken)
+
+		session, err := httpmock.NewJsonResponder(200, srvrModels.TokenPair{AccessToken: "token"})
+		require.NoError(t, err)
+		httpmock.RegisterResponder(http.MethodPut, baseURL+"/api/user/login/mfa", session)
+		tokens, err = service.AuthMFA("challenge", "123456", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "token", tokens.AccessToken)
+	})
+	t.Run("login_bad_code", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPut,
+			baseURL+"/api/user/login/mfa",
+			httpmock.NewStringResponder(401, "one-time code is incorrect"),
+		)
+		_, err := service.AuthMFA("challenge", "000000", nil)
+		assert.EqualError(t, err, "one-time code is incorrect")
+	})
+	t.Run("enroll", func(t *testing.T) {
+		httpmock.Reset()
+		responder, err := httpmock.NewJsonResponder(200, srvrModels.MFAEnrollment{
+			Secret: "SECRET",
+			URI:    "otpauth://totp/GophKeeper:testuser?secret=SECRET",
+		})
+		require.NoError(t, err)
+		httpmock.RegisterResponder(http.MethodPost, baseURL+"/api/user/mfa", responder)
+		enrollment, err := service.EnrollMFA("token", "password")
+		require.NoError(t, err)
+		assert.Equal(t, "SECRET", enrollment.Secret)
+	})
+	t.Run("enroll_wrong_password", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			baseURL+"/api/user/mfa",
+			httpmock.NewStringResponder(403, srvErrors.ErrBadCredentials.Error()),
+		)
+		_, err := service.EnrollMFA("token", "wrong")
+		assert.ErrorIs(t, err, srvErrors.ErrBadCredentials)
+	})
+	t.Run("confirm", func(t *testing.T) {
+		httpmock.Reset()
+		responder, err := httpmock.NewJsonResponder(200, srvrModels.MFARecoveryCodes{
+			RecoveryCodes: []string{"abcd-efgh"},
+		})
+		require.NoError(t, err)
+		httpmock.RegisterResponder(http.MethodPost, baseURL+"/api/user/mfa/confirm", responder)
+		codes, err := service.ConfirmMFA("token", "123456")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"abcd-efgh"}, codes)
+	})
+	t.Run("confirm_bad_code", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			baseURL+"/api/user/mfa/confirm",
+			httpmock.NewStringResponder(403, "one-time code is incorrect"),
+		)
+		_, err := service.ConfirmMFA("token", "000000")
+		assert.ErrorIs(t, err, srvErrors.ErrBadMFACode)
+	})
+	t.Run("disable", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodDelete,
+			baseURL+"/api/user/mfa",
+			httpmock.NewStringResponder(200, "Second factor disabled"),
+		)
+		assert.NoError(t, service.DisableMFA("token", "password", "123456"))
+	})
+	t.Run("disable_bad_code", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodDelete,
+			baseURL+"/api/user/mfa",
+			httpmock.NewStringResponder(403, "one-time code is incorrect"),
+		)
+		assert.ErrorIs(
+			t,
+			service.DisableMFA("token", "password", "000000"),
+			srvErrors.ErrBadMFACode,
+		)
+	})
+	t.Run("disable_unauthorized", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodDelete,
+			baseURL+"/api/user/mfa",
+			httpmock.NewStringResponder(401, "Unauthorized"),
+		)
+		assert.ErrorIs(
+			t,
+			service.DisableMFA("token", "password", "123456"),
+			srvErrors.ErrUnauthorized,
+		)
+	})
+}
+
+func TestAuthService_Account(t *testing.T) {
+	baseURL := "https://example.com"
+	service := NewAuthService(baseURL)
+	httpmock.ActivateNonDefault(service.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	t.Run("change_password", func(t *testing.T) {
+		httpmock.Reset()
+		responder, err := httpmock.NewJsonResponder(200, srvrModels.TokenPair{
+			AccessToken:  "new-token",
+			RefreshToken: "new-refresh",
+		})
+		require.NoError(t, err)
+		httpmock.RegisterResponder(http.MethodPost, baseURL+"/api/user/password", responder)
+		tokens, err := service.ChangePassword("token", "old", "new")
+		require.NoError(t, err)
+		assert.Equal(t, "new-token", tokens.AccessToken)
+		assert.Equal(t, "new-refresh", tokens.RefreshToken)
+	})
+	t.Run("change_password_wrong", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			baseURL+"/api/user/password",
+			httpmock.NewStringResponder(403, "username or password is incorrect"),
+		)
+		_, err := service.ChangePassword("token", "wrong", "new")
+		assert.ErrorIs(t, err, srvErrors.ErrBadCredentials)
+	})
+	t.Run("delete_account", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodDelete,
+			baseURL+"/api/user",
+			httpmock.NewStringResponder(200, "Account deleted"),
+		)
+		assert.NoError(t, service.DeleteAccount("token", "password"))
+	})
+	t.Run("delete_account_locked", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodDelete,
+			baseURL+"/api/user",
+			httpmock.NewStringResponder(429, "too many failed logins, try again in 1m0s"),
+		)
+		assert.EqualError(
+			t,
+			service.DeleteAccount("token", "password"),
+			"too many failed logins, try again in 1m0s",
+		)
+	})
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"io"
+
+	"github.com/go-resty/resty/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// grpcBlobService implements the BlobService interface over gRPC.
+type grpcBlobService struct {
+	client pb.BlobsClient
+}
+
+// NewGRPCBlobService returns a new instance of BlobService which uses the gRPC server addr.
+func NewGRPCBlobService(addr string, opts ...grpc.DialOption) (BlobService, error) {
+	conn, err := newGRPCConn(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcBlobService{client: pb.NewBlobsClient(conn)}, nil
+}
+
+// grpcBlobError converts the status of the Blobs service into an error.
+func grpcBlobError(err error) error {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		return srvErrors.ErrUnauthorized
+	case codes.NotFound:
+		return srvErrors.ErrBlobNotFound
+	default:
+		return grpcError(err)
+	}
+}
+
+// modelBlob converts the message into the state of the upload.
+func modelBlob(resp *pb.Blob) (*srvrModels.Blob, error) {
+	blob, err := resp.ModelBlob()
+	if err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// ChunkSize returns the maximum size of a chunk accepted by the server.
+func (s *grpcBlobService) ChunkSize() int {
+	return srvrModels.BlobChunkSize
+}
+
+// Create starts an upload of a blob of the size.
+func (s *grpcBlobService) Create(size int64, token string) (*srvrModels.Blob, error) {
+	resp, err := s.client.Create(
+		tokenContext(context.Background(), token),
+		&pb.CreateBlobRequest{Size: size},
+	)
+	if err != nil {
+		return nil, grpcBlobError(err)
+	}
+	return modelBlob(resp)
+}
+
+// Get returns the state of the upload of a blob.
+func (s *grpcBlobService) Get(id srvrModels.ObjectID, token string) (*srvrModels.Blob, error) {
+	resp, err := s.client.Get(
+		tokenContext(context.Background(), token),
+		&pb.GetBlobRequest{BlobId: id.Hex()},
+	)
+	if err != nil {
+		return nil, grpcBlobError(err)
+	}
+	return modelBlob(resp)
+}
+
+// Append uploads the chunk starting at the offset. The FailedPrecondition status
+// with the current state of the upload in its details is converted into ErrBadChunkOffset.
+func (s *grpcBlobService) Append(
+	id srvrModels.ObjectID,
+	offset int64,
+	chunk []byte,
+	token string,
+) (*srvrModels.Blob, error) {
+	resp, err := s.client.Append(
+		tokenContext(context.Background(), token),
+		&pb.AppendChunkRequest{BlobId: id.Hex(), Offset: offset, Data: chunk},
+	)
+	if st := status.Convert(err); st.Code() == codes.FailedPrecondition {
+		for _, detail := range st.Details() {
+			if current, ok := detail.(*pb.Blob); ok {
+				blob, err := modelBlob(current)
+				if err != nil {
+					return nil, err
+				}
+				return blob, srvErrors.ErrBadChunkOffset
+			}
+		}
+	}
+	if err != nil {
+		return nil, grpcBlobError(err)
+	}
+	return modelBlob(resp)
+}
+
+// Download writes the content of the uploaded blob to w as the chunks arrive.
+func (s *grpcBlobService) Download(id srvrModels.ObjectID, w io.Writer, token string) error {
+	ctx, cancel := context.WithCancel(tokenContext(context.Background(), token))
+	defer cancel()
+	stream, err := s.client.Download(ctx, &pb.DownloadBlobRequest{BlobId: id.Hex()})
+	if err != nil {
+		return grpcBlobError(err)
+	}
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return grpcBlobError(err)
+		}
+		if _, err := w.Write(chunk.GetData()); err != nil {
+			return err
+		}
+	}
+}
+
+// GetClient returns nil since the service does not use the REST API.
+func (s *grpcBlobService) GetClient() *resty.Client {
+	return nil
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// BlobService defines the interface for uploading the content of the binary
+// records in chunks and downloading it back.
+type BlobService interface {
+	// ChunkSize returns the maximum size of a chunk of the content.
+	ChunkSize() int
+	// Create starts an upload of a blob of the size.
+	Create(size int64, token string) (*srvrModels.Blob, error)
+	// Get returns the state of the upload of a blob.
+	Get(id srvrModels.ObjectID, token string) (*srvrModels.Blob, error)
+	// Append uploads the chunk starting at the offset. If the chunk doesn't start
+	// where the uploaded content ends, the current state of the upload is returned
+	// with ErrBadChunkOffset.
+	Append(
+		id srvrModels.ObjectID,
+		offset int64,
+		chunk []byte,
+		token string,
+	) (*srvrModels.Blob, error)
+	// Download writes the content of the uploaded blob to w.
+	Download(id srvrModels.ObjectID, w io.Writer, token string) error
+	// GetClient returns the service's client.
+	GetClient() *resty.Client
+}
+
+// blobService is an implementation of the BlobService interface.
+type blobService struct {
+	client *resty.Client
+}
+
+// NewBlobService returns a new instance of BlobService.
+func NewBlobService(baseURL string) BlobService {
+	client := newConfiguredClient(baseURL)
+	return &blobService{client: client}
+}
+
+// blobError converts the response of the blob API into an error.
+func blobError(resp *resty.Response) error {
+	switch resp.StatusCode() {
+	case http.StatusUnauthorized:
+		return srvErrors.ErrUnauthorized
+	case http.StatusNotFound:
+		return srvErrors.ErrBlobNotFound
+	default:
+		return errors.New(resp.String())
+	}
+}
+
+// ChunkSize returns the maximum size of a chunk accepted by the server.
+func (s *blobService) ChunkSize() int {
+	return srvrModels.BlobChunkSize
+}
+
+// Create starts an upload of a blob of the size.
+func (s *blobService) Create(size int64, token string) (*srvrModels.Blob, error) {
+	blob := &srvrModels.Blob{}
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetBody(srvrModels.NewBlobRequest{Size: size}).
+		SetResult(blob).
+		Post("/api/blobs")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return nil, blobError(resp)
+	}
+	return blob, nil
+}
+
+// Get returns the state of the upload of a blob.
+func (s *blobService) Get(id srvrModels.ObjectID, token string) (*srvrModels.Blob, error) {
+	blob := &srvrModels.Blob{}
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetResult(blob).
+		Get(fmt.Sprintf("/api/blobs/%v", id.Hex()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return nil, blobError(resp)
+	}
+	return blob, nil
+}
+
+// Append uploads the chunk starting at the offset.
+func (s *blobService) Append(
+	id srvrModels.ObjectID,
+	offset int64,
+	chunk []byte,
+	token string,
+) (*srvrModels.Blob, error) {
+	blob := &srvrModels.Blob{}
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "application/octet-stream").
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetQueryParam("offset", fmt.Sprint(offset)).
+		SetBody(chunk).
+		SetResult(blob).
+		SetError(blob).
+		Put(fmt.Sprintf("/api/blobs/%v", id.Hex()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() == http.StatusConflict {
+		return blob, srvErrors.ErrBadChunkOffset
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return nil, blobError(resp)
+	}
+	return blob, nil
+}
+
+// Download writes the content of the uploaded blob to w. The content is streamed,
+// so it is never kept in memory as a whole.
+func (s *blobService) Download(id srvrModels.ObjectID, w io.Writer, token string) error {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetDoNotParseResponse(true).
+		Get(fmt.Sprintf("/api/blobs/%v/content", id.Hex()))
+	if err != nil {
+		return fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	body := resp.RawBody()
+	defer body.Close()
+	if resp.StatusCode() >= http.StatusBadRequest {
+		msg, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		switch resp.StatusCode() {
+		case http.StatusUnauthorized:
+			return srvErrors.ErrUnauthorized
+		case http.StatusNotFound:
+			return srvErrors.ErrBlobNotFound
+		default:
+			return errors.New(string(msg))
+		}
+	}
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	// the server can't change the status after the streaming has started
+	if length := resp.RawResponse.ContentLength; length >= 0 && n != length {
+		return fmt.Errorf("the content is truncated: %v of %v bytes", n, length)
+	}
+	return nil
+}
+
+// GetClient returns the service's client.
+func (s *blobService) GetClient() *resty.Client {
+	return s.client
+}
+
+// maxUploadRetries is the number of attempts to resume an upload after
+// the server has become unavailable.
+const maxUploadRetries = 3
+
+// uploadRetryDelay is the delay before the first attempt to resume an upload.
+// It is doubled after every failed attempt.
+var uploadRetryDelay = time.Second
+
+// UploadBlob uploads the content of the size read from r in chunks. An upload
+// interrupted by the unavailable server is resumed from the offset the server
+// has received. The progress is reported after every chunk.
+func UploadBlob(
+	service BlobService,
+	r io.ReaderAt,
+	size int64,
+	token string,
+	progress func(sent, total int64),
+) (*srvrModels.Blob, error) {
+	blob, err := service.Create(size, token)
+	if err != nil {
+		return nil, err
+	}
+	return ResumeUpload(service, blob, r, token, progress)
+}
+
+// ResumeUpload uploads the rest of the content of the blob read from r.
+func ResumeUpload(
+	service BlobService,
+	blob *srvrModels.Blob,
+	r io.ReaderAt,
+	token string,
+	progress func(sent, total int64),
+) (*srvrModels.Blob, error) {
+	chunk := make([]byte, service.ChunkSize())
+	retries := 0
+	for !blob.Complete() {
+		n := int64(len(chunk))
+		if left := blob.Size - blob.Received; left < n {
+			n = left
+		}
+		read, err := r.ReadAt(chunk[:n], blob.Received)
+		if int64(read) < n {
+			return nil, fmt.Errorf("unable to read the content at %v: %w", blob.Received, err)
+		}
+		next, err := service.Append(blob.BlobID, blob.Received, chunk[:n], token)
+		switch {
+		case errors.Is(err, srvErrors.ErrBadChunkOffset):
+			if next == nil || next.Received == blob.Received {
+				return nil, err
+			}
+		case errors.Is(err, clientErr.ErrServerUnavailable) && retries < maxUploadRetries:
+			time.Sleep(uploadRetryDelay << retries)
+			retries++
+			if next, err = service.Get(blob.BlobID, token); err != nil {
+				continue
+			}
+		case err != nil:
+			return nil, err
+		default:
+			retries = 0
+		}
+		blob = next
+		if progress != nil {
+			progress(blob.Received, blob.Size)
+		}
+	}
+	return blob, nil
+}
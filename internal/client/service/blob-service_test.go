@@ -0,0 +1,403 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// memoryBlobService keeps the blobs in memory. The chunks are limited by
+// chunkSize, and the appends listed in unavailable fail like the server
+// has become unavailable after it received the chunk.
+type memoryBlobService struct {
+	chunkSize   int
+	blobs       map[srvrModels.ObjectID]*srvrModels.Blob
+	content     map[srvrModels.ObjectID][]byte
+	appends     int
+	unavailable map[int]bool
+}
+
+func newMemoryBlobService(chunkSize int) *memoryBlobService {
+	return &memoryBlobService{
+		chunkSize:   chunkSize,
+		blobs:       make(map[srvrModels.ObjectID]*srvrModels.Blob),
+		content:     make(map[srvrModels.ObjectID][]byte),
+		unavailable: make(map[int]bool),
+	}
+}
+
+func (s *memoryBlobService) ChunkSize() int {
+	return s.chunkSize
+}
+
+func (s *memoryBlobService) Create(size int64, token string) (*srvrModels.Blob, error) {
+	blob := &srvrModels.Blob{BlobID: srvrModels.NewRandomObjectID(), Size: size}
+	s.blobs[blob.BlobID] = blob
+	return s.Get(blob.BlobID, token)
+}
+
+func (s *memoryBlobService) Get(id srvrModels.ObjectID, token string) (*srvrModels.Blob, error) {
+	blob, ok := s.blobs[id]
+	if !ok {
+		return nil, srvErrors.ErrBlobNotFound
+	}
+	res := *blob
+	return &res, nil
+}
+
+func (s *memoryBlobService) Append(
+	id srvrModels.ObjectID,
+	offset int64,
+	chunk []byte,
+	token string,
+) (*srvrModels.Blob, error) {
+	blob, ok := s.blobs[id]
+	if !ok {
+		return nil, srvErrors.ErrBlobNotFound
+	}
+	if len(chunk) > s.chunkSize || offset+int64(len(chunk)) > blob.Size {
+		return nil, srvErrors.ErrBadChunkSize
+	}
+	if offset != blob.Received {
+		current, _ := s.Get(id, token)
+		return current, srvErrors.ErrBadChunkOffset
+	}
+	s.content[id] = append(s.content[id], chunk...)
+	blob.Received += int64(len(chunk))
+	blob.Chunks++
+	s.appends++
+	if s.unavailable[s.appends] {
+		return nil, fmt.Errorf("%w: connection reset", clientErr.ErrServerUnavailable)
+	}
+	return s.Get(id, token)
+}
+
+func (s *memoryBlobService) Download(id srvrModels.ObjectID, w io.Writer, token string) error {
+	blob, ok := s.blobs[id]
+	if !ok {
+		return srvErrors.ErrBlobNotFound
+	}
+	if !blob.Complete() {
+		return srvErrors.ErrBlobIncomplete
+	}
+	// the content is written in pieces which don't match the chunks
+	content := s.content[id]
+	for len(content) > 0 {
+		n := 3
+		if n > len(content) {
+			n = len(content)
+		}
+		if _, err := w.Write(content[:n]); err != nil {
+			return err
+		}
+		content = content[n:]
+	}
+	return nil
+}
+
+func (s *memoryBlobService) GetClient() *resty.Client {
+	return nil
+}
+
+func TestBlobService(t *testing.T) {
+	baseURL := "https://example.com"
+	s := NewBlobService(baseURL)
+	client := s.GetClient()
+	assert.Equal(t, baseURL, client.HostURL)
+	assert.Equal(t, srvrModels.BlobChunkSize, s.ChunkSize())
+
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	id := srvrModels.NewRandomObjectID()
+	blobURL := fmt.Sprintf("%v/api/blobs/%v", baseURL, id.Hex())
+
+	t.Run("create", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			baseURL+"/api/blobs",
+			httpmock.NewJsonResponderOrPanic(201, srvrModels.Blob{BlobID: id, Size: 9}),
+		)
+		blob, err := s.Create(9, "token")
+		require.NoError(t, err)
+		assert.Equal(t, id, blob.BlobID)
+		assert.Equal(t, int64(9), blob.Size)
+	})
+	t.Run("create_unauthorized", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			baseURL+"/api/blobs",
+			httpmock.NewStringResponder(401, "unauthorized"),
+		)
+		_, err := s.Create(9, "token")
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
+	})
+	t.Run("get_not_found", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			blobURL,
+			httpmock.NewStringResponder(404, "blob was not found"),
+		)
+		_, err := s.Get(id, "token")
+		assert.ErrorIs(t, err, srvErrors.ErrBlobNotFound)
+	})
+	t.Run("append", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponderWithQuery(
+			http.MethodPut,
+			blobURL,
+			"offset=4",
+			func(req *http.Request) (*http.Response, error) {
+				body, err := io.ReadAll(req.Body)
+				if err != nil || string(body) != "o, go" {
+					return httpmock.NewStringResponse(400, "bad chunk"), nil
+				}
+				return httpmock.NewJsonResponse(200, srvrModels.Blob{BlobID: id, Size: 9, Received: 9})
+			},
+		)
+		blob, err := s.Append(id, 4, []byte("o, go"), "token")
+		require.NoError(t, err)
+		assert.True(t, blob.Complete())
+	})
+	t.Run("append_conflict", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponderWithQuery(
+			http.MethodPut,
+			blobURL,
+			"offset=0",
+			httpmock.NewJsonResponderOrPanic(409, srvrModels.Blob{BlobID: id, Size: 9, Received: 4}),
+		)
+		blob, err := s.Append(id, 0, []byte("hell"), "token")
+		assert.ErrorIs(t, err, srvErrors.ErrBadChunkOffset)
+		require.NotNil(t, blob)
+		assert.Equal(t, int64(4), blob.Received)
+	})
+	t.Run("download", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			blobURL+"/content",
+			httpmock.NewStringResponder(200, "hello, go").SetContentLength(),
+		)
+		var buf bytes.Buffer
+		require.NoError(t, s.Download(id, &buf, "token"))
+		assert.Equal(t, "hello, go", buf.String())
+	})
+	t.Run("download_truncated", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			blobURL+"/content",
+			func(req *http.Request) (*http.Response, error) {
+				resp := httpmock.NewStringResponse(200, "hello")
+				resp.ContentLength = 9
+				return resp, nil
+			},
+		)
+		assert.Error(t, s.Download(id, &bytes.Buffer{}, "token"))
+	})
+	t.Run("download_incomplete", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			blobURL+"/content",
+			httpmock.NewStringResponder(409, "blob upload is not complete"),
+		)
+		err := s.Download(id, &bytes.Buffer{}, "token")
+		assert.EqualError(t, err, "blob upload is not complete")
+	})
+	t.Run("unavailable", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			blobURL+"/content",
+			httpmock.NewErrorResponder(errors.New("connection refused")),
+		)
+		err := s.Download(id, &bytes.Buffer{}, "token")
+		assert.ErrorIs(t, err, clientErr.ErrServerUnavailable)
+	})
+}
+
+func TestUploadBlob(t *testing.T) {
+	uploadRetryDelay = 0
+	content := "hello, go! hello, blobs!"
+
+	t.Run("ok", func(t *testing.T) {
+		s := newMemoryBlobService(5)
+		var progress []int64
+		blob, err := UploadBlob(
+			s,
+			strings.NewReader(content),
+			int64(len(content)),
+			"token",
+			func(sent, total int64) {
+				assert.Equal(t, int64(len(content)), total)
+				progress = append(progress, sent)
+			},
+		)
+		require.NoError(t, err)
+		assert.True(t, blob.Complete())
+		assert.Equal(t, int64(5), blob.Chunks)
+		assert.Equal(t, []int64{5, 10, 15, 20, 24}, progress)
+
+		var buf bytes.Buffer
+		require.NoError(t, s.Download(blob.BlobID, &buf, "token"))
+		assert.Equal(t, content, buf.String())
+	})
+	t.Run("empty", func(t *testing.T) {
+		s := newMemoryBlobService(5)
+		blob, err := UploadBlob(s, strings.NewReader(""), 0, "token", nil)
+		require.NoError(t, err)
+		assert.True(t, blob.Complete())
+	})
+	t.Run("resumed", func(t *testing.T) {
+		s := newMemoryBlobService(5)
+		s.unavailable[2] = true
+		s.unavailable[4] = true
+		blob, err := UploadBlob(s, strings.NewReader(content), int64(len(content)), "token", nil)
+		require.NoError(t, err)
+		assert.True(t, blob.Complete())
+		assert.Equal(t, content, string(s.content[blob.BlobID]))
+	})
+	t.Run("unavailable", func(t *testing.T) {
+		s := newMemoryBlobService(5)
+		for i := 1; i <= maxUploadRetries+1; i++ {
+			s.unavailable[i] = true
+		}
+		_, err := UploadBlob(s, strings.NewReader(content), int64(len(content)), "token", nil)
+		assert.ErrorIs(t, err, clientErr.ErrServerUnavailable)
+	})
+	t.Run("short_content", func(t *testing.T) {
+		s := newMemoryBlobService(5)
+		_, err := UploadBlob(s, strings.NewReader("hello"), 10, "token", nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestE2EBlobService(t *testing.T) {
+	vault := NewVault(&memoryVaultService{params: newTestVaultParams(t, "master")}, "master")
+	content := "hello, go! hello, blobs!"
+
+	t.Run("round_trip", func(t *testing.T) {
+		inner := newMemoryBlobService(5 + 28)
+		s := NewE2EBlobService(inner, vault)
+		assert.Equal(t, 5, s.ChunkSize())
+
+		blob, err := UploadBlob(s, strings.NewReader(content), int64(len(content)), "token", nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), blob.Size)
+		assert.True(t, blob.Complete())
+
+		sealed, err := inner.Get(blob.BlobID, "token")
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)+5*28), sealed.Size)
+		assert.NotContains(t, string(inner.content[blob.BlobID]), "hello")
+
+		var buf bytes.Buffer
+		require.NoError(t, s.Download(blob.BlobID, &buf, "token"))
+		assert.Equal(t, content, buf.String())
+	})
+	t.Run("resumed", func(t *testing.T) {
+		inner := newMemoryBlobService(5 + 28)
+		inner.unavailable[3] = true
+		s := NewE2EBlobService(inner, vault)
+		blob, err := UploadBlob(s, strings.NewReader(content), int64(len(content)), "token", nil)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, s.Download(blob.BlobID, &buf, "token"))
+		assert.Equal(t, content, buf.String())
+	})
+	t.Run("wrong_password", func(t *testing.T) {
+		inner := newMemoryBlobService(5 + 28)
+		blob, err := UploadBlob(
+			NewE2EBlobService(inner, vault),
+			strings.NewReader(content),
+			int64(len(content)),
+			"token",
+			nil,
+		)
+		require.NoError(t, err)
+
+		wrong := NewVault(&memoryVaultService{params: newTestVaultParams(t, "master")}, "another")
+		err = NewE2EBlobService(inner, wrong).Download(blob.BlobID, &bytes.Buffer{}, "token")
+		assert.ErrorIs(t, err, clientErr.ErrWrongMasterPassword)
+	})
+	t.Run("empty", func(t *testing.T) {
+		inner := newMemoryBlobService(5 + 28)
+		s := NewE2EBlobService(inner, vault)
+		blob, err := UploadBlob(s, strings.NewReader(""), 0, "token", nil)
+		require.NoError(t, err)
+		assert.True(t, blob.Complete())
+		assert.Equal(t, int64(0), blob.Size)
+		// the empty content has the last chunk
+		assert.Len(t, inner.content[blob.BlobID], 28)
+
+		var buf bytes.Buffer
+		require.NoError(t, s.Download(blob.BlobID, &buf, "token"))
+		assert.Empty(t, buf.String())
+	})
+
+	// upload returns the e2e service with the content uploaded twice
+	// and the ids of both blobs.
+	type objectID = srvrModels.ObjectID
+	upload := func(t *testing.T) (*memoryBlobService, BlobService, objectID, objectID) {
+		inner := newMemoryBlobService(5 + 28)
+		s := NewE2EBlobService(inner, vault)
+		first, err := UploadBlob(s, strings.NewReader(content), int64(len(content)), "token", nil)
+		require.NoError(t, err)
+		second, err := UploadBlob(s, strings.NewReader(content), int64(len(content)), "token", nil)
+		require.NoError(t, err)
+		return inner, s, first.BlobID, second.BlobID
+	}
+	const sealedChunk = 5 + 28
+	t.Run("reordered", func(t *testing.T) {
+		inner, s, id, _ := upload(t)
+		sealed := inner.content[id]
+		reordered := append([]byte{}, sealed[sealedChunk:2*sealedChunk]...)
+		reordered = append(reordered, sealed[:sealedChunk]...)
+		inner.content[id] = append(reordered, sealed[2*sealedChunk:]...)
+		err := s.Download(id, &bytes.Buffer{}, "token")
+		assert.ErrorIs(t, err, errTamperedBlob)
+	})
+	t.Run("swapped", func(t *testing.T) {
+		inner, s, first, second := upload(t)
+		copy(inner.content[first][:sealedChunk], inner.content[second][:sealedChunk])
+		err := s.Download(first, &bytes.Buffer{}, "token")
+		assert.ErrorIs(t, err, errTamperedBlob)
+	})
+	t.Run("truncated", func(t *testing.T) {
+		inner, s, id, _ := upload(t)
+		// the server pretends the blob ends at a chunk boundary
+		inner.content[id] = inner.content[id][:2*sealedChunk]
+		inner.blobs[id].Size = 2 * sealedChunk
+		inner.blobs[id].Received = 2 * sealedChunk
+		inner.blobs[id].Chunks = 2
+		err := s.Download(id, &bytes.Buffer{}, "token")
+		assert.ErrorIs(t, err, errTamperedBlob)
+
+		// or that it is empty
+		inner.content[id] = nil
+		inner.blobs[id].Size = 28
+		inner.blobs[id].Received = 28
+		inner.blobs[id].Chunks = 1
+		err = s.Download(id, &bytes.Buffer{}, "token")
+		assert.ErrorIs(t, err, errTamperedBlob)
+	})
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// ConflictResolution is a way to resolve the conflict of an update with
+// the changes made by another client.
+type ConflictResolution string
+
+const (
+	// KeepMine overwrites the current copy on the server with the local changes.
+	KeepMine ConflictResolution = "mine"
+	// KeepTheirs discards the local changes and keeps the current copy on the server.
+	KeepTheirs ConflictResolution = "theirs"
+	// MergeMetadata keeps the local data and merges the metadata of both copies.
+	// The local values win for the same keys.
+	MergeMetadata ConflictResolution = "merge"
+)
+
+// ConflictResolutions is the list of the ways to resolve a conflict.
+var ConflictResolutions = []ConflictResolution{KeepMine, KeepTheirs, MergeMetadata}
+
+// NewConflictResolution validates the name of the way to resolve a conflict.
+func NewConflictResolution(name string) (ConflictResolution, error) {
+	for _, r := range ConflictResolutions {
+		if string(r) == name {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("unknown conflict resolution %q: use mine, theirs or merge", name)
+}
+
+// mergeMetadata returns the metadata of both copies. The values of mine win.
+func mergeMetadata(mine, theirs srvrModels.Metadata) srvrModels.Metadata {
+	merged := make(srvrModels.Metadata, len(mine)+len(theirs))
+	for k, v := range theirs {
+		merged[k] = v
+	}
+	for k, v := range mine {
+		merged[k] = v
+	}
+	return merged
+}
+
+// resolvedBody returns the update request body which is applied on top of the current copy.
+func resolvedBody(
+	body string,
+	current srvrModels.UntypedRecord,
+	resolution ConflictResolution,
+) (string, error) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return "", err
+	}
+	fields["expected_version"] = current.Version
+	if resolution == MergeMetadata {
+		var record updateBody
+		if err := json.Unmarshal([]byte(body), &record); err != nil {
+			return "", err
+		}
+		for k := range fields {
+			if strings.EqualFold(k, "metadata") {
+				delete(fields, k)
+			}
+		}
+		fields["metadata"] = mergeMetadata(record.Metadata, current.Metadata)
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ResolveConflict resolves the conflict of the update request body with the
+// current copy of the record on the server and sends the resolved update with
+// the service. If the local changes are discarded, nothing is sent.
+func ResolveConflict(
+	service StorageService,
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+	conflict *clientErr.ConflictError,
+	resolution ConflictResolution,
+) (string, error) {
+	if resolution == KeepTheirs {
+		return fmt.Sprintf(
+			"Local changes discarded, record id=%v keeps version %v",
+			conflict.Current.RecordID.Hex(),
+			conflict.Current.Version,
+		), nil
+	}
+	resolved, err := resolvedBody(body, conflict.Current, resolution)
+	if err != nil {
+		return "", err
+	}
+	return service.Update(resolved, collectionName, token)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// recordingStorage remembers the body of the last update.
+type recordingStorage struct {
+	StorageService
+	body string
+}
+
+func (s *recordingStorage) Update(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	s.body = body
+	return "updated", nil
+}
+
+func TestNewConflictResolution(t *testing.T) {
+	r, err := NewConflictResolution("merge")
+	require.NoError(t, err)
+	assert.Equal(t, MergeMetadata, r)
+	_, err = NewConflictResolution("ask")
+	assert.Error(t, err)
+}
+
+func TestResolveConflict(t *testing.T) {
+	id := srvrModels.NewRandomObjectID()
+	conflict := &clientErr.ConflictError{Current: srvrModels.UntypedRecord{
+		UntypedRecordContent: srvrModels.UntypedRecordContent{
+			Data:     "theirs",
+			Metadata: srvrModels.Metadata{"site": "example.com", "tag": "old"},
+		},
+		RecordID: id,
+		Version:  4,
+	}}
+	body := `{"record_id": "` + id.Hex() + `", "Data": "mine", "Metadata": {"tag": "new"}, "expected_version": 3}`
+
+	t.Run("mine", func(t *testing.T) {
+		s := &recordingStorage{}
+		msg, err := ResolveConflict(s, body, srvrModels.TextCollection, "token", conflict, KeepMine)
+		require.NoError(t, err)
+		assert.Equal(t, "updated", msg)
+		assert.JSONEq(
+			t,
+			`{"record_id": "`+id.Hex()+`", "Data": "mine", "Metadata": {"tag": "new"}, "expected_version": 4}`,
+			s.body,
+		)
+	})
+	t.Run("theirs", func(t *testing.T) {
+		s := &recordingStorage{}
+		msg, err := ResolveConflict(s, body, srvrModels.TextCollection, "token", conflict, KeepTheirs)
+		require.NoError(t, err)
+		assert.Contains(t, msg, "discarded")
+		assert.Empty(t, s.body)
+	})
+	t.Run("merge", func(t *testing.T) {
+		s := &recordingStorage{}
+		_, err := ResolveConflict(s, body, srvrModels.TextCollection, "token", conflict, MergeMetadata)
+		require.NoError(t, err)
+		assert.JSONEq(
+			t,
+			`{"record_id": "`+id.Hex()+`", "Data": "mine", "metadata": {"site": "example.com", "tag": "new"}, "expected_version": 4}`,
+			s.body,
+		)
+	})
+	t.Run("bad_body", func(t *testing.T) {
+		_, err := ResolveConflict(&recordingStorage{}, `{`, srvrModels.TextCollection, "token", conflict, KeepMine)
+		assert.Error(t, err)
+	})
+}
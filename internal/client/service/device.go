@@ -0,0 +1,55 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// Device is the device of the client registered on login. The private key
+// never leaves the client: it signs the nonces of the server, so the server
+// registers the device and refreshes its sessions only for the holder of the key.
+type Device struct {
+	Info srvrModels.DeviceInfo
+	Key  ed25519.PrivateKey
+}
+
+// NewDevice creates the device with the key. The public key of the info
+// is set from the private one.
+func NewDevice(name, platform string, key ed25519.PrivateKey) *Device {
+	return &Device{
+		Info: srvrModels.DeviceInfo{
+			Name:      name,
+			Platform:  platform,
+			PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		},
+		Key: key,
+	}
+}
+
+// Prove returns the proof of the key of the device: the nonce signed with the key.
+func (d *Device) Prove(nonce string) *srvrModels.DeviceProof {
+	return &srvrModels.DeviceProof{
+		Nonce:     nonce,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(d.Key, []byte(nonce))),
+	}
+}
+
+// proveDevice returns the info of the device with the proof of its key signed
+// over a new nonce of the server. Returns nil for the nil device.
+func proveDevice(
+	device *Device,
+	nonce func() (string, error),
+) (*srvrModels.DeviceInfo, error) {
+	if device == nil {
+		return nil, nil
+	}
+	n, err := nonce()
+	if err != nil {
+		return nil, err
+	}
+	info := device.Info
+	info.Proof = device.Prove(n)
+	return &info, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDevice returns the device with the fixed key.
+func newTestDevice() *Device {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	return NewDevice("laptop", "linux/amd64", key)
+}
+
+func TestDevice_Prove(t *testing.T) {
+	device := newTestDevice()
+	publicKey, err := base64.StdEncoding.DecodeString(device.Info.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, ed25519.PublicKey(publicKey), device.Key.Public())
+
+	proof := device.Prove("nonce")
+	assert.Equal(t, "nonce", proof.Nonce)
+	signature, err := base64.StdEncoding.DecodeString(proof.Signature)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(publicKey, []byte("nonce"), signature))
+}
+
+func TestProveDevice(t *testing.T) {
+	nonce := func() (string, error) { return "nonce", nil }
+	t.Run("ok", func(t *testing.T) {
+		device := newTestDevice()
+		info, err := proveDevice(device, nonce)
+		require.NoError(t, err)
+		assert.Equal(t, device.Prove("nonce"), info.Proof)
+		assert.Nil(t, device.Info.Proof, "the info of the device should not be changed")
+	})
+	t.Run("no_device", func(t *testing.T) {
+		info, err := proveDevice(nil, func() (string, error) {
+			t.Fatal("no nonce should be requested without the device")
+			return "", nil
+		})
+		require.NoError(t, err)
+		assert.Nil(t, info)
+	})
+	t.Run("nonce_fail", func(t *testing.T) {
+		errNonce := errors.New("no nonce")
+		_, err := proveDevice(newTestDevice(), func() (string, error) { return "", errNonce })
+		assert.ErrorIs(t, err, errNonce)
+	})
+}
@@ -0,0 +1,488 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+// e2eStorageService wraps a StorageService and encrypts the records
+// end-to-end before they are sent to the server.
+type e2eStorageService struct {
+	StorageService
+	vault *Vault
+}
+
+// NewE2EStorageService returns a StorageService which encrypts the data and the
+// metadata of the records with the vault key and sends them with the service.
+func NewE2EStorageService(service StorageService, vault *Vault) StorageService {
+	return &e2eStorageService{StorageService: service, vault: vault}
+}
+
+// sealedBody is the body of an end-to-end encrypted record.
+type sealedBody struct {
+	RecordID        string `json:"record_id,omitempty"`
+	Data            any    `json:"data"`
+	ExpectedVersion int64  `json:"expected_version,omitempty"`
+}
+
+// sealBody replaces the data and the metadata in the body with the envelope
+// bound to the record. A new record without an ID gets the random one, since
+// the envelope is sealed before the server knows the record.
+func (s *e2eStorageService) sealBody(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, srvrModels.ObjectID, error) {
+	var record struct {
+		RecordID srvrModels.ObjectID `json:"record_id"`
+		srvrModels.UntypedRecordContent
+		ExpectedVersion int64 `json:"expected_version,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(body), &record); err != nil {
+		return "", record.RecordID, err
+	}
+	if record.RecordID.IsZero() {
+		record.RecordID = srvrModels.NewRandomObjectID()
+	}
+	envelope, err := s.vault.Seal(token, collectionName, record.RecordID, record.UntypedRecordContent)
+	if err != nil {
+		return "", record.RecordID, err
+	}
+	b, err := json.Marshal(sealedBody{
+		RecordID:        record.RecordID.Hex(),
+		Data:            envelope,
+		ExpectedVersion: record.ExpectedVersion,
+	})
+	if err != nil {
+		return "", record.RecordID, err
+	}
+	return string(b), record.RecordID, nil
+}
+
+// Add encrypts the item and adds it to a specific collection.
+func (s *e2eStorageService) Add(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	sealed, _, err := s.sealBody(body, collectionName, token)
+	if err != nil {
+		return "", err
+	}
+	return s.StorageService.Add(sealed, collectionName, token)
+}
+
+// Update encrypts the item and updates it in a specific collection.
+func (s *e2eStorageService) Update(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	sealed, id, err := s.sealBody(body, collectionName, token)
+	if err != nil {
+		return "", err
+	}
+	msg, err := s.StorageService.Update(sealed, collectionName, token)
+	var conflict *clientErr.ConflictError
+	if errors.As(err, &conflict) && srvrModels.IsEncryptedData(conflict.Current.Data) {
+		// the current copy is decrypted to resolve the conflict
+		ciphertext, cErr := srvrModels.EncryptedCiphertext(conflict.Current.Data)
+		if cErr != nil {
+			return "", cErr
+		}
+		conflict.Current.UntypedRecordContent, cErr = s.vault.Open(token, collectionName, id, ciphertext)
+		if cErr != nil {
+			return "", cErr
+		}
+	}
+	return msg, err
+}
+
+// openContent decrypts the content of the record with the vault key if it is
+// an envelope of an encrypted record.
+func openContent(
+	vault *Vault,
+	token string,
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	content *srvrModels.UntypedRecordContent,
+) error {
+	if !srvrModels.IsEncryptedData(content.Data) {
+		return nil
+	}
+	ciphertext, err := srvrModels.EncryptedCiphertext(content.Data)
+	if err != nil {
+		return err
+	}
+	opened, err := vault.Open(token, collectionName, id, ciphertext)
+	if err != nil {
+		return err
+	}
+	*content = opened
+	return nil
+}
+
+// Trash returns the deleted items of a specific collection and decrypts the encrypted ones.
+func (s *e2eStorageService) Trash(
+	collectionName srvrModels.CollectionName,
+	token string,
+) ([]srvrModels.UntypedRecord, error) {
+	records, err := s.StorageService.Trash(collectionName, token)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		err := openContent(
+			s.vault,
+			token,
+			collectionName,
+			records[i].RecordID,
+			&records[i].UntypedRecordContent,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// History returns the previous revisions of an item and decrypts the encrypted ones.
+func (s *e2eStorageService) History(
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	token string,
+) ([]srvrModels.Revision, error) {
+	revisions, err := s.StorageService.History(collectionName, id, token)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		err := openContent(
+			s.vault,
+			token,
+			collectionName,
+			revisions[i].RecordID,
+			&revisions[i].UntypedRecordContent,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+// e2eSyncService wraps a SyncService and decrypts the end-to-end encrypted records.
+type e2eSyncService struct {
+	SyncService
+	vault *Vault
+}
+
+// NewE2ESyncService returns a SyncService which decrypts the records
+// received by the service with the vault key.
+func NewE2ESyncService(service SyncService, vault *Vault) SyncService {
+	return &e2eSyncService{SyncService: service, vault: vault}
+}
+
+// open decrypts the encrypted records and moves them to their collections.
+func (s *e2eSyncService) open(token string, r *clientModels.SyncResponse) error {
+	encrypted := r.Encrypted
+	r.Encrypted = nil
+	for _, record := range encrypted {
+		content, err := s.vault.Open(token, record.Collection, record.RecordID, record.Ciphertext)
+		if err != nil {
+			return err
+		}
+		err = r.AppendRecord(record.Collection, srvrModels.UntypedRecord{
+			UntypedRecordContent: content,
+			RecordID:             record.RecordID,
+			Version:              record.Version,
+			UpdatedAt:            record.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync syncs data from collections and decrypts it. The vault is unlocked
+// first, so a wrong master password is reported even if there are no
+// encrypted records yet.
+func (s *e2eSyncService) Sync(
+	token string,
+	collectionNames []srvrModels.CollectionName,
+) (*clientModels.SyncResponse, error) {
+	if err := s.vault.Unlock(token); err != nil {
+		return nil, err
+	}
+	r, err := s.SyncService.Sync(token, collectionNames)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.open(token, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Changes retrieves the changes of the records since the cursor and decrypts
+// the created and updated records.
+func (s *e2eSyncService) Changes(token string, cursor string) (*srvrModels.Changes, error) {
+	if err := s.vault.Unlock(token); err != nil {
+		return nil, err
+	}
+	changes, err := s.SyncService.Changes(token, cursor)
+	if err != nil {
+		return nil, err
+	}
+	for i := range changes.Upserts {
+		upsert := &changes.Upserts[i]
+		err := openContent(
+			s.vault,
+			token,
+			upsert.Collection,
+			upsert.Record.RecordID,
+			&upsert.Record.UntypedRecordContent,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// SyncRecord retrieves a single record of the collection and decrypts it.
+func (s *e2eSyncService) SyncRecord(
+	token string,
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+) (*clientModels.SyncResponse, error) {
+	r, err := s.SyncService.SyncRecord(token, collectionName, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.open(token, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// e2eBlobService wraps a BlobService and encrypts every chunk of the content
+// end-to-end before it is uploaded. A sealed chunk is longer than the plain one
+// by encrypt.SealOverhead, so the sizes and the offsets of the wrapped service
+// are converted: the content is cut into chunks of ChunkSize bytes, and the
+// sealed chunks of the whole size of the wrapped service are decrypted one by one.
+// Every chunk is bound to the blob, to its position and to whether it is the last
+// one, so the server can't reorder, swap or cut the chunks; the empty content
+// is uploaded as one empty chunk.
+type e2eBlobService struct {
+	BlobService
+	vault *Vault
+	mu    sync.Mutex
+	sizes map[srvrModels.ObjectID]int64 // The plain sizes of the blobs being uploaded.
+}
+
+// NewE2EBlobService returns a BlobService which encrypts the chunks of the content
+// with the vault key and uploads them with the service.
+func NewE2EBlobService(service BlobService, vault *Vault) BlobService {
+	return &e2eBlobService{
+		BlobService: service,
+		vault:       vault,
+		sizes:       make(map[srvrModels.ObjectID]int64),
+	}
+}
+
+// chunkAD returns the additional data which binds the sealed chunk to the blob,
+// to the index of the chunk and to whether the chunk is the last one.
+func chunkAD(id srvrModels.ObjectID, index int64, final bool) []byte {
+	return []byte(id.Hex() + "|" + strconv.FormatInt(index, 10) + "|" + strconv.FormatBool(final))
+}
+
+// ChunkSize returns the size of a plain chunk which fits the wrapped service once sealed.
+func (s *e2eBlobService) ChunkSize() int {
+	return s.BlobService.ChunkSize() - encrypt.SealOverhead
+}
+
+// sealedSize returns the size of the sealed content of the plain size.
+func (s *e2eBlobService) sealedSize(size int64) int64 {
+	chunkSize := int64(s.ChunkSize())
+	chunks := (size + chunkSize - 1) / chunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return size + chunks*encrypt.SealOverhead
+}
+
+// plainBlob converts the state of the upload of the sealed content
+// into the state of the upload of the plain one.
+func (s *e2eBlobService) plainBlob(sealed *srvrModels.Blob) *srvrModels.Blob {
+	if sealed == nil {
+		return nil
+	}
+	sealedChunkSize := int64(s.BlobService.ChunkSize())
+	chunks := (sealed.Size + sealedChunkSize - 1) / sealedChunkSize
+	plain := *sealed
+	plain.Size -= chunks * encrypt.SealOverhead
+	plain.Received -= sealed.Chunks * encrypt.SealOverhead
+	s.mu.Lock()
+	s.sizes[plain.BlobID] = plain.Size
+	s.mu.Unlock()
+	return &plain
+}
+
+// Create starts an upload of the sealed content of the plain size.
+// The empty content is uploaded at once as one empty chunk.
+func (s *e2eBlobService) Create(size int64, token string) (*srvrModels.Blob, error) {
+	if err := s.vault.Unlock(token); err != nil {
+		return nil, err
+	}
+	blob, err := s.BlobService.Create(s.sealedSize(size), token)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return s.Append(blob.BlobID, 0, nil, token)
+	}
+	return s.plainBlob(blob), nil
+}
+
+// Get returns the state of the upload of the plain content.
+func (s *e2eBlobService) Get(id srvrModels.ObjectID, token string) (*srvrModels.Blob, error) {
+	blob, err := s.BlobService.Get(id, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.plainBlob(blob), nil
+}
+
+// size returns the plain size of the blob.
+func (s *e2eBlobService) size(id srvrModels.ObjectID, token string) (int64, error) {
+	s.mu.Lock()
+	size, ok := s.sizes[id]
+	s.mu.Unlock()
+	if ok {
+		return size, nil
+	}
+	blob, err := s.Get(id, token)
+	if err != nil {
+		return 0, err
+	}
+	return blob.Size, nil
+}
+
+// Append seals the chunk and uploads it. The offset must be a multiple of ChunkSize.
+func (s *e2eBlobService) Append(
+	id srvrModels.ObjectID,
+	offset int64,
+	chunk []byte,
+	token string,
+) (*srvrModels.Blob, error) {
+	chunkSize := int64(s.ChunkSize())
+	if offset%chunkSize != 0 || len(chunk) > int(chunkSize) {
+		return nil, fmt.Errorf("%w: offset %v", srvErrors.ErrBadChunkOffset, offset)
+	}
+	key, err := s.vault.unlock(token)
+	if err != nil {
+		return nil, err
+	}
+	size, err := s.size(id, token)
+	if err != nil {
+		return nil, err
+	}
+	final := offset+int64(len(chunk)) >= size
+	sealed, err := encrypt.SealBytesWithAD(chunk, key, chunkAD(id, offset/chunkSize, final))
+	if err != nil {
+		return nil, err
+	}
+	sealedOffset := offset / chunkSize * int64(s.BlobService.ChunkSize())
+	blob, err := s.BlobService.Append(id, sealedOffset, sealed, token)
+	return s.plainBlob(blob), err
+}
+
+// errTamperedBlob is returned when the chunks of the downloaded blob are
+// missing, reordered or taken from another blob.
+var errTamperedBlob = errors.New("the content of the blob is tampered with")
+
+// Download writes the decrypted content of the uploaded blob to w.
+// The content without the last chunk or of the size other than the size
+// of the blob is rejected.
+func (s *e2eBlobService) Download(id srvrModels.ObjectID, w io.Writer, token string) error {
+	key, err := s.vault.unlock(token)
+	if err != nil {
+		return err
+	}
+	blob, err := s.Get(id, token)
+	if err != nil {
+		return err
+	}
+	opener := &chunkOpener{w: w, key: key, id: id, size: s.BlobService.ChunkSize()}
+	if err := s.BlobService.Download(id, opener, token); err != nil {
+		return err
+	}
+	if err := opener.flush(); err != nil {
+		return err
+	}
+	if opener.written != blob.Size {
+		return fmt.Errorf("%w: %v of %v bytes", errTamperedBlob, opener.written, blob.Size)
+	}
+	return nil
+}
+
+// chunkOpener decrypts the stream of the sealed chunks of the size of the blob
+// and writes the plain content to w. Only the chunk at the end of the stream
+// is opened as the last one, so the stream cut at a chunk boundary fails.
+type chunkOpener struct {
+	w       io.Writer
+	key     []byte
+	id      srvrModels.ObjectID
+	size    int
+	buf     []byte
+	index   int64 // The index of the next chunk.
+	written int64 // The size of the plain content written to w.
+}
+
+// Write decrypts the complete sealed chunks of the data which are followed by more data.
+func (o *chunkOpener) Write(p []byte) (int, error) {
+	o.buf = append(o.buf, p...)
+	for len(o.buf) > o.size {
+		if err := o.open(o.buf[:o.size], false); err != nil {
+			return 0, err
+		}
+		o.buf = o.buf[o.size:]
+	}
+	return len(p), nil
+}
+
+// flush decrypts the last chunk. The stream without chunks is rejected,
+// since even the empty content has one.
+func (o *chunkOpener) flush() error {
+	if len(o.buf) == 0 {
+		return fmt.Errorf("%w: no last chunk", errTamperedBlob)
+	}
+	defer func() { o.buf = nil }()
+	return o.open(o.buf, true)
+}
+
+// open decrypts the next sealed chunk and writes it to w.
+func (o *chunkOpener) open(sealed []byte, final bool) error {
+	plain, err := encrypt.OpenBytesWithAD(sealed, o.key, chunkAD(o.id, o.index, final))
+	if errors.Is(err, encrypt.ErrDecryptionFailed) {
+		return fmt.Errorf("%w: chunk %v: %v", errTamperedBlob, o.index, err)
+	} else if err != nil {
+		return err
+	}
+	o.index++
+	n, err := o.w.Write(plain)
+	o.written += int64(n)
+	return err
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+// recordingStorageService remembers the last body it was asked to send.
+type recordingStorageService struct {
+	StorageService
+	body      string
+	revisions []srvrModels.Revision
+	trash     []srvrModels.UntypedRecord
+}
+
+func (s *recordingStorageService) Add(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	s.body = body
+	return "added", nil
+}
+
+func (s *recordingStorageService) Update(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	s.body = body
+	return "updated", nil
+}
+
+func (s *recordingStorageService) History(
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	token string,
+) ([]srvrModels.Revision, error) {
+	return s.revisions, nil
+}
+
+func (s *recordingStorageService) Trash(
+	collectionName srvrModels.CollectionName,
+	token string,
+) ([]srvrModels.UntypedRecord, error) {
+	return s.trash, nil
+}
+
+// staticSyncService returns a copy of the same response every time.
+type staticSyncService struct {
+	SyncService
+	resp    clientModels.SyncResponse
+	changes srvrModels.Changes
+}
+
+func (s *staticSyncService) Changes(token string, cursor string) (*srvrModels.Changes, error) {
+	c := s.changes
+	c.Upserts = append([]srvrModels.ChangedRecord(nil), s.changes.Upserts...)
+	return &c, nil
+}
+
+func (s *staticSyncService) Sync(
+	token string,
+	collectionNames []srvrModels.CollectionName,
+) (*clientModels.SyncResponse, error) {
+	r := s.resp
+	return &r, nil
+}
+
+func (s *staticSyncService) SyncRecord(
+	token string,
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+) (*clientModels.SyncResponse, error) {
+	r := s.resp
+	return &r, nil
+}
+
+func TestE2EStorageService(t *testing.T) {
+	vault := NewVault(&memoryVaultService{params: newTestVaultParams(t, "master")}, "master")
+	inner := &recordingStorageService{}
+	s := NewE2EStorageService(inner, vault)
+	id := srvrModels.NewRandomObjectID()
+	body, err := json.Marshal(srvrModels.CredentialRecord{
+		RecordID: id,
+		Data:     srvrModels.CredentialInfo{Login: "login", Password: "pwd"},
+		Metadata: srvrModels.Metadata{"site": "example.com"},
+	})
+	require.NoError(t, err)
+
+	for name, op := range map[string]func(string, srvrModels.CollectionName, string) (string, error){
+		"add":    s.Add,
+		"update": s.Update,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := op(string(body), srvrModels.CredentialsCollection, "token")
+			require.NoError(t, err)
+			assert.NotContains(t, inner.body, "pwd")
+			assert.NotContains(t, inner.body, "example.com")
+
+			var sent srvrModels.UntypedRecord
+			require.NoError(t, json.Unmarshal([]byte(inner.body), &sent))
+			assert.Equal(t, id, sent.RecordID)
+			ciphertext, err := srvrModels.EncryptedCiphertext(sent.Data)
+			require.NoError(t, err)
+			content, err := vault.Open("token", srvrModels.CredentialsCollection, id, ciphertext)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]any{"Login": "login", "Password": "pwd"}, content.Data)
+			assert.Equal(t, srvrModels.Metadata{"site": "example.com"}, content.Metadata)
+		})
+	}
+	t.Run("add_without_id", func(t *testing.T) {
+		// the envelope of a new record is bound to the ID chosen by the client
+		_, err := s.Add(`{"data": "secret text"}`, srvrModels.TextCollection, "token")
+		require.NoError(t, err)
+		var sent srvrModels.UntypedRecord
+		require.NoError(t, json.Unmarshal([]byte(inner.body), &sent))
+		require.False(t, sent.RecordID.IsZero())
+		ciphertext, err := srvrModels.EncryptedCiphertext(sent.Data)
+		require.NoError(t, err)
+		content, err := vault.Open("token", srvrModels.TextCollection, sent.RecordID, ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, "secret text", content.Data)
+	})
+	t.Run("bad_body", func(t *testing.T) {
+		_, err := s.Add("not json", srvrModels.CredentialsCollection, "token")
+		assert.Error(t, err)
+	})
+	t.Run("wrong_password", func(t *testing.T) {
+		wrong := NewVault(&memoryVaultService{params: newTestVaultParams(t, "master")}, "another")
+		_, err := NewE2EStorageService(inner, wrong).
+			Add(string(body), srvrModels.CredentialsCollection, "token")
+		assert.ErrorIs(t, err, clientErr.ErrWrongMasterPassword)
+	})
+	t.Run("history", func(t *testing.T) {
+		content := srvrModels.UntypedRecordContent{
+			Data:     map[string]any{"Login": "login", "Password": "old"},
+			Metadata: srvrModels.Metadata{"site": "example.com"},
+		}
+		envelope, err := vault.Seal("token", srvrModels.CredentialsCollection, id, content)
+		require.NoError(t, err)
+		inner.revisions = []srvrModels.Revision{
+			{UntypedRecordContent: srvrModels.UntypedRecordContent{Data: envelope}, RecordID: id, Rev: 2},
+			{UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "plain"}, RecordID: id, Rev: 1},
+		}
+		revisions, err := s.History(srvrModels.CredentialsCollection, id, "token")
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, content, revisions[0].UntypedRecordContent)
+		assert.Equal(t, int64(2), revisions[0].Rev)
+		assert.Equal(t, "plain", revisions[1].Data)
+	})
+	t.Run("trash", func(t *testing.T) {
+		content := srvrModels.UntypedRecordContent{Data: "deleted secret"}
+		envelope, err := vault.Seal("token", srvrModels.TextCollection, id, content)
+		require.NoError(t, err)
+		inner.trash = []srvrModels.UntypedRecord{
+			{UntypedRecordContent: srvrModels.UntypedRecordContent{Data: envelope}, RecordID: id},
+		}
+		records, err := s.Trash(srvrModels.TextCollection, "token")
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, content, records[0].UntypedRecordContent)
+		assert.Equal(t, id, records[0].RecordID)
+	})
+}
+
+func TestE2ESyncService(t *testing.T) {
+	vault := NewVault(&memoryVaultService{params: newTestVaultParams(t, "master")}, "master")
+	id := srvrModels.NewRandomObjectID()
+	sealed, err := vault.Seal("token", srvrModels.TextCollection, id, srvrModels.UntypedRecordContent{
+		Data:     "secret text",
+		Metadata: srvrModels.Metadata{"k": "v"},
+	})
+	require.NoError(t, err)
+	ciphertext, err := srvrModels.EncryptedCiphertext(sealed)
+	require.NoError(t, err)
+	plain := srvrModels.TextRecord{RecordID: srvrModels.NewRandomObjectID(), Data: "plain text"}
+	inner := &staticSyncService{
+		resp: clientModels.SyncResponse{
+			Text: []srvrModels.TextRecord{plain},
+			Encrypted: []clientModels.EncryptedRecord{{
+				Collection: srvrModels.TextCollection,
+				RecordID:   id,
+				Ciphertext: ciphertext,
+			}},
+		},
+		changes: srvrModels.Changes{Upserts: []srvrModels.ChangedRecord{{
+			Collection: srvrModels.TextCollection,
+			Record: srvrModels.UntypedRecord{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{Data: sealed},
+				RecordID:             id,
+			},
+		}}},
+	}
+	want := []srvrModels.TextRecord{
+		plain,
+		{RecordID: id, Data: "secret text", Metadata: srvrModels.Metadata{"k": "v"}},
+	}
+
+	t.Run("sync", func(t *testing.T) {
+		resp, err := NewE2ESyncService(inner, vault).
+			Sync("token", []srvrModels.CollectionName{srvrModels.TextCollection})
+		require.NoError(t, err)
+		assert.Equal(t, want, resp.Text)
+		assert.Empty(t, resp.Encrypted)
+	})
+	t.Run("sync_record", func(t *testing.T) {
+		resp, err := NewE2ESyncService(inner, vault).
+			SyncRecord("token", srvrModels.TextCollection, id)
+		require.NoError(t, err)
+		assert.Equal(t, want, resp.Text)
+	})
+	t.Run("changes", func(t *testing.T) {
+		changes, err := NewE2ESyncService(inner, vault).Changes("token", "")
+		require.NoError(t, err)
+		require.Len(t, changes.Upserts, 1)
+		assert.Equal(t, srvrModels.UntypedRecordContent{
+			Data:     "secret text",
+			Metadata: srvrModels.Metadata{"k": "v"},
+		}, changes.Upserts[0].Record.UntypedRecordContent)
+	})
+	t.Run("swapped", func(t *testing.T) {
+		// the server passes off the envelope of the record as another one
+		swapped := &staticSyncService{resp: clientModels.SyncResponse{
+			Encrypted: []clientModels.EncryptedRecord{{
+				Collection: srvrModels.TextCollection,
+				RecordID:   srvrModels.NewRandomObjectID(),
+				Ciphertext: ciphertext,
+			}},
+		}}
+		_, err := NewE2ESyncService(swapped, vault).
+			Sync("token", []srvrModels.CollectionName{srvrModels.TextCollection})
+		assert.ErrorIs(t, err, encrypt.ErrDecryptionFailed)
+	})
+	t.Run("wrong_password", func(t *testing.T) {
+		wrong := NewVault(&memoryVaultService{params: newTestVaultParams(t, "master")}, "another")
+		_, err := NewE2ESyncService(&staticSyncService{}, wrong).
+			Sync("token", []srvrModels.CollectionName{srvrModels.TextCollection})
+		assert.ErrorIs(t, err, clientErr.ErrWrongMasterPassword)
+		_, err = NewE2ESyncService(&staticSyncService{}, wrong).Changes("token", "")
+		assert.ErrorIs(t, err, clientErr.ErrWrongMasterPassword)
+	})
+}
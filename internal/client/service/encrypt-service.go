@@ -2,6 +2,8 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/blokhinnv/gophkeeper/internal/client/models"
@@ -46,13 +48,17 @@ func (s *encryptService) ToEncryptedFile(resp *models.SyncResponse, fileName, ke
 
 // FromEncryptedFile reads and decrypts models.SyncResponse data from a file.
 // It uses AES encryption with the given password to decrypt the data.
-// Returns an error if decryption or reading from file fails.
+// Returns an error if decryption or reading from file fails. The files written
+// in the legacy unauthenticated format are not read: the sync command replaces them.
 func (s *encryptService) FromEncryptedFile(fileName, key string) (*models.SyncResponse, error) {
 	ciphertext, err := os.ReadFile(fileName)
 	if err != nil {
 		return nil, err
 	}
 	decoded, err := encrypt.DecryptBytes(ciphertext, key)
+	if errors.Is(err, encrypt.ErrUnknownFormat) {
+		return nil, fmt.Errorf("%w: run sync to replace %v", err, fileName)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,604 @@
+package service
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+// LocalStore is an encrypted local copy of the user's collections. It also keeps
+// the journal of the writes made while the server was unavailable.
+type LocalStore interface {
+	// Load returns the local copy of all the collections.
+	Load() (*clientModels.SyncResponse, error)
+	// Save replaces the local copy of the collections with the synced data.
+	// The pending operations are applied on top of it.
+	Save(data *clientModels.SyncResponse, collections []srvrModels.CollectionName) error
+	// Merge adds the records to the local copy replacing the records with the same IDs.
+	Merge(data *clientModels.SyncResponse) error
+	// ApplyChanges applies the changes of all the collections since the cursor to
+	// the local copy and keeps the cursor of the next changes. The pending
+	// operations are applied on top of it.
+	ApplyChanges(changes *srvrModels.Changes) error
+	// Cursor returns the cursor of the changes made since the last sync;
+	// empty if the store has never been synced with the changes.
+	Cursor() (string, error)
+	// Apply applies the operation accepted by the server to the local copy.
+	Apply(op clientModels.PendingOp) error
+	// Enqueue journals the operation and applies it to the local copy.
+	Enqueue(op clientModels.PendingOp) error
+	// Pending returns the journaled operations in the order they were made.
+	Pending() ([]clientModels.PendingOp, error)
+	// Complete removes the operation sent to the server from the journal. If the
+	// server assigned another ID to the added record, the record and the later
+	// operations on it are moved to that ID.
+	Complete(op clientModels.PendingOp, recordID srvrModels.ObjectID) error
+	// SyncedAt returns the time of the last sync; zero if the store has never been synced.
+	SyncedAt() (time.Time, error)
+}
+
+// boltMagic is the magic number of the meta page of the bbolt files.
+const boltMagic = 0xED0CDAED
+
+var (
+	// metaBucket keeps the key derivation parameters, the time of the last sync
+	// and the cursor of the changes.
+	metaBucket = []byte("meta")
+	// journalBucket keeps the pending operations by their sequence numbers.
+	journalBucket = []byte("journal")
+
+	kdfKey      = []byte("kdf")
+	checkKey    = []byte("check")
+	syncedAtKey = []byte("synced_at")
+	cursorKey   = []byte("cursor")
+	// keyCheck is encrypted with the key of the store to detect a wrong key.
+	keyCheck = []byte("gophkeeper local store")
+)
+
+// localStore implements the LocalStore interface with a bbolt file. Every record
+// and operation is encrypted with AES-GCM by the key derived from the password.
+type localStore struct {
+	path     string
+	password string
+	// mu serializes the access to the file: bbolt locks it for a single user.
+	mu sync.Mutex
+	// key is derived on the first access.
+	key []byte
+}
+
+// NewLocalStore returns a LocalStore kept in the file and encrypted with the password.
+// The file is created on the first write.
+func NewLocalStore(path, password string) LocalStore {
+	return &localStore{path: path, password: password}
+}
+
+// checkFile checks that the file is a bbolt database if it exists and is not empty.
+func checkFile(path string) (exists bool, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	// the page header is followed by the magic number of the meta page
+	header := make([]byte, 20)
+	n, err := io.ReadFull(f, header)
+	if n == 0 {
+		return false, nil
+	}
+	if err != nil || binary.LittleEndian.Uint32(header[16:]) != boltMagic {
+		return false, fmt.Errorf("%w: %v", clientErr.ErrNotLocalStore, path)
+	}
+	return true, nil
+}
+
+// update runs the function in a read-write transaction.
+func (s *localStore) update(fn func(tx *bolt.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := checkFile(s.path); err != nil {
+		return err
+	}
+	db, err := bolt.Open(s.path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		if err := s.unlock(tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// view runs the function in a read-only transaction.
+// The function isn't called if the store hasn't been created yet.
+func (s *localStore) view(fn func(tx *bolt.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exists, err := checkFile(s.path)
+	if err != nil || !exists {
+		return err
+	}
+	db, err := bolt.Open(s.path, 0o600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(metaBucket) == nil {
+			return nil
+		}
+		if err := s.unlock(tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// unlock derives the key of the store. The parameters of the key derivation
+// are created in a new store.
+func (s *localStore) unlock(tx *bolt.Tx) error {
+	if meta := tx.Bucket(metaBucket); meta != nil {
+		if s.key != nil {
+			// the file may be replaced since the key was derived
+			if _, err := encrypt.OpenBytes(meta.Get(checkKey), s.key); err == nil {
+				return nil
+			}
+		}
+		var params encrypt.KDFParams
+		if err := json.Unmarshal(meta.Get(kdfKey), &params); err != nil {
+			return fmt.Errorf("bad local store %v: %w", s.path, err)
+		}
+		key, err := encrypt.DeriveKey(s.password, params)
+		if err != nil {
+			return err
+		}
+		if _, err := encrypt.OpenBytes(meta.Get(checkKey), key); err != nil {
+			return clientErr.ErrWrongKey
+		}
+		s.key = key
+		return nil
+	}
+	params, err := encrypt.NewKDFParams()
+	if err != nil {
+		return err
+	}
+	key, err := encrypt.DeriveKey(s.password, params)
+	if err != nil {
+		return err
+	}
+	check, err := encrypt.SealBytes(keyCheck, key)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	meta, err := tx.CreateBucket(metaBucket)
+	if err != nil {
+		return err
+	}
+	if err := meta.Put(kdfKey, b); err != nil {
+		return err
+	}
+	if err := meta.Put(checkKey, check); err != nil {
+		return err
+	}
+	s.key = key
+	return nil
+}
+
+// put encrypts the value and puts it to the bucket.
+func (s *localStore) put(b *bolt.Bucket, key []byte, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sealed, err := encrypt.SealBytes(data, s.key)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, sealed)
+}
+
+// get decrypts the value read from a bucket.
+func (s *localStore) get(data []byte, v any) error {
+	plain, err := encrypt.OpenBytes(data, s.key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plain, v)
+}
+
+// recordKey returns the key of the record in the bucket of its collection.
+func recordKey(id srvrModels.ObjectID) []byte {
+	return []byte(id.Hex())
+}
+
+// seqKey returns the key of the operation in the journal.
+func seqKey(seq uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, seq)
+	return b
+}
+
+// untypedRecords returns the records of the collection in the form they are sent by the server.
+func untypedRecords(
+	collectionName srvrModels.CollectionName,
+	data *clientModels.SyncResponse,
+) ([]srvrModels.UntypedRecord, error) {
+	var records []srvrModels.UntypedRecord
+	b, err := json.Marshal(collectionRecords(collectionName, data))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &records); err != nil {
+		return nil, err
+	}
+	for _, r := range data.Encrypted {
+		if r.Collection != collectionName {
+			continue
+		}
+		records = append(records, srvrModels.UntypedRecord{
+			UntypedRecordContent: srvrModels.UntypedRecordContent{
+				Data: srvrModels.NewEncryptedData(r.Ciphertext),
+			},
+			RecordID:  r.RecordID,
+			Version:   r.Version,
+			UpdatedAt: r.UpdatedAt,
+		})
+	}
+	return records, nil
+}
+
+// putRecords puts the records of the collection to its bucket.
+func (s *localStore) putRecords(
+	tx *bolt.Tx,
+	collectionName srvrModels.CollectionName,
+	data *clientModels.SyncResponse,
+) error {
+	records, err := untypedRecords(collectionName, data)
+	if err != nil {
+		return err
+	}
+	b, err := tx.CreateBucketIfNotExists([]byte(collectionName))
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := s.put(b, recordKey(r.RecordID), r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply applies the operation to the local copy of its collection.
+func (s *localStore) apply(tx *bolt.Tx, op clientModels.PendingOp) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(op.Collection))
+	if err != nil {
+		return err
+	}
+	if op.Op == srvrModels.OpDelete {
+		return b.Delete(recordKey(op.RecordID))
+	}
+	var body updateBody
+	if err := json.Unmarshal([]byte(op.Body), &body); err != nil {
+		return err
+	}
+	record := body.UntypedRecord
+	record.RecordID = op.RecordID
+	// the server creates the record with the first version and increments it on update
+	switch {
+	case op.Op == srvrModels.OpCreate:
+		record.Version = 1
+	case body.ExpectedVersion != 0:
+		record.Version = body.ExpectedVersion + 1
+	}
+	return s.put(b, recordKey(op.RecordID), record)
+}
+
+// pending returns the journaled operations.
+func (s *localStore) pending(tx *bolt.Tx) ([]clientModels.PendingOp, error) {
+	var ops []clientModels.PendingOp
+	b := tx.Bucket(journalBucket)
+	if b == nil {
+		return ops, nil
+	}
+	err := b.ForEach(func(k, v []byte) error {
+		var op clientModels.PendingOp
+		if err := s.get(v, &op); err != nil {
+			return err
+		}
+		op.Seq = binary.BigEndian.Uint64(k)
+		ops = append(ops, op)
+		return nil
+	})
+	return ops, err
+}
+
+// Load returns the local copy of all the collections.
+func (s *localStore) Load() (*clientModels.SyncResponse, error) {
+	if exists, err := checkFile(s.path); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, fmt.Errorf("%w: run the sync command first", fs.ErrNotExist)
+	}
+	data := &clientModels.SyncResponse{}
+	err := s.view(func(tx *bolt.Tx) error {
+		for _, collectionName := range srvrModels.AllowedCollectionNames {
+			b := tx.Bucket([]byte(collectionName))
+			if b == nil {
+				continue
+			}
+			err := b.ForEach(func(_, v []byte) error {
+				var record srvrModels.UntypedRecord
+				if err := s.get(v, &record); err != nil {
+					return err
+				}
+				return data.AppendRecord(collectionName, record)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// removeDump removes the file written by the sync command of older clients, so
+// the store replaces it. Only the file which is decrypted with the key is removed;
+// the legacy unauthenticated file must decrypt into JSON.
+func (s *localStore) removeDump() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := checkFile(s.path); !errors.Is(err, clientErr.ErrNotLocalStore) {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	_, err = encrypt.DecryptBytes(data, s.password)
+	if errors.Is(err, encrypt.ErrUnknownFormat) {
+		var plaintext []byte
+		plaintext, err = encrypt.DecryptLegacyBytes(data, s.password)
+		if err == nil && !json.Valid(plaintext) {
+			err = encrypt.ErrUnknownFormat
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", clientErr.ErrNotLocalStore, s.path)
+	}
+	return os.Remove(s.path)
+}
+
+// Save replaces the local copy of the collections with the synced data.
+// The pending operations are applied on top of it.
+func (s *localStore) Save(
+	data *clientModels.SyncResponse,
+	collections []srvrModels.CollectionName,
+) error {
+	if data == nil {
+		data = &clientModels.SyncResponse{}
+	}
+	if err := s.removeDump(); err != nil {
+		return err
+	}
+	return s.update(func(tx *bolt.Tx) error {
+		for _, collectionName := range collections {
+			err := tx.DeleteBucket([]byte(collectionName))
+			if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return err
+			}
+			if err := s.putRecords(tx, collectionName, data); err != nil {
+				return err
+			}
+		}
+		return s.synced(tx)
+	})
+}
+
+// synced applies the pending operations on top of the synced data and
+// keeps the time of the sync.
+func (s *localStore) synced(tx *bolt.Tx) error {
+	ops, err := s.pending(tx)
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		if err := s.apply(tx, op); err != nil {
+			return err
+		}
+	}
+	syncedAt, err := time.Now().MarshalText()
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(metaBucket).Put(syncedAtKey, syncedAt)
+}
+
+// ApplyChanges applies the changes of all the collections since the cursor to
+// the local copy and keeps the cursor of the next changes. All the collections
+// are replaced if the changes are reset. The pending operations are applied
+// on top of it.
+func (s *localStore) ApplyChanges(changes *srvrModels.Changes) error {
+	if err := s.removeDump(); err != nil {
+		return err
+	}
+	return s.update(func(tx *bolt.Tx) error {
+		if changes.Reset {
+			for _, collectionName := range srvrModels.AllowedCollectionNames {
+				err := tx.DeleteBucket([]byte(collectionName))
+				if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+					return err
+				}
+			}
+		}
+		for _, u := range changes.Upserts {
+			b, err := tx.CreateBucketIfNotExists([]byte(u.Collection))
+			if err != nil {
+				return err
+			}
+			if err := s.put(b, recordKey(u.Record.RecordID), u.Record); err != nil {
+				return err
+			}
+		}
+		for _, t := range changes.Tombstones {
+			if b := tx.Bucket([]byte(t.Collection)); b != nil {
+				if err := b.Delete(recordKey(t.RecordID)); err != nil {
+					return err
+				}
+			}
+		}
+		if err := s.synced(tx); err != nil {
+			return err
+		}
+		return tx.Bucket(metaBucket).Put(cursorKey, []byte(changes.Cursor))
+	})
+}
+
+// Cursor returns the cursor of the changes made since the last sync;
+// empty if the store has never been synced with the changes.
+func (s *localStore) Cursor() (string, error) {
+	var cursor string
+	err := s.view(func(tx *bolt.Tx) error {
+		cursor = string(tx.Bucket(metaBucket).Get(cursorKey))
+		return nil
+	})
+	return cursor, err
+}
+
+// Merge adds the records to the local copy replacing the records with the same IDs.
+func (s *localStore) Merge(data *clientModels.SyncResponse) error {
+	if data == nil {
+		return nil
+	}
+	return s.update(func(tx *bolt.Tx) error {
+		for _, collectionName := range srvrModels.AllowedCollectionNames {
+			if err := s.putRecords(tx, collectionName, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Apply applies the operation accepted by the server to the local copy.
+func (s *localStore) Apply(op clientModels.PendingOp) error {
+	return s.update(func(tx *bolt.Tx) error {
+		return s.apply(tx, op)
+	})
+}
+
+// Enqueue journals the operation and applies it to the local copy.
+func (s *localStore) Enqueue(op clientModels.PendingOp) error {
+	return s.update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(journalBucket)
+		if err != nil {
+			return err
+		}
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := s.put(b, seqKey(seq), op); err != nil {
+			return err
+		}
+		return s.apply(tx, op)
+	})
+}
+
+// Pending returns the journaled operations in the order they were made.
+func (s *localStore) Pending() ([]clientModels.PendingOp, error) {
+	var ops []clientModels.PendingOp
+	err := s.view(func(tx *bolt.Tx) error {
+		var err error
+		ops, err = s.pending(tx)
+		return err
+	})
+	return ops, err
+}
+
+// Complete removes the operation sent to the server from the journal. If the
+// server assigned another ID to the added record, the record and the later
+// operations on it are moved to that ID.
+func (s *localStore) Complete(op clientModels.PendingOp, recordID srvrModels.ObjectID) error {
+	return s.update(func(tx *bolt.Tx) error {
+		journal := tx.Bucket(journalBucket)
+		if journal == nil {
+			return nil
+		}
+		if err := journal.Delete(seqKey(op.Seq)); err != nil {
+			return err
+		}
+		if recordID == op.RecordID {
+			return nil
+		}
+		if b := tx.Bucket([]byte(op.Collection)); b != nil {
+			if v := b.Get(recordKey(op.RecordID)); v != nil {
+				var record srvrModels.UntypedRecord
+				if err := s.get(v, &record); err != nil {
+					return err
+				}
+				record.RecordID = recordID
+				if err := b.Delete(recordKey(op.RecordID)); err != nil {
+					return err
+				}
+				if err := s.put(b, recordKey(recordID), record); err != nil {
+					return err
+				}
+			}
+		}
+		ops, err := s.pending(tx)
+		if err != nil {
+			return err
+		}
+		for _, later := range ops {
+			if later.Collection != op.Collection || later.RecordID != op.RecordID {
+				continue
+			}
+			later.RecordID = recordID
+			if later.Body, err = withRecordID(later.Body, recordID); err != nil {
+				return err
+			}
+			if err := s.put(journal, seqKey(later.Seq), later); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SyncedAt returns the time of the last sync; zero if the store has never been synced.
+func (s *localStore) SyncedAt() (time.Time, error) {
+	var syncedAt time.Time
+	err := s.view(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(metaBucket).Get(syncedAtKey); v != nil {
+			return syncedAt.UnmarshalText(v)
+		}
+		return nil
+	})
+	return syncedAt, err
+}
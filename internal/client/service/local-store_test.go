@@ -0,0 +1,225 @@
+package service
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// newTextOp creates an operation on a text record.
+func newTextOp(
+	t *testing.T,
+	op srvrModels.ChangeOp,
+	id srvrModels.ObjectID,
+	text string,
+) clientModels.PendingOp {
+	body, err := json.Marshal(map[string]any{"record_id": id.Hex(), "data": text})
+	require.NoError(t, err)
+	return clientModels.PendingOp{
+		Op:         op,
+		Collection: srvrModels.TextCollection,
+		RecordID:   id,
+		Body:       string(body),
+	}
+}
+
+func TestLocalStore_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	store := NewLocalStore(path, "somekey")
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := store.Load()
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		ops, err := store.Pending()
+		require.NoError(t, err)
+		assert.Empty(t, ops)
+	})
+	t.Run("ok", func(t *testing.T) {
+		data := &clientModels.SyncResponse{
+			Text: []srvrModels.TextRecord{
+				{RecordID: srvrModels.NewRandomObjectID(), Data: "some text"},
+			},
+			Card: []srvrModels.CardRecord{},
+			Encrypted: []clientModels.EncryptedRecord{{
+				Collection: srvrModels.CardCollection,
+				RecordID:   srvrModels.NewRandomObjectID(),
+				Ciphertext: []byte("ciphertext"),
+			}},
+		}
+		require.NoError(t, store.Save(data, srvrModels.AllowedCollectionNames))
+		loaded, err := store.Load()
+		require.NoError(t, err)
+		assert.Equal(t, data.Text, loaded.Text)
+		assert.Equal(t, data.Encrypted, loaded.Encrypted)
+
+		syncedAt, err := store.SyncedAt()
+		require.NoError(t, err)
+		assert.False(t, syncedAt.IsZero())
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+	t.Run("partial", func(t *testing.T) {
+		// only the synced collections are replaced
+		otp := []srvrModels.OTPRecord{{
+			RecordID: srvrModels.NewRandomObjectID(),
+			Data:     srvrModels.OTPInfo{Secret: "JBSWY3DPEHPK3PXP"},
+		}}
+		err := store.Save(
+			&clientModels.SyncResponse{OTP: otp},
+			[]srvrModels.CollectionName{srvrModels.OTPCollection},
+		)
+		require.NoError(t, err)
+		loaded, err := store.Load()
+		require.NoError(t, err)
+		assert.Equal(t, otp, loaded.OTP)
+		assert.Len(t, loaded.Text, 1)
+	})
+	t.Run("wrong_key", func(t *testing.T) {
+		_, err := NewLocalStore(path, "otherkey").Load()
+		assert.ErrorIs(t, err, clientErr.ErrWrongKey)
+	})
+}
+
+func TestLocalStore_Journal(t *testing.T) {
+	store := NewLocalStore(filepath.Join(t.TempDir(), "store.db"), "somekey")
+	local, other := srvrModels.NewRandomObjectID(), srvrModels.NewRandomObjectID()
+	require.NoError(t, store.Enqueue(newTextOp(t, srvrModels.OpCreate, local, "draft")))
+	require.NoError(t, store.Enqueue(newTextOp(t, srvrModels.OpCreate, other, "other")))
+	require.NoError(t, store.Enqueue(newTextOp(t, srvrModels.OpUpdate, local, "final")))
+
+	ops, err := store.Pending()
+	require.NoError(t, err)
+	require.Len(t, ops, 3)
+	assert.Equal(t, []uint64{1, 2, 3}, []uint64{ops[0].Seq, ops[1].Seq, ops[2].Seq})
+
+	data, err := store.Load()
+	require.NoError(t, err)
+	// the version of the record updated without the expected version is unknown
+	assert.ElementsMatch(t, []srvrModels.TextRecord{
+		{RecordID: local, Data: "final"},
+		{RecordID: other, Data: "other", Version: 1},
+	}, data.Text)
+
+	t.Run("save_keeps_pending", func(t *testing.T) {
+		require.NoError(t, store.Save(&clientModels.SyncResponse{}, srvrModels.AllowedCollectionNames))
+		data, err := store.Load()
+		require.NoError(t, err)
+		assert.Len(t, data.Text, 2)
+	})
+	t.Run("complete", func(t *testing.T) {
+		serverID := srvrModels.NewRandomObjectID()
+		require.NoError(t, store.Complete(ops[0], serverID))
+
+		ops, err := store.Pending()
+		require.NoError(t, err)
+		require.Len(t, ops, 2)
+		assert.Equal(t, other, ops[0].RecordID)
+		// the update is moved to the ID assigned by the server
+		assert.Equal(t, serverID, ops[1].RecordID)
+		assert.JSONEq(t, `{"record_id": "`+serverID.Hex()+`", "data": "final"}`, ops[1].Body)
+
+		data, err := store.Load()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []srvrModels.TextRecord{
+			{RecordID: serverID, Data: "final"},
+			{RecordID: other, Data: "other", Version: 1},
+		}, data.Text)
+	})
+	t.Run("apply_update", func(t *testing.T) {
+		op := newTextOp(t, srvrModels.OpUpdate, other, "changed")
+		op.Body = `{"data": "changed", "expected_version": 1}`
+		require.NoError(t, store.Apply(op))
+		data, err := store.Load()
+		require.NoError(t, err)
+		assert.Contains(t, data.Text, srvrModels.TextRecord{RecordID: other, Data: "changed", Version: 2})
+	})
+	t.Run("apply_delete", func(t *testing.T) {
+		require.NoError(t, store.Apply(newTextOp(t, srvrModels.OpDelete, other, "")))
+		data, err := store.Load()
+		require.NoError(t, err)
+		assert.Len(t, data.Text, 1)
+	})
+}
+
+func TestLocalStore_ApplyChanges(t *testing.T) {
+	store := NewLocalStore(filepath.Join(t.TempDir(), "store.db"), "somekey")
+	first, second := srvrModels.NewRandomObjectID(), srvrModels.NewRandomObjectID()
+	text := func(id srvrModels.ObjectID, data string) srvrModels.ChangedRecord {
+		return srvrModels.ChangedRecord{
+			Collection: srvrModels.TextCollection,
+			Record: srvrModels.UntypedRecord{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{Data: data},
+				RecordID:             id,
+			},
+		}
+	}
+
+	cursor, err := store.Cursor()
+	require.NoError(t, err)
+	assert.Empty(t, cursor)
+
+	t.Run("upserts", func(t *testing.T) {
+		err := store.ApplyChanges(&srvrModels.Changes{
+			Upserts: []srvrModels.ChangedRecord{text(first, "first"), text(second, "second")},
+			Cursor:  "cursor",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]srvrModels.ObjectID{"first": first, "second": second}, texts(t, store))
+		cursor, err := store.Cursor()
+		require.NoError(t, err)
+		assert.Equal(t, "cursor", cursor)
+	})
+	t.Run("tombstones", func(t *testing.T) {
+		err := store.ApplyChanges(&srvrModels.Changes{
+			Tombstones: []srvrModels.Tombstone{
+				{Collection: srvrModels.TextCollection, RecordID: second},
+				{Collection: srvrModels.CardCollection, RecordID: first},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]srvrModels.ObjectID{"first": first}, texts(t, store))
+	})
+	t.Run("reset_keeps_pending", func(t *testing.T) {
+		local := srvrModels.NewRandomObjectID()
+		require.NoError(t, store.Enqueue(newTextOp(t, srvrModels.OpCreate, local, "local")))
+		err := store.ApplyChanges(&srvrModels.Changes{
+			Upserts: []srvrModels.ChangedRecord{text(second, "again")},
+			Reset:   true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]srvrModels.ObjectID{"again": second, "local": local}, texts(t, store))
+	})
+}
+
+func TestLocalStore_Dump(t *testing.T) {
+	dir := t.TempDir()
+	dump := filepath.Join(dir, "dump.sync")
+	require.NoError(t, NewEncryptService().ToEncryptedFile(&clientModels.SyncResponse{}, dump, "somekey"))
+
+	t.Run("not_local_store", func(t *testing.T) {
+		_, err := NewLocalStore(dump, "somekey").Load()
+		assert.ErrorIs(t, err, clientErr.ErrNotLocalStore)
+	})
+	t.Run("foreign_file", func(t *testing.T) {
+		// the dump is not replaced if it is not decrypted with the key
+		err := NewLocalStore(dump, "otherkey").Save(nil, srvrModels.AllowedCollectionNames)
+		assert.ErrorIs(t, err, clientErr.ErrNotLocalStore)
+	})
+	t.Run("replaced", func(t *testing.T) {
+		store := NewLocalStore(dump, "somekey")
+		require.NoError(t, store.Save(nil, srvrModels.AllowedCollectionNames))
+		_, err := store.Load()
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,125 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/blokhinnv/gophkeeper/internal/client/service (interfaces: BlobService)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	io "io"
+	reflect "reflect"
+
+	models "github.com/blokhinnv/gophkeeper/internal/server/models"
+	resty "github.com/go-resty/resty/v2"
+	gomock "github.com/golang/mock/gomock"
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockBlobService is a mock of BlobService interface.
+type MockBlobService struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlobServiceMockRecorder
+}
+
+// MockBlobServiceMockRecorder is the mock recorder for MockBlobService.
+type MockBlobServiceMockRecorder struct {
+	mock *MockBlobService
+}
+
+// NewMockBlobService creates a new mock instance.
+func NewMockBlobService(ctrl *gomock.Controller) *MockBlobService {
+	mock := &MockBlobService{ctrl: ctrl}
+	mock.recorder = &MockBlobServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlobService) EXPECT() *MockBlobServiceMockRecorder {
+	return m.recorder
+}
+
+// Append mocks base method.
+func (m *MockBlobService) Append(arg0 primitive.ObjectID, arg1 int64, arg2 []byte, arg3 string) (*models.Blob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*models.Blob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockBlobServiceMockRecorder) Append(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockBlobService)(nil).Append), arg0, arg1, arg2, arg3)
+}
+
+// ChunkSize mocks base method.
+func (m *MockBlobService) ChunkSize() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChunkSize")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// ChunkSize indicates an expected call of ChunkSize.
+func (mr *MockBlobServiceMockRecorder) ChunkSize() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChunkSize", reflect.TypeOf((*MockBlobService)(nil).ChunkSize))
+}
+
+// Create mocks base method.
+func (m *MockBlobService) Create(arg0 int64, arg1 string) (*models.Blob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(*models.Blob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockBlobServiceMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockBlobService)(nil).Create), arg0, arg1)
+}
+
+// Download mocks base method.
+func (m *MockBlobService) Download(arg0 primitive.ObjectID, arg1 io.Writer, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Download", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Download indicates an expected call of Download.
+func (mr *MockBlobServiceMockRecorder) Download(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Download", reflect.TypeOf((*MockBlobService)(nil).Download), arg0, arg1, arg2)
+}
+
+// Get mocks base method.
+func (m *MockBlobService) Get(arg0 primitive.ObjectID, arg1 string) (*models.Blob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(*models.Blob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockBlobServiceMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockBlobService)(nil).Get), arg0, arg1)
+}
+
+// GetClient mocks base method.
+func (m *MockBlobService) GetClient() *resty.Client {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClient")
+	ret0, _ := ret[0].(*resty.Client)
+	return ret0
+}
+
+// GetClient indicates an expected call of GetClient.
+func (mr *MockBlobServiceMockRecorder) GetClient() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClient", reflect.TypeOf((*MockBlobService)(nil).GetClient))
+}
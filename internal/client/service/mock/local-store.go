@@ -0,0 +1,182 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/blokhinnv/gophkeeper/internal/client/service (interfaces: LocalStore)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	reflect "reflect"
+	time "time"
+
+	models "github.com/blokhinnv/gophkeeper/internal/client/models"
+	models0 "github.com/blokhinnv/gophkeeper/internal/server/models"
+	gomock "github.com/golang/mock/gomock"
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockLocalStore is a mock of LocalStore interface.
+type MockLocalStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockLocalStoreMockRecorder
+}
+
+// MockLocalStoreMockRecorder is the mock recorder for MockLocalStore.
+type MockLocalStoreMockRecorder struct {
+	mock *MockLocalStore
+}
+
+// NewMockLocalStore creates a new mock instance.
+func NewMockLocalStore(ctrl *gomock.Controller) *MockLocalStore {
+	mock := &MockLocalStore{ctrl: ctrl}
+	mock.recorder = &MockLocalStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLocalStore) EXPECT() *MockLocalStoreMockRecorder {
+	return m.recorder
+}
+
+// Apply mocks base method.
+func (m *MockLocalStore) Apply(arg0 models.PendingOp) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Apply", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Apply indicates an expected call of Apply.
+func (mr *MockLocalStoreMockRecorder) Apply(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Apply", reflect.TypeOf((*MockLocalStore)(nil).Apply), arg0)
+}
+
+// ApplyChanges mocks base method.
+func (m *MockLocalStore) ApplyChanges(arg0 *models0.Changes) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyChanges", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyChanges indicates an expected call of ApplyChanges.
+func (mr *MockLocalStoreMockRecorder) ApplyChanges(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyChanges", reflect.TypeOf((*MockLocalStore)(nil).ApplyChanges), arg0)
+}
+
+// Complete mocks base method.
+func (m *MockLocalStore) Complete(arg0 models.PendingOp, arg1 primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockLocalStoreMockRecorder) Complete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockLocalStore)(nil).Complete), arg0, arg1)
+}
+
+// Cursor mocks base method.
+func (m *MockLocalStore) Cursor() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cursor")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Cursor indicates an expected call of Cursor.
+func (mr *MockLocalStoreMockRecorder) Cursor() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cursor", reflect.TypeOf((*MockLocalStore)(nil).Cursor))
+}
+
+// Enqueue mocks base method.
+func (m *MockLocalStore) Enqueue(arg0 models.PendingOp) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enqueue", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enqueue indicates an expected call of Enqueue.
+func (mr *MockLocalStoreMockRecorder) Enqueue(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enqueue", reflect.TypeOf((*MockLocalStore)(nil).Enqueue), arg0)
+}
+
+// Load mocks base method.
+func (m *MockLocalStore) Load() (*models.SyncResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Load")
+	ret0, _ := ret[0].(*models.SyncResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Load indicates an expected call of Load.
+func (mr *MockLocalStoreMockRecorder) Load() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockLocalStore)(nil).Load))
+}
+
+// Merge mocks base method.
+func (m *MockLocalStore) Merge(arg0 *models.SyncResponse) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockLocalStoreMockRecorder) Merge(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockLocalStore)(nil).Merge), arg0)
+}
+
+// Pending mocks base method.
+func (m *MockLocalStore) Pending() ([]models.PendingOp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pending")
+	ret0, _ := ret[0].([]models.PendingOp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Pending indicates an expected call of Pending.
+func (mr *MockLocalStoreMockRecorder) Pending() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pending", reflect.TypeOf((*MockLocalStore)(nil).Pending))
+}
+
+// Save mocks base method.
+func (m *MockLocalStore) Save(arg0 *models.SyncResponse, arg1 []models0.CollectionName) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockLocalStoreMockRecorder) Save(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockLocalStore)(nil).Save), arg0, arg1)
+}
+
+// SyncedAt mocks base method.
+func (m *MockLocalStore) SyncedAt() (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncedAt")
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SyncedAt indicates an expected call of SyncedAt.
+func (mr *MockLocalStoreMockRecorder) SyncedAt() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncedAt", reflect.TypeOf((*MockLocalStore)(nil).SyncedAt))
+}
@@ -11,6 +11,7 @@ import (
 	models0 "github.com/blokhinnv/gophkeeper/internal/server/models"
 	resty "github.com/go-resty/resty/v2"
 	gomock "github.com/golang/mock/gomock"
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // MockStorageService is a mock of StorageService interface.
@@ -67,17 +68,19 @@ func (mr *MockStorageServiceMockRecorder) Delete(arg0, arg1, arg2 interface{}) *
 }
 
 // GetAll mocks base method.
-func (m *MockStorageService) GetAll(arg0 models0.CollectionName, arg1 *models.SyncResponse) interface{} {
+func (m *MockStorageService) GetAll(arg0 models0.CollectionName, arg1 *models.SyncResponse, arg2 models0.GetAllOptions) (interface{}, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetAll", arg0, arg1, arg2)
 	ret0, _ := ret[0].(interface{})
-	return ret0
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *MockStorageServiceMockRecorder) GetAll(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockStorageServiceMockRecorder) GetAll(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockStorageService)(nil).GetAll), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockStorageService)(nil).GetAll), arg0, arg1, arg2)
 }
 
 // GetClient mocks base method.
@@ -94,6 +97,96 @@ func (mr *MockStorageServiceMockRecorder) GetClient() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClient", reflect.TypeOf((*MockStorageService)(nil).GetClient))
 }
 
+// History mocks base method.
+func (m *MockStorageService) History(arg0 models0.CollectionName, arg1 primitive.ObjectID, arg2 string) ([]models0.Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models0.Revision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History.
+func (mr *MockStorageServiceMockRecorder) History(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockStorageService)(nil).History), arg0, arg1, arg2)
+}
+
+// Purge mocks base method.
+func (m *MockStorageService) Purge(arg0 models0.CollectionName, arg1 primitive.ObjectID, arg2 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockStorageServiceMockRecorder) Purge(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockStorageService)(nil).Purge), arg0, arg1, arg2)
+}
+
+// Restore mocks base method.
+func (m *MockStorageService) Restore(arg0 models0.CollectionName, arg1 primitive.ObjectID, arg2 int64, arg3 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockStorageServiceMockRecorder) Restore(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStorageService)(nil).Restore), arg0, arg1, arg2, arg3)
+}
+
+// Search mocks base method.
+func (m *MockStorageService) Search(arg0, arg1 string) ([]models0.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", arg0, arg1)
+	ret0, _ := ret[0].([]models0.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockStorageServiceMockRecorder) Search(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockStorageService)(nil).Search), arg0, arg1)
+}
+
+// Trash mocks base method.
+func (m *MockStorageService) Trash(arg0 models0.CollectionName, arg1 string) ([]models0.UntypedRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trash", arg0, arg1)
+	ret0, _ := ret[0].([]models0.UntypedRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Trash indicates an expected call of Trash.
+func (mr *MockStorageServiceMockRecorder) Trash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trash", reflect.TypeOf((*MockStorageService)(nil).Trash), arg0, arg1)
+}
+
+// Undelete mocks base method.
+func (m *MockStorageService) Undelete(arg0 models0.CollectionName, arg1 primitive.ObjectID, arg2 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Undelete", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Undelete indicates an expected call of Undelete.
+func (mr *MockStorageServiceMockRecorder) Undelete(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Undelete", reflect.TypeOf((*MockStorageService)(nil).Undelete), arg0, arg1, arg2)
+}
+
 // Update mocks base method.
 func (m *MockStorageService) Update(arg0 string, arg1 models0.CollectionName, arg2 string) (string, error) {
 	m.ctrl.T.Helper()
@@ -5,12 +5,14 @@
 package mock
 
 import (
+	context "context"
 	reflect "reflect"
 
 	models "github.com/blokhinnv/gophkeeper/internal/client/models"
 	models0 "github.com/blokhinnv/gophkeeper/internal/server/models"
 	resty "github.com/go-resty/resty/v2"
 	gomock "github.com/golang/mock/gomock"
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // MockSyncService is a mock of SyncService interface.
@@ -36,33 +38,48 @@ func (m *MockSyncService) EXPECT() *MockSyncServiceMockRecorder {
 	return m.recorder
 }
 
-// GetClient mocks base method.
-func (m *MockSyncService) GetClient() *resty.Client {
+// Changes mocks base method.
+func (m *MockSyncService) Changes(arg0, arg1 string) (*models0.Changes, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetClient")
-	ret0, _ := ret[0].(*resty.Client)
-	return ret0
+	ret := m.ctrl.Call(m, "Changes", arg0, arg1)
+	ret0, _ := ret[0].(*models0.Changes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// GetClient indicates an expected call of GetClient.
-func (mr *MockSyncServiceMockRecorder) GetClient() *gomock.Call {
+// Changes indicates an expected call of Changes.
+func (mr *MockSyncServiceMockRecorder) Changes(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClient", reflect.TypeOf((*MockSyncService)(nil).GetClient))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockSyncService)(nil).Changes), arg0, arg1)
 }
 
-// Register mocks base method.
-func (m *MockSyncService) Register(arg0, arg1 string) (string, error) {
+// Events mocks base method.
+func (m *MockSyncService) Events(arg0 context.Context, arg1 string) (<-chan models0.ChangeEvent, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Register", arg0, arg1)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "Events", arg0, arg1)
+	ret0, _ := ret[0].(<-chan models0.ChangeEvent)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Register indicates an expected call of Register.
-func (mr *MockSyncServiceMockRecorder) Register(arg0, arg1 interface{}) *gomock.Call {
+// Events indicates an expected call of Events.
+func (mr *MockSyncServiceMockRecorder) Events(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockSyncService)(nil).Register), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Events", reflect.TypeOf((*MockSyncService)(nil).Events), arg0, arg1)
+}
+
+// GetClient mocks base method.
+func (m *MockSyncService) GetClient() *resty.Client {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClient")
+	ret0, _ := ret[0].(*resty.Client)
+	return ret0
+}
+
+// GetClient indicates an expected call of GetClient.
+func (mr *MockSyncServiceMockRecorder) GetClient() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClient", reflect.TypeOf((*MockSyncService)(nil).GetClient))
 }
 
 // Sync mocks base method.
@@ -80,17 +97,17 @@ func (mr *MockSyncServiceMockRecorder) Sync(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sync", reflect.TypeOf((*MockSyncService)(nil).Sync), arg0, arg1)
 }
 
-// Unregister mocks base method.
-func (m *MockSyncService) Unregister(arg0, arg1 string) (string, error) {
+// SyncRecord mocks base method.
+func (m *MockSyncService) SyncRecord(arg0 string, arg1 models0.CollectionName, arg2 primitive.ObjectID) (*models.SyncResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Unregister", arg0, arg1)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "SyncRecord", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.SyncResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Unregister indicates an expected call of Unregister.
-func (mr *MockSyncServiceMockRecorder) Unregister(arg0, arg1 interface{}) *gomock.Call {
+// SyncRecord indicates an expected call of SyncRecord.
+func (mr *MockSyncServiceMockRecorder) SyncRecord(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unregister", reflect.TypeOf((*MockSyncService)(nil).Unregister), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncRecord", reflect.TypeOf((*MockSyncService)(nil).SyncRecord), arg0, arg1, arg2)
 }
@@ -0,0 +1,79 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/blokhinnv/gophkeeper/internal/client/service (interfaces: VaultService)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	reflect "reflect"
+
+	models "github.com/blokhinnv/gophkeeper/internal/server/models"
+	resty "github.com/go-resty/resty/v2"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockVaultService is a mock of VaultService interface.
+type MockVaultService struct {
+	ctrl     *gomock.Controller
+	recorder *MockVaultServiceMockRecorder
+}
+
+// MockVaultServiceMockRecorder is the mock recorder for MockVaultService.
+type MockVaultServiceMockRecorder struct {
+	mock *MockVaultService
+}
+
+// NewMockVaultService creates a new mock instance.
+func NewMockVaultService(ctrl *gomock.Controller) *MockVaultService {
+	mock := &MockVaultService{ctrl: ctrl}
+	mock.recorder = &MockVaultServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVaultService) EXPECT() *MockVaultServiceMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockVaultService) Get(arg0 string) (*models.VaultParams, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0)
+	ret0, _ := ret[0].(*models.VaultParams)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockVaultServiceMockRecorder) Get(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockVaultService)(nil).Get), arg0)
+}
+
+// GetClient mocks base method.
+func (m *MockVaultService) GetClient() *resty.Client {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClient")
+	ret0, _ := ret[0].(*resty.Client)
+	return ret0
+}
+
+// GetClient indicates an expected call of GetClient.
+func (mr *MockVaultServiceMockRecorder) GetClient() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClient", reflect.TypeOf((*MockVaultService)(nil).GetClient))
+}
+
+// Set mocks base method.
+func (m *MockVaultService) Set(arg0 string, arg1 models.VaultParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockVaultServiceMockRecorder) Set(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockVaultService)(nil).Set), arg0, arg1)
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// recordIDRe matches the ID of the added record in the response of the server.
+var recordIDRe = regexp.MustCompile(`id=([0-9a-f]{24})`)
+
+// addedRecordID returns the ID which the server assigned to the record added by the operation.
+func addedRecordID(op clientModels.PendingOp, msg string) (srvrModels.ObjectID, bool) {
+	if op.Op != srvrModels.OpCreate {
+		return op.RecordID, false
+	}
+	match := recordIDRe.FindStringSubmatch(msg)
+	if match == nil {
+		return op.RecordID, false
+	}
+	id, err := srvrModels.ObjectIDFromString(match[1])
+	if err != nil {
+		return op.RecordID, false
+	}
+	return id, true
+}
+
+// withRecordID sets the ID of the record in the request body.
+func withRecordID(body string, id srvrModels.ObjectID) (string, error) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return "", err
+	}
+	fields["record_id"] = id.Hex()
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// sendOp sends the operation to the server with the service.
+func sendOp(service StorageService, op clientModels.PendingOp, token string) (string, error) {
+	switch op.Op {
+	case srvrModels.OpCreate:
+		return service.Add(op.Body, op.Collection, token)
+	case srvrModels.OpUpdate:
+		return service.Update(op.Body, op.Collection, token)
+	case srvrModels.OpDelete:
+		return service.Delete(op.Body, op.Collection, token)
+	default:
+		return "", fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// Replay sends the queued operations to the server in the order they were made
+// and returns the number of the operations accepted by the server. It stops when
+// the server is unavailable. The operations rejected by the server are dropped
+// from the journal and reported with ErrOperationRejected.
+func Replay(service StorageService, store LocalStore, token string) (int, error) {
+	ops, err := store.Pending()
+	if err != nil {
+		return 0, err
+	}
+	replayed := 0
+	var rejected []string
+	for len(ops) > 0 {
+		op := ops[0]
+		ops = ops[1:]
+		msg, err := sendOp(service, op, token)
+		if errors.Is(err, clientErr.ErrServerUnavailable) {
+			return replayed, err
+		}
+		recordID := op.RecordID
+		if err != nil {
+			rejected = append(
+				rejected,
+				fmt.Sprintf("%v %v id=%v: %v", op.Op, op.Collection, op.RecordID.Hex(), err),
+			)
+		} else {
+			replayed++
+			recordID, _ = addedRecordID(op, msg)
+		}
+		if err := store.Complete(op, recordID); err != nil {
+			return replayed, err
+		}
+		if recordID != op.RecordID {
+			// the later operations on the added record use the ID assigned by the server
+			if ops, err = store.Pending(); err != nil {
+				return replayed, err
+			}
+		}
+	}
+	if len(rejected) > 0 {
+		return replayed, fmt.Errorf(
+			"%w: %v",
+			clientErr.ErrOperationRejected,
+			strings.Join(rejected, "; "),
+		)
+	}
+	return replayed, nil
+}
+
+// offlineStorageService wraps a StorageService, mirrors the writes to the local
+// store and queues them while the server is unavailable.
+type offlineStorageService struct {
+	StorageService
+	store LocalStore
+}
+
+// NewOfflineStorageService returns a StorageService which sends the records with
+// the service and keeps the local store up to date. The writes made while the
+// server is unavailable are journaled and sent before the next write.
+func NewOfflineStorageService(service StorageService, store LocalStore) StorageService {
+	return &offlineStorageService{StorageService: service, store: store}
+}
+
+// newOp creates the operation of the request. A new record gets a local ID
+// which is replaced with the ID assigned by the server.
+func newOp(
+	op srvrModels.ChangeOp,
+	body string,
+	collectionName srvrModels.CollectionName,
+) (clientModels.PendingOp, error) {
+	pending := clientModels.PendingOp{
+		Op:         op,
+		Collection: collectionName,
+		Body:       body,
+		QueuedAt:   time.Now(),
+	}
+	if op == srvrModels.OpCreate {
+		pending.RecordID = srvrModels.NewRandomObjectID()
+		var err error
+		pending.Body, err = withRecordID(body, pending.RecordID)
+		return pending, err
+	}
+	var record struct {
+		RecordID string `json:"record_id"`
+	}
+	if err := json.Unmarshal([]byte(body), &record); err != nil {
+		return pending, err
+	}
+	id, err := srvrModels.ObjectIDFromString(record.RecordID)
+	if err != nil {
+		return pending, fmt.Errorf("bad record_id %q: %w", record.RecordID, err)
+	}
+	pending.RecordID = id
+	return pending, nil
+}
+
+// enqueue journals the operation until the server is available.
+func (s *offlineStorageService) enqueue(op clientModels.PendingOp) (string, error) {
+	if err := s.store.Enqueue(op); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(
+		"Server unavailable, the operation is queued: %v %v id=%v",
+		op.Op,
+		op.Collection,
+		op.RecordID.Hex(),
+	), nil
+}
+
+// write sends the queued operations and the new one to the server
+// and applies it to the local store.
+func (s *offlineStorageService) write(
+	op srvrModels.ChangeOp,
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	pending, err := newOp(op, body, collectionName)
+	if err != nil {
+		return "", err
+	}
+	// the operations are sent in the order they were made
+	_, replayErr := Replay(s.StorageService, s.store, token)
+	if errors.Is(replayErr, clientErr.ErrServerUnavailable) {
+		return s.enqueue(pending)
+	} else if replayErr != nil && !errors.Is(replayErr, clientErr.ErrOperationRejected) {
+		return "", replayErr
+	}
+	msg, err := sendOp(s.StorageService, pending, token)
+	if errors.Is(err, clientErr.ErrServerUnavailable) {
+		return s.enqueue(pending)
+	} else if err != nil {
+		return "", err
+	}
+	id, ok := addedRecordID(pending, msg)
+	if ok || op != srvrModels.OpCreate {
+		// the record added without a known ID appears after the next sync
+		pending.RecordID = id
+		if err := s.store.Apply(pending); err != nil {
+			return "", err
+		}
+	}
+	if replayErr != nil {
+		msg = fmt.Sprintf("%v\n%v", replayErr, msg)
+	}
+	return msg, nil
+}
+
+// Add adds the item to a specific collection or queues it.
+func (s *offlineStorageService) Add(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	return s.write(srvrModels.OpCreate, body, collectionName, token)
+}
+
+// Update updates the item in a specific collection or queues the update.
+func (s *offlineStorageService) Update(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	return s.write(srvrModels.OpUpdate, body, collectionName, token)
+}
+
+// Delete removes the item from a specific collection or queues the removal.
+func (s *offlineStorageService) Delete(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	return s.write(srvrModels.OpDelete, body, collectionName, token)
+}
+
+// offlineSyncService wraps a SyncService and keeps the local store up to date.
+type offlineSyncService struct {
+	SyncService
+	storage StorageService
+	store   LocalStore
+}
+
+// NewOfflineSyncService returns a SyncService which sends the queued operations
+// with the storage service before the sync and saves the synced data to the local store.
+func NewOfflineSyncService(
+	service SyncService,
+	storage StorageService,
+	store LocalStore,
+) SyncService {
+	return &offlineSyncService{SyncService: service, storage: storage, store: store}
+}
+
+// syncsAll reports whether the collections include all the allowed collections.
+func syncsAll(collections []srvrModels.CollectionName) bool {
+	synced := make(map[srvrModels.CollectionName]bool, len(collections))
+	for _, c := range collections {
+		synced[c] = true
+	}
+	for _, allowed := range srvrModels.AllowedCollectionNames {
+		if !synced[allowed] {
+			return false
+		}
+	}
+	return true
+}
+
+// Sync sends the queued operations, syncs the collections and saves them to the
+// local store. All the collections are synced with the changes made since the
+// cursor kept in the local store, the other sets of collections are retrieved
+// in full. If some operations are rejected, the data is still saved but
+// the error is returned.
+func (s *offlineSyncService) Sync(
+	token string,
+	collections []srvrModels.CollectionName,
+) (*clientModels.SyncResponse, error) {
+	_, replayErr := Replay(s.storage, s.store, token)
+	if replayErr != nil && !errors.Is(replayErr, clientErr.ErrOperationRejected) {
+		return nil, replayErr
+	}
+	var (
+		data *clientModels.SyncResponse
+		err  error
+	)
+	if syncsAll(collections) {
+		// the rejected operations remain in the local copy, so it is synced from scratch
+		data, err = s.syncChanges(token, replayErr != nil)
+	} else {
+		data, err = s.SyncService.Sync(token, collections)
+		if err == nil {
+			err = s.store.Save(data, collections)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if replayErr != nil {
+		return nil, replayErr
+	}
+	return data, nil
+}
+
+// syncChanges applies the changes made since the cursor of the local store
+// and returns the local copy. The local copy is synced from scratch if reset
+// is set or it is the file written by the sync command of older clients.
+func (s *offlineSyncService) syncChanges(
+	token string,
+	reset bool,
+) (*clientModels.SyncResponse, error) {
+	cursor, err := s.store.Cursor()
+	if reset || errors.Is(err, clientErr.ErrNotLocalStore) {
+		cursor = ""
+	} else if err != nil {
+		return nil, err
+	}
+	changes, err := s.SyncService.Changes(token, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.ApplyChanges(changes); err != nil {
+		return nil, err
+	}
+	return s.store.Load()
+}
+
+// SyncRecord retrieves a single record of the collection and saves it to the local store.
+func (s *offlineSyncService) SyncRecord(
+	token string,
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+) (*clientModels.SyncResponse, error) {
+	data, err := s.SyncService.SyncRecord(token, collectionName, id)
+	if errors.Is(err, srvErrors.ErrRecordNotFound) {
+		deleted := clientModels.PendingOp{Op: srvrModels.OpDelete, Collection: collectionName, RecordID: id}
+		if applyErr := s.store.Apply(deleted); applyErr != nil {
+			return nil, applyErr
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Merge(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// fakeServerStorage keeps the records in memory and may be switched offline.
+type fakeServerStorage struct {
+	StorageService
+	offline bool
+	// rejected is the text of the records the server rejects.
+	rejected string
+	records  map[srvrModels.ObjectID]string
+}
+
+// newFakeServerStorage returns an empty online storage.
+func newFakeServerStorage() *fakeServerStorage {
+	return &fakeServerStorage{records: make(map[srvrModels.ObjectID]string)}
+}
+
+// parse returns the record of the request or an error if the request can't be handled.
+func (s *fakeServerStorage) parse(body string) (srvrModels.ObjectID, string, error) {
+	if s.offline {
+		return srvrModels.ObjectID{}, "", fmt.Errorf("%w: connection refused", clientErr.ErrServerUnavailable)
+	}
+	var record struct {
+		RecordID srvrModels.ObjectID `json:"record_id"`
+		Data     string              `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &record); err != nil {
+		return srvrModels.ObjectID{}, "", err
+	}
+	if s.rejected != "" && record.Data == s.rejected {
+		return srvrModels.ObjectID{}, "", errors.New("bad record")
+	}
+	return record.RecordID, record.Data, nil
+}
+
+func (s *fakeServerStorage) Add(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	_, data, err := s.parse(body)
+	if err != nil {
+		return "", err
+	}
+	id := srvrModels.NewRandomObjectID()
+	s.records[id] = data
+	return fmt.Sprintf("Record added to %v collection: id=%v", collectionName, id.Hex()), nil
+}
+
+func (s *fakeServerStorage) Update(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	id, data, err := s.parse(body)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := s.records[id]; !ok {
+		return "", srvErrors.ErrRecordNotFound
+	}
+	s.records[id] = data
+	return fmt.Sprintf("Record id=%v updated in %v collection", id.Hex(), collectionName), nil
+}
+
+func (s *fakeServerStorage) Delete(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	id, _, err := s.parse(body)
+	if err != nil {
+		return "", err
+	}
+	delete(s.records, id)
+	return fmt.Sprintf("Record id=%v moved to the trash of %v collection", id.Hex(), collectionName), nil
+}
+
+// texts returns the text records of the local store by their data.
+func texts(t *testing.T, store LocalStore) map[string]srvrModels.ObjectID {
+	data, err := store.Load()
+	require.NoError(t, err)
+	res := make(map[string]srvrModels.ObjectID)
+	for _, r := range data.Text {
+		res[r.Data] = r.RecordID
+	}
+	return res
+}
+
+func TestOfflineStorageService(t *testing.T) {
+	server := newFakeServerStorage()
+	store := NewLocalStore(filepath.Join(t.TempDir(), "store.db"), "somekey")
+	s := NewOfflineStorageService(server, store)
+
+	t.Run("online", func(t *testing.T) {
+		msg, err := s.Add(`{"record_id": "000000000000000000000000", "data": "online"}`, "text", "token")
+		require.NoError(t, err)
+		assert.Contains(t, msg, "Record added")
+		id := texts(t, store)["online"]
+		assert.Equal(t, "online", server.records[id])
+	})
+	t.Run("offline", func(t *testing.T) {
+		server.offline = true
+		msg, err := s.Add(`{"data": "draft"}`, "text", "token")
+		require.NoError(t, err)
+		assert.Contains(t, msg, "queued")
+		local := texts(t, store)["draft"]
+
+		body := fmt.Sprintf(`{"record_id": "%v", "data": "final"}`, local.Hex())
+		_, err = s.Update(body, "text", "token")
+		require.NoError(t, err)
+		body = fmt.Sprintf(`{"record_id": "%v"}`, texts(t, store)["online"].Hex())
+		_, err = s.Delete(body, "text", "token")
+		require.NoError(t, err)
+
+		ops, err := store.Pending()
+		require.NoError(t, err)
+		assert.Len(t, ops, 3)
+		assert.Equal(t, map[string]srvrModels.ObjectID{"final": local}, texts(t, store))
+		assert.Len(t, server.records, 1)
+	})
+	t.Run("replay", func(t *testing.T) {
+		server.offline = false
+		_, err := s.Add(`{"data": "next"}`, "text", "token")
+		require.NoError(t, err)
+
+		ops, err := store.Pending()
+		require.NoError(t, err)
+		assert.Empty(t, ops)
+		local := texts(t, store)
+		require.Len(t, local, 2)
+		// the records use the IDs assigned by the server
+		assert.Equal(t, "final", server.records[local["final"]])
+		assert.Equal(t, "next", server.records[local["next"]])
+		assert.Len(t, server.records, 2)
+	})
+	t.Run("bad_record_id", func(t *testing.T) {
+		_, err := s.Update(`{"record_id": "bad", "data": "text"}`, "text", "token")
+		assert.Error(t, err)
+	})
+}
+
+func TestReplay(t *testing.T) {
+	server := newFakeServerStorage()
+	store := NewLocalStore(filepath.Join(t.TempDir(), "store.db"), "somekey")
+	s := NewOfflineStorageService(server, store)
+	server.offline = true
+	for _, text := range []string{"first", "bad", "second"} {
+		_, err := s.Add(fmt.Sprintf(`{"data": "%v"}`, text), "text", "token")
+		require.NoError(t, err)
+	}
+
+	t.Run("unavailable", func(t *testing.T) {
+		replayed, err := Replay(server, store, "token")
+		assert.ErrorIs(t, err, clientErr.ErrServerUnavailable)
+		assert.Equal(t, 0, replayed)
+	})
+	t.Run("rejected", func(t *testing.T) {
+		server.offline, server.rejected = false, "bad"
+		replayed, err := Replay(server, store, "token")
+		assert.ErrorIs(t, err, clientErr.ErrOperationRejected)
+		assert.Equal(t, 2, replayed)
+
+		// the rejected operation is dropped
+		ops, err := store.Pending()
+		require.NoError(t, err)
+		assert.Empty(t, ops)
+		assert.Len(t, server.records, 2)
+	})
+}
+
+// unavailableSyncService fails to sync every time.
+type unavailableSyncService struct {
+	SyncService
+}
+
+func (s *unavailableSyncService) Sync(
+	token string,
+	collectionNames []srvrModels.CollectionName,
+) (*clientModels.SyncResponse, error) {
+	return nil, clientErr.ErrServerUnavailable
+}
+
+func (s *unavailableSyncService) Changes(token string, cursor string) (*srvrModels.Changes, error) {
+	return nil, clientErr.ErrServerUnavailable
+}
+
+// changesSyncService returns the changes by the cursor and remembers the requested cursors.
+type changesSyncService struct {
+	SyncService
+	changes map[string]*srvrModels.Changes
+	cursors []string
+}
+
+func (s *changesSyncService) Changes(token string, cursor string) (*srvrModels.Changes, error) {
+	s.cursors = append(s.cursors, cursor)
+	return s.changes[cursor], nil
+}
+
+func TestOfflineSyncService(t *testing.T) {
+	id := srvrModels.NewRandomObjectID()
+	store := NewLocalStore(filepath.Join(t.TempDir(), "store.db"), "somekey")
+	server := newFakeServerStorage()
+
+	t.Run("sync", func(t *testing.T) {
+		s := NewOfflineSyncService(&staticSyncService{resp: clientModels.SyncResponse{
+			Text: []srvrModels.TextRecord{{RecordID: id, Data: "synced"}},
+		}}, server, store)
+		data, err := s.Sync("token", []srvrModels.CollectionName{srvrModels.TextCollection})
+		require.NoError(t, err)
+		assert.Len(t, data.Text, 1)
+		assert.Equal(t, map[string]srvrModels.ObjectID{"synced": id}, texts(t, store))
+	})
+	t.Run("sync_record", func(t *testing.T) {
+		updated := srvrModels.NewRandomObjectID()
+		s := NewOfflineSyncService(&staticSyncService{resp: clientModels.SyncResponse{
+			Text: []srvrModels.TextRecord{{RecordID: updated, Data: "pushed"}},
+		}}, server, store)
+		_, err := s.SyncRecord("token", srvrModels.TextCollection, updated)
+		require.NoError(t, err)
+		assert.Equal(t, updated, texts(t, store)["pushed"])
+	})
+	t.Run("unavailable", func(t *testing.T) {
+		s := NewOfflineSyncService(&unavailableSyncService{}, server, store)
+		_, err := s.Sync("token", srvrModels.AllowedCollectionNames)
+		assert.ErrorIs(t, err, clientErr.ErrServerUnavailable)
+		// the local copy is kept
+		assert.Len(t, texts(t, store), 2)
+	})
+}
+
+func TestOfflineSyncService_Changes(t *testing.T) {
+	store := NewLocalStore(filepath.Join(t.TempDir(), "store.db"), "somekey")
+	kept, deleted := srvrModels.NewRandomObjectID(), srvrModels.NewRandomObjectID()
+	text := func(id srvrModels.ObjectID, data string) srvrModels.ChangedRecord {
+		return srvrModels.ChangedRecord{
+			Collection: srvrModels.TextCollection,
+			Record: srvrModels.UntypedRecord{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{Data: data},
+				RecordID:             id,
+			},
+		}
+	}
+	sync := &changesSyncService{changes: map[string]*srvrModels.Changes{
+		"": {
+			Upserts: []srvrModels.ChangedRecord{text(kept, "kept"), text(deleted, "deleted")},
+			Cursor:  "first",
+			Reset:   true,
+		},
+		"first": {
+			Upserts:    []srvrModels.ChangedRecord{text(kept, "changed")},
+			Tombstones: []srvrModels.Tombstone{{Collection: srvrModels.TextCollection, RecordID: deleted}},
+			Cursor:     "second",
+		},
+	}}
+	s := NewOfflineSyncService(sync, newFakeServerStorage(), store)
+
+	t.Run("first", func(t *testing.T) {
+		data, err := s.Sync("token", srvrModels.AllowedCollectionNames)
+		require.NoError(t, err)
+		assert.Len(t, data.Text, 2)
+		cursor, err := store.Cursor()
+		require.NoError(t, err)
+		assert.Equal(t, "first", cursor)
+	})
+	t.Run("delta", func(t *testing.T) {
+		_, err := s.Sync("token", srvrModels.AllowedCollectionNames)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]srvrModels.ObjectID{"changed": kept}, texts(t, store))
+		cursor, err := store.Cursor()
+		require.NoError(t, err)
+		assert.Equal(t, "second", cursor)
+	})
+	t.Run("rejected", func(t *testing.T) {
+		pending := newTextOp(t, srvrModels.OpCreate, srvrModels.NewRandomObjectID(), "pending")
+		require.NoError(t, store.Enqueue(pending))
+		// the local copy with the rejected operation is synced from scratch
+		s := NewOfflineSyncService(sync, &fakeServerStorage{rejected: "pending"}, store)
+		_, err := s.Sync("token", srvrModels.AllowedCollectionNames)
+		assert.ErrorIs(t, err, clientErr.ErrOperationRejected)
+		assert.Equal(t, []string{"", "first", ""}, sync.cursors)
+		assert.Equal(
+			t,
+			map[string]srvrModels.ObjectID{"kept": kept, "deleted": deleted},
+			texts(t, store),
+		)
+	})
+}
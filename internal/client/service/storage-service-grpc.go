@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-resty/resty/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// grpcStorageService is an implementation of StorageService over gRPC.
+type grpcStorageService struct {
+	client pb.StorageClient
+}
+
+// NewGRPCStorageService returns a new instance of StorageService which uses the gRPC server addr.
+func NewGRPCStorageService(addr string, opts ...grpc.DialOption) (StorageService, error) {
+	conn, err := newGRPCConn(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcStorageService{client: pb.NewStorageClient(conn)}, nil
+}
+
+// recordFromBody converts the JSON body of the REST API into a message.
+func recordFromBody(body string, collectionName srvrModels.CollectionName) (*pb.Record, error) {
+	var r srvrModels.UntypedRecord
+	if err := json.Unmarshal([]byte(body), &r); err != nil {
+		return nil, err
+	}
+	return pb.NewRecord(collectionName, r.RecordID, r.Data, r.Metadata)
+}
+
+// GetAll retrieves a page of the data from a specific collection.
+func (s *grpcStorageService) GetAll(
+	collectionName srvrModels.CollectionName,
+	data *clientModels.SyncResponse,
+	options srvrModels.GetAllOptions,
+) (any, string, error) {
+	return listRecords(collectionName, data, options)
+}
+
+// Add adds a new item to a specific collection.
+func (s *grpcStorageService) Add(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	record, err := recordFromBody(body, collectionName)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Store(tokenContext(context.Background(), token), &pb.StoreRequest{
+		Collection: string(collectionName),
+		Record:     record,
+	})
+	if err != nil {
+		return "", grpcError(err)
+	}
+	return resp.GetMessage(), nil
+}
+
+// Update updates an existing item in a specific collection.
+func (s *grpcStorageService) Update(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	var r updateBody
+	if err := json.Unmarshal([]byte(body), &r); err != nil {
+		return "", err
+	}
+	record, err := pb.NewRecord(collectionName, r.RecordID, r.Data, r.Metadata)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Update(tokenContext(context.Background(), token), &pb.UpdateRequest{
+		Collection:      string(collectionName),
+		Record:          record,
+		ExpectedVersion: r.ExpectedVersion,
+	})
+	if err != nil {
+		return "", updateError(err, collectionName)
+	}
+	return resp.GetMessage(), nil
+}
+
+// updateError converts the Aborted status with the current record in its
+// details into ConflictError and any other status like grpcError does.
+func updateError(err error, collectionName srvrModels.CollectionName) error {
+	st := status.Convert(err)
+	if st.Code() != codes.Aborted {
+		return grpcError(err)
+	}
+	for _, detail := range st.Details() {
+		if record, ok := detail.(*pb.Record); ok {
+			current, err := record.ModelRecord(collectionName)
+			if err != nil {
+				return err
+			}
+			return &clientErr.ConflictError{Current: current}
+		}
+	}
+	return grpcError(err)
+}
+
+// Delete moves an existing item of a specific collection to the trash.
+func (s *grpcStorageService) Delete(
+	body string,
+	collectionName srvrModels.CollectionName,
+	token string,
+) (string, error) {
+	var r struct {
+		RecordID string `json:"record_id"`
+	}
+	if err := json.Unmarshal([]byte(body), &r); err != nil {
+		return "", err
+	}
+	resp, err := s.client.Delete(tokenContext(context.Background(), token), &pb.DeleteRequest{
+		Collection: string(collectionName),
+		RecordId:   r.RecordID,
+	})
+	if err != nil {
+		return "", grpcError(err)
+	}
+	return resp.GetMessage(), nil
+}
+
+// Trash returns the deleted items of a specific collection which have not been purged yet.
+func (s *grpcStorageService) Trash(
+	collectionName srvrModels.CollectionName,
+	token string,
+) ([]srvrModels.UntypedRecord, error) {
+	resp, err := s.client.Trash(tokenContext(context.Background(), token), &pb.TrashRequest{
+		Collection: string(collectionName),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	records := make([]srvrModels.UntypedRecord, 0, len(resp.GetRecords()))
+	for _, r := range resp.GetRecords() {
+		record, err := r.ModelRecord(collectionName)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Search returns the items of all the collections which have all the words of the query.
+func (s *grpcStorageService) Search(query string, token string) ([]srvrModels.SearchResult, error) {
+	resp, err := s.client.Search(tokenContext(context.Background(), token), &pb.SearchRequest{
+		Query: query,
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.ModelResults()
+}
+
+// Undelete moves an item back from the trash.
+func (s *grpcStorageService) Undelete(
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	token string,
+) (string, error) {
+	resp, err := s.client.Undelete(tokenContext(context.Background(), token), &pb.UndeleteRequest{
+		Collection: string(collectionName),
+		RecordId:   id.Hex(),
+	})
+	if err != nil {
+		return "", grpcError(err)
+	}
+	return resp.GetMessage(), nil
+}
+
+// Purge permanently deletes an item from the trash.
+func (s *grpcStorageService) Purge(
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	token string,
+) (string, error) {
+	resp, err := s.client.Purge(tokenContext(context.Background(), token), &pb.PurgeRequest{
+		Collection: string(collectionName),
+		RecordId:   id.Hex(),
+	})
+	if err != nil {
+		return "", grpcError(err)
+	}
+	return resp.GetMessage(), nil
+}
+
+// History returns the previous revisions of an item, the latest first.
+func (s *grpcStorageService) History(
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	token string,
+) ([]srvrModels.Revision, error) {
+	resp, err := s.client.History(tokenContext(context.Background(), token), &pb.HistoryRequest{
+		Collection: string(collectionName),
+		RecordId:   id.Hex(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	revisions := make([]srvrModels.Revision, 0, len(resp.GetRevisions()))
+	for _, r := range resp.GetRevisions() {
+		revision, err := r.ModelRevision(collectionName)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+// Restore replaces an item with the revision rev from its history.
+func (s *grpcStorageService) Restore(
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	rev int64,
+	token string,
+) (string, error) {
+	resp, err := s.client.Restore(tokenContext(context.Background(), token), &pb.RestoreRequest{
+		Collection: string(collectionName),
+		RecordId:   id.Hex(),
+		Rev:        rev,
+	})
+	if err != nil {
+		return "", grpcError(err)
+	}
+	return resp.GetMessage(), nil
+}
+
+// GetClient returns nil since the service does not use the REST API.
+func (s *grpcStorageService) GetClient() *resty.Client {
+	return nil
+}
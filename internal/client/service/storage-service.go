@@ -1,11 +1,15 @@
 package service
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-resty/resty/v2"
+	"golang.org/x/exp/slices"
 
 	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
 	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
@@ -14,14 +18,52 @@ import (
 
 // StorageService defines the interface for managing data storage.
 type StorageService interface {
-	// GetAll retrieves all data from a specific collection.
-	GetAll(collectionName srvrModels.CollectionName, data *clientModels.SyncResponse) any
+	// GetAll retrieves a page of the data from a specific collection listed with
+	// the same options as the server's. The cursor of the next page is returned
+	// if there are more items.
+	GetAll(
+		collectionName srvrModels.CollectionName,
+		data *clientModels.SyncResponse,
+		options srvrModels.GetAllOptions,
+	) (any, string, error)
 	// Add adds a new item to a specific collection.
 	Add(body string, collectionName srvrModels.CollectionName, token string) (string, error)
-	// Update updates an existing item in a specific collection.
+	// Update updates an existing item in a specific collection. If the body has
+	// the expected_version field and the item has another version on the server,
+	// ConflictError with the current item is returned.
 	Update(body string, collectionName srvrModels.CollectionName, token string) (string, error)
-	// Delete removes an existing item from a specific collection.
+	// Delete moves an existing item of a specific collection to the trash.
 	Delete(body string, collectionName srvrModels.CollectionName, token string) (string, error)
+	// Trash returns the deleted items of a specific collection which have not been purged yet.
+	Trash(collectionName srvrModels.CollectionName, token string) ([]srvrModels.UntypedRecord, error)
+	// Undelete moves an item back from the trash.
+	Undelete(
+		collectionName srvrModels.CollectionName,
+		id srvrModels.ObjectID,
+		token string,
+	) (string, error)
+	// Purge permanently deletes an item from the trash.
+	Purge(
+		collectionName srvrModels.CollectionName,
+		id srvrModels.ObjectID,
+		token string,
+	) (string, error)
+	// History returns the previous revisions of an item, the latest first.
+	History(
+		collectionName srvrModels.CollectionName,
+		id srvrModels.ObjectID,
+		token string,
+	) ([]srvrModels.Revision, error)
+	// Restore replaces an item with the revision rev from its history.
+	Restore(
+		collectionName srvrModels.CollectionName,
+		id srvrModels.ObjectID,
+		rev int64,
+		token string,
+	) (string, error)
+	// Search returns the items of all the collections which have all the words
+	// of the query. The end-to-end encrypted items are not found.
+	Search(query string, token string) ([]srvrModels.SearchResult, error)
 	// GetClient returns the service's client.
 	GetClient() *resty.Client
 }
@@ -37,10 +79,113 @@ func NewStorageService(baseURL string) StorageService {
 	return &storageService{client: client}
 }
 
-// GetAll retrieves all data from a specific collection.
+// GetAll retrieves a page of the data from a specific collection.
 func (s *storageService) GetAll(
 	collectionName srvrModels.CollectionName,
 	data *clientModels.SyncResponse,
+	options srvrModels.GetAllOptions,
+) (any, string, error) {
+	return listRecords(collectionName, data, options)
+}
+
+// listRecords returns a page of the records of a specific collection from the synced data.
+// The records are filtered, sorted and split into pages the same way the server does it.
+func listRecords(
+	collectionName srvrModels.CollectionName,
+	data *clientModels.SyncResponse,
+	options srvrModels.GetAllOptions,
+) (any, string, error) {
+	if err := options.Validate(); err != nil {
+		return nil, "", err
+	}
+	switch collectionName {
+	case srvrModels.TextCollection:
+		records, cursor := page(data.Text, options, func(r srvrModels.TextRecord) pageItem {
+			return pageItem{r.RecordID, r.UpdatedAt, r.Metadata}
+		})
+		return records, cursor, nil
+	case srvrModels.BinaryCollection:
+		records, cursor := page(data.Binary, options, func(r srvrModels.BinaryRecord) pageItem {
+			return pageItem{r.RecordID, r.UpdatedAt, r.Metadata}
+		})
+		if options.OmitContent {
+			omitted := make([]srvrModels.BinaryRecord, 0, len(records))
+			for _, r := range records {
+				r.Data.Content = ""
+				omitted = append(omitted, r)
+			}
+			records = omitted
+		}
+		return records, cursor, nil
+	case srvrModels.CardCollection:
+		records, cursor := page(data.Card, options, func(r srvrModels.CardRecord) pageItem {
+			return pageItem{r.RecordID, r.UpdatedAt, r.Metadata}
+		})
+		return records, cursor, nil
+	case srvrModels.CredentialsCollection:
+		records, cursor := page(
+			data.Credential,
+			options,
+			func(r srvrModels.CredentialRecord) pageItem {
+				return pageItem{r.RecordID, r.UpdatedAt, r.Metadata}
+			},
+		)
+		return records, cursor, nil
+	case srvrModels.OTPCollection:
+		records, cursor := page(data.OTP, options, func(r srvrModels.OTPRecord) pageItem {
+			return pageItem{r.RecordID, r.UpdatedAt, r.Metadata}
+		})
+		return records, cursor, nil
+	default:
+		return nil, "", nil
+	}
+}
+
+// pageItem holds the fields of a record the listing depends on.
+type pageItem struct {
+	recordID  srvrModels.ObjectID
+	updatedAt *time.Time
+	metadata  srvrModels.Metadata
+}
+
+// page returns the records listed with the options and the cursor of the next page.
+func page[T any](
+	records []T,
+	options srvrModels.GetAllOptions,
+	item func(T) pageItem,
+) ([]T, string) {
+	key := func(r T) srvrModels.PageKey {
+		i := item(r)
+		return srvrModels.PageKey{RecordID: i.recordID, UpdatedAt: i.updatedAt}
+	}
+	if len(records) == 0 {
+		return records, ""
+	}
+	after, _ := options.After()
+	res := make([]T, 0, len(records))
+	for _, r := range records {
+		if !options.Matches(item(r).metadata) {
+			continue
+		}
+		if after != nil && !options.Less(*after, key(r)) {
+			continue
+		}
+		res = append(res, r)
+	}
+	slices.SortStableFunc(res, func(a, b T) bool {
+		return options.Less(key(a), key(b))
+	})
+	if options.Limit == 0 || int64(len(res)) <= options.Limit {
+		return res, ""
+	}
+	res = res[:options.Limit]
+	return res, options.NextCursor(key(res[len(res)-1]))
+}
+
+// collectionRecords returns the records of a specific collection from the synced data.
+func collectionRecords(
+	collectionName srvrModels.CollectionName,
+	data *clientModels.SyncResponse,
 ) any {
 	switch collectionName {
 	case srvrModels.TextCollection:
@@ -51,11 +196,19 @@ func (s *storageService) GetAll(
 		return data.Card
 	case srvrModels.CredentialsCollection:
 		return data.Credential
+	case srvrModels.OTPCollection:
+		return data.OTP
 	default:
 		return nil
 	}
 }
 
+// updateBody is the body of an update request.
+type updateBody struct {
+	srvrModels.UntypedRecord
+	ExpectedVersion int64 `json:"expected_version,omitempty"`
+}
+
 // Add adds a new item to a specific collection.
 func (s *storageService) Add(
 	body string,
@@ -90,13 +243,20 @@ func (s *storageService) Update(
 	if err != nil {
 		return "", fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
 	}
+	if resp.StatusCode() == http.StatusConflict {
+		var current srvrModels.UntypedRecord
+		if err := json.Unmarshal(resp.Body(), &current); err != nil {
+			return "", err
+		}
+		return "", &clientErr.ConflictError{Current: current}
+	}
 	if resp.StatusCode() >= http.StatusBadRequest {
 		return "", errors.New(resp.String())
 	}
 	return resp.String(), nil
 }
 
-// Delete removes an existing item from a specific collection.
+// Delete moves an existing item of a specific collection to the trash.
 func (s *storageService) Delete(
 	body string,
 	collectionName srvrModels.CollectionName,
@@ -116,6 +276,124 @@ func (s *storageService) Delete(
 	return resp.String(), nil
 }
 
+// Trash returns the deleted items of a specific collection which have not been purged yet.
+func (s *storageService) Trash(
+	collectionName srvrModels.CollectionName,
+	token string,
+) ([]srvrModels.UntypedRecord, error) {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		Get(fmt.Sprintf("/api/store/%v/trash", collectionName))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return nil, errors.New(resp.String())
+	}
+	var records []srvrModels.UntypedRecord
+	if err := json.Unmarshal(resp.Body(), &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Search returns the items of all the collections which have all the words of the query.
+func (s *storageService) Search(query string, token string) ([]srvrModels.SearchResult, error) {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetQueryParam("q", query).
+		Get("/api/search")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return nil, errors.New(resp.String())
+	}
+	var results []srvrModels.SearchResult
+	if err := json.Unmarshal(resp.Body(), &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Undelete moves an item back from the trash.
+func (s *storageService) Undelete(
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	token string,
+) (string, error) {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		Post(fmt.Sprintf("/api/store/%v/trash/%v/restore", collectionName, id.Hex()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return "", errors.New(resp.String())
+	}
+	return resp.String(), nil
+}
+
+// Purge permanently deletes an item from the trash.
+func (s *storageService) Purge(
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	token string,
+) (string, error) {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		Delete(fmt.Sprintf("/api/store/%v/trash/%v", collectionName, id.Hex()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return "", errors.New(resp.String())
+	}
+	return resp.String(), nil
+}
+
+// History returns the previous revisions of an item, the latest first.
+func (s *storageService) History(
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	token string,
+) ([]srvrModels.Revision, error) {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		Get(fmt.Sprintf("/api/store/%v/%v/history", collectionName, id.Hex()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return nil, errors.New(resp.String())
+	}
+	var revisions []srvrModels.Revision
+	if err := json.Unmarshal(resp.Body(), &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// Restore replaces an item with the revision rev from its history.
+func (s *storageService) Restore(
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	rev int64,
+	token string,
+) (string, error) {
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetQueryParam("rev", strconv.FormatInt(rev, 10)).
+		Post(fmt.Sprintf("/api/store/%v/%v/restore", collectionName, id.Hex()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return "", errors.New(resp.String())
+	}
+	return resp.String(), nil
+}
+
 // GetClient returns the service's client.
 func (s *storageService) GetClient() *resty.Client {
 	return s.client
@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
 	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
 	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
 )
@@ -28,7 +32,9 @@ func TestStorageService_GetAll(t *testing.T) {
 	}
 
 	t.Run("get_text", func(t *testing.T) {
-		r := s.GetAll(srvrModels.TextCollection, data)
+		r, cursor, err := s.GetAll(srvrModels.TextCollection, data, srvrModels.GetAllOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, cursor)
 		// Assert
 		texts, ok := r.([]srvrModels.TextRecord)
 		if !ok {
@@ -44,15 +50,103 @@ func TestStorageService_GetAll(t *testing.T) {
 		}
 	})
 	t.Run("get_creds", func(t *testing.T) {
-		r := s.GetAll(srvrModels.CredentialsCollection, data)
+		r, _, err := s.GetAll(srvrModels.CredentialsCollection, data, srvrModels.GetAllOptions{})
+		require.NoError(t, err)
+		assert.Nil(t, r)
+	})
+	t.Run("get_otp", func(t *testing.T) {
+		r, _, err := s.GetAll(srvrModels.OTPCollection, data, srvrModels.GetAllOptions{})
+		require.NoError(t, err)
 		assert.Nil(t, r)
 	})
 	t.Run("other", func(t *testing.T) {
-		r := s.GetAll(srvrModels.CollectionName("other"), data)
+		r, _, err := s.GetAll(srvrModels.CollectionName("other"), data, srvrModels.GetAllOptions{})
+		require.NoError(t, err)
 		assert.Nil(t, r)
 	})
 }
 
+func TestStorageService_GetAllPage(t *testing.T) {
+	s := NewStorageService("https://example.com")
+	earlier := time.Date(2023, 5, 9, 12, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+	ids := []srvrModels.ObjectID{
+		srvrModels.NewRandomObjectID(),
+		srvrModels.NewRandomObjectID(),
+		srvrModels.NewRandomObjectID(),
+	}
+	data := &clientModels.SyncResponse{
+		Binary: []srvrModels.BinaryRecord{
+			{
+				RecordID:  ids[2],
+				Data:      srvrModels.BinaryInfo{FileName: "c.txt", Content: "Yw=="},
+				Metadata:  srvrModels.Metadata{"type": "doc"},
+				UpdatedAt: &earlier,
+			},
+			{
+				RecordID:  ids[0],
+				Data:      srvrModels.BinaryInfo{FileName: "a.txt", Content: "YQ=="},
+				Metadata:  srvrModels.Metadata{"type": "doc"},
+				UpdatedAt: &later,
+			},
+			{
+				RecordID: ids[1],
+				Data:     srvrModels.BinaryInfo{FileName: "b.png", Content: "Yg=="},
+				Metadata: srvrModels.Metadata{"type": "image"},
+			},
+		},
+	}
+	fileNames := func(r any) []string {
+		names := make([]string, 0)
+		for _, record := range r.([]srvrModels.BinaryRecord) {
+			names = append(names, record.Data.FileName)
+		}
+		return names
+	}
+
+	t.Run("created", func(t *testing.T) {
+		options := srvrModels.GetAllOptions{Limit: 2}
+		r, cursor, err := s.GetAll(srvrModels.BinaryCollection, data, options)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.txt", "b.png"}, fileNames(r))
+		require.NotEmpty(t, cursor)
+
+		options.Cursor = cursor
+		r, cursor, err = s.GetAll(srvrModels.BinaryCollection, data, options)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c.txt"}, fileNames(r))
+		assert.Empty(t, cursor)
+	})
+	t.Run("updated", func(t *testing.T) {
+		r, _, err := s.GetAll(
+			srvrModels.BinaryCollection,
+			data,
+			srvrModels.GetAllOptions{Sort: "-updated"},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.txt", "c.txt", "b.png"}, fileNames(r))
+	})
+	t.Run("metadata", func(t *testing.T) {
+		r, _, err := s.GetAll(srvrModels.BinaryCollection, data, srvrModels.GetAllOptions{
+			Metadata:    srvrModels.Metadata{"type": "doc"},
+			OmitContent: true,
+		})
+		require.NoError(t, err)
+		records := r.([]srvrModels.BinaryRecord)
+		require.Len(t, records, 2)
+		assert.Empty(t, records[0].Data.Content)
+		assert.Equal(t, "Yw==", data.Binary[0].Data.Content, "the synced data is not changed")
+	})
+	t.Run("bad_options", func(t *testing.T) {
+		_, _, err := s.GetAll(
+			srvrModels.BinaryCollection,
+			data,
+			srvrModels.GetAllOptions{Sort: "name"},
+		)
+		assert.ErrorIs(t, err, srvErrors.ErrBadListOptions)
+	})
+}
+
 func TestStorageService_Add(t *testing.T) {
 	baseURL := "https://example.com"
 	s := NewStorageService(baseURL)
@@ -149,6 +243,29 @@ func TestStorageService_Update(t *testing.T) {
 		assert.Equal(t, "", resp)
 		assert.Equal(t, "bad", err.Error())
 	})
+	t.Run("conflict", func(t *testing.T) {
+		httpmock.Reset()
+
+		current := srvrModels.UntypedRecord{
+			RecordID:             models.NewRandomObjectID(),
+			UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "their data"},
+			Version:              3,
+		}
+		responder, err := httpmock.NewJsonResponder(http.StatusConflict, current)
+		require.NoError(t, err)
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			fmt.Sprintf("%v/api/store/%v", baseURL, srvrModels.TextCollection),
+			responder,
+		)
+
+		body := fmt.Sprintf(`{"record_id": %q, "data": "my data", "expected_version": 2}`, current.RecordID.Hex())
+		_, err = s.Update(body, srvrModels.TextCollection, "some-token...")
+		var conflict *clientErr.ConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.ErrorIs(t, err, srvErrors.ErrVersionConflict)
+		assert.Equal(t, current, conflict.Current)
+	})
 }
 func TestStorageService_Delete(t *testing.T) {
 	baseURL := "https://example.com"
@@ -190,3 +307,216 @@ func TestStorageService_Delete(t *testing.T) {
 		assert.Equal(t, "bad", err.Error())
 	})
 }
+
+func TestStorageService_History(t *testing.T) {
+	baseURL := "https://example.com"
+	s := NewStorageService(baseURL)
+	httpmock.ActivateNonDefault(s.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+	id := models.NewRandomObjectID()
+	url := fmt.Sprintf("%v/api/store/%v/%v/history", baseURL, srvrModels.TextCollection, id.Hex())
+
+	t.Run("ok", func(t *testing.T) {
+		httpmock.Reset()
+
+		expected := []srvrModels.Revision{{
+			UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "old text"},
+			RecordID:             id,
+			Rev:                  1,
+			Op:                   srvrModels.OpUpdate,
+		}}
+		responder, err := httpmock.NewJsonResponder(http.StatusOK, expected)
+		require.NoError(t, err)
+		httpmock.RegisterResponder(http.MethodGet, url, responder)
+
+		revisions, err := s.History(srvrModels.TextCollection, id, "some-token...")
+		require.NoError(t, err)
+		assert.Equal(t, expected, revisions)
+	})
+	t.Run("bad", func(t *testing.T) {
+		httpmock.Reset()
+
+		httpmock.RegisterResponder(http.MethodGet, url, httpmock.NewStringResponder(500, "bad"))
+
+		_, err := s.History(srvrModels.TextCollection, id, "some-token...")
+		assert.EqualError(t, err, "bad")
+	})
+}
+
+func TestStorageService_Restore(t *testing.T) {
+	baseURL := "https://example.com"
+	s := NewStorageService(baseURL)
+	httpmock.ActivateNonDefault(s.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+	id := models.NewRandomObjectID()
+	url := fmt.Sprintf("%v/api/store/%v/%v/restore", baseURL, srvrModels.TextCollection, id.Hex())
+
+	t.Run("ok", func(t *testing.T) {
+		httpmock.Reset()
+
+		httpmock.RegisterResponderWithQuery(
+			http.MethodPost,
+			url,
+			"rev=2",
+			httpmock.NewStringResponder(http.StatusAccepted, "restored"),
+		)
+
+		msg, err := s.Restore(srvrModels.TextCollection, id, 2, "some-token...")
+		require.NoError(t, err)
+		assert.Equal(t, "restored", msg)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		httpmock.Reset()
+
+		httpmock.RegisterResponderWithQuery(
+			http.MethodPost,
+			url,
+			"rev=7",
+			httpmock.NewStringResponder(http.StatusNotFound, "revision was not found"),
+		)
+
+		_, err := s.Restore(srvrModels.TextCollection, id, 7, "some-token...")
+		assert.EqualError(t, err, "revision was not found")
+	})
+}
+
+func TestStorageService_Trash(t *testing.T) {
+	baseURL := "https://example.com"
+	s := NewStorageService(baseURL)
+	httpmock.ActivateNonDefault(s.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+	id := models.NewRandomObjectID()
+	url := fmt.Sprintf("%v/api/store/%v/trash", baseURL, srvrModels.TextCollection)
+
+	t.Run("ok", func(t *testing.T) {
+		httpmock.Reset()
+
+		expected := []srvrModels.UntypedRecord{{
+			UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "deleted text"},
+			RecordID:             id,
+		}}
+		responder, err := httpmock.NewJsonResponder(http.StatusOK, expected)
+		require.NoError(t, err)
+		httpmock.RegisterResponder(http.MethodGet, url, responder)
+
+		records, err := s.Trash(srvrModels.TextCollection, "some-token...")
+		require.NoError(t, err)
+		assert.Equal(t, expected, records)
+	})
+	t.Run("bad", func(t *testing.T) {
+		httpmock.Reset()
+
+		httpmock.RegisterResponder(http.MethodGet, url, httpmock.NewStringResponder(500, "bad"))
+
+		_, err := s.Trash(srvrModels.TextCollection, "some-token...")
+		assert.EqualError(t, err, "bad")
+	})
+}
+
+func TestStorageService_Search(t *testing.T) {
+	baseURL := "https://example.com"
+	s := NewStorageService(baseURL)
+	httpmock.ActivateNonDefault(s.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+	url := fmt.Sprintf("%v/api/search", baseURL)
+
+	t.Run("ok", func(t *testing.T) {
+		httpmock.Reset()
+
+		expected := []srvrModels.SearchResult{{
+			Collection: srvrModels.TextCollection,
+			Record: srvrModels.UntypedRecord{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{
+					Data:     "text",
+					Metadata: srvrModels.Metadata{"note": "github"},
+				},
+				RecordID: models.NewRandomObjectID(),
+			},
+		}}
+		responder, err := httpmock.NewJsonResponder(http.StatusOK, expected)
+		require.NoError(t, err)
+		httpmock.RegisterResponderWithQuery(http.MethodGet, url, "q=git+hub", responder)
+
+		results, err := s.Search("git hub", "some-token...")
+		require.NoError(t, err)
+		assert.Equal(t, expected, results)
+	})
+	t.Run("bad", func(t *testing.T) {
+		httpmock.Reset()
+
+		httpmock.RegisterResponder(http.MethodGet, url, httpmock.NewStringResponder(400, "empty search query"))
+
+		_, err := s.Search("", "some-token...")
+		assert.EqualError(t, err, "empty search query")
+	})
+}
+
+func TestStorageService_Undelete(t *testing.T) {
+	baseURL := "https://example.com"
+	s := NewStorageService(baseURL)
+	httpmock.ActivateNonDefault(s.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+	id := models.NewRandomObjectID()
+	url := fmt.Sprintf("%v/api/store/%v/trash/%v/restore", baseURL, srvrModels.TextCollection, id.Hex())
+
+	t.Run("ok", func(t *testing.T) {
+		httpmock.Reset()
+
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			url,
+			httpmock.NewStringResponder(http.StatusAccepted, "restored"),
+		)
+
+		msg, err := s.Undelete(srvrModels.TextCollection, id, "some-token...")
+		require.NoError(t, err)
+		assert.Equal(t, "restored", msg)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		httpmock.Reset()
+
+		httpmock.RegisterResponder(
+			http.MethodPost,
+			url,
+			httpmock.NewStringResponder(http.StatusNotFound, "record was not found"),
+		)
+
+		_, err := s.Undelete(srvrModels.TextCollection, id, "some-token...")
+		assert.EqualError(t, err, "record was not found")
+	})
+}
+
+func TestStorageService_Purge(t *testing.T) {
+	baseURL := "https://example.com"
+	s := NewStorageService(baseURL)
+	httpmock.ActivateNonDefault(s.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+	id := models.NewRandomObjectID()
+	url := fmt.Sprintf("%v/api/store/%v/trash/%v", baseURL, srvrModels.TextCollection, id.Hex())
+
+	t.Run("ok", func(t *testing.T) {
+		httpmock.Reset()
+
+		httpmock.RegisterResponder(
+			http.MethodDelete,
+			url,
+			httpmock.NewStringResponder(http.StatusOK, "purged"),
+		)
+
+		msg, err := s.Purge(srvrModels.TextCollection, id, "some-token...")
+		require.NoError(t, err)
+		assert.Equal(t, "purged", msg)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		httpmock.Reset()
+
+		httpmock.RegisterResponder(
+			http.MethodDelete,
+			url,
+			httpmock.NewStringResponder(http.StatusNotFound, "record was not found"),
+		)
+
+		_, err := s.Purge(srvrModels.TextCollection, id, "some-token...")
+		assert.EqualError(t, err, "record was not found")
+	})
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-resty/resty/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// grpcSyncService implements the SyncService interface over gRPC.
+type grpcSyncService struct {
+	client  pb.SyncClient
+	storage pb.StorageClient
+}
+
+// NewGRPCSyncService returns a new instance of SyncService which uses the gRPC server addr.
+func NewGRPCSyncService(addr string, opts ...grpc.DialOption) (SyncService, error) {
+	conn, err := newGRPCConn(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcSyncService{
+		client:  pb.NewSyncClient(conn),
+		storage: pb.NewStorageClient(conn),
+	}, nil
+}
+
+// appendRecords converts the messages and appends them to the synced data.
+func appendRecords(
+	r *clientModels.SyncResponse,
+	collectionName srvrModels.CollectionName,
+	records []*pb.Record,
+) error {
+	for _, record := range records {
+		untyped, err := record.ModelRecord(collectionName)
+		if err != nil {
+			return err
+		}
+		if err := r.AppendRecord(collectionName, untyped); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync syncs data from collections.
+func (s *grpcSyncService) Sync(
+	token string,
+	collectionNames []srvrModels.CollectionName,
+) (*clientModels.SyncResponse, error) {
+	r := &clientModels.SyncResponse{}
+	ctx := tokenContext(context.Background(), token)
+	for _, collectionName := range collectionNames {
+		if _, err := srvrModels.NewCollectionName(string(collectionName)); err != nil {
+			return nil, err
+		}
+		resp, err := s.storage.GetAll(ctx, &pb.GetAllRequest{Collection: string(collectionName)})
+		if status.Code(err) == codes.Unauthenticated {
+			return nil, srvErrors.ErrUnauthorized
+		}
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		if err := appendRecords(r, collectionName, resp.GetRecords()); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// SyncRecord retrieves a single record of the collection.
+func (s *grpcSyncService) SyncRecord(
+	token string,
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+) (*clientModels.SyncResponse, error) {
+	resp, err := s.storage.Get(
+		tokenContext(context.Background(), token),
+		&pb.GetRequest{Collection: string(collectionName), RecordId: id.Hex()},
+	)
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Unauthenticated:
+		return nil, srvErrors.ErrUnauthorized
+	case codes.NotFound:
+		return nil, fmt.Errorf("%w: %v", srvErrors.ErrRecordNotFound, id.Hex())
+	default:
+		return nil, grpcError(err)
+	}
+	r := &clientModels.SyncResponse{}
+	if err := appendRecords(r, collectionName, []*pb.Record{resp.GetRecord()}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Changes retrieves the changes of the records of all the collections since
+// the cursor returned by the previous call; the empty cursor retrieves all the records.
+func (s *grpcSyncService) Changes(token string, cursor string) (*srvrModels.Changes, error) {
+	resp, err := s.client.Changes(
+		tokenContext(context.Background(), token),
+		&pb.ChangesRequest{Since: cursor},
+	)
+	if status.Code(err) == codes.Unauthenticated {
+		return nil, srvErrors.ErrUnauthorized
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.ModelChanges()
+}
+
+// Events subscribes to the change events of the user's records.
+// The channel is closed when the stream is finished or the context is canceled.
+func (s *grpcSyncService) Events(
+	ctx context.Context,
+	token string,
+) (<-chan srvrModels.ChangeEvent, error) {
+	stream, err := s.client.Watch(tokenContext(ctx, token), &pb.WatchRequest{})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	events := make(chan srvrModels.ChangeEvent)
+	go func() {
+		defer close(events)
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			// malformed events are skipped
+			event, err := msg.ModelEvent()
+			if err != nil {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// GetClient returns nil since the service does not use the REST API.
+func (s *grpcSyncService) GetClient() *resty.Client {
+	return nil
+}
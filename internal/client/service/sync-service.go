@@ -1,12 +1,18 @@
 package service
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/go-resty/resty/v2"
 
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
 	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
 	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
 	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
@@ -16,8 +22,18 @@ import (
 type SyncService interface {
 	// Sync syncs data from collections.
 	Sync(token string, collections []srvrModels.CollectionName) (*clientModels.SyncResponse, error)
-	Register(token, sockAddr string) (string, error)
-	Unregister(token, sockAddr string) (string, error)
+	// SyncRecord retrieves a single record of the collection.
+	SyncRecord(
+		token string,
+		collectionName srvrModels.CollectionName,
+		id srvrModels.ObjectID,
+	) (*clientModels.SyncResponse, error)
+	// Changes retrieves the changes of the records of all the collections since
+	// the cursor returned by the previous call; the empty cursor retrieves all the records.
+	Changes(token string, cursor string) (*srvrModels.Changes, error)
+	// Events subscribes to the change events of the user's records.
+	// The channel is closed when the stream is finished or the context is canceled.
+	Events(ctx context.Context, token string) (<-chan srvrModels.ChangeEvent, error)
 	// GetClient returns the service's client.
 	GetClient() *resty.Client
 }
@@ -40,24 +56,17 @@ func (s *syncService) Sync(
 ) (*clientModels.SyncResponse, error) {
 	r := &clientModels.SyncResponse{}
 	for _, collectionName := range collectionNames {
-		req := s.client.R().
-			SetHeader("Content-Type", "application/json").
-			SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token))
-		switch collectionName {
-		case srvrModels.TextCollection:
-			req = req.SetResult(&r.Text)
-		case srvrModels.BinaryCollection:
-			req = req.SetResult(&r.Binary)
-		case srvrModels.CardCollection:
-			req = req.SetResult(&r.Card)
-		case srvrModels.CredentialsCollection:
-			req = req.SetResult(&r.Credential)
-		default:
-			return nil, fmt.Errorf("%w: %v", srvErrors.ErrUnknownCollection, collectionName)
+		if _, err := srvrModels.NewCollectionName(string(collectionName)); err != nil {
+			return nil, err
 		}
-		resp, err := req.Get(fmt.Sprintf("/api/store/%v", collectionName))
+		var records []srvrModels.UntypedRecord
+		resp, err := s.client.R().
+			SetHeader("Content-Type", "application/json").
+			SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+			SetResult(&records).
+			Get(fmt.Sprintf("/api/store/%v", collectionName))
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
 		}
 		if resp.StatusCode() == http.StatusUnauthorized {
 			return nil, srvErrors.ErrUnauthorized
@@ -65,40 +74,134 @@ func (s *syncService) Sync(
 		if resp.StatusCode() >= http.StatusBadRequest {
 			return nil, errors.New(resp.String())
 		}
+		for _, record := range records {
+			if err := r.AppendRecord(collectionName, record); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return r, nil
 }
 
-// Register registers a socket address with the synchronization service.
-func (s *syncService) Register(token, sockAddr string) (string, error) {
-	req := s.client.R().
+// SyncRecord retrieves a single record of the collection.
+func (s *syncService) SyncRecord(
+	token string,
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+) (*clientModels.SyncResponse, error) {
+	if _, err := srvrModels.NewCollectionName(string(collectionName)); err != nil {
+		return nil, err
+	}
+	var record srvrModels.UntypedRecord
+	resp, err := s.client.R().
 		SetHeader("Content-Type", "application/json").
 		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
-		SetBody(fmt.Sprintf(`{"socket_addr": "%v"}`, sockAddr))
-	resp, err := req.Post("/api/sync/register")
+		SetResult(&record).
+		Get(fmt.Sprintf("/api/store/%v/%v", collectionName, id.Hex()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	switch {
+	case resp.StatusCode() == http.StatusUnauthorized:
+		return nil, srvErrors.ErrUnauthorized
+	case resp.StatusCode() == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %v", srvErrors.ErrRecordNotFound, id.Hex())
+	case resp.StatusCode() >= http.StatusBadRequest:
+		return nil, errors.New(resp.String())
+	}
+	r := &clientModels.SyncResponse{}
+	if err := r.AppendRecord(collectionName, record); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Changes retrieves the changes of the records of all the collections since
+// the cursor returned by the previous call; the empty cursor retrieves all the records.
+func (s *syncService) Changes(token string, cursor string) (*srvrModels.Changes, error) {
+	var changes srvrModels.Changes
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetQueryParam("since", cursor).
+		SetResult(&changes).
+		Get("/api/sync/changes")
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	if resp.StatusCode() == http.StatusUnauthorized {
+		return nil, srvErrors.ErrUnauthorized
 	}
 	if resp.StatusCode() >= http.StatusBadRequest {
-		return "", errors.New(resp.String())
+		return nil, errors.New(resp.String())
 	}
-	return resp.String(), nil
+	return &changes, nil
 }
 
-// Unregister removes a registered socket address from the synchronization service.
-func (s *syncService) Unregister(token, sockAddr string) (string, error) {
-	req := s.client.R().
-		SetHeader("Content-Type", "application/json").
+// Events subscribes to the server-sent change events of the user's records.
+// The channel is closed when the stream is finished or the context is canceled.
+func (s *syncService) Events(
+	ctx context.Context,
+	token string,
+) (<-chan srvrModels.ChangeEvent, error) {
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetDoNotParseResponse(true).
+		SetHeader("Accept", "text/event-stream").
 		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
-		SetBody(fmt.Sprintf(`{"socket_addr": "%v"}`, sockAddr))
-	resp, err := req.Post("/api/sync/unregister")
+		Get("/api/sync/events")
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	body := resp.RawBody()
+	if resp.StatusCode() == http.StatusUnauthorized {
+		body.Close()
+		return nil, srvErrors.ErrUnauthorized
 	}
 	if resp.StatusCode() >= http.StatusBadRequest {
-		return "", errors.New(resp.String())
+		msg, _ := io.ReadAll(body)
+		body.Close()
+		return nil, errors.New(string(msg))
+	}
+	events := make(chan srvrModels.ChangeEvent)
+	go func() {
+		defer close(events)
+		defer body.Close()
+		readEvents(ctx, body, events)
+	}()
+	return events, nil
+}
+
+// readEvents parses the server-sent events stream and sends the change events to the channel.
+func readEvents(ctx context.Context, r io.Reader, events chan<- srvrModels.ChangeEvent) {
+	scanner := bufio.NewScanner(r)
+	var name, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				name = value
+			case "data":
+				data += value
+			}
+			continue
+		}
+		// an empty line dispatches the event
+		if name == "change" {
+			// malformed events are skipped
+			var event srvrModels.ChangeEvent
+			if err := json.Unmarshal([]byte(data), &event); err == nil {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		name, data = "", ""
 	}
-	return resp.String(), nil
 }
 
 // GetClient returns the service's client.
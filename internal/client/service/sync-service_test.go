@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"testing"
@@ -8,6 +10,7 @@ import (
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
 
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
 	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
 	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
@@ -31,6 +34,7 @@ func TestSyncService_Sync(t *testing.T) {
 			srvrModels.BinaryCollection,
 			srvrModels.CardCollection,
 			srvrModels.CredentialsCollection,
+			srvrModels.OTPCollection,
 		}
 		expectedResult := &clientModels.SyncResponse{
 			Text: []srvrModels.TextRecord{
@@ -61,6 +65,15 @@ func TestSyncService_Sync(t *testing.T) {
 					},
 				},
 			},
+			OTP: []srvrModels.OTPRecord{
+				{
+					RecordID: models.NewRandomObjectID(),
+					Data: srvrModels.OTPInfo{
+						Secret: "JBSWY3DPEHPK3PXP",
+						Issuer: "Example",
+					},
+				},
+			},
 		}
 		responderText, err := httpmock.NewJsonResponder(http.StatusOK, expectedResult.Text)
 		assert.NoError(t, err)
@@ -73,6 +86,8 @@ func TestSyncService_Sync(t *testing.T) {
 			expectedResult.Credential,
 		)
 		assert.NoError(t, err)
+		responderOTP, err := httpmock.NewJsonResponder(http.StatusOK, expectedResult.OTP)
+		assert.NoError(t, err)
 
 		httpmock.RegisterResponder(
 			http.MethodGet,
@@ -94,6 +109,11 @@ func TestSyncService_Sync(t *testing.T) {
 			fmt.Sprintf("%v/api/store/%v", baseURL, srvrModels.CredentialsCollection),
 			responderCredential,
 		)
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			fmt.Sprintf("%v/api/store/%v", baseURL, srvrModels.OTPCollection),
+			responderOTP,
+		)
 
 		// Invoke Sync method
 		actualResult, actualError := s.Sync("good-token", collectionNames)
@@ -149,89 +169,231 @@ func TestSyncService_Sync(t *testing.T) {
 		// Assert results
 		assert.Error(t, actualError)
 	})
+	t.Run("encrypted", func(t *testing.T) {
+		httpmock.Reset()
+		id := models.NewRandomObjectID()
+		responder, err := httpmock.NewJsonResponder(http.StatusOK, []srvrModels.UntypedRecord{{
+			RecordID: id,
+			UntypedRecordContent: srvrModels.UntypedRecordContent{
+				Data: srvrModels.NewEncryptedData([]byte("ciphertext")),
+			},
+		}})
+		assert.NoError(t, err)
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			fmt.Sprintf("%v/api/store/%v", baseURL, srvrModels.CardCollection),
+			responder,
+		)
 
+		actualResult, actualError := s.Sync(
+			"token",
+			[]srvrModels.CollectionName{srvrModels.CardCollection},
+		)
+		assert.NoError(t, actualError)
+		assert.Equal(t, &clientModels.SyncResponse{
+			Encrypted: []clientModels.EncryptedRecord{{
+				Collection: srvrModels.CardCollection,
+				RecordID:   id,
+				Ciphertext: []byte("ciphertext"),
+			}},
+		}, actualResult)
+	})
 }
 
-func TestSyncService_Register(t *testing.T) {
+func TestSyncService_SyncRecord(t *testing.T) {
 	baseURL := "https://example.com"
 	s := NewSyncService(baseURL)
 	client := s.GetClient()
-	assert.Equal(t, baseURL, client.HostURL)
 
 	// Get the underlying HTTP Client and set it to Mock
 	httpmock.ActivateNonDefault(client.GetClient())
 	defer httpmock.DeactivateAndReset()
+	id := models.NewRandomObjectID()
+	url := fmt.Sprintf("%v/api/store/%v/%v", baseURL, srvrModels.CardCollection, id.Hex())
 
 	t.Run("success", func(t *testing.T) {
 		httpmock.Reset()
+		record := srvrModels.CardRecord{
+			RecordID: id,
+			Data: srvrModels.CardInfo{
+				CardNumber:     "1234 1234 1234 1234",
+				CVV:            "234",
+				ExpirationDate: "01/12",
+			},
+			Metadata: srvrModels.Metadata{"bank": "gophers"},
+		}
+		responder, err := httpmock.NewJsonResponder(http.StatusOK, record)
+		assert.NoError(t, err)
+		httpmock.RegisterResponder(http.MethodGet, url, responder)
 
+		r, err := s.SyncRecord("some-token", srvrModels.CardCollection, id)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &clientModels.SyncResponse{Card: []srvrModels.CardRecord{record}}, r)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		httpmock.Reset()
 		httpmock.RegisterResponder(
-			http.MethodPost,
-			fmt.Sprintf("%v/api/sync/register", baseURL),
-			httpmock.NewStringResponder(http.StatusOK, "ok"),
+			http.MethodGet,
+			url,
+			httpmock.NewStringResponder(http.StatusNotFound, "record not found"),
 		)
 
-		r, err := s.Register("some-token", "http://localhost:1234")
+		_, err := s.SyncRecord("some-token", srvrModels.CardCollection, id)
 
-		assert.Equal(t, "ok", r)
-		assert.NoError(t, err)
+		assert.ErrorIs(t, err, srvErrors.ErrRecordNotFound)
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			url,
+			httpmock.NewStringResponder(http.StatusUnauthorized, "Unauthorized"),
+		)
+
+		_, err := s.SyncRecord("bad-token", srvrModels.CardCollection, id)
 
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
 	})
-	t.Run("fail", func(t *testing.T) {
+	t.Run("unknown_collection", func(t *testing.T) {
+		_, err := s.SyncRecord("some-token", "unknown", id)
+
+		assert.ErrorIs(t, err, srvErrors.ErrUnknownCollection)
+	})
+}
+
+func TestSyncService_Changes(t *testing.T) {
+	baseURL := "https://example.com"
+	s := NewSyncService(baseURL)
+
+	// Get the underlying HTTP Client and set it to Mock
+	httpmock.ActivateNonDefault(s.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+	url := fmt.Sprintf("%v/api/sync/changes", baseURL)
+
+	t.Run("success", func(t *testing.T) {
 		httpmock.Reset()
+		expected := &srvrModels.Changes{
+			Upserts: []srvrModels.ChangedRecord{{
+				Collection: srvrModels.TextCollection,
+				Record: srvrModels.UntypedRecord{
+					UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "some text"},
+					RecordID:             models.NewRandomObjectID(),
+				},
+			}},
+			Tombstones: []srvrModels.Tombstone{{
+				Collection: srvrModels.CardCollection,
+				RecordID:   models.NewRandomObjectID(),
+			}},
+			Cursor: "next",
+		}
+		responder, err := httpmock.NewJsonResponder(http.StatusOK, expected)
+		assert.NoError(t, err)
+		httpmock.RegisterResponderWithQuery(http.MethodGet, url, "since=prev", responder)
+
+		changes, err := s.Changes("some-token", "prev")
 
+		assert.NoError(t, err)
+		assert.Equal(t, expected, changes)
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		httpmock.Reset()
 		httpmock.RegisterResponder(
-			http.MethodPost,
-			fmt.Sprintf("%v/api/sync/register", baseURL),
-			httpmock.NewStringResponder(http.StatusBadRequest, "some error"),
+			http.MethodGet,
+			url,
+			httpmock.NewStringResponder(http.StatusUnauthorized, "Unauthorized"),
 		)
 
-		r, err := s.Register("some-token", "http://localhost:1234")
+		_, err := s.Changes("bad-token", "")
+
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
+	})
+	t.Run("bad_cursor", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			url,
+			httpmock.NewStringResponder(http.StatusBadRequest, "bad sync cursor"),
+		)
 
-		assert.Equal(t, "", r)
-		assert.Error(t, err)
+		_, err := s.Changes("some-token", "bad")
 
+		assert.EqualError(t, err, "bad sync cursor")
 	})
 }
 
-func TestSyncService_Unregister(t *testing.T) {
+func TestSyncService_Events(t *testing.T) {
 	baseURL := "https://example.com"
 	s := NewSyncService(baseURL)
 	client := s.GetClient()
-	assert.Equal(t, baseURL, client.HostURL)
 
 	// Get the underlying HTTP Client and set it to Mock
 	httpmock.ActivateNonDefault(client.GetClient())
 	defer httpmock.DeactivateAndReset()
+	url := fmt.Sprintf("%v/api/sync/events", baseURL)
 
 	t.Run("success", func(t *testing.T) {
 		httpmock.Reset()
-
+		id := models.NewRandomObjectID()
+		stream := "event:change\n" +
+			`data:{"collection":"text","record_id":"` + id.Hex() + `","op":"update","version":3}` +
+			"\n\n" +
+			"event:ping\ndata:{}\n\n" +
+			"event: change\ndata: malformed\n\n" +
+			"event: change\n" +
+			`data: {"collection":"cards","record_id":"` + id.Hex() + `","op":"delete","version":4}` +
+			"\n\n"
 		httpmock.RegisterResponder(
-			http.MethodPost,
-			fmt.Sprintf("%v/api/sync/unregister", baseURL),
-			httpmock.NewStringResponder(http.StatusOK, "ok"),
+			http.MethodGet,
+			url,
+			httpmock.NewStringResponder(http.StatusOK, stream),
 		)
 
-		r, err := s.Unregister("some-token", "http://localhost:1234")
-
-		assert.Equal(t, "ok", r)
+		events, err := s.Events(context.Background(), "some-token")
 		assert.NoError(t, err)
+		var got []srvrModels.ChangeEvent
+		for e := range events {
+			got = append(got, e)
+		}
+		assert.Equal(t, []srvrModels.ChangeEvent{
+			{Collection: srvrModels.TextCollection, RecordID: id, Op: srvrModels.OpUpdate, Version: 3},
+			{Collection: srvrModels.CardCollection, RecordID: id, Op: srvrModels.OpDelete, Version: 4},
+		}, got)
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			url,
+			httpmock.NewStringResponder(http.StatusUnauthorized, "Unauthorized"),
+		)
+
+		_, err := s.Events(context.Background(), "bad-token")
 
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
 	})
 	t.Run("fail", func(t *testing.T) {
 		httpmock.Reset()
-
 		httpmock.RegisterResponder(
-			http.MethodPost,
-			fmt.Sprintf("%v/api/sync/unregister", baseURL),
-			httpmock.NewStringResponder(http.StatusBadRequest, "some error"),
+			http.MethodGet,
+			url,
+			httpmock.NewStringResponder(http.StatusInternalServerError, "some error"),
 		)
 
-		r, err := s.Unregister("some-token", "http://localhost:1234")
+		_, err := s.Events(context.Background(), "some-token")
+
+		assert.EqualError(t, err, "some error")
+	})
+	t.Run("unavailable", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			url,
+			httpmock.NewErrorResponder(errors.New("connection refused")),
+		)
 
-		assert.Equal(t, "", r)
-		assert.Error(t, err)
+		_, err := s.Events(context.Background(), "some-token")
 
+		assert.ErrorIs(t, err, clientErr.ErrServerUnavailable)
 	})
 }
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+)
+
+// Supported transports.
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
+
+// ErrUnknownTransport is returned when the transport is not supported.
+var ErrUnknownTransport = errors.New("unknown transport")
+
+// NewAuthServiceWithTransport returns an AuthService which talks to the server
+// over the transport provided.
+func NewAuthServiceWithTransport(transport, addr string) (AuthService, error) {
+	switch transport {
+	case TransportHTTP:
+		return NewAuthService(addr), nil
+	case TransportGRPC:
+		return NewGRPCAuthService(addr)
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownTransport, transport)
+	}
+}
+
+// NewStorageServiceWithTransport returns a StorageService which talks to the server
+// over the transport provided.
+func NewStorageServiceWithTransport(transport, addr string) (StorageService, error) {
+	switch transport {
+	case TransportHTTP:
+		return NewStorageService(addr), nil
+	case TransportGRPC:
+		return NewGRPCStorageService(addr)
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownTransport, transport)
+	}
+}
+
+// NewSyncServiceWithTransport returns a SyncService which talks to the server
+// over the transport provided.
+func NewSyncServiceWithTransport(transport, addr string) (SyncService, error) {
+	switch transport {
+	case TransportHTTP:
+		return NewSyncService(addr), nil
+	case TransportGRPC:
+		return NewGRPCSyncService(addr)
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownTransport, transport)
+	}
+}
+
+// NewVaultServiceWithTransport returns a VaultService which talks to the server
+// over the transport provided.
+func NewVaultServiceWithTransport(transport, addr string) (VaultService, error) {
+	switch transport {
+	case TransportHTTP:
+		return NewVaultService(addr), nil
+	case TransportGRPC:
+		return NewGRPCVaultService(addr)
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownTransport, transport)
+	}
+}
+
+// NewBlobServiceWithTransport returns a BlobService which talks to the server
+// over the transport provided.
+func NewBlobServiceWithTransport(transport, addr string) (BlobService, error) {
+	switch transport {
+	case TransportHTTP:
+		return NewBlobService(addr), nil
+	case TransportGRPC:
+		return NewGRPCBlobService(addr)
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownTransport, transport)
+	}
+}
+
+// newGRPCConn returns a connection to the gRPC server. The address may contain
+// a scheme: https enables TLS (as for the REST client, the certificate is not verified).
+func newGRPCConn(addr string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if strings.HasPrefix(addr, "https://") {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})
+	}
+	addr = strings.TrimPrefix(strings.TrimPrefix(addr, "https://"), "http://")
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, opts...)
+	return grpc.Dial(addr, opts...)
+}
+
+// tokenContext returns a context with the authorization metadata.
+func tokenContext(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", fmt.Sprintf("Bearer: %v", token))
+}
+
+// grpcError converts a gRPC status into an error with the server message.
+func grpcError(err error) error {
+	s := status.Convert(err)
+	if s.Code() == codes.Unavailable {
+		return fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, s.Message())
+	}
+	return errors.New(s.Message())
+}
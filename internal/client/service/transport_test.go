@@ -0,0 +1,735 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	clientModels "github.com/blokhinnv/gophkeeper/internal/client/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	"github.com/blokhinnv/gophkeeper/internal/server/config"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/rpc"
+	srvService "github.com/blokhinnv/gophkeeper/internal/server/service"
+	"github.com/blokhinnv/gophkeeper/internal/server/service/mock"
+)
+
+const signingKey = "secret"
+
+// startGRPCServer runs the gRPC server on an in-memory connection
+// and returns the dial option to connect to it. No token is revoked.
+func startGRPCServer(
+	t *testing.T,
+	authService srvService.AuthService,
+	storageService srvService.StorageService,
+	syncService srvService.SyncService,
+	vaultService srvService.VaultService,
+) grpc.DialOption {
+	return startGRPCServerWithSessions(
+		t,
+		authService,
+		nil,
+		storageService,
+		syncService,
+		vaultService,
+		nil,
+		nil,
+	)
+}
+
+// startGRPCServerWithSessions is startGRPCServer with the session, blob and device services provided.
+func startGRPCServerWithSessions(
+	t *testing.T,
+	authService srvService.AuthService,
+	sessionService srvService.SessionService,
+	storageService srvService.StorageService,
+	syncService srvService.SyncService,
+	vaultService srvService.VaultService,
+	blobService srvService.BlobService,
+	deviceService srvService.DeviceService,
+) grpc.DialOption {
+	if sessionService == nil {
+		sessions := mock.NewMockSessionService(gomock.NewController(t))
+		sessions.EXPECT().IsRevoked(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+		sessionService = sessions
+	}
+	srv, err := rpc.NewServer(
+		&config.ServerConfig{},
+		auth.NewHMACKeySet([]byte(signingKey)),
+		authService,
+		sessionService,
+		storageService,
+		syncService,
+		vaultService,
+		blobService,
+		mock.NewMockMFAService(gomock.NewController(t)),
+		deviceService,
+	)
+	require.NoError(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+	return grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	})
+}
+
+// newToken returns a valid token of the user.
+func newToken(t *testing.T, username string) string {
+	tok, err := auth.GenerateJWTToken(username, []byte(signingKey), time.Hour)
+	require.NoError(t, err)
+	return tok
+}
+
+func TestNewServiceWithTransport(t *testing.T) {
+	for _, transport := range []string{TransportHTTP, TransportGRPC} {
+		a, err := NewAuthServiceWithTransport(transport, "http://localhost:8080")
+		require.NoError(t, err)
+		assert.NotNil(t, a)
+		s, err := NewStorageServiceWithTransport(transport, "http://localhost:8080")
+		require.NoError(t, err)
+		assert.NotNil(t, s)
+		sync, err := NewSyncServiceWithTransport(transport, "https://localhost:8080")
+		require.NoError(t, err)
+		assert.NotNil(t, sync)
+		v, err := NewVaultServiceWithTransport(transport, "http://localhost:8080")
+		require.NoError(t, err)
+		assert.NotNil(t, v)
+		b, err := NewBlobServiceWithTransport(transport, "http://localhost:8080")
+		require.NoError(t, err)
+		assert.NotNil(t, b)
+	}
+	_, err := NewAuthServiceWithTransport("carrier-pigeon", "localhost:8080")
+	assert.ErrorIs(t, err, ErrUnknownTransport)
+	_, err = NewStorageServiceWithTransport("carrier-pigeon", "localhost:8080")
+	assert.ErrorIs(t, err, ErrUnknownTransport)
+	_, err = NewSyncServiceWithTransport("carrier-pigeon", "localhost:8080")
+	assert.ErrorIs(t, err, ErrUnknownTransport)
+	_, err = NewVaultServiceWithTransport("carrier-pigeon", "localhost:8080")
+	assert.ErrorIs(t, err, ErrUnknownTransport)
+	_, err = NewBlobServiceWithTransport("carrier-pigeon", "localhost:8080")
+	assert.ErrorIs(t, err, ErrUnknownTransport)
+}
+
+func TestGRPCAuthService(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	authService := mock.NewMockAuthService(mockCtrl)
+	deviceService := mock.NewMockDeviceService(mockCtrl)
+	s, err := NewGRPCAuthService(
+		"bufnet",
+		startGRPCServerWithSessions(t, authService, nil, nil, nil, nil, nil, deviceService),
+	)
+	require.NoError(t, err)
+	assert.Nil(t, s.GetClient())
+
+	authService.EXPECT().Register("user", "pwd").Return(nil)
+	assert.NoError(t, s.Register("user", "pwd"))
+
+	authService.EXPECT().Register("user", "pwd").Return(srvErrors.ErrUsernameIsTaken)
+	assert.EqualError(t, s.Register("user", "pwd"), srvErrors.ErrUsernameIsTaken.Error())
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	authService.EXPECT().
+		Login("user", "pwd", gomock.Any(), gomock.Nil()).
+		Return(&srvrModels.TokenPair{AccessToken: "token", RefreshToken: "refresh", ExpiresAt: expiresAt}, nil)
+	tokens, err := s.Auth("user", "pwd", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "token", tokens.AccessToken)
+	assert.Equal(t, "refresh", tokens.RefreshToken)
+	assert.Equal(t, expiresAt, tokens.ExpiresAt)
+
+	device := newTestDevice()
+	info := device.Info
+	info.Proof = device.Prove("nonce")
+	deviceService.EXPECT().Nonce().Return(&srvrModels.DeviceNonce{Nonce: "nonce"}, nil).Times(2)
+	authService.EXPECT().
+		Login("user", "pwd", gomock.Any(), &info).
+		Return(&srvrModels.TokenPair{AccessToken: "token", DeviceID: "device"}, nil)
+	tokens, err = s.Auth("user", "pwd", device)
+	require.NoError(t, err)
+	assert.Equal(t, "device", tokens.DeviceID)
+
+	authService.EXPECT().
+		LoginMFA("challenge", "123456", gomock.Any(), &info).
+		Return(&srvrModels.TokenPair{AccessToken: "token", DeviceID: "device"}, nil)
+	tokens, err = s.AuthMFA("challenge", "123456", device)
+	require.NoError(t, err)
+	assert.Equal(t, "device", tokens.DeviceID)
+
+	authService.EXPECT().Login("user", "pwd", gomock.Any(), gomock.Nil()).Return(nil, srvErrors.ErrUnauthorized)
+	_, err = s.Auth("user", "pwd", nil)
+	assert.Error(t, err)
+
+	token := newToken(t, "user")
+	authService.EXPECT().
+		ChangePassword("user", "old", "new", gomock.Any(), "").
+		Return(&srvrModels.TokenPair{AccessToken: "new-token", RefreshToken: "new-refresh"}, nil)
+	tokens, err = s.ChangePassword(token, "old", "new")
+	require.NoError(t, err)
+	assert.Equal(t, "new-token", tokens.AccessToken)
+
+	authService.EXPECT().
+		ChangePassword("user", "wrong", "new", gomock.Any(), "").
+		Return(nil, srvErrors.ErrBadCredentials)
+	_, err = s.ChangePassword(token, "wrong", "new")
+	assert.ErrorIs(t, err, srvErrors.ErrBadCredentials)
+
+	authService.EXPECT().DeleteAccount("user", "pwd", gomock.Any()).Return(nil)
+	assert.NoError(t, s.DeleteAccount(token, "pwd"))
+	assert.ErrorIs(t, s.DeleteAccount("bad", "pwd"), srvErrors.ErrUnauthorized)
+}
+
+func TestGRPCAuthService_Sessions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	sessionService := mock.NewMockSessionService(mockCtrl)
+	sessionService.EXPECT().IsRevoked(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	deviceService := mock.NewMockDeviceService(mockCtrl)
+	s, err := NewGRPCAuthService(
+		"bufnet",
+		startGRPCServerWithSessions(t, nil, sessionService, nil, nil, nil, nil, deviceService),
+	)
+	require.NoError(t, err)
+	token := newToken(t, "user")
+	id := srvrModels.NewRandomObjectID()
+
+	t.Run("refresh", func(t *testing.T) {
+		sessionService.EXPECT().
+			Refresh(gomock.Any(), "refresh", gomock.Nil()).
+			Return(&srvrModels.TokenPair{AccessToken: "token", RefreshToken: "new"}, nil)
+		tokens, err := s.Refresh("refresh", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "new", tokens.RefreshToken)
+	})
+	t.Run("refresh_device", func(t *testing.T) {
+		device := newTestDevice()
+		deviceService.EXPECT().Nonce().Return(&srvrModels.DeviceNonce{Nonce: "nonce"}, nil)
+		sessionService.EXPECT().
+			Refresh(gomock.Any(), "refresh", device.Prove("nonce")).
+			Return(&srvrModels.TokenPair{AccessToken: "token", RefreshToken: "new"}, nil)
+		tokens, err := s.Refresh("refresh", device)
+		require.NoError(t, err)
+		assert.Equal(t, "new", tokens.RefreshToken)
+	})
+	t.Run("refresh_bad_proof", func(t *testing.T) {
+		deviceService.EXPECT().Nonce().Return(&srvrModels.DeviceNonce{Nonce: "nonce"}, nil)
+		sessionService.EXPECT().
+			Refresh(gomock.Any(), "refresh", gomock.Any()).
+			Return(nil, srvErrors.ErrBadDeviceProof)
+		_, err := s.Refresh("refresh", newTestDevice())
+		assert.ErrorIs(t, err, srvErrors.ErrBadDeviceProof)
+	})
+	t.Run("refresh_bad_token", func(t *testing.T) {
+		sessionService.EXPECT().
+			Refresh(gomock.Any(), "refresh", gomock.Nil()).
+			Return(nil, srvErrors.ErrBadRefreshToken)
+		_, err := s.Refresh("refresh", nil)
+		assert.ErrorIs(t, err, srvErrors.ErrBadRefreshToken)
+	})
+	t.Run("sessions", func(t *testing.T) {
+		sessionService.EXPECT().
+			List(gomock.Any(), "user").
+			Return([]srvrModels.Session{{ID: id}}, nil)
+		sessions, err := s.Sessions(token)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, id, sessions[0].ID)
+	})
+	t.Run("sessions_unauthorized", func(t *testing.T) {
+		_, err := s.Sessions("bad")
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
+	})
+	t.Run("revoke", func(t *testing.T) {
+		sessionService.EXPECT().Revoke(gomock.Any(), "user", id).Return(nil)
+		assert.NoError(t, s.RevokeSession(token, id.Hex()))
+	})
+	t.Run("revoke_not_found", func(t *testing.T) {
+		sessionService.EXPECT().
+			Revoke(gomock.Any(), "user", id).
+			Return(srvErrors.ErrSessionNotFound)
+		assert.ErrorIs(t, s.RevokeSession(token, id.Hex()), srvErrors.ErrSessionNotFound)
+	})
+	t.Run("logout", func(t *testing.T) {
+		sessionService.EXPECT().Logout(gomock.Any(), gomock.Any()).Return(nil)
+		assert.NoError(t, s.Logout(token))
+	})
+}
+
+func TestGRPCAuthService_Devices(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	deviceService := mock.NewMockDeviceService(mockCtrl)
+	s, err := NewGRPCAuthService(
+		"bufnet",
+		startGRPCServerWithSessions(t, nil, nil, nil, nil, nil, nil, deviceService),
+	)
+	require.NoError(t, err)
+	token := newToken(t, "user")
+	id := srvrModels.NewRandomObjectID()
+
+	t.Run("list", func(t *testing.T) {
+		deviceService.EXPECT().
+			List(gomock.Any(), "user").
+			Return([]srvrModels.Device{{ID: id, DeviceInfo: srvrModels.DeviceInfo{Name: "laptop"}}}, nil)
+		devices, err := s.Devices(token)
+		require.NoError(t, err)
+		require.Len(t, devices, 1)
+		assert.Equal(t, id, devices[0].ID)
+		assert.Equal(t, "laptop", devices[0].Name)
+	})
+	t.Run("list_unauthorized", func(t *testing.T) {
+		_, err := s.Devices("bad")
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
+	})
+	t.Run("delete", func(t *testing.T) {
+		deviceService.EXPECT().Delete(gomock.Any(), "user", id).Return(nil)
+		assert.NoError(t, s.DeleteDevice(token, id.Hex()))
+	})
+	t.Run("delete_not_found", func(t *testing.T) {
+		deviceService.EXPECT().Delete(gomock.Any(), "user", id).Return(srvErrors.ErrDeviceNotFound)
+		assert.ErrorIs(t, s.DeleteDevice(token, id.Hex()), srvErrors.ErrDeviceNotFound)
+	})
+}
+
+func TestGRPCAuthService_Unavailable(t *testing.T) {
+	s, err := NewGRPCAuthService("localhost:1")
+	require.NoError(t, err)
+	_, err = s.Auth("user", "pwd", nil)
+	assert.ErrorIs(t, err, clientErr.ErrServerUnavailable)
+}
+
+func TestGRPCStorageService(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	storageService := mock.NewMockStorageService(mockCtrl)
+	syncService := mock.NewMockSyncService(mockCtrl)
+	syncService.EXPECT().Publish(gomock.Any(), gomock.Any()).AnyTimes()
+	s, err := NewGRPCStorageService("bufnet", startGRPCServer(t, nil, storageService, syncService, nil))
+	require.NoError(t, err)
+	assert.Nil(t, s.GetClient())
+	token := newToken(t, "user")
+	id := srvrModels.NewRandomObjectID()
+
+	t.Run("add", func(t *testing.T) {
+		storageService.EXPECT().
+			Store(gomock.Any(), srvrModels.CredentialsCollection, srvrModels.UntypedRecord{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{
+					Data:     map[string]any{"Login": "login", "Password": "pwd"},
+					Metadata: srvrModels.Metadata{"site": "example.com"},
+				},
+				Username: "user",
+			}).
+			Return(id.Hex(), nil)
+		msg, err := s.Add(
+			`{"record_id":"000000000000000000000000","Data":{"Login":"login","Password":"pwd"},"Metadata":{"site":"example.com"}}`,
+			srvrModels.CredentialsCollection,
+			token,
+		)
+		require.NoError(t, err)
+		assert.Contains(t, msg, id.Hex())
+	})
+	t.Run("add_bad_body", func(t *testing.T) {
+		_, err := s.Add(`{`, srvrModels.TextCollection, token)
+		assert.Error(t, err)
+	})
+	t.Run("search", func(t *testing.T) {
+		storageService.EXPECT().
+			Search(gomock.Any(), "user", "github").
+			Return([]srvrModels.SearchResult{{
+				Collection: srvrModels.CredentialsCollection,
+				Record: srvrModels.UntypedRecord{
+					UntypedRecordContent: srvrModels.UntypedRecordContent{
+						Data: map[string]any{"Login": "john@github.com", "Password": "pwd"},
+					},
+					RecordID: id,
+				},
+			}}, nil)
+		results, err := s.Search("github", token)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, srvrModels.CredentialsCollection, results[0].Collection)
+		assert.Equal(t, id, results[0].Record.RecordID)
+		assert.Equal(
+			t,
+			srvrModels.CredentialInfo{Login: "john@github.com", Password: "pwd"},
+			results[0].Record.Data,
+		)
+	})
+	t.Run("search_empty_query", func(t *testing.T) {
+		storageService.EXPECT().
+			Search(gomock.Any(), "user", "").
+			Return(nil, srvErrors.ErrEmptySearchQuery)
+		_, err := s.Search("", token)
+		assert.Error(t, err)
+	})
+	t.Run("update", func(t *testing.T) {
+		storageService.EXPECT().
+			Update(
+				gomock.Any(),
+				srvrModels.TextCollection,
+				"user",
+				id,
+				"text",
+				srvrModels.Metadata{"k": "v"},
+				int64(0),
+			).
+			Return(&srvrModels.UntypedRecord{RecordID: id, Version: 2}, nil)
+		msg, err := s.Update(
+			fmt.Sprintf(`{"record_id":"%v","Data":"text","Metadata":{"k":"v"}}`, id.Hex()),
+			srvrModels.TextCollection,
+			token,
+		)
+		require.NoError(t, err)
+		assert.Contains(t, msg, "version=2")
+	})
+	t.Run("update_conflict", func(t *testing.T) {
+		storageService.EXPECT().
+			Update(gomock.Any(), srvrModels.TextCollection, "user", id, "mine", gomock.Any(), int64(1)).
+			Return(&srvrModels.UntypedRecord{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "theirs"},
+				RecordID:             id,
+				Version:              2,
+			}, srvErrors.ErrVersionConflict)
+		_, err := s.Update(
+			fmt.Sprintf(`{"record_id":"%v","Data":"mine","expected_version":1}`, id.Hex()),
+			srvrModels.TextCollection,
+			token,
+		)
+		var conflict *clientErr.ConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, int64(2), conflict.Current.Version)
+		assert.Equal(t, "theirs", conflict.Current.Data)
+	})
+	t.Run("delete", func(t *testing.T) {
+		storageService.EXPECT().
+			Delete(gomock.Any(), srvrModels.TextCollection, "user", id).
+			Return(nil)
+		msg, err := s.Delete(fmt.Sprintf(`{"record_id": "%v"}`, id.Hex()), srvrModels.TextCollection, token)
+		require.NoError(t, err)
+		assert.Contains(t, msg, id.Hex())
+	})
+	t.Run("delete_unauthorized", func(t *testing.T) {
+		_, err := s.Delete(fmt.Sprintf(`{"record_id": "%v"}`, id.Hex()), srvrModels.TextCollection, "bad")
+		assert.Error(t, err)
+	})
+	t.Run("history", func(t *testing.T) {
+		storageService.EXPECT().
+			History(gomock.Any(), srvrModels.TextCollection, "user", id).
+			Return([]srvrModels.Revision{{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "old text"},
+				RecordID:             id,
+				Rev:                  1,
+				Op:                   srvrModels.OpDelete,
+			}}, nil)
+		revisions, err := s.History(srvrModels.TextCollection, id, token)
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, "old text", revisions[0].Data)
+		assert.Equal(t, int64(1), revisions[0].Rev)
+		assert.Equal(t, srvrModels.OpDelete, revisions[0].Op)
+	})
+	t.Run("restore", func(t *testing.T) {
+		storageService.EXPECT().
+			Restore(gomock.Any(), srvrModels.TextCollection, "user", id, int64(1)).
+			Return(&srvrModels.UntypedRecord{RecordID: id, Version: 3}, nil)
+		msg, err := s.Restore(srvrModels.TextCollection, id, 1, token)
+		require.NoError(t, err)
+		assert.Contains(t, msg, "version=3")
+	})
+	t.Run("restore_not_found", func(t *testing.T) {
+		storageService.EXPECT().
+			Restore(gomock.Any(), srvrModels.TextCollection, "user", id, int64(7)).
+			Return(nil, srvErrors.ErrRevisionNotFound)
+		_, err := s.Restore(srvrModels.TextCollection, id, 7, token)
+		assert.Error(t, err)
+	})
+	t.Run("trash", func(t *testing.T) {
+		deletedAt := time.Now().UTC().Truncate(time.Second)
+		storageService.EXPECT().
+			Trash(gomock.Any(), srvrModels.TextCollection, "user").
+			Return([]srvrModels.UntypedRecord{{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "deleted text"},
+				RecordID:             id,
+				DeletedAt:            &deletedAt,
+			}}, nil)
+		records, err := s.Trash(srvrModels.TextCollection, token)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "deleted text", records[0].Data)
+		require.NotNil(t, records[0].DeletedAt)
+		assert.True(t, deletedAt.Equal(*records[0].DeletedAt))
+	})
+	t.Run("undelete", func(t *testing.T) {
+		storageService.EXPECT().
+			Undelete(gomock.Any(), srvrModels.TextCollection, "user", id).
+			Return(&srvrModels.UntypedRecord{RecordID: id, Version: 4}, nil)
+		msg, err := s.Undelete(srvrModels.TextCollection, id, token)
+		require.NoError(t, err)
+		assert.Contains(t, msg, "version=4")
+	})
+	t.Run("purge_not_found", func(t *testing.T) {
+		storageService.EXPECT().
+			Purge(gomock.Any(), srvrModels.TextCollection, "user", id).
+			Return(srvErrors.ErrRecordNotFound)
+		_, err := s.Purge(srvrModels.TextCollection, id, token)
+		assert.Error(t, err)
+	})
+}
+
+func TestGRPCSyncService(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	storageService := mock.NewMockStorageService(mockCtrl)
+	syncService := mock.NewMockSyncService(mockCtrl)
+	s, err := NewGRPCSyncService("bufnet", startGRPCServer(t, nil, storageService, syncService, nil))
+	require.NoError(t, err)
+	assert.Nil(t, s.GetClient())
+	token := newToken(t, "user")
+	id := srvrModels.NewRandomObjectID()
+
+	t.Run("sync", func(t *testing.T) {
+		storageService.EXPECT().
+			GetAll(gomock.Any(), srvrModels.TextCollection, "user", srvrModels.GetAllOptions{}).
+			Return([]srvrModels.UntypedRecord{{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "some text"},
+				RecordID:             id,
+			}}, "", nil)
+		storageService.EXPECT().
+			GetAll(gomock.Any(), srvrModels.CardCollection, "user", srvrModels.GetAllOptions{}).
+			Return([]srvrModels.UntypedRecord{{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{
+					Data: map[string]any{
+						"CardNumber":     "4111111111111111",
+						"CVV":            "123",
+						"ExpirationDate": "12/30",
+					},
+					Metadata: srvrModels.Metadata{"bank": "gophers"},
+				},
+				RecordID: id,
+			}}, "", nil)
+		resp, err := s.Sync(
+			token,
+			[]srvrModels.CollectionName{srvrModels.TextCollection, srvrModels.CardCollection},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []srvrModels.TextRecord{{RecordID: id, Data: "some text"}}, resp.Text)
+		assert.Equal(t, []srvrModels.CardRecord{{
+			RecordID: id,
+			Data: srvrModels.CardInfo{
+				CardNumber:     "4111111111111111",
+				CVV:            "123",
+				ExpirationDate: "12/30",
+			},
+			Metadata: srvrModels.Metadata{"bank": "gophers"},
+		}}, resp.Card)
+	})
+	t.Run("sync_encrypted", func(t *testing.T) {
+		storageService.EXPECT().
+			GetAll(gomock.Any(), srvrModels.CardCollection, "user", srvrModels.GetAllOptions{}).
+			Return([]srvrModels.UntypedRecord{{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{
+					Data: srvrModels.NewEncryptedData([]byte("ciphertext")),
+				},
+				RecordID: id,
+			}}, "", nil)
+		resp, err := s.Sync(token, []srvrModels.CollectionName{srvrModels.CardCollection})
+		require.NoError(t, err)
+		assert.Empty(t, resp.Card)
+		assert.Equal(t, []clientModels.EncryptedRecord{{
+			Collection: srvrModels.CardCollection,
+			RecordID:   id,
+			Ciphertext: []byte("ciphertext"),
+		}}, resp.Encrypted)
+	})
+	t.Run("sync_unauthorized", func(t *testing.T) {
+		_, err := s.Sync("bad", []srvrModels.CollectionName{srvrModels.TextCollection})
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
+	})
+	t.Run("sync_unknown_collection", func(t *testing.T) {
+		_, err := s.Sync(token, []srvrModels.CollectionName{"unknown"})
+		assert.ErrorIs(t, err, srvErrors.ErrUnknownCollection)
+	})
+	t.Run("sync_record", func(t *testing.T) {
+		storageService.EXPECT().
+			Get(gomock.Any(), srvrModels.TextCollection, "user", id).
+			Return(&srvrModels.UntypedRecord{
+				UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "some text"},
+				RecordID:             id,
+			}, nil)
+		resp, err := s.SyncRecord(token, srvrModels.TextCollection, id)
+		require.NoError(t, err)
+		assert.Equal(t, []srvrModels.TextRecord{{RecordID: id, Data: "some text"}}, resp.Text)
+	})
+	t.Run("sync_record_not_found", func(t *testing.T) {
+		storageService.EXPECT().
+			Get(gomock.Any(), srvrModels.TextCollection, "user", id).
+			Return(nil, srvErrors.ErrRecordNotFound)
+		_, err := s.SyncRecord(token, srvrModels.TextCollection, id)
+		assert.ErrorIs(t, err, srvErrors.ErrRecordNotFound)
+	})
+	t.Run("changes", func(t *testing.T) {
+		storageService.EXPECT().
+			Changes(gomock.Any(), "user", time.Time{}).
+			Return(&srvrModels.Changes{
+				Upserts: []srvrModels.ChangedRecord{{
+					Collection: srvrModels.TextCollection,
+					Record: srvrModels.UntypedRecord{
+						UntypedRecordContent: srvrModels.UntypedRecordContent{Data: "some text"},
+						RecordID:             id,
+					},
+				}},
+				Reset: true,
+			}, nil)
+		changes, err := s.Changes(token, "")
+		require.NoError(t, err)
+		assert.True(t, changes.Reset)
+		assert.NotEmpty(t, changes.Cursor)
+		require.Len(t, changes.Upserts, 1)
+		assert.Equal(t, "some text", changes.Upserts[0].Record.Data)
+	})
+	t.Run("changes_unauthorized", func(t *testing.T) {
+		_, err := s.Changes("bad", "")
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
+	})
+	t.Run("events", func(t *testing.T) {
+		events := make(chan srvrModels.ChangeEvent, 1)
+		syncService.EXPECT().
+			Subscribe("user", "", "", gomock.Any()).
+			Return((<-chan srvrModels.ChangeEvent)(events), func() {})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		got, err := s.Events(ctx, token)
+		require.NoError(t, err)
+
+		event := srvrModels.ChangeEvent{
+			Collection: srvrModels.TextCollection,
+			RecordID:   id,
+			Op:         srvrModels.OpDelete,
+			Version:    7,
+		}
+		events <- event
+		assert.Equal(t, event, <-got)
+		close(events)
+		_, ok := <-got
+		assert.False(t, ok, "the channel should be closed with the stream")
+	})
+}
+
+func TestGRPCVaultService(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	vaultService := mock.NewMockVaultService(mockCtrl)
+	s, err := NewGRPCVaultService("bufnet", startGRPCServer(t, nil, nil, nil, vaultService))
+	require.NoError(t, err)
+	assert.Nil(t, s.GetClient())
+	token := newToken(t, "user")
+	params := srvrModels.VaultParams{
+		KDF:      srvrModels.KDFArgon2id,
+		Salt:     []byte("0123456789abcdef"),
+		Time:     3,
+		Memory:   64 * 1024,
+		Threads:  4,
+		KeyCheck: []byte("key check"),
+	}
+
+	t.Run("get", func(t *testing.T) {
+		vaultService.EXPECT().Get(gomock.Any(), "user").Return(&params, nil)
+		resp, err := s.Get(token)
+		require.NoError(t, err)
+		assert.Equal(t, &params, resp)
+	})
+	t.Run("get_not_found", func(t *testing.T) {
+		vaultService.EXPECT().Get(gomock.Any(), "user").Return(nil, srvErrors.ErrVaultNotFound)
+		_, err := s.Get(token)
+		assert.ErrorIs(t, err, srvErrors.ErrVaultNotFound)
+	})
+	t.Run("get_unauthorized", func(t *testing.T) {
+		_, err := s.Get("bad")
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
+	})
+	t.Run("set", func(t *testing.T) {
+		vaultService.EXPECT().Set(gomock.Any(), "user", params).Return(nil)
+		assert.NoError(t, s.Set(token, params))
+	})
+	t.Run("set_exists", func(t *testing.T) {
+		vaultService.EXPECT().Set(gomock.Any(), "user", params).Return(srvErrors.ErrVaultExists)
+		assert.ErrorIs(t, s.Set(token, params), srvErrors.ErrVaultExists)
+	})
+}
+
+func TestGRPCBlobService(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	blobService := mock.NewMockBlobService(mockCtrl)
+	s, err := NewGRPCBlobService(
+		"bufnet",
+		startGRPCServerWithSessions(t, nil, nil, nil, nil, nil, blobService, nil),
+	)
+	require.NoError(t, err)
+	assert.Nil(t, s.GetClient())
+	assert.Equal(t, srvrModels.BlobChunkSize, s.ChunkSize())
+	token := newToken(t, "user")
+	id := srvrModels.NewRandomObjectID()
+
+	t.Run("create", func(t *testing.T) {
+		blobService.EXPECT().
+			Create(gomock.Any(), "user", int64(9)).
+			Return(&srvrModels.Blob{BlobID: id, Size: 9}, nil)
+		blob, err := s.Create(9, token)
+		require.NoError(t, err)
+		assert.Equal(t, id, blob.BlobID)
+	})
+	t.Run("get_not_found", func(t *testing.T) {
+		blobService.EXPECT().Get(gomock.Any(), "user", id).Return(nil, srvErrors.ErrBlobNotFound)
+		_, err := s.Get(id, token)
+		assert.ErrorIs(t, err, srvErrors.ErrBlobNotFound)
+	})
+	t.Run("append", func(t *testing.T) {
+		blobService.EXPECT().
+			Append(gomock.Any(), "user", id, int64(4), []byte("o, go")).
+			Return(&srvrModels.Blob{BlobID: id, Size: 9, Received: 9, Chunks: 2}, nil)
+		blob, err := s.Append(id, 4, []byte("o, go"), token)
+		require.NoError(t, err)
+		assert.True(t, blob.Complete())
+	})
+	t.Run("append_conflict", func(t *testing.T) {
+		blobService.EXPECT().
+			Append(gomock.Any(), "user", id, int64(0), []byte("hell")).
+			Return(&srvrModels.Blob{BlobID: id, Size: 9, Received: 4}, srvErrors.ErrBadChunkOffset)
+		blob, err := s.Append(id, 0, []byte("hell"), token)
+		assert.ErrorIs(t, err, srvErrors.ErrBadChunkOffset)
+		require.NotNil(t, blob)
+		assert.Equal(t, int64(4), blob.Received)
+	})
+	t.Run("download", func(t *testing.T) {
+		blobService.EXPECT().
+			Read(gomock.Any(), "user", id, gomock.Any()).
+			DoAndReturn(func(_ any, _ string, _ srvrModels.ObjectID, w io.Writer) error {
+				if _, err := w.Write([]byte("hello")); err != nil {
+					return err
+				}
+				_, err := w.Write([]byte(", go"))
+				return err
+			})
+		var buf bytes.Buffer
+		require.NoError(t, s.Download(id, &buf, token))
+		assert.Equal(t, "hello, go", buf.String())
+	})
+	t.Run("download_incomplete", func(t *testing.T) {
+		blobService.EXPECT().
+			Read(gomock.Any(), "user", id, gomock.Any()).
+			Return(srvErrors.ErrBlobIncomplete)
+		err := s.Download(id, &bytes.Buffer{}, token)
+		assert.Error(t, err)
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		_, err := s.Create(9, "bad")
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
+	})
+}
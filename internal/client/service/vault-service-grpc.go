@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+
+	"github.com/go-resty/resty/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// grpcVaultService implements the VaultService interface over gRPC.
+type grpcVaultService struct {
+	client pb.VaultClient
+}
+
+// NewGRPCVaultService returns a new instance of VaultService which uses the gRPC server addr.
+func NewGRPCVaultService(addr string, opts ...grpc.DialOption) (VaultService, error) {
+	conn, err := newGRPCConn(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcVaultService{client: pb.NewVaultClient(conn)}, nil
+}
+
+// Get returns the vault parameters of the user.
+func (s *grpcVaultService) Get(token string) (*srvrModels.VaultParams, error) {
+	resp, err := s.client.Get(tokenContext(context.Background(), token), &pb.GetVaultRequest{})
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Unauthenticated:
+		return nil, srvErrors.ErrUnauthorized
+	case codes.NotFound:
+		return nil, srvErrors.ErrVaultNotFound
+	default:
+		return nil, grpcError(err)
+	}
+	params, err := resp.ModelParams()
+	if err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
+
+// Set saves the vault parameters of the user.
+func (s *grpcVaultService) Set(token string, params srvrModels.VaultParams) error {
+	_, err := s.client.Set(tokenContext(context.Background(), token), pb.NewVaultParams(params))
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.Unauthenticated:
+		return srvErrors.ErrUnauthorized
+	case codes.AlreadyExists:
+		return srvErrors.ErrVaultExists
+	default:
+		return grpcError(err)
+	}
+}
+
+// GetClient returns nil since the service does not use the REST API.
+func (s *grpcVaultService) GetClient() *resty.Client {
+	return nil
+}
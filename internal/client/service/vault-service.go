@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// VaultService defines the interface for managing the parameters
+// of the end-to-end encryption kept on the server.
+type VaultService interface {
+	// Get returns the vault parameters of the user.
+	Get(token string) (*srvrModels.VaultParams, error)
+	// Set saves the vault parameters of the user.
+	Set(token string, params srvrModels.VaultParams) error
+	// GetClient returns the service's client.
+	GetClient() *resty.Client
+}
+
+// vaultService is an implementation of the VaultService interface.
+type vaultService struct {
+	client *resty.Client
+}
+
+// NewVaultService returns a new instance of VaultService.
+func NewVaultService(baseURL string) VaultService {
+	client := newConfiguredClient(baseURL)
+	return &vaultService{client: client}
+}
+
+// Get returns the vault parameters of the user.
+// Returns ErrVaultNotFound if the user has not enabled the end-to-end encryption yet.
+func (s *vaultService) Get(token string) (*srvrModels.VaultParams, error) {
+	params := &srvrModels.VaultParams{}
+	resp, err := s.client.R().
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetResult(params).
+		Get("/api/vault")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	switch {
+	case resp.StatusCode() == http.StatusUnauthorized:
+		return nil, srvErrors.ErrUnauthorized
+	case resp.StatusCode() == http.StatusNotFound:
+		return nil, srvErrors.ErrVaultNotFound
+	case resp.StatusCode() >= http.StatusBadRequest:
+		return nil, errors.New(resp.String())
+	}
+	return params, nil
+}
+
+// Set saves the vault parameters of the user.
+// Returns ErrVaultExists if another client has already set them.
+func (s *vaultService) Set(token string, params srvrModels.VaultParams) error {
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Authorization", fmt.Sprintf("Bearer: %v", token)).
+		SetBody(params).
+		Put("/api/vault")
+	if err != nil {
+		return fmt.Errorf("%w: %v", clientErr.ErrServerUnavailable, err)
+	}
+	switch {
+	case resp.StatusCode() == http.StatusUnauthorized:
+		return srvErrors.ErrUnauthorized
+	case resp.StatusCode() == http.StatusConflict:
+		return srvErrors.ErrVaultExists
+	case resp.StatusCode() >= http.StatusBadRequest:
+		return errors.New(resp.String())
+	}
+	return nil
+}
+
+// GetClient returns the service's client.
+func (s *vaultService) GetClient() *resty.Client {
+	return s.client
+}
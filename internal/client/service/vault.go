@@ -0,0 +1,171 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+// keyCheckPlaintext is the known value encrypted as the key check of the vault.
+var keyCheckPlaintext = []byte("gophkeeper vault key check")
+
+// Vault encrypts and decrypts the records with the key derived from the master password.
+// The key derivation parameters are kept on the server, so every client of the user
+// derives the same key, while the server can't read the records. The vault is unlocked
+// on first use since it needs the token of the user.
+type Vault struct {
+	service  VaultService
+	password string
+	mu       sync.Mutex
+	key      []byte
+}
+
+// NewVault creates a new instance of the Vault with the master password.
+func NewVault(service VaultService, masterPassword string) *Vault {
+	return &Vault{service: service, password: masterPassword}
+}
+
+// NewVaultWithTransport creates a new instance of the Vault which talks to the server
+// over the transport provided.
+func NewVaultWithTransport(transport, addr, masterPassword string) (*Vault, error) {
+	service, err := NewVaultServiceWithTransport(transport, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewVault(service, masterPassword), nil
+}
+
+// Unlock derives the key from the master password. The first client of the user
+// generates the parameters and saves them on the server. Returns ErrWrongMasterPassword
+// if the key can't decrypt the key check.
+func (v *Vault) Unlock(token string) error {
+	_, err := v.unlock(token)
+	return err
+}
+
+// unlock returns the key deriving it on first use.
+func (v *Vault) unlock(token string) ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key != nil {
+		return v.key, nil
+	}
+	params, err := v.service.Get(token)
+	if errors.Is(err, srvErrors.ErrVaultNotFound) {
+		params, err = v.create(token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := deriveVaultKey(v.password, *params)
+	if err != nil {
+		return nil, err
+	}
+	check, err := encrypt.OpenBytes(params.KeyCheck, key)
+	if err != nil || !bytes.Equal(check, keyCheckPlaintext) {
+		return nil, clientErr.ErrWrongMasterPassword
+	}
+	v.key = key
+	return key, nil
+}
+
+// create generates new vault parameters and saves them on the server.
+// If another client has just saved its parameters, they are used instead.
+func (v *Vault) create(token string) (*srvrModels.VaultParams, error) {
+	kdf, err := encrypt.NewKDFParams()
+	if err != nil {
+		return nil, err
+	}
+	params := srvrModels.VaultParams{
+		KDF:     srvrModels.KDFArgon2id,
+		Salt:    kdf.Salt,
+		Time:    kdf.Time,
+		Memory:  kdf.Memory,
+		Threads: kdf.Threads,
+	}
+	key, err := deriveVaultKey(v.password, params)
+	if err != nil {
+		return nil, err
+	}
+	if params.KeyCheck, err = encrypt.SealBytes(keyCheckPlaintext, key); err != nil {
+		return nil, err
+	}
+	err = v.service.Set(token, params)
+	if errors.Is(err, srvErrors.ErrVaultExists) {
+		return v.service.Get(token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
+
+// deriveVaultKey derives the key from the password with the parameters of the vault.
+func deriveVaultKey(password string, params srvrModels.VaultParams) ([]byte, error) {
+	if params.KDF != srvrModels.KDFArgon2id {
+		return nil, errors.New("unsupported key derivation function: " + params.KDF)
+	}
+	return encrypt.DeriveKey(password, encrypt.KDFParams{
+		Salt:    params.Salt,
+		Time:    params.Time,
+		Memory:  params.Memory,
+		Threads: params.Threads,
+	})
+}
+
+// recordAD returns the additional data which binds the sealed content to the record,
+// so the server can't pass off the content of one record as another one.
+func recordAD(collectionName srvrModels.CollectionName, id srvrModels.ObjectID) []byte {
+	return []byte(string(collectionName) + "|" + id.Hex())
+}
+
+// Seal encrypts the data and the metadata of the record together
+// and returns the envelope to send instead of the data. The envelope
+// opens only for the record of the collection with the ID.
+func (v *Vault) Seal(
+	token string,
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	content srvrModels.UntypedRecordContent,
+) (any, error) {
+	key, err := v.unlock(token)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := encrypt.SealBytesWithAD(b, key, recordAD(collectionName, id))
+	if err != nil {
+		return nil, err
+	}
+	return srvrModels.NewEncryptedData(ciphertext), nil
+}
+
+// Open decrypts the data and the metadata of the record of the collection with the ID.
+// encrypt.ErrDecryptionFailed is returned if the content was sealed for another record.
+func (v *Vault) Open(
+	token string,
+	collectionName srvrModels.CollectionName,
+	id srvrModels.ObjectID,
+	ciphertext []byte,
+) (srvrModels.UntypedRecordContent, error) {
+	var content srvrModels.UntypedRecordContent
+	key, err := v.unlock(token)
+	if err != nil {
+		return content, err
+	}
+	b, err := encrypt.OpenBytesWithAD(ciphertext, key, recordAD(collectionName, id))
+	if err != nil {
+		return content, err
+	}
+	err = json.Unmarshal(b, &content)
+	return content, err
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	clientErr "github.com/blokhinnv/gophkeeper/internal/client/errors"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvrModels "github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+// memoryVaultService keeps the vault parameters in memory.
+type memoryVaultService struct {
+	params *srvrModels.VaultParams
+	gets   int
+	sets   int
+}
+
+func (s *memoryVaultService) Get(token string) (*srvrModels.VaultParams, error) {
+	s.gets++
+	if s.params == nil {
+		return nil, srvErrors.ErrVaultNotFound
+	}
+	return s.params, nil
+}
+
+func (s *memoryVaultService) Set(token string, params srvrModels.VaultParams) error {
+	s.sets++
+	if s.params != nil {
+		return srvErrors.ErrVaultExists
+	}
+	s.params = &params
+	return nil
+}
+
+func (s *memoryVaultService) GetClient() *resty.Client {
+	return nil
+}
+
+// racingVaultService pretends another client saves the parameters
+// right after the first request.
+type racingVaultService struct {
+	memoryVaultService
+}
+
+func (s *racingVaultService) Get(token string) (*srvrModels.VaultParams, error) {
+	if s.gets == 0 {
+		s.gets++
+		return nil, srvErrors.ErrVaultNotFound
+	}
+	return s.memoryVaultService.Get(token)
+}
+
+// newTestVaultParams returns cheap vault parameters for the master password.
+func newTestVaultParams(t *testing.T, password string) *srvrModels.VaultParams {
+	params := srvrModels.VaultParams{
+		KDF:     srvrModels.KDFArgon2id,
+		Salt:    []byte("0123456789abcdef"),
+		Time:    1,
+		Memory:  64,
+		Threads: 1,
+	}
+	key, err := deriveVaultKey(password, params)
+	require.NoError(t, err)
+	params.KeyCheck, err = encrypt.SealBytes(keyCheckPlaintext, key)
+	require.NoError(t, err)
+	return &params
+}
+
+func TestVault_Unlock(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		service := &memoryVaultService{}
+		v := NewVault(service, "master")
+		require.NoError(t, v.Unlock("token"))
+		require.NotNil(t, service.params)
+		assert.Equal(t, srvrModels.KDFArgon2id, service.params.KDF)
+		assert.Len(t, service.params.Salt, 16)
+
+		// the key is derived once
+		require.NoError(t, v.Unlock("token"))
+		assert.Equal(t, 1, service.gets)
+
+		// another client derives the same key
+		other := NewVault(service, "master")
+		id := srvrModels.NewRandomObjectID()
+		content := srvrModels.UntypedRecordContent{Data: "some text"}
+		sealed, err := v.Seal("token", srvrModels.TextCollection, id, content)
+		require.NoError(t, err)
+		ciphertext, err := srvrModels.EncryptedCiphertext(sealed)
+		require.NoError(t, err)
+		opened, err := other.Open("token", srvrModels.TextCollection, id, ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, "some text", opened.Data)
+	})
+	t.Run("existing", func(t *testing.T) {
+		service := &memoryVaultService{params: newTestVaultParams(t, "master")}
+		v := NewVault(service, "master")
+		require.NoError(t, v.Unlock("token"))
+		assert.Equal(t, 0, service.sets)
+	})
+	t.Run("created_by_another_client", func(t *testing.T) {
+		service := &racingVaultService{
+			memoryVaultService{params: newTestVaultParams(t, "master")},
+		}
+		v := NewVault(service, "master")
+		require.NoError(t, v.Unlock("token"))
+		assert.Equal(t, 1, service.sets)
+		assert.Equal(t, 2, service.gets)
+	})
+	t.Run("wrong_password", func(t *testing.T) {
+		service := &memoryVaultService{params: newTestVaultParams(t, "master")}
+		v := NewVault(service, "another")
+		assert.ErrorIs(t, v.Unlock("token"), clientErr.ErrWrongMasterPassword)
+		_, err := v.Seal(
+			"token",
+			srvrModels.TextCollection,
+			srvrModels.NewRandomObjectID(),
+			srvrModels.UntypedRecordContent{Data: "some text"},
+		)
+		assert.ErrorIs(t, err, clientErr.ErrWrongMasterPassword)
+	})
+	t.Run("unsupported_kdf", func(t *testing.T) {
+		params := newTestVaultParams(t, "master")
+		params.KDF = "scrypt"
+		v := NewVault(&memoryVaultService{params: params}, "master")
+		assert.Error(t, v.Unlock("token"))
+	})
+	t.Run("server_error", func(t *testing.T) {
+		v := NewVault(NewVaultService("http://localhost:0"), "master")
+		assert.ErrorIs(t, v.Unlock("token"), clientErr.ErrServerUnavailable)
+	})
+}
+
+func TestVault_Seal(t *testing.T) {
+	v := NewVault(&memoryVaultService{params: newTestVaultParams(t, "master")}, "master")
+	content := srvrModels.UntypedRecordContent{
+		Data:     map[string]any{"Login": "login", "Password": "pwd"},
+		Metadata: srvrModels.Metadata{"site": "example.com"},
+	}
+	id := srvrModels.NewRandomObjectID()
+	sealed, err := v.Seal("token", srvrModels.CredentialsCollection, id, content)
+	require.NoError(t, err)
+	assert.True(t, srvrModels.IsEncryptedData(sealed))
+	ciphertext, err := srvrModels.EncryptedCiphertext(sealed)
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "pwd")
+
+	opened, err := v.Open("token", srvrModels.CredentialsCollection, id, ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, content, opened)
+
+	// the ciphertext of the record doesn't open as another record
+	otherID := srvrModels.NewRandomObjectID()
+	_, err = v.Open("token", srvrModels.CredentialsCollection, otherID, ciphertext)
+	assert.ErrorIs(t, err, encrypt.ErrDecryptionFailed)
+	_, err = v.Open("token", srvrModels.TextCollection, id, ciphertext)
+	assert.ErrorIs(t, err, encrypt.ErrDecryptionFailed)
+
+	ciphertext[len(ciphertext)-1] ^= 1
+	_, err = v.Open("token", srvrModels.CredentialsCollection, id, ciphertext)
+	assert.ErrorIs(t, err, encrypt.ErrDecryptionFailed)
+}
+
+func TestVaultService(t *testing.T) {
+	baseURL := "https://example.com"
+	s := NewVaultService(baseURL)
+	client := s.GetClient()
+	assert.Equal(t, baseURL, client.HostURL)
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+	url := fmt.Sprintf("%v/api/vault", baseURL)
+	params := newTestVaultParams(t, "master")
+
+	t.Run("get", func(t *testing.T) {
+		httpmock.Reset()
+		responder, err := httpmock.NewJsonResponder(http.StatusOK, params)
+		require.NoError(t, err)
+		httpmock.RegisterResponder(http.MethodGet, url, responder)
+		resp, err := s.Get("token")
+		require.NoError(t, err)
+		assert.Equal(t, params, resp)
+	})
+	t.Run("get_not_found", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			url,
+			httpmock.NewStringResponder(http.StatusNotFound, srvErrors.ErrVaultNotFound.Error()),
+		)
+		_, err := s.Get("token")
+		assert.ErrorIs(t, err, srvErrors.ErrVaultNotFound)
+	})
+	t.Run("get_unauthorized", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodGet,
+			url,
+			httpmock.NewStringResponder(http.StatusUnauthorized, "Unauthorized"),
+		)
+		_, err := s.Get("token")
+		assert.ErrorIs(t, err, srvErrors.ErrUnauthorized)
+	})
+	t.Run("set", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPut,
+			url,
+			httpmock.NewStringResponder(http.StatusCreated, "Vault parameters saved"),
+		)
+		assert.NoError(t, s.Set("token", *params))
+	})
+	t.Run("set_exists", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPut,
+			url,
+			httpmock.NewStringResponder(http.StatusConflict, srvErrors.ErrVaultExists.Error()),
+		)
+		assert.ErrorIs(t, s.Set("token", *params), srvErrors.ErrVaultExists)
+	})
+	t.Run("set_bad_request", func(t *testing.T) {
+		httpmock.Reset()
+		httpmock.RegisterResponder(
+			http.MethodPut,
+			url,
+			httpmock.NewStringResponder(http.StatusBadRequest, "some error"),
+		)
+		assert.EqualError(t, s.Set("token", *params), "some error")
+	})
+}
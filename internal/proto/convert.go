@@ -0,0 +1,567 @@
+package proto
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// ErrDataMismatch is returned when the record data does not match the collection.
+var ErrDataMismatch = errors.New("record data does not match the collection")
+
+// NewRecord creates a message from the record in the form of the models package.
+// The data is a string for the text collection and an info struct or a map
+// with the same fields for the other collections.
+func NewRecord(
+	collectionName models.CollectionName,
+	id models.ObjectID,
+	data any,
+	metadata models.Metadata,
+) (*Record, error) {
+	r := &Record{Metadata: metadata}
+	if !id.IsZero() {
+		r.RecordId = id.Hex()
+	}
+	if models.IsEncryptedData(data) {
+		if _, err := models.NewCollectionName(string(collectionName)); err != nil {
+			return nil, err
+		}
+		ciphertext, err := models.EncryptedCiphertext(data)
+		if err != nil {
+			return nil, err
+		}
+		r.Data = &Record_Encrypted{Encrypted: ciphertext}
+		return r, nil
+	}
+	switch collectionName {
+	case models.TextCollection:
+		text, ok := data.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", ErrDataMismatch, collectionName)
+		}
+		r.Data = &Record_Text{Text: text}
+	case models.BinaryCollection:
+		var info models.BinaryInfo
+		if err := mapstructure.Decode(data, &info); err != nil {
+			return nil, err
+		}
+		content, err := base64.StdEncoding.DecodeString(info.Content)
+		if err != nil {
+			return nil, err
+		}
+		r.Data = &Record_Binary{Binary: &BinaryInfo{FileName: info.FileName, Content: content}}
+	case models.CredentialsCollection:
+		var info models.CredentialInfo
+		if err := mapstructure.Decode(data, &info); err != nil {
+			return nil, err
+		}
+		r.Data = &Record_Credential{Credential: &CredentialInfo{
+			Login:    info.Login,
+			Password: info.Password,
+		}}
+	case models.CardCollection:
+		var info models.CardInfo
+		if err := mapstructure.Decode(data, &info); err != nil {
+			return nil, err
+		}
+		r.Data = &Record_Card{Card: &CardInfo{
+			CardNumber:     info.CardNumber,
+			Cvv:            info.CVV,
+			ExpirationDate: info.ExpirationDate,
+		}}
+	case models.OTPCollection:
+		var info models.OTPInfo
+		if err := mapstructure.Decode(data, &info); err != nil {
+			return nil, err
+		}
+		r.Data = &Record_Otp{Otp: &OTPInfo{
+			Type:      info.Type,
+			Secret:    info.Secret,
+			Issuer:    info.Issuer,
+			Account:   info.Account,
+			Algorithm: info.Algorithm,
+			Digits:    info.Digits,
+			Period:    info.Period,
+			Counter:   info.Counter,
+		}}
+	default:
+		return nil, fmt.Errorf("%w: %v", srvErrors.ErrUnknownCollection, collectionName)
+	}
+	return r, nil
+}
+
+// NewStoredRecord creates a message from the record read from the storage
+// including its version, the time of its last change and of its deletion.
+func NewStoredRecord(
+	collectionName models.CollectionName,
+	record models.UntypedRecord,
+) (*Record, error) {
+	r, err := NewRecord(collectionName, record.RecordID, record.Data, record.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	r.Version = record.Version
+	if record.UpdatedAt != nil {
+		r.UpdatedAt = record.UpdatedAt.Unix()
+	}
+	if record.DeletedAt != nil {
+		r.DeletedAt = record.DeletedAt.Unix()
+	}
+	return r, nil
+}
+
+// ModelRecord returns the record in the form of the models package.
+func (r *Record) ModelRecord(collectionName models.CollectionName) (models.UntypedRecord, error) {
+	id, err := r.ModelID()
+	if err != nil {
+		return models.UntypedRecord{}, err
+	}
+	data, err := r.ModelData(collectionName)
+	if err != nil {
+		return models.UntypedRecord{}, err
+	}
+	record := models.UntypedRecord{
+		UntypedRecordContent: models.UntypedRecordContent{Data: data, Metadata: r.GetMetadata()},
+		RecordID:             id,
+		Version:              r.GetVersion(),
+	}
+	if r.GetUpdatedAt() != 0 {
+		updatedAt := time.Unix(r.GetUpdatedAt(), 0).UTC()
+		record.UpdatedAt = &updatedAt
+	}
+	if r.GetDeletedAt() != 0 {
+		deletedAt := time.Unix(r.GetDeletedAt(), 0).UTC()
+		record.DeletedAt = &deletedAt
+	}
+	return record, nil
+}
+
+// NewRevision creates a message from the revision of the record.
+func NewRevision(collectionName models.CollectionName, revision models.Revision) (*Revision, error) {
+	record, err := NewStoredRecord(collectionName, models.UntypedRecord{
+		UntypedRecordContent: revision.UntypedRecordContent,
+		RecordID:             revision.RecordID,
+		Version:              revision.Rev,
+		UpdatedAt:            revision.UpdatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Revision{
+		Record:     record,
+		Op:         string(revision.Op),
+		ArchivedAt: revision.ArchivedAt.Unix(),
+	}, nil
+}
+
+// ModelRevision returns the revision in the form of the models package.
+func (r *Revision) ModelRevision(collectionName models.CollectionName) (models.Revision, error) {
+	record, err := r.GetRecord().ModelRecord(collectionName)
+	if err != nil {
+		return models.Revision{}, err
+	}
+	return models.Revision{
+		UntypedRecordContent: record.UntypedRecordContent,
+		RecordID:             record.RecordID,
+		Rev:                  record.Version,
+		Op:                   models.ChangeOp(r.GetOp()),
+		UpdatedAt:            record.UpdatedAt,
+		ArchivedAt:           time.Unix(r.GetArchivedAt(), 0).UTC(),
+	}, nil
+}
+
+// ModelID returns the record ID. An empty ID is converted into the zero ObjectID.
+func (r *Record) ModelID() (models.ObjectID, error) {
+	if r.GetRecordId() == "" {
+		return models.ObjectID{}, nil
+	}
+	return models.ObjectIDFromString(r.GetRecordId())
+}
+
+// ModelData returns the record data in the form of the models package:
+// a string for the text collection and an info struct for the other collections.
+// The data of an end-to-end encrypted record of any collection is an envelope.
+func (r *Record) ModelData(collectionName models.CollectionName) (any, error) {
+	if d, ok := r.GetData().(*Record_Encrypted); ok {
+		if _, err := models.NewCollectionName(string(collectionName)); err != nil {
+			return nil, err
+		}
+		return models.NewEncryptedData(d.Encrypted), nil
+	}
+	mismatch := fmt.Errorf("%w: %v", ErrDataMismatch, collectionName)
+	switch collectionName {
+	case models.TextCollection:
+		d, ok := r.GetData().(*Record_Text)
+		if !ok {
+			return nil, mismatch
+		}
+		return d.Text, nil
+	case models.BinaryCollection:
+		d, ok := r.GetData().(*Record_Binary)
+		if !ok {
+			return nil, mismatch
+		}
+		return models.BinaryInfo{
+			FileName: d.Binary.GetFileName(),
+			Content:  base64.StdEncoding.EncodeToString(d.Binary.GetContent()),
+		}, nil
+	case models.CredentialsCollection:
+		d, ok := r.GetData().(*Record_Credential)
+		if !ok {
+			return nil, mismatch
+		}
+		return models.CredentialInfo{
+			Login:    d.Credential.GetLogin(),
+			Password: d.Credential.GetPassword(),
+		}, nil
+	case models.CardCollection:
+		d, ok := r.GetData().(*Record_Card)
+		if !ok {
+			return nil, mismatch
+		}
+		return models.CardInfo{
+			CardNumber:     d.Card.GetCardNumber(),
+			CVV:            d.Card.GetCvv(),
+			ExpirationDate: d.Card.GetExpirationDate(),
+		}, nil
+	case models.OTPCollection:
+		d, ok := r.GetData().(*Record_Otp)
+		if !ok {
+			return nil, mismatch
+		}
+		return models.OTPInfo{
+			Type:      d.Otp.GetType(),
+			Secret:    d.Otp.GetSecret(),
+			Issuer:    d.Otp.GetIssuer(),
+			Account:   d.Otp.GetAccount(),
+			Algorithm: d.Otp.GetAlgorithm(),
+			Digits:    d.Otp.GetDigits(),
+			Period:    d.Otp.GetPeriod(),
+			Counter:   d.Otp.GetCounter(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %v", srvErrors.ErrUnknownCollection, collectionName)
+	}
+}
+
+// NewWatchEvent creates a message from the change event.
+func NewWatchEvent(event models.ChangeEvent) *WatchEvent {
+	return &WatchEvent{
+		Collection: string(event.Collection),
+		RecordId:   event.RecordID.Hex(),
+		Op:         string(event.Op),
+		Version:    event.Version,
+	}
+}
+
+// ModelEvent returns the change event in the form of the models package.
+func (e *WatchEvent) ModelEvent() (models.ChangeEvent, error) {
+	collectionName, err := models.NewCollectionName(e.GetCollection())
+	if err != nil {
+		return models.ChangeEvent{}, err
+	}
+	id, err := models.ObjectIDFromString(e.GetRecordId())
+	if err != nil {
+		return models.ChangeEvent{}, err
+	}
+	return models.ChangeEvent{
+		Collection: collectionName,
+		RecordID:   id,
+		Op:         models.ChangeOp(e.GetOp()),
+		Version:    e.GetVersion(),
+	}, nil
+}
+
+// NewChangesResponse creates a message from the changes since the cursor.
+func NewChangesResponse(changes *models.Changes) (*ChangesResponse, error) {
+	resp := &ChangesResponse{
+		Upserts:    make([]*ChangedRecord, 0, len(changes.Upserts)),
+		Tombstones: make([]*Tombstone, 0, len(changes.Tombstones)),
+		Cursor:     changes.Cursor,
+		Reset_:     changes.Reset,
+	}
+	for _, u := range changes.Upserts {
+		record, err := NewStoredRecord(u.Collection, u.Record)
+		if err != nil {
+			return nil, err
+		}
+		resp.Upserts = append(resp.Upserts, &ChangedRecord{
+			Collection: string(u.Collection),
+			Record:     record,
+		})
+	}
+	for _, t := range changes.Tombstones {
+		tombstone := &Tombstone{Collection: string(t.Collection), RecordId: t.RecordID.Hex()}
+		if t.DeletedAt != nil {
+			tombstone.DeletedAt = t.DeletedAt.Unix()
+		}
+		resp.Tombstones = append(resp.Tombstones, tombstone)
+	}
+	return resp, nil
+}
+
+// ModelChanges returns the changes in the form of the models package.
+func (r *ChangesResponse) ModelChanges() (*models.Changes, error) {
+	changes := &models.Changes{
+		Upserts:    make([]models.ChangedRecord, 0, len(r.GetUpserts())),
+		Tombstones: make([]models.Tombstone, 0, len(r.GetTombstones())),
+		Cursor:     r.GetCursor(),
+		Reset:      r.GetReset_(),
+	}
+	for _, u := range r.GetUpserts() {
+		collectionName, err := models.NewCollectionName(u.GetCollection())
+		if err != nil {
+			return nil, err
+		}
+		record, err := u.GetRecord().ModelRecord(collectionName)
+		if err != nil {
+			return nil, err
+		}
+		changes.Upserts = append(changes.Upserts, models.ChangedRecord{
+			Collection: collectionName,
+			Record:     record,
+		})
+	}
+	for _, t := range r.GetTombstones() {
+		collectionName, err := models.NewCollectionName(t.GetCollection())
+		if err != nil {
+			return nil, err
+		}
+		id, err := models.ObjectIDFromString(t.GetRecordId())
+		if err != nil {
+			return nil, err
+		}
+		tombstone := models.Tombstone{Collection: collectionName, RecordID: id}
+		if t.GetDeletedAt() != 0 {
+			deletedAt := time.Unix(t.GetDeletedAt(), 0).UTC()
+			tombstone.DeletedAt = &deletedAt
+		}
+		changes.Tombstones = append(changes.Tombstones, tombstone)
+	}
+	return changes, nil
+}
+
+// NewSearchResponse creates a message from the found records.
+func NewSearchResponse(results []models.SearchResult) (*SearchResponse, error) {
+	resp := &SearchResponse{Results: make([]*SearchResult, 0, len(results))}
+	for _, res := range results {
+		record, err := NewStoredRecord(res.Collection, res.Record)
+		if err != nil {
+			return nil, err
+		}
+		resp.Results = append(resp.Results, &SearchResult{
+			Collection: string(res.Collection),
+			Record:     record,
+		})
+	}
+	return resp, nil
+}
+
+// ModelResults returns the found records in the form of the models package.
+func (r *SearchResponse) ModelResults() ([]models.SearchResult, error) {
+	results := make([]models.SearchResult, 0, len(r.GetResults()))
+	for _, res := range r.GetResults() {
+		collectionName, err := models.NewCollectionName(res.GetCollection())
+		if err != nil {
+			return nil, err
+		}
+		record, err := res.GetRecord().ModelRecord(collectionName)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, models.SearchResult{Collection: collectionName, Record: record})
+	}
+	return results, nil
+}
+
+// NewVaultParams creates a message from the vault parameters.
+func NewVaultParams(params models.VaultParams) *VaultParams {
+	return &VaultParams{
+		Kdf:      params.KDF,
+		Salt:     params.Salt,
+		Time:     params.Time,
+		Memory:   params.Memory,
+		Threads:  uint32(params.Threads),
+		KeyCheck: params.KeyCheck,
+	}
+}
+
+// ModelParams returns the vault parameters in the form of the models package.
+func (p *VaultParams) ModelParams() (models.VaultParams, error) {
+	if p.GetThreads() > math.MaxUint8 {
+		return models.VaultParams{}, fmt.Errorf("too many threads: %v", p.GetThreads())
+	}
+	return models.VaultParams{
+		KDF:      p.GetKdf(),
+		Salt:     p.GetSalt(),
+		Time:     p.GetTime(),
+		Memory:   p.GetMemory(),
+		Threads:  uint8(p.GetThreads()),
+		KeyCheck: p.GetKeyCheck(),
+	}, nil
+}
+
+// NewLoginResponse creates a message from the tokens of the session.
+func NewLoginResponse(tokens models.TokenPair) *LoginResponse {
+	return &LoginResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt.Unix(),
+		SessionId:    tokens.SessionID,
+		MfaRequired:  tokens.MFARequired,
+		MfaToken:     tokens.MFAToken,
+		DeviceId:     tokens.DeviceID,
+	}
+}
+
+// ModelTokens returns the tokens of the session in the form of the models package.
+func (r *LoginResponse) ModelTokens() models.TokenPair {
+	return models.TokenPair{
+		AccessToken:  r.GetToken(),
+		RefreshToken: r.GetRefreshToken(),
+		ExpiresAt:    time.Unix(r.GetExpiresAt(), 0).UTC(),
+		SessionID:    r.GetSessionId(),
+		MFARequired:  r.GetMfaRequired(),
+		MFAToken:     r.GetMfaToken(),
+		DeviceID:     r.GetDeviceId(),
+	}
+}
+
+// NewSession creates a message from the session.
+func NewSession(session models.Session) *Session {
+	return &Session{
+		SessionId:  session.ID.Hex(),
+		CreatedAt:  session.CreatedAt.Unix(),
+		LastUsedAt: session.LastUsedAt.Unix(),
+		ExpiresAt:  session.ExpiresAt.Unix(),
+		Current:    session.Current,
+		DeviceId:   session.DeviceID,
+	}
+}
+
+// ModelSession returns the session in the form of the models package.
+func (s *Session) ModelSession() (models.Session, error) {
+	id, err := models.ObjectIDFromString(s.GetSessionId())
+	if err != nil {
+		return models.Session{}, err
+	}
+	return models.Session{
+		ID:         id,
+		CreatedAt:  time.Unix(s.GetCreatedAt(), 0).UTC(),
+		LastUsedAt: time.Unix(s.GetLastUsedAt(), 0).UTC(),
+		ExpiresAt:  time.Unix(s.GetExpiresAt(), 0).UTC(),
+		Current:    s.GetCurrent(),
+		DeviceID:   s.GetDeviceId(),
+	}, nil
+}
+
+// NewDeviceInfo creates a message from the device registered on login.
+// Returns nil for the nil device.
+func NewDeviceInfo(info *models.DeviceInfo) *DeviceInfo {
+	if info == nil {
+		return nil
+	}
+	return &DeviceInfo{
+		Name:      info.Name,
+		Platform:  info.Platform,
+		PublicKey: info.PublicKey,
+		Proof:     NewDeviceProof(info.Proof),
+	}
+}
+
+// ModelDeviceInfo returns the device in the form of the models package.
+// Returns nil for the nil message.
+func (d *DeviceInfo) ModelDeviceInfo() *models.DeviceInfo {
+	if d == nil {
+		return nil
+	}
+	return &models.DeviceInfo{
+		Name:      d.GetName(),
+		Platform:  d.GetPlatform(),
+		PublicKey: d.GetPublicKey(),
+		Proof:     d.GetProof().ModelDeviceProof(),
+	}
+}
+
+// NewDeviceProof creates a message from the proof of the key of the device.
+// Returns nil for the nil proof.
+func NewDeviceProof(proof *models.DeviceProof) *DeviceProof {
+	if proof == nil {
+		return nil
+	}
+	return &DeviceProof{Nonce: proof.Nonce, Signature: proof.Signature}
+}
+
+// ModelDeviceProof returns the proof in the form of the models package.
+// Returns nil for the nil message.
+func (p *DeviceProof) ModelDeviceProof() *models.DeviceProof {
+	if p == nil {
+		return nil
+	}
+	return &models.DeviceProof{Nonce: p.GetNonce(), Signature: p.GetSignature()}
+}
+
+// NewDevice creates a message from the device of the user.
+func NewDevice(device models.Device) *Device {
+	return &Device{
+		DeviceId:    device.ID.Hex(),
+		Name:        device.Name,
+		Platform:    device.Platform,
+		PublicKey:   device.PublicKey,
+		CreatedAt:   device.CreatedAt.Unix(),
+		LastLoginAt: device.LastLoginAt.Unix(),
+		Current:     device.Current,
+	}
+}
+
+// ModelDevice returns the device in the form of the models package.
+func (d *Device) ModelDevice() (models.Device, error) {
+	id, err := models.ObjectIDFromString(d.GetDeviceId())
+	if err != nil {
+		return models.Device{}, err
+	}
+	return models.Device{
+		ID: id,
+		DeviceInfo: models.DeviceInfo{
+			Name:      d.GetName(),
+			Platform:  d.GetPlatform(),
+			PublicKey: d.GetPublicKey(),
+		},
+		CreatedAt:   time.Unix(d.GetCreatedAt(), 0).UTC(),
+		LastLoginAt: time.Unix(d.GetLastLoginAt(), 0).UTC(),
+		Current:     d.GetCurrent(),
+	}, nil
+}
+
+// NewBlob creates a message from the state of the upload of the blob.
+func NewBlob(blob models.Blob) *Blob {
+	return &Blob{
+		BlobId:    blob.BlobID.Hex(),
+		Size:      blob.Size,
+		Received:  blob.Received,
+		Chunks:    blob.Chunks,
+		CreatedAt: blob.CreatedAt.Unix(),
+	}
+}
+
+// ModelBlob returns the state of the upload of the blob in the form of the models package.
+func (b *Blob) ModelBlob() (models.Blob, error) {
+	id, err := models.ObjectIDFromString(b.GetBlobId())
+	if err != nil {
+		return models.Blob{}, err
+	}
+	return models.Blob{
+		BlobID:    id,
+		Size:      b.GetSize(),
+		Received:  b.GetReceived(),
+		Chunks:    b.GetChunks(),
+		CreatedAt: time.Unix(b.GetCreatedAt(), 0).UTC(),
+	}, nil
+}
@@ -0,0 +1,323 @@
+package proto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestRecordConversion(t *testing.T) {
+	id := models.NewRandomObjectID()
+	tests := []struct {
+		name       string
+		collection models.CollectionName
+		data       any
+		want       any
+	}{
+		{
+			name:       "text",
+			collection: models.TextCollection,
+			data:       "some text",
+			want:       "some text",
+		},
+		{
+			name:       "binary",
+			collection: models.BinaryCollection,
+			data:       map[string]any{"FileName": "a.txt", "Content": "aGVsbG8="},
+			want:       models.BinaryInfo{FileName: "a.txt", Content: "aGVsbG8="},
+		},
+		{
+			name:       "credentials",
+			collection: models.CredentialsCollection,
+			data:       models.CredentialInfo{Login: "user", Password: "pwd"},
+			want:       models.CredentialInfo{Login: "user", Password: "pwd"},
+		},
+		{
+			name:       "cards",
+			collection: models.CardCollection,
+			data: map[string]any{
+				"CardNumber":     "4111111111111111",
+				"CVV":            "123",
+				"ExpirationDate": "12/30",
+			},
+			want: models.CardInfo{
+				CardNumber:     "4111111111111111",
+				CVV:            "123",
+				ExpirationDate: "12/30",
+			},
+		},
+		{
+			name:       "otp",
+			collection: models.OTPCollection,
+			data:       map[string]any{"Secret": "JBSWY3DPEHPK3PXP", "Digits": "8"},
+			want:       models.OTPInfo{Secret: "JBSWY3DPEHPK3PXP", Digits: "8"},
+		},
+		{
+			name:       "encrypted",
+			collection: models.CardCollection,
+			data:       map[string]any{models.EncryptedField: "Y2lwaGVydGV4dA=="},
+			want:       map[string]any{models.EncryptedField: "Y2lwaGVydGV4dA=="},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRecord(tt.collection, id, tt.data, models.Metadata{"k": "v"})
+			require.NoError(t, err)
+			assert.Equal(t, id.Hex(), r.RecordId)
+			assert.Equal(t, map[string]string{"k": "v"}, r.Metadata)
+
+			gotID, err := r.ModelID()
+			require.NoError(t, err)
+			assert.Equal(t, id, gotID)
+			got, err := r.ModelData(tt.collection)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRecordConversionErrors(t *testing.T) {
+	_, err := NewRecord(models.TextCollection, models.ObjectID{}, map[string]any{}, nil)
+	assert.ErrorIs(t, err, ErrDataMismatch)
+	_, err = NewRecord("unknown", models.ObjectID{}, "text", nil)
+	assert.ErrorIs(t, err, srvErrors.ErrUnknownCollection)
+	_, err = NewRecord(
+		models.BinaryCollection,
+		models.ObjectID{},
+		models.BinaryInfo{FileName: "a.txt", Content: "not base64"},
+		nil,
+	)
+	assert.Error(t, err)
+
+	_, err = NewRecord(
+		models.TextCollection,
+		models.ObjectID{},
+		map[string]any{models.EncryptedField: "not base64"},
+		nil,
+	)
+	assert.ErrorIs(t, err, srvErrors.ErrBadEnvelope)
+	_, err = (&Record{Data: &Record_Encrypted{Encrypted: []byte("ciphertext")}}).ModelData("unknown")
+	assert.ErrorIs(t, err, srvErrors.ErrUnknownCollection)
+
+	r := &Record{Data: &Record_Text{Text: "text"}}
+	_, err = r.ModelData(models.CredentialsCollection)
+	assert.ErrorIs(t, err, ErrDataMismatch)
+	id, err := r.ModelID()
+	require.NoError(t, err)
+	assert.True(t, id.IsZero())
+
+	r.RecordId = "bad"
+	_, err = r.ModelID()
+	assert.Error(t, err)
+}
+
+func TestWatchEventConversion(t *testing.T) {
+	event := models.ChangeEvent{
+		Collection: models.CardCollection,
+		RecordID:   models.NewRandomObjectID(),
+		Op:         models.OpUpdate,
+		Version:    42,
+	}
+	got, err := NewWatchEvent(event).ModelEvent()
+	require.NoError(t, err)
+	assert.Equal(t, event, got)
+
+	_, err = (&WatchEvent{Collection: "unknown"}).ModelEvent()
+	assert.ErrorIs(t, err, srvErrors.ErrUnknownCollection)
+	_, err = (&WatchEvent{Collection: "text", RecordId: "bad"}).ModelEvent()
+	assert.Error(t, err)
+}
+
+func TestStoredRecordConversion(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	updatedAt, deletedAt := now.Add(-time.Hour), now
+	record := models.UntypedRecord{
+		UntypedRecordContent: models.UntypedRecordContent{
+			Data:     "deleted text",
+			Metadata: models.Metadata{"k": "v"},
+		},
+		RecordID:  models.NewRandomObjectID(),
+		Version:   4,
+		UpdatedAt: &updatedAt,
+		DeletedAt: &deletedAt,
+	}
+	msg, err := NewStoredRecord(models.TextCollection, record)
+	require.NoError(t, err)
+	got, err := msg.ModelRecord(models.TextCollection)
+	require.NoError(t, err)
+	assert.Equal(t, record, got)
+}
+
+func TestRevisionConversion(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	updatedAt := now.Add(-time.Hour)
+	revision := models.Revision{
+		UntypedRecordContent: models.UntypedRecordContent{
+			Data:     "old text",
+			Metadata: models.Metadata{"k": "v"},
+		},
+		RecordID:   models.NewRandomObjectID(),
+		Rev:        3,
+		Op:         models.OpDelete,
+		UpdatedAt:  &updatedAt,
+		ArchivedAt: now,
+	}
+	msg, err := NewRevision(models.TextCollection, revision)
+	require.NoError(t, err)
+	got, err := msg.ModelRevision(models.TextCollection)
+	require.NoError(t, err)
+	assert.Equal(t, revision, got)
+
+	_, err = NewRevision(models.TextCollection, models.Revision{
+		UntypedRecordContent: models.UntypedRecordContent{Data: 42},
+	})
+	assert.ErrorIs(t, err, ErrDataMismatch)
+	_, err = msg.ModelRevision(models.CardCollection)
+	assert.ErrorIs(t, err, ErrDataMismatch)
+}
+
+func TestChangesConversion(t *testing.T) {
+	deletedAt := time.Now().UTC().Truncate(time.Second)
+	changes := &models.Changes{
+		Upserts: []models.ChangedRecord{{
+			Collection: models.TextCollection,
+			Record: models.UntypedRecord{
+				UntypedRecordContent: models.UntypedRecordContent{Data: "text"},
+				RecordID:             models.NewRandomObjectID(),
+				Version:              2,
+			},
+		}},
+		Tombstones: []models.Tombstone{{
+			Collection: models.CardCollection,
+			RecordID:   models.NewRandomObjectID(),
+			DeletedAt:  &deletedAt,
+		}},
+		Cursor: "cursor",
+		Reset:  true,
+	}
+	msg, err := NewChangesResponse(changes)
+	require.NoError(t, err)
+	got, err := msg.ModelChanges()
+	require.NoError(t, err)
+	assert.Equal(t, changes, got)
+
+	msg.Tombstones[0].Collection = "unknown"
+	_, err = msg.ModelChanges()
+	assert.ErrorIs(t, err, srvErrors.ErrUnknownCollection)
+}
+
+func TestSearchResultsConversion(t *testing.T) {
+	updatedAt := time.Now().UTC().Truncate(time.Second)
+	results := []models.SearchResult{{
+		Collection: models.CredentialsCollection,
+		Record: models.UntypedRecord{
+			UntypedRecordContent: models.UntypedRecordContent{
+				Data:     models.CredentialInfo{Login: "john@github.com", Password: "secret"},
+				Metadata: models.Metadata{"site": "github.com"},
+			},
+			RecordID:  models.NewRandomObjectID(),
+			Version:   3,
+			UpdatedAt: &updatedAt,
+		},
+	}}
+	msg, err := NewSearchResponse(results)
+	require.NoError(t, err)
+	got, err := msg.ModelResults()
+	require.NoError(t, err)
+	assert.Equal(t, results, got)
+
+	msg.Results[0].Collection = "unknown"
+	_, err = msg.ModelResults()
+	assert.ErrorIs(t, err, srvErrors.ErrUnknownCollection)
+}
+
+func TestVaultParamsConversion(t *testing.T) {
+	params := models.VaultParams{
+		KDF:      models.KDFArgon2id,
+		Salt:     []byte("0123456789abcdef"),
+		Time:     3,
+		Memory:   64 * 1024,
+		Threads:  4,
+		KeyCheck: []byte("key check"),
+	}
+	got, err := NewVaultParams(params).ModelParams()
+	require.NoError(t, err)
+	assert.Equal(t, params, got)
+
+	_, err = (&VaultParams{Threads: 256}).ModelParams()
+	assert.Error(t, err)
+}
+
+func TestSessionConversion(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	tokens := models.TokenPair{
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		ExpiresAt:    now,
+		SessionID:    "session",
+		DeviceID:     "device",
+	}
+	assert.Equal(t, tokens, NewLoginResponse(tokens).ModelTokens())
+	challenge := models.TokenPair{ExpiresAt: now, MFARequired: true, MFAToken: "challenge"}
+	assert.Equal(t, challenge, NewLoginResponse(challenge).ModelTokens())
+
+	session := models.Session{
+		ID:         models.NewRandomObjectID(),
+		CreatedAt:  now.Add(-time.Hour),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(time.Hour),
+		Current:    true,
+		DeviceID:   "device",
+	}
+	got, err := NewSession(session).ModelSession()
+	require.NoError(t, err)
+	assert.Equal(t, session, got)
+
+	_, err = (&Session{SessionId: "bad"}).ModelSession()
+	assert.Error(t, err)
+}
+
+func TestDeviceConversion(t *testing.T) {
+	info := &models.DeviceInfo{Name: "laptop", Platform: "linux/amd64", PublicKey: "key"}
+	assert.Equal(t, info, NewDeviceInfo(info).ModelDeviceInfo())
+	assert.Nil(t, NewDeviceInfo(nil).ModelDeviceInfo())
+	proven := *info
+	proven.Proof = &models.DeviceProof{Nonce: "nonce", Signature: "signature"}
+	assert.Equal(t, &proven, NewDeviceInfo(&proven).ModelDeviceInfo())
+
+	now := time.Now().UTC().Truncate(time.Second)
+	device := models.Device{
+		ID:          models.NewRandomObjectID(),
+		DeviceInfo:  *info,
+		CreatedAt:   now.Add(-time.Hour),
+		LastLoginAt: now,
+		Current:     true,
+	}
+	got, err := NewDevice(device).ModelDevice()
+	require.NoError(t, err)
+	assert.Equal(t, device, got)
+
+	_, err = (&Device{DeviceId: "bad"}).ModelDevice()
+	assert.Error(t, err)
+}
+
+func TestBlobConversion(t *testing.T) {
+	blob := models.Blob{
+		BlobID:    models.NewRandomObjectID(),
+		Size:      10,
+		Received:  4,
+		Chunks:    1,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	got, err := NewBlob(blob).ModelBlob()
+	require.NoError(t, err)
+	assert.Equal(t, blob, got)
+
+	_, err = (&Blob{BlobId: "bad"}).ModelBlob()
+	assert.Error(t, err)
+}
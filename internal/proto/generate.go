@@ -0,0 +1,5 @@
+// Package proto contains the gRPC API of the gophkeeper server generated
+// from gophkeeper.proto and the helpers to convert its messages into models.
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative gophkeeper.proto
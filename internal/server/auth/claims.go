@@ -4,7 +4,10 @@ package auth
 import "github.com/golang-jwt/jwt/v4"
 
 // Claims is a struct containg custom fields included into JWT-token.
+// The token id (jti) is kept in RegisteredClaims.ID.
 type Claims struct {
 	jwt.RegisteredClaims
-	Username string `json:"username"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid,omitempty"` // SessionID is the id of the session which issued the token.
+	DeviceID  string `json:"did,omitempty"` // DeviceID is the id of the device the token was issued to.
 }
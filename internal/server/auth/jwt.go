@@ -1,7 +1,9 @@
 package auth
 
 import (
-	"fmt"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -16,19 +18,51 @@ func GenerateJWTToken(
 	signingKey []byte,
 	expireDuration time.Duration,
 ) (string, error) {
-	claims := Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expireDuration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-		Username: username,
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(signingKey)
+	tokenString, _, err := NewAccessToken(username, "", signingKey, expireDuration)
+	return tokenString, err
+}
+
+// NewAccessToken generates a JWT token of the session like GenerateJWTToken.
+// The token gets a random id (jti), so it can be revoked before it expires.
+// The claims of the token are returned along with it.
+func NewAccessToken(
+	username string,
+	sessionID string,
+	signingKey []byte,
+	expireDuration time.Duration,
+) (string, *Claims, error) {
+	return NewHMACKeySet(signingKey).NewAccessToken(username, sessionID, "", expireDuration)
+}
+
+// newToken generates the token with the claims signed with the key. The id
+// and the times of the token are set to the claims. The kid header is set
+// if the id of the key is not empty.
+func newToken(
+	method jwt.SigningMethod,
+	kid string,
+	key any,
+	claims *Claims,
+	expireDuration time.Duration,
+) (string, *Claims, error) {
+	jti, err := NewRandomToken(16)
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		ExpiresAt: jwt.NewNumericDate(now.Add(expireDuration)),
+		IssuedAt:  jwt.NewNumericDate(now),
 	}
-	return tokenString, nil
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		return "", nil, err
+	}
+	return tokenString, claims, nil
 }
 
 // ValidateJWTToken validates the provided JWT token string using the specified signing key.
@@ -36,19 +70,74 @@ func GenerateJWTToken(
 // a string. If the token is not valid, an error is returned. The function uses the HMAC-SHA256
 // signing method to validate the token.
 func ValidateJWTToken(tokenString string, signingKey []byte) (string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Don't forget to validate the alg is what you expect:
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return signingKey, nil
-	})
+	claims, err := ParseJWTToken(tokenString, signingKey)
 	if err != nil {
 		return "", err
 	}
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if ok && token.Valid {
-		return claims["username"].(string), nil
+	return claims.Username, nil
+}
+
+// ParseJWTToken validates the token like ValidateJWTToken and returns all its claims.
+func ParseJWTToken(tokenString string, signingKey []byte) (*Claims, error) {
+	return NewHMACKeySet(signingKey).Parse(tokenString)
+}
+
+// parseToken validates the token with the key returned by the function
+// and returns its claims. The token without a username is invalid.
+func parseToken(tokenString string, keyFunc jwt.Keyfunc) (*Claims, error) {
+	claims, err := parseClaims(tokenString, keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Username == "" {
+		return nil, errors.New("no username in the token")
 	}
-	return "", err
+	return claims, nil
+}
+
+// parseClaims validates the token with the key returned by the function
+// and returns its claims.
+func parseClaims(tokenString string, keyFunc jwt.Keyfunc) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// derivedKey derives the key of the tokens with the purpose from the signing key.
+func derivedKey(signingKey []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// mfaSigningKey derives the key of the challenge tokens from the signing key.
+func mfaSigningKey(signingKey []byte) []byte {
+	return derivedKey(signingKey, "gophkeeper mfa challenge")
+}
+
+// nonceSigningKey derives the key of the device nonces from the signing key.
+func nonceSigningKey(signingKey []byte) []byte {
+	return derivedKey(signingKey, "gophkeeper device nonce")
+}
+
+// NewMFAToken generates the short-lived challenge token of the login which
+// waits for the second factor. The token is signed with a key derived from
+// the signing key, so it is never accepted as an access token.
+func NewMFAToken(
+	username string,
+	signingKey []byte,
+	expireDuration time.Duration,
+) (string, *Claims, error) {
+	return NewHMACKeySet(signingKey).NewMFAToken(username, expireDuration)
+}
+
+// ParseMFAToken validates the challenge token issued by NewMFAToken and returns its claims.
+func ParseMFAToken(tokenString string, signingKey []byte) (*Claims, error) {
+	return NewHMACKeySet(signingKey).ParseMFAToken(tokenString)
 }
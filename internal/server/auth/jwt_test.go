@@ -77,3 +77,59 @@ func TestGenerateJWTToken(t *testing.T) {
 		)
 	}
 }
+
+func TestNewAccessToken(t *testing.T) {
+	signingKey := []byte("mySecretKey")
+	tok1, claims1, err := NewAccessToken("blokhinnv", "session", signingKey, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error while generating JWT token: %v", err)
+	}
+	_, claims2, err := NewAccessToken("blokhinnv", "session", signingKey, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error while generating JWT token: %v", err)
+	}
+	if claims1.ID == "" || claims1.ID == claims2.ID {
+		t.Errorf("expected unique token ids, but got %q and %q", claims1.ID, claims2.ID)
+	}
+
+	parsed, err := ParseJWTToken(tok1, signingKey)
+	if err != nil {
+		t.Fatalf("unexpected error while parsing JWT token: %v", err)
+	}
+	if parsed.ID != claims1.ID || parsed.SessionID != "session" || parsed.Username != "blokhinnv" {
+		t.Errorf("unexpected claims: %+v", parsed)
+	}
+
+	expired, _, err := NewAccessToken("blokhinnv", "session", signingKey, -time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error while generating JWT token: %v", err)
+	}
+	if _, err := ParseJWTToken(expired, signingKey); err == nil {
+		t.Errorf("expected error while parsing an expired token, but got no error")
+	}
+}
+
+func TestNewMFAToken(t *testing.T) {
+	signingKey := []byte("mySecretKey")
+	tok, _, err := NewMFAToken("blokhinnv", signingKey, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error while generating MFA token: %v", err)
+	}
+	claims, err := ParseMFAToken(tok, signingKey)
+	if err != nil {
+		t.Fatalf("unexpected error while parsing MFA token: %v", err)
+	}
+	if claims.Username != "blokhinnv" {
+		t.Errorf("expected username to be \"blokhinnv\", but got %q", claims.Username)
+	}
+	if _, err := ParseJWTToken(tok, signingKey); err == nil {
+		t.Errorf("expected error while parsing MFA token as an access token, but got no error")
+	}
+	access, _, err := NewAccessToken("blokhinnv", "session", signingKey, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error while generating JWT token: %v", err)
+	}
+	if _, err := ParseMFAToken(access, signingKey); err == nil {
+		t.Errorf("expected error while parsing an access token as MFA token, but got no error")
+	}
+}
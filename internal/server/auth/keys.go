@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// The algorithms of the access tokens. HS256 signs and verifies the tokens
+// with the same shared secret and is kept as the legacy mode.
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+	AlgorithmHS256 = "HS256"
+)
+
+// ErrUnknownAlgorithm is returned for the algorithms other than EdDSA, RS256 and HS256.
+var ErrUnknownAlgorithm = errors.New("unknown signing algorithm")
+
+// verificationKey is a key which verifies the tokens signed with the method.
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    any
+}
+
+// KeySet signs the access tokens with the active key and verifies them with any
+// of its keys, so the tokens signed with the previous key are accepted while
+// the keys are rolled over. The asymmetric tokens carry the id of their key
+// in the kid header. The challenge tokens of the second factor and the nonces
+// of the devices are signed with the secrets derived from the active key,
+// so they are never accepted as access tokens.
+type KeySet struct {
+	method     jwt.SigningMethod
+	kid        string
+	signingKey any
+	keys       map[string]verificationKey
+	mfaKey     []byte
+	nonceKey   []byte
+}
+
+// NewHMACKeySet creates the legacy key set which signs and verifies
+// the tokens with the shared secret using HS256. The tokens have no kid.
+func NewHMACKeySet(secret []byte) *KeySet {
+	return &KeySet{
+		method:     jwt.SigningMethodHS256,
+		signingKey: secret,
+		keys: map[string]verificationKey{
+			"": {method: jwt.SigningMethodHS256, key: secret},
+		},
+		mfaKey:   mfaSigningKey(secret),
+		nonceKey: nonceSigningKey(secret),
+	}
+}
+
+// NewKeySet creates the key set which signs the tokens with the Ed25519
+// or the RSA private key. The tokens signed with the verification keys,
+// e.g. the previous signing keys, are accepted as well.
+func NewKeySet(signingKey crypto.Signer, verificationKeys ...crypto.PublicKey) (*KeySet, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(signingKey)
+	if err != nil {
+		return nil, err
+	}
+	s := &KeySet{
+		signingKey: signingKey,
+		keys:       make(map[string]verificationKey, len(verificationKeys)+1),
+		mfaKey:     mfaSigningKey(der),
+		nonceKey:   nonceSigningKey(der),
+	}
+	for i, public := range append([]crypto.PublicKey{signingKey.Public()}, verificationKeys...) {
+		method, err := publicKeyMethod(public)
+		if err != nil {
+			return nil, err
+		}
+		kid, err := KeyID(public)
+		if err != nil {
+			return nil, err
+		}
+		s.keys[kid] = verificationKey{method: method, key: public}
+		if i == 0 {
+			s.method, s.kid = method, kid
+		}
+	}
+	return s, nil
+}
+
+// publicKeyMethod returns the signing method of the public key.
+func publicKeyMethod(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := public.(type) {
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	case *rsa.PublicKey:
+		if key.Size() < 256 {
+			return nil, fmt.Errorf("RSA key is too short: %d bits", key.N.BitLen())
+		}
+		return jwt.SigningMethodRS256, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrUnknownAlgorithm, public)
+	}
+}
+
+// Algorithm returns the algorithm of the active key.
+func (s *KeySet) Algorithm() string {
+	return s.method.Alg()
+}
+
+// KeyID returns the id of the active key or an empty string in the legacy mode.
+func (s *KeySet) KeyID() string {
+	return s.kid
+}
+
+// NewAccessToken generates the access token of the session started on the device
+// signed with the active key. The token gets a random id (jti), so it can be revoked
+// before it expires. The claims of the token are returned along with it.
+func (s *KeySet) NewAccessToken(
+	username string,
+	sessionID string,
+	deviceID string,
+	expireDuration time.Duration,
+) (string, *Claims, error) {
+	claims := &Claims{Username: username, SessionID: sessionID, DeviceID: deviceID}
+	return newToken(s.method, s.kid, s.signingKey, claims, expireDuration)
+}
+
+// Parse validates the token with the key of its kid and returns its claims.
+func (s *KeySet) Parse(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id: %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.key, nil
+	})
+}
+
+// NewMFAToken generates the short-lived challenge token of the login which
+// waits for the second factor.
+func (s *KeySet) NewMFAToken(username string, expireDuration time.Duration) (string, *Claims, error) {
+	return newToken(jwt.SigningMethodHS256, "", s.mfaKey, &Claims{Username: username}, expireDuration)
+}
+
+// ParseMFAToken validates the challenge token issued by NewMFAToken and returns its claims.
+func (s *KeySet) ParseMFAToken(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.mfaKey, nil
+	})
+}
+
+// NewDeviceNonce generates the short-lived nonce which the client signs with
+// the private key of its device to prove that it holds the key. The nonce
+// belongs to no user; its id (jti) lets the nonce be used only once.
+func (s *KeySet) NewDeviceNonce(expireDuration time.Duration) (string, *Claims, error) {
+	return newToken(jwt.SigningMethodHS256, "", s.nonceKey, &Claims{}, expireDuration)
+}
+
+// ParseDeviceNonce validates the nonce issued by NewDeviceNonce and returns its claims.
+func (s *KeySet) ParseDeviceNonce(tokenString string) (*Claims, error) {
+	claims, err := parseClaims(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.nonceKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, errors.New("no id in the nonce")
+	}
+	return claims, nil
+}
+
+// JWK is the public key in the JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv,omitempty"` // Crv and X are set for the Ed25519 keys.
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"` // N and E are set for the RSA keys.
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS is the JSON Web Key Set of the public keys which verify the access tokens.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set, the active key first.
+// The set of the legacy mode is empty, since its secret can't be published.
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	kids := make([]string, 0, len(s.keys))
+	for kid := range s.keys {
+		if kid != "" {
+			kids = append(kids, kid)
+		}
+	}
+	sort.Slice(kids, func(i, j int) bool {
+		if kids[i] == s.kid || kids[j] == s.kid {
+			return kids[i] == s.kid
+		}
+		return kids[i] < kids[j]
+	})
+	for _, kid := range kids {
+		jwk, err := publicJWK(s.keys[kid].key)
+		if err != nil {
+			continue
+		}
+		jwk.Use, jwk.Alg, jwk.Kid = "sig", s.keys[kid].method.Alg(), kid
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// publicJWK returns the members of the public key which identify it.
+func publicJWK(public crypto.PublicKey) (JWK, error) {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch key := public.(type) {
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: encode(key)}, nil
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", N: encode(key.N.Bytes()), E: encode(big.NewInt(int64(key.E)).Bytes())}, nil
+	default:
+		return JWK{}, fmt.Errorf("%w: unsupported key type %T", ErrUnknownAlgorithm, public)
+	}
+}
+
+// KeyID returns the JWK thumbprint of the public key (RFC 7638),
+// which is used as the kid of the tokens signed with the key.
+func KeyID(public crypto.PublicKey) (string, error) {
+	jwk, err := publicJWK(public)
+	if err != nil {
+		return "", err
+	}
+	// the required members in the lexicographic order
+	var members any
+	if jwk.Kty == "OKP" {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	} else {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
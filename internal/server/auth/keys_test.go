@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEd25519KeySet creates the key set with a new Ed25519 key.
+func newEd25519KeySet(t *testing.T) (*KeySet, ed25519.PrivateKey) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys, err := NewKeySet(key)
+	require.NoError(t, err)
+	return keys, key
+}
+
+func TestKeyID(t *testing.T) {
+	// the example of RFC 8037, appendix A.3
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	require.NoError(t, err)
+	kid, err := KeyID(ed25519.PublicKey(x))
+	require.NoError(t, err)
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", kid)
+
+	_, err = KeyID("key")
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
+
+func TestKeySet_AccessToken(t *testing.T) {
+	keys, _ := newEd25519KeySet(t)
+	assert.Equal(t, AlgorithmEdDSA, keys.Algorithm())
+	tok, claims, err := keys.NewAccessToken("blokhinnv", "session", "device", time.Minute)
+	require.NoError(t, err)
+
+	token, _, err := jwt.NewParser().ParseUnverified(tok, &Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, keys.KeyID(), token.Header["kid"])
+	assert.Equal(t, "EdDSA", token.Header["alg"])
+
+	parsed, err := keys.Parse(tok)
+	require.NoError(t, err)
+	assert.Equal(t, claims.ID, parsed.ID)
+	assert.Equal(t, "session", parsed.SessionID)
+	assert.Equal(t, "device", parsed.DeviceID)
+
+	// the tokens of the unknown keys are rejected
+	another, _ := newEd25519KeySet(t)
+	_, err = another.Parse(tok)
+	assert.Error(t, err)
+
+	expired, _, err := keys.NewAccessToken("blokhinnv", "session", "", -time.Minute)
+	require.NoError(t, err)
+	_, err = keys.Parse(expired)
+	assert.Error(t, err)
+}
+
+func TestKeySet_AlgorithmConfusion(t *testing.T) {
+	keys, key := newEd25519KeySet(t)
+	public := []byte(key.Public().(ed25519.PublicKey))
+
+	// the HS256 tokens signed with the public key are rejected with and without the kid
+	for _, kid := range []string{keys.KeyID(), ""} {
+		tok, _, err := newToken(jwt.SigningMethodHS256, kid, public, &Claims{Username: "blokhinnv"}, time.Minute)
+		require.NoError(t, err)
+		_, err = keys.Parse(tok)
+		assert.Error(t, err)
+	}
+	// and so are the legacy tokens
+	tok, _, err := NewAccessToken("blokhinnv", "session", []byte("practicum"), time.Minute)
+	require.NoError(t, err)
+	_, err = keys.Parse(tok)
+	assert.Error(t, err)
+}
+
+func TestKeySet_Rollover(t *testing.T) {
+	old, oldKey := newEd25519KeySet(t)
+	oldToken, _, err := old.NewAccessToken("blokhinnv", "session", "", time.Minute)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keys, err := NewKeySet(rsaKey, oldKey.Public())
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmRS256, keys.Algorithm())
+
+	claims, err := keys.Parse(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, "blokhinnv", claims.Username)
+	rsaToken, _, err := keys.NewAccessToken("blokhinnv", "session", "", time.Minute)
+	require.NoError(t, err)
+	_, err = keys.Parse(rsaToken)
+	require.NoError(t, err)
+	_, err = old.Parse(rsaToken)
+	assert.Error(t, err)
+
+	// the active key goes first
+	jwks := keys.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: keys.KeyID(),
+		N:   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		E:   "AQAB",
+	}, jwks.Keys[0])
+	assert.Equal(t, JWK{
+		Kty: "OKP",
+		Use: "sig",
+		Alg: "EdDSA",
+		Kid: old.KeyID(),
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(oldKey.Public().(ed25519.PublicKey)),
+	}, jwks.Keys[1])
+}
+
+func TestNewKeySet_ShortRSAKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = NewKeySet(key)
+	assert.Error(t, err)
+}
+
+func TestKeySet_MFAToken(t *testing.T) {
+	keys, _ := newEd25519KeySet(t)
+	tok, _, err := keys.NewMFAToken("blokhinnv", time.Minute)
+	require.NoError(t, err)
+	claims, err := keys.ParseMFAToken(tok)
+	require.NoError(t, err)
+	assert.Equal(t, "blokhinnv", claims.Username)
+	_, err = keys.Parse(tok)
+	assert.Error(t, err)
+
+	access, _, err := keys.NewAccessToken("blokhinnv", "session", "", time.Minute)
+	require.NoError(t, err)
+	_, err = keys.ParseMFAToken(access)
+	assert.Error(t, err)
+
+	another, _ := newEd25519KeySet(t)
+	_, err = another.ParseMFAToken(tok)
+	assert.Error(t, err)
+}
+
+func TestKeySet_DeviceNonce(t *testing.T) {
+	keys, _ := newEd25519KeySet(t)
+	nonce, issued, err := keys.NewDeviceNonce(time.Minute)
+	require.NoError(t, err)
+	claims, err := keys.ParseDeviceNonce(nonce)
+	require.NoError(t, err)
+	assert.Equal(t, issued.ID, claims.ID)
+	_, err = keys.Parse(nonce)
+	assert.Error(t, err)
+	_, err = keys.ParseMFAToken(nonce)
+	assert.Error(t, err)
+
+	challenge, _, err := keys.NewMFAToken("blokhinnv", time.Minute)
+	require.NoError(t, err)
+	_, err = keys.ParseDeviceNonce(challenge)
+	assert.Error(t, err)
+
+	expired, _, err := keys.NewDeviceNonce(-time.Minute)
+	require.NoError(t, err)
+	_, err = keys.ParseDeviceNonce(expired)
+	assert.Error(t, err)
+
+	another, _ := newEd25519KeySet(t)
+	_, err = another.ParseDeviceNonce(nonce)
+	assert.Error(t, err)
+}
+
+func TestHMACKeySet(t *testing.T) {
+	keys := NewHMACKeySet([]byte("mySecretKey"))
+	assert.Equal(t, AlgorithmHS256, keys.Algorithm())
+	assert.Empty(t, keys.KeyID())
+	assert.Empty(t, keys.JWKS().Keys)
+
+	// the tokens are compatible with the legacy helpers
+	tok, _, err := keys.NewAccessToken("blokhinnv", "session", "", time.Minute)
+	require.NoError(t, err)
+	claims, err := ParseJWTToken(tok, []byte("mySecretKey"))
+	require.NoError(t, err)
+	assert.Equal(t, "session", claims.SessionID)
+	tok, err = GenerateJWTToken("blokhinnv", []byte("mySecretKey"), time.Minute)
+	require.NoError(t, err)
+	_, err = keys.Parse(tok)
+	require.NoError(t, err)
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// rsaKeyBits is the size of the generated RSA keys.
+const rsaKeyBits = 3072
+
+// GenerateSigningKey generates a new private key of the algorithm.
+func GenerateSigningKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	case AlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownAlgorithm, algorithm)
+	}
+}
+
+// SaveSigningKey writes the private key to a new PKCS #8 PEM file readable only by its owner.
+// An existing file is never overwritten.
+func SaveSigningKey(path string, key crypto.Signer) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readPEM returns the first PEM block of the file.
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %v", path)
+	}
+	return block, nil
+}
+
+// parsePrivateKey parses the PKCS #8 or the PKCS #1 private key.
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrUnknownAlgorithm, key)
+	}
+	return signer, nil
+}
+
+// LoadSigningKey reads the private key from the PEM file.
+func LoadSigningKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, fmt.Errorf("bad private key in %v: %w", path, err)
+	}
+	return key, nil
+}
+
+// LoadOrGenerateSigningKey reads the private key of the algorithm from the PEM file.
+// If there is no such file, a new key is generated and saved to it.
+// Returns true if the key has been generated.
+func LoadOrGenerateSigningKey(path, algorithm string) (crypto.Signer, bool, error) {
+	key, err := LoadSigningKey(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if key, err = GenerateSigningKey(algorithm); err != nil {
+			return nil, false, err
+		}
+		if err := SaveSigningKey(path, key); err != nil {
+			return nil, false, err
+		}
+		return key, true, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	method, err := publicKeyMethod(key.Public())
+	if err != nil {
+		return nil, false, err
+	}
+	if method.Alg() != algorithm {
+		return nil, false, fmt.Errorf("key in %v is for %v, not %v", path, method.Alg(), algorithm)
+	}
+	return key, false, nil
+}
+
+// LoadVerificationKey reads the public key from the PEM file. The file may
+// also keep a private key, e.g. the previous signing key, whose public key is returned.
+func LoadVerificationKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != "PUBLIC KEY" {
+		key, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("bad key in %v: %w", path, err)
+		}
+		return key.Public(), nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("bad public key in %v: %w", path, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrGenerateSigningKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	key, generated, err := LoadOrGenerateSigningKey(path, AlgorithmEdDSA)
+	require.NoError(t, err)
+	assert.True(t, generated)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	loaded, generated, err := LoadOrGenerateSigningKey(path, AlgorithmEdDSA)
+	require.NoError(t, err)
+	assert.False(t, generated)
+	assert.Equal(t, key, loaded)
+
+	// the key of another algorithm is not replaced
+	_, _, err = LoadOrGenerateSigningKey(path, AlgorithmRS256)
+	assert.Error(t, err)
+	_, _, err = LoadOrGenerateSigningKey(filepath.Join(t.TempDir(), "key.pem"), "none")
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
+
+func TestGenerateSigningKey_RS256(t *testing.T) {
+	key, err := GenerateSigningKey(AlgorithmRS256)
+	require.NoError(t, err)
+	assert.Equal(t, rsaKeyBits, key.(*rsa.PrivateKey).N.BitLen())
+}
+
+func TestLoadVerificationKey(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// the private key of PKCS #8
+	private := filepath.Join(dir, "private.pem")
+	require.NoError(t, SaveSigningKey(private, edKey))
+	assert.Error(t, SaveSigningKey(private, edKey))
+	public, err := LoadVerificationKey(private)
+	require.NoError(t, err)
+	assert.Equal(t, edKey.Public(), public)
+
+	// the public key of PKIX
+	der, err := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	require.NoError(t, err)
+	pkix := filepath.Join(dir, "public.pem")
+	writePEM(t, pkix, "PUBLIC KEY", der)
+	public, err = LoadVerificationKey(pkix)
+	require.NoError(t, err)
+	assert.Equal(t, rsaKey.Public(), public)
+
+	// the private key of PKCS #1
+	pkcs1 := filepath.Join(dir, "rsa.pem")
+	writePEM(t, pkcs1, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	signer, err := LoadSigningKey(pkcs1)
+	require.NoError(t, err)
+	assert.Equal(t, rsaKey.Public(), signer.Public())
+
+	bad := filepath.Join(dir, "bad.pem")
+	require.NoError(t, os.WriteFile(bad, []byte("not a key"), 0o600))
+	_, err = LoadVerificationKey(bad)
+	assert.Error(t, err)
+	writePEM(t, filepath.Join(dir, "cert.pem"), "CERTIFICATE", []byte("cert"))
+	_, err = LoadVerificationKey(filepath.Join(dir, "cert.pem"))
+	assert.Error(t, err)
+}
+
+// writePEM writes the PEM block to the file.
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRandomToken(t *testing.T) {
+	tok1, err := NewRandomToken(32)
+	require.NoError(t, err)
+	tok2, err := NewRandomToken(32)
+	require.NoError(t, err)
+	assert.Len(t, tok1, 43)
+	assert.NotEqual(t, tok1, tok2)
+}
+
+func TestHashToken(t *testing.T) {
+	assert.Equal(t, HashToken("token"), HashToken("token"))
+	assert.NotEqual(t, HashToken("token"), HashToken("another token"))
+	assert.Len(t, HashToken("token"), 64)
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/config"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
+)
+
+// newBackupService connects to the DB and creates the backup service.
+// The returned function disconnects from the DB.
+func newBackupService(
+	ctx context.Context,
+	cfg *config.ServerConfig,
+	snapshot bool,
+) (service.BackupService, func(), error) {
+	keyring, err := cfg.Keyring()
+	if err != nil {
+		return nil, nil, err
+	}
+	index, err := cfg.BlindIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		return nil, nil, err
+	}
+	backupService := service.NewBackupService(client.Database(cfg.DBName), keyring, index, snapshot)
+	return backupService, func() { client.Disconnect(context.Background()) }, nil
+}
+
+// RunBackup writes the encrypted backup of the user's data or of the whole DB
+// if the username is empty to the file. The file is replaced only when
+// the backup is complete.
+func RunBackup(cfg *config.ServerConfig, file, username, password string, snapshot bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	backupService, disconnect, err := newBackupService(ctx, cfg, snapshot)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	manifest, err := backupService.Backup(ctx, tmp, username, password)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return err
+	}
+	for _, section := range manifest.Sections {
+		log.Printf("%v: %d documents saved", section.Name, section.Documents)
+	}
+	log.Printf("The backup is saved to %v", file)
+	return nil
+}
+
+// RunRestore checks the backup from the file and restores its data
+// encrypted with the active key of the server.
+func RunRestore(cfg *config.ServerConfig, file, password string, opts service.RestoreOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	backupService, disconnect, err := newBackupService(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	sections, err := backupService.Restore(ctx, f, info.Size(), password, opts)
+	for _, section := range sections {
+		if opts.DryRun {
+			log.Printf("%v: %d documents checked", section.Name, section.Documents)
+		} else {
+			log.Printf("%v: %d documents restored", section.Name, section.Documents)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if opts.DryRun {
+		log.Println("The backup is intact, nothing is restored")
+	} else {
+		log.Println("The backup is restored")
+	}
+	return nil
+}
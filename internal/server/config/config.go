@@ -1,14 +1,24 @@
 // Package config provides a configuration structure for a server application.
 package config
 
-import "github.com/caarlos0/env/v6"
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/caarlos0/env/v6"
+)
+
+// redacted replaces the secrets in the printed config.
+const redacted = "xxxxx"
 
 // ServerConfig is a configuration struct for a server application that includes
-// both database and JWT configurations.
+// the database, JWT, network and login lockout configurations.
 type ServerConfig struct {
 	dbConfig
 	jwtConfig
 	netConfig
+	lockoutConfig
 }
 
 // NewServerConfig creates a new ServerConfig object and populates its fields
@@ -26,5 +36,35 @@ func NewServerConfig() (*ServerConfig, error) {
 	if err := env.Parse(&cfg.jwtConfig); err != nil {
 		return nil, err
 	}
+	if err := env.Parse(&cfg.lockoutConfig); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
+
+// String returns the config with the keys and the password of the DB
+// redacted, so the config can be logged.
+func (c ServerConfig) String() string {
+	type plain ServerConfig
+	cfg := plain(c)
+	cfg.MongoURI = redactURI(c.MongoURI)
+	cfg.EncryptionKey = redacted
+	cfg.OldEncryptionKeys = make([]string, len(c.OldEncryptionKeys))
+	for i, pair := range c.OldEncryptionKeys {
+		id, _, _ := strings.Cut(pair, ":")
+		cfg.OldEncryptionKeys[i] = id + ":" + redacted
+	}
+	cfg.SearchIndexKey = redacted
+	cfg.SigningKey = redacted
+	return fmt.Sprintf("%+v", cfg)
+}
+
+// redactURI returns the URI with the password redacted.
+// The URI which can't be parsed is redacted as a whole.
+func redactURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return redacted
+	}
+	return u.Redacted()
+}
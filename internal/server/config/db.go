@@ -1,8 +1,52 @@
 package config
 
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
 // dbConfig is a part of the config which contains setting for the DB.
+// The values are encrypted with EncryptionKey and saved with EncryptionKeyID.
+// OldEncryptionKeys ("id1:key1,id2:key2") are used to read the values
+// written with the previous keys until they are re-encrypted.
+// The previous revisions of the records are kept for HistoryRetention;
+// zero retention keeps them forever. The deleted records are purged from
+// the trash after TrashTTL, which is checked every TrashPurgeInterval;
+// zero TTL keeps them in the trash forever. The blob uploads which are not
+// complete after UploadTTL are deleted at the same interval; zero TTL keeps
+// them forever. The search tokens of the records
+// are HMACs with SearchIndexKey; the records have to be reindexed when it is changed.
 type dbConfig struct {
-	MongoURI      string `env:"GOPHKEEPER_DB_URI"            envDefault:"mongodb://localhost:27017"`
-	DBName        string `env:"GOPHKEEPER_DB_NAME"           envDefault:"gophkeeper"`
-	EncryptionKey string `env:"GOPHKEEPER_DB_ENCRYPTION_KEY" envDefault:"gophkeeper"`
+	MongoURI           string        `env:"GOPHKEEPER_DB_URI"                 envDefault:"mongodb://localhost:27017"`
+	DBName             string        `env:"GOPHKEEPER_DB_NAME"                envDefault:"gophkeeper"`
+	EncryptionKey      string        `env:"GOPHKEEPER_DB_ENCRYPTION_KEY"      envDefault:"gophkeeper"`
+	EncryptionKeyID    string        `env:"GOPHKEEPER_DB_ENCRYPTION_KEY_ID"   envDefault:"default"`
+	OldEncryptionKeys  []string      `env:"GOPHKEEPER_DB_OLD_ENCRYPTION_KEYS"`
+	HistoryRetention   time.Duration `env:"GOPHKEEPER_HISTORY_RETENTION"      envDefault:"720h"`
+	TrashTTL           time.Duration `env:"GOPHKEEPER_TRASH_TTL"              envDefault:"720h"`
+	TrashPurgeInterval time.Duration `env:"GOPHKEEPER_TRASH_PURGE_INTERVAL"   envDefault:"1h"`
+	UploadTTL          time.Duration `env:"GOPHKEEPER_UPLOAD_TTL"             envDefault:"24h"`
+	SearchIndexKey     string        `env:"GOPHKEEPER_SEARCH_INDEX_KEY"       envDefault:"gophkeeper-search"`
+}
+
+// Keyring returns the keyring with the active and the old encryption keys.
+func (c *dbConfig) Keyring() (*encrypt.Keyring, error) {
+	keys := make(map[string]string, len(c.OldEncryptionKeys)+1)
+	for _, pair := range c.OldEncryptionKeys {
+		id, key, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("bad old encryption key: expected id:key")
+		}
+		keys[id] = key
+	}
+	keys[c.EncryptionKeyID] = c.EncryptionKey
+	return encrypt.NewKeyring(c.EncryptionKeyID, keys)
+}
+
+// BlindIndex returns the blind index of the searchable terms of the records.
+func (c *dbConfig) BlindIndex() (*encrypt.BlindIndex, error) {
+	return encrypt.NewBlindIndex(c.SearchIndexKey)
 }
@@ -1,9 +1,47 @@
 package config
 
-import "time"
+import (
+	"crypto"
+	"time"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+)
 
 // jwtConfig is a part of the config which contains setting for the JWT tokens.
+// The access tokens are signed with the private key of JWTAlgorithm (EdDSA or RS256)
+// from JWTKeyFile, which is generated on the first start if there is no such file.
+// The tokens signed with the keys of JWTVerificationKeyFiles ("old.pem,next.pem")
+// are accepted as well, so the keys can be rolled over. The HS256 algorithm
+// is the legacy mode which signs the tokens with the shared SigningKey.
 type jwtConfig struct {
-	SigningKey     string        `env:"GOPHKEEPER_JWT_SIGNING_KEY"     envDefault:"practicum"`
-	ExpireDuration time.Duration `env:"GOPHKEEPER_JWT_EXPIRE_DURATION" envDefault:"1h"`
+	JWTAlgorithm            string        `env:"GOPHKEEPER_JWT_ALGORITHM"         envDefault:"EdDSA"`
+	JWTKeyFile              string        `env:"GOPHKEEPER_JWT_KEY_FILE"          envDefault:"jwt-signing-key.pem"`
+	JWTVerificationKeyFiles []string      `env:"GOPHKEEPER_JWT_VERIFICATION_KEYS"`
+	SigningKey              string        `env:"GOPHKEEPER_JWT_SIGNING_KEY"       envDefault:"practicum"`
+	ExpireDuration          time.Duration `env:"GOPHKEEPER_JWT_EXPIRE_DURATION"   envDefault:"15m"`
+	// RefreshExpireDuration is the lifetime of a session since its last refresh.
+	RefreshExpireDuration time.Duration `env:"GOPHKEEPER_JWT_REFRESH_EXPIRE_DURATION" envDefault:"720h"`
+	// MFAExpireDuration is the lifetime of the challenge token of the login with the second factor.
+	MFAExpireDuration time.Duration `env:"GOPHKEEPER_MFA_EXPIRE_DURATION" envDefault:"5m"`
+}
+
+// JWTKeys returns the keys which sign and verify the access tokens.
+// The signing key is generated and saved to JWTKeyFile if there is no such file.
+func (c *jwtConfig) JWTKeys() (*auth.KeySet, error) {
+	if c.JWTAlgorithm == auth.AlgorithmHS256 {
+		return auth.NewHMACKeySet([]byte(c.SigningKey)), nil
+	}
+	key, _, err := auth.LoadOrGenerateSigningKey(c.JWTKeyFile, c.JWTAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	verificationKeys := make([]crypto.PublicKey, 0, len(c.JWTVerificationKeyFiles))
+	for _, path := range c.JWTVerificationKeyFiles {
+		public, err := auth.LoadVerificationKey(path)
+		if err != nil {
+			return nil, err
+		}
+		verificationKeys = append(verificationKeys, public)
+	}
+	return auth.NewKeySet(key, verificationKeys...)
 }
@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// lockoutConfig is a part of the config which contains setting for the protection
+// of the login against brute force. After LoginFreeAttempts failures of a username
+// (or LoginIPFreeAttempts failures from an address) every next failure locks
+// the logins for LoginBackoff, which doubles with every failure up to LoginMaxLockout;
+// zero LoginMaxLockout disables the lockout. The failures are forgotten
+// after LoginAttemptsWindow without them. The security events of the logins
+// are kept for SecurityEventsRetention; zero retention keeps them forever.
+type lockoutConfig struct {
+	LoginFreeAttempts       int           `env:"GOPHKEEPER_LOGIN_FREE_ATTEMPTS"       envDefault:"5"`
+	LoginIPFreeAttempts     int           `env:"GOPHKEEPER_LOGIN_IP_FREE_ATTEMPTS"    envDefault:"20"`
+	LoginBackoff            time.Duration `env:"GOPHKEEPER_LOGIN_BACKOFF"             envDefault:"1s"`
+	LoginMaxLockout         time.Duration `env:"GOPHKEEPER_LOGIN_MAX_LOCKOUT"         envDefault:"15m"`
+	LoginAttemptsWindow     time.Duration `env:"GOPHKEEPER_LOGIN_ATTEMPTS_WINDOW"     envDefault:"1h"`
+	SecurityEventsRetention time.Duration `env:"GOPHKEEPER_SECURITY_EVENTS_RETENTION" envDefault:"2160h"`
+}
@@ -1,9 +1,14 @@
 package config
 
 // netConfig is a part of the config which contains setting for network.
+// The address of the client is taken from the X-Forwarded-For header only
+// if the request comes from one of TrustedProxies ("10.0.0.1,10.1.0.0/16");
+// no proxies are trusted by default.
 type netConfig struct {
-	Port     string `env:"GOPHKEEPER_SERVER_PORT" envDefault:"8080"`
-	UseHTTPS bool   `env:"GOPHKEEPER_USE_HTTPS"   envDefault:"true"`
-	CertFile string `env:"GOPHKEEPER_CERT_FILE"`
-	KeyFile  string `env:"GOPHKEEPER_KEY_FILE"`
+	Port           string   `env:"GOPHKEEPER_SERVER_PORT"     envDefault:"8080"`
+	GRPCPort       string   `env:"GOPHKEEPER_GRPC_PORT"       envDefault:"8081"`
+	UseHTTPS       bool     `env:"GOPHKEEPER_USE_HTTPS"       envDefault:"true"`
+	CertFile       string   `env:"GOPHKEEPER_CERT_FILE"`
+	KeyFile        string   `env:"GOPHKEEPER_KEY_FILE"`
+	TrustedProxies []string `env:"GOPHKEEPER_TRUSTED_PROXIES"`
 }
@@ -6,12 +6,13 @@ package controller
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
 	"github.com/blokhinnv/gophkeeper/internal/server/service"
 )
@@ -20,8 +21,15 @@ import (
 type AuthController interface {
 	// Register saves a new user to the database based on the data provided in the request.
 	Register(*gin.Context)
-	// Login checks users' credentials and returns a JWT token.
+	// Login checks users' credentials and returns the tokens of a new session.
 	Login(*gin.Context)
+	// LoginMFA exchanges the challenge token of the login and the code
+	// of the second factor for the tokens of a new session.
+	LoginMFA(*gin.Context)
+	// ChangePassword replaces the password of the user and finishes all the sessions.
+	ChangePassword(*gin.Context)
+	// DeleteAccount deletes the user with all the data.
+	DeleteAccount(*gin.Context)
 }
 
 // authController implements AuthController interface.
@@ -68,33 +76,185 @@ func (c *authController) Register(ctx *gin.Context) {
 // Login godoc
 //
 //	@Summary Logs in a user
-//	@Description Logs in a user with the provided username and password
-//	@Produce plain
+//	@Description Logs in a user with the provided username and password. The short-lived access token is used in the Authorization header, and the refresh token is exchanged for the new tokens at /api/user/refresh. The device registered with the login keeps its id between the logins with the same public key; the tokens of the session are bound to it. The device is registered only with the proof of its key: a nonce of /api/user/devices/nonce signed with the private key of the device. If the user has the second factor enabled, only mfa_required and the short-lived mfa_token are returned; the token is exchanged for the tokens of the session at /api/user/login/mfa. After too many failed logins of the username or from the address the logins are rejected until the time in the Retry-After header passes.
+//	@Produce json
 //	@ID Login
 //	@Tags Authy
-//	@Param	credentials body	models.UserCredentials	true	"Credentials"
-//	@Success 200 {string}	string	"some JWT token"
+//	@Param	credentials body	models.LoginRequest	true	"Credentials and device"
+//	@Success 200 {object}	models.TokenPair	"Tokens of the new session or the challenge token"
 //	@Failure 400 {string}	string	"no username provided"
-//	@Failure 401 {string}	string	"username or password is incorrect: testuser/qwerty"
+//	@Failure 401 {string}	string	"username or password is incorrect or device proof is invalid"
+//	@Failure 429 {string}	string	"too many failed logins, try again in 30s"
+//	@Header 429 {integer}	Retry-After	"Seconds until the lockout ends"
+//	@Failure 500 {string}	string	"Server error"
 //	@Router /api/user/login [put]
 func (c *authController) Login(ctx *gin.Context) {
-	var user models.UserCredentials
-	if err := ctx.ShouldBindJSON(&user); err != nil {
+	var req models.LoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	tokens, err := c.service.Login(req.Username, req.Password, ctx.ClientIP(), req.Device)
+	if lockedError(ctx, err) {
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrBadCredentials) ||
+			errors.Is(err, srvErrors.ErrBadDeviceProof) {
+			status = http.StatusUnauthorized
+		} else if errors.Is(err, srvErrors.ErrBadDevice) {
+			status = http.StatusBadRequest
+		}
+		ctx.String(status, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// lockedError responds with 429 and the Retry-After header if the login is
+// rejected because of the lockout. Returns false for the other errors.
+func lockedError(ctx *gin.Context, err error) bool {
+	var locked *srvErrors.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	ctx.Header("Retry-After", strconv.FormatInt(locked.RetryAfterSeconds(), 10))
+	ctx.String(http.StatusTooManyRequests, locked.Error())
+	return true
+}
+
+// LoginMFA godoc
+//
+//	@Summary Completes the login with the second factor
+//	@Description Exchanges the challenge token returned by /api/user/login and the current one-time code of the authenticator app or one of the recovery codes for the tokens of a new session. The device is registered like the one of /api/user/login. Every code can be used only once. The wrong codes are counted as the failed logins.
+//	@Accept json
+//	@Produce json
+//	@ID LoginMFA
+//	@Tags Authy
+//	@Param	request body	models.MFALoginRequest	true	"Challenge token and code"
+//	@Success 200 {object}	models.TokenPair	"Tokens of the new session"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"one-time code is incorrect"
+//	@Failure 429 {string}	string	"too many failed logins, try again in 30s"
+//	@Header 429 {integer}	Retry-After	"Seconds until the lockout ends"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/login/mfa [put]
+func (c *authController) LoginMFA(ctx *gin.Context) {
+	var req models.MFALoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	tokens, err := c.service.LoginMFA(req.MFAToken, req.Code, ctx.ClientIP(), req.Device)
+	if lockedError(ctx, err) {
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrBadMFAToken) ||
+			errors.Is(err, srvErrors.ErrBadMFACode) ||
+			errors.Is(err, srvErrors.ErrBadDeviceProof) {
+			status = http.StatusUnauthorized
+		} else if errors.Is(err, srvErrors.ErrBadDevice) {
+			status = http.StatusBadRequest
+		}
+		ctx.String(status, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// passwordErrorStatus returns the HTTP status of the error of the password check.
+func passwordErrorStatus(err error) int {
+	if errors.Is(err, srvErrors.ErrBadCredentials) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// ChangePassword godoc
+//
+//	@Summary Change the password
+//	@Security bearerAuth
+//	@Description Checks the current password and replaces it with the new one. All the sessions of the user are finished and their access tokens are revoked, the tokens of a new session on the device of the request are returned. The wrong passwords are counted as the failed logins.
+//	@Accept json
+//	@Produce json
+//	@ID ChangePassword
+//	@Tags Authy
+//	@Param	request	body	models.PasswordChangeRequest	true	"Current and new passwords"
+//	@Success 200 {object}	models.TokenPair	"Tokens of the new session"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 403 {string}	string	"username or password is incorrect"
+//	@Failure 429 {string}	string	"too many failed logins, try again in 30s"
+//	@Header 429 {integer}	Retry-After	"Seconds until the lockout ends"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/password [post]
+func (c *authController) ChangePassword(ctx *gin.Context) {
+	claims := middleware.ClaimsFromGinContext(ctx)
+	if claims == nil {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	var req models.PasswordChangeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	tokens, err := c.service.ChangePassword(
+		claims.Username,
+		req.OldPassword,
+		req.NewPassword,
+		ctx.ClientIP(),
+		claims.DeviceID,
+	)
+	if lockedError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.String(passwordErrorStatus(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// DeleteAccount godoc
+//
+//	@Summary Delete the account
+//	@Security bearerAuth
+//	@Description Checks the password, finishes all the sessions of the user and deletes the user with the records of all the collections, their history and the files. The deletion can't be undone. The wrong passwords are counted as the failed logins.
+//	@Accept json
+//	@Produce plain
+//	@ID DeleteAccount
+//	@Tags Authy
+//	@Param	request	body	models.AccountDeleteRequest	true	"Password"
+//	@Success 200 {string}	string	"Account deleted"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 403 {string}	string	"username or password is incorrect"
+//	@Failure 429 {string}	string	"too many failed logins, try again in 30s"
+//	@Header 429 {integer}	Retry-After	"Seconds until the lockout ends"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user [delete]
+func (c *authController) DeleteAccount(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	var req models.AccountDeleteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
 	}
-	tok, err := c.service.Login(user.Username, user.Password)
+	err := c.service.DeleteAccount(username, req.Password, ctx.ClientIP())
+	if lockedError(ctx, err) {
+		return
+	}
 	if err != nil {
-		ctx.String(
-			http.StatusUnauthorized,
-			fmt.Sprintf(
-				"%v: %v %v",
-				srvErrors.ErrBadCredentials.Error(),
-				user.Username,
-				user.Password,
-			),
-		)
-		return
-	}
-	ctx.String(http.StatusOK, tok)
+		ctx.String(passwordErrorStatus(err), err.Error())
+		return
+	}
+	ctx.String(http.StatusOK, "Account deleted")
 }
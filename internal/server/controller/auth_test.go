@@ -6,12 +6,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
 	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
 	"github.com/blokhinnv/gophkeeper/internal/server/service/mock"
 )
 
@@ -104,9 +108,9 @@ func TestAuthController_Login(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
 		// test logging in with valid credentials
 		srvc.EXPECT().
-			Login(gomock.Eq("testuser"), gomock.Eq("testpassword")).
+			Login(gomock.Eq("testuser"), gomock.Eq("testpassword"), gomock.Any(), gomock.Nil()).
 			Times(1).
-			Return("some-token", nil)
+			Return(&models.TokenPair{AccessToken: "some-token", RefreshToken: "refresh"}, nil)
 
 		w := httptest.NewRecorder()
 		c, r := gin.CreateTestContext(w)
@@ -119,13 +123,14 @@ func TestAuthController_Login(t *testing.T) {
 		c.Request = req
 		ctrl.Login(c)
 		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"access_token":"some-token"`)
 	})
 	t.Run("duplicate", func(t *testing.T) {
 		// test logging in with invalid credentials
 		srvc.EXPECT().
-			Login(gomock.Eq("testuser"), gomock.Eq("wrongpassword")).
+			Login(gomock.Eq("testuser"), gomock.Eq("wrongpassword"), gomock.Any(), gomock.Nil()).
 			Times(1).
-			Return("", errors.ErrNoDocuments)
+			Return(nil, errors.ErrBadCredentials)
 		w := httptest.NewRecorder()
 		c, r := gin.CreateTestContext(w)
 		r.POST("/login", ctrl.Login)
@@ -137,6 +142,73 @@ func TestAuthController_Login(t *testing.T) {
 		c.Request = req
 		ctrl.Login(c)
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotContains(t, w.Body.String(), "wrongpassword")
+	})
+	t.Run("server_error", func(t *testing.T) {
+		srvc.EXPECT().
+			Login(gomock.Eq("testuser"), gomock.Eq("testpassword"), gomock.Any(), gomock.Nil()).
+			Return(nil, fmt.Errorf("server selection timeout"))
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(
+			http.MethodPut,
+			"/login",
+			bytes.NewBufferString(`{"username": "testuser", "password": "testpassword"}`),
+		)
+		ctrl.Login(c)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+	t.Run("locked", func(t *testing.T) {
+		srvc.EXPECT().
+			Login(gomock.Eq("testuser"), gomock.Eq("testpassword"), gomock.Eq("10.0.0.1"), gomock.Nil()).
+			Return(nil, &errors.LockedError{RetryAfter: 1500 * time.Millisecond})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(
+			http.MethodPut,
+			"/login",
+			bytes.NewBufferString(`{"username": "testuser", "password": "testpassword"}`),
+		)
+		c.Request.RemoteAddr = "10.0.0.1:54321"
+		ctrl.Login(c)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+		assert.NotContains(t, w.Body.String(), "testpassword")
+	})
+	t.Run("device", func(t *testing.T) {
+		device := &models.DeviceInfo{
+			Name:      "laptop",
+			Platform:  "linux/amd64",
+			PublicKey: "key",
+			Proof:     &models.DeviceProof{Nonce: "nonce", Signature: "signature"},
+		}
+		srvc.EXPECT().
+			Login("testuser", "testpassword", gomock.Any(), device).
+			Return(&models.TokenPair{AccessToken: "some-token", DeviceID: "device"}, nil)
+		srvc.EXPECT().
+			Login("testuser", "testpassword", gomock.Any(), device).
+			Return(nil, fmt.Errorf("%w: public key must be an Ed25519 key", errors.ErrBadDevice))
+		srvc.EXPECT().
+			Login("testuser", "testpassword", gomock.Any(), device).
+			Return(nil, errors.ErrBadDeviceProof)
+		body := `{"username": "testuser", "password": "testpassword",
+			"device": {"name": "laptop", "platform": "linux/amd64", "public_key": "key",
+			"proof": {"nonce": "nonce", "signature": "signature"}}}`
+		for _, tc := range []struct {
+			code int
+			body string
+		}{
+			{http.StatusOK, `"device_id":"device"`},
+			{http.StatusBadRequest, "bad device"},
+			{http.StatusUnauthorized, errors.ErrBadDeviceProof.Error()},
+		} {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPut, "/login", bytes.NewBufferString(body))
+			ctrl.Login(c)
+			assert.Equal(t, tc.code, w.Code)
+			assert.Contains(t, w.Body.String(), tc.body)
+		}
 	})
 	t.Run("invalid_data", func(t *testing.T) {
 		// test invalid JSON data
@@ -153,3 +225,140 @@ func TestAuthController_Login(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 }
+
+func TestAuthController_LoginMFA(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	srvc := mock.NewMockAuthService(mockCtrl)
+	ctrl := NewAuthController(srvc)
+	loginMFA := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPut, "/login/mfa", bytes.NewBufferString(body))
+		ctrl.LoginMFA(c)
+		return w
+	}
+	t.Run("ok", func(t *testing.T) {
+		srvc.EXPECT().
+			LoginMFA("challenge", "123456", gomock.Any(), gomock.Nil()).
+			Return(&models.TokenPair{AccessToken: "some-token", RefreshToken: "refresh"}, nil)
+		w := loginMFA(`{"mfa_token": "challenge", "code": "123456"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"access_token":"some-token"`)
+	})
+	t.Run("bad_code", func(t *testing.T) {
+		srvc.EXPECT().LoginMFA("challenge", "000000", gomock.Any(), gomock.Nil()).Return(nil, errors.ErrBadMFACode)
+		w := loginMFA(`{"mfa_token": "challenge", "code": "000000"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	t.Run("bad_token", func(t *testing.T) {
+		srvc.EXPECT().LoginMFA("expired", "123456", gomock.Any(), gomock.Nil()).Return(nil, errors.ErrBadMFAToken)
+		w := loginMFA(`{"mfa_token": "expired", "code": "123456"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	t.Run("bad_proof", func(t *testing.T) {
+		srvc.EXPECT().
+			LoginMFA("challenge", "123456", gomock.Any(), gomock.Not(gomock.Nil())).
+			Return(nil, errors.ErrBadDeviceProof)
+		w := loginMFA(`{"mfa_token": "challenge", "code": "123456",
+			"device": {"name": "laptop", "public_key": "key", "proof": {"nonce": "nonce"}}}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, errors.ErrBadDeviceProof.Error(), w.Body.String())
+	})
+	t.Run("invalid_data", func(t *testing.T) {
+		w := loginMFA(`{"mfa_token": "challenge"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAuthController_ChangePassword(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	srvc := mock.NewMockAuthService(mockCtrl)
+	ctrl := NewAuthController(srvc)
+	changePassword := func(username, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/user/password", bytes.NewBufferString(body))
+		if username != "" {
+			c.Set(middleware.ClaimsContextValue, &auth.Claims{Username: username, DeviceID: "device"})
+		}
+		ctrl.ChangePassword(c)
+		return w
+	}
+	t.Run("ok", func(t *testing.T) {
+		srvc.EXPECT().
+			ChangePassword("testuser", "old", "new", gomock.Any(), "device").
+			Return(&models.TokenPair{AccessToken: "some-token", RefreshToken: "refresh"}, nil)
+		w := changePassword("testuser", `{"old_password": "old", "new_password": "new"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"access_token":"some-token"`)
+	})
+	t.Run("wrong_password", func(t *testing.T) {
+		srvc.EXPECT().
+			ChangePassword("testuser", "wrong", "new", gomock.Any(), "device").
+			Return(nil, errors.ErrBadCredentials)
+		w := changePassword("testuser", `{"old_password": "wrong", "new_password": "new"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+	t.Run("locked", func(t *testing.T) {
+		srvc.EXPECT().
+			ChangePassword("testuser", "old", "new", gomock.Any(), "device").
+			Return(nil, &errors.LockedError{RetryAfter: time.Minute})
+		w := changePassword("testuser", `{"old_password": "old", "new_password": "new"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	})
+	t.Run("no_username", func(t *testing.T) {
+		w := changePassword("", `{"old_password": "old", "new_password": "new"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	t.Run("invalid_data", func(t *testing.T) {
+		w := changePassword("testuser", `{"old_password": "old"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAuthController_DeleteAccount(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	srvc := mock.NewMockAuthService(mockCtrl)
+	ctrl := NewAuthController(srvc)
+	deleteAccount := func(username, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/api/user", bytes.NewBufferString(body))
+		if username != "" {
+			c.Set(middleware.UsernameContextValue, username)
+		}
+		ctrl.DeleteAccount(c)
+		return w
+	}
+	t.Run("ok", func(t *testing.T) {
+		srvc.EXPECT().DeleteAccount("testuser", "password", gomock.Any()).Return(nil)
+		w := deleteAccount("testuser", `{"password": "password"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Account deleted", w.Body.String())
+	})
+	t.Run("wrong_password", func(t *testing.T) {
+		srvc.EXPECT().DeleteAccount("testuser", "wrong", gomock.Any()).Return(errors.ErrBadCredentials)
+		w := deleteAccount("testuser", `{"password": "wrong"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+	t.Run("db_error", func(t *testing.T) {
+		srvc.EXPECT().DeleteAccount("testuser", "password", gomock.Any()).Return(fmt.Errorf("db error"))
+		w := deleteAccount("testuser", `{"password": "password"}`)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+	t.Run("no_username", func(t *testing.T) {
+		w := deleteAccount("", `{"password": "password"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	t.Run("invalid_data", func(t *testing.T) {
+		w := deleteAccount("testuser", `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,218 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+)
+
+// BlobController defines the interface for the controller of the chunked
+// uploads and downloads of the binary records content.
+type BlobController interface {
+	// Create starts an upload of a blob.
+	Create(ctx *gin.Context)
+	// Get returns the state of the upload of a blob.
+	Get(ctx *gin.Context)
+	// Append appends a chunk to the content of a blob.
+	Append(ctx *gin.Context)
+	// Download streams the content of a blob.
+	Download(ctx *gin.Context)
+}
+
+// blobController implements BlobController interface.
+type blobController struct {
+	service service.BlobService
+}
+
+// NewBlobController creates a new instance of BlobController with the given BlobService.
+func NewBlobController(service service.BlobService) BlobController {
+	return &blobController{
+		service: service,
+	}
+}
+
+// blobStatus returns the HTTP status of the error of the blob service.
+func blobStatus(err error) int {
+	switch {
+	case errors.Is(err, srvErrors.ErrBlobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, srvErrors.ErrBadChunkOffset), errors.Is(err, srvErrors.ErrBlobIncomplete):
+		return http.StatusConflict
+	case errors.Is(err, srvErrors.ErrBadChunkSize):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Create godoc
+//
+//	@Summary Start an upload of a blob.
+//	@Security bearerAuth
+//	@Description Starts an upload of the content of a binary record. The content is appended in chunks, and the binary record refers to the blob by its ID.
+//	@Accept json
+//	@Produce json
+//	@ID BlobCreate
+//	@Tags Blobs
+//	@Param	request	body	models.NewBlobRequest	true	"Size of the content"
+//	@Success 201 {object}	models.Blob	"Blob"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/blobs [post]
+func (c *blobController) Create(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	var req models.NewBlobRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	blob, err := c.service.Create(ctx.Request.Context(), username, req.Size)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusCreated, blob)
+}
+
+// Get godoc
+//
+//	@Summary Get the state of an upload.
+//	@Security bearerAuth
+//	@Description Returns the state of the upload of a blob. An interrupted upload is resumed from the received offset.
+//	@Produce json
+//	@ID BlobGet
+//	@Tags Blobs
+//	@Param        blobID   path      string  true  "Blob ID"
+//	@Success 200 {object}	models.Blob	"Blob"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 404 {string}	string	"Blob was not found"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/blobs/{blobID} [get]
+func (c *blobController) Get(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	id, err := models.ObjectIDFromString(ctx.Param("blobID"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	blob, err := c.service.Get(ctx.Request.Context(), username, id)
+	if err != nil {
+		ctx.String(blobStatus(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, blob)
+}
+
+// Append godoc
+//
+//	@Summary Append a chunk to a blob.
+//	@Security bearerAuth
+//	@Description Appends a chunk of at most 1 MiB to the content of a blob. The chunk must start at the offset where the uploaded content ends; otherwise the current state of the upload is returned with 409, so the client can resume from it.
+//	@Accept octet-stream
+//	@Produce json
+//	@ID BlobAppend
+//	@Tags Blobs
+//	@Param        blobID   path      string  true  "Blob ID"
+//	@Param        offset   query      int  true  "Offset of the chunk"
+//	@Param	chunk	body	string	true	"Chunk"
+//	@Success 200 {object}	models.Blob	"Blob"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 404 {string}	string	"Blob was not found"
+//	@Failure 409 {object}	models.Blob	"Chunk doesn't start at the end of the uploaded content"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/blobs/{blobID} [put]
+func (c *blobController) Append(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	id, err := models.ObjectIDFromString(ctx.Param("blobID"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	offset, err := strconv.ParseInt(ctx.Query("offset"), 10, 64)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	chunk, err := io.ReadAll(io.LimitReader(ctx.Request.Body, models.BlobChunkSize+1))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	blob, err := c.service.Append(ctx.Request.Context(), username, id, offset, chunk)
+	if errors.Is(err, srvErrors.ErrBadChunkOffset) {
+		ctx.JSON(http.StatusConflict, blob)
+		return
+	} else if err != nil {
+		ctx.String(blobStatus(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, blob)
+}
+
+// Download godoc
+//
+//	@Summary Download the content of a blob.
+//	@Security bearerAuth
+//	@Description Streams the decrypted content of the uploaded blob.
+//	@Produce octet-stream
+//	@ID BlobDownload
+//	@Tags Blobs
+//	@Param        blobID   path      string  true  "Blob ID"
+//	@Success 200 {file}	file	"Content"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 404 {string}	string	"Blob was not found"
+//	@Failure 409 {string}	string	"Blob upload is not complete"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/blobs/{blobID}/content [get]
+func (c *blobController) Download(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	id, err := models.ObjectIDFromString(ctx.Param("blobID"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	blob, err := c.service.Get(ctx.Request.Context(), username, id)
+	if err == nil && !blob.Complete() {
+		err = srvErrors.ErrBlobIncomplete
+	}
+	if err != nil {
+		ctx.String(blobStatus(err), err.Error())
+		return
+	}
+	ctx.Header("Content-Type", "application/octet-stream")
+	ctx.Header("Content-Length", strconv.FormatInt(blob.Size, 10))
+	ctx.Status(http.StatusOK)
+	// the status is already sent, so the client detects a failure by the short content
+	if err := c.service.Read(ctx.Request.Context(), username, id, ctx.Writer); err != nil {
+		ctx.Error(err)
+		ctx.Abort()
+	}
+}
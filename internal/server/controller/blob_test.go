@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service/mock"
+)
+
+// newBlobContext returns the context of a request to the blob controller.
+func newBlobContext(
+	method, target, username, blobID string,
+	body []byte,
+) (*gin.Context, *httptest.ResponseRecorder) {
+	req, _ := http.NewRequest(method, target, bytes.NewBuffer(body))
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = req
+	ctx.Params = gin.Params{{Key: "blobID", Value: blobID}}
+	if username != "" {
+		ctx.Set(middleware.UsernameContextValue, username)
+	}
+	return ctx, rec
+}
+
+func TestNewBlobController(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	blobs := mock.NewMockBlobService(mockCtrl)
+	ctrl := NewBlobController(blobs)
+	assert.NotNil(t, ctrl)
+}
+
+func TestBlobController_Create(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	blobs := mock.NewMockBlobService(mockCtrl)
+	ctrl := NewBlobController(blobs)
+
+	t.Run("no_username", func(t *testing.T) {
+		ctx, rec := newBlobContext("POST", "/api/blobs", "", "", []byte(`{"size": 10}`))
+
+		ctrl.Create(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("bad_body", func(t *testing.T) {
+		ctx, rec := newBlobContext("POST", "/api/blobs", "username", "", []byte(`{"size": -1}`))
+
+		ctrl.Create(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("service_err", func(t *testing.T) {
+		blobs.EXPECT().
+			Create(gomock.Any(), "username", int64(10)).
+			Return(nil, fmt.Errorf("some error"))
+		ctx, rec := newBlobContext("POST", "/api/blobs", "username", "", []byte(`{"size": 10}`))
+
+		ctrl.Create(ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+	t.Run("ok", func(t *testing.T) {
+		blob := &models.Blob{BlobID: models.NewRandomObjectID(), Size: 10}
+		blobs.EXPECT().Create(gomock.Any(), "username", int64(10)).Return(blob, nil)
+		ctx, rec := newBlobContext("POST", "/api/blobs", "username", "", []byte(`{"size": 10}`))
+
+		ctrl.Create(ctx)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		var res models.Blob
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, blob.BlobID, res.BlobID)
+	})
+}
+
+func TestBlobController_Get(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	blobs := mock.NewMockBlobService(mockCtrl)
+	ctrl := NewBlobController(blobs)
+	id := models.NewRandomObjectID()
+
+	t.Run("bad_id", func(t *testing.T) {
+		ctx, rec := newBlobContext("GET", "/api/blobs/1", "username", "1", nil)
+
+		ctrl.Get(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		blobs.EXPECT().Get(gomock.Any(), "username", id).Return(nil, srvErrors.ErrBlobNotFound)
+		ctx, rec := newBlobContext("GET", "/api/blobs/"+id.Hex(), "username", id.Hex(), nil)
+
+		ctrl.Get(ctx)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("ok", func(t *testing.T) {
+		blobs.EXPECT().
+			Get(gomock.Any(), "username", id).
+			Return(&models.Blob{BlobID: id, Size: 10, Received: 4}, nil)
+		ctx, rec := newBlobContext("GET", "/api/blobs/"+id.Hex(), "username", id.Hex(), nil)
+
+		ctrl.Get(ctx)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var res models.Blob
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, int64(4), res.Received)
+	})
+}
+
+func TestBlobController_Append(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	blobs := mock.NewMockBlobService(mockCtrl)
+	ctrl := NewBlobController(blobs)
+	id := models.NewRandomObjectID()
+	target := func(offset string) string {
+		return "/api/blobs/" + id.Hex() + "?offset=" + offset
+	}
+
+	t.Run("no_username", func(t *testing.T) {
+		ctx, rec := newBlobContext("PUT", target("0"), "", id.Hex(), []byte("hell"))
+
+		ctrl.Append(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("bad_offset", func(t *testing.T) {
+		ctx, rec := newBlobContext("PUT", target("abc"), "username", id.Hex(), []byte("hell"))
+
+		ctrl.Append(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("bad_size", func(t *testing.T) {
+		blobs.EXPECT().
+			Append(gomock.Any(), "username", id, int64(0), []byte{}).
+			Return(nil, srvErrors.ErrBadChunkSize)
+		ctx, rec := newBlobContext("PUT", target("0"), "username", id.Hex(), []byte{})
+
+		ctrl.Append(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("conflict", func(t *testing.T) {
+		blobs.EXPECT().
+			Append(gomock.Any(), "username", id, int64(0), []byte("hell")).
+			Return(&models.Blob{BlobID: id, Size: 10, Received: 4}, srvErrors.ErrBadChunkOffset)
+		ctx, rec := newBlobContext("PUT", target("0"), "username", id.Hex(), []byte("hell"))
+
+		ctrl.Append(ctx)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		var res models.Blob
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, int64(4), res.Received)
+	})
+	t.Run("ok", func(t *testing.T) {
+		blobs.EXPECT().
+			Append(gomock.Any(), "username", id, int64(4), []byte("o, go")).
+			Return(&models.Blob{BlobID: id, Size: 9, Received: 9}, nil)
+		ctx, rec := newBlobContext("PUT", target("4"), "username", id.Hex(), []byte("o, go"))
+
+		ctrl.Append(ctx)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestBlobController_Download(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	blobs := mock.NewMockBlobService(mockCtrl)
+	ctrl := NewBlobController(blobs)
+	id := models.NewRandomObjectID()
+	target := "/api/blobs/" + id.Hex() + "/content"
+
+	t.Run("not_found", func(t *testing.T) {
+		blobs.EXPECT().Get(gomock.Any(), "username", id).Return(nil, srvErrors.ErrBlobNotFound)
+		ctx, rec := newBlobContext("GET", target, "username", id.Hex(), nil)
+
+		ctrl.Download(ctx)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("incomplete", func(t *testing.T) {
+		blobs.EXPECT().
+			Get(gomock.Any(), "username", id).
+			Return(&models.Blob{BlobID: id, Size: 9, Received: 4}, nil)
+		ctx, rec := newBlobContext("GET", target, "username", id.Hex(), nil)
+
+		ctrl.Download(ctx)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+	t.Run("ok", func(t *testing.T) {
+		blobs.EXPECT().
+			Get(gomock.Any(), "username", id).
+			Return(&models.Blob{BlobID: id, Size: 9, Received: 9}, nil)
+		blobs.EXPECT().
+			Read(gomock.Any(), "username", id, gomock.Any()).
+			DoAndReturn(func(_ any, _ string, _ models.ObjectID, w io.Writer) error {
+				_, err := w.Write([]byte("hello, go"))
+				return err
+			})
+		ctx, rec := newBlobContext("GET", target, "username", id.Hex(), nil)
+
+		ctrl.Download(ctx)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "9", rec.Header().Get("Content-Length"))
+		assert.Equal(t, "hello, go", rec.Body.String())
+	})
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+)
+
+// DeviceController defines the interface for the controller of the user devices.
+type DeviceController interface {
+	// Nonce issues the nonce which the client signs with the key of the device.
+	Nonce(ctx *gin.Context)
+	// List returns the devices of the user.
+	List(ctx *gin.Context)
+	// Delete removes a device of the user and signs it out.
+	Delete(ctx *gin.Context)
+}
+
+// deviceController implements DeviceController interface.
+type deviceController struct {
+	service service.DeviceService
+}
+
+// NewDeviceController creates a new instance of DeviceController with the given DeviceService.
+func NewDeviceController(service service.DeviceService) DeviceController {
+	return &deviceController{
+		service: service,
+	}
+}
+
+// Nonce godoc
+//
+//	@Summary Issue a device nonce
+//	@Description Issues the short-lived nonce which the client signs with the Ed25519 private key of its device. The signature is sent as the proof of the device to /api/user/login, /api/user/login/mfa and /api/user/refresh, so the server registers the device and refreshes its sessions only for the client which holds the key. Every nonce can be used only once.
+//	@Produce json
+//	@ID DeviceNonce
+//	@Tags Authy
+//	@Success 200 {object}	models.DeviceNonce	"Nonce"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/devices/nonce [post]
+func (c *deviceController) Nonce(ctx *gin.Context) {
+	nonce, err := c.service.Nonce()
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, nonce)
+}
+
+// List godoc
+//
+//	@Summary List the devices
+//	@Security bearerAuth
+//	@Description Returns the devices registered by the logins of the user. The device of the token of the request is marked as current.
+//	@Produce json
+//	@ID DeviceList
+//	@Tags Authy
+//	@Success 200 {array}	models.Device	"Devices"
+//	@Failure 401 {string}	string	"Unauthorized"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/devices [get]
+func (c *deviceController) List(ctx *gin.Context) {
+	claims := middleware.ClaimsFromGinContext(ctx)
+	if claims == nil {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	devices, err := c.service.List(ctx.Request.Context(), claims.Username)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range devices {
+		devices[i].Current = devices[i].ID.Hex() == claims.DeviceID
+	}
+	ctx.JSON(http.StatusOK, devices)
+}
+
+// Delete godoc
+//
+//	@Summary Delete a device
+//	@Security bearerAuth
+//	@Description Removes the device: its sessions are finished, their access tokens are revoked and its event streams are closed at once. The device gets a new id on its next login.
+//	@Produce plain
+//	@ID DeviceDelete
+//	@Tags Authy
+//	@Param	deviceID	path	string	true	"Device ID"
+//	@Success 200 {string}	string	"Device deleted"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"Unauthorized"
+//	@Failure 404 {string}	string	"device was not found"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/devices/{deviceID} [delete]
+func (c *deviceController) Delete(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	deviceID, err := models.ObjectIDFromString(ctx.Param("deviceID"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := c.service.Delete(ctx.Request.Context(), username, deviceID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrDeviceNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.String(status, err.Error())
+		return
+	}
+	ctx.String(http.StatusOK, "Device deleted")
+}
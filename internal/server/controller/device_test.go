@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service/mock"
+)
+
+func TestDeviceController_Nonce(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	devices := mock.NewMockDeviceService(mockCtrl)
+	ctrl := NewDeviceController(devices)
+
+	t.Run("ok", func(t *testing.T) {
+		expiresAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+		devices.EXPECT().Nonce().Return(&models.DeviceNonce{Nonce: "nonce", ExpiresAt: expiresAt}, nil)
+		ctx, rec := newSessionContext("POST", "/api/user/devices/nonce", "", nil)
+		ctrl.Nonce(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var nonce models.DeviceNonce
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &nonce))
+		assert.Equal(t, "nonce", nonce.Nonce)
+		assert.Equal(t, expiresAt, nonce.ExpiresAt)
+	})
+	t.Run("error", func(t *testing.T) {
+		devices.EXPECT().Nonce().Return(nil, fmt.Errorf("some error"))
+		ctx, rec := newSessionContext("POST", "/api/user/devices/nonce", "", nil)
+		ctrl.Nonce(ctx)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestDeviceController_List(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	devices := mock.NewMockDeviceService(mockCtrl)
+	ctrl := NewDeviceController(devices)
+	current, other := models.NewRandomObjectID(), models.NewRandomObjectID()
+	claims := &auth.Claims{Username: "testuser", DeviceID: current.Hex()}
+
+	t.Run("ok", func(t *testing.T) {
+		devices.EXPECT().
+			List(gomock.Any(), "testuser").
+			Return([]models.Device{
+				{ID: current, DeviceInfo: models.DeviceInfo{Name: "laptop", PublicKey: "key"}},
+				{ID: other, DeviceInfo: models.DeviceInfo{Name: "phone"}},
+			}, nil)
+		ctx, rec := newSessionContext("GET", "/api/user/devices", "", claims)
+		ctrl.List(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"name":"laptop","platform":"","public_key":"key"`)
+
+		var res []models.Device
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.Len(t, res, 2)
+		assert.True(t, res[0].Current)
+		assert.False(t, res[1].Current)
+	})
+	t.Run("no_claims", func(t *testing.T) {
+		ctx, rec := newSessionContext("GET", "/api/user/devices", "", nil)
+		ctrl.List(ctx)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("error", func(t *testing.T) {
+		devices.EXPECT().
+			List(gomock.Any(), "testuser").
+			Return(nil, fmt.Errorf("some db error"))
+		ctx, rec := newSessionContext("GET", "/api/user/devices", "", claims)
+		ctrl.List(ctx)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestDeviceController_Delete(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	devices := mock.NewMockDeviceService(mockCtrl)
+	ctrl := NewDeviceController(devices)
+	id := models.NewRandomObjectID()
+	claims := &auth.Claims{Username: "testuser"}
+
+	newContext := func(deviceID string, claims *auth.Claims) (*gin.Context, *httptest.ResponseRecorder) {
+		ctx, rec := newSessionContext("DELETE", "/api/user/devices/"+deviceID, "", claims)
+		ctx.Params = gin.Params{{Key: "deviceID", Value: deviceID}}
+		return ctx, rec
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		devices.EXPECT().Delete(gomock.Any(), "testuser", id).Return(nil)
+		ctx, rec := newContext(id.Hex(), claims)
+		ctrl.Delete(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("bad_id", func(t *testing.T) {
+		ctx, rec := newContext("bad-id", claims)
+		ctrl.Delete(ctx)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("no_username", func(t *testing.T) {
+		ctx, rec := newContext(id.Hex(), nil)
+		ctrl.Delete(ctx)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		devices.EXPECT().Delete(gomock.Any(), "testuser", id).Return(srvErrors.ErrDeviceNotFound)
+		ctx, rec := newContext(id.Hex(), claims)
+		ctrl.Delete(ctx)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("error", func(t *testing.T) {
+		devices.EXPECT().Delete(gomock.Any(), "testuser", id).Return(fmt.Errorf("some db error"))
+		ctx, rec := newContext(id.Hex(), claims)
+		ctrl.Delete(ctx)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
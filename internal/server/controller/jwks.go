@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+)
+
+// JWKSController defines the interface for the controller
+// which publishes the keys verifying the access tokens.
+type JWKSController interface {
+	// JWKS returns the public keys of the access tokens.
+	JWKS(*gin.Context)
+}
+
+// jwksController implements JWKSController interface.
+type jwksController struct {
+	keys *auth.KeySet
+}
+
+// NewJWKSController creates a new instance of JWKSController which publishes the public keys of the set.
+func NewJWKSController(keys *auth.KeySet) JWKSController {
+	return &jwksController{
+		keys: keys,
+	}
+}
+
+// JWKS godoc
+//
+//	@Summary Public keys of the access tokens
+//	@Description Returns the JSON Web Key Set with the public keys which verify the access tokens: the active signing key first and the keys accepted during the rollover. The key of a token is found by the kid header of the token. The set is empty in the legacy HS256 mode.
+//	@Produce json
+//	@ID JWKS
+//	@Tags Authy
+//	@Success 200 {object}	auth.JWKS	"Public keys"
+//	@Router /.well-known/jwks.json [get]
+func (c *jwksController) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, c.keys.JWKS())
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+)
+
+func TestJWKSController(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys, err := auth.NewKeySet(key)
+	require.NoError(t, err)
+	jwks := func(keys *auth.KeySet) (*httptest.ResponseRecorder, auth.JWKS) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		NewJWKSController(keys).JWKS(c)
+		var set auth.JWKS
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &set))
+		return w, set
+	}
+
+	t.Run("ed25519", func(t *testing.T) {
+		w, set := jwks(keys)
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, set.Keys, 1)
+		assert.Equal(t, keys.KeyID(), set.Keys[0].Kid)
+		assert.Equal(t, "OKP", set.Keys[0].Kty)
+		assert.Equal(t, "EdDSA", set.Keys[0].Alg)
+	})
+	t.Run("legacy", func(t *testing.T) {
+		w, set := jwks(auth.NewHMACKeySet([]byte("secret")))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, set.Keys)
+		assert.NotContains(t, w.Body.String(), "secret")
+	})
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+)
+
+// MFAController defines the interface for the controller of the second factor.
+type MFAController interface {
+	// Enroll generates a new secret of the second factor.
+	Enroll(ctx *gin.Context)
+	// Confirm enables the second factor and returns the recovery codes.
+	Confirm(ctx *gin.Context)
+	// Disable turns off the second factor.
+	Disable(ctx *gin.Context)
+}
+
+// mfaController implements MFAController interface.
+type mfaController struct {
+	service service.MFAService
+}
+
+// NewMFAController creates a new instance of MFAController with the given MFAService.
+func NewMFAController(service service.MFAService) MFAController {
+	return &mfaController{
+		service: service,
+	}
+}
+
+// mfaErrorStatus returns the HTTP status of the error of the MFA service.
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, srvErrors.ErrBadMFACode), errors.Is(err, srvErrors.ErrBadCredentials):
+		return http.StatusForbidden
+	case errors.Is(err, srvErrors.ErrMFAEnabled),
+		errors.Is(err, srvErrors.ErrMFANotEnrolled),
+		errors.Is(err, srvErrors.ErrMFADisabled):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Enroll godoc
+//
+//	@Summary Enroll the second factor.
+//	@Security bearerAuth
+//	@Description Checks the password and generates a new TOTP secret and its provisioning URI to add to an authenticator app. The second factor is enabled only after the secret is confirmed at /api/user/mfa/confirm. Enrolling again replaces the unconfirmed secret. The wrong passwords are counted as the failed logins.
+//	@Accept json
+//	@Produce json
+//	@ID MFAEnroll
+//	@Tags Authy
+//	@Param	request	body	models.MFAEnrollRequest	true	"Password"
+//	@Success 200 {object}	models.MFAEnrollment	"Secret and provisioning URI"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 403 {string}	string	"username or password is incorrect"
+//	@Failure 409 {string}	string	"second factor is already enabled"
+//	@Failure 429 {string}	string	"too many failed logins, try again in 30s"
+//	@Header 429 {integer}	Retry-After	"Seconds until the lockout ends"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/mfa [post]
+func (c *mfaController) Enroll(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	var req models.MFAEnrollRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	enrollment, err := c.service.Enroll(ctx.Request.Context(), username, req.Password, ctx.ClientIP())
+	if lockedError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.String(mfaErrorStatus(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, enrollment)
+}
+
+// Confirm godoc
+//
+//	@Summary Confirm the second factor.
+//	@Security bearerAuth
+//	@Description Checks the one-time code of the enrolled secret and enables the second factor. The recovery codes are returned only once; every code replaces the one-time code once.
+//	@Accept json
+//	@Produce json
+//	@ID MFAConfirm
+//	@Tags Authy
+//	@Param	request	body	models.MFACodeRequest	true	"One-time code"
+//	@Success 200 {object}	models.MFARecoveryCodes	"Recovery codes"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 403 {string}	string	"one-time code is incorrect"
+//	@Failure 409 {string}	string	"second factor is not enrolled"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/mfa/confirm [post]
+func (c *mfaController) Confirm(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	var req models.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	codes, err := c.service.Confirm(ctx.Request.Context(), username, req.Code)
+	if err != nil {
+		ctx.String(mfaErrorStatus(err), err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, codes)
+}
+
+// Disable godoc
+//
+//	@Summary Disable the second factor.
+//	@Security bearerAuth
+//	@Description Checks the password and the one-time code or a recovery code and turns off the second factor. The wrong passwords are counted as the failed logins.
+//	@Accept json
+//	@Produce plain
+//	@ID MFADisable
+//	@Tags Authy
+//	@Param	request	body	models.MFADisableRequest	true	"Password and one-time or recovery code"
+//	@Success 200 {string}	string	"Second factor disabled"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 403 {string}	string	"one-time code is incorrect"
+//	@Failure 409 {string}	string	"second factor is not enabled"
+//	@Failure 429 {string}	string	"too many failed logins, try again in 30s"
+//	@Header 429 {integer}	Retry-After	"Seconds until the lockout ends"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/mfa [delete]
+func (c *mfaController) Disable(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	var req models.MFADisableRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	err := c.service.Disable(ctx.Request.Context(), username, req.Password, req.Code, ctx.ClientIP())
+	if lockedError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.String(mfaErrorStatus(err), err.Error())
+		return
+	}
+	ctx.String(http.StatusOK, "Second factor disabled")
+}
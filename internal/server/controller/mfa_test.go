@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service/mock"
+)
+
+func TestMFAController(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mfa := mock.NewMockMFAService(mockCtrl)
+	ctrl := NewMFAController(mfa)
+
+	newContext := func(username, body string) (*gin.Context, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest(http.MethodPost, "/api/user/mfa", bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		if username != "" {
+			ctx.Set(middleware.UsernameContextValue, username)
+		}
+		return ctx, rec
+	}
+
+	t.Run("no_username", func(t *testing.T) {
+		for _, handler := range []gin.HandlerFunc{ctrl.Enroll, ctrl.Confirm, ctrl.Disable} {
+			ctx, rec := newContext("", `{"code":"123456"}`)
+			handler(ctx)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		}
+	})
+	t.Run("enroll", func(t *testing.T) {
+		mfa.EXPECT().
+			Enroll(gomock.Any(), "username", "password", gomock.Any()).
+			Return(&models.MFAEnrollment{Secret: "SECRET", URI: "otpauth://totp/GophKeeper:username"}, nil)
+		ctx, rec := newContext("username", `{"password":"password"}`)
+		ctrl.Enroll(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"secret":"SECRET"`)
+	})
+	t.Run("enroll_enabled", func(t *testing.T) {
+		mfa.EXPECT().
+			Enroll(gomock.Any(), "username", "password", gomock.Any()).
+			Return(nil, srvErrors.ErrMFAEnabled)
+		ctx, rec := newContext("username", `{"password":"password"}`)
+		ctrl.Enroll(ctx)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+	t.Run("enroll_no_password", func(t *testing.T) {
+		ctx, rec := newContext("username", `{}`)
+		ctrl.Enroll(ctx)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("enroll_wrong_password", func(t *testing.T) {
+		mfa.EXPECT().
+			Enroll(gomock.Any(), "username", "wrong", gomock.Any()).
+			Return(nil, srvErrors.ErrBadCredentials)
+		ctx, rec := newContext("username", `{"password":"wrong"}`)
+		ctrl.Enroll(ctx)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+	t.Run("enroll_locked", func(t *testing.T) {
+		mfa.EXPECT().
+			Enroll(gomock.Any(), "username", "wrong", gomock.Any()).
+			Return(nil, &srvErrors.LockedError{RetryAfter: 30 * time.Second})
+		ctx, rec := newContext("username", `{"password":"wrong"}`)
+		ctrl.Enroll(ctx)
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	})
+	t.Run("confirm", func(t *testing.T) {
+		mfa.EXPECT().
+			Confirm(gomock.Any(), "username", "123456").
+			Return(&models.MFARecoveryCodes{RecoveryCodes: []string{"abcd-efgh"}}, nil)
+		ctx, rec := newContext("username", `{"code":"123456"}`)
+		ctrl.Confirm(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"recovery_codes":["abcd-efgh"]`)
+	})
+	t.Run("confirm_bad_code", func(t *testing.T) {
+		mfa.EXPECT().Confirm(gomock.Any(), "username", "000000").Return(nil, srvErrors.ErrBadMFACode)
+		ctx, rec := newContext("username", `{"code":"000000"}`)
+		ctrl.Confirm(ctx)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+	t.Run("confirm_no_code", func(t *testing.T) {
+		ctx, rec := newContext("username", `{}`)
+		ctrl.Confirm(ctx)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("disable", func(t *testing.T) {
+		mfa.EXPECT().Disable(gomock.Any(), "username", "password", "123456", gomock.Any()).Return(nil)
+		ctx, rec := newContext("username", `{"password":"password","code":"123456"}`)
+		ctrl.Disable(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("disable_disabled", func(t *testing.T) {
+		mfa.EXPECT().
+			Disable(gomock.Any(), "username", "password", "123456", gomock.Any()).
+			Return(srvErrors.ErrMFADisabled)
+		ctx, rec := newContext("username", `{"password":"password","code":"123456"}`)
+		ctrl.Disable(ctx)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+	t.Run("disable_no_password", func(t *testing.T) {
+		ctx, rec := newContext("username", `{"code":"123456"}`)
+		ctrl.Disable(ctx)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("disable_wrong_password", func(t *testing.T) {
+		mfa.EXPECT().
+			Disable(gomock.Any(), "username", "wrong", "123456", gomock.Any()).
+			Return(srvErrors.ErrBadCredentials)
+		ctx, rec := newContext("username", `{"password":"wrong","code":"123456"}`)
+		ctrl.Disable(ctx)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, srvErrors.ErrBadCredentials.Error(), rec.Body.String())
+	})
+	t.Run("service_err", func(t *testing.T) {
+		mfa.EXPECT().
+			Disable(gomock.Any(), "username", "password", "123456", gomock.Any()).
+			Return(fmt.Errorf("some error"))
+		ctx, rec := newContext("username", `{"password":"password","code":"123456"}`)
+		ctrl.Disable(ctx)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
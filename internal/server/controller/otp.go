@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mitchellh/mapstructure"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+)
+
+// OTPController defines the interface for one-time passwords controller.
+type OTPController interface {
+	// Code generates the current one-time password for a record of the otp collection.
+	Code(ctx *gin.Context)
+}
+
+// otpIssueAttempts is the number of attempts to issue a HOTP code
+// when the record is updated concurrently.
+const otpIssueAttempts = 3
+
+// otpController implements OTPController interface.
+type otpController struct {
+	service service.StorageService
+	sync    service.SyncService
+	now     func() time.Time
+}
+
+// NewOTPController creates a new instance of OTPController with the given StorageService.
+// The clients are notified with the SyncService when a HOTP counter is advanced.
+func NewOTPController(service service.StorageService, sync service.SyncService) OTPController {
+	return &otpController{
+		service: service,
+		sync:    sync,
+		now:     time.Now,
+	}
+}
+
+// Code godoc
+//
+//	@Summary Generate a one-time password.
+//	@Security bearerAuth
+//	@Description Generates the current RFC 6238 (TOTP) or RFC 4226 (HOTP) code for a record of the otp collection.
+//	@Description The counter of a HOTP record is advanced, so every request issues a new code.
+//	@Description The request is a POST, so the code isn't issued by a prefetch or a retried GET.
+//	@Produce json
+//	@ID OTPCode
+//	@Tags OTP
+//	@Param        recordID   path      string  true  "Record ID"
+//	@Success 200 {object}	models.OTPCode	"Current code"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 404 {string}	string	"Record not found"
+//	@Failure 409 {string}	string	"The record is updated concurrently"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/otp/{recordID}/code [post]
+func (c *otpController) Code(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	id, err := models.ObjectIDFromString(ctx.Param("recordID"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	for attempt := 0; attempt < otpIssueAttempts; attempt++ {
+		record, err := c.service.Get(ctx.Request.Context(), models.OTPCollection, username, id)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, srvErrors.ErrRecordNotFound) {
+				status = http.StatusNotFound
+			}
+			ctx.String(status, err.Error())
+			return
+		}
+		// the server can't read the secret of an end-to-end encrypted record,
+		// the client generates the code by itself
+		if models.IsEncryptedData(record.Data) {
+			ctx.String(http.StatusBadRequest, srvErrors.ErrEncryptedRecord.Error())
+			return
+		}
+		var info models.OTPInfo
+		if err := mapstructure.Decode(record.Data, &info); err != nil {
+			ctx.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		code, err := info.Code(c.now())
+		if err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if !info.IsHOTP() {
+			ctx.JSON(http.StatusOK, code)
+			return
+		}
+		// the code is issued only if the advanced counter is saved,
+		// otherwise the same code could be issued twice
+		err = c.advance(ctx, username, record, info)
+		if errors.Is(err, srvErrors.ErrVersionConflict) {
+			continue
+		} else if err != nil {
+			ctx.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		ctx.JSON(http.StatusOK, code)
+		return
+	}
+	ctx.String(http.StatusConflict, srvErrors.ErrVersionConflict.Error())
+}
+
+// advance saves the incremented counter of the HOTP record. The record isn't
+// updated if its version has changed since it was read.
+func (c *otpController) advance(
+	ctx *gin.Context,
+	username string,
+	record *models.UntypedRecord,
+	info models.OTPInfo,
+) error {
+	next, err := info.Next()
+	if err != nil {
+		return err
+	}
+	data, ok := record.Data.(map[string]any)
+	if !ok {
+		return fmt.Errorf("unexpected record data type %T", record.Data)
+	}
+	newData := make(map[string]any, len(data))
+	for k, v := range data {
+		newData[k] = v
+	}
+	newData["Counter"] = next.Counter
+	_, err = c.service.Update(
+		ctx.Request.Context(),
+		models.OTPCollection,
+		username,
+		record.RecordID,
+		newData,
+		record.Metadata,
+		record.Version,
+	)
+	if err != nil {
+		return err
+	}
+	c.sync.Publish(username, models.ChangeEvent{
+		Collection: models.OTPCollection,
+		RecordID:   record.RecordID,
+		Op:         models.OpUpdate,
+	})
+	return nil
+}
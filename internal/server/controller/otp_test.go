@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service/mock"
+)
+
+func TestNewOTPController(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	storage := mock.NewMockStorageService(mockCtrl)
+	sync := mock.NewMockSyncService(mockCtrl)
+	ctrl := NewOTPController(storage, sync)
+	assert.NotNil(t, ctrl)
+}
+
+func TestOTPController_Code(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	storage := mock.NewMockStorageService(mockCtrl)
+	sync := mock.NewMockSyncService(mockCtrl)
+	ctrl := NewOTPController(storage, sync).(*otpController)
+	ctrl.now = func() time.Time { return time.Unix(1111111109, 0) }
+
+	username := "testuser"
+	id := models.NewRandomObjectID()
+	newContext := func(recordID string) (*gin.Context, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/api/otp/%v/code", recordID), nil)
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(ctx.Params, gin.Param{Key: "recordID", Value: recordID})
+		ctx.Set(middleware.UsernameContextValue, username)
+		return ctx, rec
+	}
+
+	t.Run("no_username", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+
+		ctrl.Code(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("bad_id", func(t *testing.T) {
+		ctx, rec := newContext("bad-id")
+
+		ctrl.Code(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		storage.EXPECT().
+			Get(gomock.Any(), models.OTPCollection, username, id).
+			Return(nil, srvErrors.ErrRecordNotFound)
+		ctx, rec := newContext(id.Hex())
+
+		ctrl.Code(ctx)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("service_err", func(t *testing.T) {
+		storage.EXPECT().
+			Get(gomock.Any(), models.OTPCollection, username, id).
+			Return(nil, fmt.Errorf("some error"))
+		ctx, rec := newContext(id.Hex())
+
+		ctrl.Code(ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+	t.Run("bad_secret", func(t *testing.T) {
+		storage.EXPECT().
+			Get(gomock.Any(), models.OTPCollection, username, id).
+			Return(&models.UntypedRecord{
+				RecordID: id,
+				UntypedRecordContent: models.UntypedRecordContent{
+					Data: map[string]any{"Secret": "1"},
+				},
+			}, nil)
+		ctx, rec := newContext(id.Hex())
+
+		ctrl.Code(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("encrypted", func(t *testing.T) {
+		storage.EXPECT().
+			Get(gomock.Any(), models.OTPCollection, username, id).
+			Return(&models.UntypedRecord{
+				RecordID: id,
+				UntypedRecordContent: models.UntypedRecordContent{
+					Data: map[string]any{models.EncryptedField: "Y2lwaGVydGV4dA=="},
+				},
+			}, nil)
+		ctx, rec := newContext(id.Hex())
+
+		ctrl.Code(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, srvErrors.ErrEncryptedRecord.Error(), rec.Body.String())
+	})
+	t.Run("ok", func(t *testing.T) {
+		storage.EXPECT().
+			Get(gomock.Any(), models.OTPCollection, username, id).
+			Return(&models.UntypedRecord{
+				RecordID: id,
+				UntypedRecordContent: models.UntypedRecordContent{
+					Data: map[string]any{
+						"Secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+						"Digits": "8",
+					},
+				},
+			}, nil)
+		ctx, rec := newContext(id.Hex())
+
+		ctrl.Code(ctx)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var code models.OTPCode
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &code))
+		assert.Equal(t, models.OTPCode{Code: "07081804", RemainingSeconds: 1}, code)
+	})
+	t.Run("hotp_advances_counter", func(t *testing.T) {
+		hotp := func(counter string, version int64) *models.UntypedRecord {
+			return &models.UntypedRecord{
+				RecordID: id,
+				Version:  version,
+				UntypedRecordContent: models.UntypedRecordContent{
+					Data: map[string]any{
+						"Type":    "hotp",
+						"Secret":  "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+						"Counter": counter,
+					},
+				},
+			}
+		}
+		gomock.InOrder(
+			storage.EXPECT().
+				Get(gomock.Any(), models.OTPCollection, username, id).
+				Return(hotp("0", 1), nil),
+			storage.EXPECT().
+				Update(gomock.Any(), models.OTPCollection, username, id,
+					hotp("1", 0).Data, gomock.Any(), int64(1)).
+				Return(hotp("1", 2), nil),
+			storage.EXPECT().
+				Get(gomock.Any(), models.OTPCollection, username, id).
+				Return(hotp("1", 2), nil),
+			storage.EXPECT().
+				Update(gomock.Any(), models.OTPCollection, username, id,
+					hotp("2", 0).Data, gomock.Any(), int64(2)).
+				Return(hotp("2", 3), nil),
+		)
+		sync.EXPECT().
+			Publish(username, models.ChangeEvent{
+				Collection: models.OTPCollection,
+				RecordID:   id,
+				Op:         models.OpUpdate,
+			}).
+			Times(2)
+
+		codes := make([]models.OTPCode, 2)
+		for i := range codes {
+			ctx, rec := newContext(id.Hex())
+			ctrl.Code(ctx)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &codes[i]))
+		}
+		assert.Equal(t, "755224", codes[0].Code)
+		assert.Equal(t, "287082", codes[1].Code)
+		assert.NotEqual(t, codes[0].Code, codes[1].Code)
+	})
+	t.Run("hotp_conflict", func(t *testing.T) {
+		record := &models.UntypedRecord{
+			RecordID: id,
+			Version:  1,
+			UntypedRecordContent: models.UntypedRecordContent{
+				Data: map[string]any{
+					"Type":   "hotp",
+					"Secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+				},
+			},
+		}
+		storage.EXPECT().
+			Get(gomock.Any(), models.OTPCollection, username, id).
+			Return(record, nil).
+			Times(otpIssueAttempts)
+		storage.EXPECT().
+			Update(gomock.Any(), models.OTPCollection, username, id,
+				gomock.Any(), gomock.Any(), int64(1)).
+			Return(record, srvErrors.ErrVersionConflict).
+			Times(otpIssueAttempts)
+		ctx, rec := newContext(id.Hex())
+
+		ctrl.Code(ctx)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
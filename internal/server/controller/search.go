@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+)
+
+// SearchController defines the interface for the search of the records.
+type SearchController interface {
+	// Search finds the user's records of all the collections by the query.
+	Search(ctx *gin.Context)
+}
+
+// searchController implements SearchController interface.
+type searchController struct {
+	service service.StorageService
+}
+
+// NewSearchController creates a new instance of SearchController with the given StorageService.
+func NewSearchController(service service.StorageService) SearchController {
+	return &searchController{service: service}
+}
+
+// Search godoc
+//
+//	@Summary Search the records.
+//	@Security bearerAuth
+//	@Description Finds the records of all the collections whose metadata values, credential logins,
+//	@Description file names or last four digits of the card numbers have all the words of the query.
+//	@Description A word matches a word of the record it is equal to or is a prefix of (at least three characters long).
+//	@Description The server keeps only the keyed hashes of the words, so the end-to-end encrypted records are not found.
+//	@Description The content of the binary records is omitted.
+//	@Produce json
+//	@ID Search
+//	@Tags Search
+//	@Param        q   query      string  true  "Search query"
+//	@Success 200 {array}	models.SearchResult	"Found records, the recently updated first"
+//	@Failure 400 {string}	string	"Empty query"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/search [get]
+func (c *searchController) Search(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	results, err := c.service.Search(ctx.Request.Context(), username, ctx.Query("q"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrEmptySearchQuery) {
+			status = http.StatusBadRequest
+		}
+		ctx.String(status, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, results)
+}
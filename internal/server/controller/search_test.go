@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service/mock"
+)
+
+func TestNewSearchController(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	storage := mock.NewMockStorageService(mockCtrl)
+	ctrl := NewSearchController(storage)
+	assert.NotNil(t, ctrl)
+}
+
+func TestSearchController_Search(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	storage := mock.NewMockStorageService(mockCtrl)
+	ctrl := NewSearchController(storage)
+
+	username := "testuser"
+	newContext := func(query string) (*gin.Context, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest("GET", "/api/search?q="+url.QueryEscape(query), nil)
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Set(middleware.UsernameContextValue, username)
+		return ctx, rec
+	}
+
+	t.Run("no_username", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/api/search?q=github", nil)
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+
+		ctrl.Search(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("empty_query", func(t *testing.T) {
+		storage.EXPECT().
+			Search(gomock.Any(), username, " ").
+			Return(nil, srvErrors.ErrEmptySearchQuery)
+		ctx, rec := newContext(" ")
+
+		ctrl.Search(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("service_err", func(t *testing.T) {
+		storage.EXPECT().
+			Search(gomock.Any(), username, "github").
+			Return(nil, fmt.Errorf("some error"))
+		ctx, rec := newContext("github")
+
+		ctrl.Search(ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+	t.Run("ok", func(t *testing.T) {
+		results := []models.SearchResult{{
+			Collection: models.CredentialsCollection,
+			Record: models.UntypedRecord{
+				RecordID: models.NewRandomObjectID(),
+				UntypedRecordContent: models.UntypedRecordContent{
+					Data:     map[string]any{"Login": "john@github.com", "Password": "secret"},
+					Metadata: models.Metadata{"site": "github.com"},
+				},
+			},
+		}}
+		storage.EXPECT().
+			Search(gomock.Any(), username, "john github").
+			Return(results, nil)
+		ctx, rec := newContext("john github")
+
+		ctrl.Search(ctx)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var actual []models.SearchResult
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &actual))
+		assert.Equal(t, results[0].Record.RecordID, actual[0].Record.RecordID)
+		assert.Equal(t, models.CredentialsCollection, actual[0].Collection)
+	})
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+)
+
+// SessionController defines the interface for the controller of the user sessions.
+type SessionController interface {
+	// Refresh exchanges the refresh token for the new tokens.
+	Refresh(ctx *gin.Context)
+	// Logout revokes the access token and finishes its session.
+	Logout(ctx *gin.Context)
+	// List returns the active sessions of the user.
+	List(ctx *gin.Context)
+	// Revoke finishes a session of the user.
+	Revoke(ctx *gin.Context)
+}
+
+// sessionController implements SessionController interface.
+type sessionController struct {
+	service service.SessionService
+}
+
+// NewSessionController creates a new instance of SessionController with the given SessionService.
+func NewSessionController(service service.SessionService) SessionController {
+	return &sessionController{
+		service: service,
+	}
+}
+
+// Refresh godoc
+//
+//	@Summary Refresh the tokens
+//	@Description Exchanges the refresh token for the new access and refresh tokens. Every refresh token can be used only once; using it again revokes the whole session. The session started on a device is refreshed only with the proof: a nonce of /api/user/devices/nonce signed with the key of the device.
+//	@Accept json
+//	@Produce json
+//	@ID Refresh
+//	@Tags Authy
+//	@Param	request body	models.RefreshRequest	true	"Refresh token"
+//	@Success 200 {object}	models.TokenPair	"New tokens of the session"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"refresh token is invalid or expired or device proof is invalid"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/refresh [post]
+func (c *sessionController) Refresh(ctx *gin.Context) {
+	var req models.RefreshRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	tokens, err := c.service.Refresh(ctx.Request.Context(), req.RefreshToken, req.Proof)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrBadRefreshToken) ||
+			errors.Is(err, srvErrors.ErrBadDeviceProof) {
+			status = http.StatusUnauthorized
+		}
+		ctx.String(status, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+//
+//	@Summary Log out
+//	@Security bearerAuth
+//	@Description Revokes the access token of the request and finishes its session, so its refresh token can't be used anymore.
+//	@Produce plain
+//	@ID Logout
+//	@Tags Authy
+//	@Success 200 {string}	string	"Logged out"
+//	@Failure 401 {string}	string	"Unauthorized"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/logout [post]
+func (c *sessionController) Logout(ctx *gin.Context) {
+	claims := middleware.ClaimsFromGinContext(ctx)
+	if claims == nil {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	if err := c.service.Logout(ctx.Request.Context(), claims); err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.String(http.StatusOK, "Logged out")
+}
+
+// List godoc
+//
+//	@Summary List the sessions
+//	@Security bearerAuth
+//	@Description Returns the active sessions of the user. The session of the token of the request is marked as current.
+//	@Produce json
+//	@ID SessionList
+//	@Tags Authy
+//	@Success 200 {array}	models.Session	"Sessions"
+//	@Failure 401 {string}	string	"Unauthorized"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/sessions [get]
+func (c *sessionController) List(ctx *gin.Context) {
+	claims := middleware.ClaimsFromGinContext(ctx)
+	if claims == nil {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	sessions, err := c.service.List(ctx.Request.Context(), claims.Username)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID.Hex() == claims.SessionID
+	}
+	ctx.JSON(http.StatusOK, sessions)
+}
+
+// Revoke godoc
+//
+//	@Summary Revoke a session
+//	@Security bearerAuth
+//	@Description Finishes the session: its refresh token can't be used anymore, and its access token is revoked.
+//	@Produce plain
+//	@ID SessionRevoke
+//	@Tags Authy
+//	@Param	sessionID	path	string	true	"Session ID"
+//	@Success 200 {string}	string	"Session revoked"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"Unauthorized"
+//	@Failure 404 {string}	string	"session was not found"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/user/sessions/{sessionID} [delete]
+func (c *sessionController) Revoke(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	sessionID, err := models.ObjectIDFromString(ctx.Param("sessionID"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := c.service.Revoke(ctx.Request.Context(), username, sessionID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrSessionNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.String(status, err.Error())
+		return
+	}
+	ctx.String(http.StatusOK, "Session revoked")
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service/mock"
+)
+
+func TestNewSessionController(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	sessions := mock.NewMockSessionService(mockCtrl)
+	ctrl := NewSessionController(sessions)
+	assert.NotNil(t, ctrl)
+}
+
+// newSessionContext creates a context of the request authorized with the claims.
+func newSessionContext(
+	method, url, body string,
+	claims *auth.Claims,
+) (*gin.Context, *httptest.ResponseRecorder) {
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = req
+	if claims != nil {
+		ctx.Set(middleware.UsernameContextValue, claims.Username)
+		ctx.Set(middleware.ClaimsContextValue, claims)
+	}
+	return ctx, rec
+}
+
+func TestSessionController_Refresh(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	sessions := mock.NewMockSessionService(mockCtrl)
+	ctrl := NewSessionController(sessions)
+
+	t.Run("ok", func(t *testing.T) {
+		sessions.EXPECT().
+			Refresh(gomock.Any(), "refresh", nil).
+			Return(&models.TokenPair{AccessToken: "access", RefreshToken: "new-refresh"}, nil)
+		ctx, rec := newSessionContext("POST", "/api/user/refresh", `{"refresh_token": "refresh"}`, nil)
+		ctrl.Refresh(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var tokens models.TokenPair
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+		assert.Equal(t, "new-refresh", tokens.RefreshToken)
+	})
+	t.Run("bad_request", func(t *testing.T) {
+		ctx, rec := newSessionContext("POST", "/api/user/refresh", `{}`, nil)
+		ctrl.Refresh(ctx)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("bad_token", func(t *testing.T) {
+		sessions.EXPECT().
+			Refresh(gomock.Any(), "refresh", nil).
+			Return(nil, srvErrors.ErrBadRefreshToken)
+		ctx, rec := newSessionContext("POST", "/api/user/refresh", `{"refresh_token": "refresh"}`, nil)
+		ctrl.Refresh(ctx)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("device", func(t *testing.T) {
+		proof := &models.DeviceProof{Nonce: "nonce", Signature: "signature"}
+		sessions.EXPECT().
+			Refresh(gomock.Any(), "refresh", proof).
+			Return(&models.TokenPair{AccessToken: "access", DeviceID: "device"}, nil)
+		sessions.EXPECT().
+			Refresh(gomock.Any(), "refresh", proof).
+			Return(nil, srvErrors.ErrBadDeviceProof)
+		body := `{"refresh_token": "refresh", "proof": {"nonce": "nonce", "signature": "signature"}}`
+		for _, code := range []int{http.StatusOK, http.StatusUnauthorized} {
+			ctx, rec := newSessionContext("POST", "/api/user/refresh", body, nil)
+			ctrl.Refresh(ctx)
+			assert.Equal(t, code, rec.Code)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		sessions.EXPECT().
+			Refresh(gomock.Any(), "refresh", nil).
+			Return(nil, fmt.Errorf("some db error"))
+		ctx, rec := newSessionContext("POST", "/api/user/refresh", `{"refresh_token": "refresh"}`, nil)
+		ctrl.Refresh(ctx)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestSessionController_Logout(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	sessions := mock.NewMockSessionService(mockCtrl)
+	ctrl := NewSessionController(sessions)
+	claims := &auth.Claims{Username: "testuser", SessionID: "session"}
+
+	t.Run("ok", func(t *testing.T) {
+		sessions.EXPECT().Logout(gomock.Any(), claims).Return(nil)
+		ctx, rec := newSessionContext("POST", "/api/user/logout", "", claims)
+		ctrl.Logout(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("no_claims", func(t *testing.T) {
+		ctx, rec := newSessionContext("POST", "/api/user/logout", "", nil)
+		ctrl.Logout(ctx)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("error", func(t *testing.T) {
+		sessions.EXPECT().Logout(gomock.Any(), claims).Return(fmt.Errorf("some db error"))
+		ctx, rec := newSessionContext("POST", "/api/user/logout", "", claims)
+		ctrl.Logout(ctx)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestSessionController_List(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	sessions := mock.NewMockSessionService(mockCtrl)
+	ctrl := NewSessionController(sessions)
+	current, other := models.NewRandomObjectID(), models.NewRandomObjectID()
+	claims := &auth.Claims{Username: "testuser", SessionID: current.Hex()}
+
+	t.Run("ok", func(t *testing.T) {
+		sessions.EXPECT().
+			List(gomock.Any(), "testuser").
+			Return([]models.Session{{ID: other}, {ID: current}}, nil)
+		ctx, rec := newSessionContext("GET", "/api/user/sessions", "", claims)
+		ctrl.List(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var res []models.Session
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.Len(t, res, 2)
+		assert.False(t, res[0].Current)
+		assert.True(t, res[1].Current)
+	})
+	t.Run("no_claims", func(t *testing.T) {
+		ctx, rec := newSessionContext("GET", "/api/user/sessions", "", nil)
+		ctrl.List(ctx)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("error", func(t *testing.T) {
+		sessions.EXPECT().
+			List(gomock.Any(), "testuser").
+			Return(nil, fmt.Errorf("some db error"))
+		ctx, rec := newSessionContext("GET", "/api/user/sessions", "", claims)
+		ctrl.List(ctx)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestSessionController_Revoke(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	sessions := mock.NewMockSessionService(mockCtrl)
+	ctrl := NewSessionController(sessions)
+	id := models.NewRandomObjectID()
+	claims := &auth.Claims{Username: "testuser"}
+
+	newContext := func(sessionID string, claims *auth.Claims) (*gin.Context, *httptest.ResponseRecorder) {
+		ctx, rec := newSessionContext("DELETE", "/api/user/sessions/"+sessionID, "", claims)
+		ctx.Params = gin.Params{{Key: "sessionID", Value: sessionID}}
+		return ctx, rec
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		sessions.EXPECT().Revoke(gomock.Any(), "testuser", id).Return(nil)
+		ctx, rec := newContext(id.Hex(), claims)
+		ctrl.Revoke(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+	t.Run("bad_id", func(t *testing.T) {
+		ctx, rec := newContext("bad-id", claims)
+		ctrl.Revoke(ctx)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("no_username", func(t *testing.T) {
+		ctx, rec := newContext(id.Hex(), nil)
+		ctrl.Revoke(ctx)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		sessions.EXPECT().Revoke(gomock.Any(), "testuser", id).Return(srvErrors.ErrSessionNotFound)
+		ctx, rec := newContext(id.Hex(), claims)
+		ctrl.Revoke(ctx)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("error", func(t *testing.T) {
+		sessions.EXPECT().Revoke(gomock.Any(), "testuser", id).Return(fmt.Errorf("some db error"))
+		ctx, rec := newContext(id.Hex(), claims)
+		ctrl.Revoke(ctx)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
@@ -4,9 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mitchellh/mapstructure"
 
 	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
 	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
@@ -22,10 +23,22 @@ type StorageController interface {
 	Store(ctx *gin.Context)
 	// GetAll returns all the untyped records from the database based on the data provided in the request.
 	GetAll(ctx *gin.Context)
+	// Get returns a single untyped record from the database by its ID.
+	Get(ctx *gin.Context)
 	// Updates the data and metadata of a document in the collection specified by the request URL.
 	Update(ctx *gin.Context)
 	// Delete deletes a record from the collection specified in the request URI.
 	Delete(ctx *gin.Context)
+	// History returns the previous revisions of a record.
+	History(ctx *gin.Context)
+	// Restore replaces a record with a revision from its history.
+	Restore(ctx *gin.Context)
+	// Trash returns the deleted records which have not been purged yet.
+	Trash(ctx *gin.Context)
+	// Undelete moves a record back from the trash.
+	Undelete(ctx *gin.Context)
+	// Purge permanently deletes a record from the trash.
+	Purge(ctx *gin.Context)
 }
 
 // storageController implements StorageController interface.
@@ -50,50 +63,55 @@ func (c *storageController) validateDataField(
 	data any,
 	collectionName models.CollectionName,
 ) error {
-	switch collectionName {
-	case models.CredentialsCollection:
-		var c models.CredentialInfo
-		err := mapstructure.Decode(data, &c)
-		if err != nil {
-			return err
-		}
-		err = validation.Validate.Struct(c)
-		return err
-	case models.CardCollection:
-		var c models.CardInfo
-		err := mapstructure.Decode(data, &c)
-		if err != nil {
-			return err
-		}
-		err = validation.Validate.Struct(c)
-		return err
-	case models.BinaryCollection:
-		var c models.BinaryInfo
-		err := mapstructure.Decode(data, &c)
-		if err != nil {
-			return err
-		}
-		err = validation.Validate.Struct(c)
+	return validation.ValidateRecordData(data, collectionName)
+}
+
+// validateContent validates the content of the record. The data of an end-to-end
+// encrypted record is opaque to the server, so only the envelope is checked and
+// the metadata is dropped since the client encrypts it together with the data.
+func (c *storageController) validateContent(
+	content *models.UntypedRecordContent,
+	collectionName models.CollectionName,
+) error {
+	if models.IsEncryptedData(content.Data) {
+		content.Metadata = nil
+		_, err := models.EncryptedCiphertext(content.Data)
 		return err
-	default:
-		return nil
 	}
+	return c.validateDataField(content.Data, collectionName)
+}
+
+// publish notifies the user's clients about the change of the record.
+func (c *storageController) publish(
+	username string,
+	collectionName models.CollectionName,
+	recordID models.ObjectID,
+	op models.ChangeOp,
+) {
+	c.sync.Publish(username, models.ChangeEvent{
+		Collection: collectionName,
+		RecordID:   recordID,
+		Op:         op,
+	})
 }
 
 // Store godoc
 //
 //	@Summary Store an untyped record to the database.
 //	@Security bearerAuth
-//	@Description Stores an untyped record to the database based on the data provided in the request.
+//	@Description Stores an untyped record to the database based on the data provided in the request. The data of an end-to-end encrypted record of any collection is an envelope {"encrypted": "<base64 ciphertext>"}; such records are not validated and their metadata is dropped.
+//	@Description The client may choose the ID of the record with record_id, e.g. to bind the encrypted data to it; otherwise the ID is generated.
+//	@Description The blob a binary record refers to must be uploaded completely by the user.
 //	@Accept json
 //	@Produce plain
 //	@ID Store
 //	@Tags Storage
-//	@Param	record	body	models.UntypedRecordContent	true	"Record"
+//	@Param	record	body	storeRequestBody	true	"Record"
 //	@Param        collectionName   path      string  true  "Collection name"
 //	@Success 202 {string}	string	"Record added to collection"
 //	@Failure 400 {string}	string	"Bad Request"
 //	@Failure 401 {string}	string	"No username provided"
+//	@Failure 409 {string}	string	"Record with the id already exists"
 //	@Router /api/store/{collectionName} [put]
 func (c *storageController) Store(ctx *gin.Context) {
 	username := ctx.GetString(middleware.UsernameContextValue)
@@ -101,29 +119,37 @@ func (c *storageController) Store(ctx *gin.Context) {
 		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
 		return
 	}
-	record := models.UntypedRecord{
-		Username: username,
-	}
-	if err := ctx.ShouldBindJSON(&record.UntypedRecordContent); err != nil {
+	var body storeRequestBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
 	}
+	record := models.UntypedRecord{
+		UntypedRecordContent: body.UntypedRecordContent,
+		RecordID:             body.RecordID,
+		Username:             username,
+	}
 	collectionName, err := models.NewCollectionName(ctx.Param("collectionName"))
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := c.validateDataField(record.Data, collectionName); err != nil {
+	if err := c.validateContent(&record.UntypedRecordContent, collectionName); err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
 	}
 
 	id, err := c.service.Store(ctx.Request.Context(), collectionName, record)
-	if err != nil {
+	if errors.Is(err, srvErrors.ErrRecordExists) {
+		ctx.String(http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
 	}
-	go c.sync.Signal(&models.Client{Username: username})
+	if recordID, err := models.ObjectIDFromString(id); err == nil {
+		c.publish(username, collectionName, recordID, models.OpCreate)
+	}
 	ctx.String(
 		http.StatusAccepted,
 		fmt.Sprintf(
@@ -138,15 +164,21 @@ func (c *storageController) Store(ctx *gin.Context) {
 
 // GetAll godoc
 //
-//	@Summary Retrieve all untyped records for the authenticated user from a collection.
-//	@Description Returns all the untyped records from the database based on the data provided in the request.
+//	@Summary Retrieve the untyped records of the authenticated user from a collection.
+//	@Description Returns the untyped records from the database based on the data provided in the request. The records are listed in pages when the limit is set: the cursor of the next page is returned in the X-Next-Cursor header, which is omitted for the last page.
 //	@Security bearerAuth
 //	@Accept json
 //	@Produce json
 //	@ID GetAll
 //	@Tags Storage
 //	@Param        collectionName   path      string  true  "Collection name"
+//	@Param        limit   query      int  false  "Maximum number of the records in the page (up to 1000); all the records by default"
+//	@Param        cursor   query      string  false  "Cursor of the page returned in X-Next-Cursor"
+//	@Param        sort   query      string  false  "Sort field: created (default) or updated, prefixed with - for the descending order"
+//	@Param        metadata.key   query      string  false  "Value the metadata key of the records must have; any key may be used"
+//	@Param        omit_content   query      bool  false  "Omit the content of the binary records"
 //	@Success 200 {array}	models.UntypedRecord	"Record added by the user in the specified collection"
+//	@Header 200 {string}	X-Next-Cursor	"Cursor of the next page"
 //	@Failure 400 {string}	string	"Bad Request"
 //	@Failure 401 {string}	string	"No username provided"
 //	@Router /api/store/{collectionName} [get]
@@ -161,14 +193,107 @@ func (c *storageController) GetAll(ctx *gin.Context) {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
 	}
-	records, err := c.service.GetAll(ctx.Request.Context(), collectionName, username)
+	options, err := getAllOptions(ctx)
 	if err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
 	}
+	records, cursor, err := c.service.GetAll(
+		ctx.Request.Context(),
+		collectionName,
+		username,
+		options,
+	)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if cursor != "" {
+		ctx.Header(models.NextCursorHeader, cursor)
+	}
 	ctx.JSON(http.StatusOK, records)
 }
 
+// metadataQueryPrefix is the prefix of the query parameters which filter the records by their metadata.
+const metadataQueryPrefix = "metadata."
+
+// getAllOptions reads the options of the listing of the records from the query.
+func getAllOptions(ctx *gin.Context) (models.GetAllOptions, error) {
+	options := models.GetAllOptions{
+		Cursor: ctx.Query("cursor"),
+		Sort:   ctx.Query("sort"),
+	}
+	var err error
+	if limit := ctx.Query("limit"); limit != "" {
+		if options.Limit, err = strconv.ParseInt(limit, 10, 64); err != nil {
+			return options, fmt.Errorf("%w: bad limit %q", srvErrors.ErrBadListOptions, limit)
+		}
+	}
+	if omit := ctx.Query("omit_content"); omit != "" {
+		if options.OmitContent, err = strconv.ParseBool(omit); err != nil {
+			return options, fmt.Errorf("%w: bad omit_content %q", srvErrors.ErrBadListOptions, omit)
+		}
+	}
+	for key, values := range ctx.Request.URL.Query() {
+		if !strings.HasPrefix(key, metadataQueryPrefix) || len(values) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(key, metadataQueryPrefix)
+		if name == "" {
+			return options, fmt.Errorf("%w: empty metadata key", srvErrors.ErrBadListOptions)
+		}
+		if options.Metadata == nil {
+			options.Metadata = make(models.Metadata)
+		}
+		options.Metadata[name] = values[0]
+	}
+	return options, options.Validate()
+}
+
+// Get godoc
+//
+//	@Summary Retrieve a single record of the authenticated user by ID.
+//	@Description Returns the untyped record with the specified ID from the collection. Clients use it to fetch the record mentioned in a change event.
+//	@Security bearerAuth
+//	@Produce json
+//	@ID Get
+//	@Tags Storage
+//	@Param        collectionName   path      string  true  "Collection name"
+//	@Param        recordID   path      string  true  "Record ID"
+//	@Success 200 {object}	models.UntypedRecord	"Record"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 404 {string}	string	"Record not found"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/store/{collectionName}/{recordID} [get]
+func (c *storageController) Get(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	collectionName, err := models.NewCollectionName(ctx.Param("collectionName"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := models.ObjectIDFromString(ctx.Param("recordID"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	record, err := c.service.Get(ctx.Request.Context(), collectionName, username, id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.String(status, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, record)
+}
+
 // Update godoc
 //
 //	@Summary Update an existing record in the database.
@@ -178,11 +303,12 @@ func (c *storageController) GetAll(ctx *gin.Context) {
 //	@Produce plain
 //	@ID Update
 //	@Tags Storage
-//	@Param	record	body	models.UntypedRecord	true	"Record"
+//	@Param	record	body	updateRequestBody	true	"Record"
 //	@Param        collectionName   path      string  true  "Collection name"
 //	@Success 202 {string}	string	"Record updated"
 //	@Failure 400 {string}	string	"Bad Request"
 //	@Failure 401 {string}	string	"No username provided"
+//	@Failure 409 {object}	models.UntypedRecord	"Current record if its version is not the expected one"
 //	@Failure 500 {string}	string	"Server error"
 //	@Router /api/store/{collectionName} [post]
 func (c *storageController) Update(ctx *gin.Context) {
@@ -191,10 +317,8 @@ func (c *storageController) Update(ctx *gin.Context) {
 		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
 		return
 	}
-	record := models.UntypedRecord{
-		Username: username,
-	}
-	if err := ctx.ShouldBindJSON(&record); err != nil {
+	var body updateRequestBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
 	}
@@ -203,44 +327,72 @@ func (c *storageController) Update(ctx *gin.Context) {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := c.validateDataField(record.Data, collectionName); err != nil {
+	record := body.UntypedRecord
+	if err := c.validateContent(&record.UntypedRecordContent, collectionName); err != nil {
 		ctx.String(http.StatusBadRequest, err.Error())
 		return
 	}
 
-	err = c.service.Update(
+	updated, err := c.service.Update(
 		ctx.Request.Context(),
 		collectionName,
 		username,
 		record.RecordID,
 		record.Data,
 		record.Metadata,
+		body.ExpectedVersion,
 	)
-	if err != nil {
+	if errors.Is(err, srvErrors.ErrVersionConflict) {
+		ctx.JSON(http.StatusConflict, updated)
+		return
+	} else if err != nil {
 		status := http.StatusInternalServerError
-		if errors.Is(err, srvErrors.ErrRecordNotFound) {
+		if errors.Is(err, srvErrors.ErrRecordNotFound) ||
+			errors.Is(err, srvErrors.ErrBlobNotFound) ||
+			errors.Is(err, srvErrors.ErrBlobIncomplete) ||
+			errors.Is(err, srvErrors.ErrBlobInUse) {
 			status = http.StatusBadRequest
 		}
 		ctx.String(status, err.Error())
 		return
 	}
-	go c.sync.Signal(&models.Client{Username: username})
+	c.publish(username, collectionName, record.RecordID, models.OpUpdate)
 	ctx.String(
 		http.StatusAccepted,
 		fmt.Sprintf(
-			"Record id=%v updated in %v collection: data=%v metadata=%v",
+			"Record id=%v updated in %v collection: data=%v metadata=%v version=%v",
 			record.RecordID,
 			collectionName,
 			record.Data,
 			record.Metadata,
+			updated.Version,
 		),
 	)
 }
 
+// storeRequestBody is the body of a store request. The ID of the record
+// is optional and generated by the server if it's zero.
+type storeRequestBody struct {
+	RecordID models.ObjectID `json:"record_id" swaggertype:"string"`
+	models.UntypedRecordContent
+}
+
+// updateRequestBody is the body of an update request. If the expected version
+// is not zero, the record is updated only if it has this version.
+type updateRequestBody struct {
+	models.UntypedRecord
+	ExpectedVersion int64 `json:"expected_version"`
+}
+
+// deleteRequestBody describes the body of a delete request in the API documentation.
+type deleteRequestBody struct {
+	RecordID string `json:"record_id" binding:"required"`
+}
+
 // Delete godoc
 //
 //	@Summary Delete a record by ID
-//	@Description Deletes a record from the specified collection by ID.
+//	@Description Moves a record of the specified collection to the trash by ID. The deleted record is not returned with the other records, but it can be restored until it is purged.
 //	@Security bearerAuth
 //	@Accept json
 //	@Produce plain
@@ -284,9 +436,240 @@ func (c *storageController) Delete(ctx *gin.Context) {
 		ctx.String(status, err.Error())
 		return
 	}
-	go c.sync.Signal(&models.Client{Username: username})
+	c.publish(username, collectionName, record.RecordID, models.OpDelete)
+	ctx.String(
+		http.StatusOK,
+		fmt.Sprintf("Record id=%v moved to the trash of %v collection", record.RecordID, collectionName),
+	)
+}
+
+// History godoc
+//
+//	@Summary Retrieve the previous revisions of a record.
+//	@Description Returns the revisions replaced by the updates and the deletion of the record, the latest first. The revisions are kept for the retention period configured on the server.
+//	@Security bearerAuth
+//	@Produce json
+//	@ID History
+//	@Tags Storage
+//	@Param        collectionName   path      string  true  "Collection name"
+//	@Param        recordID   path      string  true  "Record ID"
+//	@Success 200 {array}	models.Revision	"Revisions"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/store/{collectionName}/{recordID}/history [get]
+func (c *storageController) History(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	collectionName, err := models.NewCollectionName(ctx.Param("collectionName"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := models.ObjectIDFromString(ctx.Param("recordID"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	revisions, err := c.service.History(ctx.Request.Context(), collectionName, username, id)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, revisions)
+}
+
+// Restore godoc
+//
+//	@Summary Restore a record from its history.
+//	@Description Replaces the record with the revision rev from its history. The replaced state is archived as well. A deleted record is recreated with the same ID.
+//	@Security bearerAuth
+//	@Produce plain
+//	@ID Restore
+//	@Tags Storage
+//	@Param        collectionName   path      string  true  "Collection name"
+//	@Param        recordID   path      string  true  "Record ID"
+//	@Param        rev   query      int  true  "Revision"
+//	@Success 202 {string}	string	"Record restored"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 404 {string}	string	"Revision not found"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/store/{collectionName}/{recordID}/restore [post]
+func (c *storageController) Restore(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	collectionName, err := models.NewCollectionName(ctx.Param("collectionName"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := models.ObjectIDFromString(ctx.Param("recordID"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	rev, err := strconv.ParseInt(ctx.Query("rev"), 10, 64)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, "bad revision: %v", err)
+		return
+	}
+	record, err := c.service.Restore(ctx.Request.Context(), collectionName, username, id, rev)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrRevisionNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.String(status, err.Error())
+		return
+	}
+	c.publish(username, collectionName, id, models.OpUpdate)
+	ctx.String(
+		http.StatusAccepted,
+		fmt.Sprintf(
+			"Record id=%v restored from revision %v in %v collection: version=%v",
+			id,
+			rev,
+			collectionName,
+			record.Version,
+		),
+	)
+}
+
+// Trash godoc
+//
+//	@Summary Retrieve the deleted records of the authenticated user from a collection.
+//	@Description Returns the records moved to the trash which have not been purged yet. The records are purged automatically after the TTL configured on the server.
+//	@Security bearerAuth
+//	@Produce json
+//	@ID Trash
+//	@Tags Storage
+//	@Param        collectionName   path      string  true  "Collection name"
+//	@Success 200 {array}	models.UntypedRecord	"Deleted records"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/store/{collectionName}/trash [get]
+func (c *storageController) Trash(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	collectionName, err := models.NewCollectionName(ctx.Param("collectionName"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	records, err := c.service.Trash(ctx.Request.Context(), collectionName, username)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, records)
+}
+
+// Undelete godoc
+//
+//	@Summary Restore a record from the trash.
+//	@Description Moves the deleted record back from the trash. The version of the record is incremented.
+//	@Security bearerAuth
+//	@Produce plain
+//	@ID Undelete
+//	@Tags Storage
+//	@Param        collectionName   path      string  true  "Collection name"
+//	@Param        recordID   path      string  true  "Record ID"
+//	@Success 202 {string}	string	"Record restored"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 404 {string}	string	"Record not found in the trash"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/store/{collectionName}/trash/{recordID}/restore [post]
+func (c *storageController) Undelete(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	collectionName, err := models.NewCollectionName(ctx.Param("collectionName"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := models.ObjectIDFromString(ctx.Param("recordID"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	record, err := c.service.Undelete(ctx.Request.Context(), collectionName, username, id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.String(status, err.Error())
+		return
+	}
+	c.publish(username, collectionName, id, models.OpCreate)
+	ctx.String(
+		http.StatusAccepted,
+		fmt.Sprintf(
+			"Record id=%v restored from the trash of %v collection: version=%v",
+			id,
+			collectionName,
+			record.Version,
+		),
+	)
+}
+
+// Purge godoc
+//
+//	@Summary Permanently delete a record from the trash.
+//	@Description Deletes the record in the trash permanently together with its history.
+//	@Security bearerAuth
+//	@Produce plain
+//	@ID Purge
+//	@Tags Storage
+//	@Param        collectionName   path      string  true  "Collection name"
+//	@Param        recordID   path      string  true  "Record ID"
+//	@Success 200 {string}	string	"Record purged"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 404 {string}	string	"Record not found in the trash"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/store/{collectionName}/trash/{recordID} [delete]
+func (c *storageController) Purge(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	collectionName, err := models.NewCollectionName(ctx.Param("collectionName"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := models.ObjectIDFromString(ctx.Param("recordID"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := c.service.Purge(ctx.Request.Context(), collectionName, username, id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.String(status, err.Error())
+		return
+	}
 	ctx.String(
 		http.StatusOK,
-		fmt.Sprintf("Record id=%v deleted from %v collection", record.RecordID, collectionName),
+		fmt.Sprintf("Record id=%v permanently deleted from %v collection", id, collectionName),
 	)
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
 	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
@@ -93,8 +94,28 @@ func TestStorageController_ValidateDataField(t *testing.T) {
 		err := ctrl.validateDataField(binary, models.BinaryCollection)
 		assert.Error(t, err)
 	})
+	t.Run("ok_otp", func(t *testing.T) {
+		// Test case 7: Successful validation for OTPCollection
+		otp := models.OTPInfo{
+			Secret:    "JBSWY3DPEHPK3PXP",
+			Algorithm: "SHA256",
+			Digits:    "8",
+			Period:    "30",
+		}
+		err := ctrl.validateDataField(otp, models.OTPCollection)
+		assert.NoError(t, err)
+	})
+	t.Run("bad_otp", func(t *testing.T) {
+		// Test case 8: Failed validation for OTPCollection
+		otp := models.OTPInfo{
+			Secret: "not-base32!",
+			Digits: "4",
+		}
+		err := ctrl.validateDataField(otp, models.OTPCollection)
+		assert.Error(t, err)
+	})
 	t.Run("other", func(t *testing.T) {
-		// Test case 7: No validation required for other collections
+		// Test case 9: No validation required for other collections
 		data := "test"
 		err := ctrl.validateDataField(data, "text")
 		assert.NoError(t, err)
@@ -184,8 +205,13 @@ func TestStorageController_Store(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
 		storage.EXPECT().
 			Store(gomock.Any(), gomock.Eq(models.CollectionName("credentials")), gomock.Any()).
-			Return("some-id", nil)
-		sync.EXPECT().Signal(gomock.Any()).AnyTimes()
+			Return("645b34a19affed5a60fcfadd", nil)
+		recordID, _ := models.ObjectIDFromString("645b34a19affed5a60fcfadd")
+		sync.EXPECT().Publish("username", models.ChangeEvent{
+			Collection: models.CredentialsCollection,
+			RecordID:   recordID,
+			Op:         models.OpCreate,
+		})
 		reqBody := bytes.NewBufferString(
 			`{"data": {"login": "user123", "password": "password123"}}`,
 		)
@@ -205,6 +231,93 @@ func TestStorageController_Store(t *testing.T) {
 			"Record added to credentials collection",
 		)
 	})
+	t.Run("client_id", func(t *testing.T) {
+		recordID := models.NewRandomObjectID()
+		storage.EXPECT().
+			Store(gomock.Any(), models.TextCollection, models.UntypedRecord{
+				UntypedRecordContent: models.UntypedRecordContent{Data: "some text"},
+				RecordID:             recordID,
+				Username:             "username",
+			}).
+			Return(recordID.Hex(), nil)
+		sync.EXPECT().Publish("username", models.ChangeEvent{
+			Collection: models.TextCollection,
+			RecordID:   recordID,
+			Op:         models.OpCreate,
+		})
+		reqBody := bytes.NewBufferString(
+			fmt.Sprintf(`{"record_id": %q, "data": "some text"}`, recordID.Hex()),
+		)
+		req, _ := http.NewRequest("POST", "/collections/text", reqBody)
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(ctx.Params, gin.Param{Key: "collectionName", Value: "text"})
+		ctx.Set(middleware.UsernameContextValue, "username")
+
+		ctrl.Store(ctx)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+	t.Run("client_id_taken", func(t *testing.T) {
+		storage.EXPECT().
+			Store(gomock.Any(), models.TextCollection, gomock.Any()).
+			Return("", srvErrors.ErrRecordExists)
+		reqBody := bytes.NewBufferString(
+			`{"record_id": "645b34a19affed5a60fcfadd", "data": "some text"}`,
+		)
+		req, _ := http.NewRequest("POST", "/collections/text", reqBody)
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(ctx.Params, gin.Param{Key: "collectionName", Value: "text"})
+		ctx.Set(middleware.UsernameContextValue, "username")
+
+		ctrl.Store(ctx)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+	t.Run("encrypted", func(t *testing.T) {
+		// the envelope is not validated as a card and the plain metadata is dropped
+		storage.EXPECT().
+			Store(gomock.Any(), models.CardCollection, models.UntypedRecord{
+				Username: "username",
+				UntypedRecordContent: models.UntypedRecordContent{
+					Data: map[string]any{models.EncryptedField: "Y2lwaGVydGV4dA=="},
+				},
+			}).
+			Return("645b34a19affed5a60fcfadd", nil)
+		sync.EXPECT().Publish("username", gomock.Any())
+		reqBody := bytes.NewBufferString(
+			`{"data": {"encrypted": "Y2lwaGVydGV4dA=="}, "metadata": {"bank": "some bank"}}`,
+		)
+		req, _ := http.NewRequest("POST", "/collections/cards", reqBody)
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(ctx.Params, gin.Param{Key: "collectionName", Value: "cards"})
+		ctx.Set(middleware.UsernameContextValue, "username")
+
+		ctrl.Store(ctx)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+	t.Run("bad_envelope", func(t *testing.T) {
+		reqBody := bytes.NewBufferString(
+			`{"data": {"encrypted": "Y2lwaGVydGV4dA==", "CardNumber": "4111111111111111"}}`,
+		)
+		req, _ := http.NewRequest("POST", "/collections/cards", reqBody)
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(ctx.Params, gin.Param{Key: "collectionName", Value: "cards"})
+		ctx.Set(middleware.UsernameContextValue, "username")
+
+		ctrl.Store(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, srvErrors.ErrBadEnvelope.Error(), rec.Body.String())
+	})
 }
 
 func TestStorageController_GetAll(t *testing.T) {
@@ -235,8 +348,8 @@ func TestStorageController_GetAll(t *testing.T) {
 			},
 		}
 		storage.EXPECT().
-			GetAll(gomock.Any(), models.CollectionName("credentials"), username).
-			Return(expectedRecords, nil)
+			GetAll(gomock.Any(), models.CredentialsCollection, username, models.GetAllOptions{}).
+			Return(expectedRecords, "", nil)
 		req, _ := http.NewRequest("GET", "/collections/credentials", nil)
 		rec := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(rec)
@@ -258,8 +371,8 @@ func TestStorageController_GetAll(t *testing.T) {
 
 	t.Run("bad_response", func(t *testing.T) {
 		storage.EXPECT().
-			GetAll(gomock.Any(), models.CollectionName("credentials"), username).
-			Return(nil, fmt.Errorf("some error"))
+			GetAll(gomock.Any(), models.CredentialsCollection, username, models.GetAllOptions{}).
+			Return(nil, "", fmt.Errorf("some error"))
 		req, _ := http.NewRequest("GET", "/collections/credentials", nil)
 		rec := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(rec)
@@ -300,6 +413,139 @@ func TestStorageController_GetAll(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 
+	t.Run("page", func(t *testing.T) {
+		options := models.GetAllOptions{
+			Limit:       10,
+			Sort:        "-updated",
+			Metadata:    models.Metadata{"site": "github.com"},
+			OmitContent: true,
+		}
+		storage.EXPECT().
+			GetAll(gomock.Any(), models.BinaryCollection, username, options).
+			Return([]models.UntypedRecord{}, "next", nil)
+		req, _ := http.NewRequest(
+			"GET",
+			"/collections/binary?limit=10&sort=-updated&metadata.site=github.com&omit_content=true",
+			nil,
+		)
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(ctx.Params, gin.Param{Key: "collectionName", Value: "binary"})
+		ctx.Set(middleware.UsernameContextValue, username)
+
+		ctrl.GetAll(ctx)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "next", rec.Header().Get(models.NextCursorHeader))
+		assert.Equal(t, "[]", rec.Body.String())
+	})
+
+	badQueries := []string{
+		"limit=abc",
+		"limit=5000",
+		"sort=name",
+		"omit_content=maybe",
+		"metadata.=x",
+	}
+	for _, query := range badQueries {
+		t.Run("bad_options_"+query, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/collections/text?"+query, nil)
+			rec := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(rec)
+			ctx.Request = req
+			ctx.Params = append(ctx.Params, gin.Param{Key: "collectionName", Value: "text"})
+			ctx.Set(middleware.UsernameContextValue, username)
+
+			ctrl.GetAll(ctx)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestStorageController_Get(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	// create a new storageController instance with a mocked service
+	storage := mock.NewMockStorageService(mockCtrl)
+	sync := mock.NewMockSyncService(mockCtrl)
+	ctrl := NewStorageController(storage, sync)
+
+	username := "testuser"
+	recordID := models.NewRandomObjectID()
+	newContext := func(rec *httptest.ResponseRecorder, collectionName, id string) *gin.Context {
+		req, _ := http.NewRequest("GET", "/api/store/"+collectionName+"/"+id, nil)
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(
+			ctx.Params,
+			gin.Param{Key: "collectionName", Value: collectionName},
+			gin.Param{Key: "recordID", Value: id},
+		)
+		ctx.Set(middleware.UsernameContextValue, username)
+		return ctx
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		expected := &models.UntypedRecord{
+			RecordID: recordID,
+			UntypedRecordContent: models.UntypedRecordContent{
+				Data:     "some text",
+				Metadata: models.Metadata{"k": "v"},
+			},
+		}
+		storage.EXPECT().
+			Get(gomock.Any(), models.TextCollection, username, recordID).
+			Return(expected, nil)
+		rec := httptest.NewRecorder()
+		ctrl.Get(newContext(rec, "text", recordID.Hex()))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response models.UntypedRecord
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, *expected, response)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		storage.EXPECT().
+			Get(gomock.Any(), models.TextCollection, username, recordID).
+			Return(nil, srvErrors.ErrRecordNotFound)
+		rec := httptest.NewRecorder()
+		ctrl.Get(newContext(rec, "text", recordID.Hex()))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("service_error", func(t *testing.T) {
+		storage.EXPECT().
+			Get(gomock.Any(), models.TextCollection, username, recordID).
+			Return(nil, fmt.Errorf("some error"))
+		rec := httptest.NewRecorder()
+		ctrl.Get(newContext(rec, "text", recordID.Hex()))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+	t.Run("bad_id", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ctrl.Get(newContext(rec, "text", "bad"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("bad_collection", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ctrl.Get(newContext(rec, "invalid-collection", recordID.Hex()))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("no_username", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request, _ = http.NewRequest("GET", "/api/store/text/"+recordID.Hex(), nil)
+
+		ctrl.Get(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
 }
 
 func TestStorageController_Update(t *testing.T) {
@@ -343,10 +589,14 @@ func TestStorageController_Update(t *testing.T) {
 
 	t.Run("ok", func(t *testing.T) {
 		storage.EXPECT().
-			Update(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
-			Return(nil)
-		sync.EXPECT().Signal(gomock.Any()).AnyTimes()
+			Update(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&models.UntypedRecord{Version: 2}, nil)
 		recordID := models.NewRandomObjectID()
+		sync.EXPECT().Publish(username, models.ChangeEvent{
+			Collection: models.CredentialsCollection,
+			RecordID:   recordID,
+			Op:         models.OpUpdate,
+		})
 		record := models.UntypedRecord{
 			RecordID: recordID,
 			Username: username,
@@ -375,12 +625,55 @@ func TestStorageController_Update(t *testing.T) {
 		ctrl.Update(ctx)
 
 		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Contains(t, rec.Body.String(), "version=2")
+	})
+	t.Run("conflict", func(t *testing.T) {
+		recordID := models.NewRandomObjectID()
+		current := &models.UntypedRecord{
+			RecordID: recordID,
+			Version:  3,
+			UntypedRecordContent: models.UntypedRecordContent{
+				Data: map[string]any{"login": "jane", "password": "password456"},
+			},
+		}
+		storage.EXPECT().
+			Update(
+				gomock.Any(),
+				models.CredentialsCollection,
+				username,
+				recordID,
+				gomock.Any(),
+				gomock.Any(),
+				int64(2),
+			).
+			Return(current, srvErrors.ErrVersionConflict)
+
+		data := fmt.Sprintf(
+			`{"record_id": %q, "data": {"login": "john", "password": "password123"}, "expected_version": 2}`,
+			recordID.Hex(),
+		)
+		req, _ := http.NewRequest("POST", "/api/store/credentials", bytes.NewBufferString(data))
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Set(middleware.UsernameContextValue, username)
+		ctx.Params = append(
+			ctx.Params,
+			gin.Param{Key: "collectionName", Value: "credentials"},
+		)
+
+		ctrl.Update(ctx)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		var got models.UntypedRecord
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, int64(3), got.Version)
+		assert.Equal(t, recordID, got.RecordID)
 	})
 	t.Run("not_found", func(t *testing.T) {
 		storage.EXPECT().
-			Update(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
-			Return(srvErrors.ErrRecordNotFound)
-		sync.EXPECT().Signal(gomock.Any()).AnyTimes()
+			Update(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, srvErrors.ErrRecordNotFound)
 		recordID := models.NewRandomObjectID()
 		record := models.UntypedRecord{
 			RecordID: recordID,
@@ -411,11 +704,30 @@ func TestStorageController_Update(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
+	t.Run("incomplete_blob", func(t *testing.T) {
+		storage.EXPECT().
+			Update(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, srvErrors.ErrBlobIncomplete)
+		data := fmt.Sprintf(
+			`{"record_id": %q, "data": {"FileName": "report.pdf", "BlobID": %q}}`,
+			models.NewRandomObjectID().Hex(),
+			models.NewRandomObjectID().Hex(),
+		)
+		req, _ := http.NewRequest("POST", "/api/store/binary", bytes.NewBufferString(data))
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Set(middleware.UsernameContextValue, username)
+		ctx.Params = append(ctx.Params, gin.Param{Key: "collectionName", Value: "binary"})
+
+		ctrl.Update(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
 	t.Run("service_error", func(t *testing.T) {
 		storage.EXPECT().
-			Update(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
-			Return(fmt.Errorf("some error"))
-		sync.EXPECT().Signal(gomock.Any()).AnyTimes()
+			Update(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("some error"))
 		recordID := models.NewRandomObjectID()
 		record := models.UntypedRecord{
 			RecordID: recordID,
@@ -556,9 +868,12 @@ func TestStorageController_Delete(t *testing.T) {
 		storage.EXPECT().
 			Delete(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 			Return(nil)
-		sync.EXPECT().Signal(gomock.Any()).AnyTimes()
-
 		recordID := models.NewRandomObjectID()
+		sync.EXPECT().Publish(username, models.ChangeEvent{
+			Collection: models.CredentialsCollection,
+			RecordID:   recordID,
+			Op:         models.OpDelete,
+		})
 		record := deleteRequestBody{recordID}
 		data, _ := json.Marshal(record)
 		fmt.Println(">>>>>", string(data))
@@ -580,7 +895,6 @@ func TestStorageController_Delete(t *testing.T) {
 		storage.EXPECT().
 			Delete(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 			Return(fmt.Errorf("some error"))
-		sync.EXPECT().Signal(gomock.Any()).AnyTimes()
 
 		recordID := models.NewRandomObjectID()
 		record := deleteRequestBody{recordID}
@@ -604,7 +918,6 @@ func TestStorageController_Delete(t *testing.T) {
 		storage.EXPECT().
 			Delete(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 			Return(srvErrors.ErrRecordNotFound)
-		sync.EXPECT().Signal(gomock.Any()).AnyTimes()
 
 		recordID := models.NewRandomObjectID()
 		record := deleteRequestBody{recordID}
@@ -625,3 +938,297 @@ func TestStorageController_Delete(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 }
+
+func TestStorageController_History(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	storage := mock.NewMockStorageService(mockCtrl)
+	sync := mock.NewMockSyncService(mockCtrl)
+	ctrl := NewStorageController(storage, sync)
+
+	username := "testuser"
+	recordID := models.NewRandomObjectID()
+	newContext := func(rec *httptest.ResponseRecorder, collectionName, id string) *gin.Context {
+		req, _ := http.NewRequest("GET", "/api/store/"+collectionName+"/"+id+"/history", nil)
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(
+			ctx.Params,
+			gin.Param{Key: "collectionName", Value: collectionName},
+			gin.Param{Key: "recordID", Value: id},
+		)
+		ctx.Set(middleware.UsernameContextValue, username)
+		return ctx
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		expected := []models.Revision{{
+			UntypedRecordContent: models.UntypedRecordContent{Data: "old text"},
+			RecordID:             recordID,
+			Rev:                  1,
+			Op:                   models.OpUpdate,
+		}}
+		storage.EXPECT().
+			History(gomock.Any(), models.TextCollection, username, recordID).
+			Return(expected, nil)
+		rec := httptest.NewRecorder()
+		ctrl.History(newContext(rec, "text", recordID.Hex()))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response []models.Revision
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, expected, response)
+	})
+	t.Run("service_error", func(t *testing.T) {
+		storage.EXPECT().
+			History(gomock.Any(), models.TextCollection, username, recordID).
+			Return(nil, fmt.Errorf("some error"))
+		rec := httptest.NewRecorder()
+		ctrl.History(newContext(rec, "text", recordID.Hex()))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+	t.Run("bad_id", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ctrl.History(newContext(rec, "text", "bad"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("no_username", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request, _ = http.NewRequest("GET", "/api/store/text/"+recordID.Hex()+"/history", nil)
+
+		ctrl.History(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func TestStorageController_Restore(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	storage := mock.NewMockStorageService(mockCtrl)
+	sync := mock.NewMockSyncService(mockCtrl)
+	ctrl := NewStorageController(storage, sync)
+
+	username := "testuser"
+	recordID := models.NewRandomObjectID()
+	newContext := func(rec *httptest.ResponseRecorder, id, rev string) *gin.Context {
+		req, _ := http.NewRequest("POST", "/api/store/text/"+id+"/restore?rev="+rev, nil)
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(
+			ctx.Params,
+			gin.Param{Key: "collectionName", Value: "text"},
+			gin.Param{Key: "recordID", Value: id},
+		)
+		ctx.Set(middleware.UsernameContextValue, username)
+		return ctx
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		storage.EXPECT().
+			Restore(gomock.Any(), models.TextCollection, username, recordID, int64(2)).
+			Return(&models.UntypedRecord{RecordID: recordID, Version: 4}, nil)
+		sync.EXPECT().Publish(username, gomock.Any())
+		rec := httptest.NewRecorder()
+		ctrl.Restore(newContext(rec, recordID.Hex(), "2"))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Contains(t, rec.Body.String(), "restored from revision 2")
+		assert.Contains(t, rec.Body.String(), "version=4")
+	})
+	t.Run("revision_not_found", func(t *testing.T) {
+		storage.EXPECT().
+			Restore(gomock.Any(), models.TextCollection, username, recordID, int64(7)).
+			Return(nil, srvErrors.ErrRevisionNotFound)
+		rec := httptest.NewRecorder()
+		ctrl.Restore(newContext(rec, recordID.Hex(), "7"))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("service_error", func(t *testing.T) {
+		storage.EXPECT().
+			Restore(gomock.Any(), models.TextCollection, username, recordID, int64(1)).
+			Return(nil, fmt.Errorf("some error"))
+		rec := httptest.NewRecorder()
+		ctrl.Restore(newContext(rec, recordID.Hex(), "1"))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+	t.Run("bad_rev", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ctrl.Restore(newContext(rec, recordID.Hex(), "latest"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("bad_id", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ctrl.Restore(newContext(rec, "bad", "1"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestStorageController_Trash(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	storage := mock.NewMockStorageService(mockCtrl)
+	sync := mock.NewMockSyncService(mockCtrl)
+	ctrl := NewStorageController(storage, sync)
+
+	username := "testuser"
+	newContext := func(rec *httptest.ResponseRecorder, collectionName string) *gin.Context {
+		req, _ := http.NewRequest("GET", "/api/store/"+collectionName+"/trash", nil)
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(ctx.Params, gin.Param{Key: "collectionName", Value: collectionName})
+		ctx.Set(middleware.UsernameContextValue, username)
+		return ctx
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		expected := []models.UntypedRecord{{
+			UntypedRecordContent: models.UntypedRecordContent{Data: "deleted text"},
+			RecordID:             models.NewRandomObjectID(),
+		}}
+		storage.EXPECT().Trash(gomock.Any(), models.TextCollection, username).Return(expected, nil)
+		rec := httptest.NewRecorder()
+		ctrl.Trash(newContext(rec, "text"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response []models.UntypedRecord
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, expected, response)
+	})
+	t.Run("service_error", func(t *testing.T) {
+		storage.EXPECT().
+			Trash(gomock.Any(), models.TextCollection, username).
+			Return(nil, fmt.Errorf("some error"))
+		rec := httptest.NewRecorder()
+		ctrl.Trash(newContext(rec, "text"))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+	t.Run("bad_collection", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ctrl.Trash(newContext(rec, "invalid-collection"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestStorageController_Undelete(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	storage := mock.NewMockStorageService(mockCtrl)
+	sync := mock.NewMockSyncService(mockCtrl)
+	ctrl := NewStorageController(storage, sync)
+
+	username := "testuser"
+	recordID := models.NewRandomObjectID()
+	newContext := func(rec *httptest.ResponseRecorder, id string) *gin.Context {
+		req, _ := http.NewRequest("POST", "/api/store/text/trash/"+id+"/restore", nil)
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(
+			ctx.Params,
+			gin.Param{Key: "collectionName", Value: "text"},
+			gin.Param{Key: "recordID", Value: id},
+		)
+		ctx.Set(middleware.UsernameContextValue, username)
+		return ctx
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		storage.EXPECT().
+			Undelete(gomock.Any(), models.TextCollection, username, recordID).
+			Return(&models.UntypedRecord{RecordID: recordID, Version: 3}, nil)
+		sync.EXPECT().Publish(username, gomock.Any())
+		rec := httptest.NewRecorder()
+		ctrl.Undelete(newContext(rec, recordID.Hex()))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Contains(t, rec.Body.String(), "version=3")
+	})
+	t.Run("not_found", func(t *testing.T) {
+		storage.EXPECT().
+			Undelete(gomock.Any(), models.TextCollection, username, recordID).
+			Return(nil, srvErrors.ErrRecordNotFound)
+		rec := httptest.NewRecorder()
+		ctrl.Undelete(newContext(rec, recordID.Hex()))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("bad_id", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ctrl.Undelete(newContext(rec, "bad"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestStorageController_Purge(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	storage := mock.NewMockStorageService(mockCtrl)
+	sync := mock.NewMockSyncService(mockCtrl)
+	ctrl := NewStorageController(storage, sync)
+
+	username := "testuser"
+	recordID := models.NewRandomObjectID()
+	newContext := func(rec *httptest.ResponseRecorder, id string) *gin.Context {
+		req, _ := http.NewRequest("DELETE", "/api/store/text/trash/"+id, nil)
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		ctx.Params = append(
+			ctx.Params,
+			gin.Param{Key: "collectionName", Value: "text"},
+			gin.Param{Key: "recordID", Value: id},
+		)
+		ctx.Set(middleware.UsernameContextValue, username)
+		return ctx
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		storage.EXPECT().Purge(gomock.Any(), models.TextCollection, username, recordID).Return(nil)
+		rec := httptest.NewRecorder()
+		ctrl.Purge(newContext(rec, recordID.Hex()))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "permanently deleted")
+	})
+	t.Run("not_found", func(t *testing.T) {
+		storage.EXPECT().
+			Purge(gomock.Any(), models.TextCollection, username, recordID).
+			Return(srvErrors.ErrRecordNotFound)
+		rec := httptest.NewRecorder()
+		ctrl.Purge(newContext(rec, recordID.Hex()))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("service_error", func(t *testing.T) {
+		storage.EXPECT().
+			Purge(gomock.Any(), models.TextCollection, username, recordID).
+			Return(fmt.Errorf("some error"))
+		rec := httptest.NewRecorder()
+		ctrl.Purge(newContext(rec, recordID.Hex()))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+	t.Run("no_username", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request, _ = http.NewRequest("DELETE", "/api/store/text/trash/"+recordID.Hex(), nil)
+
+		ctrl.Purge(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
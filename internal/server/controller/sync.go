@@ -1,93 +1,129 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
-	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
 	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
-	"github.com/blokhinnv/gophkeeper/internal/server/models"
 	"github.com/blokhinnv/gophkeeper/internal/server/service"
 )
 
 // SyncController defines the interface for handling sync-related HTTP requests.
 type SyncController interface {
-	Register(ctx *gin.Context)
-	Unregister(ctx *gin.Context)
+	// Events streams the change events of the user's records.
+	Events(ctx *gin.Context)
+	// Changes returns the changes of the user's records since the cursor.
+	Changes(ctx *gin.Context)
 }
 
 // syncController implements the SyncController interface.
 type syncController struct {
-	service service.SyncService
+	service  service.SyncService
+	storage  service.StorageService
+	trashTTL time.Duration
 }
 
 // NewSyncController creates a new SyncController instance with the given SyncService.
-func NewSyncController(service service.SyncService) SyncController {
+// The changes are read from the storage service; the cursors older than
+// trashTTL are reset.
+func NewSyncController(
+	service service.SyncService,
+	storage service.StorageService,
+	trashTTL time.Duration,
+) SyncController {
 	return &syncController{
-		service: service,
+		service:  service,
+		storage:  storage,
+		trashTTL: trashTTL,
 	}
 }
 
-// Register godoc
+// Events godoc
 //
-//	@Summary Registers a new client with the synchronization service.
+//	@Summary Stream the changes of the user's records.
 //	@Security bearerAuth
-//	@Description Allows a client to register with the synchronization service.
-//	@Accept json
-//	@Produce plain
-//	@ID RegisterClient
+//	@Description Streams server-sent events named "change" every time a record of the user is created, updated or deleted by any client. The stream is closed by the server on shutdown, when the access token expires, when its session or device is revoked or if the client does not keep up with the events; the client should reconnect and sync all the data then.
+//	@Produce text/event-stream
+//	@ID Events
 //	@Tags Sync
-//	@Param	client	body	models.Client	true	"Client"
-//	@Success 200 {string}	string	"client registered"
-//	@Failure 400 {string}	string	"Bad Request"
+//	@Success 200 {object}	models.ChangeEvent	"Stream of change events"
 //	@Failure 401 {string}	string	"No username provided"
-//	@Router /api/sync/register [post]
-func (s *syncController) Register(ctx *gin.Context) {
-	username := ctx.GetString(middleware.UsernameContextValue)
-	if username == "" {
-		ctx.String(http.StatusUnauthorized, errors.ErrNoUsernameProvided.Error())
+//	@Router /api/sync/events [get]
+func (s *syncController) Events(ctx *gin.Context) {
+	claims := middleware.ClaimsFromGinContext(ctx)
+	if claims == nil {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
 		return
 	}
-	client := &models.Client{
-		Username: username,
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
 	}
-	if err := ctx.ShouldBindJSON(&client); err != nil {
-		ctx.String(http.StatusBadRequest, err.Error())
-		return
+	events, unsubscribe := s.service.Subscribe(
+		claims.Username,
+		claims.DeviceID,
+		claims.SessionID,
+		expiresAt,
+	)
+	defer unsubscribe()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			ctx.SSEvent("change", event)
+			ctx.Writer.Flush()
+		}
 	}
-	s.service.Register(client)
-	ctx.String(http.StatusOK, "client registered")
 }
 
-// Unregister godoc
+// Changes godoc
 //
-//	@Summary Unregisters an existing client from the server.
+//	@Summary Get the changes of the user's records since the cursor.
 //	@Security bearerAuth
-//	@Description Allows a client to unregister from the synchronization service.
-//	@Accept json
-//	@Produce plain
-//	@ID UnregisterClient
+//	@Description Returns the records of all the collections created or updated since the cursor and the tombstones of the records deleted since then together with the cursor of the next changes. Without the cursor, or if the cursor is older than the trash TTL, all the records are returned with the reset flag: the client should drop the records missing from them.
+//	@Produce json
+//	@ID Changes
 //	@Tags Sync
-//	@Param	client	body	models.Client	true	"Client"
-//	@Success 200 {string}	string	"client unregistered"
-//	@Failure 400 {string}	string	"Bad Request"
+//	@Param        since   query      string  false  "Cursor returned by the previous call"
+//	@Success 200 {object}	models.Changes	"Changes"
+//	@Failure 400 {string}	string	"Bad cursor"
 //	@Failure 401 {string}	string	"No username provided"
-//	@Router /api/sync/unregister [post]
-func (s *syncController) Unregister(ctx *gin.Context) {
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/sync/changes [get]
+func (s *syncController) Changes(ctx *gin.Context) {
 	username := ctx.GetString(middleware.UsernameContextValue)
 	if username == "" {
-		ctx.String(http.StatusUnauthorized, errors.ErrNoUsernameProvided.Error())
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
 		return
 	}
-	client := &models.Client{
-		Username: username,
-	}
-	if err := ctx.ShouldBindJSON(&client); err != nil {
-		ctx.String(http.StatusBadRequest, err.Error())
+	changes, err := service.ChangesSince(
+		ctx.Request.Context(),
+		s.storage,
+		username,
+		ctx.Query("since"),
+		s.trashTTL,
+	)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrBadSyncCursor) {
+			status = http.StatusBadRequest
+		}
+		ctx.String(status, err.Error())
 		return
 	}
-
-	s.service.Unregister(client)
-	ctx.String(http.StatusOK, "client unregistered")
+	ctx.JSON(http.StatusOK, changes)
 }
@@ -1,129 +1,152 @@
 package controller
 
 import (
-	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
 	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
 	"github.com/blokhinnv/gophkeeper/internal/server/service/mock"
 )
 
-func TestSyncController_Register(t *testing.T) {
+func TestSyncController_Events(t *testing.T) {
 	gin.SetMode(gin.ReleaseMode)
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 	sync := mock.NewMockSyncService(mockCtrl)
-	ctrl := NewSyncController(sync)
+	ctrl := NewSyncController(sync, mock.NewMockStorageService(mockCtrl), 0)
 	assert.NotNil(t, ctrl)
 
 	t.Run("no_username", func(t *testing.T) {
 		// Test case 1: Missing username
 		w := httptest.NewRecorder()
-		c, r := gin.CreateTestContext(w)
-		r.POST("/register", ctrl.Register)
-		req, _ := http.NewRequest(http.MethodPost, "/register", nil)
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest(http.MethodGet, "/events", nil)
 		c.Request = req
-		ctrl.Register(c)
+		ctrl.Events(c)
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
-	t.Run("invalid_data", func(t *testing.T) {
-		// Test case 2: Invalid JSON payload
+
+	t.Run("ok", func(t *testing.T) {
+		// Test case 2: the events are streamed until the subscription is closed
+		id := models.NewRandomObjectID()
+		events := make(chan models.ChangeEvent, 1)
+		events <- models.ChangeEvent{
+			Collection: models.TextCollection,
+			RecordID:   id,
+			Op:         models.OpCreate,
+			Version:    1,
+		}
+		close(events)
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+		unsubscribed := false
+		sync.EXPECT().
+			Subscribe("blokhinnv", "device", "session", expiresAt).
+			Return((<-chan models.ChangeEvent)(events), func() { unsubscribed = true })
 		w := httptest.NewRecorder()
-		c, r := gin.CreateTestContext(w)
-		c.Set(middleware.UsernameContextValue, "blokhinnv")
-		r.POST("/register", ctrl.Register)
-		req, _ := http.NewRequest(
-			http.MethodPost,
-			"/register",
-			bytes.NewBufferString(`{"invalid": json}`),
-		)
+		c, _ := gin.CreateTestContext(w)
+		c.Set(middleware.ClaimsContextValue, &auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(expiresAt)},
+			Username:         "blokhinnv",
+			DeviceID:         "device",
+			SessionID:        "session",
+		})
+		req, _ := http.NewRequest(http.MethodGet, "/events", nil)
 		c.Request = req
-		ctrl.Register(c)
-		assert.Equal(t, http.StatusBadRequest, w.Code)
+		ctrl.Events(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Equal(
+			t,
+			"event:change\ndata:{\"collection\":\"text\",\"record_id\":\""+id.Hex()+
+				"\",\"op\":\"create\",\"version\":1}\n\n",
+			w.Body.String(),
+		)
+		assert.True(t, unsubscribed)
 	})
 
-	t.Run("ok", func(t *testing.T) {
-		// Test case 3: Valid registration
-		sync.EXPECT().Register(gomock.Any()).Times(1)
+	t.Run("client_gone", func(t *testing.T) {
+		// Test case 3: the stream is finished when the client disconnects
+		sync.EXPECT().
+			Subscribe("blokhinnv", "", "", time.Time{}).
+			Return(make(<-chan models.ChangeEvent), func() {})
 		w := httptest.NewRecorder()
-		c, r := gin.CreateTestContext(w)
-		c.Set(middleware.UsernameContextValue, "blokhinnv")
-		r.POST("/register", ctrl.Register)
-		req, _ := http.NewRequest(
-			http.MethodPost,
-			"/register",
-			bytes.NewBufferString(`{"socker_addr": "http://localhost:1234"}`),
-		)
+		c, _ := gin.CreateTestContext(w)
+		c.Set(middleware.ClaimsContextValue, &auth.Claims{Username: "blokhinnv"})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/events", nil)
 		c.Request = req
-		ctrl.Register(c)
+		ctrl.Events(c)
 		assert.Equal(t, http.StatusOK, w.Code)
-
+		assert.Empty(t, w.Body.String())
 	})
 }
 
-func TestSyncController_Unregister(t *testing.T) {
+func TestSyncController_Changes(t *testing.T) {
 	gin.SetMode(gin.ReleaseMode)
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
-	sync := mock.NewMockSyncService(mockCtrl)
-	ctrl := NewSyncController(sync)
-	assert.NotNil(t, ctrl)
+	storage := mock.NewMockStorageService(mockCtrl)
+	ctrl := NewSyncController(mock.NewMockSyncService(mockCtrl), storage, time.Hour)
 
-	t.Run("no_username", func(t *testing.T) {
-		// Test case 1: Missing username
+	request := func(username, query string) *httptest.ResponseRecorder {
 		w := httptest.NewRecorder()
-		c, r := gin.CreateTestContext(w)
-		r.POST("/unregister", ctrl.Unregister)
-		req, _ := http.NewRequest(http.MethodPost, "/unregister", nil)
-		c.Request = req
-		ctrl.Unregister(c)
+		c, _ := gin.CreateTestContext(w)
+		if username != "" {
+			c.Set(middleware.UsernameContextValue, username)
+		}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/changes"+query, nil)
+		ctrl.Changes(c)
+		return w
+	}
+
+	t.Run("no_username", func(t *testing.T) {
+		w := request("", "")
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
-	t.Run("invalid_data", func(t *testing.T) {
-		// Test case 2: Invalid JSON payload
-		w := httptest.NewRecorder()
-		c, r := gin.CreateTestContext(w)
-		r.POST("/unregister", ctrl.Unregister)
-		c.Set(middleware.UsernameContextValue, "blokhinnv")
-		req, _ := http.NewRequest(
-			http.MethodPost,
-			"/unregister",
-			bytes.NewBufferString(`{"invalid": json}`),
-		)
-		c.Request = req
-		ctrl.Unregister(c)
+	t.Run("bad_cursor", func(t *testing.T) {
+		w := request("blokhinnv", "?since=bad")
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
-
 	t.Run("ok", func(t *testing.T) {
-		// Test case 3: Valid registration
-		sync.EXPECT().Unregister(gomock.Any()).Times(1)
-		w := httptest.NewRecorder()
-		c, r := gin.CreateTestContext(w)
-		r.POST("/unregister", ctrl.Unregister)
-		c.Set(middleware.UsernameContextValue, "blokhinnv")
-		req, _ := http.NewRequest(
-			http.MethodPost,
-			"/unregister",
-			bytes.NewBufferString(`{"socker_addr": "http://localhost:1234"}`),
-		)
-		c.Request = req
-		ctrl.Unregister(c)
+		since := time.Now().Add(-time.Minute).UTC()
+		id := models.NewRandomObjectID()
+		storage.EXPECT().
+			Changes(gomock.Any(), "blokhinnv", since).
+			Return(&models.Changes{
+				Upserts: []models.ChangedRecord{{
+					Collection: models.TextCollection,
+					Record:     models.UntypedRecord{RecordID: id},
+				}},
+				Tombstones: []models.Tombstone{},
+			}, nil)
+		w := request("blokhinnv", "?since="+models.NewSyncCursor(since))
 		assert.Equal(t, http.StatusOK, w.Code)
-
+		var changes models.Changes
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &changes))
+		require.Len(t, changes.Upserts, 1)
+		assert.Equal(t, id, changes.Upserts[0].Record.RecordID)
+		assert.NotEmpty(t, changes.Cursor)
+	})
+	t.Run("expired_cursor", func(t *testing.T) {
+		// the cursor older than the trash TTL is reset
+		storage.EXPECT().
+			Changes(gomock.Any(), "blokhinnv", time.Time{}).
+			Return(&models.Changes{Reset: true}, nil)
+		w := request("blokhinnv", "?since="+models.NewSyncCursor(time.Now().Add(-2*time.Hour)))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"reset":true`)
 	})
-}
-
-func TestNewSyncController(t *testing.T) {
-	mockCtrl := gomock.NewController(t)
-	defer mockCtrl.Finish()
-	sync := mock.NewMockSyncService(mockCtrl)
-	ctrl := NewSyncController(sync)
-	assert.NotNil(t, ctrl)
 }
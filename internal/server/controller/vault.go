@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+)
+
+// VaultController defines the interface for the controller of the
+// end-to-end encryption parameters.
+type VaultController interface {
+	// Get returns the vault parameters of the user.
+	Get(ctx *gin.Context)
+	// Set saves the vault parameters of the user.
+	Set(ctx *gin.Context)
+}
+
+// vaultController implements VaultController interface.
+type vaultController struct {
+	service service.VaultService
+}
+
+// NewVaultController creates a new instance of VaultController with the given VaultService.
+func NewVaultController(service service.VaultService) VaultController {
+	return &vaultController{
+		service: service,
+	}
+}
+
+// Get godoc
+//
+//	@Summary Get the vault parameters.
+//	@Security bearerAuth
+//	@Description Returns the parameters the clients use to derive the key of the end-to-end encryption from the master password.
+//	@Produce json
+//	@ID VaultGet
+//	@Tags Vault
+//	@Success 200 {object}	models.VaultParams	"Vault parameters"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 404 {string}	string	"Vault parameters were not found"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/vault [get]
+func (c *vaultController) Get(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	params, err := c.service.Get(ctx.Request.Context(), username)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrVaultNotFound) {
+			status = http.StatusNotFound
+		}
+		ctx.String(status, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, params)
+}
+
+// Set godoc
+//
+//	@Summary Set the vault parameters.
+//	@Security bearerAuth
+//	@Description Saves the parameters of the end-to-end encryption. The parameters can be set only once since the records encrypted with the old key would become unreadable.
+//	@Accept json
+//	@Produce plain
+//	@ID VaultSet
+//	@Tags Vault
+//	@Param	params	body	models.VaultParams	true	"Vault parameters"
+//	@Success 201 {string}	string	"Vault parameters saved"
+//	@Failure 400 {string}	string	"Bad Request"
+//	@Failure 401 {string}	string	"No username provided"
+//	@Failure 409 {string}	string	"Vault parameters are already set"
+//	@Failure 500 {string}	string	"Server error"
+//	@Router /api/vault [put]
+func (c *vaultController) Set(ctx *gin.Context) {
+	username := ctx.GetString(middleware.UsernameContextValue)
+	if username == "" {
+		ctx.String(http.StatusUnauthorized, srvErrors.ErrNoUsernameProvided.Error())
+		return
+	}
+	var params models.VaultParams
+	if err := ctx.ShouldBindJSON(&params); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := c.service.Set(ctx.Request.Context(), username, params); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, srvErrors.ErrVaultExists) {
+			status = http.StatusConflict
+		}
+		ctx.String(status, err.Error())
+		return
+	}
+	ctx.String(http.StatusCreated, "Vault parameters saved")
+}
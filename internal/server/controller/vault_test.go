@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service/mock"
+)
+
+// testVaultParams are valid vault parameters.
+var testVaultParams = models.VaultParams{
+	KDF:      models.KDFArgon2id,
+	Salt:     []byte("0123456789abcdef"),
+	Time:     3,
+	Memory:   64 * 1024,
+	Threads:  4,
+	KeyCheck: []byte("key check"),
+}
+
+func TestNewVaultController(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	vault := mock.NewMockVaultService(mockCtrl)
+	ctrl := NewVaultController(vault)
+	assert.NotNil(t, ctrl)
+}
+
+func TestVaultController_Get(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	vault := mock.NewMockVaultService(mockCtrl)
+	ctrl := NewVaultController(vault)
+
+	newContext := func(username string) (*gin.Context, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest("GET", "/api/vault", nil)
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		if username != "" {
+			ctx.Set(middleware.UsernameContextValue, username)
+		}
+		return ctx, rec
+	}
+
+	t.Run("no_username", func(t *testing.T) {
+		ctx, rec := newContext("")
+
+		ctrl.Get(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("not_found", func(t *testing.T) {
+		vault.EXPECT().Get(gomock.Any(), "username").Return(nil, srvErrors.ErrVaultNotFound)
+		ctx, rec := newContext("username")
+
+		ctrl.Get(ctx)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("service_err", func(t *testing.T) {
+		vault.EXPECT().Get(gomock.Any(), "username").Return(nil, fmt.Errorf("some error"))
+		ctx, rec := newContext("username")
+
+		ctrl.Get(ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+	t.Run("ok", func(t *testing.T) {
+		params := testVaultParams
+		vault.EXPECT().Get(gomock.Any(), "username").Return(&params, nil)
+		ctx, rec := newContext("username")
+
+		ctrl.Get(ctx)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var res models.VaultParams
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, testVaultParams, res)
+	})
+}
+
+func TestVaultController_Set(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	vault := mock.NewMockVaultService(mockCtrl)
+	ctrl := NewVaultController(vault)
+
+	body, _ := json.Marshal(testVaultParams)
+	newContext := func(username string, body []byte) (*gin.Context, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest("PUT", "/api/vault", bytes.NewBuffer(body))
+		rec := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(rec)
+		ctx.Request = req
+		if username != "" {
+			ctx.Set(middleware.UsernameContextValue, username)
+		}
+		return ctx, rec
+	}
+
+	t.Run("no_username", func(t *testing.T) {
+		ctx, rec := newContext("", body)
+
+		ctrl.Set(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+	t.Run("bad_body", func(t *testing.T) {
+		ctx, rec := newContext("username", []byte(`{"kdf": "pbkdf2"}`))
+
+		ctrl.Set(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+	t.Run("exists", func(t *testing.T) {
+		vault.EXPECT().
+			Set(gomock.Any(), "username", testVaultParams).
+			Return(srvErrors.ErrVaultExists)
+		ctx, rec := newContext("username", body)
+
+		ctrl.Set(ctx)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+	t.Run("ok", func(t *testing.T) {
+		vault.EXPECT().Set(gomock.Any(), "username", testVaultParams).Return(nil)
+		ctx, rec := newContext("username", body)
+
+		ctrl.Set(ctx)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+}
@@ -16,41 +16,35 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/ping": {
+        "/.well-known/jwks.json": {
             "get": {
-                "description": "Returns plain text response with a \"pong\" message if the server is available, otherwise returns an error message.",
+                "description": "Returns the JSON Web Key Set with the public keys which verify the access tokens: the active signing key first and the keys accepted during the rollover. The key of a token is found by the kid header of the token. The set is empty in the legacy HS256 mode.",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "Utils"
+                    "Authy"
                 ],
-                "summary": "Ping server",
-                "operationId": "Ping",
+                "summary": "Public keys of the access tokens",
+                "operationId": "JWKS",
                 "responses": {
                     "200": {
-                        "description": "pong",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "500": {
-                        "description": "not pong",
+                        "description": "Public keys",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/auth.JWKS"
                         }
                     }
                 }
             }
         },
-        "/api/store/{collectionName}": {
-            "get": {
+        "/api/blobs": {
+            "post": {
                 "security": [
                     {
                         "bearerAuth": []
                     }
                 ],
-                "description": "Returns all the untyped records from the database based on the data provided in the request.",
+                "description": "Starts an upload of the content of a binary record. The content is appended in chunks, and the binary record refers to the blob by its ID.",
                 "consumes": [
                     "application/json"
                 ],
@@ -58,27 +52,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Storage"
+                    "Blobs"
                 ],
-                "summary": "Retrieve all untyped records for the authenticated user from a collection.",
-                "operationId": "GetAll",
+                "summary": "Start an upload of a blob.",
+                "operationId": "BlobCreate",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Collection name",
-                        "name": "collectionName",
-                        "in": "path",
-                        "required": true
+                        "description": "Size of the content",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.NewBlobRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Record added by the user in the specified collection",
+                    "201": {
+                        "description": "Blob",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.UntypedRecord"
-                            }
+                            "$ref": "#/definitions/models.Blob"
                         }
                     },
                     "400": {
@@ -92,50 +85,46 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/api/blobs/{blobID}": {
+            "get": {
                 "security": [
                     {
                         "bearerAuth": []
                     }
                 ],
-                "description": "Stores an untyped record to the database based on the data provided in the request.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the state of the upload of a blob. An interrupted upload is resumed from the received offset.",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "Storage"
+                    "Blobs"
                 ],
-                "summary": "Store an untyped record to the database.",
-                "operationId": "Store",
+                "summary": "Get the state of an upload.",
+                "operationId": "BlobGet",
                 "parameters": [
-                    {
-                        "description": "Record",
-                        "name": "record",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.UntypedRecordContent"
-                        }
-                    },
                     {
                         "type": "string",
-                        "description": "Collection name",
-                        "name": "collectionName",
+                        "description": "Blob ID",
+                        "name": "blobID",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Record added to collection",
+                    "200": {
+                        "description": "Blob",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Blob"
                         }
                     },
                     "400": {
@@ -149,50 +138,69 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "404": {
+                        "description": "Blob was not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
-            "post": {
+            "put": {
                 "security": [
                     {
                         "bearerAuth": []
                     }
                 ],
-                "description": "Updates the data and metadata of a document in the collection specified by the request URL, based on the data provided in the request body. The updated document is identified by its ID, which is included in the request body as well.",
+                "description": "Appends a chunk of at most 1 MiB to the content of a blob. The chunk must start at the offset where the uploaded content ends; otherwise the current state of the upload is returned with 409, so the client can resume from it.",
                 "consumes": [
-                    "application/json"
+                    "application/octet-stream"
                 ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "Storage"
+                    "Blobs"
                 ],
-                "summary": "Update an existing record in the database.",
-                "operationId": "Update",
+                "summary": "Append a chunk to a blob.",
+                "operationId": "BlobAppend",
                 "parameters": [
                     {
-                        "description": "Record",
-                        "name": "record",
+                        "type": "string",
+                        "description": "Blob ID",
+                        "name": "blobID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset of the chunk",
+                        "name": "offset",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Chunk",
+                        "name": "chunk",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UntypedRecord"
+                            "type": "string"
                         }
-                    },
-                    {
-                        "type": "string",
-                        "description": "Collection name",
-                        "name": "collectionName",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Record updated",
+                    "200": {
+                        "description": "Blob",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Blob"
                         }
                     },
                     "400": {
@@ -207,6 +215,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "404": {
+                        "description": "Blob was not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Chunk doesn't start at the end of the uploaded content",
+                        "schema": {
+                            "$ref": "#/definitions/models.Blob"
+                        }
+                    },
                     "500": {
                         "description": "Server error",
                         "schema": {
@@ -214,48 +234,38 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/blobs/{blobID}/content": {
+            "get": {
                 "security": [
                     {
                         "bearerAuth": []
                     }
                 ],
-                "description": "Deletes a record from the specified collection by ID.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Streams the decrypted content of the uploaded blob.",
                 "produces": [
-                    "text/plain"
+                    "application/octet-stream"
                 ],
                 "tags": [
-                    "Storage"
+                    "Blobs"
                 ],
-                "summary": "Delete a record by ID",
-                "operationId": "Delete",
+                "summary": "Download the content of a blob.",
+                "operationId": "BlobDownload",
                 "parameters": [
-                    {
-                        "description": "RecordID",
-                        "name": "record_id",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/controller.deleteRequestBody"
-                        }
-                    },
                     {
                         "type": "string",
-                        "description": "Collection name",
-                        "name": "collectionName",
+                        "description": "Blob ID",
+                        "name": "blobID",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Record deleted",
+                        "description": "Content",
                         "schema": {
-                            "type": "string"
+                            "type": "file"
                         }
                     },
                     "400": {
@@ -270,6 +280,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "404": {
+                        "description": "Blob was not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Blob upload is not complete",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Server error",
                         "schema": {
@@ -279,41 +301,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/sync/register": {
+        "/api/otp/{recordID}/code": {
             "post": {
                 "security": [
                     {
                         "bearerAuth": []
                     }
                 ],
-                "description": "Allows a client to register with the synchronization service.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Generates the current RFC 6238 (TOTP) or RFC 4226 (HOTP) code for a record of the otp collection.\nThe counter of a HOTP record is advanced, so every request issues a new code.\nThe request is a POST, so the code isn't issued by a prefetch or a retried GET.",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "Sync"
+                    "OTP"
                 ],
-                "summary": "Registers a new client with the synchronization service.",
-                "operationId": "RegisterClient",
+                "summary": "Generate a one-time password.",
+                "operationId": "OTPCode",
                 "parameters": [
                     {
-                        "description": "Client",
-                        "name": "client",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Client"
-                        }
+                        "type": "string",
+                        "description": "Record ID",
+                        "name": "recordID",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "client registered",
+                        "description": "Current code",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.OTPCode"
                         }
                     },
                     "400": {
@@ -327,55 +344,48 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "404": {
+                        "description": "Record not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "The record is updated concurrently",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/api/sync/unregister": {
-            "post": {
-                "security": [
-                    {
-                        "bearerAuth": []
-                    }
-                ],
-                "description": "Allows a client to unregister from the synchronization service.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/ping": {
+            "get": {
+                "description": "Returns plain text response with a \"pong\" message if the server is available, otherwise returns an error message.",
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
-                    "Sync"
-                ],
-                "summary": "Unregisters an existing client from the server.",
-                "operationId": "UnregisterClient",
-                "parameters": [
-                    {
-                        "description": "Client",
-                        "name": "client",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Client"
-                        }
-                    }
+                    "Utils"
                 ],
+                "summary": "Ping server",
+                "operationId": "Ping",
                 "responses": {
                     "200": {
-                        "description": "client unregistered",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "pong",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "401": {
-                        "description": "No username provided",
+                    "500": {
+                        "description": "not pong",
                         "schema": {
                             "type": "string"
                         }
@@ -383,43 +393,55 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/user/login": {
-            "put": {
-                "description": "Logs in a user with the provided username and password",
+        "/api/search": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Finds the records of all the collections whose metadata values, credential logins,\nfile names or last four digits of the card numbers have all the words of the query.\nA word matches a word of the record it is equal to or is a prefix of (at least three characters long).\nThe server keeps only the keyed hashes of the words, so the end-to-end encrypted records are not found.\nThe content of the binary records is omitted.",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "Authy"
+                    "Search"
                 ],
-                "summary": "Logs in a user",
-                "operationId": "Login",
+                "summary": "Search the records.",
+                "operationId": "Search",
                 "parameters": [
                     {
-                        "description": "Credentials",
-                        "name": "credentials",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.UserCredentials"
-                        }
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "some JWT token",
+                        "description": "Found records, the recently updated first",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SearchResult"
+                            }
                         }
                     },
                     "400": {
-                        "description": "no username provided",
+                        "description": "Empty query",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "401": {
-                        "description": "username or password is incorrect: testuser/qwerty",
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
                         "schema": {
                             "type": "string"
                         }
@@ -427,31 +449,265 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/user/register": {
-            "put": {
-                "description": "Register a new user with provided credentials",
-                "produces": [
-                    "text/plain"
+        "/api/store/{collectionName}": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Returns the untyped records from the database based on the data provided in the request. The records are listed in pages when the limit is set: the cursor of the next page is returned in the X-Next-Cursor header, which is omitted for the last page.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
                 ],
                 "tags": [
-                    "Authy"
+                    "Storage"
                 ],
-                "summary": "Register a new user",
-                "operationId": "Register",
+                "summary": "Retrieve the untyped records of the authenticated user from a collection.",
+                "operationId": "GetAll",
                 "parameters": [
                     {
-                        "description": "Credentials",
-                        "name": "credentials",
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collectionName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of the records in the page (up to 1000); all the records by default",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page returned in X-Next-Cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field: created (default) or updated, prefixed with - for the descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value the metadata key of the records must have; any key may be used",
+                        "name": "metadata.key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Omit the content of the binary records",
+                        "name": "omit_content",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Record added by the user in the specified collection",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.UntypedRecord"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor of the next page"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Stores an untyped record to the database based on the data provided in the request. The data of an end-to-end encrypted record of any collection is an envelope {\"encrypted\": \"\u003cbase64 ciphertext\u003e\"}; such records are not validated and their metadata is dropped.\nThe client may choose the ID of the record with record_id, e.g. to bind the encrypted data to it; otherwise the ID is generated.\nThe blob a binary record refers to must be uploaded completely by the user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Store an untyped record to the database.",
+                "operationId": "Store",
+                "parameters": [
+                    {
+                        "description": "Record",
+                        "name": "record",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.UserCredentials"
+                            "$ref": "#/definitions/controller.storeRequestBody"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collectionName",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Record added to collection",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Record with the id already exists",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Updates the data and metadata of a document in the collection specified by the request URL, based on the data provided in the request body. The updated document is identified by its ID, which is included in the request body as well.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Update an existing record in the database.",
+                "operationId": "Update",
+                "parameters": [
+                    {
+                        "description": "Record",
+                        "name": "record",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.updateRequestBody"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collectionName",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Record updated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Current record if its version is not the expected one",
+                        "schema": {
+                            "$ref": "#/definitions/models.UntypedRecord"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Moves a record of the specified collection to the trash by ID. The deleted record is not returned with the other records, but it can be restored until it is purged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Delete a record by ID",
+                "operationId": "Delete",
+                "parameters": [
+                    {
+                        "description": "RecordID",
+                        "name": "record_id",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controller.deleteRequestBody"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collectionName",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "success",
+                        "description": "Record deleted",
                         "schema": {
                             "type": "string"
                         }
@@ -461,49 +717,1981 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
-                    },
-                    "409": {
-                        "description": "Username is already taken",
-                        "schema": {
-                            "type": "string"
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/store/{collectionName}/trash": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Returns the records moved to the trash which have not been purged yet. The records are purged automatically after the TTL configured on the server.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Retrieve the deleted records of the authenticated user from a collection.",
+                "operationId": "Trash",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collectionName",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deleted records",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.UntypedRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/store/{collectionName}/trash/{recordID}": {
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Deletes the record in the trash permanently together with its history.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Permanently delete a record from the trash.",
+                "operationId": "Purge",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collectionName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
+                        "name": "recordID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Record purged",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Record not found in the trash",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/store/{collectionName}/trash/{recordID}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Moves the deleted record back from the trash. The version of the record is incremented.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Restore a record from the trash.",
+                "operationId": "Undelete",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collectionName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
+                        "name": "recordID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Record restored",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Record not found in the trash",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/store/{collectionName}/{recordID}": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Returns the untyped record with the specified ID from the collection. Clients use it to fetch the record mentioned in a change event.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Retrieve a single record of the authenticated user by ID.",
+                "operationId": "Get",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collectionName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
+                        "name": "recordID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Record",
+                        "schema": {
+                            "$ref": "#/definitions/models.UntypedRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Record not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/store/{collectionName}/{recordID}/history": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Returns the revisions replaced by the updates and the deletion of the record, the latest first. The revisions are kept for the retention period configured on the server.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Retrieve the previous revisions of a record.",
+                "operationId": "History",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collectionName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
+                        "name": "recordID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Revisions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Revision"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/store/{collectionName}/{recordID}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Replaces the record with the revision rev from its history. The replaced state is archived as well. A deleted record is recreated with the same ID.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Restore a record from its history.",
+                "operationId": "Restore",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collectionName",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
+                        "name": "recordID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision",
+                        "name": "rev",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Record restored",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Revision not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sync/changes": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Returns the records of all the collections created or updated since the cursor and the tombstones of the records deleted since then together with the cursor of the next changes. Without the cursor, or if the cursor is older than the trash TTL, all the records are returned with the reset flag: the client should drop the records missing from them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sync"
+                ],
+                "summary": "Get the changes of the user's records since the cursor.",
+                "operationId": "Changes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cursor returned by the previous call",
+                        "name": "since",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Changes",
+                        "schema": {
+                            "$ref": "#/definitions/models.Changes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad cursor",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sync/events": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Streams server-sent events named \"change\" every time a record of the user is created, updated or deleted by any client. The stream is closed by the server on shutdown, when the access token expires, when its session or device is revoked or if the client does not keep up with the events; the client should reconnect and sync all the data then.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Sync"
+                ],
+                "summary": "Stream the changes of the user's records.",
+                "operationId": "Events",
+                "responses": {
+                    "200": {
+                        "description": "Stream of change events",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeEvent"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user": {
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Checks the password, finishes all the sessions of the user and deletes the user with the records of all the collections, their history and the files. The deletion can't be undone. The wrong passwords are counted as the failed logins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Delete the account",
+                "operationId": "DeleteAccount",
+                "parameters": [
+                    {
+                        "description": "Password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AccountDeleteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Account deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "username or password is incorrect",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "too many failed logins, try again in 30s",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds until the lockout ends"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/devices": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Returns the devices registered by the logins of the user. The device of the token of the request is marked as current.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "List the devices",
+                "operationId": "DeviceList",
+                "responses": {
+                    "200": {
+                        "description": "Devices",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Device"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/devices/nonce": {
+            "post": {
+                "description": "Issues the short-lived nonce which the client signs with the Ed25519 private key of its device. The signature is sent as the proof of the device to /api/user/login, /api/user/login/mfa and /api/user/refresh, so the server registers the device and refreshes its sessions only for the client which holds the key. Every nonce can be used only once.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Issue a device nonce",
+                "operationId": "DeviceNonce",
+                "responses": {
+                    "200": {
+                        "description": "Nonce",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceNonce"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/devices/{deviceID}": {
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Removes the device: its sessions are finished, their access tokens are revoked and its event streams are closed at once. The device gets a new id on its next login.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Delete a device",
+                "operationId": "DeviceDelete",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "deviceID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Device deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "device was not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/login": {
+            "put": {
+                "description": "Logs in a user with the provided username and password. The short-lived access token is used in the Authorization header, and the refresh token is exchanged for the new tokens at /api/user/refresh. The device registered with the login keeps its id between the logins with the same public key; the tokens of the session are bound to it. The device is registered only with the proof of its key: a nonce of /api/user/devices/nonce signed with the private key of the device. If the user has the second factor enabled, only mfa_required and the short-lived mfa_token are returned; the token is exchanged for the tokens of the session at /api/user/login/mfa. After too many failed logins of the username or from the address the logins are rejected until the time in the Retry-After header passes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Logs in a user",
+                "operationId": "Login",
+                "parameters": [
+                    {
+                        "description": "Credentials and device",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tokens of the new session or the challenge token",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "no username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "username or password is incorrect or device proof is invalid",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "too many failed logins, try again in 30s",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds until the lockout ends"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/login/mfa": {
+            "put": {
+                "description": "Exchanges the challenge token returned by /api/user/login and the current one-time code of the authenticator app or one of the recovery codes for the tokens of a new session. The device is registered like the one of /api/user/login. Every code can be used only once. The wrong codes are counted as the failed logins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Completes the login with the second factor",
+                "operationId": "LoginMFA",
+                "parameters": [
+                    {
+                        "description": "Challenge token and code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MFALoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tokens of the new session",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "one-time code is incorrect",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "too many failed logins, try again in 30s",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds until the lockout ends"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/logout": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Revokes the access token of the request and finishes its session, so its refresh token can't be used anymore.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Log out",
+                "operationId": "Logout",
+                "responses": {
+                    "200": {
+                        "description": "Logged out",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/mfa": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Checks the password and generates a new TOTP secret and its provisioning URI to add to an authenticator app. The second factor is enabled only after the secret is confirmed at /api/user/mfa/confirm. Enrolling again replaces the unconfirmed secret. The wrong passwords are counted as the failed logins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Enroll the second factor.",
+                "operationId": "MFAEnroll",
+                "parameters": [
+                    {
+                        "description": "Password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MFAEnrollRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Secret and provisioning URI",
+                        "schema": {
+                            "$ref": "#/definitions/models.MFAEnrollment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "username or password is incorrect",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "second factor is already enabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "too many failed logins, try again in 30s",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds until the lockout ends"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Checks the password and the one-time code or a recovery code and turns off the second factor. The wrong passwords are counted as the failed logins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Disable the second factor.",
+                "operationId": "MFADisable",
+                "parameters": [
+                    {
+                        "description": "Password and one-time or recovery code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MFADisableRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Second factor disabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "one-time code is incorrect",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "second factor is not enabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "too many failed logins, try again in 30s",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds until the lockout ends"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/mfa/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Checks the one-time code of the enrolled secret and enables the second factor. The recovery codes are returned only once; every code replaces the one-time code once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Confirm the second factor.",
+                "operationId": "MFAConfirm",
+                "parameters": [
+                    {
+                        "description": "One-time code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MFACodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Recovery codes",
+                        "schema": {
+                            "$ref": "#/definitions/models.MFARecoveryCodes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "one-time code is incorrect",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "second factor is not enrolled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/password": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Checks the current password and replaces it with the new one. All the sessions of the user are finished and their access tokens are revoked, the tokens of a new session on the device of the request are returned. The wrong passwords are counted as the failed logins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Change the password",
+                "operationId": "ChangePassword",
+                "parameters": [
+                    {
+                        "description": "Current and new passwords",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PasswordChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tokens of the new session",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "username or password is incorrect",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "too many failed logins, try again in 30s",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds until the lockout ends"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/refresh": {
+            "post": {
+                "description": "Exchanges the refresh token for the new access and refresh tokens. Every refresh token can be used only once; using it again revokes the whole session. The session started on a device is refreshed only with the proof: a nonce of /api/user/devices/nonce signed with the key of the device.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Refresh the tokens",
+                "operationId": "Refresh",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "New tokens of the session",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "refresh token is invalid or expired or device proof is invalid",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/register": {
+            "put": {
+                "description": "Register a new user with provided credentials",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Register a new user",
+                "operationId": "Register",
+                "parameters": [
+                    {
+                        "description": "Credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserCredentials"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Username is already taken",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Returns the active sessions of the user. The session of the token of the request is marked as current.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "List the sessions",
+                "operationId": "SessionList",
+                "responses": {
+                    "200": {
+                        "description": "Sessions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Session"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/sessions/{sessionID}": {
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Finishes the session: its refresh token can't be used anymore, and its access token is revoked.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Authy"
+                ],
+                "summary": "Revoke a session",
+                "operationId": "SessionRevoke",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Session revoked",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "session was not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/vault": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Returns the parameters the clients use to derive the key of the end-to-end encryption from the master password.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vault"
+                ],
+                "summary": "Get the vault parameters.",
+                "operationId": "VaultGet",
+                "responses": {
+                    "200": {
+                        "description": "Vault parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.VaultParams"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Vault parameters were not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Saves the parameters of the end-to-end encryption. The parameters can be set only once since the records encrypted with the old key would become unreadable.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Vault"
+                ],
+                "summary": "Set the vault parameters.",
+                "operationId": "VaultSet",
+                "parameters": [
+                    {
+                        "description": "Vault parameters",
+                        "name": "params",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.VaultParams"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Vault parameters saved",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "No username provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Vault parameters are already set",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "auth.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "description": "Crv and X are set for the Ed25519 keys.",
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "description": "N and E are set for the RSA keys.",
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.JWKS": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.JWK"
+                    }
+                }
+            }
+        },
+        "controller.deleteRequestBody": {
+            "type": "object",
+            "required": [
+                "record_id"
+            ],
+            "properties": {
+                "record_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.storeRequestBody": {
+            "type": "object",
+            "required": [
+                "data"
+            ],
+            "properties": {
+                "data": {
+                    "description": "Data is an interface{} that can hold any type of data for the record."
+                },
+                "metadata": {
+                    "description": "Metadata is a map that can hold additional metadata for the record.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Metadata"
+                        }
+                    ]
+                },
+                "record_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "controller.updateRequestBody": {
+            "type": "object",
+            "required": [
+                "data"
+            ],
+            "properties": {
+                "data": {
+                    "description": "Data is an interface{} that can hold any type of data for the record."
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is the time the record was moved to the trash.",
+                    "type": "string"
+                },
+                "expected_version": {
+                    "type": "integer"
+                },
+                "metadata": {
+                    "description": "Metadata is a map that can hold additional metadata for the record.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Metadata"
+                        }
+                    ]
+                },
+                "record_id": {
+                    "description": "Unique ID of a document in the DB.",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "description": "UpdatedAt is the time of the last change of the record.",
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version is incremented on every update of the record.",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AccountDeleteRequest": {
+            "type": "object",
+            "required": [
+                "password"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Blob": {
+            "type": "object",
+            "properties": {
+                "blob_id": {
+                    "description": "BlobID is the ID the binary records refer to.",
+                    "type": "string"
+                },
+                "chunks": {
+                    "description": "Chunks is the number of chunks uploaded so far.",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "description": "CreatedAt is the time the upload was started.",
+                    "type": "string"
+                },
+                "received": {
+                    "description": "Received is the number of bytes uploaded so far.",
+                    "type": "integer"
+                },
+                "size": {
+                    "description": "Size is the size of the content in bytes.",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ChangeEvent": {
+            "type": "object",
+            "properties": {
+                "collection": {
+                    "$ref": "#/definitions/models.CollectionName"
+                },
+                "op": {
+                    "$ref": "#/definitions/models.ChangeOp"
+                },
+                "record_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version is a number of the change among all the changes of the user.",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ChangeOp": {
+            "type": "string",
+            "enum": [
+                "create",
+                "update",
+                "delete"
+            ],
+            "x-enum-varnames": [
+                "OpCreate",
+                "OpUpdate",
+                "OpDelete"
+            ]
+        },
+        "models.ChangedRecord": {
+            "type": "object",
+            "properties": {
+                "collection": {
+                    "description": "Collection is the name of the record's collection.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CollectionName"
+                        }
+                    ]
+                },
+                "record": {
+                    "description": "Record is the current state of the record.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UntypedRecord"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.Changes": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "description": "Cursor is the cursor to pass to get the next changes.",
+                    "type": "string"
+                },
+                "reset": {
+                    "description": "Reset is set if the changes are not tracked since the cursor. Upserts then hold\nall the user's records and the records missing from them are to be dropped.",
+                    "type": "boolean"
+                },
+                "tombstones": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Tombstone"
+                    }
+                },
+                "upserts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChangedRecord"
+                    }
+                }
+            }
+        },
+        "models.CollectionName": {
+            "type": "string",
+            "enum": [
+                "text",
+                "credentials",
+                "binary",
+                "cards",
+                "otp"
+            ],
+            "x-enum-varnames": [
+                "TextCollection",
+                "CredentialsCollection",
+                "BinaryCollection",
+                "CardCollection",
+                "OTPCollection"
+            ]
+        },
+        "models.Device": {
+            "type": "object",
+            "required": [
+                "name",
+                "public_key"
+            ],
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "Current is set for the device of the token of the request.",
+                    "type": "boolean"
+                },
+                "device_id": {
+                    "type": "string"
+                },
+                "last_login_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "proof": {
+                    "description": "Proof proves that the client holds the private key of the device.\nIt is checked on login and is never saved.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.DeviceProof"
+                        }
+                    ]
+                },
+                "public_key": {
+                    "description": "PublicKey is the Ed25519 public key of the device in the standard base64.\nThe device keeps its private key, so the key identifies the device between the logins.",
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeviceInfo": {
+            "type": "object",
+            "required": [
+                "name",
+                "public_key"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "proof": {
+                    "description": "Proof proves that the client holds the private key of the device.\nIt is checked on login and is never saved.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.DeviceProof"
                         }
-                    }
+                    ]
+                },
+                "public_key": {
+                    "description": "PublicKey is the Ed25519 public key of the device in the standard base64.\nThe device keeps its private key, so the key identifies the device between the logins.",
+                    "type": "string"
                 }
             }
-        }
-    },
-    "definitions": {
-        "controller.deleteRequestBody": {
+        },
+        "models.DeviceNonce": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "nonce": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeviceProof": {
+            "type": "object",
+            "properties": {
+                "nonce": {
+                    "description": "Nonce is the nonce issued by the server.",
+                    "type": "string"
+                },
+                "signature": {
+                    "description": "Signature is the Ed25519 signature of the nonce made with the private key\nof the device, in the standard base64.",
+                    "type": "string"
+                }
+            }
+        },
+        "models.LoginRequest": {
             "type": "object",
             "required": [
-                "record_id"
+                "password",
+                "username"
             ],
             "properties": {
-                "record_id": {
+                "device": {
+                    "$ref": "#/definitions/models.DeviceInfo"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MFACodeRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MFADisableRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "description": "Code is the current one-time code or one of the recovery codes.",
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MFAEnrollRequest": {
+            "type": "object",
+            "required": [
+                "password"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MFAEnrollment": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "type": "string"
+                },
+                "uri": {
+                    "description": "URI is the otpauth:// provisioning URI of the secret.",
                     "type": "string"
                 }
             }
         },
-        "models.Client": {
+        "models.MFALoginRequest": {
             "type": "object",
+            "required": [
+                "code",
+                "mfa_token"
+            ],
             "properties": {
-                "socket_addr": {
+                "code": {
+                    "description": "Code is the current one-time code or one of the recovery codes.",
+                    "type": "string"
+                },
+                "device": {
+                    "description": "Device is the device registered by the login like the one of LoginRequest.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.DeviceInfo"
+                        }
+                    ]
+                },
+                "mfa_token": {
                     "type": "string"
                 }
             }
         },
+        "models.MFARecoveryCodes": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "models.Metadata": {
             "type": "object",
             "additionalProperties": {
                 "type": "string"
             }
         },
-        "models.UntypedRecord": {
+        "models.NewBlobRequest": {
+            "type": "object",
+            "properties": {
+                "size": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "models.OTPCode": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is the current one-time password.",
+                    "type": "string"
+                },
+                "remaining_seconds": {
+                    "description": "RemainingSeconds is the time left until the code changes (0 for HOTP).",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.PasswordChangeRequest": {
+            "type": "object",
+            "required": [
+                "new_password",
+                "old_password"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string"
+                },
+                "old_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RefreshRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "proof": {
+                    "$ref": "#/definitions/models.DeviceProof"
+                },
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Revision": {
             "type": "object",
             "required": [
                 "data"
             ],
             "properties": {
+                "archived_at": {
+                    "description": "ArchivedAt is the time the revision was replaced.",
+                    "type": "string"
+                },
                 "data": {
                     "description": "Data is an interface{} that can hold any type of data for the record."
                 },
@@ -515,13 +2703,126 @@ const docTemplate = `{
                         }
                     ]
                 },
+                "op": {
+                    "description": "Op is the change which replaced the revision: update or delete.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ChangeOp"
+                        }
+                    ]
+                },
                 "record_id": {
-                    "description": "Unique ID of a document in the DB.",
+                    "description": "RecordID is the ID of the record.",
+                    "type": "string"
+                },
+                "rev": {
+                    "description": "Rev is the version of the record the revision holds.",
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "description": "UpdatedAt is the time the revision was made.",
+                    "type": "string"
+                }
+            }
+        },
+        "models.SearchResult": {
+            "type": "object",
+            "properties": {
+                "collection": {
+                    "description": "Collection is the name of the record's collection.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CollectionName"
+                        }
+                    ]
+                },
+                "record": {
+                    "description": "Record is the found record.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UntypedRecord"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.Session": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "Current is set for the session of the token of the request.",
+                    "type": "boolean"
+                },
+                "device_id": {
+                    "description": "DeviceID is the id of the device which started the session.",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "description": "LastUsedAt is the time of the last refresh.",
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TokenPair": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "device_id": {
+                    "description": "DeviceID is the id of the device registered by the login.",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is the expiration time of the access token or of MFAToken.",
+                    "type": "string"
+                },
+                "mfa_required": {
+                    "type": "boolean"
+                },
+                "mfa_token": {
+                    "description": "MFAToken is the short-lived challenge token of the login.",
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Tombstone": {
+            "type": "object",
+            "properties": {
+                "collection": {
+                    "description": "Collection is the name of the record's collection.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CollectionName"
+                        }
+                    ]
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is the time the record was moved to the trash.",
+                    "type": "string"
+                },
+                "record_id": {
+                    "description": "RecordID is the ID of the deleted record.",
                     "type": "string"
                 }
             }
         },
-        "models.UntypedRecordContent": {
+        "models.UntypedRecord": {
             "type": "object",
             "required": [
                 "data"
@@ -530,6 +2831,10 @@ const docTemplate = `{
                 "data": {
                     "description": "Data is an interface{} that can hold any type of data for the record."
                 },
+                "deleted_at": {
+                    "description": "DeletedAt is the time the record was moved to the trash.",
+                    "type": "string"
+                },
                 "metadata": {
                     "description": "Metadata is a map that can hold additional metadata for the record.",
                     "allOf": [
@@ -537,6 +2842,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.Metadata"
                         }
                     ]
+                },
+                "record_id": {
+                    "description": "Unique ID of a document in the DB.",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "description": "UpdatedAt is the time of the last change of the record.",
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version is incremented on every update of the record.",
+                    "type": "integer"
                 }
             }
         },
@@ -554,6 +2871,47 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "models.VaultParams": {
+            "type": "object",
+            "required": [
+                "kdf",
+                "key_check",
+                "memory",
+                "salt",
+                "threads",
+                "time"
+            ],
+            "properties": {
+                "kdf": {
+                    "type": "string",
+                    "enum": [
+                        "argon2id"
+                    ]
+                },
+                "key_check": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "memory": {
+                    "type": "integer"
+                },
+                "salt": {
+                    "type": "array",
+                    "minItems": 16,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "threads": {
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "integer"
+                }
+            }
         }
     },
     "securityDefinitions": {
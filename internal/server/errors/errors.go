@@ -3,6 +3,8 @@ package errors
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -20,8 +22,105 @@ var (
 	ErrBadCredentials = errors.New("username or password is incorrect")
 	// ErrRecordNotFound is a predefined error for a case when the record is not found.
 	ErrRecordNotFound = errors.New("document was not found")
+	// ErrRevisionNotFound is a predefined error for a case when the revision is not in the history.
+	ErrRevisionNotFound = errors.New("revision was not found")
+	// ErrRecordExists is a predefined error for a case when a record with the ID chosen
+	// by the client already exists.
+	ErrRecordExists = errors.New("record with the id already exists")
+	// ErrVersionConflict is a predefined error for a case when the record was changed
+	// after the version the client expects.
+	ErrVersionConflict = errors.New("record was modified by another client")
+	// ErrBadEnvelope is a predefined error for a malformed end-to-end encrypted record.
+	ErrBadEnvelope = errors.New("bad encrypted record envelope")
+	// ErrEncryptedRecord is a predefined error for a case when the server has to read
+	// the data of an end-to-end encrypted record.
+	ErrEncryptedRecord = errors.New("record is end-to-end encrypted")
+	// ErrVaultNotFound is a predefined error for a case when the user has not set up
+	// the end-to-end encryption yet.
+	ErrVaultNotFound = errors.New("vault parameters were not found")
+	// ErrVaultExists is a predefined error for a case when the vault parameters are already set.
+	ErrVaultExists = errors.New("vault parameters are already set")
+	// ErrBadRefreshToken is a predefined error for an unknown, expired or already used refresh token.
+	ErrBadRefreshToken = errors.New("refresh token is invalid or expired")
+	// ErrSessionNotFound is a predefined error for a case when the session is not found.
+	ErrSessionNotFound = errors.New("session was not found")
+	// ErrBadSyncCursor is a predefined error for a malformed sync cursor.
+	ErrBadSyncCursor = errors.New("bad sync cursor")
+	// ErrBadListOptions is a predefined error for bad options of the listing of the records.
+	ErrBadListOptions = errors.New("bad list options")
+	// ErrEmptySearchQuery is a predefined error for a search query without any terms.
+	ErrEmptySearchQuery = errors.New("empty search query")
+	// ErrBlobNotFound is a predefined error for a case when the blob is not found.
+	ErrBlobNotFound = errors.New("blob was not found")
+	// ErrBadChunkOffset is a predefined error for a chunk which doesn't start
+	// where the uploaded content of the blob ends.
+	ErrBadChunkOffset = errors.New("chunk doesn't start at the end of the uploaded content")
+	// ErrBadChunkSize is a predefined error for a chunk which is empty, too large
+	// or goes past the size of the blob.
+	ErrBadChunkSize = errors.New("bad chunk size")
+	// ErrBlobIncomplete is a predefined error for a case when the content of the blob
+	// is read before it is uploaded.
+	ErrBlobIncomplete = errors.New("blob upload is not complete")
+	// ErrBlobInUse is a predefined error for a case when the blob is already
+	// referred to by another record.
+	ErrBlobInUse = errors.New("blob is referred to by another record")
+	// ErrWrongBackupPassword is a predefined error for a case when the password
+	// can't decrypt the key check of the backup.
+	ErrWrongBackupPassword = errors.New("wrong password of the backup")
+	// ErrCorruptedBackup is a predefined error for a backup which content
+	// doesn't match its manifest.
+	ErrCorruptedBackup = errors.New("corrupted backup")
+	// ErrMFANotEnrolled is a predefined error for a case when the user has not
+	// started the enrollment of the second factor.
+	ErrMFANotEnrolled = errors.New("second factor is not enrolled")
+	// ErrMFAEnabled is a predefined error for a case when the second factor is already enabled.
+	ErrMFAEnabled = errors.New("second factor is already enabled")
+	// ErrMFADisabled is a predefined error for a case when the second factor is not enabled.
+	ErrMFADisabled = errors.New("second factor is not enabled")
+	// ErrBadMFACode is a predefined error for a wrong, expired or already used one-time code.
+	ErrBadMFACode = errors.New("one-time code is incorrect")
+	// ErrBadMFAToken is a predefined error for an invalid or expired challenge token of the login.
+	ErrBadMFAToken = errors.New("mfa token is invalid or expired")
+	// ErrLoginLocked is a predefined error for a case when the logins of the username
+	// or from the address are rejected after too many failures.
+	ErrLoginLocked = errors.New("too many failed logins")
+	// ErrBadDevice is a predefined error for a device with a bad name, platform or public key.
+	ErrBadDevice = errors.New("bad device")
+	// ErrBadDeviceProof is a predefined error for a missing or wrong signature of the device nonce
+	// or for the nonce which is invalid, expired or already used.
+	ErrBadDeviceProof = errors.New("device proof is invalid")
+	// ErrDeviceNotFound is a predefined error for a case when the device is not found.
+	ErrDeviceNotFound = errors.New("device was not found")
+	// ErrLegacyNotMigrated is a predefined error for the records written before
+	// the authenticated format when the legacy migration has not been run.
+	ErrLegacyNotMigrated = errors.New("legacy records are not migrated, run migrate-legacy")
 	// ErrNoDocuments is returned by SingleResult methods when the operation that created the SingleResult did not return any documents.
 	ErrNoDocuments = mongo.ErrNoDocuments
 	// ErrUsernameIsTakenMongo is a predefined mongo server error for when username is already taken.
 	ErrUsernameIsTakenMongo = mongo.CommandError{Code: 11000}
 )
+
+// LockedError is returned when the login is rejected because of the lockout.
+// It holds the time left until the lockout ends.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+// Error returns the message with the time left until the lockout ends.
+func (e *LockedError) Error() string {
+	return fmt.Sprintf(
+		"%v, try again in %v",
+		ErrLoginLocked,
+		time.Duration(e.RetryAfterSeconds())*time.Second,
+	)
+}
+
+// Unwrap returns ErrLoginLocked.
+func (e *LockedError) Unwrap() error {
+	return ErrLoginLocked
+}
+
+// RetryAfterSeconds returns the time left until the lockout ends rounded up to seconds.
+func (e *LockedError) RetryAfterSeconds() int64 {
+	return int64((e.RetryAfter + time.Second - 1) / time.Second)
+}
@@ -2,20 +2,26 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
 )
 
 // UsernameContextValue is the key used to set and get the username value in gin.Context.
 const UsernameContextValue = "username"
 
+// ClaimsContextValue is the key used to set and get the *auth.Claims of the token in gin.Context.
+const ClaimsContextValue = "claims"
+
 // JWTAuthMiddleware is a middleware that performs JWT token validation.
+// The revoked tokens are rejected.
 // It returns a gin.HandlerFunc which can be used in a gin route.
-func JWTAuthMiddleware(signingKey []byte) gin.HandlerFunc {
+func JWTAuthMiddleware(keys *auth.KeySet, revocations RevocationList) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.Request.Header.Get("Authorization")
 		var tokenString string
@@ -23,13 +29,25 @@ func JWTAuthMiddleware(signingKey []byte) gin.HandlerFunc {
 			tokenString = strings.Split(authHeader, " ")[1]
 		}
 
-		username, err := auth.ValidateJWTToken(tokenString, signingKey)
-		if err != nil {
+		claims, err := verifyToken(ctx, tokenString, keys, revocations)
+		if errors.Is(err, errUnauthenticated) {
 			ctx.String(http.StatusUnauthorized, "Unauthorized")
 			ctx.Abort()
 			return
+		} else if err != nil {
+			log.Errorf("unable to check the token: %v", err)
+			ctx.String(http.StatusInternalServerError, "Internal Server Error")
+			ctx.Abort()
+			return
 		}
-		ctx.Set(UsernameContextValue, username)
+		ctx.Set(UsernameContextValue, claims.Username)
+		ctx.Set(ClaimsContextValue, claims)
 		ctx.Next()
 	}
 }
+
+// ClaimsFromGinContext returns the claims of the token set by JWTAuthMiddleware or nil.
+func ClaimsFromGinContext(ctx *gin.Context) *auth.Claims {
+	claims, _ := ctx.Value(ClaimsContextValue).(*auth.Claims)
+	return claims
+}
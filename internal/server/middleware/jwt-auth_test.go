@@ -1,19 +1,33 @@
 package middleware
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/blokhinnv/gophkeeper/internal/server/auth"
 )
 
+// revocationList is a RevocationList with the revoked tokens ids.
+type revocationList map[string]bool
+
+func (l revocationList) IsRevoked(_ context.Context, jti string) (bool, error) {
+	return l[jti], nil
+}
+
 func TestJWTAuthMiddleware(t *testing.T) {
 	signingKey := []byte("secret")
+	keys := auth.NewHMACKeySet(signingKey)
 	t.Run("unauthorized", func(t *testing.T) {
 		// Test unauthorized request
 		// Create a mock gin context
@@ -21,7 +35,7 @@ func TestJWTAuthMiddleware(t *testing.T) {
 		c, _ := gin.CreateTestContext(w)
 		req := httptest.NewRequest("GET", "/test", nil)
 		c.Request = req
-		JWTAuthMiddleware(signingKey)(c)
+		JWTAuthMiddleware(keys, revocationList{})(c)
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
 	t.Run("authorized", func(t *testing.T) {
@@ -32,8 +46,87 @@ func TestJWTAuthMiddleware(t *testing.T) {
 		c.Request = req
 		tokenString, _ := auth.GenerateJWTToken("user", signingKey, time.Hour)
 		req.Header.Set("Authorization", "Bearer: "+tokenString)
-		JWTAuthMiddleware(signingKey)(c)
+		JWTAuthMiddleware(keys, revocationList{})(c)
 		assert.Equal(t, http.StatusOK, w.Code)
 		assert.Equal(t, "user", c.GetString(UsernameContextValue))
 	})
+	t.Run("revoked", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest("GET", "/test", nil)
+		c.Request = req
+		tokenString, claims, _ := auth.NewAccessToken("user", "session", signingKey, time.Hour)
+		req.Header.Set("Authorization", "Bearer: "+tokenString)
+		JWTAuthMiddleware(keys, revocationList{claims.ID: true})(c)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	t.Run("without_id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest("GET", "/test", nil)
+		c.Request = req
+		tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Username: "user",
+		}).SignedString(signingKey)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer: "+tokenString)
+		JWTAuthMiddleware(keys, revocationList{})(c)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	t.Run("claims", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest("GET", "/test", nil)
+		c.Request = req
+		tokenString, claims, _ := auth.NewAccessToken("user", "session", signingKey, time.Hour)
+		req.Header.Set("Authorization", "Bearer: "+tokenString)
+		JWTAuthMiddleware(keys, revocationList{})(c)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, claims.ID, ClaimsFromGinContext(c).ID)
+		assert.Equal(t, "session", ClaimsFromGinContext(c).SessionID)
+	})
+	t.Run("unavailable", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req := httptest.NewRequest("GET", "/test", nil)
+		c.Request = req
+		tokenString, _, _ := auth.NewAccessToken("user", "session", signingKey, time.Hour)
+		req.Header.Set("Authorization", "Bearer: "+tokenString)
+		JWTAuthMiddleware(keys, brokenRevocationList{})(c)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+	t.Run("ed25519", func(t *testing.T) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		edKeys, err := auth.NewKeySet(key)
+		require.NoError(t, err)
+		tokenString, _, err := edKeys.NewAccessToken("user", "session", "", time.Hour)
+		require.NoError(t, err)
+		for _, tc := range []struct {
+			keys *auth.KeySet
+			code int
+		}{
+			{edKeys, http.StatusOK},
+			// the legacy keys don't accept the tokens of the key pair
+			{keys, http.StatusUnauthorized},
+		} {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			req := httptest.NewRequest("GET", "/test", nil)
+			c.Request = req
+			req.Header.Set("Authorization", "Bearer: "+tokenString)
+			JWTAuthMiddleware(tc.keys, revocationList{})(c)
+			assert.Equal(t, tc.code, w.Code)
+		}
+	})
+}
+
+// brokenRevocationList is a RevocationList which always fails.
+type brokenRevocationList struct{}
+
+func (brokenRevocationList) IsRevoked(context.Context, string) (bool, error) {
+	return false, errors.New("db is unavailable")
 }
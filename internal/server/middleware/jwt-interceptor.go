@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
+)
+
+// usernameContextKey is the key used to set and get the username value in context.Context.
+type usernameContextKey struct{}
+
+// claimsContextKey is the key used to set and get the claims of the token in context.Context.
+type claimsContextKey struct{}
+
+// NewUsernameContext returns a copy of the context with the username.
+func NewUsernameContext(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, usernameContextKey{}, username)
+}
+
+// UsernameFromContext returns the username set by the JWT interceptors
+// or an empty string.
+func UsernameFromContext(ctx context.Context) string {
+	username, _ := ctx.Value(usernameContextKey{}).(string)
+	return username
+}
+
+// NewClaimsContext returns a copy of the context with the claims of the token.
+func NewClaimsContext(ctx context.Context, claims *auth.Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the token set by the JWT interceptors or nil.
+func ClaimsFromContext(ctx context.Context) *auth.Claims {
+	claims, _ := ctx.Value(claimsContextKey{}).(*auth.Claims)
+	return claims
+}
+
+// authenticate validates the JWT token from the authorization metadata
+// and returns a context with the username and the claims of the token.
+func authenticate(
+	ctx context.Context,
+	keys *auth.KeySet,
+	revocations RevocationList,
+) (context.Context, error) {
+	var tokenString string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			if parts := strings.Split(values[0], " "); len(parts) == 2 {
+				tokenString = parts[1]
+			}
+		}
+	}
+	claims, err := verifyToken(ctx, tokenString, keys, revocations)
+	if errors.Is(err, errUnauthenticated) {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	} else if err != nil {
+		log.Errorf("unable to check the token: %v", err)
+		return nil, status.Error(codes.Internal, "Internal Server Error")
+	}
+	return NewClaimsContext(NewUsernameContext(ctx, claims.Username), claims), nil
+}
+
+// JWTAuthUnaryInterceptor is a gRPC interceptor that performs JWT token
+// validation for unary calls except the public methods.
+func JWTAuthUnaryInterceptor(
+	keys *auth.KeySet,
+	revocations RevocationList,
+	publicMethods ...string,
+) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if slices.Contains(publicMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, keys, revocations)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authenticatedStream is a server stream with the context containing the username.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context containing the username.
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// JWTAuthStreamInterceptor is a gRPC interceptor that performs JWT token
+// validation for streaming calls except the public methods.
+func JWTAuthStreamInterceptor(
+	keys *auth.KeySet,
+	revocations RevocationList,
+	publicMethods ...string,
+) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if slices.Contains(publicMethods, info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), keys, revocations)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
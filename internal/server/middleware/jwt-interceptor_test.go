@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+)
+
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestJWTAuthUnaryInterceptor(t *testing.T) {
+	signingKey := []byte("secret")
+	keys := auth.NewHMACKeySet(signingKey)
+	interceptor := JWTAuthUnaryInterceptor(keys, revocationList{}, "/public")
+	handler := func(ctx context.Context, req any) (any, error) {
+		return UsernameFromContext(ctx), nil
+	}
+	tokenString, err := auth.GenerateJWTToken("user", signingKey, time.Hour)
+	require.NoError(t, err)
+
+	t.Run("unauthorized", func(t *testing.T) {
+		_, err := interceptor(
+			context.Background(),
+			nil,
+			&grpc.UnaryServerInfo{FullMethod: "/private"},
+			handler,
+		)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("bad_token", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(
+			context.Background(),
+			metadata.Pairs("authorization", "Bearer: "+tokenString+"x"),
+		)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/private"}, handler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("authorized", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(
+			context.Background(),
+			metadata.Pairs("authorization", "Bearer: "+tokenString),
+		)
+		username, err := interceptor(
+			ctx,
+			nil,
+			&grpc.UnaryServerInfo{FullMethod: "/private"},
+			handler,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "user", username)
+	})
+	t.Run("revoked", func(t *testing.T) {
+		tokenString, claims, err := auth.NewAccessToken("user", "session", signingKey, time.Hour)
+		require.NoError(t, err)
+		ctx := metadata.NewIncomingContext(
+			context.Background(),
+			metadata.Pairs("authorization", "Bearer: "+tokenString),
+		)
+		interceptor := JWTAuthUnaryInterceptor(keys, revocationList{claims.ID: true})
+		_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/private"}, handler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		interceptor = JWTAuthUnaryInterceptor(keys, brokenRevocationList{})
+		_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/private"}, handler)
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+	t.Run("claims", func(t *testing.T) {
+		tokenString, claims, err := auth.NewAccessToken("user", "session", signingKey, time.Hour)
+		require.NoError(t, err)
+		ctx := metadata.NewIncomingContext(
+			context.Background(),
+			metadata.Pairs("authorization", "Bearer: "+tokenString),
+		)
+		res, err := interceptor(
+			ctx,
+			nil,
+			&grpc.UnaryServerInfo{FullMethod: "/private"},
+			func(ctx context.Context, req any) (any, error) {
+				return ClaimsFromContext(ctx), nil
+			},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, claims.ID, res.(*auth.Claims).ID)
+	})
+	t.Run("public", func(t *testing.T) {
+		username, err := interceptor(
+			context.Background(),
+			nil,
+			&grpc.UnaryServerInfo{FullMethod: "/public"},
+			handler,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "", username)
+	})
+}
+
+func TestJWTAuthStreamInterceptor(t *testing.T) {
+	signingKey := []byte("secret")
+	keys := auth.NewHMACKeySet(signingKey)
+	interceptor := JWTAuthStreamInterceptor(keys, revocationList{})
+	var username string
+	handler := func(srv any, stream grpc.ServerStream) error {
+		username = UsernameFromContext(stream.Context())
+		return nil
+	}
+	info := &grpc.StreamServerInfo{FullMethod: "/private"}
+
+	err := interceptor(nil, &testStream{ctx: context.Background()}, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	tokenString, err := auth.GenerateJWTToken("user", signingKey, time.Hour)
+	require.NoError(t, err)
+	ctx := metadata.NewIncomingContext(
+		context.Background(),
+		metadata.Pairs("authorization", "Bearer: "+tokenString),
+	)
+	err = interceptor(nil, &testStream{ctx: ctx}, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "user", username)
+}
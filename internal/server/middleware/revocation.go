@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+)
+
+// RevocationList reports whether an access token was revoked before it expired.
+type RevocationList interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// errUnauthenticated is returned for the invalid, expired and revoked tokens.
+var errUnauthenticated = errors.New("unauthenticated")
+
+// verifyToken validates the token and checks its id against the revocation list.
+// The tokens without an id can't be revoked, so they are rejected.
+// Errors other than errUnauthenticated mean the revocation list is unavailable.
+func verifyToken(
+	ctx context.Context,
+	tokenString string,
+	keys *auth.KeySet,
+	revocations RevocationList,
+) (*auth.Claims, error) {
+	claims, err := keys.Parse(tokenString)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+	if claims.ID == "" {
+		return nil, errUnauthenticated
+	}
+	revoked, err := revocations.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errUnauthenticated
+	}
+	return claims, nil
+}
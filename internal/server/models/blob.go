@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// BlobChunkSize is the maximum size of a chunk of a blob accepted by the server.
+const BlobChunkSize = 1 << 20
+
+// Blob is the content of a binary record uploaded in chunks. The chunks are
+// appended one by one, so an interrupted upload is resumed from Received.
+//
+// A blob is claimed by the first binary record which refers to it, so two
+// records never share a blob. When the record is changed to refer to another
+// blob, the previous one is released and only the revisions of the record
+// may still refer to it.
+type Blob struct {
+	BlobID     ObjectID   `bson:"_id"                   json:"blob_id"`    // BlobID is the ID the binary records refer to.
+	Username   string     `bson:"username"              json:"-"`          // Username represents the username of the blob owner.
+	Size       int64      `bson:"size"                  json:"size"`       // Size is the size of the content in bytes.
+	Received   int64      `bson:"received"              json:"received"`   // Received is the number of bytes uploaded so far.
+	Chunks     int64      `bson:"chunks"                json:"chunks"`     // Chunks is the number of chunks uploaded so far.
+	CreatedAt  time.Time  `bson:"created_at"            json:"created_at"` // CreatedAt is the time the upload was started.
+	RecordID   ObjectID   `bson:"record_id,omitempty"   json:"-"`          // RecordID is the ID of the record which claimed the blob.
+	ReleasedAt *time.Time `bson:"released_at,omitempty" json:"-"`          // ReleasedAt is the time the record stopped referring to the blob.
+}
+
+// Complete reports whether the whole content of the blob was uploaded.
+func (b *Blob) Complete() bool {
+	return b.Received == b.Size
+}
+
+// NewBlobRequest is the body of the request which starts an upload.
+type NewBlobRequest struct {
+	Size int64 `json:"size" binding:"min=0"`
+}
+
+// BlobChunk is an encrypted part of the content of a blob.
+type BlobChunk struct {
+	BlobID ObjectID `bson:"blob_id"` // BlobID is the ID of the blob the chunk belongs to.
+	N      int64    `bson:"n"`       // N is the number of the chunk in the blob starting from zero.
+	KeyID  string   `bson:"key_id"`  // KeyID is the id of the key which encrypted the data.
+	Data   []byte   `bson:"data"`    // Data is the encrypted content of the chunk.
+}
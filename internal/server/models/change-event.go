@@ -0,0 +1,19 @@
+package models
+
+// ChangeOp is a kind of the change made to a record.
+type ChangeOp string
+
+// OpCreate, OpUpdate and OpDelete are constants representing the changes of a record.
+const (
+	OpCreate ChangeOp = "create"
+	OpUpdate ChangeOp = "update"
+	OpDelete ChangeOp = "delete"
+)
+
+// ChangeEvent describes a change of a record pushed to the clients of the record owner.
+type ChangeEvent struct {
+	Collection CollectionName `json:"collection"`
+	RecordID   ObjectID       `json:"record_id"`
+	Op         ChangeOp       `json:"op"`
+	Version    int64          `json:"version"` // Version is a number of the change among all the changes of the user.
+}
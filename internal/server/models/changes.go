@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+)
+
+// ChangedRecord is a record created or updated since the sync cursor.
+type ChangedRecord struct {
+	Collection CollectionName `json:"collection"` // Collection is the name of the record's collection.
+	Record     UntypedRecord  `json:"record"`     // Record is the current state of the record.
+}
+
+// Tombstone is a record deleted since the sync cursor.
+type Tombstone struct {
+	Collection CollectionName `json:"collection"`           // Collection is the name of the record's collection.
+	RecordID   ObjectID       `json:"record_id"`            // RecordID is the ID of the deleted record.
+	DeletedAt  *time.Time     `json:"deleted_at,omitempty"` // DeletedAt is the time the record was moved to the trash.
+}
+
+// Changes are the changes of the user's records across all the collections since the sync cursor.
+type Changes struct {
+	Upserts    []ChangedRecord `json:"upserts"`
+	Tombstones []Tombstone     `json:"tombstones"`
+	// Cursor is the cursor to pass to get the next changes.
+	Cursor string `json:"cursor"`
+	// Reset is set if the changes are not tracked since the cursor. Upserts then hold
+	// all the user's records and the records missing from them are to be dropped.
+	Reset bool `json:"reset,omitempty"`
+}
+
+// NewSyncCursor returns an opaque cursor of the changes made after the time.
+func NewSyncCursor(t time.Time) string {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseSyncCursor returns the time of the cursor. The empty cursor
+// is the zero time, which means all the records.
+func ParseSyncCursor(cursor string) (time.Time, error) {
+	if cursor == "" {
+		return time.Time{}, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(b) != 8 {
+		return time.Time{}, fmt.Errorf("%w: %q", errors.ErrBadSyncCursor, cursor)
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b))).UTC(), nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+)
+
+func TestSyncCursor(t *testing.T) {
+	now := time.Now().UTC()
+	since, err := ParseSyncCursor(NewSyncCursor(now))
+	require.NoError(t, err)
+	assert.True(t, now.Equal(since))
+
+	since, err = ParseSyncCursor("")
+	require.NoError(t, err)
+	assert.True(t, since.IsZero())
+
+	for _, cursor := range []string{"bad cursor", "AAAA"} {
+		_, err = ParseSyncCursor(cursor)
+		assert.ErrorIs(t, err, errors.ErrBadSyncCursor)
+	}
+}
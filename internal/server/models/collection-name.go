@@ -24,7 +24,7 @@ func NewCollectionName(s string) (CollectionName, error) {
 }
 
 // TextCollection, CredentialsCollection,
-// BinaryCollection, and CardCollection
+// BinaryCollection, CardCollection and OTPCollection
 // are constants representing the different types
 // of collections that can be used in the server.
 const (
@@ -32,6 +32,7 @@ const (
 	CredentialsCollection CollectionName = "credentials"
 	BinaryCollection      CollectionName = "binary"
 	CardCollection        CollectionName = "cards"
+	OTPCollection         CollectionName = "otp"
 )
 
 // AllowedCollectionNames is a slice of implemented collection names.
@@ -40,4 +41,5 @@ var AllowedCollectionNames = []CollectionName{
 	CredentialsCollection,
 	BinaryCollection,
 	CardCollection,
+	OTPCollection,
 }
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+)
+
+// MaxDeviceFieldLength is the maximum length of the name and of the platform of a device.
+const MaxDeviceFieldLength = 128
+
+// DeviceInfo is the device which the client registers on login.
+type DeviceInfo struct {
+	Name     string `json:"name"       bson:"name"       binding:"required"`
+	Platform string `json:"platform"   bson:"platform"`
+	// PublicKey is the Ed25519 public key of the device in the standard base64.
+	// The device keeps its private key, so the key identifies the device between the logins.
+	PublicKey string `json:"public_key" bson:"public_key" binding:"required"`
+	// Proof proves that the client holds the private key of the device.
+	// It is checked on login and is never saved.
+	Proof *DeviceProof `json:"proof,omitempty" bson:"-"`
+}
+
+// DeviceNonce is the short-lived nonce which the client signs with the key
+// of the device on login and on refresh. Every nonce can be used only once.
+type DeviceNonce struct {
+	Nonce     string    `json:"nonce"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DeviceProof proves that the client holds the private key of the device.
+type DeviceProof struct {
+	// Nonce is the nonce issued by the server.
+	Nonce string `json:"nonce"`
+	// Signature is the Ed25519 signature of the nonce made with the private key
+	// of the device, in the standard base64.
+	Signature string `json:"signature"`
+}
+
+// Validate checks the name, the platform and the public key of the device
+// and that the proof of the key is present; the proof itself is verified on registration.
+func (d DeviceInfo) Validate() error {
+	if d.Name == "" || len(d.Name) > MaxDeviceFieldLength {
+		return fmt.Errorf(
+			"%w: name must have from 1 to %d characters",
+			errors.ErrBadDevice,
+			MaxDeviceFieldLength,
+		)
+	}
+	if len(d.Platform) > MaxDeviceFieldLength {
+		return fmt.Errorf(
+			"%w: platform must have at most %d characters",
+			errors.ErrBadDevice,
+			MaxDeviceFieldLength,
+		)
+	}
+	key, err := base64.StdEncoding.DecodeString(d.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: public key must be an Ed25519 key in base64", errors.ErrBadDevice)
+	}
+	if d.Proof == nil {
+		return fmt.Errorf("%w: proof of the key is required", errors.ErrBadDevice)
+	}
+	return nil
+}
+
+// Device is the device of the user registered on login. The sessions started
+// on the device and their access tokens are bound to its id, so deleting
+// the device signs it out.
+type Device struct {
+	ID          ObjectID `json:"device_id"     bson:"_id"`
+	Username    string   `json:"-"             bson:"username"`
+	DeviceInfo  `bson:",inline"`
+	CreatedAt   time.Time `json:"created_at"    bson:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at" bson:"last_login_at"`
+	Current     bool      `json:"current"       bson:"-"` // Current is set for the device of the token of the request.
+}
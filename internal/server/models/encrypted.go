@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+)
+
+// EncryptedField is the only field of the data of an end-to-end encrypted record.
+// The client encrypts the data and the metadata of the record together, so the
+// server stores an opaque envelope of any collection in the same form:
+//
+//	{"data": {"encrypted": "<base64 ciphertext>"}}
+const EncryptedField = "encrypted"
+
+// NewEncryptedData creates the data of an end-to-end encrypted record.
+func NewEncryptedData(ciphertext []byte) map[string]any {
+	return map[string]any{EncryptedField: base64.StdEncoding.EncodeToString(ciphertext)}
+}
+
+// IsEncryptedData reports whether the data is the envelope of an end-to-end
+// encrypted record, i.e. a map with the encrypted field.
+func IsEncryptedData(data any) bool {
+	switch m := data.(type) {
+	case map[string]any:
+		_, ok := m[EncryptedField]
+		return ok
+	case map[string]string:
+		_, ok := m[EncryptedField]
+		return ok
+	}
+	return false
+}
+
+// EncryptedCiphertext returns the ciphertext from the envelope of an
+// end-to-end encrypted record. The envelope must not have any other fields.
+func EncryptedCiphertext(data any) ([]byte, error) {
+	var value any
+	var size int
+	switch m := data.(type) {
+	case map[string]any:
+		value, size = m[EncryptedField], len(m)
+	case map[string]string:
+		value, size = m[EncryptedField], len(m)
+	}
+	s, ok := value.(string)
+	if !ok || s == "" || size != 1 {
+		return nil, errors.ErrBadEnvelope
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrBadEnvelope, err)
+	}
+	return ciphertext, nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+)
+
+func TestEncryptedData(t *testing.T) {
+	data := NewEncryptedData([]byte("ciphertext"))
+	assert.True(t, IsEncryptedData(data))
+	ciphertext, err := EncryptedCiphertext(data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ciphertext"), ciphertext)
+
+	// the storage returns the decrypted maps with string values
+	ciphertext, err = EncryptedCiphertext(map[string]string{EncryptedField: "Y2lwaGVydGV4dA=="})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ciphertext"), ciphertext)
+}
+
+func TestIsEncryptedData(t *testing.T) {
+	assert.False(t, IsEncryptedData("some text"))
+	assert.False(t, IsEncryptedData(map[string]any{"Login": "login", "Password": "password"}))
+	assert.True(t, IsEncryptedData(map[string]any{EncryptedField: 42}))
+}
+
+func TestEncryptedCiphertext(t *testing.T) {
+	tests := []struct {
+		name string
+		data any
+	}{
+		{name: "not_a_map", data: "some text"},
+		{name: "no_field", data: map[string]any{"Login": "login"}},
+		{name: "not_a_string", data: map[string]any{EncryptedField: 42}},
+		{name: "empty", data: map[string]any{EncryptedField: ""}},
+		{name: "not_base64", data: map[string]any{EncryptedField: "???"}},
+		{
+			name: "extra_fields",
+			data: map[string]any{EncryptedField: "Y2lwaGVydGV4dA==", "Login": "login"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EncryptedCiphertext(tt.data)
+			assert.ErrorIs(t, err, errors.ErrBadEnvelope)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// LoginAttempts counts the recent failed logins of a username or of an address.
+// The document is removed by a TTL index after ExpiresAt.
+type LoginAttempts struct {
+	Key         string    `bson:"_id"` // Key is "user:<username>" or "ip:<address>".
+	Failures    int       `bson:"failures"`
+	LastFailure time.Time `bson:"last_failure"`
+	LockedUntil time.Time `bson:"locked_until,omitempty"` // LockedUntil is the end of the lockout; the logins are rejected until then.
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
+
+// Locked reports whether the logins are rejected at the moment.
+func (a *LoginAttempts) Locked(now time.Time) bool {
+	return a != nil && a.LockedUntil.After(now)
+}
+
+// Types of the security events.
+const (
+	// EventLoginFailed is recorded when the username or the password is incorrect.
+	EventLoginFailed = "login_failed"
+	// EventLockout is recorded when too many failures lock the username or the address.
+	EventLockout = "lockout"
+	// EventLoginLocked is recorded when a login is rejected because of the lockout.
+	EventLoginLocked = "login_locked"
+	// EventUnlock is recorded when the administrator removes the lockout.
+	EventUnlock = "unlock"
+)
+
+// Scopes of the lockout.
+const (
+	LockoutScopeUser = "user"
+	LockoutScopeIP   = "ip"
+)
+
+// SecurityEvent is a record of the audit log of the logins.
+// The event is removed by a TTL index after ExpiresAt unless it is zero.
+type SecurityEvent struct {
+	ID          ObjectID  `bson:"_id"`
+	Type        string    `bson:"type"`
+	Username    string    `bson:"username,omitempty"`
+	IP          string    `bson:"ip,omitempty"`
+	Scope       string    `bson:"scope,omitempty"`        // Scope is the kind of the lockout: by the username or by the address.
+	Failures    int       `bson:"failures,omitempty"`     // Failures is the number of the recent failures of the scope.
+	LockedUntil time.Time `bson:"locked_until,omitempty"` // LockedUntil is the end of the lockout.
+	CreatedAt   time.Time `bson:"created_at"`
+	ExpiresAt   time.Time `bson:"expires_at,omitempty"`
+}
@@ -0,0 +1,51 @@
+package models
+
+// MFA holds the TOTP second factor of the user. The secret is encrypted
+// with the key of the server, the recovery codes are saved hashed.
+type MFA struct {
+	Secret        string   `bson:"secret"`
+	Enabled       bool     `bson:"enabled"`                  // Enabled is set once the secret is confirmed with a code.
+	RecoveryCodes []string `bson:"recovery_codes,omitempty"` // RecoveryCodes are the hashes of the unused recovery codes.
+	LastStep      int64    `bson:"last_step,omitempty"`      // LastStep is the time step of the last accepted code, so a code can't be used twice.
+}
+
+// MFAEnrollment is returned when the user starts the enrollment of the second factor.
+// The secret or the provisioning URI is added to an authenticator app.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // URI is the otpauth:// provisioning URI of the secret.
+}
+
+// MFACodeRequest is the request body with a code of the second factor.
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAEnrollRequest is the request body which confirms the enrollment
+// of the second factor with the password.
+type MFAEnrollRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// MFADisableRequest is the request body which turns off the second factor.
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	// Code is the current one-time code or one of the recovery codes.
+	Code string `json:"code" binding:"required"`
+}
+
+// MFALoginRequest is the request body which exchanges the challenge token
+// of the login for the tokens of the session.
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// Code is the current one-time code or one of the recovery codes.
+	Code string `json:"code" binding:"required"`
+	// Device is the device registered by the login like the one of LoginRequest.
+	Device *DeviceInfo `json:"device,omitempty"`
+}
+
+// MFARecoveryCodes is returned when the second factor is enabled. Every code
+// replaces the one-time code once, for example, when the device is lost.
+type MFARecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
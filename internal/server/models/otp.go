@@ -0,0 +1,101 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/blokhinnv/gophkeeper/pkg/otp"
+)
+
+// OTPCode represents a generated one-time password.
+type OTPCode struct {
+	Code             string `json:"code"`              // Code is the current one-time password.
+	RemainingSeconds int    `json:"remaining_seconds"` // RemainingSeconds is the time left until the code changes (0 for HOTP).
+}
+
+// NewOTPInfo converts the generator parameters into OTPInfo.
+func NewOTPInfo(key *otp.Key) OTPInfo {
+	info := OTPInfo{
+		Type:      string(key.Type),
+		Secret:    key.Secret,
+		Issuer:    key.Issuer,
+		Account:   key.Account,
+		Algorithm: string(key.Algorithm),
+	}
+	if key.Digits != 0 {
+		info.Digits = strconv.Itoa(key.Digits)
+	}
+	if key.Type == otp.TypeHOTP {
+		info.Counter = strconv.FormatUint(key.Counter, 10)
+	} else if key.Period != 0 {
+		info.Period = strconv.Itoa(key.Period)
+	}
+	return info
+}
+
+// Key converts OTPInfo into the generator parameters.
+// Missing parameters are replaced with the defaults.
+func (i OTPInfo) Key() (*otp.Key, error) {
+	t := otp.Type(i.Type)
+	if t == "" {
+		t = otp.TypeTOTP
+	}
+	key := otp.NewKey(t, i.Secret)
+	key.Issuer = i.Issuer
+	key.Account = i.Account
+	if i.Algorithm != "" {
+		key.Algorithm = otp.Algorithm(i.Algorithm)
+	}
+	var err error
+	if i.Digits != "" {
+		if key.Digits, err = strconv.Atoi(i.Digits); err != nil {
+			return nil, err
+		}
+	}
+	if i.Period != "" {
+		if key.Period, err = strconv.Atoi(i.Period); err != nil {
+			return nil, err
+		}
+	}
+	if i.Counter != "" {
+		if key.Counter, err = strconv.ParseUint(i.Counter, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// IsHOTP returns true if the codes depend on the counter instead of the time.
+func (i OTPInfo) IsHOTP() bool {
+	return otp.Type(i.Type) == otp.TypeHOTP
+}
+
+// Next returns the parameters of the generator after a code has been issued:
+// the counter of a HOTP generator is incremented so the code isn't reused.
+func (i OTPInfo) Next() (OTPInfo, error) {
+	if !i.IsHOTP() {
+		return i, nil
+	}
+	key, err := i.Key()
+	if err != nil {
+		return i, err
+	}
+	i.Counter = strconv.FormatUint(key.Counter+1, 10)
+	return i, nil
+}
+
+// Code generates a one-time password for the moment t.
+func (i OTPInfo) Code(t time.Time) (*OTPCode, error) {
+	key, err := i.Key()
+	if err != nil {
+		return nil, err
+	}
+	code, remaining, err := key.Code(t)
+	if err != nil {
+		return nil, err
+	}
+	return &OTPCode{
+		Code:             code,
+		RemainingSeconds: int(remaining.Round(time.Second).Seconds()),
+	}, nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/pkg/otp"
+)
+
+func TestOTPInfo_Key(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		key, err := OTPInfo{Secret: "JBSWY3DPEHPK3PXP"}.Key()
+		require.NoError(t, err)
+		assert.Equal(t, otp.NewKey(otp.TypeTOTP, "JBSWY3DPEHPK3PXP"), key)
+	})
+	t.Run("round_trip", func(t *testing.T) {
+		info := OTPInfo{
+			Type:      "hotp",
+			Secret:    "JBSWY3DPEHPK3PXP",
+			Issuer:    "Example",
+			Account:   "bob",
+			Algorithm: "SHA256",
+			Digits:    "8",
+			Counter:   "42",
+		}
+		key, err := info.Key()
+		require.NoError(t, err)
+		assert.Equal(t, info, NewOTPInfo(key))
+	})
+	t.Run("bad_digits", func(t *testing.T) {
+		_, err := OTPInfo{Secret: "JBSWY3DPEHPK3PXP", Digits: "six"}.Key()
+		assert.Error(t, err)
+	})
+}
+
+func TestOTPInfo_Code(t *testing.T) {
+	// "12345678901234567890" in base32, see RFC 6238
+	info := OTPInfo{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Digits: "8"}
+	code, err := info.Code(time.Unix(1111111109, 0))
+	require.NoError(t, err)
+	assert.Equal(t, &OTPCode{Code: "07081804", RemainingSeconds: 1}, code)
+
+	_, err = OTPInfo{Secret: "1"}.Code(time.Now())
+	assert.Error(t, err)
+}
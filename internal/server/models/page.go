@@ -0,0 +1,130 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+)
+
+// SortField is the field the records are listed by.
+type SortField string
+
+// SortCreated lists the records by the time they were created,
+// SortUpdated lists them by the time of the last change. The "-" prefix
+// reverses the order.
+const (
+	SortCreated SortField = "created"
+	SortUpdated SortField = "updated"
+)
+
+// MaxPageLimit is the maximum number of the records in a page.
+const MaxPageLimit = 1000
+
+// NextCursorHeader is the HTTP header with the cursor of the next page of the records.
+const NextCursorHeader = "X-Next-Cursor"
+
+// GetAllOptions are the options of the listing of the records of a collection.
+type GetAllOptions struct {
+	Limit       int64    // Limit is the maximum number of the records; zero means all the records.
+	Cursor      string   // Cursor is the cursor returned with the previous page.
+	Sort        string   // Sort is the SortField, optionally prefixed with "-" for the descending order.
+	Metadata    Metadata // Metadata holds the values the metadata of the records must have.
+	OmitContent bool     // OmitContent omits the content of the binary records.
+}
+
+// PageKey is the position of a record in the listing.
+type PageKey struct {
+	RecordID  ObjectID   `json:"id"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// pageCursor is the content of the opaque cursor of a page.
+type pageCursor struct {
+	Sort string `json:"sort"`
+	PageKey
+}
+
+// SortField returns the field the records are listed by and whether the order is descending.
+func (o GetAllOptions) SortField() (SortField, bool) {
+	if o.Sort == "" {
+		return SortCreated, false
+	}
+	return SortField(strings.TrimPrefix(o.Sort, "-")), strings.HasPrefix(o.Sort, "-")
+}
+
+// Validate checks the limit, the sort field and the cursor.
+func (o GetAllOptions) Validate() error {
+	if o.Limit < 0 || o.Limit > MaxPageLimit {
+		return fmt.Errorf(
+			"%w: limit must be between 0 and %d",
+			errors.ErrBadListOptions,
+			MaxPageLimit,
+		)
+	}
+	if field, _ := o.SortField(); field != SortCreated && field != SortUpdated {
+		return fmt.Errorf("%w: unknown sort %q", errors.ErrBadListOptions, o.Sort)
+	}
+	_, err := o.After()
+	return err
+}
+
+// After returns the key of the last record of the previous page;
+// nil for the first page.
+func (o GetAllOptions) After() (*PageKey, error) {
+	if o.Cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(o.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad cursor %q", errors.ErrBadListOptions, o.Cursor)
+	}
+	var c pageCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%w: bad cursor %q", errors.ErrBadListOptions, o.Cursor)
+	}
+	if c.Sort != o.Sort {
+		return nil, fmt.Errorf("%w: the cursor belongs to another sort", errors.ErrBadListOptions)
+	}
+	return &c.PageKey, nil
+}
+
+// NextCursor returns the opaque cursor of the page which starts after the key.
+func (o GetAllOptions) NextCursor(key PageKey) string {
+	b, _ := json.Marshal(pageCursor{Sort: o.Sort, PageKey: key})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Less reports whether the record with the key a is listed before b.
+func (o GetAllOptions) Less(a, b PageKey) bool {
+	field, desc := o.SortField()
+	if desc {
+		a, b = b, a
+	}
+	if field == SortUpdated {
+		var ta, tb time.Time
+		if a.UpdatedAt != nil {
+			ta = *a.UpdatedAt
+		}
+		if b.UpdatedAt != nil {
+			tb = *b.UpdatedAt
+		}
+		if !ta.Equal(tb) {
+			return ta.Before(tb)
+		}
+	}
+	return a.RecordID.Hex() < b.RecordID.Hex()
+}
+
+// Matches reports whether the metadata has all the values of the filter.
+func (o GetAllOptions) Matches(md Metadata) bool {
+	for k, v := range o.Metadata {
+		if value, ok := md[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
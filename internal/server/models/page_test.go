@@ -0,0 +1,86 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+)
+
+func TestGetAllOptions_Validate(t *testing.T) {
+	key := PageKey{RecordID: NewRandomObjectID()}
+	tests := []struct {
+		name    string
+		options GetAllOptions
+		ok      bool
+	}{
+		{name: "default", options: GetAllOptions{}, ok: true},
+		{name: "limit", options: GetAllOptions{Limit: MaxPageLimit, Sort: "-updated"}, ok: true},
+		{name: "negative_limit", options: GetAllOptions{Limit: -1}},
+		{name: "large_limit", options: GetAllOptions{Limit: MaxPageLimit + 1}},
+		{name: "unknown_sort", options: GetAllOptions{Sort: "name"}},
+		{name: "bad_cursor", options: GetAllOptions{Cursor: "bad cursor"}},
+		{name: "not_json_cursor", options: GetAllOptions{Cursor: "AAAA"}},
+		{
+			name:    "cursor",
+			options: GetAllOptions{Sort: "updated", Cursor: GetAllOptions{Sort: "updated"}.NextCursor(key)},
+			ok:      true,
+		},
+		{
+			name:    "cursor_of_another_sort",
+			options: GetAllOptions{Sort: "-updated", Cursor: GetAllOptions{Sort: "updated"}.NextCursor(key)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, errors.ErrBadListOptions)
+			}
+		})
+	}
+}
+
+func TestGetAllOptions_Cursor(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	key := PageKey{RecordID: NewRandomObjectID(), UpdatedAt: &now}
+	options := GetAllOptions{Sort: "-updated"}
+
+	after, err := options.After()
+	require.NoError(t, err)
+	assert.Nil(t, after)
+
+	options.Cursor = options.NextCursor(key)
+	after, err = options.After()
+	require.NoError(t, err)
+	assert.Equal(t, key.RecordID, after.RecordID)
+	assert.True(t, now.Equal(*after.UpdatedAt))
+}
+
+func TestGetAllOptions_Less(t *testing.T) {
+	earlier := time.Now().UTC()
+	later := earlier.Add(time.Minute)
+	first := PageKey{RecordID: NewRandomObjectID(), UpdatedAt: &later}
+	second := PageKey{RecordID: NewRandomObjectID(), UpdatedAt: &earlier}
+	unknown := PageKey{RecordID: NewRandomObjectID()}
+
+	assert.True(t, GetAllOptions{}.Less(first, second))
+	assert.False(t, GetAllOptions{}.Less(second, first))
+	assert.True(t, GetAllOptions{Sort: "-created"}.Less(second, first))
+	assert.True(t, GetAllOptions{Sort: "updated"}.Less(second, first))
+	assert.True(t, GetAllOptions{Sort: "updated"}.Less(unknown, second))
+	assert.True(t, GetAllOptions{Sort: "-updated"}.Less(first, second))
+}
+
+func TestGetAllOptions_Matches(t *testing.T) {
+	options := GetAllOptions{Metadata: Metadata{"site": "github.com"}}
+	assert.True(t, options.Matches(Metadata{"site": "github.com", "note": "work"}))
+	assert.False(t, options.Matches(Metadata{"site": "gitlab.com"}))
+	assert.False(t, options.Matches(nil))
+	assert.True(t, GetAllOptions{}.Matches(nil))
+}
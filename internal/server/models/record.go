@@ -1,7 +1,11 @@
 // Package models provides the data structures used in the application.
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 // Metadata is a map that holds key-value pairs as additional metadata for a record.
 type Metadata map[string]string
@@ -16,8 +20,11 @@ type UntypedRecordContent struct {
 // It contains a username, data, and metadata.
 type UntypedRecord struct {
 	UntypedRecordContent `bson:",inline"`
-	RecordID             ObjectID `bson:"_id"     json:"record_id"` // Unique ID of a document in the DB.
-	Username             string   `bson:"-"       json:"-"`         // Username represents the username of the record owner.
+	RecordID             ObjectID   `bson:"_id"        json:"record_id"`                      // Unique ID of a document in the DB.
+	Username             string     `bson:"-"          json:"-"`                              // Username represents the username of the record owner.
+	Version              int64      `bson:"version,omitempty"    json:"version,omitempty"`    // Version is incremented on every update of the record.
+	UpdatedAt            *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"` // UpdatedAt is the time of the last change of the record.
+	DeletedAt            *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // DeletedAt is the time the record was moved to the trash.
 }
 
 // TextInfo is an alias for text string
@@ -26,25 +33,33 @@ type TextInfo = string
 // TextRecord represents a record that holds text data. It
 // contains a username, text data, and metadata.
 type TextRecord struct {
-	RecordID ObjectID `json:"record_id,omitempty"` // Unique ID of a document in the DB.
-	Username string   `json:",omitempty"`          // Username represents the username of the record owner.
-	Data     TextInfo // Data is the text data for the record.
-	Metadata Metadata // Metadata is a map that can hold additional metadata for the record.
+	RecordID  ObjectID   `json:"record_id,omitempty"` // Unique ID of a document in the DB.
+	Username  string     `json:",omitempty"`          // Username represents the username of the record owner.
+	Data      TextInfo   // Data is the text data for the record.
+	Metadata  Metadata   // Metadata is a map that can hold additional metadata for the record.
+	Version   int64      `json:"version,omitempty"`    // Version is the version of the record on the server.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // UpdatedAt is the time of the last change of the record.
 }
 
-// BinaryInfo represents a binary data from a file.
+// BinaryInfo represents a binary data from a file. Small files may be kept
+// inline in Content, the larger ones are uploaded in chunks as a blob.
+// The size is kept as a string since the storage encrypts string values only.
 type BinaryInfo struct {
 	FileName string `validate:"required"`
-	Content  string `validate:"required,base64"`
+	Content  string `validate:"required_without=BlobID,omitempty,base64" json:",omitempty"`
+	BlobID   string `validate:"omitempty,hexadecimal,len=24"              json:",omitempty"` // BlobID is the ID of the uploaded blob.
+	Size     string `validate:"omitempty,number"                         json:",omitempty"`  // Size is the size of the blob in bytes.
 }
 
 // BinaryRecord represents a record that holds binary data.
 // It contains a username, binary data, and metadata.
 type BinaryRecord struct {
-	RecordID ObjectID   `json:"record_id,omitempty"` // Unique ID of a document in the DB.
-	Username string     `json:",omitempty"`          // Username represents the username of the record owner.
-	Data     BinaryInfo // Data is the binary data with filename and its content in base64 for the record.
-	Metadata Metadata   // Metadata is a map that can hold additional metadata for the record.
+	RecordID  ObjectID   `json:"record_id,omitempty"` // Unique ID of a document in the DB.
+	Username  string     `json:",omitempty"`          // Username represents the username of the record owner.
+	Data      BinaryInfo // Data is the binary data with filename and its content in base64 for the record.
+	Metadata  Metadata   // Metadata is a map that can hold additional metadata for the record.
+	Version   int64      `json:"version,omitempty"`    // Version is the version of the record on the server.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // UpdatedAt is the time of the last change of the record.
 }
 
 // CredentialInfo represents a user's login credentials.
@@ -57,10 +72,12 @@ type CredentialInfo struct {
 // CredentialRecord represents a record that holds user credentials.
 // It contains a username, credential data, and metadata.
 type CredentialRecord struct {
-	RecordID ObjectID       `json:"record_id,omitempty"` // Unique ID of a document in the DB.
-	Username string         `json:",omitempty"`          // Username represents the username of the credential owner.
-	Data     CredentialInfo // Data is the credential data.
-	Metadata Metadata       // Metadata is a map that can hold additional metadata for the record.
+	RecordID  ObjectID       `json:"record_id,omitempty"` // Unique ID of a document in the DB.
+	Username  string         `json:",omitempty"`          // Username represents the username of the credential owner.
+	Data      CredentialInfo // Data is the credential data.
+	Metadata  Metadata       // Metadata is a map that can hold additional metadata for the record.
+	Version   int64          `json:"version,omitempty"`    // Version is the version of the record on the server.
+	UpdatedAt *time.Time     `json:"updated_at,omitempty"` // UpdatedAt is the time of the last change of the record.
 }
 
 // CardInfo represents information about a credit card.
@@ -74,10 +91,37 @@ type CardInfo struct {
 // CardRecord represents a record that holds credit card information.
 // It contains a username, card information, and metadata.
 type CardRecord struct {
-	RecordID ObjectID `json:"record_id,omitempty"` // Unique ID of a document in the DB.
-	Username string   `json:",omitempty"`          // Username represents the username of the card owner.
-	Data     CardInfo // Data is the card information.
-	Metadata Metadata // Metadata is a map that can hold additional metadata for the record.
+	RecordID  ObjectID   `json:"record_id,omitempty"` // Unique ID of a document in the DB.
+	Username  string     `json:",omitempty"`          // Username represents the username of the card owner.
+	Data      CardInfo   // Data is the card information.
+	Metadata  Metadata   // Metadata is a map that can hold additional metadata for the record.
+	Version   int64      `json:"version,omitempty"`    // Version is the version of the record on the server.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // UpdatedAt is the time of the last change of the record.
+}
+
+// OTPInfo represents a one-time password generator (TOTP or HOTP).
+// Numeric parameters are kept as strings since the storage encrypts
+// string values only.
+type OTPInfo struct {
+	Type      string `validate:"omitempty,oneof=totp hotp"` // Type is a generator type: totp (default) or hotp.
+	Secret    string `validate:"required,otp_secret"`       // Secret is a base32 encoded shared secret.
+	Issuer    string // Issuer is a name of the service the key belongs to.
+	Account   string // Account is a name of the account the key belongs to.
+	Algorithm string `validate:"omitempty,oneof=SHA1 SHA256 SHA512"` // Algorithm is a hash function used in HMAC.
+	Digits    string `validate:"omitempty,oneof=6 7 8"`              // Digits is a length of the generated code.
+	Period    string `validate:"omitempty,number"`                   // Period is a TOTP time step in seconds.
+	Counter   string `validate:"omitempty,number"`                   // Counter is a HOTP moving factor.
+}
+
+// OTPRecord represents a record that holds a one-time password generator.
+// It contains a username, generator parameters, and metadata.
+type OTPRecord struct {
+	RecordID  ObjectID   `json:"record_id,omitempty"` // Unique ID of a document in the DB.
+	Username  string     `json:",omitempty"`          // Username represents the username of the record owner.
+	Data      OTPInfo    // Data is the one-time password generator parameters.
+	Metadata  Metadata   // Metadata is a map that can hold additional metadata for the record.
+	Version   int64      `json:"version,omitempty"`    // Version is the version of the record on the server.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // UpdatedAt is the time of the last change of the record.
 }
 
 // ObjectID represents entity id.
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Revision is a previous state of a record kept in the history of its collection.
+type Revision struct {
+	UntypedRecordContent `bson:",inline"`
+	RecordID             ObjectID   `bson:"record_id"            json:"record_id"`            // RecordID is the ID of the record.
+	Username             string     `bson:"username"             json:"-"`                    // Username represents the username of the record owner.
+	Rev                  int64      `bson:"rev"                  json:"rev"`                  // Rev is the version of the record the revision holds.
+	Op                   ChangeOp   `bson:"op"                   json:"op"`                   // Op is the change which replaced the revision: update or delete.
+	UpdatedAt            *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"` // UpdatedAt is the time the revision was made.
+	ArchivedAt           time.Time  `bson:"archived_at"          json:"archived_at"`          // ArchivedAt is the time the revision was replaced.
+}
@@ -0,0 +1,10 @@
+package models
+
+// MaxSearchResults is the maximum number of the records found by a search.
+const MaxSearchResults = 100
+
+// SearchResult is a record found by a search.
+type SearchResult struct {
+	Collection CollectionName `json:"collection"` // Collection is the name of the record's collection.
+	Record     UntypedRecord  `json:"record"`     // Record is the found record.
+}
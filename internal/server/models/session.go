@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Session represents a login of the user on a device. The session lasts
+// until its refresh token expires or the session is revoked.
+type Session struct {
+	ID                ObjectID  `json:"session_id"    bson:"_id"`
+	Username          string    `json:"-"             bson:"username"`
+	DeviceID          string    `json:"device_id,omitempty" bson:"device_id,omitempty"` // DeviceID is the id of the device which started the session.
+	RefreshTokenHash  string    `json:"-"             bson:"refresh_token_hash"`
+	AccessTokenID     string    `json:"-"             bson:"access_token_id"`         // AccessTokenID is the jti of the last access token of the session.
+	AccessTokenExpiry time.Time `json:"-"             bson:"access_token_expires_at"` // AccessTokenExpiry is the expiration time of the last access token.
+	CreatedAt         time.Time `json:"created_at"    bson:"created_at"`
+	LastUsedAt        time.Time `json:"last_used_at"  bson:"last_used_at"` // LastUsedAt is the time of the last refresh.
+	ExpiresAt         time.Time `json:"expires_at"    bson:"expires_at"`
+	Current           bool      `json:"current"       bson:"-"` // Current is set for the session of the token of the request.
+}
+
+// TokenPair is returned after the login and the refresh of the tokens.
+// If the user has the second factor enabled, the login returns only MFAToken
+// with MFARequired set; the token is exchanged for the tokens of the session
+// together with the one-time code.
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"` // ExpiresAt is the expiration time of the access token or of MFAToken.
+	SessionID    string    `json:"session_id"`
+	DeviceID     string    `json:"device_id,omitempty"` // DeviceID is the id of the device registered by the login.
+	MFARequired  bool      `json:"mfa_required,omitempty"`
+	MFAToken     string    `json:"mfa_token,omitempty"` // MFAToken is the short-lived challenge token of the login.
+}
+
+// RefreshRequest is the request body for refreshing the tokens. The session
+// started on a device is refreshed only with the proof of the key of the device.
+type RefreshRequest struct {
+	RefreshToken string       `json:"refresh_token"   binding:"required"`
+	Proof        *DeviceProof `json:"proof,omitempty"`
+}
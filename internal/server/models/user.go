@@ -5,8 +5,28 @@ type UserCredentials struct {
 	Password string `json:"password" bson:"password" binding:"required"`
 }
 
+// LoginRequest is the request body of the login. The client registers the device
+// it runs on with the login; the older clients may log in without the device.
+type LoginRequest struct {
+	UserCredentials
+	Device *DeviceInfo `json:"device,omitempty"`
+}
+
 // User represents a user in the system. It contains a username, password, and hashed password.
 type User struct {
 	UserCredentials
-	HashedPassword string `json:"hashedPassword" bson:"hashedPassword"`
+	HashedPassword string       `json:"hashedPassword" bson:"hashedPassword"`
+	Vault          *VaultParams `json:"-"              bson:"vault,omitempty"` // Vault is set when the user enables the end-to-end encryption.
+	MFA            *MFA         `json:"-"              bson:"mfa,omitempty"`   // MFA is set when the user enrolls the second factor.
+}
+
+// PasswordChangeRequest is the request body which replaces the password of the user.
+type PasswordChangeRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// AccountDeleteRequest is the request body which confirms the deletion of the account.
+type AccountDeleteRequest struct {
+	Password string `json:"password" binding:"required"`
 }
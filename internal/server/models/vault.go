@@ -0,0 +1,17 @@
+package models
+
+// KDFArgon2id is the only supported key derivation function.
+const KDFArgon2id = "argon2id"
+
+// VaultParams holds the parameters the clients of the user need to derive the
+// key of the end-to-end encryption from the master password. The server never
+// sees the password or the key. KeyCheck is a known value encrypted with the key,
+// so a client can detect a wrong master password before reading any record.
+type VaultParams struct {
+	KDF      string `json:"kdf"       bson:"kdf"       binding:"required,oneof=argon2id"`
+	Salt     []byte `json:"salt"      bson:"salt"      binding:"required,min=16"`
+	Time     uint32 `json:"time"      bson:"time"      binding:"required"`
+	Memory   uint32 `json:"memory"    bson:"memory"    binding:"required"`
+	Threads  uint8  `json:"threads"   bson:"threads"   binding:"required"`
+	KeyCheck []byte `json:"key_check" bson:"key_check" binding:"required"`
+}
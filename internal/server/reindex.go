@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/config"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
+)
+
+// RunReindex replaces the search tokens of all the records. It is needed for
+// the records saved before the search was introduced and after the search
+// index key is changed. The records can be reindexed while the server is running.
+func RunReindex(cfg *config.ServerConfig, batchSize int) error {
+	keyring, err := cfg.Keyring()
+	if err != nil {
+		return err
+	}
+	index, err := cfg.BlindIndex()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	storageService := service.NewStorageService(client.Database(cfg.DBName), keyring, index)
+	for _, collectionName := range models.AllowedCollectionNames {
+		n, err := storageService.Reindex(ctx, collectionName, batchSize)
+		if err != nil {
+			return err
+		}
+		log.Printf("%v: %d records reindexed", collectionName, n)
+	}
+	log.Println("All the records are reindexed")
+	return nil
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/config"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
+)
+
+// RunKeyRotation re-encrypts all the records, the chunks of the blobs and the
+// MFA secrets of the users with the active encryption key.
+// The old keys must be listed in the config until the rotation is finished.
+// An interrupted rotation continues from the last finished batch when it is run again.
+func RunKeyRotation(cfg *config.ServerConfig, batchSize int) error {
+	keyring, err := cfg.Keyring()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	rotationService := service.NewRotationService(client.Database(cfg.DBName), keyring)
+	log.Printf("Re-encrypting the records with the key %q", keyring.ActiveID())
+	err = rotationService.Rotate(ctx, batchSize, func(p service.RotationProgress) {
+		log.Printf(
+			"%v: %d/%d records checked, %d re-encrypted",
+			p.Collection,
+			p.Processed,
+			p.Total,
+			p.Rotated,
+		)
+	})
+	if err != nil {
+		return err
+	}
+	log.Println("All the records are encrypted with the active key")
+	return nil
+}
+
+// RunLegacyMigration marks the records encrypted in the legacy unauthenticated
+// format, so the server reads them until they are re-encrypted by the key
+// rotation. It must be run once before the server is started after an upgrade:
+// the records which aren't marked are never read in the legacy format, so the
+// server doesn't start until the migration is finished.
+func RunLegacyMigration(cfg *config.ServerConfig, batchSize int) error {
+	keyring, err := cfg.Keyring()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	rotationService := service.NewRotationService(client.Database(cfg.DBName), keyring)
+	log.Println("Marking the records encrypted in the legacy format")
+	err = rotationService.MarkLegacy(ctx, batchSize, func(p service.RotationProgress) {
+		log.Printf(
+			"%v: %d/%d records checked, %d marked",
+			p.Collection,
+			p.Processed,
+			p.Total,
+			p.Rotated,
+		)
+	})
+	if err != nil {
+		return err
+	}
+	log.Println("All the legacy records are marked, run rotate-key to re-encrypt them")
+	return nil
+}
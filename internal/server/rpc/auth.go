@@ -0,0 +1,396 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+)
+
+// authServer implements the Auth gRPC service.
+type authServer struct {
+	pb.UnimplementedAuthServer
+	service  service.AuthService
+	sessions service.SessionService
+	mfa      service.MFAService
+	devices  service.DeviceService
+}
+
+// NewAuthServer creates a new instance of the Auth gRPC service.
+func NewAuthServer(
+	service service.AuthService,
+	sessions service.SessionService,
+	mfa service.MFAService,
+	devices service.DeviceService,
+) pb.AuthServer {
+	return &authServer{service: service, sessions: sessions, mfa: mfa, devices: devices}
+}
+
+// Register creates a new user.
+func (s *authServer) Register(
+	ctx context.Context,
+	in *pb.Credentials,
+) (*pb.RegisterResponse, error) {
+	if in.GetUsername() == "" || in.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "username and password are required")
+	}
+	if err := s.service.Register(in.GetUsername(), in.GetPassword()); err != nil {
+		if errors.Is(err, srvErrors.ErrUsernameIsTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.RegisterResponse{Message: "success"}, nil
+}
+
+// peerAddress returns the host of the client of the call.
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// lockedError converts LockedError into ResourceExhausted and sends the time
+// left until the lockout ends in the retry-after header. Returns nil for the other errors.
+func lockedError(ctx context.Context, err error) error {
+	var locked *srvErrors.LockedError
+	if !errors.As(err, &locked) {
+		return nil
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(
+		"retry-after",
+		strconv.FormatInt(locked.RetryAfterSeconds(), 10),
+	))
+	return status.Error(codes.ResourceExhausted, locked.Error())
+}
+
+// Login checks user's credentials and starts a new session on the device.
+func (s *authServer) Login(ctx context.Context, in *pb.Credentials) (*pb.LoginResponse, error) {
+	tokens, err := s.service.Login(
+		in.GetUsername(),
+		in.GetPassword(),
+		peerAddress(ctx),
+		in.GetDevice().ModelDeviceInfo(),
+	)
+	if err := lockedError(ctx, err); err != nil {
+		return nil, err
+	}
+	if errors.Is(err, srvErrors.ErrBadDevice) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	} else if errors.Is(err, srvErrors.ErrBadCredentials) ||
+		errors.Is(err, srvErrors.ErrBadDeviceProof) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return pb.NewLoginResponse(*tokens), nil
+}
+
+// LoginMFA exchanges the challenge token of the login and the code for the tokens of a new session.
+func (s *authServer) LoginMFA(
+	ctx context.Context,
+	in *pb.LoginMFARequest,
+) (*pb.LoginResponse, error) {
+	if in.GetMfaToken() == "" || in.GetCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "mfa token and code are required")
+	}
+	tokens, err := s.service.LoginMFA(
+		in.GetMfaToken(),
+		in.GetCode(),
+		peerAddress(ctx),
+		in.GetDevice().ModelDeviceInfo(),
+	)
+	if err := lockedError(ctx, err); err != nil {
+		return nil, err
+	}
+	if errors.Is(err, srvErrors.ErrBadDevice) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	} else if errors.Is(err, srvErrors.ErrBadMFAToken) ||
+		errors.Is(err, srvErrors.ErrBadMFACode) ||
+		errors.Is(err, srvErrors.ErrBadDeviceProof) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return pb.NewLoginResponse(*tokens), nil
+}
+
+// Refresh exchanges the refresh token for the new tokens of the session.
+func (s *authServer) Refresh(
+	ctx context.Context,
+	in *pb.RefreshRequest,
+) (*pb.LoginResponse, error) {
+	if in.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+	tokens, err := s.sessions.Refresh(
+		ctx,
+		in.GetRefreshToken(),
+		in.GetProof().ModelDeviceProof(),
+	)
+	if errors.Is(err, srvErrors.ErrBadRefreshToken) ||
+		errors.Is(err, srvErrors.ErrBadDeviceProof) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return pb.NewLoginResponse(*tokens), nil
+}
+
+// DeviceNonce issues the nonce which the client signs with the key of its device.
+func (s *authServer) DeviceNonce(
+	_ context.Context,
+	_ *pb.DeviceNonceRequest,
+) (*pb.DeviceNonceResponse, error) {
+	nonce, err := s.devices.Nonce()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.DeviceNonceResponse{Nonce: nonce.Nonce, ExpiresAt: nonce.ExpiresAt.Unix()}, nil
+}
+
+// claimsFromContext returns the claims of the token set by the JWT interceptor.
+func claimsFromContext(ctx context.Context) (*auth.Claims, error) {
+	claims := middleware.ClaimsFromContext(ctx)
+	if claims == nil {
+		return nil, status.Error(codes.Unauthenticated, srvErrors.ErrNoUsernameProvided.Error())
+	}
+	return claims, nil
+}
+
+// Logout revokes the access token and finishes its session.
+func (s *authServer) Logout(ctx context.Context, _ *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessions.Logout(ctx, claims); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.LogoutResponse{Message: "Logged out"}, nil
+}
+
+// ListSessions returns the active sessions of the user.
+func (s *authServer) ListSessions(
+	ctx context.Context,
+	_ *pb.ListSessionsRequest,
+) (*pb.ListSessionsResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessions.List(ctx, claims.Username)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &pb.ListSessionsResponse{Sessions: make([]*pb.Session, 0, len(sessions))}
+	for _, session := range sessions {
+		session.Current = session.ID.Hex() == claims.SessionID
+		resp.Sessions = append(resp.Sessions, pb.NewSession(session))
+	}
+	return resp, nil
+}
+
+// RevokeSession finishes a session of the user.
+func (s *authServer) RevokeSession(
+	ctx context.Context,
+	in *pb.RevokeSessionRequest,
+) (*pb.RevokeSessionResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := models.ObjectIDFromString(in.GetSessionId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err = s.sessions.Revoke(ctx, username, sessionID)
+	if errors.Is(err, srvErrors.ErrSessionNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.RevokeSessionResponse{Message: "Session revoked"}, nil
+}
+
+// mfaError converts the error of the MFA service into the gRPC status.
+func mfaError(ctx context.Context, err error) error {
+	if err := lockedError(ctx, err); err != nil {
+		return err
+	}
+	switch {
+	case errors.Is(err, srvErrors.ErrBadMFACode), errors.Is(err, srvErrors.ErrBadCredentials):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, srvErrors.ErrMFAEnabled),
+		errors.Is(err, srvErrors.ErrMFANotEnrolled),
+		errors.Is(err, srvErrors.ErrMFADisabled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// EnrollMFA checks the password and generates a new secret of the second factor of the user.
+func (s *authServer) EnrollMFA(
+	ctx context.Context,
+	in *pb.EnrollMFARequest,
+) (*pb.MFAEnrollment, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	enrollment, err := s.mfa.Enroll(ctx, username, in.GetPassword(), peerAddress(ctx))
+	if err != nil {
+		return nil, mfaError(ctx, err)
+	}
+	return &pb.MFAEnrollment{Secret: enrollment.Secret, Uri: enrollment.URI}, nil
+}
+
+// ConfirmMFA enables the second factor of the user and returns the recovery codes.
+func (s *authServer) ConfirmMFA(
+	ctx context.Context,
+	in *pb.MFACodeRequest,
+) (*pb.MFARecoveryCodes, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.mfa.Confirm(ctx, username, in.GetCode())
+	if err != nil {
+		return nil, mfaError(ctx, err)
+	}
+	return &pb.MFARecoveryCodes{RecoveryCodes: codes.RecoveryCodes}, nil
+}
+
+// DisableMFA checks the password and turns off the second factor of the user.
+func (s *authServer) DisableMFA(
+	ctx context.Context,
+	in *pb.DisableMFARequest,
+) (*pb.DisableMFAResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = s.mfa.Disable(ctx, username, in.GetPassword(), in.GetCode(), peerAddress(ctx))
+	if err != nil {
+		return nil, mfaError(ctx, err)
+	}
+	return &pb.DisableMFAResponse{Message: "Second factor disabled"}, nil
+}
+
+// passwordError converts the error of the password check into the gRPC status.
+func passwordError(ctx context.Context, err error) error {
+	if err := lockedError(ctx, err); err != nil {
+		return err
+	}
+	if errors.Is(err, srvErrors.ErrBadCredentials) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// ChangePassword replaces the password of the user, finishes all the sessions
+// of the user and returns the tokens of a new session on the current device.
+func (s *authServer) ChangePassword(
+	ctx context.Context,
+	in *pb.ChangePasswordRequest,
+) (*pb.LoginResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if in.GetOldPassword() == "" || in.GetNewPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "old and new passwords are required")
+	}
+	tokens, err := s.service.ChangePassword(
+		claims.Username,
+		in.GetOldPassword(),
+		in.GetNewPassword(),
+		peerAddress(ctx),
+		claims.DeviceID,
+	)
+	if err != nil {
+		return nil, passwordError(ctx, err)
+	}
+	return pb.NewLoginResponse(*tokens), nil
+}
+
+// DeleteAccount deletes the user with all the data.
+func (s *authServer) DeleteAccount(
+	ctx context.Context,
+	in *pb.DeleteAccountRequest,
+) (*pb.DeleteAccountResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if in.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "password is required")
+	}
+	if err := s.service.DeleteAccount(username, in.GetPassword(), peerAddress(ctx)); err != nil {
+		return nil, passwordError(ctx, err)
+	}
+	return &pb.DeleteAccountResponse{Message: "Account deleted"}, nil
+}
+
+// ListDevices returns the devices of the user.
+func (s *authServer) ListDevices(
+	ctx context.Context,
+	_ *pb.ListDevicesRequest,
+) (*pb.ListDevicesResponse, error) {
+	claims, err := claimsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	devices, err := s.devices.List(ctx, claims.Username)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &pb.ListDevicesResponse{Devices: make([]*pb.Device, 0, len(devices))}
+	for _, device := range devices {
+		device.Current = device.ID.Hex() == claims.DeviceID
+		resp.Devices = append(resp.Devices, pb.NewDevice(device))
+	}
+	return resp, nil
+}
+
+// DeleteDevice removes a device of the user and signs it out.
+func (s *authServer) DeleteDevice(
+	ctx context.Context,
+	in *pb.DeleteDeviceRequest,
+) (*pb.DeleteDeviceResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deviceID, err := models.ObjectIDFromString(in.GetDeviceId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err = s.devices.Delete(ctx, username, deviceID)
+	if errors.Is(err, srvErrors.ErrDeviceNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.DeleteDeviceResponse{Message: "Device deleted"}, nil
+}
@@ -0,0 +1,127 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+)
+
+// blobServer implements the Blobs gRPC service.
+type blobServer struct {
+	pb.UnimplementedBlobsServer
+	service service.BlobService
+}
+
+// NewBlobServer creates a new instance of the Blobs gRPC service.
+func NewBlobServer(service service.BlobService) pb.BlobsServer {
+	return &blobServer{service: service}
+}
+
+// blobError converts an error of the blob service into a gRPC status.
+func blobError(err error) error {
+	switch {
+	case errors.Is(err, srvErrors.ErrBlobNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, srvErrors.ErrBlobIncomplete):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, srvErrors.ErrBadChunkSize):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// Create starts an upload of a blob.
+func (s *blobServer) Create(ctx context.Context, in *pb.CreateBlobRequest) (*pb.Blob, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if in.GetSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "size must not be negative")
+	}
+	blob, err := s.service.Create(ctx, username, in.GetSize())
+	if err != nil {
+		return nil, blobError(err)
+	}
+	return pb.NewBlob(*blob), nil
+}
+
+// Get returns the state of the upload of the blob.
+func (s *blobServer) Get(ctx context.Context, in *pb.GetBlobRequest) (*pb.Blob, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := models.ObjectIDFromString(in.GetBlobId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	blob, err := s.service.Get(ctx, username, id)
+	if err != nil {
+		return nil, blobError(err)
+	}
+	return pb.NewBlob(*blob), nil
+}
+
+// Append appends a chunk to the content of the blob. If the chunk doesn't start
+// where the uploaded content ends, the FailedPrecondition status is returned
+// with the current state of the blob in its details.
+func (s *blobServer) Append(ctx context.Context, in *pb.AppendChunkRequest) (*pb.Blob, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := models.ObjectIDFromString(in.GetBlobId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	blob, err := s.service.Append(ctx, username, id, in.GetOffset(), in.GetData())
+	if errors.Is(err, srvErrors.ErrBadChunkOffset) {
+		st, detailsErr := status.New(codes.FailedPrecondition, err.Error()).
+			WithDetails(pb.NewBlob(*blob))
+		if detailsErr != nil {
+			return nil, status.Error(codes.Internal, detailsErr.Error())
+		}
+		return nil, st.Err()
+	} else if err != nil {
+		return nil, blobError(err)
+	}
+	return pb.NewBlob(*blob), nil
+}
+
+// chunkWriter sends every write to the stream as a chunk of the blob.
+type chunkWriter struct {
+	stream pb.Blobs_DownloadServer
+}
+
+// Write sends the data as a chunk.
+func (w chunkWriter) Write(p []byte) (int, error) {
+	if err := w.stream.Send(&pb.BlobChunk{Data: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Download streams the content of the uploaded blob in chunks.
+func (s *blobServer) Download(in *pb.DownloadBlobRequest, stream pb.Blobs_DownloadServer) error {
+	username, err := usernameFromContext(stream.Context())
+	if err != nil {
+		return err
+	}
+	id, err := models.ObjectIDFromString(in.GetBlobId())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.service.Read(stream.Context(), username, id, chunkWriter{stream: stream}); err != nil {
+		return blobError(err)
+	}
+	return nil
+}
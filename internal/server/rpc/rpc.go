@@ -0,0 +1,59 @@
+// Package rpc provides the gRPC transport of the server. It exposes
+// the same operations as the REST API using the same services.
+package rpc
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	"github.com/blokhinnv/gophkeeper/internal/server/config"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+)
+
+// NewServer creates a gRPC server with the auth, storage, sync, vault and blob services registered.
+// All the methods except registration, login and refresh require an access token
+// which has not been revoked by the session service.
+func NewServer(
+	cfg *config.ServerConfig,
+	keys *auth.KeySet,
+	authService service.AuthService,
+	sessionService service.SessionService,
+	storageService service.StorageService,
+	syncService service.SyncService,
+	vaultService service.VaultService,
+	blobService service.BlobService,
+	mfaService service.MFAService,
+	deviceService service.DeviceService,
+) (*grpc.Server, error) {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(middleware.JWTAuthUnaryInterceptor(
+			keys,
+			sessionService,
+			pb.Auth_Register_FullMethodName,
+			pb.Auth_Login_FullMethodName,
+			pb.Auth_LoginMFA_FullMethodName,
+			pb.Auth_Refresh_FullMethodName,
+			pb.Auth_DeviceNonce_FullMethodName,
+		)),
+		grpc.ChainStreamInterceptor(
+			middleware.JWTAuthStreamInterceptor(keys, sessionService),
+		),
+	}
+	if cfg.UseHTTPS {
+		creds, err := credentials.NewServerTLSFromFile(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	s := grpc.NewServer(opts...)
+	pb.RegisterAuthServer(s, NewAuthServer(authService, sessionService, mfaService, deviceService))
+	pb.RegisterStorageServer(s, NewStorageServer(storageService, syncService))
+	pb.RegisterSyncServer(s, NewSyncServer(syncService, storageService, cfg.TrashTTL))
+	pb.RegisterVaultServer(s, NewVaultServer(vaultService))
+	pb.RegisterBlobsServer(s, NewBlobServer(blobService))
+	return s, nil
+}
@@ -0,0 +1,921 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	"github.com/blokhinnv/gophkeeper/internal/server/config"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+	"github.com/blokhinnv/gophkeeper/internal/server/service/mock"
+)
+
+const signingKey = "secret"
+
+// startServer runs the gRPC server on an in-memory connection and returns a client connection.
+// If the session service is nil, no token is revoked.
+func startServer(
+	t *testing.T,
+	authService service.AuthService,
+	sessionService service.SessionService,
+	storageService service.StorageService,
+	syncService service.SyncService,
+	vaultService service.VaultService,
+	blobService service.BlobService,
+	mfaService service.MFAService,
+	deviceService service.DeviceService,
+) *grpc.ClientConn {
+	if sessionService == nil {
+		sessions := mock.NewMockSessionService(gomock.NewController(t))
+		sessions.EXPECT().IsRevoked(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+		sessionService = sessions
+	}
+	srv, err := NewServer(
+		&config.ServerConfig{},
+		auth.NewHMACKeySet([]byte(signingKey)),
+		authService,
+		sessionService,
+		storageService,
+		syncService,
+		vaultService,
+		blobService,
+		mfaService,
+		deviceService,
+	)
+	require.NoError(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// authContext returns a context with the token of the user.
+func authContext(t *testing.T, username string) context.Context {
+	tok, err := auth.GenerateJWTToken(username, []byte(signingKey), time.Hour)
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer: "+tok)
+}
+
+func TestAuthServer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	authService := mock.NewMockAuthService(mockCtrl)
+	mfaService := mock.NewMockMFAService(mockCtrl)
+	conn := startServer(t, authService, nil, nil, nil, nil, nil, mfaService, nil)
+	client := pb.NewAuthClient(conn)
+	ctx := context.Background()
+
+	t.Run("register", func(t *testing.T) {
+		authService.EXPECT().Register("user", "pwd").Return(nil)
+		resp, err := client.Register(ctx, &pb.Credentials{Username: "user", Password: "pwd"})
+		require.NoError(t, err)
+		assert.Equal(t, "success", resp.Message)
+	})
+	t.Run("register_taken", func(t *testing.T) {
+		authService.EXPECT().Register("user", "pwd").Return(srvErrors.ErrUsernameIsTaken)
+		_, err := client.Register(ctx, &pb.Credentials{Username: "user", Password: "pwd"})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+	t.Run("register_empty", func(t *testing.T) {
+		_, err := client.Register(ctx, &pb.Credentials{Username: "user"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("login", func(t *testing.T) {
+		authService.EXPECT().
+			Login("user", "pwd", gomock.Any(), gomock.Nil()).
+			Return(&models.TokenPair{AccessToken: "token", RefreshToken: "refresh"}, nil)
+		resp, err := client.Login(ctx, &pb.Credentials{Username: "user", Password: "pwd"})
+		require.NoError(t, err)
+		assert.Equal(t, "token", resp.Token)
+		assert.Equal(t, "refresh", resp.RefreshToken)
+	})
+	t.Run("login_failed", func(t *testing.T) {
+		authService.EXPECT().
+			Login("user", "pwd", gomock.Any(), gomock.Nil()).
+			Return(nil, srvErrors.ErrBadCredentials)
+		_, err := client.Login(ctx, &pb.Credentials{Username: "user", Password: "pwd"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.NotContains(t, err.Error(), "pwd")
+	})
+	t.Run("login_server_error", func(t *testing.T) {
+		authService.EXPECT().
+			Login("user", "pwd", gomock.Any(), gomock.Nil()).
+			Return(nil, context.DeadlineExceeded)
+		_, err := client.Login(ctx, &pb.Credentials{Username: "user", Password: "pwd"})
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+	t.Run("login_locked", func(t *testing.T) {
+		authService.EXPECT().
+			Login("user", "pwd", gomock.Any(), gomock.Nil()).
+			Return(nil, &srvErrors.LockedError{RetryAfter: 90 * time.Second})
+		var header metadata.MD
+		_, err := client.Login(ctx, &pb.Credentials{Username: "user", Password: "pwd"}, grpc.Header(&header))
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, []string{"90"}, header.Get("retry-after"))
+	})
+	t.Run("login_device", func(t *testing.T) {
+		device := &pb.DeviceInfo{
+			Name:      "laptop",
+			Platform:  "linux/amd64",
+			PublicKey: "key",
+			Proof:     &pb.DeviceProof{Nonce: "nonce", Signature: "signature"},
+		}
+		authService.EXPECT().
+			Login("user", "pwd", gomock.Any(), device.ModelDeviceInfo()).
+			Return(&models.TokenPair{AccessToken: "token", DeviceID: "device"}, nil)
+		resp, err := client.Login(ctx, &pb.Credentials{Username: "user", Password: "pwd", Device: device})
+		require.NoError(t, err)
+		assert.Equal(t, "device", resp.DeviceId)
+
+		authService.EXPECT().
+			Login("user", "pwd", gomock.Any(), device.ModelDeviceInfo()).
+			Return(nil, srvErrors.ErrBadDevice)
+		_, err = client.Login(ctx, &pb.Credentials{Username: "user", Password: "pwd", Device: device})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		authService.EXPECT().
+			Login("user", "pwd", gomock.Any(), device.ModelDeviceInfo()).
+			Return(nil, srvErrors.ErrBadDeviceProof)
+		_, err = client.Login(ctx, &pb.Credentials{Username: "user", Password: "pwd", Device: device})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, srvErrors.ErrBadDeviceProof.Error(), status.Convert(err).Message())
+	})
+	t.Run("login_mfa", func(t *testing.T) {
+		authService.EXPECT().
+			Login("user", "pwd", gomock.Any(), gomock.Nil()).
+			Return(&models.TokenPair{MFARequired: true, MFAToken: "challenge"}, nil)
+		resp, err := client.Login(ctx, &pb.Credentials{Username: "user", Password: "pwd"})
+		require.NoError(t, err)
+		assert.True(t, resp.MfaRequired)
+		assert.Empty(t, resp.Token)
+
+		authService.EXPECT().
+			LoginMFA("challenge", "123456", gomock.Any(), gomock.Nil()).
+			Return(&models.TokenPair{AccessToken: "token", RefreshToken: "refresh"}, nil)
+		resp, err = client.LoginMFA(ctx, &pb.LoginMFARequest{MfaToken: resp.MfaToken, Code: "123456"})
+		require.NoError(t, err)
+		assert.Equal(t, "token", resp.Token)
+
+		authService.EXPECT().LoginMFA("challenge", "000000", gomock.Any(), gomock.Nil()).Return(nil, srvErrors.ErrBadMFACode)
+		_, err = client.LoginMFA(ctx, &pb.LoginMFARequest{MfaToken: "challenge", Code: "000000"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("mfa", func(t *testing.T) {
+		_, err := client.EnrollMFA(ctx, &pb.EnrollMFARequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		mfaService.EXPECT().
+			Enroll(gomock.Any(), "user", "password", gomock.Any()).
+			Return(&models.MFAEnrollment{Secret: "secret", URI: "otpauth://totp/user"}, nil)
+		enrollment, err := client.EnrollMFA(
+			authContext(t, "user"),
+			&pb.EnrollMFARequest{Password: "password"},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", enrollment.Secret)
+
+		mfaService.EXPECT().
+			Enroll(gomock.Any(), "user", "wrong", gomock.Any()).
+			Return(nil, srvErrors.ErrBadCredentials)
+		_, err = client.EnrollMFA(authContext(t, "user"), &pb.EnrollMFARequest{Password: "wrong"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, srvErrors.ErrBadCredentials.Error(), status.Convert(err).Message())
+
+		mfaService.EXPECT().
+			Confirm(gomock.Any(), "user", "123456").
+			Return(&models.MFARecoveryCodes{RecoveryCodes: []string{"abcd-efgh"}}, nil)
+		recovery, err := client.ConfirmMFA(authContext(t, "user"), &pb.MFACodeRequest{Code: "123456"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"abcd-efgh"}, recovery.RecoveryCodes)
+
+		mfaService.EXPECT().
+			Disable(gomock.Any(), "user", "password", "000000", gomock.Any()).
+			Return(srvErrors.ErrBadMFACode)
+		_, err = client.DisableMFA(
+			authContext(t, "user"),
+			&pb.DisableMFARequest{Password: "password", Code: "000000"},
+		)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		mfaService.EXPECT().
+			Disable(gomock.Any(), "user", "wrong", "123456", gomock.Any()).
+			Return(&srvErrors.LockedError{RetryAfter: 90 * time.Second})
+		var header metadata.MD
+		_, err = client.DisableMFA(
+			authContext(t, "user"),
+			&pb.DisableMFARequest{Password: "wrong", Code: "123456"},
+			grpc.Header(&header),
+		)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, []string{"90"}, header.Get("retry-after"))
+	})
+	t.Run("change_password", func(t *testing.T) {
+		_, err := client.ChangePassword(ctx, &pb.ChangePasswordRequest{OldPassword: "old", NewPassword: "new"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		authService.EXPECT().
+			ChangePassword("user", "old", "new", gomock.Any(), "").
+			Return(&models.TokenPair{AccessToken: "token", RefreshToken: "refresh"}, nil)
+		resp, err := client.ChangePassword(
+			authContext(t, "user"),
+			&pb.ChangePasswordRequest{OldPassword: "old", NewPassword: "new"},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "token", resp.Token)
+
+		authService.EXPECT().
+			ChangePassword("user", "wrong", "new", gomock.Any(), "").
+			Return(nil, srvErrors.ErrBadCredentials)
+		_, err = client.ChangePassword(
+			authContext(t, "user"),
+			&pb.ChangePasswordRequest{OldPassword: "wrong", NewPassword: "new"},
+		)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = client.ChangePassword(authContext(t, "user"), &pb.ChangePasswordRequest{OldPassword: "old"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("delete_account", func(t *testing.T) {
+		authService.EXPECT().DeleteAccount("user", "pwd", gomock.Any()).Return(nil)
+		resp, err := client.DeleteAccount(authContext(t, "user"), &pb.DeleteAccountRequest{Password: "pwd"})
+		require.NoError(t, err)
+		assert.Equal(t, "Account deleted", resp.Message)
+
+		authService.EXPECT().
+			DeleteAccount("user", "pwd", gomock.Any()).
+			Return(&srvErrors.LockedError{RetryAfter: time.Minute})
+		_, err = client.DeleteAccount(authContext(t, "user"), &pb.DeleteAccountRequest{Password: "pwd"})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+}
+
+func TestAuthServer_Sessions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	sessionService := mock.NewMockSessionService(mockCtrl)
+	conn := startServer(t, nil, sessionService, nil, nil, nil, nil, nil, nil)
+	client := pb.NewAuthClient(conn)
+	current, other := models.NewRandomObjectID(), models.NewRandomObjectID()
+	tok, claims, err := auth.NewAccessToken("user", current.Hex(), []byte(signingKey), time.Hour)
+	require.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(
+		context.Background(),
+		"authorization",
+		"Bearer: "+tok,
+	)
+
+	t.Run("refresh", func(t *testing.T) {
+		sessionService.EXPECT().
+			Refresh(gomock.Any(), "refresh", gomock.Nil()).
+			Return(&models.TokenPair{AccessToken: "token", RefreshToken: "new-refresh"}, nil)
+		resp, err := client.Refresh(context.Background(), &pb.RefreshRequest{RefreshToken: "refresh"})
+		require.NoError(t, err)
+		assert.Equal(t, "new-refresh", resp.RefreshToken)
+	})
+	t.Run("refresh_bad_token", func(t *testing.T) {
+		sessionService.EXPECT().
+			Refresh(gomock.Any(), "refresh", gomock.Nil()).
+			Return(nil, srvErrors.ErrBadRefreshToken)
+		_, err := client.Refresh(context.Background(), &pb.RefreshRequest{RefreshToken: "refresh"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("refresh_device", func(t *testing.T) {
+		proof := &models.DeviceProof{Nonce: "nonce", Signature: "signature"}
+		sessionService.EXPECT().
+			Refresh(gomock.Any(), "refresh", proof).
+			Return(&models.TokenPair{AccessToken: "token", DeviceID: "device"}, nil)
+		resp, err := client.Refresh(
+			context.Background(),
+			&pb.RefreshRequest{RefreshToken: "refresh", Proof: pb.NewDeviceProof(proof)},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "device", resp.DeviceId)
+
+		sessionService.EXPECT().
+			Refresh(gomock.Any(), "refresh", gomock.Nil()).
+			Return(nil, srvErrors.ErrBadDeviceProof)
+		_, err = client.Refresh(context.Background(), &pb.RefreshRequest{RefreshToken: "refresh"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("revoked_token", func(t *testing.T) {
+		sessionService.EXPECT().IsRevoked(gomock.Any(), claims.ID).Return(true, nil)
+		_, err := client.ListSessions(ctx, &pb.ListSessionsRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("list", func(t *testing.T) {
+		sessionService.EXPECT().IsRevoked(gomock.Any(), claims.ID).Return(false, nil)
+		sessionService.EXPECT().
+			List(gomock.Any(), "user").
+			Return([]models.Session{{ID: current}, {ID: other}}, nil)
+		resp, err := client.ListSessions(ctx, &pb.ListSessionsRequest{})
+		require.NoError(t, err)
+		require.Len(t, resp.Sessions, 2)
+		assert.True(t, resp.Sessions[0].Current)
+		assert.False(t, resp.Sessions[1].Current)
+	})
+	t.Run("revoke", func(t *testing.T) {
+		sessionService.EXPECT().IsRevoked(gomock.Any(), claims.ID).Return(false, nil)
+		sessionService.EXPECT().Revoke(gomock.Any(), "user", other).Return(nil)
+		_, err := client.RevokeSession(ctx, &pb.RevokeSessionRequest{SessionId: other.Hex()})
+		require.NoError(t, err)
+	})
+	t.Run("revoke_not_found", func(t *testing.T) {
+		sessionService.EXPECT().IsRevoked(gomock.Any(), claims.ID).Return(false, nil)
+		sessionService.EXPECT().
+			Revoke(gomock.Any(), "user", other).
+			Return(srvErrors.ErrSessionNotFound)
+		_, err := client.RevokeSession(ctx, &pb.RevokeSessionRequest{SessionId: other.Hex()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("logout", func(t *testing.T) {
+		sessionService.EXPECT().IsRevoked(gomock.Any(), claims.ID).Return(false, nil)
+		sessionService.EXPECT().
+			Logout(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, c *auth.Claims) error {
+				assert.Equal(t, claims.ID, c.ID)
+				return nil
+			})
+		_, err := client.Logout(ctx, &pb.LogoutRequest{})
+		require.NoError(t, err)
+	})
+}
+
+func TestAuthServer_Devices(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	deviceService := mock.NewMockDeviceService(mockCtrl)
+	conn := startServer(t, nil, nil, nil, nil, nil, nil, nil, deviceService)
+	client := pb.NewAuthClient(conn)
+	current, other := models.NewRandomObjectID(), models.NewRandomObjectID()
+	tok, _, err := auth.NewHMACKeySet([]byte(signingKey)).
+		NewAccessToken("user", models.NewRandomObjectID().Hex(), current.Hex(), time.Hour)
+	require.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer: "+tok)
+
+	t.Run("nonce", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Minute)
+		deviceService.EXPECT().
+			Nonce().
+			Return(&models.DeviceNonce{Nonce: "nonce", ExpiresAt: expiresAt}, nil)
+		// the nonce is issued before the login, so no token is required
+		resp, err := client.DeviceNonce(context.Background(), &pb.DeviceNonceRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "nonce", resp.Nonce)
+		assert.Equal(t, expiresAt.Unix(), resp.ExpiresAt)
+	})
+	t.Run("list", func(t *testing.T) {
+		deviceService.EXPECT().
+			List(gomock.Any(), "user").
+			Return([]models.Device{{ID: current}, {ID: other}}, nil)
+		resp, err := client.ListDevices(ctx, &pb.ListDevicesRequest{})
+		require.NoError(t, err)
+		require.Len(t, resp.Devices, 2)
+		assert.True(t, resp.Devices[0].Current)
+		assert.False(t, resp.Devices[1].Current)
+	})
+	t.Run("list_no_token", func(t *testing.T) {
+		_, err := client.ListDevices(context.Background(), &pb.ListDevicesRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("delete", func(t *testing.T) {
+		deviceService.EXPECT().Delete(gomock.Any(), "user", other).Return(nil)
+		resp, err := client.DeleteDevice(ctx, &pb.DeleteDeviceRequest{DeviceId: other.Hex()})
+		require.NoError(t, err)
+		assert.Equal(t, "Device deleted", resp.Message)
+	})
+	t.Run("delete_not_found", func(t *testing.T) {
+		deviceService.EXPECT().Delete(gomock.Any(), "user", other).Return(srvErrors.ErrDeviceNotFound)
+		_, err := client.DeleteDevice(ctx, &pb.DeleteDeviceRequest{DeviceId: other.Hex()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("delete_bad_id", func(t *testing.T) {
+		_, err := client.DeleteDevice(ctx, &pb.DeleteDeviceRequest{DeviceId: "bad"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestStorageServer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	storageService := mock.NewMockStorageService(mockCtrl)
+	syncService := mock.NewMockSyncService(mockCtrl)
+	syncService.EXPECT().Publish(gomock.Any(), gomock.Any()).AnyTimes()
+	conn := startServer(t, nil, nil, storageService, syncService, nil, nil, nil, nil)
+	client := pb.NewStorageClient(conn)
+	ctx := authContext(t, "user")
+	id := models.NewRandomObjectID()
+	credentials := &pb.Record{
+		Data: &pb.Record_Credential{
+			Credential: &pb.CredentialInfo{Login: "login", Password: "pwd"},
+		},
+		Metadata: map[string]string{"site": "example.com"},
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := client.GetAll(context.Background(), &pb.GetAllRequest{Collection: "text"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("store", func(t *testing.T) {
+		storageService.EXPECT().
+			Store(gomock.Any(), models.CredentialsCollection, models.UntypedRecord{
+				UntypedRecordContent: models.UntypedRecordContent{
+					Data:     map[string]any{"Login": "login", "Password": "pwd"},
+					Metadata: models.Metadata{"site": "example.com"},
+				},
+				Username: "user",
+			}).
+			Return(id.Hex(), nil)
+		resp, err := client.Store(ctx, &pb.StoreRequest{
+			Collection: "credentials",
+			Record:     credentials,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, id.Hex(), resp.RecordId)
+	})
+	t.Run("store_encrypted", func(t *testing.T) {
+		// the envelope is not validated as a card and the metadata is dropped
+		storageService.EXPECT().
+			Store(gomock.Any(), models.CardCollection, models.UntypedRecord{
+				UntypedRecordContent: models.UntypedRecordContent{
+					Data: models.NewEncryptedData([]byte("ciphertext")),
+				},
+				Username: "user",
+			}).
+			Return(id.Hex(), nil)
+		_, err := client.Store(ctx, &pb.StoreRequest{
+			Collection: "cards",
+			Record: &pb.Record{
+				Data:     &pb.Record_Encrypted{Encrypted: []byte("ciphertext")},
+				Metadata: map[string]string{"bank": "some bank"},
+			},
+		})
+		require.NoError(t, err)
+	})
+	t.Run("store_invalid", func(t *testing.T) {
+		_, err := client.Store(ctx, &pb.StoreRequest{
+			Collection: "cards",
+			Record: &pb.Record{
+				Data: &pb.Record_Card{Card: &pb.CardInfo{CardNumber: "123", Cvv: "1"}},
+			},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("store_mismatch", func(t *testing.T) {
+		_, err := client.Store(ctx, &pb.StoreRequest{Collection: "text", Record: credentials})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("store_bad_collection", func(t *testing.T) {
+		_, err := client.Store(ctx, &pb.StoreRequest{Collection: "unknown", Record: credentials})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("get_all", func(t *testing.T) {
+		storageService.EXPECT().
+			GetAll(gomock.Any(), models.TextCollection, "user", models.GetAllOptions{}).
+			Return([]models.UntypedRecord{{
+				UntypedRecordContent: models.UntypedRecordContent{Data: "some text"},
+				RecordID:             id,
+			}}, "", nil)
+		resp, err := client.GetAll(ctx, &pb.GetAllRequest{Collection: "text"})
+		require.NoError(t, err)
+		require.Len(t, resp.Records, 1)
+		assert.Equal(t, id.Hex(), resp.Records[0].RecordId)
+		assert.Equal(t, "some text", resp.Records[0].GetText())
+		assert.Empty(t, resp.NextCursor)
+	})
+	t.Run("get_all_page", func(t *testing.T) {
+		storageService.EXPECT().
+			GetAll(gomock.Any(), models.TextCollection, "user", models.GetAllOptions{
+				Limit:    1,
+				Cursor:   "cursor",
+				Sort:     "updated",
+				Metadata: models.Metadata{"site": "example.com"},
+			}).
+			Return([]models.UntypedRecord{}, "next", nil)
+		resp, err := client.GetAll(ctx, &pb.GetAllRequest{
+			Collection: "text",
+			Limit:      1,
+			Cursor:     "cursor",
+			Sort:       "updated",
+			Metadata:   map[string]string{"site": "example.com"},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, resp.Records)
+		assert.Equal(t, "next", resp.NextCursor)
+	})
+	t.Run("get_all_bad_options", func(t *testing.T) {
+		storageService.EXPECT().
+			GetAll(gomock.Any(), models.TextCollection, "user", models.GetAllOptions{Sort: "name"}).
+			Return(nil, "", srvErrors.ErrBadListOptions)
+		_, err := client.GetAll(ctx, &pb.GetAllRequest{Collection: "text", Sort: "name"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("get", func(t *testing.T) {
+		storageService.EXPECT().
+			Get(gomock.Any(), models.TextCollection, "user", id).
+			Return(&models.UntypedRecord{
+				UntypedRecordContent: models.UntypedRecordContent{Data: "some text"},
+				RecordID:             id,
+			}, nil)
+		resp, err := client.Get(ctx, &pb.GetRequest{Collection: "text", RecordId: id.Hex()})
+		require.NoError(t, err)
+		assert.Equal(t, id.Hex(), resp.Record.RecordId)
+		assert.Equal(t, "some text", resp.Record.GetText())
+	})
+	t.Run("get_encrypted", func(t *testing.T) {
+		storageService.EXPECT().
+			Get(gomock.Any(), models.TextCollection, "user", id).
+			Return(&models.UntypedRecord{
+				UntypedRecordContent: models.UntypedRecordContent{
+					Data: map[string]any{models.EncryptedField: "Y2lwaGVydGV4dA=="},
+				},
+				RecordID: id,
+			}, nil)
+		resp, err := client.Get(ctx, &pb.GetRequest{Collection: "text", RecordId: id.Hex()})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("ciphertext"), resp.Record.GetEncrypted())
+	})
+	t.Run("get_not_found", func(t *testing.T) {
+		storageService.EXPECT().
+			Get(gomock.Any(), models.TextCollection, "user", id).
+			Return(nil, srvErrors.ErrRecordNotFound)
+		_, err := client.Get(ctx, &pb.GetRequest{Collection: "text", RecordId: id.Hex()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("update_not_found", func(t *testing.T) {
+		storageService.EXPECT().
+			Update(
+				gomock.Any(),
+				models.CredentialsCollection,
+				"user",
+				id,
+				map[string]any{"Login": "login", "Password": "pwd"},
+				models.Metadata{"site": "example.com"},
+				int64(0),
+			).
+			Return(nil, srvErrors.ErrRecordNotFound)
+		record := &pb.Record{
+			RecordId: id.Hex(),
+			Data:     credentials.Data,
+			Metadata: credentials.Metadata,
+		}
+		_, err := client.Update(ctx, &pb.UpdateRequest{Collection: "credentials", Record: record})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("update_conflict", func(t *testing.T) {
+		storageService.EXPECT().
+			Update(gomock.Any(), models.TextCollection, "user", id, "mine", gomock.Any(), int64(1)).
+			Return(&models.UntypedRecord{
+				UntypedRecordContent: models.UntypedRecordContent{Data: "theirs"},
+				RecordID:             id,
+				Version:              2,
+			}, srvErrors.ErrVersionConflict)
+		_, err := client.Update(ctx, &pb.UpdateRequest{
+			Collection:      "text",
+			Record:          &pb.Record{RecordId: id.Hex(), Data: &pb.Record_Text{Text: "mine"}},
+			ExpectedVersion: 1,
+		})
+		st := status.Convert(err)
+		require.Equal(t, codes.Aborted, st.Code())
+		require.Len(t, st.Details(), 1)
+		current, ok := st.Details()[0].(*pb.Record)
+		require.True(t, ok)
+		assert.Equal(t, int64(2), current.Version)
+		assert.Equal(t, "theirs", current.GetText())
+	})
+	t.Run("delete", func(t *testing.T) {
+		storageService.EXPECT().
+			Delete(gomock.Any(), models.TextCollection, "user", id).
+			Return(nil)
+		resp, err := client.Delete(ctx, &pb.DeleteRequest{Collection: "text", RecordId: id.Hex()})
+		require.NoError(t, err)
+		assert.Contains(t, resp.Message, id.Hex())
+	})
+	t.Run("delete_bad_id", func(t *testing.T) {
+		_, err := client.Delete(ctx, &pb.DeleteRequest{Collection: "text", RecordId: "bad"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("trash", func(t *testing.T) {
+		deletedAt := time.Now().UTC().Truncate(time.Second)
+		storageService.EXPECT().
+			Trash(gomock.Any(), models.TextCollection, "user").
+			Return([]models.UntypedRecord{{
+				UntypedRecordContent: models.UntypedRecordContent{Data: "deleted text"},
+				RecordID:             id,
+				DeletedAt:            &deletedAt,
+			}}, nil)
+		resp, err := client.Trash(ctx, &pb.TrashRequest{Collection: "text"})
+		require.NoError(t, err)
+		require.Len(t, resp.Records, 1)
+		assert.Equal(t, "deleted text", resp.Records[0].GetText())
+		assert.Equal(t, deletedAt.Unix(), resp.Records[0].DeletedAt)
+	})
+	t.Run("search", func(t *testing.T) {
+		storageService.EXPECT().
+			Search(gomock.Any(), "user", "john").
+			Return([]models.SearchResult{{
+				Collection: models.CredentialsCollection,
+				Record: models.UntypedRecord{
+					UntypedRecordContent: models.UntypedRecordContent{
+						Data: map[string]any{"Login": "john", "Password": "secret"},
+					},
+					RecordID: id,
+				},
+			}}, nil)
+		resp, err := client.Search(ctx, &pb.SearchRequest{Query: "john"})
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, "credentials", resp.Results[0].Collection)
+		assert.Equal(t, "john", resp.Results[0].Record.GetCredential().GetLogin())
+	})
+	t.Run("search_empty_query", func(t *testing.T) {
+		storageService.EXPECT().
+			Search(gomock.Any(), "user", "").
+			Return(nil, srvErrors.ErrEmptySearchQuery)
+		_, err := client.Search(ctx, &pb.SearchRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("undelete", func(t *testing.T) {
+		storageService.EXPECT().
+			Undelete(gomock.Any(), models.TextCollection, "user", id).
+			Return(&models.UntypedRecord{RecordID: id, Version: 3}, nil)
+		resp, err := client.Undelete(ctx, &pb.UndeleteRequest{Collection: "text", RecordId: id.Hex()})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), resp.Version)
+	})
+	t.Run("purge_not_found", func(t *testing.T) {
+		storageService.EXPECT().
+			Purge(gomock.Any(), models.TextCollection, "user", id).
+			Return(srvErrors.ErrRecordNotFound)
+		_, err := client.Purge(ctx, &pb.PurgeRequest{Collection: "text", RecordId: id.Hex()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("history", func(t *testing.T) {
+		storageService.EXPECT().
+			History(gomock.Any(), models.TextCollection, "user", id).
+			Return([]models.Revision{{
+				UntypedRecordContent: models.UntypedRecordContent{Data: "old text"},
+				RecordID:             id,
+				Rev:                  1,
+				Op:                   models.OpUpdate,
+			}}, nil)
+		resp, err := client.History(ctx, &pb.HistoryRequest{Collection: "text", RecordId: id.Hex()})
+		require.NoError(t, err)
+		require.Len(t, resp.Revisions, 1)
+		assert.Equal(t, "old text", resp.Revisions[0].Record.GetText())
+		assert.Equal(t, int64(1), resp.Revisions[0].Record.Version)
+		assert.Equal(t, "update", resp.Revisions[0].Op)
+	})
+	t.Run("restore", func(t *testing.T) {
+		storageService.EXPECT().
+			Restore(gomock.Any(), models.TextCollection, "user", id, int64(1)).
+			Return(&models.UntypedRecord{RecordID: id, Version: 3}, nil)
+		resp, err := client.Restore(ctx, &pb.RestoreRequest{
+			Collection: "text",
+			RecordId:   id.Hex(),
+			Rev:        1,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), resp.Version)
+	})
+	t.Run("restore_not_found", func(t *testing.T) {
+		storageService.EXPECT().
+			Restore(gomock.Any(), models.TextCollection, "user", id, int64(7)).
+			Return(nil, srvErrors.ErrRevisionNotFound)
+		_, err := client.Restore(ctx, &pb.RestoreRequest{
+			Collection: "text",
+			RecordId:   id.Hex(),
+			Rev:        7,
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestSyncServer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	syncService := mock.NewMockSyncService(mockCtrl)
+	events := make(chan models.ChangeEvent, 1)
+	syncService.EXPECT().
+		Subscribe("user", "", "", gomock.Any()).
+		Return((<-chan models.ChangeEvent)(events), func() {})
+	conn := startServer(t, nil, nil, nil, syncService, nil, nil, nil, nil)
+	client := pb.NewSyncClient(conn)
+
+	ctx, cancel := context.WithTimeout(authContext(t, "user"), 5*time.Second)
+	defer cancel()
+	stream, err := client.Watch(ctx, &pb.WatchRequest{})
+	require.NoError(t, err)
+
+	event := models.ChangeEvent{
+		Collection: models.TextCollection,
+		RecordID:   models.NewRandomObjectID(),
+		Op:         models.OpCreate,
+		Version:    1,
+	}
+	events <- event
+	got, err := stream.Recv()
+	require.NoError(t, err)
+	gotEvent, err := got.ModelEvent()
+	require.NoError(t, err)
+	assert.Equal(t, event, gotEvent)
+
+	// the stream is finished when the subscription is closed
+	close(events)
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+
+	stream, err = client.Watch(context.Background(), &pb.WatchRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestSyncServer_Changes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	storageService := mock.NewMockStorageService(mockCtrl)
+	conn := startServer(t, nil, nil, storageService, nil, nil, nil, nil, nil)
+	client := pb.NewSyncClient(conn)
+	ctx := authContext(t, "user")
+	id := models.NewRandomObjectID()
+
+	t.Run("ok", func(t *testing.T) {
+		storageService.EXPECT().
+			Changes(gomock.Any(), "user", time.Time{}).
+			Return(&models.Changes{
+				Tombstones: []models.Tombstone{{Collection: models.TextCollection, RecordID: id}},
+				Reset:      true,
+			}, nil)
+		resp, err := client.Changes(ctx, &pb.ChangesRequest{})
+		require.NoError(t, err)
+		changes, err := resp.ModelChanges()
+		require.NoError(t, err)
+		assert.True(t, changes.Reset)
+		assert.NotEmpty(t, changes.Cursor)
+		assert.Equal(t, id, changes.Tombstones[0].RecordID)
+	})
+	t.Run("bad_cursor", func(t *testing.T) {
+		_, err := client.Changes(ctx, &pb.ChangesRequest{Since: "bad"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := client.Changes(context.Background(), &pb.ChangesRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestVaultServer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	vaultService := mock.NewMockVaultService(mockCtrl)
+	conn := startServer(t, nil, nil, nil, nil, vaultService, nil, nil, nil)
+	client := pb.NewVaultClient(conn)
+	ctx := authContext(t, "user")
+	params := models.VaultParams{
+		KDF:      models.KDFArgon2id,
+		Salt:     []byte("0123456789abcdef"),
+		Time:     3,
+		Memory:   64 * 1024,
+		Threads:  4,
+		KeyCheck: []byte("key check"),
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := client.Get(context.Background(), &pb.GetVaultRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("get", func(t *testing.T) {
+		vaultService.EXPECT().Get(gomock.Any(), "user").Return(&params, nil)
+		resp, err := client.Get(ctx, &pb.GetVaultRequest{})
+		require.NoError(t, err)
+		got, err := resp.ModelParams()
+		require.NoError(t, err)
+		assert.Equal(t, params, got)
+	})
+	t.Run("get_not_found", func(t *testing.T) {
+		vaultService.EXPECT().Get(gomock.Any(), "user").Return(nil, srvErrors.ErrVaultNotFound)
+		_, err := client.Get(ctx, &pb.GetVaultRequest{})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("set", func(t *testing.T) {
+		vaultService.EXPECT().Set(gomock.Any(), "user", params).Return(nil)
+		_, err := client.Set(ctx, pb.NewVaultParams(params))
+		require.NoError(t, err)
+	})
+	t.Run("set_exists", func(t *testing.T) {
+		vaultService.EXPECT().Set(gomock.Any(), "user", params).Return(srvErrors.ErrVaultExists)
+		_, err := client.Set(ctx, pb.NewVaultParams(params))
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+	t.Run("set_invalid", func(t *testing.T) {
+		_, err := client.Set(ctx, &pb.VaultParams{Kdf: "pbkdf2"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestBlobServer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	blobService := mock.NewMockBlobService(mockCtrl)
+	conn := startServer(t, nil, nil, nil, nil, nil, blobService, nil, nil)
+	client := pb.NewBlobsClient(conn)
+	ctx := authContext(t, "user")
+	id := models.NewRandomObjectID()
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := client.Get(context.Background(), &pb.GetBlobRequest{BlobId: id.Hex()})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("create", func(t *testing.T) {
+		blobService.EXPECT().
+			Create(gomock.Any(), "user", int64(9)).
+			Return(&models.Blob{BlobID: id, Size: 9}, nil)
+		resp, err := client.Create(ctx, &pb.CreateBlobRequest{Size: 9})
+		require.NoError(t, err)
+		assert.Equal(t, id.Hex(), resp.GetBlobId())
+	})
+	t.Run("create_negative", func(t *testing.T) {
+		_, err := client.Create(ctx, &pb.CreateBlobRequest{Size: -1})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("get_not_found", func(t *testing.T) {
+		blobService.EXPECT().Get(gomock.Any(), "user", id).Return(nil, srvErrors.ErrBlobNotFound)
+		_, err := client.Get(ctx, &pb.GetBlobRequest{BlobId: id.Hex()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("append", func(t *testing.T) {
+		blobService.EXPECT().
+			Append(gomock.Any(), "user", id, int64(0), []byte("hell")).
+			Return(&models.Blob{BlobID: id, Size: 9, Received: 4, Chunks: 1}, nil)
+		resp, err := client.Append(
+			ctx,
+			&pb.AppendChunkRequest{BlobId: id.Hex(), Offset: 0, Data: []byte("hell")},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), resp.GetReceived())
+	})
+	t.Run("append_bad_offset", func(t *testing.T) {
+		blobService.EXPECT().
+			Append(gomock.Any(), "user", id, int64(0), []byte("hell")).
+			Return(&models.Blob{BlobID: id, Size: 9, Received: 4, Chunks: 1}, srvErrors.ErrBadChunkOffset)
+		_, err := client.Append(
+			ctx,
+			&pb.AppendChunkRequest{BlobId: id.Hex(), Offset: 0, Data: []byte("hell")},
+		)
+		st := status.Convert(err)
+		assert.Equal(t, codes.FailedPrecondition, st.Code())
+		require.Len(t, st.Details(), 1)
+		assert.Equal(t, int64(4), st.Details()[0].(*pb.Blob).GetReceived())
+	})
+	t.Run("download", func(t *testing.T) {
+		blobService.EXPECT().
+			Read(gomock.Any(), "user", id, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, _ models.ObjectID, w io.Writer) error {
+				for _, chunk := range []string{"hell", "o, go"} {
+					if _, err := w.Write([]byte(chunk)); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		stream, err := client.Download(ctx, &pb.DownloadBlobRequest{BlobId: id.Hex()})
+		require.NoError(t, err)
+		var content []byte
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			content = append(content, chunk.GetData()...)
+		}
+		assert.Equal(t, "hello, go", string(content))
+	})
+	t.Run("download_incomplete", func(t *testing.T) {
+		blobService.EXPECT().
+			Read(gomock.Any(), "user", id, gomock.Any()).
+			Return(srvErrors.ErrBlobIncomplete)
+		stream, err := client.Download(ctx, &pb.DownloadBlobRequest{BlobId: id.Hex()})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
@@ -0,0 +1,462 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+	"github.com/blokhinnv/gophkeeper/internal/server/validation"
+)
+
+// storageServer implements the Storage gRPC service.
+type storageServer struct {
+	pb.UnimplementedStorageServer
+	service service.StorageService
+	sync    service.SyncService
+}
+
+// NewStorageServer creates a new instance of the Storage gRPC service.
+func NewStorageServer(service service.StorageService, sync service.SyncService) pb.StorageServer {
+	return &storageServer{
+		service: service,
+		sync:    sync,
+	}
+}
+
+// usernameFromContext returns the username set by the JWT interceptor.
+func usernameFromContext(ctx context.Context) (string, error) {
+	username := middleware.UsernameFromContext(ctx)
+	if username == "" {
+		return "", status.Error(codes.Unauthenticated, srvErrors.ErrNoUsernameProvided.Error())
+	}
+	return username, nil
+}
+
+// recordContent converts the data and the metadata of the record into the form
+// the storage service accepts and validates them like the REST API does.
+func recordContent(
+	r *pb.Record,
+	collectionName models.CollectionName,
+) (models.UntypedRecordContent, error) {
+	info, err := r.ModelData(collectionName)
+	if err != nil {
+		return models.UntypedRecordContent{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// the envelope of an end-to-end encrypted record holds the metadata as well
+	if models.IsEncryptedData(info) {
+		return models.UntypedRecordContent{Data: info}, nil
+	}
+	data := info
+	if collectionName != models.TextCollection {
+		m := make(map[string]any)
+		if err := mapstructure.Decode(info, &m); err != nil {
+			return models.UntypedRecordContent{}, status.Error(codes.InvalidArgument, err.Error())
+		}
+		data = m
+	}
+	if err := validation.ValidateRecordData(data, collectionName); err != nil {
+		return models.UntypedRecordContent{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return models.UntypedRecordContent{Data: data, Metadata: r.GetMetadata()}, nil
+}
+
+// storageError converts an error of the storage service into a gRPC status.
+func storageError(err error) error {
+	if errors.Is(err, srvErrors.ErrRecordNotFound) || errors.Is(err, srvErrors.ErrRevisionNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, srvErrors.ErrBadListOptions) || errors.Is(err, srvErrors.ErrEmptySearchQuery) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, srvErrors.ErrBlobNotFound) ||
+		errors.Is(err, srvErrors.ErrBlobIncomplete) ||
+		errors.Is(err, srvErrors.ErrBlobInUse) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, srvErrors.ErrRecordExists) {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// conflictError returns the Aborted status with the current record in its details.
+func conflictError(
+	collectionName models.CollectionName,
+	current *models.UntypedRecord,
+	err error,
+) error {
+	record, convErr := pb.NewStoredRecord(collectionName, *current)
+	if convErr != nil {
+		return status.Error(codes.Internal, convErr.Error())
+	}
+	st, detailsErr := status.New(codes.Aborted, err.Error()).WithDetails(record)
+	if detailsErr != nil {
+		return status.Error(codes.Internal, detailsErr.Error())
+	}
+	return st.Err()
+}
+
+// publish notifies the user's clients about the change of the record.
+func (s *storageServer) publish(
+	username string,
+	collectionName models.CollectionName,
+	recordID models.ObjectID,
+	op models.ChangeOp,
+) {
+	s.sync.Publish(username, models.ChangeEvent{
+		Collection: collectionName,
+		RecordID:   recordID,
+		Op:         op,
+	})
+}
+
+// Store saves a new record to the collection.
+func (s *storageServer) Store(ctx context.Context, in *pb.StoreRequest) (*pb.StoreResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionName, err := models.NewCollectionName(in.GetCollection())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	content, err := recordContent(in.GetRecord(), collectionName)
+	if err != nil {
+		return nil, err
+	}
+	record := models.UntypedRecord{
+		UntypedRecordContent: content,
+		Username:             username,
+	}
+	// the client may choose the ID of the record
+	if recordID := in.GetRecord().GetRecordId(); recordID != "" {
+		if record.RecordID, err = models.ObjectIDFromString(recordID); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	id, err := s.service.Store(ctx, collectionName, record)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	if recordID, err := models.ObjectIDFromString(id); err == nil {
+		s.publish(username, collectionName, recordID, models.OpCreate)
+	}
+	return &pb.StoreResponse{
+		RecordId: id,
+		Message:  fmt.Sprintf("Record added to %v collection: id=%v", collectionName, id),
+	}, nil
+}
+
+// GetAll returns a page of the records of the collection.
+func (s *storageServer) GetAll(
+	ctx context.Context,
+	in *pb.GetAllRequest,
+) (*pb.GetAllResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionName, err := models.NewCollectionName(in.GetCollection())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	records, cursor, err := s.service.GetAll(ctx, collectionName, username, models.GetAllOptions{
+		Limit:       in.GetLimit(),
+		Cursor:      in.GetCursor(),
+		Sort:        in.GetSort(),
+		Metadata:    in.GetMetadata(),
+		OmitContent: in.GetOmitContent(),
+	})
+	if err != nil {
+		return nil, storageError(err)
+	}
+	resp := &pb.GetAllResponse{
+		Records:    make([]*pb.Record, 0, len(records)),
+		NextCursor: cursor,
+	}
+	for _, r := range records {
+		record, err := pb.NewStoredRecord(collectionName, r)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		resp.Records = append(resp.Records, record)
+	}
+	return resp, nil
+}
+
+// Get returns a single record of the collection by its ID.
+func (s *storageServer) Get(ctx context.Context, in *pb.GetRequest) (*pb.GetResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionName, err := models.NewCollectionName(in.GetCollection())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	id, err := models.ObjectIDFromString(in.GetRecordId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	r, err := s.service.Get(ctx, collectionName, username, id)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	record, err := pb.NewStoredRecord(collectionName, *r)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.GetResponse{Record: record}, nil
+}
+
+// Update updates the data and the metadata of the record.
+func (s *storageServer) Update(
+	ctx context.Context,
+	in *pb.UpdateRequest,
+) (*pb.UpdateResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionName, err := models.NewCollectionName(in.GetCollection())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	id, err := in.GetRecord().ModelID()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	content, err := recordContent(in.GetRecord(), collectionName)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.service.Update(
+		ctx,
+		collectionName,
+		username,
+		id,
+		content.Data,
+		content.Metadata,
+		in.GetExpectedVersion(),
+	)
+	if errors.Is(err, srvErrors.ErrVersionConflict) {
+		return nil, conflictError(collectionName, updated, err)
+	} else if err != nil {
+		return nil, storageError(err)
+	}
+	s.publish(username, collectionName, id, models.OpUpdate)
+	return &pb.UpdateResponse{
+		Message: fmt.Sprintf(
+			"Record id=%v updated in %v collection: version=%v",
+			id.Hex(),
+			collectionName,
+			updated.Version,
+		),
+		Version: updated.Version,
+	}, nil
+}
+
+// Delete moves the record of the collection to the trash.
+func (s *storageServer) Delete(
+	ctx context.Context,
+	in *pb.DeleteRequest,
+) (*pb.DeleteResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionName, err := models.NewCollectionName(in.GetCollection())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	id, err := models.ObjectIDFromString(in.GetRecordId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.service.Delete(ctx, collectionName, username, id); err != nil {
+		return nil, storageError(err)
+	}
+	s.publish(username, collectionName, id, models.OpDelete)
+	return &pb.DeleteResponse{
+		Message: fmt.Sprintf("Record id=%v moved to the trash of %v collection", id.Hex(), collectionName),
+	}, nil
+}
+
+// History returns the previous revisions of the record, the latest first.
+func (s *storageServer) History(
+	ctx context.Context,
+	in *pb.HistoryRequest,
+) (*pb.HistoryResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionName, err := models.NewCollectionName(in.GetCollection())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	id, err := models.ObjectIDFromString(in.GetRecordId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	revisions, err := s.service.History(ctx, collectionName, username, id)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	resp := &pb.HistoryResponse{Revisions: make([]*pb.Revision, 0, len(revisions))}
+	for _, r := range revisions {
+		revision, err := pb.NewRevision(collectionName, r)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		resp.Revisions = append(resp.Revisions, revision)
+	}
+	return resp, nil
+}
+
+// Restore replaces the record with the revision from its history.
+func (s *storageServer) Restore(
+	ctx context.Context,
+	in *pb.RestoreRequest,
+) (*pb.RestoreResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionName, err := models.NewCollectionName(in.GetCollection())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	id, err := models.ObjectIDFromString(in.GetRecordId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	restored, err := s.service.Restore(ctx, collectionName, username, id, in.GetRev())
+	if err != nil {
+		return nil, storageError(err)
+	}
+	s.publish(username, collectionName, id, models.OpUpdate)
+	return &pb.RestoreResponse{
+		Message: fmt.Sprintf(
+			"Record id=%v restored from revision %v in %v collection: version=%v",
+			id.Hex(),
+			in.GetRev(),
+			collectionName,
+			restored.Version,
+		),
+		Version: restored.Version,
+	}, nil
+}
+
+// Trash returns the deleted records of the collection which have not been purged yet.
+func (s *storageServer) Trash(ctx context.Context, in *pb.TrashRequest) (*pb.TrashResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionName, err := models.NewCollectionName(in.GetCollection())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	records, err := s.service.Trash(ctx, collectionName, username)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	resp := &pb.TrashResponse{Records: make([]*pb.Record, 0, len(records))}
+	for _, r := range records {
+		record, err := pb.NewStoredRecord(collectionName, r)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		resp.Records = append(resp.Records, record)
+	}
+	return resp, nil
+}
+
+// Undelete moves the record back from the trash.
+func (s *storageServer) Undelete(
+	ctx context.Context,
+	in *pb.UndeleteRequest,
+) (*pb.UndeleteResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionName, err := models.NewCollectionName(in.GetCollection())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	id, err := models.ObjectIDFromString(in.GetRecordId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	restored, err := s.service.Undelete(ctx, collectionName, username, id)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	s.publish(username, collectionName, id, models.OpCreate)
+	return &pb.UndeleteResponse{
+		Message: fmt.Sprintf(
+			"Record id=%v restored from the trash of %v collection: version=%v",
+			id.Hex(),
+			collectionName,
+			restored.Version,
+		),
+		Version: restored.Version,
+	}, nil
+}
+
+// Purge permanently deletes the record from the trash.
+func (s *storageServer) Purge(ctx context.Context, in *pb.PurgeRequest) (*pb.PurgeResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionName, err := models.NewCollectionName(in.GetCollection())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	id, err := models.ObjectIDFromString(in.GetRecordId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.service.Purge(ctx, collectionName, username, id); err != nil {
+		return nil, storageError(err)
+	}
+	return &pb.PurgeResponse{
+		Message: fmt.Sprintf(
+			"Record id=%v permanently deleted from %v collection",
+			id.Hex(),
+			collectionName,
+		),
+	}, nil
+}
+
+// Search returns the records of all the collections which have all the words of the query.
+func (s *storageServer) Search(
+	ctx context.Context,
+	in *pb.SearchRequest,
+) (*pb.SearchResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.service.Search(ctx, username, in.GetQuery())
+	if err != nil {
+		return nil, storageError(err)
+	}
+	resp, err := pb.NewSearchResponse(results)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return resp, nil
+}
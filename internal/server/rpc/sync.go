@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
+)
+
+// syncServer implements the Sync gRPC service.
+type syncServer struct {
+	pb.UnimplementedSyncServer
+	service  service.SyncService
+	storage  service.StorageService
+	trashTTL time.Duration
+}
+
+// NewSyncServer creates a new instance of the Sync gRPC service. The changes are
+// read from the storage service; the cursors older than trashTTL are reset.
+func NewSyncServer(
+	service service.SyncService,
+	storage service.StorageService,
+	trashTTL time.Duration,
+) pb.SyncServer {
+	return &syncServer{service: service, storage: storage, trashTTL: trashTTL}
+}
+
+// Watch sends an event every time a record of the user is changed by any client.
+// The stream is finished when the subscription is canceled by the sync service,
+// e.g. when the access token expires or its session or device is revoked.
+func (s *syncServer) Watch(_ *pb.WatchRequest, stream pb.Sync_WatchServer) error {
+	claims, err := claimsFromContext(stream.Context())
+	if err != nil {
+		return err
+	}
+	username := claims.Username
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	events, unsubscribe := s.service.Subscribe(username, claims.DeviceID, claims.SessionID, expiresAt)
+	defer unsubscribe()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(pb.NewWatchEvent(event)); err != nil {
+				log.Infof("unable to send sync event to %v: %v", username, err)
+				return err
+			}
+		}
+	}
+}
+
+// Changes returns the changes of the user's records of all the collections since the cursor.
+func (s *syncServer) Changes(ctx context.Context, in *pb.ChangesRequest) (*pb.ChangesResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := service.ChangesSince(ctx, s.storage, username, in.GetSince(), s.trashTTL)
+	if errors.Is(err, srvErrors.ErrBadSyncCursor) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return nil, storageError(err)
+	}
+	resp, err := pb.NewChangesResponse(changes)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return resp, nil
+}
@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/blokhinnv/gophkeeper/internal/proto"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+)
+
+// vaultServer implements the Vault gRPC service.
+type vaultServer struct {
+	pb.UnimplementedVaultServer
+	service service.VaultService
+}
+
+// NewVaultServer creates a new instance of the Vault gRPC service.
+func NewVaultServer(service service.VaultService) pb.VaultServer {
+	return &vaultServer{service: service}
+}
+
+// Get returns the vault parameters of the user.
+func (s *vaultServer) Get(ctx context.Context, _ *pb.GetVaultRequest) (*pb.VaultParams, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params, err := s.service.Get(ctx, username)
+	if errors.Is(err, srvErrors.ErrVaultNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return pb.NewVaultParams(*params), nil
+}
+
+// Set saves the vault parameters of the user. They can be set only once.
+func (s *vaultServer) Set(ctx context.Context, in *pb.VaultParams) (*pb.SetVaultResponse, error) {
+	username, err := usernameFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params, err := in.ModelParams()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := validateVaultParams(params); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err = s.service.Set(ctx, username, params)
+	if errors.Is(err, srvErrors.ErrVaultExists) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.SetVaultResponse{Message: "Vault parameters saved"}, nil
+}
+
+// validateVaultParams checks the parameters like the binding of the REST API does.
+func validateVaultParams(params models.VaultParams) error {
+	switch {
+	case params.KDF != models.KDFArgon2id:
+		return errors.New("unsupported key derivation function")
+	case len(params.Salt) < 16:
+		return errors.New("salt is too short")
+	case params.Time == 0 || params.Memory == 0 || params.Threads == 0:
+		return errors.New("bad key derivation parameters")
+	case len(params.KeyCheck) == 0:
+		return errors.New("no key check")
+	}
+	return nil
+}
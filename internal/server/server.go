@@ -6,6 +6,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -22,6 +23,7 @@ import (
 	"github.com/blokhinnv/gophkeeper/internal/server/controller"
 	_ "github.com/blokhinnv/gophkeeper/internal/server/docs"
 	"github.com/blokhinnv/gophkeeper/internal/server/middleware"
+	"github.com/blokhinnv/gophkeeper/internal/server/rpc"
 	"github.com/blokhinnv/gophkeeper/internal/server/service"
 	"github.com/blokhinnv/gophkeeper/pkg/log"
 )
@@ -38,7 +40,7 @@ import (
 //	@name Authorization
 func RunServer(cfg *config.ServerConfig) {
 	// Connect to MongoDB.
-	log.Printf("Starting server with config %+v\n", cfg)
+	log.Printf("Starting server with config %v\n", cfg)
 	ctx := context.Background()
 	client, err := mongo.Connect(
 		ctx,
@@ -50,49 +52,188 @@ func RunServer(cfg *config.ServerConfig) {
 		}
 	}()
 
+	keyring, err := cfg.Keyring()
+	if err != nil {
+		log.Fatalf("bad encryption keys: %v", err)
+	}
+	// the records of the legacy format are read only after they are marked
+	err = service.NewRotationService(client.Database(cfg.DBName), keyring).
+		CheckLegacyMigration(ctx)
+	if err != nil {
+		log.Fatalf("can't start the server: %v", err)
+	}
+	index, err := cfg.BlindIndex()
+	if err != nil {
+		log.Fatalf("bad search index key: %v", err)
+	}
+	jwtKeys, err := cfg.JWTKeys()
+	if err != nil {
+		log.Fatalf("bad JWT signing keys: %v", err)
+	}
+	log.Printf("Signing the access tokens with %v key %q\n", jwtKeys.Algorithm(), jwtKeys.KeyID())
+
 	// Create service and controller instances.
 	var (
+		syncService    service.SyncService    = service.NewSyncService()
+		sessionService service.SessionService = service.NewSessionService(
+			client.Database(cfg.DBName),
+			jwtKeys,
+			cfg.ExpireDuration,
+			cfg.RefreshExpireDuration,
+			syncService,
+		)
 		storageService service.StorageService = service.NewStorageService(
-			client.Database(cfg.DBName), cfg.EncryptionKey,
+			client.Database(cfg.DBName), keyring, index,
 		)
 		utilsService service.UtilsService = service.NewUtilsService(
 			client,
 		)
+		lockoutService service.LockoutService = service.NewLockoutService(
+			service.NewMongoAttemptStore(client.Database(cfg.DBName)),
+			lockoutPolicy(cfg),
+		)
+		mfaService service.MFAService = service.NewMFAService(
+			client.Database(cfg.DBName).Collection(service.UsersCollection),
+			keyring,
+			jwtKeys,
+			lockoutService,
+			cfg.MFAExpireDuration,
+		)
+		deviceService service.DeviceService = service.NewDeviceService(
+			client.Database(cfg.DBName), jwtKeys, sessionService, syncService,
+		)
 		authService service.AuthService = service.NewAuthService(
-			client.Database(cfg.DBName).Collection("users"),
-			cfg.SigningKey,
-			cfg.ExpireDuration,
+			client.Database(cfg.DBName).Collection(service.UsersCollection),
+			sessionService,
+			mfaService,
+			lockoutService,
+			deviceService,
+			syncService,
+		)
+		blobService service.BlobService = service.NewBlobService(
+			client.Database(cfg.DBName), keyring,
+		)
+		vaultService service.VaultService = service.NewVaultService(
+			client.Database(cfg.DBName).Collection(service.UsersCollection),
 		)
-		syncService service.SyncService = service.NewSyncService()
 
 		storageController controller.StorageController = controller.NewStorageController(
 			storageService, syncService,
 		)
-		utilsController controller.UtilsController = controller.NewUtilsController(utilsService)
-		authController  controller.AuthController  = controller.NewAuthController(authService)
-		syncController  controller.SyncController  = controller.NewSyncController(syncService)
+		utilsController   controller.UtilsController   = controller.NewUtilsController(utilsService)
+		authController    controller.AuthController    = controller.NewAuthController(authService)
+		sessionController controller.SessionController = controller.NewSessionController(
+			sessionService,
+		)
+		syncController controller.SyncController = controller.NewSyncController(
+			syncService, storageService, cfg.TrashTTL,
+		)
+		otpController    controller.OTPController    = controller.NewOTPController(storageService, syncService)
+		searchController controller.SearchController = controller.NewSearchController(
+			storageService,
+		)
+		vaultController  controller.VaultController  = controller.NewVaultController(vaultService)
+		blobController   controller.BlobController   = controller.NewBlobController(blobService)
+		mfaController    controller.MFAController    = controller.NewMFAController(mfaService)
+		jwksController   controller.JWKSController   = controller.NewJWKSController(jwtKeys)
+		deviceController controller.DeviceController = controller.NewDeviceController(
+			deviceService,
+		)
 	)
 
+	if err := sessionService.EnsureIndexes(ctx); err != nil {
+		log.Printf("unable to create the indexes of the sessions: %v\n", err)
+	}
+	if err := storageService.EnsureIndexes(ctx, cfg.HistoryRetention); err != nil {
+		log.Printf("unable to create the indexes of the history: %v\n", err)
+	}
+	if err := blobService.EnsureIndexes(ctx); err != nil {
+		log.Printf("unable to create the indexes of the blobs: %v\n", err)
+	}
+	if err := lockoutService.EnsureIndexes(ctx); err != nil {
+		log.Printf("unable to create the indexes of the login attempts: %v\n", err)
+	}
+	if err := deviceService.EnsureIndexes(ctx); err != nil {
+		log.Printf("unable to create the indexes of the devices: %v\n", err)
+	}
+	// The purgers are stopped together with the server.
+	purgerCtx, stopPurger := context.WithCancel(ctx)
+	defer stopPurger()
+	if cfg.TrashTTL > 0 {
+		go service.RunTrashPurger(purgerCtx, storageService, cfg.TrashTTL, cfg.TrashPurgeInterval)
+	}
+	if cfg.UploadTTL > 0 {
+		go service.RunUploadPurger(purgerCtx, blobService, cfg.UploadTTL, cfg.TrashPurgeInterval)
+	}
+	jwtAuth := middleware.JWTAuthMiddleware(jwtKeys, sessionService)
+
 	// Set up routes and middleware.
 	gin.SetMode(gin.ReleaseMode)
-	r := gin.Default()
+	r, err := newRouter(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("bad trusted proxies: %v", err)
+	}
+
+	r.GET("/.well-known/jwks.json", jwksController.JWKS)
 
 	public := r.Group("/api")
 	public.GET("/ping", utilsController.Ping)
 	public.PUT("/user/register", authController.Register)
 	public.PUT("/user/login", authController.Login)
+	public.PUT("/user/login/mfa", authController.LoginMFA)
+	public.POST("/user/refresh", sessionController.Refresh)
+	public.POST("/user/devices/nonce", deviceController.Nonce)
+
+	user := r.Group("/api/user")
+	user.Use(jwtAuth)
+	user.POST("/logout", sessionController.Logout)
+	user.POST("/password", authController.ChangePassword)
+	user.DELETE("", authController.DeleteAccount)
+	user.GET("/sessions", sessionController.List)
+	user.DELETE("/sessions/:sessionID", sessionController.Revoke)
+	user.GET("/devices", deviceController.List)
+	user.DELETE("/devices/:deviceID", deviceController.Delete)
+	user.POST("/mfa", mfaController.Enroll)
+	user.POST("/mfa/confirm", mfaController.Confirm)
+	user.DELETE("/mfa", mfaController.Disable)
 
 	protected := r.Group("/api/store")
-	protected.Use(middleware.JWTAuthMiddleware([]byte(cfg.SigningKey)))
+	protected.Use(jwtAuth)
 	protected.PUT("/:collectionName", storageController.Store)
 	protected.POST("/:collectionName", storageController.Update)
 	protected.GET("/:collectionName", storageController.GetAll)
+	protected.GET("/:collectionName/trash", storageController.Trash)
+	protected.POST("/:collectionName/trash/:recordID/restore", storageController.Undelete)
+	protected.DELETE("/:collectionName/trash/:recordID", storageController.Purge)
+	protected.GET("/:collectionName/:recordID", storageController.Get)
+	protected.GET("/:collectionName/:recordID/history", storageController.History)
+	protected.POST("/:collectionName/:recordID/restore", storageController.Restore)
 	protected.DELETE("/:collectionName", storageController.Delete)
 
 	sync := r.Group("/api/sync")
-	sync.Use(middleware.JWTAuthMiddleware([]byte(cfg.SigningKey)))
-	sync.POST("/register", syncController.Register)
-	sync.POST("/unregister", syncController.Unregister)
+	sync.Use(jwtAuth)
+	sync.GET("/events", syncController.Events)
+	sync.GET("/changes", syncController.Changes)
+
+	otp := r.Group("/api/otp")
+	otp.Use(jwtAuth)
+	otp.POST("/:recordID/code", otpController.Code)
+
+	search := r.Group("/api/search")
+	search.Use(jwtAuth)
+	search.GET("", searchController.Search)
+
+	blobs := r.Group("/api/blobs")
+	blobs.Use(jwtAuth)
+	blobs.POST("", blobController.Create)
+	blobs.GET("/:blobID", blobController.Get)
+	blobs.PUT("/:blobID", blobController.Append)
+	blobs.GET("/:blobID/content", blobController.Download)
+
+	vault := r.Group("/api/vault")
+	vault.Use(jwtAuth)
+	vault.GET("", vaultController.Get)
+	vault.PUT("", vaultController.Set)
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -100,6 +241,9 @@ func RunServer(cfg *config.ServerConfig) {
 		Addr:    fmt.Sprintf("127.0.0.1:%v", cfg.Port),
 		Handler: r,
 	}
+	// The event streams never finish by themselves, so they are closed
+	// to let the server shut down gracefully.
+	srv.RegisterOnShutdown(syncService.Close)
 	go func() {
 		var err error
 		if cfg.UseHTTPS {
@@ -114,6 +258,33 @@ func RunServer(cfg *config.ServerConfig) {
 			}
 		}
 	}()
+
+	// The gRPC transport exposes the same services on the second port.
+	grpcServer, err := rpc.NewServer(
+		cfg,
+		jwtKeys,
+		authService,
+		sessionService,
+		storageService,
+		syncService,
+		vaultService,
+		blobService,
+		mfaService,
+		deviceService,
+	)
+	if err != nil {
+		log.Fatalf("provide correct certfile and keyfile or disable https: %v", err)
+	}
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%v", cfg.GRPCPort))
+	if err != nil {
+		log.Fatalf("grpc listen error: %v", err)
+	}
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatalf("grpc serve error: %v", err)
+		}
+	}()
+
 	quit := make(chan os.Signal, 2)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -123,6 +294,30 @@ func RunServer(cfg *config.ServerConfig) {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server Shutdown:", err)
 	}
+	// Watch streams are closed together with the event streams above;
+	// the server is stopped forcibly if the graceful stop still takes too long.
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
 	log.Println("Bye!")
 
 }
+
+// newRouter creates the router which takes the address of the client from
+// the X-Forwarded-For header only if the request comes from one of the
+// trusted proxies. Otherwise the header could be set by the client to evade
+// the lockout of its address.
+func newRouter(trustedProxies []string) (*gin.Engine, error) {
+	r := gin.Default()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// deleteUserData deletes the user and all the data of the user: the records
+// and their history in all the collections, the blobs and their chunks.
+// The user is deleted last, so the deletion which failed halfway can be repeated.
+func deleteUserData(ctx context.Context, db *mongo.Database, username string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	cur, err := db.Collection(BlobsCollection).Find(
+		ctx,
+		bson.M{"username": username},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	var blobs []models.Blob
+	if err := cur.All(ctx, &blobs); err != nil {
+		return err
+	}
+	blobIDs := make([]models.ObjectID, 0, len(blobs))
+	for _, b := range blobs {
+		blobIDs = append(blobIDs, b.BlobID)
+	}
+	for _, src := range backupSources() {
+		if src.kind == backupUser {
+			continue
+		}
+		filter := bson.M{"username": username}
+		if src.kind == backupChunk {
+			filter = bson.M{"blob_id": bson.M{"$in": blobIDs}}
+		}
+		if _, err := db.Collection(src.name).DeleteMany(ctx, filter); err != nil {
+			return err
+		}
+	}
+	_, err = db.Collection(UsersCollection).DeleteOne(ctx, bson.M{"username": username})
+	return err
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// LoginAttemptsCollection and SecurityEventsCollection are the collections
+// which keep the counters of the failed logins and the security events.
+const (
+	LoginAttemptsCollection  = "login_attempts"
+	SecurityEventsCollection = "security_events"
+)
+
+// AttemptStore keeps the counters of the failed logins and the security events.
+type AttemptStore interface {
+	// Get returns the counter of the key or nil if there were no recent failures.
+	Get(ctx context.Context, key string) (*models.LoginAttempts, error)
+	// Fail counts the failure of the key and returns the updated counter.
+	// The failures older than the window are forgotten.
+	Fail(ctx context.Context, key string, now time.Time, window time.Duration) (*models.LoginAttempts, error)
+	// Lock rejects the logins of the key until the time.
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset removes the counter and the lockout of the key.
+	// Returns false if there was nothing to remove.
+	Reset(ctx context.Context, key string) (bool, error)
+	// Record saves the security event.
+	Record(ctx context.Context, event *models.SecurityEvent) error
+	// EnsureIndexes creates the indexes which remove the expired counters and events.
+	EnsureIndexes(ctx context.Context) error
+}
+
+// mongoAttemptStore is the implementation of the AttemptStore interface
+// which keeps the data in the DB, so it is shared by the instances of the server.
+type mongoAttemptStore struct {
+	attempts *mongo.Collection
+	events   *mongo.Collection
+}
+
+// NewMongoAttemptStore creates a new instance of the AttemptStore which keeps the data in the DB.
+func NewMongoAttemptStore(db *mongo.Database) AttemptStore {
+	return &mongoAttemptStore{
+		attempts: db.Collection(LoginAttemptsCollection),
+		events:   db.Collection(SecurityEventsCollection),
+	}
+}
+
+// Get returns the counter of the key or nil if there were no recent failures.
+func (s *mongoAttemptStore) Get(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	var attempts models.LoginAttempts
+	err := s.attempts.FindOne(ctx, bson.M{"_id": key}).Decode(&attempts)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+// Fail counts the failure of the key. The counter is updated by a single pipeline,
+// so the concurrent failures are not lost.
+func (s *mongoAttemptStore) Fail(
+	ctx context.Context,
+	key string,
+	now time.Time,
+	window time.Duration,
+) (*models.LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.D{
+		{Key: "failures", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$gt", Value: bson.A{"$last_failure", now.Add(-window)}}},
+			bson.D{{Key: "$add", Value: bson.A{"$failures", 1}}},
+			1,
+		}}}},
+		{Key: "last_failure", Value: now},
+		{Key: "expires_at", Value: bson.D{{Key: "$max", Value: bson.A{
+			"$locked_until",
+			now.Add(window),
+		}}}},
+	}}}}
+	var attempts models.LoginAttempts
+	err := s.attempts.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": key},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempts)
+	if err != nil {
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+// Lock rejects the logins of the key until the time.
+func (s *mongoAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	_, err := s.attempts.UpdateOne(ctx, bson.M{"_id": key}, bson.M{
+		"$set": bson.M{"locked_until": until},
+		"$max": bson.M{"expires_at": until},
+	})
+	return err
+}
+
+// Reset removes the counter and the lockout of the key.
+func (s *mongoAttemptStore) Reset(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	res, err := s.attempts.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+// Record saves the security event.
+func (s *mongoAttemptStore) Record(ctx context.Context, event *models.SecurityEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	_, err := s.events.InsertOne(ctx, event)
+	return err
+}
+
+// EnsureIndexes creates the indexes which remove the expired counters and events
+// and the index of the events of the user.
+func (s *mongoAttemptStore) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	for _, collection := range []*mongo.Collection{s.attempts, s.events} {
+		_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_, err := s.events.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "username", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	return err
+}
+
+// memoryEventsLimit is the number of the last security events kept by the memory store.
+const memoryEventsLimit = 1000
+
+// memoryAttemptStore is the implementation of the AttemptStore interface
+// which keeps the data in the memory of the process.
+type memoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempts
+	events   []models.SecurityEvent
+}
+
+// NewMemoryAttemptStore creates a new instance of the AttemptStore which keeps
+// the data in the memory. The data is lost on restart and isn't shared
+// by the instances of the server.
+func NewMemoryAttemptStore() AttemptStore {
+	return &memoryAttemptStore{attempts: make(map[string]models.LoginAttempts)}
+}
+
+// Get returns the counter of the key or nil if there were no recent failures.
+func (s *memoryAttemptStore) Get(_ context.Context, key string) (*models.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempts, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempts, nil
+}
+
+// Fail counts the failure of the key and returns the updated counter.
+func (s *memoryAttemptStore) Fail(
+	_ context.Context,
+	key string,
+	now time.Time,
+	window time.Duration,
+) (*models.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the expired counters are removed like the TTL index of the DB does
+	for k, a := range s.attempts {
+		if !a.ExpiresAt.After(now) {
+			delete(s.attempts, k)
+		}
+	}
+	attempts := s.attempts[key]
+	attempts.Key = key
+	if attempts.LastFailure.After(now.Add(-window)) {
+		attempts.Failures++
+	} else {
+		attempts.Failures = 1
+	}
+	attempts.LastFailure = now
+	attempts.ExpiresAt = now.Add(window)
+	if attempts.LockedUntil.After(attempts.ExpiresAt) {
+		attempts.ExpiresAt = attempts.LockedUntil
+	}
+	s.attempts[key] = attempts
+	return &attempts, nil
+}
+
+// Lock rejects the logins of the key until the time.
+func (s *memoryAttemptStore) Lock(_ context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempts, ok := s.attempts[key]
+	if !ok {
+		return nil
+	}
+	attempts.LockedUntil = until
+	if until.After(attempts.ExpiresAt) {
+		attempts.ExpiresAt = until
+	}
+	s.attempts[key] = attempts
+	return nil
+}
+
+// Reset removes the counter and the lockout of the key.
+func (s *memoryAttemptStore) Reset(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.attempts[key]
+	delete(s.attempts, key)
+	return ok, nil
+}
+
+// Record saves the security event.
+func (s *memoryAttemptStore) Record(_ context.Context, event *models.SecurityEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, *event)
+	if len(s.events) > memoryEventsLimit {
+		s.events = s.events[len(s.events)-memoryEventsLimit:]
+	}
+	return nil
+}
+
+// EnsureIndexes does nothing since the memory store has no indexes.
+func (s *memoryAttemptStore) EnsureIndexes(context.Context) error {
+	return nil
+}
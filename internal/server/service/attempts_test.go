@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMongoAttemptStore(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mt.Run("fail", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: "user:alice"},
+			{Key: "failures", Value: 3},
+			{Key: "last_failure", Value: now},
+			{Key: "expires_at", Value: now.Add(time.Hour)},
+		}}))
+		attempts, err := NewMongoAttemptStore(mt.DB).Fail(context.TODO(), "user:alice", now, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts.Failures)
+		assert.False(t, attempts.Locked(now))
+
+		// the counter is updated by a pipeline, so the concurrent failures are not lost
+		cmd := mt.GetStartedEvent().Command
+		assert.True(t, cmd.Lookup("upsert").Boolean())
+		_, err = cmd.Lookup("update").Array().Index(0).Value().Document().LookupErr("$set", "failures", "$cond")
+		assert.NoError(t, err)
+	})
+	mt.Run("get_missing", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.login_attempts", mtest.FirstBatch))
+		attempts, err := NewMongoAttemptStore(mt.DB).Get(context.TODO(), "user:alice")
+		require.NoError(t, err)
+		assert.Nil(t, attempts)
+	})
+	mt.Run("get_locked", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.login_attempts", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "user:alice"},
+			{Key: "failures", Value: 6},
+			{Key: "locked_until", Value: now.Add(time.Minute)},
+		}))
+		attempts, err := NewMongoAttemptStore(mt.DB).Get(context.TODO(), "user:alice")
+		require.NoError(t, err)
+		assert.True(t, attempts.Locked(now))
+	})
+	mt.Run("reset", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		ok, err := NewMongoAttemptStore(mt.DB).Reset(context.TODO(), "user:alice")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+		ok, err = NewMongoAttemptStore(mt.DB).Reset(context.TODO(), "user:bob")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -16,32 +16,60 @@ import (
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
 )
 
+// UsersCollection is the collection which keeps the users.
+const UsersCollection = "users"
+
 // AuthService is an interface that defines the methods to handle authentication-related operations.
 type AuthService interface {
 	// Register creates a new user with the specified username and hashed password.
 	Register(username, password string) error
-	// Login attempts to authenticate a user with the specified username and password,
-	// and returns a JWT token if successful.
-	Login(username, password string) (string, error)
+	// Login attempts to authenticate a user with the specified username and password
+	// sent from the address, registers the device, which may be nil, and starts
+	// a new session on it returning its tokens if successful.
+	// If the user has the second factor enabled, only the challenge token is returned.
+	Login(username, password, ip string, device *models.DeviceInfo) (*models.TokenPair, error)
+	// LoginMFA exchanges the challenge token of the login and the one-time
+	// or the recovery code sent from the address for the tokens of a new session
+	// on the device like Login.
+	LoginMFA(mfaToken, code, ip string, device *models.DeviceInfo) (*models.TokenPair, error)
+	// ChangePassword checks the current password of the user sent from the address,
+	// replaces it with the new one, finishes all the sessions of the user and
+	// cancels the sync subscriptions. Returns the tokens of a new session on the device of the request.
+	ChangePassword(
+		username, oldPassword, newPassword, ip, deviceID string,
+	) (*models.TokenPair, error)
+	// DeleteAccount checks the password of the user sent from the address,
+	// finishes all the sessions of the user, cancels the sync subscriptions
+	// and deletes the user with all the data.
+	DeleteAccount(username, password, ip string) error
 }
 
 // authService is an implementation of the AuthService interface.
 type authService struct {
-	collection     *mongo.Collection // The MongoDB collection used to store user data.
-	signingKey     []byte            // The signing key used to generate JWT tokens.
-	expireDuration time.Duration     // The duration for which JWT tokens are valid.
+	collection *mongo.Collection // The MongoDB collection used to store user data.
+	sessions   SessionService    // The service which issues the tokens.
+	mfa        MFAService        // The service which checks the second factor.
+	lockout    LockoutService    // The service which throttles the failed logins.
+	devices    DeviceService     // The service which registers the devices.
+	sync       SyncService       // The service which keeps the sync subscriptions of the user.
 }
 
 // NewAuthService creates a new instance of the authService struct with the specified parameters.
 func NewAuthService(
 	collection *mongo.Collection,
-	signingKey string,
-	expireDuration time.Duration,
+	sessions SessionService,
+	mfa MFAService,
+	lockout LockoutService,
+	devices DeviceService,
+	sync SyncService,
 ) AuthService {
 	return &authService{
-		collection:     collection,
-		signingKey:     []byte(signingKey),
-		expireDuration: expireDuration,
+		collection: collection,
+		sessions:   sessions,
+		mfa:        mfa,
+		lockout:    lockout,
+		devices:    devices,
+		sync:       sync,
 	}
 }
 
@@ -75,28 +103,216 @@ func (t *authService) Register(username, password string) error {
 }
 
 // Login attempts to authenticate a user with the specified username and password.
-// Returns a JWT token if authentication is successful, or an error otherwise.
-func (t *authService) Login(username, password string) (string, error) {
+// Returns the tokens of the new session if authentication is successful, or an error otherwise.
+// The user with the second factor gets the challenge token instead. An unknown username
+// and a wrong password are counted as failures alike and return ErrBadCredentials;
+// after too many failures the logins are rejected with LockedError for a while.
+// The malformed device is rejected with ErrBadDevice before the password is checked.
+func (t *authService) Login(
+	username, password, ip string,
+	device *models.DeviceInfo,
+) (*models.TokenPair, error) {
+	if err := validateDevice(device); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	user, err := t.checkPassword(ctx, username, password, ip)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFA != nil && user.MFA.Enabled {
+		return t.mfa.Challenge(username)
+	}
+	if err := t.lockout.Succeed(ctx, username); err != nil {
+		return nil, err
+	}
+	return t.startSession(ctx, username, device)
+}
+
+// validateDevice checks the device of the login if there is one.
+func validateDevice(device *models.DeviceInfo) error {
+	if device == nil {
+		return nil
+	}
+	return device.Validate()
+}
+
+// startSession registers the device of the login, if there is one,
+// and starts a new session of the user on it.
+func (t *authService) startSession(
+	ctx context.Context,
+	username string,
+	device *models.DeviceInfo,
+) (*models.TokenPair, error) {
+	if device == nil {
+		return t.sessions.Create(ctx, username, "")
+	}
+	registered, err := t.devices.Register(ctx, username, *device)
+	if err != nil {
+		return nil, err
+	}
+	return t.sessions.Create(ctx, username, registered.ID.Hex())
+}
+
+// LoginMFA exchanges the challenge token of the login and the code for the tokens
+// of a new session. Returns ErrBadMFAToken or ErrBadMFACode if the check fails.
+// The wrong codes are counted as the failed logins of the user.
+func (t *authService) LoginMFA(
+	mfaToken, code, ip string,
+	device *models.DeviceInfo,
+) (*models.TokenPair, error) {
+	if err := validateDevice(device); err != nil {
+		return nil, err
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
+	username, err := t.mfa.ParseChallenge(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.lockout.Check(ctx, username, ip); err != nil {
+		return nil, err
+	}
+	if _, err := t.mfa.Verify(ctx, mfaToken, code); errors.Is(err, srvErrors.ErrBadMFACode) {
+		return nil, t.fail(ctx, username, ip, err)
+	} else if err != nil {
+		return nil, err
+	}
+	if err := t.lockout.Succeed(ctx, username); err != nil {
+		return nil, err
+	}
+	return t.startSession(ctx, username, device)
+}
+
+// ChangePassword checks the current password and replaces it with the new one.
+// All the sessions of the user are finished, their access tokens are revoked
+// and the sync subscriptions are canceled, so the stolen tokens stop working
+// and their event streams are closed. Returns the tokens of a new session
+// on the device of the request, so the device stays signed in.
+// The wrong password is counted as the failed login and returns ErrBadCredentials.
+func (t *authService) ChangePassword(
+	username, oldPassword, newPassword, ip, deviceID string,
+) (*models.TokenPair, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user, err := t.checkPassword(ctx, username, oldPassword, ip)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.lockout.Succeed(ctx, username); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	// the current hash is in the filter, so of the concurrent changes only one succeeds
+	res, err := t.collection.UpdateOne(
+		ctx,
+		bson.D{
+			{Key: "username", Value: username},
+			{Key: "hashedPassword", Value: user.HashedPassword},
+		},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "hashedPassword", Value: string(hashedPassword)}}}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, srvErrors.ErrBadCredentials
+	}
+	if err := t.sessions.RevokeAll(ctx, username); err != nil {
+		return nil, err
+	}
+	t.sync.DisconnectUser(username)
+	return t.sessions.Create(ctx, username, deviceID)
+}
+
+// DeleteAccount checks the password, finishes all the sessions of the user
+// and deletes the user with the devices, the records in all the collections,
+// their history and the blobs. The sync subscriptions of the user are canceled
+// once the user is deleted, so no stream is left for the deleted user.
+// The wrong password is counted as the failed login and returns ErrBadCredentials.
+func (t *authService) DeleteAccount(username, password, ip string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := t.checkPassword(ctx, username, password, ip); err != nil {
+		return err
+	}
+	if err := t.lockout.Succeed(ctx, username); err != nil {
+		return err
+	}
+	if err := t.sessions.RevokeAll(ctx, username); err != nil {
+		return err
+	}
+	_, err := t.collection.Database().Collection(DevicesCollection).DeleteMany(
+		ctx,
+		bson.M{"username": username},
+	)
+	if err != nil {
+		return err
+	}
+	if err := deleteUserData(ctx, t.collection.Database(), username); err != nil {
+		return err
+	}
+	t.sync.DisconnectUser(username)
+	return nil
+}
+
+// checkPassword returns the user if the password sent from the address is right.
+func (t *authService) checkPassword(
+	ctx context.Context,
+	username, password, ip string,
+) (*models.User, error) {
+	return checkPassword(ctx, t.collection, t.lockout, username, password, ip)
+}
+
+// fail counts the failed login and returns the error of the login
+// or the error of counting.
+func (t *authService) fail(ctx context.Context, username, ip string, loginErr error) error {
+	return failLogin(ctx, t.lockout, username, ip, loginErr)
+}
 
+// checkPassword returns the user of the collection if the password sent from
+// the address is right. An unknown username and a wrong password are counted
+// as failures alike and return ErrBadCredentials; the locked logins return LockedError.
+func checkPassword(
+	ctx context.Context,
+	users *mongo.Collection,
+	lockout LockoutService,
+	username, password, ip string,
+) (*models.User, error) {
+	if err := lockout.Check(ctx, username, ip); err != nil {
+		return nil, err
+	}
 	var user models.User
-	err := t.collection.FindOne(ctx, bson.D{{Key: "username", Value: username}}).Decode(&user)
+	err := users.FindOne(ctx, bson.D{{Key: "username", Value: username}}).Decode(&user)
 	if errors.Is(err, mongo.ErrNoDocuments) {
-		return "", err
+		return nil, failLogin(ctx, lockout, username, ip, srvErrors.ErrBadCredentials)
 	} else if err != nil {
-		return "", err
+		return nil, err
 	}
 	ok, err := auth.VerifyPassword(password, user.HashedPassword)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if !ok {
-		return "", srvErrors.ErrUnauthorized
+		return nil, failLogin(ctx, lockout, username, ip, srvErrors.ErrBadCredentials)
 	}
-	tok, err := auth.GenerateJWTToken(username, t.signingKey, t.expireDuration)
-	if err != nil {
-		return "", err
+	return &user, nil
+}
+
+// failLogin counts the failed login and returns the error of the login
+// or the error of counting.
+func failLogin(
+	ctx context.Context,
+	lockout LockoutService,
+	username, ip string,
+	loginErr error,
+) error {
+	if err := lockout.Fail(ctx, username, ip); err != nil {
+		return err
 	}
-	return tok, err
+	return loginErr
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -10,6 +11,8 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
 )
 
@@ -17,6 +20,57 @@ type AuthServiceTestSuite struct {
 	suite.Suite
 }
 
+// testLockoutPolicy locks the logins of a user after two failures.
+var testLockoutPolicy = LockoutPolicy{
+	UserFreeAttempts: 2,
+	IPFreeAttempts:   10,
+	Backoff:          time.Minute,
+	MaxLockout:       time.Hour,
+	Window:           time.Hour,
+}
+
+// newTestAuthService creates the auth service with the sessions and the second factor
+// in the test DB. The failed logins are counted in the memory store.
+func newTestAuthService(t *testing.T, mt *mtest.T) AuthService {
+	return newTestAuthServiceWithLockout(t, mt, NewLockoutService(NewMemoryAttemptStore(), testLockoutPolicy))
+}
+
+// newTestAuthServiceWithLockout creates the auth service which counts the failed logins with the lockout service.
+func newTestAuthServiceWithLockout(t *testing.T, mt *mtest.T, lockout LockoutService) AuthService {
+	return newTestAuthServiceWithSync(t, mt, lockout, NewSyncService())
+}
+
+// newTestAuthServiceWithSync creates the auth service which cancels the subscriptions
+// of the sync service.
+func newTestAuthServiceWithSync(
+	t *testing.T,
+	mt *mtest.T,
+	lockout LockoutService,
+	sync SyncService,
+) AuthService {
+	sessions := NewSessionService(
+		mt.DB,
+		auth.NewHMACKeySet([]byte("my-secret-key")),
+		time.Hour,
+		24*time.Hour,
+		sync,
+	)
+	return NewAuthService(
+		mt.Coll,
+		sessions,
+		NewMFAService(
+			mt.Coll,
+			newTestKeyring(t, "key"),
+			auth.NewHMACKeySet([]byte("my-secret-key")),
+			lockout,
+			time.Minute,
+		),
+		lockout,
+		NewDeviceService(mt.DB, auth.NewHMACKeySet([]byte("my-secret-key")), sessions, sync),
+		sync,
+	)
+}
+
 func (suite *AuthServiceTestSuite) SetupSuite()    {}
 func (suite *AuthServiceTestSuite) TearDownSuite() {}
 
@@ -26,14 +80,14 @@ func (suite *AuthServiceTestSuite) TestRegister() {
 	defer mt.Close()
 	mt.Run("success", func(mt *mtest.T) {
 		// Test registering a new user
-		authService := NewAuthService(mt.Coll, "my-secret-key", time.Hour)
+		authService := newTestAuthService(t, mt)
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
 		err := authService.Register("testuser", "testpassword")
 		require.NoError(t, err)
 	})
 	mt.Run("err", func(mt *mtest.T) {
 		// Test registering a user with an already taken username
-		authService := NewAuthService(mt.Coll, "my-secret-key", time.Hour)
+		authService := newTestAuthService(t, mt)
 		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
 			Index:   1,
 			Code:    11000,
@@ -58,35 +112,258 @@ func (suite *AuthServiceTestSuite) TestLogin() {
 	require.NoError(t, err)
 
 	mt.Run("valid credentials", func(mt *mtest.T) {
-		authService := NewAuthService(mt.Coll, "my-secret-key", time.Hour)
+		authService := newTestAuthService(t, mt)
 		mt.AddMockResponses(mtest.CreateCursorResponse(1, "login.valid", mtest.FirstBatch, bson.D{
 			{Key: "_id", Value: models.NewRandomObjectID()},
 			{Key: "username", Value: user},
 			{Key: "hashedPassword", Value: string(hashedPassword)},
 		}))
-		tok, err := authService.Login(user, password)
+		// the first response finishes the cursor of the user
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		tokens, err := authService.Login(user, password, "127.0.0.1", nil)
 		require.NoError(t, err)
-		require.NotEmpty(t, tok)
+		require.NotEmpty(t, tokens.AccessToken)
+		require.NotEmpty(t, tokens.RefreshToken)
+	})
+	mt.Run("device", func(mt *mtest.T) {
+		authService := newTestAuthService(t, mt)
+		deviceID := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "login.valid", mtest.FirstBatch, bson.D{
+				{Key: "username", Value: user},
+				{Key: "hashedPassword", Value: string(hashedPassword)},
+			}),
+			mtest.CreateSuccessResponse(),
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{
+				{Key: "_id", Value: deviceID},
+				{Key: "username", Value: user},
+				{Key: "name", Value: "laptop"},
+			}}},
+			mtest.CreateSuccessResponse(),
+		)
+		device := provenTestDevice(t)
+		tokens, err := authService.Login(user, password, "127.0.0.1", &device)
+		require.NoError(t, err)
+		require.Equal(t, deviceID.Hex(), tokens.DeviceID)
+		claims, err := auth.ParseJWTToken(tokens.AccessToken, []byte("my-secret-key"))
+		require.NoError(t, err)
+		require.Equal(t, deviceID.Hex(), claims.DeviceID)
+	})
+	mt.Run("bad device", func(mt *mtest.T) {
+		store := NewMemoryAttemptStore()
+		authService := newTestAuthServiceWithLockout(t, mt, NewLockoutService(store, testLockoutPolicy))
+		device := testDevice
+		device.PublicKey = "key"
+		_, err := authService.Login(user, password, "127.0.0.1", &device)
+		require.ErrorIs(t, err, errors.ErrBadDevice)
+		// the password is not checked
+		require.Nil(t, mt.GetStartedEvent())
+	})
+	mt.Run("second factor", func(mt *mtest.T) {
+		authService := newTestAuthService(t, mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "login.valid", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: models.NewRandomObjectID()},
+			{Key: "username", Value: user},
+			{Key: "hashedPassword", Value: string(hashedPassword)},
+			{Key: "mfa", Value: bson.D{{Key: "secret", Value: "secret"}, {Key: "enabled", Value: true}}},
+		}))
+		tokens, err := authService.Login(user, password, "127.0.0.1", nil)
+		require.NoError(t, err)
+		require.True(t, tokens.MFARequired)
+		require.NotEmpty(t, tokens.MFAToken)
+		require.Empty(t, tokens.AccessToken)
+		require.Empty(t, tokens.RefreshToken)
 	})
 	mt.Run("invalid credentials", func(mt *mtest.T) {
-		authService := NewAuthService(mt.Coll, "my-secret-key", time.Hour)
+		authService := newTestAuthService(t, mt)
 		mt.AddMockResponses(mtest.CreateCursorResponse(1, "login.valid", mtest.FirstBatch, bson.D{
 			{Key: "_id", Value: models.NewRandomObjectID()},
 			{Key: "username", Value: user},
 			{Key: "hashedPassword", Value: string(hashedPassword)},
 		}))
-		_, err := authService.Login(user, "test-password-2")
-		require.Error(t, err)
+		_, err := authService.Login(user, "test-password-2", "127.0.0.1", nil)
+		require.ErrorIs(t, err, errors.ErrBadCredentials)
 	})
 	mt.Run("invalid credentials", func(mt *mtest.T) {
-		authService := NewAuthService(mt.Coll, "my-secret-key", time.Hour)
+		authService := newTestAuthService(t, mt)
 		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
 			Index:   0,
 			Message: "mongo: no documents in result",
 		}))
-		_, err := authService.Login(user, password)
+		_, err := authService.Login(user, password, "127.0.0.1", nil)
 		require.Error(t, err)
 	})
+	mt.Run("unknown user", func(mt *mtest.T) {
+		store := NewMemoryAttemptStore()
+		authService := newTestAuthServiceWithLockout(t, mt, NewLockoutService(store, testLockoutPolicy))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "login.valid", mtest.FirstBatch))
+		_, err := authService.Login("unknown", password, "127.0.0.1", nil)
+		require.ErrorIs(t, err, errors.ErrBadCredentials)
+		attempts, err := store.Get(context.TODO(), "user:unknown")
+		require.NoError(t, err)
+		require.Equal(t, 1, attempts.Failures)
+	})
+	mt.Run("locked", func(mt *mtest.T) {
+		lockout := NewLockoutService(NewMemoryAttemptStore(), testLockoutPolicy)
+		for i := 0; i < testLockoutPolicy.UserFreeAttempts+1; i++ {
+			require.NoError(t, lockout.Fail(context.TODO(), user, "10.0.0.1"))
+		}
+		// the password is not checked while the user is locked
+		_, err := newTestAuthServiceWithLockout(t, mt, lockout).Login(user, password, "127.0.0.1", nil)
+		var locked *errors.LockedError
+		require.ErrorAs(t, err, &locked)
+		require.Equal(t, testLockoutPolicy.Backoff, locked.RetryAfter.Round(time.Minute))
+	})
+}
+
+func (suite *AuthServiceTestSuite) TestLoginMFA() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("bad token", func(mt *mtest.T) {
+		_, err := newTestAuthService(t, mt).LoginMFA("token", "123456", "127.0.0.1", nil)
+		require.ErrorIs(t, err, errors.ErrBadMFAToken)
+	})
+	mt.Run("recovery code", func(mt *mtest.T) {
+		authService := newTestAuthService(t, mt)
+		mfaToken, _, err := auth.NewMFAToken("testuser", []byte("my-secret-key"), time.Minute)
+		require.NoError(t, err)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "login.valid", mtest.FirstBatch, bson.D{
+				{Key: "username", Value: "testuser"},
+				{Key: "mfa", Value: bson.D{{Key: "secret", Value: "secret"}, {Key: "enabled", Value: true}}},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(),
+		)
+		tokens, err := authService.LoginMFA(mfaToken, "abcd-efgh", "127.0.0.1", nil)
+		require.NoError(t, err)
+		require.NotEmpty(t, tokens.AccessToken)
+		require.NotEmpty(t, tokens.RefreshToken)
+	})
+	mt.Run("wrong code", func(mt *mtest.T) {
+		store := NewMemoryAttemptStore()
+		authService := newTestAuthServiceWithLockout(t, mt, NewLockoutService(store, testLockoutPolicy))
+		mfaToken, _, err := auth.NewMFAToken("testuser", []byte("my-secret-key"), time.Minute)
+		require.NoError(t, err)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "login.valid", mtest.FirstBatch, bson.D{
+				{Key: "username", Value: "testuser"},
+				{Key: "mfa", Value: bson.D{{Key: "secret", Value: "secret"}, {Key: "enabled", Value: true}}},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+		)
+		_, err = authService.LoginMFA(mfaToken, "abcd-efgh", "127.0.0.1", nil)
+		require.ErrorIs(t, err, errors.ErrBadMFACode)
+		attempts, err := store.Get(context.TODO(), "user:testuser")
+		require.NoError(t, err)
+		require.Equal(t, 1, attempts.Failures)
+	})
+}
+
+func (suite *AuthServiceTestSuite) TestChangePassword() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("old"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+	userDocument := bson.D{
+		{Key: "username", Value: "testuser"},
+		{Key: "hashedPassword", Value: string(hashedPassword)},
+	}
+	mt.Run("success", func(mt *mtest.T) {
+		sync := NewSyncService()
+		// the stream of the stolen token without a session
+		events, unsubscribe := sync.Subscribe("testuser", "", "", time.Time{})
+		defer unsubscribe()
+		authService := newTestAuthServiceWithSync(
+			t, mt, NewLockoutService(NewMemoryAttemptStore(), testLockoutPolicy), sync,
+		)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "users.find", mtest.FirstBatch, userDocument),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateCursorResponse(0, "sessions.find", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+		tokens, err := authService.ChangePassword("testuser", "old", "new", "127.0.0.1", "")
+		require.NoError(t, err)
+		require.NotEmpty(t, tokens.AccessToken)
+		require.NotEmpty(t, tokens.RefreshToken)
+		_, ok := <-events
+		require.False(t, ok, "the sync subscriptions of the user should be canceled")
+	})
+	mt.Run("wrong password", func(mt *mtest.T) {
+		store := NewMemoryAttemptStore()
+		authService := newTestAuthServiceWithLockout(t, mt, NewLockoutService(store, testLockoutPolicy))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "users.find", mtest.FirstBatch, userDocument))
+		_, err := authService.ChangePassword("testuser", "wrong", "new", "127.0.0.1", "")
+		require.ErrorIs(t, err, errors.ErrBadCredentials)
+		attempts, err := store.Get(context.TODO(), "user:testuser")
+		require.NoError(t, err)
+		require.Equal(t, 1, attempts.Failures)
+	})
+	mt.Run("changed concurrently", func(mt *mtest.T) {
+		authService := newTestAuthService(t, mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "users.find", mtest.FirstBatch, userDocument),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+		)
+		_, err := authService.ChangePassword("testuser", "old", "new", "127.0.0.1", "")
+		require.ErrorIs(t, err, errors.ErrBadCredentials)
+	})
+}
+
+func (suite *AuthServiceTestSuite) TestDeleteAccount() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+	userDocument := bson.D{
+		{Key: "username", Value: "testuser"},
+		{Key: "hashedPassword", Value: string(hashedPassword)},
+	}
+	mt.Run("success", func(mt *mtest.T) {
+		sync := NewSyncService()
+		events, unsubscribe := sync.Subscribe("testuser", "", "", time.Time{})
+		defer unsubscribe()
+		authService := newTestAuthServiceWithSync(
+			t, mt, NewLockoutService(NewMemoryAttemptStore(), testLockoutPolicy), sync,
+		)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "users.find", mtest.FirstBatch, userDocument),
+			mtest.CreateCursorResponse(0, "sessions.find", mtest.FirstBatch),
+			// the devices
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateCursorResponse(0, "blobs.find", mtest.FirstBatch),
+		)
+		// the records and the history of every collection, the blobs, the chunks and the user
+		deletes := 2*len(models.AllowedCollectionNames) + 3
+		for i := 0; i < deletes; i++ {
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		}
+		err := authService.DeleteAccount("testuser", "password", "127.0.0.1")
+		require.NoError(t, err)
+		// the user is deleted last
+		last := mt.GetStartedEvent()
+		for e := mt.GetStartedEvent(); e != nil; e = mt.GetStartedEvent() {
+			last = e
+		}
+		require.Equal(t, "delete", last.CommandName)
+		require.Equal(t, UsersCollection, last.Command.Lookup("delete").StringValue())
+		_, ok := <-events
+		require.False(t, ok, "the sync subscriptions of the user should be canceled")
+	})
+	mt.Run("wrong password", func(mt *mtest.T) {
+		authService := newTestAuthService(t, mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "users.find", mtest.FirstBatch, userDocument))
+		err := authService.DeleteAccount("testuser", "wrong", "127.0.0.1")
+		require.ErrorIs(t, err, errors.ErrBadCredentials)
+		// nothing is deleted
+		for e := mt.GetStartedEvent(); e != nil; e = mt.GetStartedEvent() {
+			require.NotEqual(t, "delete", e.CommandName)
+		}
+	})
 }
 
 func TestAuthServiceTestSuite(t *testing.T) {
@@ -0,0 +1,747 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+// BackupFormat and BackupVersion identify the format of the backups.
+const (
+	BackupFormat  = "gophkeeper-backup"
+	BackupVersion = 1
+)
+
+const (
+	backupHeaderFile   = "header.json"
+	backupManifestFile = "manifest.enc"
+	// backupFrameSize is the size of the documents encrypted together.
+	backupFrameSize = 1 << 20
+	// maxBackupFrameSize fits a frame ending with the largest BSON document.
+	maxBackupFrameSize = backupFrameSize + 16<<20 + encrypt.SealOverhead
+	// restoreBatchSize is the number of documents inserted at once.
+	restoreBatchSize = 100
+)
+
+// backupKeyCheck is the known value encrypted as the key check of the backup.
+var backupKeyCheck = []byte("gophkeeper backup key check")
+
+// newBackupKDFParams returns the parameters of the key derivation of a new backup.
+var newBackupKDFParams = encrypt.NewKDFParams
+
+// BackupHeader is the unencrypted header of the backup.
+type BackupHeader struct {
+	Format    string             `json:"format"`
+	Version   int                `json:"version"`
+	CreatedAt time.Time          `json:"created_at"`
+	Username  string             `json:"username,omitempty"` // Username is empty in the backup of the whole database.
+	Key       models.VaultParams `json:"key"`                // Key holds the parameters of the key derivation and the key check.
+}
+
+// BackupSection describes the documents of a collection saved in the backup.
+// The checksum is computed over the decrypted documents.
+type BackupSection struct {
+	Name      string `json:"name"`
+	File      string `json:"file"`
+	Documents int64  `json:"documents"`
+	SHA256    string `json:"sha256"`
+}
+
+// BackupManifest lists the sections of the backup.
+type BackupManifest struct {
+	Sections []BackupSection `json:"sections"`
+}
+
+// RestoreOptions are the options of the restore of a backup.
+type RestoreOptions struct {
+	Username  string // Username restores only the data of the user; empty restores all the users of the backup.
+	Overwrite bool   // Overwrite replaces the data of the users which already exist.
+	DryRun    bool   // DryRun checks the backup and the existing users without writing anything.
+}
+
+// BackupService is an interface for backing up and restoring the data of the users.
+type BackupService interface {
+	// Backup writes the encrypted backup of the data of the user or of all
+	// the users if the username is empty.
+	Backup(ctx context.Context, w io.Writer, username, password string) (*BackupManifest, error)
+	// Restore checks the integrity of the whole backup and then writes its data.
+	// The restored sections are returned.
+	Restore(
+		ctx context.Context,
+		r io.ReaderAt,
+		size int64,
+		password string,
+		opts RestoreOptions,
+	) ([]BackupSection, error)
+}
+
+// backupKind is the way the documents of a collection are saved in the backup.
+type backupKind int
+
+const (
+	backupPlain   backupKind = iota // the documents are saved as they are
+	backupRecord                    // the data is decrypted, the search tokens are rebuilt on restore
+	backupHistory                   // the data is decrypted
+	backupChunk                     // the content is decrypted
+	backupUser                      // the secret of the second factor is decrypted
+)
+
+// backupSource is a collection saved in the backup.
+type backupSource struct {
+	name       string
+	kind       backupKind
+	collection models.CollectionName // collection is the records collection of the records and their history.
+}
+
+// backupSources returns the collections saved in the backup. The blobs
+// go before their chunks, so the chunks of the user's blobs can be found.
+func backupSources() []backupSource {
+	sources := []backupSource{{name: UsersCollection, kind: backupUser}}
+	for _, c := range models.AllowedCollectionNames {
+		sources = append(
+			sources,
+			backupSource{name: string(c), kind: backupRecord, collection: c},
+			backupSource{name: string(HistoryCollectionName(c)), kind: backupHistory, collection: c},
+		)
+	}
+	return append(
+		sources,
+		backupSource{name: BlobsCollection, kind: backupPlain},
+		backupSource{name: BlobChunksCollection, kind: backupChunk},
+	)
+}
+
+// backupService is the implementation of the BackupService interface. The data
+// is decrypted with the keyring of the server, so the backup depends only on
+// its password and can be restored on a server with other keys.
+type backupService struct {
+	storage  *storageService
+	snapshot bool
+}
+
+// NewBackupService creates a new instance of the BackupService. The restored
+// data is encrypted with the active key of the keyring and indexed with the
+// index. With snapshot all the collections are read from the same snapshot
+// of the database, which needs a replica set.
+func NewBackupService(
+	db *mongo.Database,
+	keyring *encrypt.Keyring,
+	index *encrypt.BlindIndex,
+	snapshot bool,
+) BackupService {
+	return &backupService{
+		storage:  &storageService{db: db, keyring: keyring, index: index},
+		snapshot: snapshot,
+	}
+}
+
+// Backup writes the encrypted backup of the data of the user or of all the users.
+func (s *backupService) Backup(
+	ctx context.Context,
+	w io.Writer,
+	username, password string,
+) (*BackupManifest, error) {
+	params, err := newBackupKDFParams()
+	if err != nil {
+		return nil, err
+	}
+	key, err := encrypt.DeriveKey(password, params)
+	if err != nil {
+		return nil, err
+	}
+	keyCheck, err := encrypt.SealBytes(backupKeyCheck, key)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.MarshalIndent(BackupHeader{
+		Format:    BackupFormat,
+		Version:   BackupVersion,
+		CreatedAt: time.Now().UTC(),
+		Username:  username,
+		Key: models.VaultParams{
+			KDF:      models.KDFArgon2id,
+			Salt:     params.Salt,
+			Time:     params.Time,
+			Memory:   params.Memory,
+			Threads:  params.Threads,
+			KeyCheck: keyCheck,
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	zw := zip.NewWriter(w)
+	f, err := zw.Create(backupHeaderFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(header); err != nil {
+		return nil, err
+	}
+
+	if s.snapshot {
+		session, err := s.storage.db.Client().StartSession(options.Session().SetSnapshot(true))
+		if err != nil {
+			return nil, err
+		}
+		defer session.EndSession(ctx)
+		ctx = mongo.NewSessionContext(ctx, session)
+	}
+	manifest := &BackupManifest{}
+	blobIDs := make([]models.ObjectID, 0)
+	for _, src := range backupSources() {
+		filter := bson.M{}
+		if username != "" {
+			filter["username"] = username
+			if src.kind == backupChunk {
+				filter = bson.M{"blob_id": bson.M{"$in": blobIDs}}
+			}
+		}
+		section, err := s.writeSection(ctx, zw, key, src, filter, func(doc bson.Raw) {
+			if src.name == BlobsCollection {
+				blobIDs = append(blobIDs, doc.Lookup("_id").ObjectID())
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", src.name, err)
+		}
+		manifest.Sections = append(manifest.Sections, section)
+	}
+
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := encrypt.SealBytes(content, key)
+	if err != nil {
+		return nil, err
+	}
+	if f, err = zw.CreateHeader(&zip.FileHeader{Name: backupManifestFile, Method: zip.Store}); err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(sealed); err != nil {
+		return nil, err
+	}
+	return manifest, zw.Close()
+}
+
+// writeSection writes the documents of the collection which match the filter.
+// saved is called with every saved document before it is decrypted.
+func (s *backupService) writeSection(
+	ctx context.Context,
+	zw *zip.Writer,
+	key []byte,
+	src backupSource,
+	filter bson.M,
+	saved func(doc bson.Raw),
+) (BackupSection, error) {
+	section := BackupSection{Name: src.name, File: "collections/" + src.name}
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: section.File, Method: zip.Store})
+	if err != nil {
+		return section, err
+	}
+	fw := &frameWriter{w: f, key: key, hash: sha256.New()}
+	cur, err := s.storage.db.Collection(src.name).Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return section, err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		doc, err := s.exportDocument(src, cur.Current)
+		if err != nil {
+			return section, err
+		}
+		if err := fw.write(doc); err != nil {
+			return section, err
+		}
+		saved(cur.Current)
+		section.Documents++
+	}
+	if err := cur.Err(); err != nil {
+		return section, err
+	}
+	if err := fw.flush(); err != nil {
+		return section, err
+	}
+	section.SHA256 = hex.EncodeToString(fw.hash.Sum(nil))
+	return section, nil
+}
+
+// exportDocument returns the document with the data decrypted.
+func (s *backupService) exportDocument(src backupSource, raw bson.Raw) ([]byte, error) {
+	switch src.kind {
+	case backupRecord, backupHistory:
+		var doc bson.D
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		exported := make(bson.D, 0, len(doc))
+		for _, e := range doc {
+			if e.Key == searchTokensField {
+				continue
+			}
+			// the purged records have no data
+			if e.Key == "data" && e.Value != nil {
+				data, err := decryptData(s.storage.keyring, e.Value)
+				if err != nil {
+					return nil, err
+				}
+				e.Value = data
+			}
+			exported = append(exported, e)
+		}
+		return bson.Marshal(exported)
+	case backupChunk:
+		var chunk models.BlobChunk
+		if err := bson.Unmarshal(raw, &chunk); err != nil {
+			return nil, err
+		}
+		data, err := s.storage.keyring.DecryptBytesWithAD(
+			chunk.KeyID,
+			chunk.Data,
+			chunkAD(chunk.BlobID, chunk.N),
+		)
+		if err != nil {
+			return nil, err
+		}
+		chunk.KeyID, chunk.Data = "", data
+		return bson.Marshal(chunk)
+	case backupUser:
+		return replaceMFASecret(raw, s.storage.keyring.DecryptString)
+	default:
+		return raw, nil
+	}
+}
+
+// importDocument returns the document with the data encrypted with
+// the active key and the search tokens of the record.
+func (s *backupService) importDocument(src backupSource, raw bson.Raw) (any, error) {
+	switch src.kind {
+	case backupRecord, backupHistory:
+		var (
+			doc     bson.D
+			indexed indexedDocument
+		)
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		if err := bson.Unmarshal(raw, &indexed); err != nil {
+			return nil, err
+		}
+		data := indexed.Data
+		if d, ok := data.(bson.D); ok {
+			data = map[string]any(d.Map())
+		}
+		for i, e := range doc {
+			if e.Key == "data" && data != nil {
+				encryptedData, err := encryptData(s.storage.keyring, data)
+				if err != nil {
+					return nil, err
+				}
+				doc[i].Value = encryptedData
+			}
+		}
+		if src.kind == backupRecord {
+			tokens := s.storage.searchTokens(indexed.Username, src.collection, data, indexed.Metadata)
+			doc = append(doc, bson.E{Key: searchTokensField, Value: tokens})
+		}
+		return doc, nil
+	case backupChunk:
+		var chunk models.BlobChunk
+		if err := bson.Unmarshal(raw, &chunk); err != nil {
+			return nil, err
+		}
+		keyID, encrypted, err := s.storage.keyring.EncryptBytesWithAD(
+			chunk.Data,
+			chunkAD(chunk.BlobID, chunk.N),
+		)
+		if err != nil {
+			return nil, err
+		}
+		chunk.KeyID, chunk.Data = keyID, encrypted
+		return chunk, nil
+	case backupUser:
+		doc, err := replaceMFASecret(raw, s.storage.keyring.EncryptString)
+		return bson.Raw(doc), err
+	default:
+		return raw, nil
+	}
+}
+
+// replaceMFASecret returns the user document with the secret of the second
+// factor replaced with the result of fn. The document without it is returned as it is.
+func replaceMFASecret(raw bson.Raw, fn func(string) (string, error)) ([]byte, error) {
+	if _, err := raw.LookupErr("mfa", "secret"); err != nil {
+		return raw, nil
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	for _, e := range doc {
+		mfa, ok := e.Value.(bson.D)
+		if e.Key != "mfa" || !ok {
+			continue
+		}
+		for i, f := range mfa {
+			if secret, ok := f.Value.(string); ok && f.Key == "secret" {
+				replaced, err := fn(secret)
+				if err != nil {
+					return nil, err
+				}
+				mfa[i].Value = replaced
+			}
+		}
+	}
+	return bson.Marshal(doc)
+}
+
+// Restore checks the integrity of the whole backup and then writes the data of
+// the users. The users which already exist are replaced only with Overwrite.
+func (s *backupService) Restore(
+	ctx context.Context,
+	r io.ReaderAt,
+	size int64,
+	password string,
+	opts RestoreOptions,
+) ([]BackupSection, error) {
+	br, err := openBackup(r, size, password)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Username != "" && br.header.Username != "" && opts.Username != br.header.Username {
+		return nil, fmt.Errorf("the backup has the data of the user %q only", br.header.Username)
+	}
+	usernames := make(map[string]bool)
+	for _, section := range br.manifest.Sections {
+		err := br.readSection(section, func(doc bson.Raw) error {
+			if section.Name != UsersCollection {
+				return nil
+			}
+			username, _ := doc.Lookup("username").StringValueOK()
+			if opts.Username == "" || opts.Username == username {
+				usernames[username] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Username != "" && !usernames[opts.Username] {
+		return nil, fmt.Errorf("the user %q is not in the backup", opts.Username)
+	}
+	existing, err := s.existingUsers(ctx, usernames)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 && !opts.Overwrite {
+		return nil, fmt.Errorf("%w: %v", srvErrors.ErrUsernameIsTaken, strings.Join(existing, ", "))
+	}
+	if opts.DryRun {
+		return br.manifest.Sections, nil
+	}
+	for _, username := range existing {
+		if err := s.deleteUser(ctx, username); err != nil {
+			return nil, err
+		}
+	}
+
+	restored := make([]BackupSection, 0, len(br.manifest.Sections))
+	blobIDs := make(map[models.ObjectID]bool)
+	for _, section := range br.manifest.Sections {
+		src, ok := backupSourceByName(section.Name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown collection %v", srvErrors.ErrCorruptedBackup, section.Name)
+		}
+		collection := s.storage.db.Collection(src.name)
+		done := BackupSection{Name: section.Name, File: section.File, SHA256: section.SHA256}
+		batch := make([]any, 0, restoreBatchSize)
+		insert := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			insertCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+			_, err := collection.InsertMany(insertCtx, batch)
+			batch = batch[:0]
+			return err
+		}
+		err := br.readSection(section, func(doc bson.Raw) error {
+			if src.kind == backupChunk {
+				if !blobIDs[doc.Lookup("blob_id").ObjectID()] {
+					return nil
+				}
+			} else {
+				username, _ := doc.Lookup("username").StringValueOK()
+				if !usernames[username] {
+					return nil
+				}
+				if src.name == BlobsCollection {
+					blobIDs[doc.Lookup("_id").ObjectID()] = true
+				}
+			}
+			imported, err := s.importDocument(src, doc)
+			if err != nil {
+				return err
+			}
+			batch = append(batch, imported)
+			done.Documents++
+			if len(batch) == restoreBatchSize {
+				return insert()
+			}
+			return nil
+		})
+		if err == nil {
+			err = insert()
+		}
+		if err != nil {
+			return restored, fmt.Errorf("%v: %w", section.Name, err)
+		}
+		restored = append(restored, done)
+	}
+	return restored, nil
+}
+
+// backupSourceByName returns the collection of the section of the backup.
+func backupSourceByName(name string) (backupSource, bool) {
+	for _, src := range backupSources() {
+		if src.name == name {
+			return src, true
+		}
+	}
+	return backupSource{}, false
+}
+
+// existingUsers returns the sorted names of the users which already exist.
+func (s *backupService) existingUsers(
+	ctx context.Context,
+	usernames map[string]bool,
+) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	names := make([]string, 0, len(usernames))
+	for username := range usernames {
+		names = append(names, username)
+	}
+	cur, err := s.storage.db.Collection(UsersCollection).Find(
+		ctx,
+		bson.M{"username": bson.M{"$in": names}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	var users []models.User
+	if err := cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	existing := make([]string, 0, len(users))
+	for _, u := range users {
+		existing = append(existing, u.Username)
+	}
+	sort.Strings(existing)
+	return existing, nil
+}
+
+// deleteUser deletes the user and all the data of the user.
+func (s *backupService) deleteUser(ctx context.Context, username string) error {
+	return deleteUserData(ctx, s.storage.db, username)
+}
+
+// backupReader reads the backup.
+type backupReader struct {
+	zr       *zip.Reader
+	key      []byte
+	header   BackupHeader
+	manifest BackupManifest
+}
+
+// openBackup reads the header and the manifest of the backup.
+// ErrWrongBackupPassword is returned if the password is wrong.
+func openBackup(r io.ReaderAt, size int64, password string) (*backupReader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", srvErrors.ErrCorruptedBackup, err)
+	}
+	br := &backupReader{zr: zr}
+	header, err := br.readFile(backupHeaderFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(header, &br.header); err != nil {
+		return nil, fmt.Errorf("%w: %v", srvErrors.ErrCorruptedBackup, err)
+	}
+	if br.header.Format != BackupFormat {
+		return nil, fmt.Errorf("%w: not a %v", srvErrors.ErrCorruptedBackup, BackupFormat)
+	}
+	if br.header.Version != BackupVersion {
+		return nil, fmt.Errorf("unsupported backup version: %v", br.header.Version)
+	}
+	params := br.header.Key
+	if params.KDF != models.KDFArgon2id {
+		return nil, fmt.Errorf("unsupported key derivation function: %v", params.KDF)
+	}
+	br.key, err = encrypt.DeriveKey(password, encrypt.KDFParams{
+		Salt:    params.Salt,
+		Time:    params.Time,
+		Memory:  params.Memory,
+		Threads: params.Threads,
+	})
+	if err != nil {
+		return nil, err
+	}
+	check, err := encrypt.OpenBytes(params.KeyCheck, br.key)
+	if err != nil || !bytes.Equal(check, backupKeyCheck) {
+		return nil, srvErrors.ErrWrongBackupPassword
+	}
+	sealed, err := br.readFile(backupManifestFile)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := encrypt.OpenBytes(sealed, br.key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v: %v", srvErrors.ErrCorruptedBackup, backupManifestFile, err)
+	}
+	if err := json.Unmarshal(manifest, &br.manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", srvErrors.ErrCorruptedBackup, err)
+	}
+	return br, nil
+}
+
+// readFile returns the content of the file of the backup.
+func (br *backupReader) readFile(name string) ([]byte, error) {
+	f, err := br.zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: no %v", srvErrors.ErrCorruptedBackup, name)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// readSection calls fn with every decrypted document of the section and checks
+// the number of the documents and their checksum.
+func (br *backupReader) readSection(section BackupSection, fn func(doc bson.Raw) error) error {
+	f, err := br.zr.Open(section.File)
+	if err != nil {
+		return fmt.Errorf("%w: no %v", srvErrors.ErrCorruptedBackup, section.File)
+	}
+	defer f.Close()
+	corrupted := func(err any) error {
+		return fmt.Errorf("%w: %v: %v", srvErrors.ErrCorruptedBackup, section.File, err)
+	}
+	h := sha256.New()
+	var documents int64
+	for {
+		var length [4]byte
+		if _, err := io.ReadFull(f, length[:]); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return corrupted(err)
+		}
+		n := binary.BigEndian.Uint32(length[:])
+		if n > maxBackupFrameSize {
+			return corrupted("bad frame")
+		}
+		sealed := make([]byte, n)
+		if _, err := io.ReadFull(f, sealed); err != nil {
+			return corrupted(err)
+		}
+		frame, err := encrypt.OpenBytes(sealed, br.key)
+		if err != nil {
+			return corrupted(err)
+		}
+		h.Write(frame)
+		for len(frame) > 0 {
+			doc, rest, ok := splitDocument(frame)
+			if !ok {
+				return corrupted("bad document")
+			}
+			if err := fn(doc); err != nil {
+				return err
+			}
+			documents++
+			frame = rest
+		}
+	}
+	if documents != section.Documents || hex.EncodeToString(h.Sum(nil)) != section.SHA256 {
+		return corrupted("bad checksum")
+	}
+	return nil
+}
+
+// splitDocument splits the first BSON document from the data.
+func splitDocument(data []byte) (bson.Raw, []byte, bool) {
+	if len(data) < 5 {
+		return nil, nil, false
+	}
+	n := int(binary.LittleEndian.Uint32(data))
+	if n < 5 || n > len(data) {
+		return nil, nil, false
+	}
+	doc := bson.Raw(data[:n])
+	if doc.Validate() != nil {
+		return nil, nil, false
+	}
+	return doc, data[n:], true
+}
+
+// frameWriter encrypts the documents in frames of about backupFrameSize.
+// Every frame is prefixed with the big-endian uint32 length of the sealed frame.
+type frameWriter struct {
+	w    io.Writer
+	key  []byte
+	buf  []byte
+	hash hash.Hash
+}
+
+// write adds the document to the frame.
+func (fw *frameWriter) write(doc []byte) error {
+	fw.buf = append(fw.buf, doc...)
+	if len(fw.buf) >= backupFrameSize {
+		return fw.flush()
+	}
+	return nil
+}
+
+// flush encrypts and writes the frame.
+func (fw *frameWriter) flush() error {
+	if len(fw.buf) == 0 {
+		return nil
+	}
+	fw.hash.Write(fw.buf)
+	sealed, err := encrypt.SealBytes(fw.buf, fw.key)
+	if err != nil {
+		return err
+	}
+	fw.buf = fw.buf[:0]
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(sealed)))
+	if _, err := fw.w.Write(length[:]); err != nil {
+		return err
+	}
+	_, err = fw.w.Write(sealed)
+	return err
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+type BackupServiceTestSuite struct {
+	suite.Suite
+	oldKeyring *encrypt.Keyring
+	newKeyring *encrypt.Keyring
+}
+
+func (suite *BackupServiceTestSuite) SetupSuite() {
+	t := suite.T()
+	suite.oldKeyring = newTestKeyring(t, "old-key")
+	suite.newKeyring = newTestKeyring(t, "new-key")
+	// the tests don't need the memory-hard key derivation
+	newBackupKDFParams = func() (encrypt.KDFParams, error) {
+		params, err := encrypt.NewKDFParams()
+		params.Time, params.Memory, params.Threads = 1, 64, 1
+		return params, err
+	}
+}
+
+func (suite *BackupServiceTestSuite) TearDownSuite() {
+	newBackupKDFParams = encrypt.NewKDFParams
+}
+
+// backupDocuments returns the documents of alice encrypted with the old keyring.
+func (suite *BackupServiceTestSuite) backupDocuments() map[string][]bson.D {
+	t := suite.T()
+	noteID, blobID := models.NewRandomObjectID(), models.NewRandomObjectID()
+	note, err := suite.oldKeyring.EncryptString("note")
+	require.NoError(t, err)
+	oldNote, err := suite.oldKeyring.EncryptString("old note")
+	require.NoError(t, err)
+	credential, err := suite.oldKeyring.EncryptMap(map[string]any{"Login": "alice", "Password": "secret"})
+	require.NoError(t, err)
+	keyID, chunk, err := suite.oldKeyring.EncryptBytesWithAD([]byte("hello"), chunkAD(blobID, 0))
+	require.NoError(t, err)
+	mfaSecret, err := suite.oldKeyring.EncryptString("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	return map[string][]bson.D{
+		UsersCollection: {{
+			{Key: "_id", Value: models.NewRandomObjectID()},
+			{Key: "username", Value: "alice"},
+			{Key: "hashedPassword", Value: "hash"},
+			{Key: "mfa", Value: bson.D{{Key: "secret", Value: mfaSecret}, {Key: "enabled", Value: true}}},
+		}},
+		string(models.TextCollection): {{
+			{Key: "_id", Value: noteID},
+			{Key: "username", Value: "alice"},
+			{Key: "data", Value: note},
+			{Key: "metadata", Value: bson.D{{Key: "title", Value: "groceries"}}},
+			{Key: searchTokensField, Value: bson.A{"old-token"}},
+			{Key: "version", Value: 2},
+		}},
+		string(HistoryCollectionName(models.TextCollection)): {{
+			{Key: "_id", Value: models.NewRandomObjectID()},
+			{Key: "record_id", Value: noteID},
+			{Key: "username", Value: "alice"},
+			{Key: "data", Value: oldNote},
+			{Key: "rev", Value: 1},
+		}},
+		string(models.CredentialsCollection): {{
+			{Key: "_id", Value: models.NewRandomObjectID()},
+			{Key: "username", Value: "alice"},
+			{Key: "data", Value: credential},
+			{Key: "version", Value: 1},
+		}},
+		BlobsCollection: {{
+			{Key: "_id", Value: blobID},
+			{Key: "username", Value: "alice"},
+			{Key: "size", Value: int64(5)},
+			{Key: "received", Value: int64(5)},
+			{Key: "chunks", Value: int64(1)},
+		}},
+		BlobChunksCollection: {{
+			{Key: "_id", Value: models.NewRandomObjectID()},
+			{Key: "blob_id", Value: blobID},
+			{Key: "n", Value: int64(0)},
+			{Key: "key_id", Value: keyID},
+			{Key: "data", Value: chunk},
+		}},
+	}
+}
+
+// insertedDocuments returns the documents inserted to the collections.
+func insertedDocuments(mt *mtest.T) map[string][]bson.Raw {
+	inserted := make(map[string][]bson.Raw)
+	for _, e := range mt.GetAllStartedEvents() {
+		if e.CommandName != "insert" {
+			continue
+		}
+		collection := e.Command.Lookup("insert").StringValue()
+		values, err := e.Command.Lookup("documents").Array().Values()
+		require.NoError(mt, err)
+		for _, v := range values {
+			inserted[collection] = append(inserted[collection], v.Document())
+		}
+	}
+	return inserted
+}
+
+// countCommands returns the number of the started commands with the name.
+func countCommands(mt *mtest.T, name string) int {
+	n := 0
+	for _, e := range mt.GetAllStartedEvents() {
+		if e.CommandName == name {
+			n++
+		}
+	}
+	return n
+}
+
+func (suite *BackupServiceTestSuite) TestBackupRestore() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	var backup []byte
+	mt.Run("backup", func(mt *mtest.T) {
+		backupService := NewBackupService(mt.DB, suite.oldKeyring, newTestIndex(t), false)
+		docs := suite.backupDocuments()
+		for _, src := range backupSources() {
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, mt.DB.Name()+"."+src.name, mtest.FirstBatch, docs[src.name]...),
+			)
+		}
+		var buf bytes.Buffer
+		manifest, err := backupService.Backup(context.TODO(), &buf, "alice", "pass")
+		require.NoError(t, err)
+		require.Len(t, manifest.Sections, len(backupSources()))
+		assert.Equal(t, UsersCollection, manifest.Sections[0].Name)
+		assert.Equal(t, int64(1), manifest.Sections[0].Documents)
+		backup = buf.Bytes()
+		assert.NotContains(t, string(backup), "groceries")
+		assert.NotContains(t, string(backup), "JBSWY3DPEHPK3PXP")
+	})
+	restore := func(mt *mtest.T, data []byte, password string, opts RestoreOptions) ([]BackupSection, error) {
+		backupService := NewBackupService(mt.DB, suite.newKeyring, newTestIndex(t), false)
+		return backupService.Restore(
+			context.TODO(),
+			bytes.NewReader(data),
+			int64(len(data)),
+			password,
+			opts,
+		)
+	}
+	usersResponse := func(mt *mtest.T, usernames ...string) bson.D {
+		docs := make([]bson.D, 0, len(usernames))
+		for _, u := range usernames {
+			docs = append(docs, bson.D{{Key: "username", Value: u}})
+		}
+		return mtest.CreateCursorResponse(0, mt.DB.Name()+"."+UsersCollection, mtest.FirstBatch, docs...)
+	}
+	mt.Run("restore", func(mt *mtest.T) {
+		mt.AddMockResponses(usersResponse(mt))
+		for i := 0; i < 6; i++ {
+			mt.AddMockResponses(mtest.CreateSuccessResponse())
+		}
+		sections, err := restore(mt, backup, "pass", RestoreOptions{})
+		require.NoError(t, err)
+		require.Len(t, sections, len(backupSources()))
+
+		inserted := insertedDocuments(mt)
+		require.Len(t, inserted[UsersCollection], 1)
+		assert.Equal(t, "hash", inserted[UsersCollection][0].Lookup("hashedPassword").StringValue())
+		mfaSecret, err := suite.newKeyring.DecryptString(
+			inserted[UsersCollection][0].Lookup("mfa", "secret").StringValue(),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", mfaSecret)
+
+		require.Len(t, inserted[string(models.TextCollection)], 1)
+		note := inserted[string(models.TextCollection)][0]
+		data, err := suite.newKeyring.DecryptString(note.Lookup("data").StringValue())
+		require.NoError(t, err)
+		assert.Equal(t, "note", data)
+		tokens, err := note.Lookup(searchTokensField).Array().Values()
+		require.NoError(t, err)
+		assert.NotEmpty(t, tokens)
+		assert.NotContains(t, note.Lookup(searchTokensField).String(), "old-token")
+
+		history := inserted[string(HistoryCollectionName(models.TextCollection))]
+		require.Len(t, history, 1)
+		data, err = suite.newKeyring.DecryptString(history[0].Lookup("data").StringValue())
+		require.NoError(t, err)
+		assert.Equal(t, "old note", data)
+		_, err = history[0].LookupErr(searchTokensField)
+		assert.Error(t, err)
+
+		credentials := inserted[string(models.CredentialsCollection)]
+		require.Len(t, credentials, 1)
+		var encrypted bson.D
+		require.NoError(t, bson.Unmarshal(credentials[0].Lookup("data").Document(), &encrypted))
+		credential, err := suite.newKeyring.DecryptMap(encrypted.Map())
+		require.NoError(t, err)
+		assert.Equal(t, "secret", credential["Password"])
+
+		require.Len(t, inserted[BlobChunksCollection], 1)
+		var chunk models.BlobChunk
+		require.NoError(t, bson.Unmarshal(inserted[BlobChunksCollection][0], &chunk))
+		content, err := suite.newKeyring.DecryptBytesWithAD(
+			chunk.KeyID,
+			chunk.Data,
+			chunkAD(chunk.BlobID, chunk.N),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(content))
+	})
+	mt.Run("user_exists", func(mt *mtest.T) {
+		mt.AddMockResponses(usersResponse(mt, "alice"))
+		_, err := restore(mt, backup, "pass", RestoreOptions{})
+		assert.ErrorIs(t, err, errors.ErrUsernameIsTaken)
+		assert.Zero(t, countCommands(mt, "insert"))
+	})
+	mt.Run("overwrite", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			usersResponse(mt, "alice"),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+"."+BlobsCollection, mtest.FirstBatch),
+		)
+		for range backupSources() {
+			mt.AddMockResponses(mtest.CreateSuccessResponse())
+		}
+		for i := 0; i < 6; i++ {
+			mt.AddMockResponses(mtest.CreateSuccessResponse())
+		}
+		_, err := restore(mt, backup, "pass", RestoreOptions{Overwrite: true})
+		require.NoError(t, err)
+		assert.Equal(t, len(backupSources()), countCommands(mt, "delete"))
+		assert.Equal(t, 6, countCommands(mt, "insert"))
+	})
+	mt.Run("dry_run", func(mt *mtest.T) {
+		mt.AddMockResponses(usersResponse(mt))
+		_, err := restore(mt, backup, "pass", RestoreOptions{DryRun: true})
+		require.NoError(t, err)
+		assert.Zero(t, countCommands(mt, "insert"))
+	})
+	mt.Run("another_user", func(mt *mtest.T) {
+		_, err := restore(mt, backup, "pass", RestoreOptions{Username: "bob"})
+		assert.Error(t, err)
+	})
+	mt.Run("wrong_password", func(mt *mtest.T) {
+		_, err := restore(mt, backup, "wrong", RestoreOptions{})
+		assert.ErrorIs(t, err, errors.ErrWrongBackupPassword)
+	})
+	mt.Run("corrupted", func(mt *mtest.T) {
+		zr, err := zip.NewReader(bytes.NewReader(backup), int64(len(backup)))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			if f.Name == "collections/"+string(models.CredentialsCollection) {
+				content[len(content)-1] ^= 1
+			}
+			fw, err := zw.Create(f.Name)
+			require.NoError(t, err)
+			_, err = fw.Write(content)
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+
+		_, err = restore(mt, buf.Bytes(), "pass", RestoreOptions{})
+		assert.ErrorIs(t, err, errors.ErrCorruptedBackup)
+		assert.Zero(t, countCommands(mt, "insert"))
+	})
+}
+
+func TestBackupServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(BackupServiceTestSuite))
+}
@@ -0,0 +1,471 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+// BlobsCollection and BlobChunksCollection are the collections which keep
+// the state of the blob uploads and the encrypted chunks of the blobs.
+const (
+	BlobsCollection      = "blobs"
+	BlobChunksCollection = "blob_chunks"
+)
+
+// BlobService is an interface that defines the methods to upload the content
+// of the binary records in chunks and to read it back.
+type BlobService interface {
+	// Create starts an upload of the blob of the size.
+	Create(ctx context.Context, username string, size int64) (*models.Blob, error)
+	// Get returns the state of the upload of the blob.
+	Get(ctx context.Context, username string, id models.ObjectID) (*models.Blob, error)
+	// Append encrypts the chunk and appends it to the content of the blob.
+	// The chunk must start at the offset where the uploaded content ends.
+	Append(
+		ctx context.Context,
+		username string,
+		id models.ObjectID,
+		offset int64,
+		chunk []byte,
+	) (*models.Blob, error)
+	// Read writes the decrypted content of the uploaded blob to w chunk by chunk.
+	Read(ctx context.Context, username string, id models.ObjectID, w io.Writer) error
+	// PurgeIncomplete deletes the uploads started before the time which are not
+	// complete yet together with their chunks. The number of the deleted uploads is returned.
+	PurgeIncomplete(ctx context.Context, startedBefore time.Time) (int64, error)
+	// PurgeReleased deletes the blobs released before the time which neither
+	// the record nor its revisions refer to anymore together with their chunks.
+	// The number of the deleted blobs is returned.
+	PurgeReleased(ctx context.Context, releasedBefore time.Time) (int64, error)
+	// EnsureIndexes creates the index of the chunks.
+	EnsureIndexes(ctx context.Context) error
+}
+
+// blobService is an implementation of the BlobService interface. The chunks
+// are kept in a separate collection, so a blob is not limited by the size
+// of a document, and each chunk is encrypted on its own.
+type blobService struct {
+	blobs   *mongo.Collection
+	chunks  *mongo.Collection
+	keyring *encrypt.Keyring
+}
+
+// NewBlobService creates a new instance of the BlobService. The chunks are
+// encrypted with the active key of the keyring.
+func NewBlobService(db *mongo.Database, keyring *encrypt.Keyring) BlobService {
+	return &blobService{
+		blobs:   db.Collection(BlobsCollection),
+		chunks:  db.Collection(BlobChunksCollection),
+		keyring: keyring,
+	}
+}
+
+// chunkAD returns the additional data which binds the encrypted chunk to its blob
+// and to its number, so the chunks can't be reordered or moved to another blob.
+func chunkAD(blobID models.ObjectID, n int64) []byte {
+	return []byte(blobID.Hex() + "|" + strconv.FormatInt(n, 10))
+}
+
+// Create starts an upload of the blob of the size.
+func (s *blobService) Create(
+	ctx context.Context,
+	username string,
+	size int64,
+) (*models.Blob, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if size < 0 {
+		return nil, fmt.Errorf("bad blob size %v", size)
+	}
+	blob := models.Blob{
+		BlobID:    models.NewRandomObjectID(),
+		Username:  username,
+		Size:      size,
+		CreatedAt: time.Now().UTC(),
+	}
+	if _, err := s.blobs.InsertOne(ctx, blob); err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// Get returns the state of the upload of the blob owned by the user.
+func (s *blobService) Get(
+	ctx context.Context,
+	username string,
+	id models.ObjectID,
+) (*models.Blob, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	var blob models.Blob
+	err := s.blobs.FindOne(ctx, bson.M{"_id": id, "username": username}).Decode(&blob)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.ErrBlobNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// Append encrypts the chunk and appends it to the content of the blob.
+// If the chunk doesn't start at the end of the uploaded content, the current
+// state of the blob is returned with ErrBadChunkOffset, so the client can
+// resume the upload. The chunk is saved before the state is updated, so
+// a chunk left by an interrupted request is overwritten by the retry.
+func (s *blobService) Append(
+	ctx context.Context,
+	username string,
+	id models.ObjectID,
+	offset int64,
+	chunk []byte,
+) (*models.Blob, error) {
+	if len(chunk) == 0 || len(chunk) > models.BlobChunkSize {
+		return nil, fmt.Errorf("%w: %v", errors.ErrBadChunkSize, len(chunk))
+	}
+	blob, err := s.Get(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != blob.Received {
+		return blob, errors.ErrBadChunkOffset
+	}
+	if offset+int64(len(chunk)) > blob.Size {
+		return nil, fmt.Errorf("%w: the blob has %v bytes", errors.ErrBadChunkSize, blob.Size)
+	}
+
+	keyID, encrypted, err := s.keyring.EncryptBytesWithAD(chunk, chunkAD(id, blob.Chunks))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, err = s.chunks.ReplaceOne(
+		ctx,
+		bson.M{"blob_id": id, "n": blob.Chunks},
+		models.BlobChunk{BlobID: id, N: blob.Chunks, KeyID: keyID, Data: encrypted},
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated models.Blob
+	err = s.blobs.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id, "username": username, "received": offset},
+		bson.M{"$inc": bson.M{"received": len(chunk), "chunks": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		// another request has appended a chunk at the same offset
+		current, err := s.Get(ctx, username, id)
+		if err != nil {
+			return nil, err
+		}
+		return current, errors.ErrBadChunkOffset
+	} else if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Read writes the decrypted content of the blob to w chunk by chunk.
+// Returns ErrBlobIncomplete if the content is not uploaded yet.
+func (s *blobService) Read(
+	ctx context.Context,
+	username string,
+	id models.ObjectID,
+	w io.Writer,
+) error {
+	blob, err := s.Get(ctx, username, id)
+	if err != nil {
+		return err
+	}
+	if !blob.Complete() {
+		return errors.ErrBlobIncomplete
+	}
+	cur, err := s.chunks.Find(
+		ctx,
+		bson.M{"blob_id": id, "n": bson.M{"$lt": blob.Chunks}},
+		options.Find().SetSort(bson.D{{Key: "n", Value: 1}}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	var n int64
+	for ; cur.Next(ctx); n++ {
+		var chunk models.BlobChunk
+		if err := cur.Decode(&chunk); err != nil {
+			return err
+		}
+		if chunk.N != n {
+			return fmt.Errorf("chunk %v of the blob %v is missing", n, id.Hex())
+		}
+		data, err := s.keyring.DecryptBytesWithAD(chunk.KeyID, chunk.Data, chunkAD(id, n))
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	if n != blob.Chunks {
+		return fmt.Errorf("chunk %v of the blob %v is missing", n, id.Hex())
+	}
+	return nil
+}
+
+// PurgeIncomplete deletes the uploads of all the users started before the time
+// which are not complete yet. The chunks are deleted first, so the uploads
+// which failed to be deleted are purged next time.
+func (s *blobService) PurgeIncomplete(ctx context.Context, startedBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	filter := bson.M{
+		"created_at": bson.M{"$lt": startedBefore},
+		"$expr":      bson.M{"$lt": bson.A{"$received", "$size"}},
+	}
+	ids, err := s.blobs.Distinct(ctx, "_id", filter)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	if _, err := s.chunks.DeleteMany(ctx, bson.M{"blob_id": bson.M{"$in": ids}}); err != nil {
+		return 0, err
+	}
+	filter["_id"] = bson.M{"$in": ids}
+	res, err := s.blobs.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// PurgeReleased deletes the blobs of all the users released before the time
+// which are not referred to by the record which claimed them or by its revisions.
+// The released blobs which a revision still refers to are kept until the
+// revision is removed from the history.
+func (s *blobService) PurgeReleased(ctx context.Context, releasedBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	released := bson.M{"released_at": bson.M{"$lt": releasedBefore}}
+	cur, err := s.blobs.Find(
+		ctx,
+		released,
+		options.Find().SetProjection(bson.M{"_id": 1, "record_id": 1}),
+	)
+	if err != nil {
+		return 0, err
+	}
+	var candidates []models.Blob
+	if err := cur.All(ctx, &candidates); err != nil {
+		return 0, err
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+	recordIDs := make([]any, 0, len(candidates))
+	for _, b := range candidates {
+		recordIDs = append(recordIDs, b.RecordID)
+	}
+	referenced, err := referencedBlobs(ctx, s.blobs.Database(), s.keyring, recordIDs)
+	if err != nil {
+		return 0, err
+	}
+	inUse := make(map[models.ObjectID]bool, len(referenced))
+	for _, b := range referenced {
+		inUse[b.BlobID] = true
+	}
+	ids := make(bson.A, 0, len(candidates))
+	for _, b := range candidates {
+		if !inUse[b.BlobID] {
+			ids = append(ids, b.BlobID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	released["_id"] = bson.M{"$in": ids}
+	return deleteBlobs(ctx, s.blobs.Database(), released)
+}
+
+// EnsureIndexes creates the index which keeps the chunks of a blob in order.
+func (s *blobService) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, err := s.chunks.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "blob_id", Value: 1}, {Key: "n", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// blobID returns the ID of the blob the data of a binary record refers to.
+// The content kept inline and the end-to-end encrypted data have no blob.
+func blobID(data any) (models.ObjectID, bool) {
+	fields, ok := data.(map[string]any)
+	if !ok {
+		return models.ObjectID{}, false
+	}
+	hex, ok := fields["BlobID"].(string)
+	if !ok || hex == "" {
+		return models.ObjectID{}, false
+	}
+	id, err := models.ObjectIDFromString(hex)
+	return id, err == nil
+}
+
+// claimBlob checks that the blob the data of a binary record refers to belongs
+// to the user and is uploaded completely, and claims it for the record, so no
+// other record can refer to it. The blob released by the record is claimed again.
+// Returns ErrBlobNotFound, ErrBlobIncomplete or ErrBlobInUse.
+func claimBlob(
+	ctx context.Context,
+	db *mongo.Database,
+	collectionName models.CollectionName,
+	username string,
+	recordID models.ObjectID,
+	data any,
+) error {
+	if collectionName != models.BinaryCollection {
+		return nil
+	}
+	id, ok := blobID(data)
+	if !ok {
+		return nil
+	}
+	var blob models.Blob
+	err := db.Collection(BlobsCollection).
+		FindOne(ctx, bson.M{"_id": id, "username": username}).
+		Decode(&blob)
+	if err == mongo.ErrNoDocuments {
+		return fmt.Errorf("%w: %v", errors.ErrBlobNotFound, id.Hex())
+	} else if err != nil {
+		return err
+	}
+	if !blob.Complete() {
+		return fmt.Errorf("%w: %v", errors.ErrBlobIncomplete, id.Hex())
+	}
+	if !blob.RecordID.IsZero() && blob.RecordID != recordID {
+		return fmt.Errorf("%w: %v", errors.ErrBlobInUse, id.Hex())
+	}
+	// the filter keeps another record from claiming the blob at the same time
+	res, err := db.Collection(BlobsCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": id, "username": username, "record_id": bson.M{"$in": bson.A{nil, recordID}}},
+		bson.M{"$set": bson.M{"record_id": recordID}, "$unset": bson.M{"released_at": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: %v", errors.ErrBlobInUse, id.Hex())
+	}
+	return nil
+}
+
+// releaseBlobs releases the blobs claimed by the binary record except the one
+// its current data refers to. The revisions of the record may still refer to
+// the released blobs, so they are deleted by PurgeReleased after checking the history.
+func releaseBlobs(
+	ctx context.Context,
+	db *mongo.Database,
+	collectionName models.CollectionName,
+	recordID models.ObjectID,
+	data any,
+) error {
+	if collectionName != models.BinaryCollection {
+		return nil
+	}
+	filter := bson.M{"record_id": recordID, "released_at": bson.M{"$exists": false}}
+	if id, ok := blobID(data); ok {
+		filter["_id"] = bson.M{"$ne": id}
+	}
+	_, err := db.Collection(BlobsCollection).UpdateMany(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"released_at": time.Now().UTC()}},
+	)
+	return err
+}
+
+// referencedBlobs returns the blobs the binary records with the IDs and their
+// revisions refer to. The blobs are identified by their IDs and owners.
+func referencedBlobs(
+	ctx context.Context,
+	db *mongo.Database,
+	keyring *encrypt.Keyring,
+	ids []any,
+) ([]models.Blob, error) {
+	sources := []struct {
+		name   models.CollectionName
+		filter bson.M
+	}{
+		{models.BinaryCollection, bson.M{"_id": bson.M{"$in": ids}}},
+		{HistoryCollectionName(models.BinaryCollection), bson.M{"record_id": bson.M{"$in": ids}}},
+	}
+	blobs := make([]models.Blob, 0)
+	for _, src := range sources {
+		cur, err := db.Collection(string(src.name)).Find(
+			ctx,
+			src.filter,
+			options.Find().SetProjection(bson.M{"username": 1, "data": 1}),
+		)
+		if err != nil {
+			return nil, err
+		}
+		var docs []struct {
+			Username string `bson:"username"`
+			Data     any    `bson:"data"`
+		}
+		if err := cur.All(ctx, &docs); err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			if doc.Data == nil {
+				continue
+			}
+			data, err := decryptData(keyring, doc.Data)
+			if err != nil {
+				return nil, err
+			}
+			if id, ok := blobID(data); ok {
+				blobs = append(blobs, models.Blob{BlobID: id, Username: doc.Username})
+			}
+		}
+	}
+	return blobs, nil
+}
+
+// deleteBlobs deletes the blobs matching the filter together with their chunks.
+// The chunks are deleted first, so the blobs which failed to be deleted are
+// found again by the next attempt. The number of the deleted blobs is returned.
+func deleteBlobs(ctx context.Context, db *mongo.Database, filter bson.M) (int64, error) {
+	ids, err := db.Collection(BlobsCollection).Distinct(ctx, "_id", filter)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	_, err = db.Collection(BlobChunksCollection).DeleteMany(ctx, bson.M{"blob_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Collection(BlobsCollection).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+type BlobServiceTestSuite struct {
+	suite.Suite
+}
+
+func (suite *BlobServiceTestSuite) SetupSuite()    {}
+func (suite *BlobServiceTestSuite) TearDownSuite() {}
+
+// blobDoc returns the state of the upload of a blob as it is kept in the DB.
+func blobDoc(id models.ObjectID, size, received, chunks int64) bson.D {
+	return bson.D{
+		{Key: "_id", Value: id},
+		{Key: "username", Value: "testuser"},
+		{Key: "size", Value: size},
+		{Key: "received", Value: received},
+		{Key: "chunks", Value: chunks},
+		{Key: "created_at", Value: time.Now().UTC()},
+	}
+}
+
+func (suite *BlobServiceTestSuite) TestCreate() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		blob, err := blobService.Create(context.TODO(), "testuser", 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), blob.Size)
+		assert.Equal(t, int64(0), blob.Received)
+		assert.False(t, blob.Complete())
+	})
+	mt.Run("bad_size", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		_, err := blobService.Create(context.TODO(), "testuser", -1)
+		assert.Error(t, err)
+	})
+}
+
+func (suite *BlobServiceTestSuite) TestGet() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		id := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, blobDoc(id, 10, 4, 1)),
+		)
+		blob, err := blobService.Get(context.TODO(), "testuser", id)
+		require.NoError(t, err)
+		assert.Equal(t, id, blob.BlobID)
+		assert.Equal(t, int64(4), blob.Received)
+	})
+	mt.Run("not_found", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch))
+		_, err := blobService.Get(context.TODO(), "testuser", models.NewRandomObjectID())
+		assert.ErrorIs(t, err, errors.ErrBlobNotFound)
+	})
+}
+
+func (suite *BlobServiceTestSuite) TestAppend() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		id := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, blobDoc(id, 10, 4, 1)),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: blobDoc(id, 10, 10, 2)}},
+		)
+		blob, err := blobService.Append(context.TODO(), "testuser", id, 4, []byte("world!"))
+		require.NoError(t, err)
+		assert.True(t, blob.Complete())
+		assert.Equal(t, int64(2), blob.Chunks)
+	})
+	mt.Run("bad_offset", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		id := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, blobDoc(id, 10, 4, 1)),
+		)
+		blob, err := blobService.Append(context.TODO(), "testuser", id, 0, []byte("hell"))
+		assert.ErrorIs(t, err, errors.ErrBadChunkOffset)
+		require.NotNil(t, blob)
+		assert.Equal(t, int64(4), blob.Received)
+	})
+	mt.Run("concurrent_append", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		id := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, blobDoc(id, 10, 4, 1)),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+			mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, blobDoc(id, 10, 10, 2)),
+		)
+		blob, err := blobService.Append(context.TODO(), "testuser", id, 4, []byte("world!"))
+		assert.ErrorIs(t, err, errors.ErrBadChunkOffset)
+		require.NotNil(t, blob)
+		assert.Equal(t, int64(10), blob.Received)
+	})
+	mt.Run("past_the_end", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		id := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, blobDoc(id, 10, 4, 1)),
+		)
+		_, err := blobService.Append(context.TODO(), "testuser", id, 4, []byte("world!!"))
+		assert.ErrorIs(t, err, errors.ErrBadChunkSize)
+	})
+	mt.Run("empty_chunk", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		_, err := blobService.Append(context.TODO(), "testuser", models.NewRandomObjectID(), 0, nil)
+		assert.ErrorIs(t, err, errors.ErrBadChunkSize)
+	})
+}
+
+func (suite *BlobServiceTestSuite) TestRead() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	keyring := newTestKeyring(t, "my-secret-key")
+	chunkDoc := func(id models.ObjectID, n int64, data string) bson.D {
+		keyID, encrypted, err := keyring.EncryptBytesWithAD([]byte(data), chunkAD(id, n))
+		require.NoError(t, err)
+		return bson.D{
+			{Key: "blob_id", Value: id},
+			{Key: "n", Value: n},
+			{Key: "key_id", Value: keyID},
+			{Key: "data", Value: encrypted},
+		}
+	}
+	mt.Run("success", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, keyring)
+		id := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, blobDoc(id, 10, 10, 2)),
+			mtest.CreateCursorResponse(
+				0,
+				"db.blob_chunks",
+				mtest.FirstBatch,
+				chunkDoc(id, 0, "hell"),
+				chunkDoc(id, 1, "o, go"),
+			),
+		)
+		var buf bytes.Buffer
+		err := blobService.Read(context.TODO(), "testuser", id, &buf)
+		require.NoError(t, err)
+		assert.Equal(t, "hello, go", buf.String())
+	})
+	mt.Run("missing_chunk", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, keyring)
+		id := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, blobDoc(id, 10, 10, 2)),
+			mtest.CreateCursorResponse(0, "db.blob_chunks", mtest.FirstBatch, chunkDoc(id, 1, "o, go")),
+		)
+		err := blobService.Read(context.TODO(), "testuser", id, &bytes.Buffer{})
+		assert.Error(t, err)
+	})
+	mt.Run("moved_chunks", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, keyring)
+		id, other := models.NewRandomObjectID(), models.NewRandomObjectID()
+		// the chunks are swapped and the first one is taken from another blob
+		first, second := chunkDoc(id, 1, "hell"), chunkDoc(id, 0, "o, go")
+		first[1].Value, second[1].Value = int64(0), int64(1)
+		moved := []bson.D{chunkDoc(other, 0, "hell"), chunkDoc(id, 1, "o, go")}
+		for _, chunks := range [][]bson.D{{first, second}, moved} {
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, blobDoc(id, 10, 10, 2)),
+				mtest.CreateCursorResponse(0, "db.blob_chunks", mtest.FirstBatch, chunks...),
+			)
+			err := blobService.Read(context.TODO(), "testuser", id, &bytes.Buffer{})
+			assert.ErrorIs(t, err, encrypt.ErrDecryptionFailed)
+		}
+	})
+	mt.Run("incomplete", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, keyring)
+		id := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, blobDoc(id, 10, 4, 1)),
+		)
+		err := blobService.Read(context.TODO(), "testuser", id, &bytes.Buffer{})
+		assert.ErrorIs(t, err, errors.ErrBlobIncomplete)
+	})
+}
+
+func (suite *BlobServiceTestSuite) TestPurgeIncomplete() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		ids := bson.A{models.NewRandomObjectID(), models.NewRandomObjectID()}
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: ids}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 5}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}},
+		)
+		purged, err := blobService.PurgeIncomplete(context.TODO(), time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), purged)
+		// the chunks are deleted before the uploads
+		var deleted []string
+		for _, e := range mt.GetAllStartedEvents() {
+			if e.CommandName == "delete" {
+				deleted = append(deleted, e.Command.Lookup("delete").StringValue())
+			}
+		}
+		assert.Equal(t, []string{BlobChunksCollection, BlobsCollection}, deleted)
+	})
+	mt.Run("nothing_expired", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{}}})
+		purged, err := blobService.PurgeIncomplete(context.TODO(), time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), purged)
+	})
+	mt.Run("error", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{models.NewRandomObjectID()}}},
+			bson.D{{Key: "ok", Value: 0}},
+		)
+		_, err := blobService.PurgeIncomplete(context.TODO(), time.Now())
+		assert.Error(t, err)
+	})
+}
+
+func (suite *BlobServiceTestSuite) TestPurgeReleased() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		keyring := newTestKeyring(t, "my-secret-key")
+		blobService := NewBlobService(mt.DB, keyring)
+		recordID := models.NewRandomObjectID()
+		kept, orphan := models.NewRandomObjectID(), models.NewRandomObjectID()
+		released := func(id models.ObjectID) bson.D {
+			return bson.D{{Key: "_id", Value: id}, {Key: "record_id", Value: recordID}}
+		}
+		data, err := keyring.EncryptMap(map[string]any{"FileName": "report.pdf", "BlobID": kept.Hex()})
+		require.NoError(t, err)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, released(kept), released(orphan)),
+			// the record refers to another blob now
+			mtest.CreateCursorResponse(0, "db.binary", mtest.FirstBatch),
+			// but a revision still refers to one of the released blobs
+			mtest.CreateCursorResponse(0, "db.binary_history", mtest.FirstBatch, bson.D{
+				{Key: "username", Value: "testuser"},
+				{Key: "data", Value: data},
+			}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{orphan}}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 3}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+		)
+		purged, err := blobService.PurgeReleased(context.TODO(), time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		var deleted []string
+		for _, e := range mt.GetAllStartedEvents() {
+			switch e.CommandName {
+			case "distinct":
+				// only the blob which nothing refers to is deleted
+				ids := e.Command.Lookup("query", "_id", "$in").Array()
+				assert.Equal(t, orphan, ids.Index(0).Value().ObjectID())
+				_, err := ids.IndexErr(1)
+				assert.Error(t, err)
+				assert.NotEmpty(t, e.Command.Lookup("query", "released_at"))
+			case "delete":
+				deleted = append(deleted, e.Command.Lookup("delete").StringValue())
+			}
+		}
+		assert.Equal(t, []string{BlobChunksCollection, BlobsCollection}, deleted)
+	})
+	mt.Run("nothing_released", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch))
+		purged, err := blobService.PurgeReleased(context.TODO(), time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), purged)
+	})
+	mt.Run("error", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		_, err := blobService.PurgeReleased(context.TODO(), time.Now())
+		assert.Error(t, err)
+	})
+}
+
+func (suite *BlobServiceTestSuite) TestEnsureIndexes() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		blobService := NewBlobService(mt.DB, newTestKeyring(t, "my-secret-key"))
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		assert.NoError(t, blobService.EnsureIndexes(context.TODO()))
+	})
+}
+
+func TestBlobServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(BlobServiceTestSuite))
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// changesCursorLag is the time the cursor is moved back by to return the
+// changes being written while the changes are read once again. The records
+// are stamped with updated_at before they are written, so a change is returned
+// by a later call only if it is committed within the lag after its stamp.
+// The writes of the storage service time out after 2 seconds, well within the
+// lag; the clocks of the servers sharing the database must agree within the rest.
+const changesCursorLag = 5 * time.Second
+
+// ChangesSince returns the changes of the user's records since the cursor and
+// the cursor of the next changes. The tombstones of the purged records are
+// removed together with the trash after trashTTL, so all the records are
+// returned with the reset flag for the older cursor; zero trashTTL keeps
+// the tombstones forever. The changes committed later than changesCursorLag
+// after their updated_at are missed.
+func ChangesSince(
+	ctx context.Context,
+	storage StorageService,
+	username string,
+	cursor string,
+	trashTTL time.Duration,
+) (*models.Changes, error) {
+	since, err := models.ParseSyncCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if trashTTL > 0 && since.Before(now.Add(-trashTTL)) {
+		since = time.Time{}
+	}
+	changes, err := storage.Changes(ctx, username, since)
+	if err != nil {
+		return nil, err
+	}
+	next := now.Add(-changesCursorLag)
+	if next.Before(since) {
+		next = since
+	}
+	changes.Cursor = models.NewSyncCursor(next)
+	return changes, nil
+}
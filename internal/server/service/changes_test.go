@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// sinceStorage remembers the time passed to Changes.
+type sinceStorage struct {
+	StorageService
+	since time.Time
+}
+
+func (s *sinceStorage) Changes(
+	ctx context.Context,
+	username string,
+	since time.Time,
+) (*models.Changes, error) {
+	s.since = since
+	return &models.Changes{Reset: since.IsZero()}, nil
+}
+
+// lateStorage returns the records committed so far which were updated after the time.
+type lateStorage struct {
+	StorageService
+	committed []models.UntypedRecord
+}
+
+func (s *lateStorage) Changes(
+	ctx context.Context,
+	username string,
+	since time.Time,
+) (*models.Changes, error) {
+	changes := &models.Changes{Reset: since.IsZero()}
+	for _, r := range s.committed {
+		if r.UpdatedAt.After(since) {
+			changes.Upserts = append(changes.Upserts, models.ChangedRecord{Record: r})
+		}
+	}
+	return changes, nil
+}
+
+func TestChangesSince_LateCommit(t *testing.T) {
+	storage := &lateStorage{}
+	since := time.Now().Add(-time.Minute).UTC()
+	changes, err := ChangesSince(context.TODO(), storage, "user", models.NewSyncCursor(since), 0)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Upserts)
+
+	// the records stamped before the call are committed after it
+	for _, delay := range []time.Duration{time.Second, changesCursorLag - time.Second} {
+		stamped := time.Now().Add(-delay).UTC()
+		storage.committed = append(storage.committed, models.UntypedRecord{
+			RecordID:  models.NewRandomObjectID(),
+			UpdatedAt: &stamped,
+		})
+	}
+	next, err := ChangesSince(context.TODO(), storage, "user", changes.Cursor, 0)
+	require.NoError(t, err)
+	require.Len(t, next.Upserts, len(storage.committed))
+	for i, change := range next.Upserts {
+		assert.Equal(t, storage.committed[i], change.Record)
+	}
+}
+
+func TestChangesSince(t *testing.T) {
+	storage := &sinceStorage{}
+
+	t.Run("cursor", func(t *testing.T) {
+		since := time.Now().Add(-time.Minute).UTC()
+		changes, err := ChangesSince(context.TODO(), storage, "user", models.NewSyncCursor(since), time.Hour)
+		require.NoError(t, err)
+		assert.True(t, since.Equal(storage.since))
+		next, err := models.ParseSyncCursor(changes.Cursor)
+		require.NoError(t, err)
+		// the next cursor overlaps the changes being written
+		assert.True(t, next.After(since))
+		assert.True(t, next.Before(time.Now().Add(-changesCursorLag/2)))
+	})
+	t.Run("recent_cursor", func(t *testing.T) {
+		since := time.Now().UTC()
+		changes, err := ChangesSince(context.TODO(), storage, "user", models.NewSyncCursor(since), time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, models.NewSyncCursor(since), changes.Cursor)
+	})
+	t.Run("expired_cursor", func(t *testing.T) {
+		since := time.Now().Add(-2 * time.Hour)
+		changes, err := ChangesSince(context.TODO(), storage, "user", models.NewSyncCursor(since), time.Hour)
+		require.NoError(t, err)
+		assert.True(t, changes.Reset)
+		assert.True(t, storage.since.IsZero())
+	})
+	t.Run("no_trash_ttl", func(t *testing.T) {
+		since := time.Now().Add(-2 * time.Hour).UTC()
+		_, err := ChangesSince(context.TODO(), storage, "user", models.NewSyncCursor(since), 0)
+		require.NoError(t, err)
+		assert.True(t, since.Equal(storage.since))
+	})
+	t.Run("bad_cursor", func(t *testing.T) {
+		_, err := ChangesSince(context.TODO(), storage, "user", "bad", time.Hour)
+		assert.ErrorIs(t, err, errors.ErrBadSyncCursor)
+	})
+}
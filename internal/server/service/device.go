@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// DevicesCollection and DeviceNoncesCollection are the collections which keep
+// the devices of the users and the ids of the used device nonces.
+const (
+	DevicesCollection      = "devices"
+	DeviceNoncesCollection = "device_nonces"
+)
+
+// DeviceNonceTTL is the duration for which the device nonces are valid.
+const DeviceNonceTTL = 2 * time.Minute
+
+// DeviceService is an interface for managing the devices of the users.
+type DeviceService interface {
+	// Nonce issues the nonce which the client signs with the key of the device.
+	Nonce() (*models.DeviceNonce, error)
+	// Register checks the proof of the key and saves the device of the user and returns it.
+	// The device with the same public key is updated, so the device keeps its id between the logins.
+	Register(ctx context.Context, username string, info models.DeviceInfo) (*models.Device, error)
+	// List returns the devices of the user.
+	List(ctx context.Context, username string) ([]models.Device, error)
+	// Delete removes the device of the user, finishes its sessions, revokes
+	// their access tokens and cancels its sync subscriptions.
+	Delete(ctx context.Context, username string, deviceID models.ObjectID) error
+	// EnsureIndexes creates the unique index of the public keys of the user's devices
+	// and the index which removes the expired nonces.
+	EnsureIndexes(ctx context.Context) error
+}
+
+// deviceService is the implementation of the DeviceService interface.
+type deviceService struct {
+	devices  *mongo.Collection
+	nonces   *mongo.Collection
+	keys     *auth.KeySet   // The keys which sign the nonces.
+	sessions SessionService // The service which revokes the sessions of the deleted devices.
+	sync     SyncService    // The service which keeps the sync subscriptions of the devices.
+	now      func() time.Time
+}
+
+// NewDeviceService creates a new instance of the DeviceService
+// which signs the nonces with the keys.
+func NewDeviceService(
+	db *mongo.Database,
+	keys *auth.KeySet,
+	sessions SessionService,
+	sync SyncService,
+) DeviceService {
+	return &deviceService{
+		devices:  db.Collection(DevicesCollection),
+		nonces:   db.Collection(DeviceNoncesCollection),
+		keys:     keys,
+		sessions: sessions,
+		sync:     sync,
+		now:      time.Now,
+	}
+}
+
+// Nonce issues the nonce which the client signs with the key of the device.
+// The nonce is not saved until it is used, so issuing it costs nothing.
+func (s *deviceService) Nonce() (*models.DeviceNonce, error) {
+	nonce, claims, err := s.keys.NewDeviceNonce(DeviceNonceTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DeviceNonce{Nonce: nonce, ExpiresAt: claims.ExpiresAt.Time}, nil
+}
+
+// Register checks the proof of the key and saves the device of the user
+// and returns it. The name and the platform of the device with the same
+// public key are updated. Returns ErrBadDevice if the device is malformed
+// and ErrBadDeviceProof if the client doesn't prove that it holds the key.
+func (s *deviceService) Register(
+	ctx context.Context,
+	username string,
+	info models.DeviceInfo,
+) (*models.Device, error) {
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := verifyDeviceProof(ctx, s.nonces, s.keys, info.PublicKey, info.Proof); err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	update := bson.M{
+		"$set": bson.M{
+			"name":          info.Name,
+			"platform":      info.Platform,
+			"last_login_at": now,
+		},
+		"$setOnInsert": bson.M{
+			"_id":        models.NewRandomObjectID(),
+			"created_at": now,
+		},
+	}
+	var device models.Device
+	err := s.devices.FindOneAndUpdate(
+		ctx,
+		bson.M{"username": username, "public_key": info.PublicKey},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&device)
+	if mongo.IsDuplicateKeyError(err) {
+		// the concurrent login of the device has inserted it, so it is updated now
+		err = s.devices.FindOneAndUpdate(
+			ctx,
+			bson.M{"username": username, "public_key": info.PublicKey},
+			bson.M{"$set": update["$set"]},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&device)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// List returns the devices of the user in the order of the registration.
+func (s *deviceService) List(ctx context.Context, username string) ([]models.Device, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	cur, err := s.devices.Find(
+		ctx,
+		bson.M{"username": username},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.Device, 0)
+	if err := cur.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Delete removes the device of the user. The sessions of the device are finished
+// and the sync subscriptions are canceled before the device is removed, so
+// the deletion which failed halfway can be repeated.
+// Returns ErrDeviceNotFound if the user has no such device.
+func (s *deviceService) Delete(
+	ctx context.Context,
+	username string,
+	deviceID models.ObjectID,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	filter := bson.M{"_id": deviceID, "username": username}
+	err := s.devices.FindOne(ctx, filter).Err()
+	if err == mongo.ErrNoDocuments {
+		return errors.ErrDeviceNotFound
+	} else if err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeDevice(ctx, username, deviceID.Hex()); err != nil {
+		return err
+	}
+	s.sync.Disconnect(username, deviceID.Hex())
+	_, err = s.devices.DeleteOne(ctx, filter)
+	return err
+}
+
+// EnsureIndexes creates the unique index of the public keys of the user's devices
+// and the TTL index which removes the used nonces once they expire.
+func (s *deviceService) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, err := s.devices.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}, {Key: "public_key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.nonces.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// verifyDeviceProof checks that the proof is the signature of a valid nonce made
+// with the private key of the public key and marks the nonce as used, so the proof
+// can't be replayed. Returns ErrBadDeviceProof if the check fails.
+func verifyDeviceProof(
+	ctx context.Context,
+	nonces *mongo.Collection,
+	keys *auth.KeySet,
+	publicKey string,
+	proof *models.DeviceProof,
+) error {
+	if proof == nil {
+		return errors.ErrBadDeviceProof
+	}
+	claims, err := keys.ParseDeviceNonce(proof.Nonce)
+	if err != nil {
+		return errors.ErrBadDeviceProof
+	}
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.ErrBadDeviceProof
+	}
+	signature, err := base64.StdEncoding.DecodeString(proof.Signature)
+	if err != nil || !ed25519.Verify(key, []byte(proof.Nonce), signature) {
+		return errors.ErrBadDeviceProof
+	}
+	_, err = nonces.InsertOne(ctx, bson.M{"_id": claims.ID, "expires_at": claims.ExpiresAt.Time})
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.ErrBadDeviceProof
+	}
+	return err
+}
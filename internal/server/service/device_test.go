@@ -0,0 +1,238 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// testDeviceKey is the private key of the test device.
+var testDeviceKey = ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+
+// testDevice is a device with a valid public key and no proof.
+var testDevice = models.DeviceInfo{
+	Name:      "laptop",
+	Platform:  "linux/amd64",
+	PublicKey: base64.StdEncoding.EncodeToString(testDeviceKey.Public().(ed25519.PublicKey)),
+}
+
+// testDeviceProof signs a new nonce of the test keys with the key.
+func testDeviceProof(t *testing.T, key ed25519.PrivateKey) *models.DeviceProof {
+	nonce, _, err := auth.NewHMACKeySet([]byte(testSigningKey)).NewDeviceNonce(time.Minute)
+	require.NoError(t, err)
+	return &models.DeviceProof{
+		Nonce:     nonce,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(nonce))),
+	}
+}
+
+// provenTestDevice returns the test device with the proof of its key.
+func provenTestDevice(t *testing.T) models.DeviceInfo {
+	device := testDevice
+	device.Proof = testDeviceProof(t, testDeviceKey)
+	return device
+}
+
+type DeviceServiceTestSuite struct {
+	suite.Suite
+}
+
+// newTestDeviceService creates the device service with the sessions in the test DB.
+func newTestDeviceService(mt *mtest.T, sync SyncService) DeviceService {
+	return NewDeviceService(
+		mt.DB,
+		auth.NewHMACKeySet([]byte(testSigningKey)),
+		newTestSessionService(mt),
+		sync,
+	)
+}
+
+func (suite *DeviceServiceTestSuite) TestNonce() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		nonce, err := newTestDeviceService(mt, NewSyncService()).Nonce()
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(DeviceNonceTTL), nonce.ExpiresAt, time.Second)
+		_, err = auth.NewHMACKeySet([]byte(testSigningKey)).ParseDeviceNonce(nonce.Nonce)
+		require.NoError(t, err)
+		// the nonce is saved only when it is used
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+}
+
+func (suite *DeviceServiceTestSuite) TestRegister() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	id := models.NewRandomObjectID()
+	deviceDocument := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "username", Value: "testuser"},
+		{Key: "name", Value: testDevice.Name},
+		{Key: "platform", Value: testDevice.Platform},
+		{Key: "public_key", Value: testDevice.PublicKey},
+	}
+	mt.Run("success", func(mt *mtest.T) {
+		devices := newTestDeviceService(mt, NewSyncService())
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: deviceDocument}},
+		)
+		device, err := devices.Register(context.TODO(), "testuser", provenTestDevice(t))
+		require.NoError(t, err)
+		assert.Equal(t, id, device.ID)
+		assert.Equal(t, testDevice, device.DeviceInfo)
+
+		// the nonce is marked as used
+		used := mt.GetStartedEvent().Command
+		assert.Equal(t, DeviceNoncesCollection, used.Lookup("insert").StringValue())
+		// the device is found by the public key of the user
+		cmd := mt.GetStartedEvent().Command
+		assert.True(t, cmd.Lookup("upsert").Boolean())
+		query := cmd.Lookup("query").Document()
+		assert.Equal(t, "testuser", query.Lookup("username").StringValue())
+		assert.Equal(t, testDevice.PublicKey, query.Lookup("public_key").StringValue())
+	})
+	mt.Run("concurrent", func(mt *mtest.T) {
+		devices := newTestDeviceService(mt, NewSyncService())
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "dup"}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: deviceDocument}},
+		)
+		device, err := devices.Register(context.TODO(), "testuser", provenTestDevice(t))
+		require.NoError(t, err)
+		assert.Equal(t, id, device.ID)
+	})
+	mt.Run("bad device", func(mt *mtest.T) {
+		devices := newTestDeviceService(mt, NewSyncService())
+		for _, device := range []models.DeviceInfo{
+			{Name: "", PublicKey: testDevice.PublicKey},
+			{Name: "laptop", PublicKey: "bm90IGEga2V5"},
+			{Name: "laptop", PublicKey: "not base64"},
+			{Name: "laptop", PublicKey: testDevice.PublicKey},
+		} {
+			_, err := devices.Register(context.TODO(), "testuser", device)
+			require.ErrorIs(t, err, errors.ErrBadDevice)
+		}
+	})
+	mt.Run("bad proof", func(mt *mtest.T) {
+		devices := newTestDeviceService(mt, NewSyncService())
+		expired, _, err := auth.NewHMACKeySet([]byte(testSigningKey)).NewDeviceNonce(-time.Minute)
+		require.NoError(t, err)
+		foreign, _, err := auth.NewHMACKeySet([]byte("another-key")).NewDeviceNonce(time.Minute)
+		require.NoError(t, err)
+		_, anotherKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		for name, proof := range map[string]*models.DeviceProof{
+			"another key": testDeviceProof(t, anotherKey),
+			"expired":     {Nonce: expired, Signature: signNonce(expired)},
+			"foreign":     {Nonce: foreign, Signature: signNonce(foreign)},
+			"not base64":  {Nonce: testDeviceProof(t, testDeviceKey).Nonce, Signature: "not base64"},
+		} {
+			device := testDevice
+			device.Proof = proof
+			_, err := devices.Register(context.TODO(), "testuser", device)
+			require.ErrorIs(t, err, errors.ErrBadDeviceProof, name)
+		}
+		// the device is not saved
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+	mt.Run("replayed proof", func(mt *mtest.T) {
+		devices := newTestDeviceService(mt, NewSyncService())
+		mt.AddMockResponses(
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "dup"}),
+		)
+		_, err := devices.Register(context.TODO(), "testuser", provenTestDevice(t))
+		require.ErrorIs(t, err, errors.ErrBadDeviceProof)
+		assert.Equal(t, "insert", mt.GetStartedEvent().CommandName)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+}
+
+func (suite *DeviceServiceTestSuite) TestList() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		devices := newTestDeviceService(mt, NewSyncService())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "devices.find", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: models.NewRandomObjectID()}, {Key: "name", Value: "laptop"}},
+			bson.D{{Key: "_id", Value: models.NewRandomObjectID()}, {Key: "name", Value: "phone"}},
+		))
+		result, err := devices.List(context.TODO(), "testuser")
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.Equal(t, "phone", result[1].Name)
+	})
+	mt.Run("error", func(mt *mtest.T) {
+		devices := newTestDeviceService(mt, NewSyncService())
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		_, err := devices.List(context.TODO(), "testuser")
+		require.Error(t, err)
+	})
+}
+
+func (suite *DeviceServiceTestSuite) TestDelete() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	id, sessionID := models.NewRandomObjectID(), models.NewRandomObjectID()
+	mt.Run("success", func(mt *mtest.T) {
+		sync := NewSyncService()
+		events, unsubscribe := sync.Subscribe("testuser", id.Hex(), "", time.Time{})
+		defer unsubscribe()
+		devices := newTestDeviceService(mt, sync)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "devices.find", mtest.FirstBatch, bson.D{{Key: "_id", Value: id}}),
+			mtest.CreateCursorResponse(0, "sessions.find", mtest.FirstBatch, bson.D{{Key: "_id", Value: sessionID}}),
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: sessionDocument(sessionID, "token", time.Now().Add(time.Hour))},
+			},
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+		require.NoError(t, devices.Delete(context.TODO(), "testuser", id))
+
+		// the sessions of the device are revoked and its token is added to the revocation list
+		mt.GetStartedEvent()
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, id.Hex(), filter.Lookup("device_id").StringValue())
+		mt.GetStartedEvent()
+		assert.Equal(t, "insert", mt.GetStartedEvent().CommandName)
+		assert.Equal(t, DevicesCollection, mt.GetStartedEvent().Command.Lookup("delete").StringValue())
+		_, ok := <-events
+		assert.False(t, ok, "the sync subscriptions of the device should be canceled")
+	})
+	mt.Run("not found", func(mt *mtest.T) {
+		devices := newTestDeviceService(mt, NewSyncService())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "devices.find", mtest.FirstBatch))
+		err := devices.Delete(context.TODO(), "testuser", id)
+		require.ErrorIs(t, err, errors.ErrDeviceNotFound)
+	})
+}
+
+// signNonce signs the nonce with the key of the test device.
+func signNonce(nonce string) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(testDeviceKey, []byte(nonce)))
+}
+
+func TestDeviceServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(DeviceServiceTestSuite))
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// LockoutPolicy sets how the failed logins are throttled. After the free attempts
+// every next failure locks the username or the address for Backoff, which
+// doubles with every failure up to MaxLockout. The failures are forgotten
+// after Window without them.
+type LockoutPolicy struct {
+	UserFreeAttempts int           // UserFreeAttempts is the number of the failures of a username without the lockout.
+	IPFreeAttempts   int           // IPFreeAttempts is the number of the failures from an address without the lockout.
+	Backoff          time.Duration // Backoff is the first lockout after the free attempts.
+	MaxLockout       time.Duration
+	Window           time.Duration
+	EventsRetention  time.Duration // EventsRetention is how long the security events are kept; zero keeps them forever.
+}
+
+// LockoutService is an interface for the protection of the login against brute force.
+type LockoutService interface {
+	// Check returns LockedError if the logins of the username or from the address are locked.
+	Check(ctx context.Context, username, ip string) error
+	// Fail counts the failed login of the username from the address
+	// and locks them if there were too many failures.
+	Fail(ctx context.Context, username, ip string) error
+	// Succeed forgets the failures of the username after the successful login.
+	Succeed(ctx context.Context, username string) error
+	// Unlock removes the lockout and the failures of the username and of the address,
+	// any of which may be empty. Returns false if there was nothing to unlock.
+	Unlock(ctx context.Context, username, ip string) (bool, error)
+	// EnsureIndexes creates the indexes which remove the expired counters and events.
+	EnsureIndexes(ctx context.Context) error
+}
+
+// lockoutService is the implementation of the LockoutService interface.
+type lockoutService struct {
+	store  AttemptStore
+	policy LockoutPolicy
+	now    func() time.Time
+}
+
+// NewLockoutService creates a new instance of the LockoutService
+// which keeps the failures in the store.
+func NewLockoutService(store AttemptStore, policy LockoutPolicy) LockoutService {
+	return &lockoutService{
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// lockoutTarget is the username or the address which failures are counted.
+type lockoutTarget struct {
+	scope, value string
+}
+
+// key returns the key of the counter of the target.
+func (t lockoutTarget) key() string {
+	return t.scope + ":" + t.value
+}
+
+// lockoutTargets returns the targets of the login which are not empty.
+func lockoutTargets(username, ip string) []lockoutTarget {
+	targets := make([]lockoutTarget, 0, 2)
+	if username != "" {
+		targets = append(targets, lockoutTarget{models.LockoutScopeUser, username})
+	}
+	if ip != "" {
+		targets = append(targets, lockoutTarget{models.LockoutScopeIP, ip})
+	}
+	return targets
+}
+
+// freeAttempts returns the number of the failures of the target without the lockout.
+func (s *lockoutService) freeAttempts(t lockoutTarget) int {
+	if t.scope == models.LockoutScopeIP {
+		return s.policy.IPFreeAttempts
+	}
+	return s.policy.UserFreeAttempts
+}
+
+// lockoutDuration returns the lockout after the failures beyond the free attempts.
+func (s *lockoutService) lockoutDuration(over int) time.Duration {
+	lockout := s.policy.Backoff
+	for i := 1; i < over && lockout < s.policy.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > s.policy.MaxLockout {
+		lockout = s.policy.MaxLockout
+	}
+	return lockout
+}
+
+// record saves the security event.
+func (s *lockoutService) record(ctx context.Context, event models.SecurityEvent) error {
+	event.ID = models.NewRandomObjectID()
+	event.CreatedAt = s.now().UTC()
+	if s.policy.EventsRetention > 0 {
+		event.ExpiresAt = event.CreatedAt.Add(s.policy.EventsRetention)
+	}
+	return s.store.Record(ctx, &event)
+}
+
+// Check returns LockedError if the logins of the username or from the address are locked.
+// The rejected login is recorded as a security event.
+func (s *lockoutService) Check(ctx context.Context, username, ip string) error {
+	now := s.now().UTC()
+	var lockedUntil time.Time
+	var scope string
+	for _, target := range lockoutTargets(username, ip) {
+		attempts, err := s.store.Get(ctx, target.key())
+		if err != nil {
+			return err
+		}
+		if attempts.Locked(now) && attempts.LockedUntil.After(lockedUntil) {
+			lockedUntil, scope = attempts.LockedUntil, target.scope
+		}
+	}
+	if lockedUntil.IsZero() {
+		return nil
+	}
+	err := s.record(ctx, models.SecurityEvent{
+		Type:        models.EventLoginLocked,
+		Username:    username,
+		IP:          ip,
+		Scope:       scope,
+		LockedUntil: lockedUntil,
+	})
+	if err != nil {
+		return err
+	}
+	return &srvErrors.LockedError{RetryAfter: lockedUntil.Sub(now)}
+}
+
+// Fail counts the failed login of the username from the address. The failures
+// are counted for the username and for the address on their own, so guessing
+// the passwords of many users from one address locks the address, and guessing
+// the password of one user from many addresses locks the username.
+func (s *lockoutService) Fail(ctx context.Context, username, ip string) error {
+	now := s.now().UTC()
+	err := s.record(ctx, models.SecurityEvent{
+		Type:     models.EventLoginFailed,
+		Username: username,
+		IP:       ip,
+	})
+	if err != nil {
+		return err
+	}
+	for _, target := range lockoutTargets(username, ip) {
+		attempts, err := s.store.Fail(ctx, target.key(), now, s.policy.Window)
+		if err != nil {
+			return err
+		}
+		over := attempts.Failures - s.freeAttempts(target)
+		if over <= 0 || s.policy.MaxLockout <= 0 {
+			continue
+		}
+		until := now.Add(s.lockoutDuration(over))
+		if err := s.store.Lock(ctx, target.key(), until); err != nil {
+			return err
+		}
+		err = s.record(ctx, models.SecurityEvent{
+			Type:        models.EventLockout,
+			Username:    username,
+			IP:          ip,
+			Scope:       target.scope,
+			Failures:    attempts.Failures,
+			LockedUntil: until,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Succeed forgets the failures of the username. The failures from the address
+// are kept, so a valid account doesn't reset the counter of an attacker.
+func (s *lockoutService) Succeed(ctx context.Context, username string) error {
+	_, err := s.store.Reset(ctx, lockoutTarget{models.LockoutScopeUser, username}.key())
+	return err
+}
+
+// Unlock removes the lockout and the failures of the username and of the address.
+func (s *lockoutService) Unlock(ctx context.Context, username, ip string) (bool, error) {
+	unlocked := false
+	for _, target := range lockoutTargets(username, ip) {
+		ok, err := s.store.Reset(ctx, target.key())
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			continue
+		}
+		unlocked = true
+		err = s.record(ctx, models.SecurityEvent{
+			Type:     models.EventUnlock,
+			Username: username,
+			IP:       ip,
+			Scope:    target.scope,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	return unlocked, nil
+}
+
+// EnsureIndexes creates the indexes of the store.
+func (s *lockoutService) EnsureIndexes(ctx context.Context) error {
+	return s.store.EnsureIndexes(ctx)
+}
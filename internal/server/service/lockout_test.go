@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// newTestLockout creates the lockout service with the memory store which time
+// is controlled by the returned pointer.
+func newTestLockout(policy LockoutPolicy) (*lockoutService, *memoryAttemptStore, *time.Time) {
+	store := NewMemoryAttemptStore().(*memoryAttemptStore)
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := NewLockoutService(store, policy).(*lockoutService)
+	s.now = func() time.Time { return now }
+	return s, store, &now
+}
+
+// eventTypes returns the types of the recorded security events.
+func eventTypes(store *memoryAttemptStore) []string {
+	types := make([]string, 0, len(store.events))
+	for _, e := range store.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestLockoutService_Backoff(t *testing.T) {
+	ctx := context.Background()
+	s, store, now := newTestLockout(testLockoutPolicy)
+
+	for i := 0; i < testLockoutPolicy.UserFreeAttempts; i++ {
+		require.NoError(t, s.Fail(ctx, "alice", "10.0.0.1"))
+		require.NoError(t, s.Check(ctx, "alice", "10.0.0.1"))
+	}
+	// every failure after the free attempts doubles the lockout
+	for _, lockout := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		require.NoError(t, s.Fail(ctx, "alice", "10.0.0.1"))
+		var locked *errors.LockedError
+		require.ErrorAs(t, s.Check(ctx, "alice", "10.0.0.2"), &locked)
+		assert.Equal(t, lockout, locked.RetryAfter)
+		assert.ErrorIs(t, locked, errors.ErrLoginLocked)
+		// the other users are not locked
+		require.NoError(t, s.Check(ctx, "bob", "10.0.0.2"))
+		*now = now.Add(lockout)
+		require.NoError(t, s.Check(ctx, "alice", "10.0.0.2"))
+	}
+	assert.Equal(t, time.Hour, s.lockoutDuration(100))
+
+	assert.Equal(t, []string{
+		models.EventLoginFailed,
+		models.EventLoginFailed,
+		models.EventLoginFailed, models.EventLockout, models.EventLoginLocked,
+		models.EventLoginFailed, models.EventLockout, models.EventLoginLocked,
+		models.EventLoginFailed, models.EventLockout, models.EventLoginLocked,
+	}, eventTypes(store))
+	lockout := store.events[3]
+	assert.Equal(t, "alice", lockout.Username)
+	assert.Equal(t, "10.0.0.1", lockout.IP)
+	assert.Equal(t, models.LockoutScopeUser, lockout.Scope)
+	assert.Equal(t, 3, lockout.Failures)
+}
+
+func TestLockoutService_Window(t *testing.T) {
+	ctx := context.Background()
+	s, store, now := newTestLockout(testLockoutPolicy)
+	require.NoError(t, s.Fail(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, s.Fail(ctx, "alice", "10.0.0.1"))
+
+	// the failures are forgotten after the window
+	*now = now.Add(testLockoutPolicy.Window)
+	require.NoError(t, s.Fail(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, s.Check(ctx, "alice", "10.0.0.1"))
+	attempts, err := store.Get(ctx, "user:alice")
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts.Failures)
+}
+
+func TestLockoutService_IP(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestLockout(testLockoutPolicy)
+	for i := 0; i <= testLockoutPolicy.IPFreeAttempts; i++ {
+		require.NoError(t, s.Fail(ctx, string(rune('a'+i)), "10.0.0.1"))
+	}
+	// the address is locked for any username, the usernames from the other addresses are not
+	var locked *errors.LockedError
+	require.ErrorAs(t, s.Check(ctx, "alice", "10.0.0.1"), &locked)
+	assert.Equal(t, testLockoutPolicy.Backoff, locked.RetryAfter)
+	require.NoError(t, s.Check(ctx, "b", "10.0.0.2"))
+}
+
+func TestLockoutService_Succeed(t *testing.T) {
+	ctx := context.Background()
+	s, store, _ := newTestLockout(testLockoutPolicy)
+	require.NoError(t, s.Fail(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, s.Succeed(ctx, "alice"))
+
+	attempts, err := store.Get(ctx, "user:alice")
+	require.NoError(t, err)
+	assert.Nil(t, attempts)
+	// the failures from the address are kept
+	attempts, err = store.Get(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts.Failures)
+}
+
+func TestLockoutService_Unlock(t *testing.T) {
+	ctx := context.Background()
+	s, store, _ := newTestLockout(testLockoutPolicy)
+	for i := 0; i <= testLockoutPolicy.UserFreeAttempts; i++ {
+		require.NoError(t, s.Fail(ctx, "alice", "10.0.0.1"))
+	}
+	require.Error(t, s.Check(ctx, "alice", "10.0.0.2"))
+
+	unlocked, err := s.Unlock(ctx, "alice", "")
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+	require.NoError(t, s.Check(ctx, "alice", "10.0.0.2"))
+	last := store.events[len(store.events)-1]
+	assert.Equal(t, models.EventUnlock, last.Type)
+	assert.Equal(t, models.LockoutScopeUser, last.Scope)
+
+	unlocked, err = s.Unlock(ctx, "bob", "")
+	require.NoError(t, err)
+	assert.False(t, unlocked)
+}
+
+func TestLockoutService_EventsRetention(t *testing.T) {
+	ctx := context.Background()
+	policy := testLockoutPolicy
+	policy.EventsRetention = 24 * time.Hour
+	s, store, now := newTestLockout(policy)
+	require.NoError(t, s.Fail(ctx, "alice", ""))
+	require.Len(t, store.events, 1)
+	assert.Equal(t, now.Add(24*time.Hour), store.events[0].ExpiresAt)
+	assert.Empty(t, store.events[0].IP)
+}
+
+func TestLockoutService_Disabled(t *testing.T) {
+	ctx := context.Background()
+	policy := testLockoutPolicy
+	policy.MaxLockout = 0
+	s, store, _ := newTestLockout(policy)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Fail(ctx, "alice", "10.0.0.1"))
+	}
+	require.NoError(t, s.Check(ctx, "alice", "10.0.0.1"))
+	// the failures are recorded anyway
+	assert.Len(t, store.events, 10)
+}
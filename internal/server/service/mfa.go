@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+	"github.com/blokhinnv/gophkeeper/pkg/otp"
+)
+
+// MFAIssuer is the name of the service shown by the authenticator apps.
+const MFAIssuer = "GophKeeper"
+
+// Parameters of the generated secrets and recovery codes.
+const (
+	mfaSecretSize         = 20 // the size of the TOTP secret in bytes, as recommended by RFC 4226
+	mfaRecoveryCodesCount = 10
+	mfaRecoveryCodeSize   = 10 // the size of a recovery code in bytes
+)
+
+// MFAService is an interface that defines the methods to manage the TOTP
+// second factor of the users and to check it during the login.
+type MFAService interface {
+	// Enroll checks the password of the user sent from the address and generates
+	// a new secret of the second factor of the user. The second factor is enabled
+	// only after the secret is confirmed with a code.
+	Enroll(ctx context.Context, username, password, ip string) (*models.MFAEnrollment, error)
+	// Confirm checks the code of the enrolled secret, enables the second factor
+	// and returns the recovery codes.
+	Confirm(ctx context.Context, username, code string) (*models.MFARecoveryCodes, error)
+	// Disable checks the password of the user sent from the address and the one-time
+	// or the recovery code and turns off the second factor.
+	Disable(ctx context.Context, username, password, code, ip string) error
+	// Challenge issues the challenge token of the login of the user
+	// whose password is checked.
+	Challenge(username string) (*models.TokenPair, error)
+	// ParseChallenge checks the challenge token and returns its username.
+	ParseChallenge(mfaToken string) (string, error)
+	// Verify checks the challenge token and the one-time or the recovery code.
+	// Returns the username of the token.
+	Verify(ctx context.Context, mfaToken, code string) (string, error)
+}
+
+// mfaService is an implementation of the MFAService interface
+// which keeps the second factor in the users collection.
+type mfaService struct {
+	collection   *mongo.Collection
+	keyring      *encrypt.Keyring
+	keys         *auth.KeySet
+	lockout      LockoutService // The service which counts the wrong passwords as the failed logins.
+	challengeTTL time.Duration  // The duration for which challenge tokens are valid.
+	now          func() time.Time
+}
+
+// NewMFAService creates a new instance of the MFAService. The secrets are
+// encrypted with the keyring, the challenge tokens are signed with a key
+// derived from the active signing key of the keys. The wrong passwords
+// are counted by the lockout like the failed logins.
+func NewMFAService(
+	collection *mongo.Collection,
+	keyring *encrypt.Keyring,
+	keys *auth.KeySet,
+	lockout LockoutService,
+	challengeTTL time.Duration,
+) MFAService {
+	return &mfaService{
+		collection:   collection,
+		keyring:      keyring,
+		keys:         keys,
+		lockout:      lockout,
+		challengeTTL: challengeTTL,
+		now:          time.Now,
+	}
+}
+
+// base32NoPadding is the encoding of the secrets and the recovery codes.
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRandomBase32 returns n random bytes encoded in base32.
+func newRandomBase32(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(b), nil
+}
+
+// newRecoveryCodes generates the recovery codes and their hashes.
+// The codes are split into groups of four characters to be easy to type.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, mfaRecoveryCodesCount)
+	hashes := make([]string, 0, mfaRecoveryCodesCount)
+	for i := 0; i < mfaRecoveryCodesCount; i++ {
+		code, err := newRandomBase32(mfaRecoveryCodeSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		code = strings.ToLower(code)
+		hashes = append(hashes, auth.HashToken(code))
+		groups := make([]string, 0, len(code)/4)
+		for j := 0; j < len(code); j += 4 {
+			groups = append(groups, code[j:j+4])
+		}
+		codes = append(codes, strings.Join(groups, "-"))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeCode removes the separators the user may type with the code.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+// isOneTimeCode reports whether the code looks like a TOTP code, not a recovery code.
+func isOneTimeCode(code string) bool {
+	if len(code) != otp.DefaultDigits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// findUser returns the user with the username.
+func (s *mfaService) findUser(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	err := s.collection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// checkCode checks the one-time code of the encrypted secret and returns its
+// time step. The codes of the previous and the next steps are accepted since
+// the clocks of the devices drift. The steps up to lastStep are already used.
+func (s *mfaService) checkCode(encryptedSecret, code string, lastStep int64) (int64, error) {
+	secret, err := s.keyring.DecryptString(encryptedSecret)
+	if err != nil {
+		return 0, err
+	}
+	key := otp.NewKey(otp.TypeTOTP, secret)
+	now := s.now()
+	for _, drift := range []int{-1, 0, 1} {
+		t := now.Add(time.Duration(drift*key.Period) * time.Second)
+		expected, _, err := key.Code(t)
+		if err != nil {
+			return 0, err
+		}
+		step := t.Unix() / int64(key.Period)
+		if step > lastStep && subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, nil
+		}
+	}
+	return 0, srvErrors.ErrBadMFACode
+}
+
+// verifyCode checks the one-time or the recovery code of the enabled second
+// factor of the user and marks it as used. The secret encrypted with an old key
+// is re-encrypted with the active one.
+func (s *mfaService) verifyCode(
+	ctx context.Context,
+	username string,
+	mfa *models.MFA,
+	code string,
+) error {
+	code = normalizeCode(code)
+	if !isOneTimeCode(code) {
+		hash := auth.HashToken(code)
+		res, err := s.collection.UpdateOne(
+			ctx,
+			bson.M{"username": username, "mfa.enabled": true, "mfa.recovery_codes": hash},
+			bson.M{"$pull": bson.M{"mfa.recovery_codes": hash}},
+		)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return srvErrors.ErrBadMFACode
+		}
+		return nil
+	}
+	step, err := s.checkCode(mfa.Secret, code, mfa.LastStep)
+	if err != nil {
+		return err
+	}
+	set := bson.M{"mfa.last_step": step}
+	if !s.keyring.IsActive(mfa.Secret) {
+		secret, err := s.keyring.DecryptString(mfa.Secret)
+		if err != nil {
+			return err
+		}
+		if set["mfa.secret"], err = s.keyring.EncryptString(secret); err != nil {
+			return err
+		}
+	}
+	// the filter rejects the code accepted by a concurrent request
+	res, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"username":      username,
+			"mfa.enabled":   true,
+			"mfa.last_step": bson.M{"$not": bson.M{"$gte": step}},
+		},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return srvErrors.ErrBadMFACode
+	}
+	return nil
+}
+
+// Enroll checks the password and generates a new secret of the second factor
+// of the user, so a stolen access token can't replace the second factor.
+// The wrong password is counted as the failed login and returns ErrBadCredentials.
+// Returns ErrMFAEnabled if the second factor is already enabled.
+func (s *mfaService) Enroll(
+	ctx context.Context,
+	username, password, ip string,
+) (*models.MFAEnrollment, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if _, err := checkPassword(ctx, s.collection, s.lockout, username, password, ip); err != nil {
+		return nil, err
+	}
+	if err := s.lockout.Succeed(ctx, username); err != nil {
+		return nil, err
+	}
+	secret, err := newRandomBase32(mfaSecretSize)
+	if err != nil {
+		return nil, err
+	}
+	encryptedSecret, err := s.keyring.EncryptString(secret)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"username": username, "mfa.enabled": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"mfa": models.MFA{Secret: encryptedSecret}}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, srvErrors.ErrMFAEnabled
+	}
+	key := otp.NewKey(otp.TypeTOTP, secret)
+	key.Issuer, key.Account = MFAIssuer, username
+	return &models.MFAEnrollment{Secret: secret, URI: key.URI()}, nil
+}
+
+// Confirm checks the code of the enrolled secret, enables the second factor
+// and returns the recovery codes. Returns ErrMFANotEnrolled if there is no
+// enrolled secret and ErrMFAEnabled if the second factor is already enabled.
+func (s *mfaService) Confirm(
+	ctx context.Context,
+	username, code string,
+) (*models.MFARecoveryCodes, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	user, err := s.findUser(ctx, username)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, srvErrors.ErrMFANotEnrolled
+	} else if err != nil {
+		return nil, err
+	}
+	switch {
+	case user.MFA == nil:
+		return nil, srvErrors.ErrMFANotEnrolled
+	case user.MFA.Enabled:
+		return nil, srvErrors.ErrMFAEnabled
+	}
+	step, err := s.checkCode(user.MFA.Secret, normalizeCode(code), 0)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	// the filter rejects the secret replaced by a concurrent enrollment
+	res, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"username": username, "mfa.enabled": false, "mfa.secret": user.MFA.Secret},
+		bson.M{"$set": bson.M{
+			"mfa.enabled":        true,
+			"mfa.recovery_codes": hashes,
+			"mfa.last_step":      step,
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, srvErrors.ErrMFANotEnrolled
+	}
+	return &models.MFARecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// Disable checks the password and the one-time or the recovery code and turns
+// off the second factor, so a stolen access token can't remove the second factor.
+// The wrong password is counted as the failed login and returns ErrBadCredentials.
+// Returns ErrMFADisabled if the second factor is not enabled.
+func (s *mfaService) Disable(ctx context.Context, username, password, code, ip string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	user, err := checkPassword(ctx, s.collection, s.lockout, username, password, ip)
+	if err != nil {
+		return err
+	}
+	if err := s.lockout.Succeed(ctx, username); err != nil {
+		return err
+	}
+	if user.MFA == nil || !user.MFA.Enabled {
+		return srvErrors.ErrMFADisabled
+	}
+	if err := s.verifyCode(ctx, username, user.MFA, code); err != nil {
+		return err
+	}
+	_, err = s.collection.UpdateOne(
+		ctx,
+		bson.M{"username": username},
+		bson.M{"$unset": bson.M{"mfa": ""}},
+	)
+	return err
+}
+
+// Challenge issues the challenge token of the login of the user.
+func (s *mfaService) Challenge(username string) (*models.TokenPair, error) {
+	token, claims, err := s.keys.NewMFAToken(username, s.challengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TokenPair{
+		ExpiresAt:   claims.ExpiresAt.Time,
+		MFARequired: true,
+		MFAToken:    token,
+	}, nil
+}
+
+// ParseChallenge checks the challenge token and returns its username.
+// Returns ErrBadMFAToken if the token is invalid or expired.
+func (s *mfaService) ParseChallenge(mfaToken string) (string, error) {
+	claims, err := s.keys.ParseMFAToken(mfaToken)
+	if err != nil {
+		return "", srvErrors.ErrBadMFAToken
+	}
+	return claims.Username, nil
+}
+
+// Verify checks the challenge token and the one-time or the recovery code.
+// Returns ErrBadMFAToken if the token is invalid or expired and ErrBadMFACode
+// if the code is wrong or already used.
+func (s *mfaService) Verify(ctx context.Context, mfaToken, code string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	username, err := s.ParseChallenge(mfaToken)
+	if err != nil {
+		return "", err
+	}
+	user, err := s.findUser(ctx, username)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", srvErrors.ErrBadMFAToken
+	} else if err != nil {
+		return "", err
+	}
+	if user.MFA == nil || !user.MFA.Enabled {
+		return "", srvErrors.ErrBadMFAToken
+	}
+	if err := s.verifyCode(ctx, username, user.MFA, code); err != nil {
+		return "", err
+	}
+	return username, nil
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+	"github.com/blokhinnv/gophkeeper/pkg/otp"
+)
+
+type MFAServiceTestSuite struct {
+	suite.Suite
+	keyring        *encrypt.Keyring
+	secret         string
+	hashedPassword string
+	now            time.Time
+}
+
+// mfaPassword is the password of the user of the tests.
+const mfaPassword = "testpassword"
+
+func (suite *MFAServiceTestSuite) SetupSuite() {
+	suite.keyring = newTestKeyring(suite.T(), "key")
+	suite.secret = "JBSWY3DPEHPK3PXP"
+	suite.now = time.Unix(1700000000, 0)
+	hashed, err := bcrypt.GenerateFromPassword([]byte(mfaPassword), bcrypt.MinCost)
+	require.NoError(suite.T(), err)
+	suite.hashedPassword = string(hashed)
+}
+
+// newService creates the service which time is fixed.
+func (suite *MFAServiceTestSuite) newService(mt *mtest.T) MFAService {
+	s := NewMFAService(
+		mt.Coll,
+		suite.keyring,
+		auth.NewHMACKeySet([]byte("my-secret-key")),
+		NewLockoutService(NewMemoryAttemptStore(), testLockoutPolicy),
+		time.Minute,
+	)
+	s.(*mfaService).now = func() time.Time { return suite.now }
+	return s
+}
+
+// code returns the one-time code of the secret at the moment shifted by the steps.
+func (suite *MFAServiceTestSuite) code(steps int) string {
+	code, _, err := otp.NewKey(otp.TypeTOTP, suite.secret).
+		Code(suite.now.Add(time.Duration(steps*otp.DefaultPeriod) * time.Second))
+	require.NoError(suite.T(), err)
+	return code
+}
+
+// userResponse returns the response with the user having the second factor;
+// no second factor if mfa is nil.
+func (suite *MFAServiceTestSuite) userResponse(mt *mtest.T, mfa bson.D) bson.D {
+	user := bson.D{
+		{Key: "username", Value: "alice"},
+		{Key: "hashedPassword", Value: suite.hashedPassword},
+	}
+	if mfa != nil {
+		user = append(user, bson.E{Key: "mfa", Value: mfa})
+	}
+	return mtest.CreateCursorResponse(0, mt.DB.Name()+"."+mt.Coll.Name(), mtest.FirstBatch, user)
+}
+
+// commandNames returns the names of the commands sent to the DB.
+func commandNames(mt *mtest.T) []string {
+	var names []string
+	for _, e := range mt.GetAllStartedEvents() {
+		names = append(names, e.CommandName)
+	}
+	return names
+}
+
+// pendingMFA returns the second factor which is enrolled but not confirmed.
+func (suite *MFAServiceTestSuite) pendingMFA() bson.D {
+	encrypted, err := suite.keyring.EncryptString(suite.secret)
+	require.NoError(suite.T(), err)
+	return bson.D{{Key: "secret", Value: encrypted}, {Key: "enabled", Value: false}}
+}
+
+// enabledMFA returns the enabled second factor with the recovery code.
+func (suite *MFAServiceTestSuite) enabledMFA(lastStep int64, recoveryCode string) bson.D {
+	return append(
+		suite.pendingMFA()[:1],
+		bson.E{Key: "enabled", Value: true},
+		bson.E{Key: "recovery_codes", Value: bson.A{auth.HashToken(recoveryCode)}},
+		bson.E{Key: "last_step", Value: lastStep},
+	)
+}
+
+// updated returns the number of the documents matched by the update.
+func updated(n int) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+}
+
+// lastUpdate returns the last update command.
+func lastUpdate(mt *mtest.T) bson.Raw {
+	var cmd bson.Raw
+	for _, e := range mt.GetAllStartedEvents() {
+		if e.CommandName == "update" {
+			cmd = e.Command
+		}
+	}
+	require.NotNil(mt, cmd)
+	return cmd
+}
+
+func (suite *MFAServiceTestSuite) TestEnroll() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("ok", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, nil), updated(1))
+		enrollment, err := suite.newService(mt).Enroll(context.TODO(), "alice", mfaPassword, "127.0.0.1")
+		require.NoError(t, err)
+		key, err := otp.ParseURI(enrollment.URI)
+		require.NoError(t, err)
+		assert.Equal(t, enrollment.Secret, key.Secret)
+		assert.Equal(t, MFAIssuer, key.Issuer)
+		assert.Equal(t, "alice", key.Account)
+
+		// the secret is saved encrypted
+		update := lastUpdate(mt).Lookup("updates").Array().Index(0).Value().Document()
+		saved := update.Lookup("u", "$set", "mfa", "secret").StringValue()
+		assert.NotEqual(t, enrollment.Secret, saved)
+		decrypted, err := suite.keyring.DecryptString(saved)
+		require.NoError(t, err)
+		assert.Equal(t, enrollment.Secret, decrypted)
+	})
+	mt.Run("enabled", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, nil), updated(0))
+		_, err := suite.newService(mt).Enroll(context.TODO(), "alice", mfaPassword, "127.0.0.1")
+		assert.ErrorIs(t, err, errors.ErrMFAEnabled)
+	})
+	mt.Run("wrong_password", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, nil))
+		_, err := suite.newService(mt).Enroll(context.TODO(), "alice", "wrong", "127.0.0.1")
+		assert.ErrorIs(t, err, errors.ErrBadCredentials)
+		// no secret is saved
+		assert.Equal(t, []string{"find"}, commandNames(mt))
+	})
+}
+
+func (suite *MFAServiceTestSuite) TestConfirm() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("ok", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, suite.pendingMFA()), updated(1))
+		codes, err := suite.newService(mt).Confirm(context.TODO(), "alice", suite.code(-1))
+		require.NoError(t, err)
+		assert.Len(t, codes.RecoveryCodes, mfaRecoveryCodesCount)
+		assert.NotEqual(t, codes.RecoveryCodes[0], codes.RecoveryCodes[1])
+	})
+	mt.Run("wrong_code", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, suite.pendingMFA()))
+		_, err := suite.newService(mt).Confirm(context.TODO(), "alice", suite.code(-2))
+		assert.ErrorIs(t, err, errors.ErrBadMFACode)
+	})
+	mt.Run("not_enrolled", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.users", mtest.FirstBatch, bson.D{
+			{Key: "username", Value: "alice"},
+		}))
+		_, err := suite.newService(mt).Confirm(context.TODO(), "alice", suite.code(0))
+		assert.ErrorIs(t, err, errors.ErrMFANotEnrolled)
+	})
+	mt.Run("enabled", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, suite.enabledMFA(0, "recovery")))
+		_, err := suite.newService(mt).Confirm(context.TODO(), "alice", suite.code(0))
+		assert.ErrorIs(t, err, errors.ErrMFAEnabled)
+	})
+}
+
+func (suite *MFAServiceTestSuite) TestVerify() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	step := suite.now.Unix() / otp.DefaultPeriod
+	challenge := func(mt *mtest.T) string {
+		tokens, err := suite.newService(mt).Challenge("alice")
+		require.NoError(t, err)
+		assert.True(t, tokens.MFARequired)
+		assert.Empty(t, tokens.AccessToken)
+		return tokens.MFAToken
+	}
+	mt.Run("code", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, suite.enabledMFA(step-1, "recovery")), updated(1))
+		username, err := suite.newService(mt).Verify(context.TODO(), challenge(mt), suite.code(0))
+		require.NoError(t, err)
+		assert.Equal(t, "alice", username)
+	})
+	mt.Run("used_code", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, suite.enabledMFA(step, "recovery")))
+		_, err := suite.newService(mt).Verify(context.TODO(), challenge(mt), suite.code(0))
+		assert.ErrorIs(t, err, errors.ErrBadMFACode)
+	})
+	mt.Run("recovery_code", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, suite.enabledMFA(step, "abcd2345")), updated(1))
+		_, err := suite.newService(mt).Verify(context.TODO(), challenge(mt), "ABCD-2345")
+		require.NoError(t, err)
+		update := lastUpdate(mt).Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(
+			t,
+			auth.HashToken("abcd2345"),
+			update.Lookup("u", "$pull", "mfa.recovery_codes").StringValue(),
+		)
+	})
+	mt.Run("used_recovery_code", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, suite.enabledMFA(step, "abcd2345")), updated(0))
+		_, err := suite.newService(mt).Verify(context.TODO(), challenge(mt), "abcd-2345")
+		assert.ErrorIs(t, err, errors.ErrBadMFACode)
+	})
+	mt.Run("bad_token", func(mt *mtest.T) {
+		access, _, err := auth.NewAccessToken("alice", "", []byte("my-secret-key"), time.Minute)
+		require.NoError(t, err)
+		_, err = suite.newService(mt).Verify(context.TODO(), access, suite.code(0))
+		assert.ErrorIs(t, err, errors.ErrBadMFAToken)
+	})
+}
+
+func (suite *MFAServiceTestSuite) TestDisable() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("ok", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, suite.enabledMFA(0, "recovery")), updated(1), updated(1))
+		err := suite.newService(mt).
+			Disable(context.TODO(), "alice", mfaPassword, suite.code(0), "127.0.0.1")
+		require.NoError(t, err)
+		update := lastUpdate(mt).Lookup("updates").Array().Index(0).Value().Document()
+		_, err = update.LookupErr("u", "$unset", "mfa")
+		assert.NoError(t, err)
+	})
+	mt.Run("wrong_code", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, suite.enabledMFA(0, "recovery")))
+		err := suite.newService(mt).
+			Disable(context.TODO(), "alice", mfaPassword, suite.code(2), "127.0.0.1")
+		assert.ErrorIs(t, err, errors.ErrBadMFACode)
+	})
+	mt.Run("wrong_password", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, suite.enabledMFA(0, "recovery")))
+		err := suite.newService(mt).Disable(context.TODO(), "alice", "wrong", suite.code(0), "127.0.0.1")
+		assert.ErrorIs(t, err, errors.ErrBadCredentials)
+		// the second factor is kept
+		assert.Equal(t, []string{"find"}, commandNames(mt))
+	})
+	mt.Run("disabled", func(mt *mtest.T) {
+		mt.AddMockResponses(suite.userResponse(mt, suite.pendingMFA()))
+		err := suite.newService(mt).
+			Disable(context.TODO(), "alice", mfaPassword, suite.code(0), "127.0.0.1")
+		assert.ErrorIs(t, err, errors.ErrMFADisabled)
+	})
+}
+
+func TestMFAServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(MFAServiceTestSuite))
+}
@@ -0,0 +1,142 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/blokhinnv/gophkeeper/internal/server/service (interfaces: BlobService)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	io "io"
+	reflect "reflect"
+	time "time"
+
+	models "github.com/blokhinnv/gophkeeper/internal/server/models"
+	gomock "github.com/golang/mock/gomock"
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockBlobService is a mock of BlobService interface.
+type MockBlobService struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlobServiceMockRecorder
+}
+
+// MockBlobServiceMockRecorder is the mock recorder for MockBlobService.
+type MockBlobServiceMockRecorder struct {
+	mock *MockBlobService
+}
+
+// NewMockBlobService creates a new mock instance.
+func NewMockBlobService(ctrl *gomock.Controller) *MockBlobService {
+	mock := &MockBlobService{ctrl: ctrl}
+	mock.recorder = &MockBlobServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlobService) EXPECT() *MockBlobServiceMockRecorder {
+	return m.recorder
+}
+
+// Append mocks base method.
+func (m *MockBlobService) Append(arg0 context.Context, arg1 string, arg2 primitive.ObjectID, arg3 int64, arg4 []byte) (*models.Blob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*models.Blob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockBlobServiceMockRecorder) Append(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockBlobService)(nil).Append), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Create mocks base method.
+func (m *MockBlobService) Create(arg0 context.Context, arg1 string, arg2 int64) (*models.Blob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Blob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockBlobServiceMockRecorder) Create(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockBlobService)(nil).Create), arg0, arg1, arg2)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockBlobService) EnsureIndexes(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockBlobServiceMockRecorder) EnsureIndexes(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockBlobService)(nil).EnsureIndexes), arg0)
+}
+
+// Get mocks base method.
+func (m *MockBlobService) Get(arg0 context.Context, arg1 string, arg2 primitive.ObjectID) (*models.Blob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Blob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockBlobServiceMockRecorder) Get(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockBlobService)(nil).Get), arg0, arg1, arg2)
+}
+
+// PurgeIncomplete mocks base method.
+func (m *MockBlobService) PurgeIncomplete(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeIncomplete", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeIncomplete indicates an expected call of PurgeIncomplete.
+func (mr *MockBlobServiceMockRecorder) PurgeIncomplete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeIncomplete", reflect.TypeOf((*MockBlobService)(nil).PurgeIncomplete), arg0, arg1)
+}
+
+// PurgeReleased mocks base method.
+func (m *MockBlobService) PurgeReleased(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeReleased", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeReleased indicates an expected call of PurgeReleased.
+func (mr *MockBlobServiceMockRecorder) PurgeReleased(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeReleased", reflect.TypeOf((*MockBlobService)(nil).PurgeReleased), arg0, arg1)
+}
+
+// Read mocks base method.
+func (m *MockBlobService) Read(arg0 context.Context, arg1 string, arg2 primitive.ObjectID, arg3 io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockBlobServiceMockRecorder) Read(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockBlobService)(nil).Read), arg0, arg1, arg2, arg3)
+}
@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/blokhinnv/gophkeeper/internal/server/service (interfaces: DeviceService)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/blokhinnv/gophkeeper/internal/server/models"
+	gomock "github.com/golang/mock/gomock"
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockDeviceService is a mock of DeviceService interface.
+type MockDeviceService struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeviceServiceMockRecorder
+}
+
+// MockDeviceServiceMockRecorder is the mock recorder for MockDeviceService.
+type MockDeviceServiceMockRecorder struct {
+	mock *MockDeviceService
+}
+
+// NewMockDeviceService creates a new mock instance.
+func NewMockDeviceService(ctrl *gomock.Controller) *MockDeviceService {
+	mock := &MockDeviceService{ctrl: ctrl}
+	mock.recorder = &MockDeviceServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeviceService) EXPECT() *MockDeviceServiceMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockDeviceService) Delete(arg0 context.Context, arg1 string, arg2 primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockDeviceServiceMockRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDeviceService)(nil).Delete), arg0, arg1, arg2)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockDeviceService) EnsureIndexes(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockDeviceServiceMockRecorder) EnsureIndexes(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockDeviceService)(nil).EnsureIndexes), arg0)
+}
+
+// List mocks base method.
+func (m *MockDeviceService) List(arg0 context.Context, arg1 string) ([]models.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].([]models.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockDeviceServiceMockRecorder) List(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockDeviceService)(nil).List), arg0, arg1)
+}
+
+// Nonce mocks base method.
+func (m *MockDeviceService) Nonce() (*models.DeviceNonce, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Nonce")
+	ret0, _ := ret[0].(*models.DeviceNonce)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Nonce indicates an expected call of Nonce.
+func (mr *MockDeviceServiceMockRecorder) Nonce() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Nonce", reflect.TypeOf((*MockDeviceService)(nil).Nonce))
+}
+
+// Register mocks base method.
+func (m *MockDeviceService) Register(arg0 context.Context, arg1 string, arg2 models.DeviceInfo) (*models.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Register", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockDeviceServiceMockRecorder) Register(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockDeviceService)(nil).Register), arg0, arg1, arg2)
+}
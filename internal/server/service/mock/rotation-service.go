@@ -0,0 +1,93 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/blokhinnv/gophkeeper/internal/server/service (interfaces: RotationService)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/blokhinnv/gophkeeper/internal/server/models"
+	service "github.com/blokhinnv/gophkeeper/internal/server/service"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockRotationService is a mock of RotationService interface.
+type MockRotationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockRotationServiceMockRecorder
+}
+
+// MockRotationServiceMockRecorder is the mock recorder for MockRotationService.
+type MockRotationServiceMockRecorder struct {
+	mock *MockRotationService
+}
+
+// NewMockRotationService creates a new mock instance.
+func NewMockRotationService(ctrl *gomock.Controller) *MockRotationService {
+	mock := &MockRotationService{ctrl: ctrl}
+	mock.recorder = &MockRotationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRotationService) EXPECT() *MockRotationServiceMockRecorder {
+	return m.recorder
+}
+
+// CheckLegacyMigration mocks base method.
+func (m *MockRotationService) CheckLegacyMigration(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckLegacyMigration", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckLegacyMigration indicates an expected call of CheckLegacyMigration.
+func (mr *MockRotationServiceMockRecorder) CheckLegacyMigration(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLegacyMigration", reflect.TypeOf((*MockRotationService)(nil).CheckLegacyMigration), arg0)
+}
+
+// MarkLegacy mocks base method.
+func (m *MockRotationService) MarkLegacy(arg0 context.Context, arg1 int, arg2 func(service.RotationProgress)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkLegacy", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkLegacy indicates an expected call of MarkLegacy.
+func (mr *MockRotationServiceMockRecorder) MarkLegacy(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkLegacy", reflect.TypeOf((*MockRotationService)(nil).MarkLegacy), arg0, arg1, arg2)
+}
+
+// Rotate mocks base method.
+func (m *MockRotationService) Rotate(arg0 context.Context, arg1 int, arg2 func(service.RotationProgress)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rotate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rotate indicates an expected call of Rotate.
+func (mr *MockRotationServiceMockRecorder) Rotate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rotate", reflect.TypeOf((*MockRotationService)(nil).Rotate), arg0, arg1, arg2)
+}
+
+// RotateCollection mocks base method.
+func (m *MockRotationService) RotateCollection(arg0 context.Context, arg1 models.CollectionName, arg2 int, arg3 func(service.RotationProgress)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateCollection", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateCollection indicates an expected call of RotateCollection.
+func (mr *MockRotationServiceMockRecorder) RotateCollection(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateCollection", reflect.TypeOf((*MockRotationService)(nil).RotateCollection), arg0, arg1, arg2, arg3)
+}
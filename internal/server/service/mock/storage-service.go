@@ -7,6 +7,7 @@ package mock
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	models "github.com/blokhinnv/gophkeeper/internal/server/models"
 	gomock "github.com/golang/mock/gomock"
@@ -36,6 +37,21 @@ func (m *MockStorageService) EXPECT() *MockStorageServiceMockRecorder {
 	return m.recorder
 }
 
+// Changes mocks base method.
+func (m *MockStorageService) Changes(arg0 context.Context, arg1 string, arg2 time.Time) (*models.Changes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Changes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockStorageServiceMockRecorder) Changes(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockStorageService)(nil).Changes), arg0, arg1, arg2)
+}
+
 // Delete mocks base method.
 func (m *MockStorageService) Delete(arg0 context.Context, arg1 models.CollectionName, arg2 string, arg3 primitive.ObjectID) error {
 	m.ctrl.T.Helper()
@@ -50,19 +66,138 @@ func (mr *MockStorageServiceMockRecorder) Delete(arg0, arg1, arg2, arg3 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorageService)(nil).Delete), arg0, arg1, arg2, arg3)
 }
 
+// EnsureIndexes mocks base method.
+func (m *MockStorageService) EnsureIndexes(arg0 context.Context, arg1 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockStorageServiceMockRecorder) EnsureIndexes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockStorageService)(nil).EnsureIndexes), arg0, arg1)
+}
+
+// Get mocks base method.
+func (m *MockStorageService) Get(arg0 context.Context, arg1 models.CollectionName, arg2 string, arg3 primitive.ObjectID) (*models.UntypedRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*models.UntypedRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockStorageServiceMockRecorder) Get(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStorageService)(nil).Get), arg0, arg1, arg2, arg3)
+}
+
 // GetAll mocks base method.
-func (m *MockStorageService) GetAll(arg0 context.Context, arg1 models.CollectionName, arg2 string) ([]models.UntypedRecord, error) {
+func (m *MockStorageService) GetAll(arg0 context.Context, arg1 models.CollectionName, arg2 string, arg3 models.GetAllOptions) ([]models.UntypedRecord, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "GetAll", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].([]models.UntypedRecord)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockStorageServiceMockRecorder) GetAll(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockStorageService)(nil).GetAll), arg0, arg1, arg2, arg3)
+}
+
+// History mocks base method.
+func (m *MockStorageService) History(arg0 context.Context, arg1 models.CollectionName, arg2 string, arg3 primitive.ObjectID) ([]models.Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]models.Revision)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAll indicates an expected call of GetAll.
-func (mr *MockStorageServiceMockRecorder) GetAll(arg0, arg1, arg2 interface{}) *gomock.Call {
+// History indicates an expected call of History.
+func (mr *MockStorageServiceMockRecorder) History(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockStorageService)(nil).GetAll), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockStorageService)(nil).History), arg0, arg1, arg2, arg3)
+}
+
+// Purge mocks base method.
+func (m *MockStorageService) Purge(arg0 context.Context, arg1 models.CollectionName, arg2 string, arg3 primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockStorageServiceMockRecorder) Purge(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockStorageService)(nil).Purge), arg0, arg1, arg2, arg3)
+}
+
+// PurgeTrash mocks base method.
+func (m *MockStorageService) PurgeTrash(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeTrash", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeTrash indicates an expected call of PurgeTrash.
+func (mr *MockStorageServiceMockRecorder) PurgeTrash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeTrash", reflect.TypeOf((*MockStorageService)(nil).PurgeTrash), arg0, arg1)
+}
+
+// Reindex mocks base method.
+func (m *MockStorageService) Reindex(arg0 context.Context, arg1 models.CollectionName, arg2 int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reindex", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reindex indicates an expected call of Reindex.
+func (mr *MockStorageServiceMockRecorder) Reindex(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reindex", reflect.TypeOf((*MockStorageService)(nil).Reindex), arg0, arg1, arg2)
+}
+
+// Restore mocks base method.
+func (m *MockStorageService) Restore(arg0 context.Context, arg1 models.CollectionName, arg2 string, arg3 primitive.ObjectID, arg4 int64) (*models.UntypedRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*models.UntypedRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockStorageServiceMockRecorder) Restore(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStorageService)(nil).Restore), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Search mocks base method.
+func (m *MockStorageService) Search(arg0 context.Context, arg1, arg2 string) ([]models.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockStorageServiceMockRecorder) Search(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockStorageService)(nil).Search), arg0, arg1, arg2)
 }
 
 // Store mocks base method.
@@ -80,16 +215,47 @@ func (mr *MockStorageServiceMockRecorder) Store(arg0, arg1, arg2 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Store", reflect.TypeOf((*MockStorageService)(nil).Store), arg0, arg1, arg2)
 }
 
+// Trash mocks base method.
+func (m *MockStorageService) Trash(arg0 context.Context, arg1 models.CollectionName, arg2 string) ([]models.UntypedRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trash", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.UntypedRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Trash indicates an expected call of Trash.
+func (mr *MockStorageServiceMockRecorder) Trash(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trash", reflect.TypeOf((*MockStorageService)(nil).Trash), arg0, arg1, arg2)
+}
+
+// Undelete mocks base method.
+func (m *MockStorageService) Undelete(arg0 context.Context, arg1 models.CollectionName, arg2 string, arg3 primitive.ObjectID) (*models.UntypedRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Undelete", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*models.UntypedRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Undelete indicates an expected call of Undelete.
+func (mr *MockStorageServiceMockRecorder) Undelete(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Undelete", reflect.TypeOf((*MockStorageService)(nil).Undelete), arg0, arg1, arg2, arg3)
+}
+
 // Update mocks base method.
-func (m *MockStorageService) Update(arg0 context.Context, arg1 models.CollectionName, arg2 string, arg3 primitive.ObjectID, arg4 interface{}, arg5 models.Metadata) error {
+func (m *MockStorageService) Update(arg0 context.Context, arg1 models.CollectionName, arg2 string, arg3 primitive.ObjectID, arg4 interface{}, arg5 models.Metadata, arg6 int64) (*models.UntypedRecord, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2, arg3, arg4, arg5)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(*models.UntypedRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockStorageServiceMockRecorder) Update(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+func (mr *MockStorageServiceMockRecorder) Update(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorageService)(nil).Update), arg0, arg1, arg2, arg3, arg4, arg5)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorageService)(nil).Update), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
 }
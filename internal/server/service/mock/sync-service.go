@@ -6,6 +6,7 @@ package mock
 
 import (
 	reflect "reflect"
+	time "time"
 
 	models "github.com/blokhinnv/gophkeeper/internal/server/models"
 	gomock "github.com/golang/mock/gomock"
@@ -34,38 +35,77 @@ func (m *MockSyncService) EXPECT() *MockSyncServiceMockRecorder {
 	return m.recorder
 }
 
-// Register mocks base method.
-func (m *MockSyncService) Register(arg0 *models.Client) {
+// Close mocks base method.
+func (m *MockSyncService) Close() {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Register", arg0)
+	m.ctrl.Call(m, "Close")
 }
 
-// Register indicates an expected call of Register.
-func (mr *MockSyncServiceMockRecorder) Register(arg0 interface{}) *gomock.Call {
+// Close indicates an expected call of Close.
+func (mr *MockSyncServiceMockRecorder) Close() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockSyncService)(nil).Register), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockSyncService)(nil).Close))
 }
 
-// Signal mocks base method.
-func (m *MockSyncService) Signal(arg0 *models.Client) {
+// Disconnect mocks base method.
+func (m *MockSyncService) Disconnect(arg0, arg1 string) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Signal", arg0)
+	m.ctrl.Call(m, "Disconnect", arg0, arg1)
 }
 
-// Signal indicates an expected call of Signal.
-func (mr *MockSyncServiceMockRecorder) Signal(arg0 interface{}) *gomock.Call {
+// Disconnect indicates an expected call of Disconnect.
+func (mr *MockSyncServiceMockRecorder) Disconnect(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Signal", reflect.TypeOf((*MockSyncService)(nil).Signal), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disconnect", reflect.TypeOf((*MockSyncService)(nil).Disconnect), arg0, arg1)
 }
 
-// Unregister mocks base method.
-func (m *MockSyncService) Unregister(arg0 *models.Client) {
+// DisconnectSession mocks base method.
+func (m *MockSyncService) DisconnectSession(arg0, arg1 string) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Unregister", arg0)
+	m.ctrl.Call(m, "DisconnectSession", arg0, arg1)
 }
 
-// Unregister indicates an expected call of Unregister.
-func (mr *MockSyncServiceMockRecorder) Unregister(arg0 interface{}) *gomock.Call {
+// DisconnectSession indicates an expected call of DisconnectSession.
+func (mr *MockSyncServiceMockRecorder) DisconnectSession(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unregister", reflect.TypeOf((*MockSyncService)(nil).Unregister), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisconnectSession", reflect.TypeOf((*MockSyncService)(nil).DisconnectSession), arg0, arg1)
+}
+
+// DisconnectUser mocks base method.
+func (m *MockSyncService) DisconnectUser(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DisconnectUser", arg0)
+}
+
+// DisconnectUser indicates an expected call of DisconnectUser.
+func (mr *MockSyncServiceMockRecorder) DisconnectUser(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisconnectUser", reflect.TypeOf((*MockSyncService)(nil).DisconnectUser), arg0)
+}
+
+// Publish mocks base method.
+func (m *MockSyncService) Publish(arg0 string, arg1 models.ChangeEvent) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Publish", arg0, arg1)
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockSyncServiceMockRecorder) Publish(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockSyncService)(nil).Publish), arg0, arg1)
+}
+
+// Subscribe mocks base method.
+func (m *MockSyncService) Subscribe(arg0, arg1, arg2 string, arg3 time.Time) (<-chan models.ChangeEvent, func()) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(<-chan models.ChangeEvent)
+	ret1, _ := ret[1].(func())
+	return ret0, ret1
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockSyncServiceMockRecorder) Subscribe(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockSyncService)(nil).Subscribe), arg0, arg1, arg2, arg3)
 }
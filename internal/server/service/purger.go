@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/blokhinnv/gophkeeper/pkg/log"
+)
+
+// RunTrashPurger permanently deletes the records which have been in the trash
+// for longer than ttl. The trash is checked at start and then every interval
+// until the context is done.
+func RunTrashPurger(
+	ctx context.Context,
+	storage StorageService,
+	ttl time.Duration,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := storage.PurgeTrash(ctx, time.Now().UTC().Add(-ttl))
+		if err != nil {
+			log.Printf("unable to purge the trash: %v\n", err)
+		} else if purged > 0 {
+			log.Printf("%v records purged from the trash\n", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunUploadPurger deletes the blob uploads which are not complete after ttl
+// and the blobs released more than ttl ago which nothing refers to anymore.
+// The uploads are checked at start and then every interval until the context is done.
+func RunUploadPurger(
+	ctx context.Context,
+	blobs BlobService,
+	ttl time.Duration,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		before := time.Now().UTC().Add(-ttl)
+		purged, err := blobs.PurgeIncomplete(ctx, before)
+		if err != nil {
+			log.Printf("unable to purge the incomplete uploads: %v\n", err)
+		} else if purged > 0 {
+			log.Printf("%v incomplete uploads purged\n", purged)
+		}
+		purged, err = blobs.PurgeReleased(ctx, before)
+		if err != nil {
+			log.Printf("unable to purge the released blobs: %v\n", err)
+		} else if purged > 0 {
+			log.Printf("%v released blobs purged\n", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStorage remembers the times passed to PurgeTrash.
+type countingStorage struct {
+	StorageService
+	mu     sync.Mutex
+	before []time.Time
+}
+
+func (s *countingStorage) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.before = append(s.before, deletedBefore)
+	if len(s.before)%2 == 0 {
+		return 0, fmt.Errorf("some error")
+	}
+	return 1, nil
+}
+
+// calls returns the number of the PurgeTrash calls.
+func (s *countingStorage) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.before)
+}
+
+func TestRunTrashPurger(t *testing.T) {
+	storage := &countingStorage{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	start := time.Now().UTC()
+	go func() {
+		RunTrashPurger(ctx, storage, time.Hour, 10*time.Millisecond)
+		close(done)
+	}()
+
+	// the purger keeps running after an error
+	assert.Eventually(t, func() bool { return storage.calls() >= 3 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+	first := storage.before[0]
+	assert.WithinDuration(t, start.Add(-time.Hour), first, time.Second)
+}
+
+// countingBlobs remembers the times passed to PurgeIncomplete and PurgeReleased.
+type countingBlobs struct {
+	BlobService
+	mu       sync.Mutex
+	before   []time.Time
+	released []time.Time
+}
+
+func (s *countingBlobs) PurgeIncomplete(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.before = append(s.before, before)
+	if len(s.before)%2 == 0 {
+		return 0, fmt.Errorf("some error")
+	}
+	return 1, nil
+}
+
+func (s *countingBlobs) PurgeReleased(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.released = append(s.released, before)
+	return 0, nil
+}
+
+// calls returns the number of the PurgeIncomplete calls.
+func (s *countingBlobs) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.before)
+}
+
+func TestRunUploadPurger(t *testing.T) {
+	blobs := &countingBlobs{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	start := time.Now().UTC()
+	go func() {
+		RunUploadPurger(ctx, blobs, time.Hour, 10*time.Millisecond)
+		close(done)
+	}()
+
+	// the purger keeps running after an error
+	assert.Eventually(t, func() bool { return blobs.calls() >= 3 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+	first := blobs.before[0]
+	assert.WithinDuration(t, start.Add(-time.Hour), first, time.Second)
+	// the released blobs are purged after the same period
+	assert.Equal(t, first, blobs.released[0])
+}
@@ -0,0 +1,520 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+// RotationCheckpointsCollection is the collection which keeps
+// the progress of the key rotation of every records collection.
+const RotationCheckpointsCollection = "key_rotation"
+
+// legacyCheckpointKeyID is the key id of the checkpoints of the legacy migration,
+// which doesn't depend on the active key.
+const legacyCheckpointKeyID = "legacy-v0"
+
+// legacyMigrationID is the id of the checkpoint saved when the legacy migration is finished.
+const legacyMigrationID = legacyCheckpointKeyID + "/done"
+
+// RotationProgress describes the progress of the key rotation of a collection.
+type RotationProgress struct {
+	Collection models.CollectionName
+	Processed  int64 // Processed is the number of checked documents.
+	Rotated    int64 // Rotated is the number of re-encrypted documents.
+	Total      int64 // Total is the number of documents in the collection.
+}
+
+// RotationService is an interface for re-encrypting the records with the active key.
+type RotationService interface {
+	// Rotate re-encrypts the records of all the collections and their history,
+	// the chunks of the blobs and the MFA secrets of the users.
+	Rotate(ctx context.Context, batchSize int, progress func(RotationProgress)) error
+	// RotateCollection re-encrypts the records of the collection in batches.
+	// The progress is saved after every batch, so an interrupted rotation
+	// continues from the last finished batch.
+	RotateCollection(
+		ctx context.Context,
+		collectionName models.CollectionName,
+		batchSize int,
+		progress func(RotationProgress),
+	) error
+	// MarkLegacy marks the values of the records in the legacy v0 format,
+	// so they are decrypted until Rotate re-encrypts them. It is run once
+	// on the data written before the authenticated format.
+	MarkLegacy(ctx context.Context, batchSize int, progress func(RotationProgress)) error
+	// CheckLegacyMigration returns ErrLegacyNotMigrated unless MarkLegacy has been
+	// finished. The DB without records needs no migration and is recorded as migrated.
+	CheckLegacyMigration(ctx context.Context) error
+}
+
+// rotationService is the implementation of the RotationService interface.
+type rotationService struct {
+	db      *mongo.Database
+	keyring *encrypt.Keyring
+}
+
+// rotationCheckpoint is the progress of the rotation saved in the DB.
+type rotationCheckpoint struct {
+	KeyID     string          `bson:"key_id"`
+	LastID    models.ObjectID `bson:"last_id"`
+	Processed int64           `bson:"processed"`
+	Rotated   int64           `bson:"rotated"`
+}
+
+// encryptedDocument is a record document with the encrypted data.
+type encryptedDocument struct {
+	ID   models.ObjectID `bson:"_id"`
+	Data any             `bson:"data"`
+}
+
+// NewRotationService creates a new instance of the RotationService.
+func NewRotationService(db *mongo.Database, keyring *encrypt.Keyring) RotationService {
+	return &rotationService{db: db, keyring: keyring}
+}
+
+// Rotate re-encrypts the records of all the collections and their history,
+// then the chunks of the blobs and the MFA secrets of the users.
+func (s *rotationService) Rotate(
+	ctx context.Context,
+	batchSize int,
+	progress func(RotationProgress),
+) error {
+	for _, collectionName := range models.AllowedCollectionNames {
+		if err := s.RotateCollection(ctx, collectionName, batchSize, progress); err != nil {
+			return err
+		}
+		err := s.RotateCollection(ctx, HistoryCollectionName(collectionName), batchSize, progress)
+		if err != nil {
+			return err
+		}
+	}
+	err := s.walk(
+		ctx,
+		BlobChunksCollection,
+		BlobChunksCollection,
+		s.keyring.ActiveID(),
+		batchSize,
+		progress,
+		s.rotateChunk,
+	)
+	if err != nil {
+		return err
+	}
+	return s.walk(
+		ctx,
+		UsersCollection,
+		UsersCollection,
+		s.keyring.ActiveID(),
+		batchSize,
+		progress,
+		s.rotateMFASecret,
+	)
+}
+
+// RotateCollection re-encrypts the records of the collection in batches.
+func (s *rotationService) RotateCollection(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	batchSize int,
+	progress func(RotationProgress),
+) error {
+	return s.walk(
+		ctx,
+		collectionName,
+		string(collectionName),
+		s.keyring.ActiveID(),
+		batchSize,
+		progress,
+		s.rotateDocument,
+	)
+}
+
+// MarkLegacy marks the legacy values of the records of all the collections and their history.
+func (s *rotationService) MarkLegacy(
+	ctx context.Context,
+	batchSize int,
+	progress func(RotationProgress),
+) error {
+	for _, collectionName := range models.AllowedCollectionNames {
+		for _, name := range []models.CollectionName{
+			collectionName,
+			HistoryCollectionName(collectionName),
+		} {
+			err := s.walk(
+				ctx,
+				name,
+				legacyCheckpointKeyID+"/"+string(name),
+				legacyCheckpointKeyID,
+				batchSize,
+				progress,
+				s.markDocument,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return s.finishLegacyMigration(ctx)
+}
+
+// CheckLegacyMigration checks that the legacy migration has been finished.
+func (s *rotationService) CheckLegacyMigration(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := s.db.Collection(RotationCheckpointsCollection).
+		FindOne(ctx, bson.M{"_id": legacyMigrationID}).
+		Err()
+	if err != mongo.ErrNoDocuments {
+		return err
+	}
+	for _, collectionName := range models.AllowedCollectionNames {
+		for _, name := range []models.CollectionName{
+			collectionName,
+			HistoryCollectionName(collectionName),
+		} {
+			err := s.db.Collection(string(name)).
+				FindOne(ctx, bson.M{}, options.FindOne().SetProjection(bson.M{"_id": 1})).
+				Err()
+			if err == nil {
+				return srvErrors.ErrLegacyNotMigrated
+			} else if err != mongo.ErrNoDocuments {
+				return err
+			}
+		}
+	}
+	return s.finishLegacyMigration(ctx)
+}
+
+// finishLegacyMigration saves the checkpoint of the finished legacy migration.
+func (s *rotationService) finishLegacyMigration(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	_, err := s.db.Collection(RotationCheckpointsCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": legacyMigrationID},
+		bson.M{"$set": bson.M{"key_id": legacyCheckpointKeyID, "finished_at": time.Now().UTC()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// walk applies fn to the documents of the collection in batches. The progress is saved
+// with the checkpoint ID after every batch and is kept while the key id is the same.
+// fn returns true if the document is changed.
+func (s *rotationService) walk(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	checkpointID string,
+	keyID string,
+	batchSize int,
+	progress func(RotationProgress),
+	fn func(context.Context, *mongo.Collection, bson.Raw) (bool, error),
+) error {
+	collection := s.db.Collection(string(collectionName))
+	checkpoint, err := s.loadCheckpoint(ctx, checkpointID, keyID)
+	if err != nil {
+		return err
+	}
+	total, err := s.count(ctx, collection)
+	if err != nil {
+		return err
+	}
+	for {
+		batch, err := s.nextBatch(ctx, collection, checkpoint.LastID, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		for _, doc := range batch {
+			rotated, err := fn(ctx, collection, doc)
+			if err != nil {
+				return err
+			}
+			if rotated {
+				checkpoint.Rotated++
+			}
+			checkpoint.Processed++
+			checkpoint.LastID = doc.Lookup("_id").ObjectID()
+		}
+		if err := s.saveCheckpoint(ctx, checkpointID, checkpoint); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(RotationProgress{
+				Collection: collectionName,
+				Processed:  checkpoint.Processed,
+				Rotated:    checkpoint.Rotated,
+				Total:      total,
+			})
+		}
+	}
+}
+
+// loadCheckpoint returns the saved progress of the rotation to the key.
+// The rotation starts from the beginning if another key was active.
+func (s *rotationService) loadCheckpoint(
+	ctx context.Context,
+	checkpointID string,
+	keyID string,
+) (*rotationCheckpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	var checkpoint rotationCheckpoint
+	err := s.db.Collection(RotationCheckpointsCollection).
+		FindOne(ctx, bson.M{"_id": checkpointID}).
+		Decode(&checkpoint)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if err == mongo.ErrNoDocuments || checkpoint.KeyID != keyID {
+		return &rotationCheckpoint{KeyID: keyID}, nil
+	}
+	return &checkpoint, nil
+}
+
+// saveCheckpoint saves the progress of the rotation.
+func (s *rotationService) saveCheckpoint(
+	ctx context.Context,
+	checkpointID string,
+	checkpoint *rotationCheckpoint,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	_, err := s.db.Collection(RotationCheckpointsCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": checkpointID},
+		bson.M{"$set": checkpoint},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// count returns the number of documents in the collection.
+func (s *rotationService) count(ctx context.Context, collection *mongo.Collection) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	return collection.CountDocuments(ctx, bson.M{})
+}
+
+// nextBatch returns the documents following the one with the specified ID.
+func (s *rotationService) nextBatch(
+	ctx context.Context,
+	collection *mongo.Collection,
+	lastID models.ObjectID,
+	batchSize int,
+) ([]bson.Raw, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	filter := bson.M{}
+	if !lastID.IsZero() {
+		filter["_id"] = bson.M{"$gt": lastID}
+	}
+	cur, err := collection.Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(batchSize)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	batch := make([]bson.Raw, 0, batchSize)
+	if err := cur.All(ctx, &batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// rotateDocument re-encrypts the data of the document if any of its values
+// was encrypted with an old key. The document is not changed if it was
+// updated concurrently: the update has already used the active key.
+func (s *rotationService) rotateDocument(
+	ctx context.Context,
+	collection *mongo.Collection,
+	raw bson.Raw,
+) (bool, error) {
+	var doc encryptedDocument
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return false, err
+	}
+	if s.isActive(doc.Data) {
+		return false, nil
+	}
+	data, err := decryptData(s.keyring, doc.Data)
+	if err != nil {
+		return false, err
+	}
+	encryptedData, err := encryptData(s.keyring, data)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	res, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": doc.ID, "data": doc.Data},
+		bson.M{"$set": bson.M{"data": encryptedData}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// rotateChunk re-encrypts the chunk of a blob if it was encrypted with an old key.
+// The chunk is not changed if it was replaced concurrently: the upload has
+// already used the active key.
+func (s *rotationService) rotateChunk(
+	ctx context.Context,
+	collection *mongo.Collection,
+	raw bson.Raw,
+) (bool, error) {
+	var doc struct {
+		ID               models.ObjectID `bson:"_id"`
+		models.BlobChunk `bson:",inline"`
+	}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return false, err
+	}
+	if doc.KeyID == s.keyring.ActiveID() {
+		return false, nil
+	}
+	ad := chunkAD(doc.BlobID, doc.N)
+	data, err := s.keyring.DecryptBytesWithAD(doc.KeyID, doc.Data, ad)
+	if err != nil {
+		return false, err
+	}
+	keyID, reencrypted, err := s.keyring.EncryptBytesWithAD(data, ad)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	res, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": doc.ID, "key_id": doc.KeyID},
+		bson.M{"$set": bson.M{"key_id": keyID, "data": reencrypted}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// rotateMFASecret re-encrypts the MFA secret of the user if it was encrypted
+// with an old key. The user is not changed if the secret was replaced
+// concurrently: the new secret is encrypted with the active key.
+func (s *rotationService) rotateMFASecret(
+	ctx context.Context,
+	collection *mongo.Collection,
+	raw bson.Raw,
+) (bool, error) {
+	var user struct {
+		ID  models.ObjectID `bson:"_id"`
+		MFA *models.MFA     `bson:"mfa,omitempty"`
+	}
+	if err := bson.Unmarshal(raw, &user); err != nil {
+		return false, err
+	}
+	if user.MFA == nil || user.MFA.Secret == "" || s.keyring.IsActive(user.MFA.Secret) {
+		return false, nil
+	}
+	secret, err := s.keyring.DecryptString(user.MFA.Secret)
+	if err != nil {
+		return false, err
+	}
+	encryptedSecret, err := s.keyring.EncryptString(secret)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	res, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": user.ID, "mfa.secret": user.MFA.Secret},
+		bson.M{"$set": bson.M{"mfa.secret": encryptedSecret}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// isActive reports whether all the values of the data were encrypted with the active key.
+func (s *rotationService) isActive(data any) bool {
+	switch v := data.(type) {
+	case string:
+		return s.keyring.IsActive(v)
+	case bson.D:
+		for _, e := range v {
+			if x, ok := e.Value.(string); ok && !s.keyring.IsActive(x) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// markDocument marks the legacy values of the document. The document is not
+// changed if it was updated concurrently: the update has used the current format.
+func (s *rotationService) markDocument(
+	ctx context.Context,
+	collection *mongo.Collection,
+	raw bson.Raw,
+) (bool, error) {
+	var doc encryptedDocument
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return false, err
+	}
+	data, marked, err := s.markLegacy(doc.Data)
+	if err != nil || !marked {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	res, err := collection.UpdateOne(
+		ctx,
+		bson.M{"_id": doc.ID, "data": doc.Data},
+		bson.M{"$set": bson.M{"data": data}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// markLegacy returns the data with the legacy values marked.
+// Returns true if any of the values is marked.
+func (s *rotationService) markLegacy(data any) (any, bool, error) {
+	switch v := data.(type) {
+	case string:
+		return s.keyring.MarkLegacy(v)
+	case bson.D:
+		marked := make(bson.D, 0, len(v))
+		changed := false
+		for _, e := range v {
+			if x, ok := e.Value.(string); ok {
+				value, ok, err := s.keyring.MarkLegacy(x)
+				if err != nil {
+					return nil, false, err
+				}
+				e.Value, changed = value, changed || ok
+			}
+			marked = append(marked, e)
+		}
+		return marked, changed, nil
+	default:
+		return data, false, nil
+	}
+}
@@ -0,0 +1,488 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/xdg-go/pbkdf2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+type RotationServiceTestSuite struct {
+	suite.Suite
+	oldKeyring *encrypt.Keyring
+	keyring    *encrypt.Keyring
+}
+
+func (suite *RotationServiceTestSuite) SetupSuite() {
+	var err error
+	suite.oldKeyring, err = encrypt.NewKeyring("1", map[string]string{"1": "old-key"})
+	suite.Require().NoError(err)
+	suite.keyring, err = encrypt.NewKeyring(
+		"2",
+		map[string]string{"1": "old-key", "2": "new-key"},
+	)
+	suite.Require().NoError(err)
+}
+
+func (suite *RotationServiceTestSuite) TearDownSuite() {}
+
+// findFilter returns the filter of the find command sent to the collection.
+func findFilter(mt *mtest.T, collection string) bson.Raw {
+	for _, e := range mt.GetAllStartedEvents() {
+		if e.CommandName == "find" && e.Command.Lookup("find").StringValue() == collection {
+			return e.Command.Lookup("filter").Document()
+		}
+	}
+	return nil
+}
+
+func (suite *RotationServiceTestSuite) TestRotateCollection() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		rotationService := NewRotationService(mt.DB, suite.keyring)
+		oldData, err := suite.oldKeyring.EncryptString("old text")
+		require.NoError(t, err)
+		newData, err := suite.keyring.EncryptString("new text")
+		require.NoError(t, err)
+
+		ns := mt.DB.Name() + "." + string(models.TextCollection)
+		mt.AddMockResponses(
+			// no checkpoint
+			mtest.CreateCursorResponse(0, mt.DB.Name()+"."+RotationCheckpointsCollection, mtest.FirstBatch),
+			// count
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 2}}),
+			// the first batch
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: models.NewRandomObjectID()}, {Key: "data", Value: oldData}},
+				bson.D{{Key: "_id", Value: models.NewRandomObjectID()}, {Key: "data", Value: newData}},
+			),
+			// re-encryption of the first document
+			bson.D{{Key: "ok", Value: 1}, {Key: "nModified", Value: 1}},
+			// checkpoint
+			mtest.CreateSuccessResponse(),
+			// the last batch
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+		var reports []RotationProgress
+		err = rotationService.RotateCollection(
+			context.TODO(),
+			models.TextCollection,
+			2,
+			func(p RotationProgress) { reports = append(reports, p) },
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []RotationProgress{{
+			Collection: models.TextCollection,
+			Processed:  2,
+			Rotated:    1,
+			Total:      2,
+		}}, reports)
+	})
+	mt.Run("legacy", func(mt *mtest.T) {
+		// the legacy value is re-encrypted with the active key once it's marked
+		rotationService := NewRotationService(mt.DB, suite.keyring)
+		ns := mt.DB.Name() + "." + string(models.TextCollection)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+"."+RotationCheckpointsCollection, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: models.NewRandomObjectID()},
+				{Key: "data", Value: "v0:2:" + encryptLegacy(t, "old text", "new-key")},
+			}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+		var last RotationProgress
+		err := rotationService.RotateCollection(
+			context.TODO(),
+			models.TextCollection,
+			10,
+			func(p RotationProgress) { last = p },
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), last.Rotated)
+	})
+	mt.Run("resume", func(mt *mtest.T) {
+		rotationService := NewRotationService(mt.DB, suite.keyring)
+		lastID := models.NewRandomObjectID()
+		ns := mt.DB.Name() + "." + string(models.TextCollection)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+"."+RotationCheckpointsCollection, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: models.TextCollection},
+				{Key: "key_id", Value: "2"},
+				{Key: "last_id", Value: lastID},
+				{Key: "processed", Value: 10},
+				{Key: "rotated", Value: 10},
+			}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 10}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+		err := rotationService.RotateCollection(context.TODO(), models.TextCollection, 2, nil)
+		require.NoError(t, err)
+
+		filter := findFilter(mt, string(models.TextCollection))
+		require.NotNil(t, filter)
+		assert.Equal(t, lastID, filter.Lookup("_id", "$gt").ObjectID())
+	})
+	mt.Run("another_key", func(mt *mtest.T) {
+		rotationService := NewRotationService(mt.DB, suite.keyring)
+		ns := mt.DB.Name() + "." + string(models.TextCollection)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+"."+RotationCheckpointsCollection, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: models.TextCollection},
+				{Key: "key_id", Value: "1"},
+				{Key: "last_id", Value: models.NewRandomObjectID()},
+			}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 10}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+		err := rotationService.RotateCollection(context.TODO(), models.TextCollection, 2, nil)
+		require.NoError(t, err)
+
+		filter := findFilter(mt, string(models.TextCollection))
+		require.NotNil(t, filter)
+		_, err = filter.LookupErr("_id")
+		assert.Error(t, err)
+	})
+	mt.Run("unknown_key", func(mt *mtest.T) {
+		rotationService := NewRotationService(mt.DB, suite.oldKeyring)
+		newData, err := suite.keyring.EncryptString("new text")
+		require.NoError(t, err)
+		ns := mt.DB.Name() + "." + string(models.TextCollection)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+"."+RotationCheckpointsCollection, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: models.NewRandomObjectID()}, {Key: "data", Value: newData}},
+			),
+		)
+		err = rotationService.RotateCollection(context.TODO(), models.TextCollection, 2, nil)
+		require.ErrorIs(t, err, encrypt.ErrUnknownKey)
+	})
+	mt.Run("map", func(mt *mtest.T) {
+		rotationService := NewRotationService(mt.DB, suite.keyring)
+		oldData, err := suite.oldKeyring.EncryptMap(map[string]any{"Login": "alice", "Password": "secret"})
+		require.NoError(t, err)
+		ns := mt.DB.Name() + "." + string(models.CredentialsCollection)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+"."+RotationCheckpointsCollection, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: models.NewRandomObjectID()}, {Key: "data", Value: oldData}},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+		var last RotationProgress
+		err = rotationService.RotateCollection(
+			context.TODO(),
+			models.CredentialsCollection,
+			10,
+			func(p RotationProgress) { last = p },
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), last.Rotated)
+	})
+}
+
+func (suite *RotationServiceTestSuite) TestRotate() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("blob", func(mt *mtest.T) {
+		rotationService := NewRotationService(mt.DB, suite.keyring)
+		checkpoints := mt.DB.Name() + "." + RotationCheckpointsCollection
+		for i := 0; i < 2*len(models.AllowedCollectionNames); i++ {
+			ns := mt.DB.Name() + "." + string(models.TextCollection)
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, checkpoints, mtest.FirstBatch),
+				mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}),
+				mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			)
+		}
+		blobID := models.NewRandomObjectID()
+		plain := []byte("file content")
+		keyID, encrypted, err := suite.oldKeyring.EncryptBytesWithAD(plain, chunkAD(blobID, 0))
+		require.NoError(t, err)
+		chunks := mt.DB.Name() + "." + BlobChunksCollection
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, checkpoints, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, chunks, mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateCursorResponse(0, chunks, mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: models.NewRandomObjectID()},
+				{Key: "blob_id", Value: blobID},
+				{Key: "n", Value: int64(0)},
+				{Key: "key_id", Value: keyID},
+				{Key: "data", Value: encrypted},
+			}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(0, chunks, mtest.FirstBatch),
+		)
+		users := mt.DB.Name() + "." + UsersCollection
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, checkpoints, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, users, mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}),
+			mtest.CreateCursorResponse(0, users, mtest.FirstBatch),
+		)
+		var last RotationProgress
+		err = rotationService.Rotate(context.TODO(), 10, func(p RotationProgress) { last = p })
+		require.NoError(t, err)
+		assert.Equal(t, RotationProgress{
+			Collection: BlobChunksCollection,
+			Processed:  1,
+			Rotated:    1,
+			Total:      1,
+		}, last)
+
+		var rotated bson.Raw
+		for _, e := range mt.GetAllStartedEvents() {
+			if e.CommandName != "update" {
+				continue
+			}
+			if e.Command.Lookup("update").StringValue() == BlobChunksCollection {
+				rotated = e.Command.Lookup("updates").Array().Index(0).Value().Document()
+			}
+		}
+		require.NotNil(t, rotated)
+		require.Equal(t, "2", rotated.Lookup("u", "$set", "key_id").StringValue())
+		_, data := rotated.Lookup("u", "$set", "data").Binary()
+
+		// the blob is read after the old key is removed
+		newKeyring, err := encrypt.NewKeyring("2", map[string]string{"2": "new-key"})
+		require.NoError(t, err)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+"."+BlobsCollection, mtest.FirstBatch,
+				blobDoc(blobID, int64(len("file content")), int64(len("file content")), 1),
+			),
+			mtest.CreateCursorResponse(0, chunks, mtest.FirstBatch, bson.D{
+				{Key: "blob_id", Value: blobID},
+				{Key: "n", Value: int64(0)},
+				{Key: "key_id", Value: "2"},
+				{Key: "data", Value: data},
+			}),
+		)
+		var content bytes.Buffer
+		err = NewBlobService(mt.DB, newKeyring).Read(context.TODO(), "testuser", blobID, &content)
+		require.NoError(t, err)
+		assert.Equal(t, "file content", content.String())
+	})
+	mt.Run("mfa", func(mt *mtest.T) {
+		rotationService := NewRotationService(mt.DB, suite.keyring)
+		checkpoints := mt.DB.Name() + "." + RotationCheckpointsCollection
+		for i := 0; i < 2*len(models.AllowedCollectionNames)+1; i++ {
+			ns := mt.DB.Name() + "." + string(models.TextCollection)
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, checkpoints, mtest.FirstBatch),
+				mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}),
+				mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			)
+		}
+		secret, err := suite.oldKeyring.EncryptString("JBSWY3DPEHPK3PXP")
+		require.NoError(t, err)
+		rotatedSecret, err := suite.keyring.EncryptString("JBSWY3DPEHPK3PXP")
+		require.NoError(t, err)
+		users := mt.DB.Name() + "." + UsersCollection
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, checkpoints, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, users, mtest.FirstBatch, bson.D{{Key: "n", Value: 3}}),
+			mtest.CreateCursorResponse(0, users, mtest.FirstBatch,
+				bson.D{
+					{Key: "_id", Value: models.NewRandomObjectID()},
+					{Key: "username", Value: "testuser"},
+					{Key: "mfa", Value: bson.D{{Key: "secret", Value: secret}}},
+				},
+				bson.D{
+					{Key: "_id", Value: models.NewRandomObjectID()},
+					{Key: "username", Value: "otheruser"},
+				},
+				bson.D{
+					{Key: "_id", Value: models.NewRandomObjectID()},
+					{Key: "username", Value: "rotateduser"},
+					{Key: "mfa", Value: bson.D{{Key: "secret", Value: rotatedSecret}}},
+				},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(0, users, mtest.FirstBatch),
+		)
+		var last RotationProgress
+		err = rotationService.Rotate(context.TODO(), 10, func(p RotationProgress) { last = p })
+		require.NoError(t, err)
+		assert.Equal(t, RotationProgress{
+			Collection: UsersCollection,
+			Processed:  3,
+			Rotated:    1,
+			Total:      3,
+		}, last)
+
+		var updates []bson.Raw
+		for _, e := range mt.GetAllStartedEvents() {
+			if e.CommandName == "update" && e.Command.Lookup("update").StringValue() == UsersCollection {
+				updates = append(updates, e.Command.Lookup("updates").Array().Index(0).Value().Document())
+			}
+		}
+		require.Len(t, updates, 1)
+		assert.Equal(t, secret, updates[0].Lookup("q", "mfa.secret").StringValue())
+
+		// the secret is read after the old key is removed
+		newKeyring, err := encrypt.NewKeyring("2", map[string]string{"2": "new-key"})
+		require.NoError(t, err)
+		decrypted, err := newKeyring.DecryptString(
+			updates[0].Lookup("u", "$set", "mfa.secret").StringValue(),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", decrypted)
+	})
+}
+
+// encryptLegacy encrypts the text with the key in the legacy v0 format
+// and returns the base64 ciphertext.
+func encryptLegacy(t *testing.T, text, key string) string {
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(key), nil, 1000, 16, sha256.New))
+	require.NoError(t, err)
+	ciphertext := make([]byte, aes.BlockSize+len(text))
+	_, err = rand.Read(ciphertext[:aes.BlockSize])
+	require.NoError(t, err)
+	cipher.NewCFBEncrypter(block, ciphertext[:aes.BlockSize]).
+		XORKeyStream(ciphertext[aes.BlockSize:], []byte(text))
+	return base64.StdEncoding.EncodeToString(ciphertext)
+}
+
+func (suite *RotationServiceTestSuite) TestMarkLegacy() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		rotationService := NewRotationService(mt.DB, suite.keyring)
+		legacy := "1:" + encryptLegacy(t, "old text", "old-key")
+		current, err := suite.keyring.EncryptString("new text")
+		require.NoError(t, err)
+
+		checkpoints := mt.DB.Name() + "." + RotationCheckpointsCollection
+		ns := mt.DB.Name() + "." + string(models.TextCollection)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, checkpoints, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 2}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: models.NewRandomObjectID()}, {Key: "data", Value: legacy}},
+				bson.D{{Key: "_id", Value: models.NewRandomObjectID()}, {Key: "data", Value: current}},
+			),
+			// the legacy value is marked
+			bson.D{{Key: "ok", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+		// the other collections are empty
+		for i := 1; i < 2*len(models.AllowedCollectionNames); i++ {
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, checkpoints, mtest.FirstBatch),
+				mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}),
+				mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			)
+		}
+		// the finished migration is saved
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		var reports []RotationProgress
+		err = rotationService.MarkLegacy(
+			context.TODO(),
+			10,
+			func(p RotationProgress) { reports = append(reports, p) },
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []RotationProgress{{
+			Collection: models.TextCollection,
+			Processed:  2,
+			Rotated:    1,
+			Total:      2,
+		}}, reports)
+
+		var (
+			marked   string
+			finished bool
+		)
+		for _, e := range mt.GetAllStartedEvents() {
+			if e.CommandName != "update" {
+				continue
+			}
+			update := e.Command.Lookup("updates").Array().Index(0).Value().Document()
+			switch e.Command.Lookup("update").StringValue() {
+			case "text":
+				marked = update.Lookup("u", "$set", "data").StringValue()
+			case RotationCheckpointsCollection:
+				finished = finished || update.Lookup("q", "_id").StringValue() == legacyMigrationID
+			}
+		}
+		assert.True(t, finished, "the finished migration should be saved")
+		// the unmarked legacy value is never read in the legacy format
+		_, err = suite.keyring.DecryptString(legacy)
+		assert.Error(t, err)
+		assert.True(t, encrypt.IsLegacy(marked))
+		decrypted, err := suite.keyring.DecryptString(marked)
+		require.NoError(t, err)
+		assert.Equal(t, "old text", decrypted)
+	})
+}
+
+func (suite *RotationServiceTestSuite) TestCheckLegacyMigration() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("migrated", func(mt *mtest.T) {
+		rotationService := NewRotationService(mt.DB, suite.keyring)
+		checkpoints := mt.DB.Name() + "." + RotationCheckpointsCollection
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, checkpoints, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: legacyMigrationID}},
+		))
+		require.NoError(t, rotationService.CheckLegacyMigration(context.TODO()))
+	})
+	mt.Run("not migrated", func(mt *mtest.T) {
+		rotationService := NewRotationService(mt.DB, suite.keyring)
+		checkpoints := mt.DB.Name() + "." + RotationCheckpointsCollection
+		ns := mt.DB.Name() + "." + string(models.TextCollection)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, checkpoints, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: models.NewRandomObjectID()}},
+			),
+		)
+		err := rotationService.CheckLegacyMigration(context.TODO())
+		assert.ErrorIs(t, err, errors.ErrLegacyNotMigrated)
+	})
+	mt.Run("no records", func(mt *mtest.T) {
+		rotationService := NewRotationService(mt.DB, suite.keyring)
+		checkpoints := mt.DB.Name() + "." + RotationCheckpointsCollection
+		ns := mt.DB.Name() + "." + string(models.TextCollection)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, checkpoints, mtest.FirstBatch))
+		for i := 0; i < 2*len(models.AllowedCollectionNames); i++ {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+		}
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		require.NoError(t, rotationService.CheckLegacyMigration(context.TODO()))
+		names := commandNames(mt)
+		assert.Equal(t, "update", names[len(names)-1], "the migration should be recorded")
+	})
+}
+
+func TestRotationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(RotationServiceTestSuite))
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/exp/slices"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
+)
+
+// searchTokensField is the field of a record with the blind index
+// tokens of its searchable terms.
+const searchTokensField = "search_tokens"
+
+// searchableTexts returns the texts of the record which can be searched for:
+// the metadata values, the login of the credentials, the name of the file
+// and the last four digits of the card. The end-to-end encrypted records
+// have no searchable texts since the server can't read them.
+func searchableTexts(
+	collectionName models.CollectionName,
+	data any,
+	metadata models.Metadata,
+) []string {
+	texts := make([]string, 0, len(metadata)+1)
+	for _, v := range metadata {
+		texts = append(texts, v)
+	}
+	m, ok := data.(map[string]any)
+	if !ok || models.IsEncryptedData(data) {
+		return texts
+	}
+	field := func(name string) string {
+		s, _ := m[name].(string)
+		return s
+	}
+	switch collectionName {
+	case models.CredentialsCollection:
+		texts = append(texts, field("Login"))
+	case models.BinaryCollection:
+		// the directories of the file are not a part of its name
+		name := field("FileName")
+		texts = append(texts, name[strings.LastIndexAny(name, `/\`)+1:])
+	case models.CardCollection:
+		digits := strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, field("CardNumber"))
+		if len(digits) >= 4 {
+			texts = append(texts, digits[len(digits)-4:])
+		}
+	}
+	return texts
+}
+
+// searchTokens returns the blind index tokens of the searchable terms of
+// the user's record and their prefixes.
+func (t *storageService) searchTokens(
+	username string,
+	collectionName models.CollectionName,
+	data any,
+	metadata models.Metadata,
+) []string {
+	var terms []string
+	for _, text := range searchableTexts(collectionName, data, metadata) {
+		terms = append(terms, encrypt.Terms(text)...)
+	}
+	return t.index.Tokens(username, encrypt.Prefixes(terms))
+}
+
+// Search returns the user's records of all the collections which have all
+// the terms of the query, the recently updated first. A term matches the
+// searchable terms of the record it is equal to or is a prefix of. The records
+// in the trash are not found and the content of the binary records is omitted.
+func (t *storageService) Search(
+	ctx context.Context,
+	username string,
+	query string,
+) ([]models.SearchResult, error) {
+	terms := encrypt.Terms(query)
+	if len(terms) == 0 {
+		return nil, errors.ErrEmptySearchQuery
+	}
+	filter := bson.M{
+		"username":        username,
+		"deleted_at":      bson.M{"$exists": false},
+		searchTokensField: bson.M{"$all": t.index.Tokens(username, terms)},
+	}
+	results := make([]models.SearchResult, 0)
+	for _, collectionName := range models.AllowedCollectionNames {
+		findOptions := options.Find().
+			SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+			SetLimit(models.MaxSearchResults)
+		if collectionName == models.BinaryCollection {
+			findOptions.SetProjection(bson.M{"data.Content": 0})
+		}
+		records, err := t.find(ctx, collectionName, filter, findOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			results = append(results, models.SearchResult{Collection: collectionName, Record: r})
+		}
+	}
+	slices.SortStableFunc(results, func(a, b models.SearchResult) bool {
+		return updatedAt(a.Record).After(updatedAt(b.Record))
+	})
+	if len(results) > models.MaxSearchResults {
+		results = results[:models.MaxSearchResults]
+	}
+	return results, nil
+}
+
+// updatedAt returns the time of the last change of the record;
+// the zero time if it is unknown.
+func updatedAt(r models.UntypedRecord) time.Time {
+	if r.UpdatedAt == nil {
+		return time.Time{}
+	}
+	return *r.UpdatedAt
+}
+
+// indexedDocument is a record document with the fields the search tokens are made of.
+type indexedDocument struct {
+	ID       models.ObjectID `bson:"_id"`
+	Username string          `bson:"username"`
+	Data     any             `bson:"data"`
+	Metadata models.Metadata `bson:"metadata"`
+}
+
+// Reindex replaces the search tokens of all the records of the collection
+// in batches. It is needed for the records saved before the search was
+// introduced and after the key of the index is changed. The record is not
+// changed if it was updated concurrently: the update has already saved
+// the new tokens. The number of the reindexed records is returned.
+func (t *storageService) Reindex(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	batchSize int,
+) (int64, error) {
+	collection := t.db.Collection(string(collectionName))
+	var (
+		lastID    models.ObjectID
+		reindexed int64
+	)
+	for {
+		filter := bson.M{}
+		if !lastID.IsZero() {
+			filter["_id"] = bson.M{"$gt": lastID}
+		}
+		batch, err := t.nextIndexBatch(ctx, collectionName, filter, batchSize)
+		if err != nil {
+			return reindexed, err
+		}
+		if len(batch) == 0 {
+			return reindexed, nil
+		}
+		for _, doc := range batch {
+			lastID = doc.ID
+			var data any
+			// the purged records have no data
+			if doc.Data != nil {
+				if data, err = decryptData(t.keyring, doc.Data); err != nil {
+					return reindexed, err
+				}
+			}
+			tokens := t.searchTokens(doc.Username, collectionName, data, doc.Metadata)
+			updCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+			res, err := collection.UpdateOne(
+				updCtx,
+				bson.M{"_id": doc.ID, "data": doc.Data},
+				bson.M{"$set": bson.M{searchTokensField: tokens}},
+			)
+			cancel()
+			if err != nil {
+				return reindexed, err
+			}
+			reindexed += res.MatchedCount
+		}
+	}
+}
+
+// nextIndexBatch returns the documents of the collection which match the filter
+// in the order of their IDs.
+func (t *storageService) nextIndexBatch(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	filter bson.M,
+	batchSize int,
+) ([]indexedDocument, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	cur, err := t.db.Collection(string(collectionName)).Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(batchSize)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	batch := make([]indexedDocument, 0, batchSize)
+	if err := cur.All(ctx, &batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
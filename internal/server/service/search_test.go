@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestSearchableTexts(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection models.CollectionName
+		data       any
+		metadata   models.Metadata
+		want       []string
+	}{
+		{
+			name:       "text",
+			collection: models.TextCollection,
+			data:       "the text itself is not searchable",
+			metadata:   models.Metadata{"note": "shopping list"},
+			want:       []string{"shopping list"},
+		},
+		{
+			name:       "credentials",
+			collection: models.CredentialsCollection,
+			data:       map[string]any{"Login": "john@github.com", "Password": "secret"},
+			want:       []string{"john@github.com"},
+		},
+		{
+			name:       "binary",
+			collection: models.BinaryCollection,
+			data:       map[string]any{"FileName": "/home/john/report.pdf", "Content": "YQ=="},
+			want:       []string{"report.pdf"},
+		},
+		{
+			name:       "card",
+			collection: models.CardCollection,
+			data:       map[string]any{"CardNumber": "4111 1111 1111 1234", "CVV": "123"},
+			want:       []string{"1234"},
+		},
+		{
+			name:       "otp",
+			collection: models.OTPCollection,
+			data:       map[string]any{"Secret": "GEZDGNBVGY3TQOJQ", "Issuer": "GitHub"},
+			want:       []string{},
+		},
+		{
+			name:       "encrypted",
+			collection: models.CredentialsCollection,
+			data:       models.NewEncryptedData([]byte("ciphertext")),
+			want:       []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.want, searchableTexts(tt.collection, tt.data, tt.metadata))
+		})
+	}
+}
+
+// lookupStrings returns the strings of the array found by the path in the document.
+func lookupStrings(t *testing.T, doc bson.Raw, path ...string) []string {
+	values, err := doc.Lookup(path...).Array().Values()
+	require.NoError(t, err)
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		result = append(result, v.StringValue())
+	}
+	return result
+}
+
+func TestStorageService_StoreSearchTokens(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		index := newTestIndex(t)
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), index)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		_, err := storageService.Store(context.TODO(), models.CredentialsCollection, models.UntypedRecord{
+			UntypedRecordContent: models.UntypedRecordContent{
+				Data:     map[string]any{"Login": "john@github.com", "Password": "secret"},
+				Metadata: models.Metadata{"site": "work"},
+			},
+			Username: "blokhinnv",
+		})
+		require.NoError(t, err)
+		doc := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		tokens := lookupStrings(t, doc, searchTokensField)
+		for _, term := range []string{"john", "git", "github", "com", "work"} {
+			assert.Contains(t, tokens, index.Token("blokhinnv", term))
+		}
+		assert.NotContains(t, tokens, index.Token("blokhinnv", "secret"))
+		assert.NotContains(t, tokens, index.Token("another", "github"))
+	})
+}
+
+func TestStorageService_Search(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		index := newTestIndex(t)
+		keyring := newTestKeyring(t, "my-secret-key")
+		storageService := NewStorageService(mt.DB, keyring, index)
+		login, err := keyring.EncryptString("john@github.com")
+		require.NoError(t, err)
+		fileName, err := keyring.EncryptString("github.pdf")
+		require.NoError(t, err)
+		earlier := time.Now().UTC().Truncate(time.Millisecond)
+		later := earlier.Add(time.Minute)
+		credID, binaryID := models.NewRandomObjectID(), models.NewRandomObjectID()
+		ns := func(c models.CollectionName) string { return mt.DB.Name() + "." + string(c) }
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns(models.TextCollection), mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns(models.CredentialsCollection), mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: credID},
+				{Key: "data", Value: bson.D{{Key: "Login", Value: login}}},
+				{Key: "updated_at", Value: earlier},
+			}),
+			mtest.CreateCursorResponse(0, ns(models.BinaryCollection), mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: binaryID},
+				{Key: "data", Value: bson.D{{Key: "FileName", Value: fileName}}},
+				{Key: "updated_at", Value: later},
+			}),
+			mtest.CreateCursorResponse(0, ns(models.CardCollection), mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns(models.OTPCollection), mtest.FirstBatch),
+		)
+		results, err := storageService.Search(context.TODO(), "blokhinnv", "GitHub")
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, models.BinaryCollection, results[0].Collection)
+		assert.Equal(t, binaryID, results[0].Record.RecordID)
+		assert.Equal(t, models.CredentialsCollection, results[1].Collection)
+		assert.Equal(t, "john@github.com", results[1].Record.Data.(map[string]any)["Login"])
+
+		filter := findFilter(mt, string(models.BinaryCollection))
+		assert.Equal(
+			t,
+			[]string{index.Token("blokhinnv", "github")},
+			lookupStrings(t, filter, searchTokensField, "$all"),
+		)
+		assert.Equal(t, "blokhinnv", filter.Lookup("username").StringValue())
+	})
+	mt.Run("empty_query", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		_, err := storageService.Search(context.TODO(), "blokhinnv", " .,-")
+		assert.ErrorIs(t, err, errors.ErrEmptySearchQuery)
+	})
+	mt.Run("find_error", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1}))
+		_, err := storageService.Search(context.TODO(), "blokhinnv", "github")
+		assert.Error(t, err)
+	})
+}
+
+func TestStorageService_Reindex(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		index := newTestIndex(t)
+		keyring := newTestKeyring(t, "my-secret-key")
+		storageService := NewStorageService(mt.DB, keyring, index)
+		login, err := keyring.EncryptString("john@github.com")
+		require.NoError(t, err)
+		ns := mt.DB.Name() + "." + string(models.CredentialsCollection)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+				bson.D{
+					{Key: "_id", Value: models.NewRandomObjectID()},
+					{Key: "username", Value: "blokhinnv"},
+					{Key: "data", Value: bson.D{{Key: "Login", Value: login}}},
+				},
+				// purged
+				bson.D{{Key: "_id", Value: models.NewRandomObjectID()}, {Key: "username", Value: "blokhinnv"}},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			// updated concurrently
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}},
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+		n, err := storageService.Reindex(context.TODO(), models.CredentialsCollection, 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		var updates []bson.Raw
+		for _, e := range mt.GetAllStartedEvents() {
+			if e.CommandName == "update" {
+				updates = append(updates, e.Command.Lookup("updates").Array().Index(0).Value().Document())
+			}
+		}
+		require.Len(t, updates, 2)
+		assert.Contains(
+			t,
+			lookupStrings(t, updates[0], "u", "$set", searchTokensField),
+			index.Token("blokhinnv", "github"),
+		)
+		assert.Empty(t, lookupStrings(t, updates[1], "u", "$set", searchTokensField))
+	})
+	mt.Run("find_error", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1}))
+		_, err := storageService.Reindex(context.TODO(), models.CredentialsCollection, 2)
+		assert.Error(t, err)
+	})
+}
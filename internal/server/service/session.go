@@ -0,0 +1,402 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// SessionsCollection and RevokedTokensCollection are the collections
+// which keep the sessions and the ids of the revoked access tokens.
+const (
+	SessionsCollection      = "sessions"
+	RevokedTokensCollection = "revoked_tokens"
+)
+
+// SessionService is an interface for managing the sessions of the users.
+// A session issues short-lived access tokens in exchange for its refresh token.
+type SessionService interface {
+	// Create starts a new session of the user on the device and returns its tokens.
+	// The device id is empty for the clients which don't register the devices.
+	Create(ctx context.Context, username, deviceID string) (*models.TokenPair, error)
+	// Refresh exchanges the refresh token for the new pair of tokens.
+	// The refresh token can be used only once. The session started on a device
+	// is refreshed only with the proof of the key of the device.
+	Refresh(
+		ctx context.Context,
+		refreshToken string,
+		proof *models.DeviceProof,
+	) (*models.TokenPair, error)
+	// List returns the active sessions of the user.
+	List(ctx context.Context, username string) ([]models.Session, error)
+	// Revoke finishes the session of the user, revokes its access token
+	// and cancels its sync subscriptions.
+	Revoke(ctx context.Context, username string, sessionID models.ObjectID) error
+	// RevokeAll finishes all the sessions of the user, revokes their access tokens
+	// and cancels all the sync subscriptions of the user.
+	RevokeAll(ctx context.Context, username string) error
+	// RevokeDevice finishes the sessions of the user's device, revokes their access tokens
+	// and cancels their sync subscriptions.
+	RevokeDevice(ctx context.Context, username, deviceID string) error
+	// Logout revokes the access token and finishes its session like Revoke.
+	Logout(ctx context.Context, claims *auth.Claims) error
+	// IsRevoked reports whether the access token with the id was revoked.
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// EnsureIndexes creates the indexes which remove the expired sessions and tokens.
+	EnsureIndexes(ctx context.Context) error
+}
+
+// sessionService is the implementation of the SessionService interface.
+type sessionService struct {
+	sessions      *mongo.Collection
+	revokedTokens *mongo.Collection
+	devices       *mongo.Collection
+	deviceNonces  *mongo.Collection
+	keys          *auth.KeySet  // The keys which sign the access tokens and the device nonces.
+	accessTTL     time.Duration // The duration for which access tokens are valid.
+	refreshTTL    time.Duration // The duration of the session since its last refresh.
+	sync          SyncService   // The service which keeps the sync subscriptions of the sessions.
+}
+
+// NewSessionService creates a new instance of the SessionService
+// which signs the access tokens with the keys. The sync subscriptions
+// of the revoked sessions are canceled by the sync service.
+func NewSessionService(
+	db *mongo.Database,
+	keys *auth.KeySet,
+	accessTTL time.Duration,
+	refreshTTL time.Duration,
+	sync SyncService,
+) SessionService {
+	return &sessionService{
+		sessions:      db.Collection(SessionsCollection),
+		revokedTokens: db.Collection(RevokedTokensCollection),
+		devices:       db.Collection(DevicesCollection),
+		deviceNonces:  db.Collection(DeviceNoncesCollection),
+		keys:          keys,
+		accessTTL:     accessTTL,
+		refreshTTL:    refreshTTL,
+		sync:          sync,
+	}
+}
+
+// refreshTokenSeparator separates the session id from the secret in refresh tokens.
+const refreshTokenSeparator = "."
+
+// newTokens generates the access and the refresh tokens of the session.
+func (s *sessionService) newTokens(
+	session *models.Session,
+	now time.Time,
+) (*models.TokenPair, error) {
+	accessToken, claims, err := s.keys.NewAccessToken(
+		session.Username,
+		session.ID.Hex(),
+		session.DeviceID,
+		s.accessTTL,
+	)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := auth.NewRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken := session.ID.Hex() + refreshTokenSeparator + secret
+	session.RefreshTokenHash = auth.HashToken(refreshToken)
+	session.AccessTokenID = claims.ID
+	session.AccessTokenExpiry = claims.ExpiresAt.Time
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(s.refreshTTL)
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    claims.ExpiresAt.Time,
+		SessionID:    session.ID.Hex(),
+		DeviceID:     session.DeviceID,
+	}, nil
+}
+
+// Create starts a new session of the user on the device and returns its tokens.
+// The access tokens of the session are bound to the device.
+func (s *sessionService) Create(
+	ctx context.Context,
+	username, deviceID string,
+) (*models.TokenPair, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	session := &models.Session{
+		ID:        models.NewRandomObjectID(),
+		Username:  username,
+		DeviceID:  deviceID,
+		CreatedAt: now,
+	}
+	tokens, err := s.newTokens(session, now)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.sessions.InsertOne(ctx, session); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Refresh exchanges the refresh token for the new pair of tokens.
+// A refresh token used for the second time means it was stolen,
+// so the whole session is revoked. The session started on a device is
+// refreshed only if the proof is signed with the key of the device, so
+// the refresh token stolen from the device is useless without the key;
+// ErrBadDeviceProof is returned otherwise.
+func (s *sessionService) Refresh(
+	ctx context.Context,
+	refreshToken string,
+	proof *models.DeviceProof,
+) (*models.TokenPair, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	hexID, _, ok := strings.Cut(refreshToken, refreshTokenSeparator)
+	if !ok {
+		return nil, errors.ErrBadRefreshToken
+	}
+	sessionID, err := models.ObjectIDFromString(hexID)
+	if err != nil {
+		return nil, errors.ErrBadRefreshToken
+	}
+	var session models.Session
+	err = s.sessions.FindOne(ctx, bson.M{"_id": sessionID}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.ErrBadRefreshToken
+	} else if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if now.After(session.ExpiresAt) {
+		return nil, errors.ErrBadRefreshToken
+	}
+	oldHash := session.RefreshTokenHash
+	if auth.HashToken(refreshToken) != oldHash {
+		if err := s.Revoke(ctx, session.Username, session.ID); err != nil &&
+			err != errors.ErrSessionNotFound {
+			return nil, err
+		}
+		return nil, errors.ErrBadRefreshToken
+	}
+	if err := s.verifyDevice(ctx, &session, proof); err != nil {
+		return nil, err
+	}
+	oldTokenID, oldTokenExpiry := session.AccessTokenID, session.AccessTokenExpiry
+	tokens, err := s.newTokens(&session, now)
+	if err != nil {
+		return nil, err
+	}
+	// the hash in the filter makes the concurrent refreshes with the same token fail
+	res, err := s.sessions.UpdateOne(
+		ctx,
+		bson.M{"_id": session.ID, "refresh_token_hash": oldHash},
+		bson.M{"$set": bson.M{
+			"refresh_token_hash":      session.RefreshTokenHash,
+			"access_token_id":         session.AccessTokenID,
+			"access_token_expires_at": session.AccessTokenExpiry,
+			"last_used_at":            session.LastUsedAt,
+			"expires_at":              session.ExpiresAt,
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, errors.ErrBadRefreshToken
+	}
+	// the session has the only valid access token
+	if err := s.revokeToken(ctx, oldTokenID, oldTokenExpiry); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// verifyDevice checks the proof of the key of the device which started the session.
+// The sessions started without a device need no proof.
+func (s *sessionService) verifyDevice(
+	ctx context.Context,
+	session *models.Session,
+	proof *models.DeviceProof,
+) error {
+	if session.DeviceID == "" {
+		return nil
+	}
+	deviceID, err := models.ObjectIDFromString(session.DeviceID)
+	if err != nil {
+		return errors.ErrBadRefreshToken
+	}
+	var device models.Device
+	err = s.devices.FindOne(
+		ctx,
+		bson.M{"_id": deviceID, "username": session.Username},
+	).Decode(&device)
+	if err == mongo.ErrNoDocuments {
+		// the device is deleted, so is the session
+		return errors.ErrBadRefreshToken
+	} else if err != nil {
+		return err
+	}
+	return verifyDeviceProof(ctx, s.deviceNonces, s.keys, device.PublicKey, proof)
+}
+
+// List returns the active sessions of the user.
+func (s *sessionService) List(ctx context.Context, username string) ([]models.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	cur, err := s.sessions.Find(
+		ctx,
+		bson.M{"username": username, "expires_at": bson.M{"$gt": time.Now().UTC()}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.Session, 0)
+	if err := cur.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Revoke finishes the session of the user and revokes its access token.
+// The sync subscriptions of the session are canceled after the token is revoked,
+// so the client can't subscribe again with it.
+func (s *sessionService) Revoke(
+	ctx context.Context,
+	username string,
+	sessionID models.ObjectID,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	var session models.Session
+	err := s.sessions.FindOneAndDelete(
+		ctx,
+		bson.M{"_id": sessionID, "username": username},
+	).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return errors.ErrSessionNotFound
+	} else if err != nil {
+		return err
+	}
+	err = s.revokeToken(ctx, session.AccessTokenID, session.AccessTokenExpiry)
+	s.sync.DisconnectSession(username, sessionID.Hex())
+	return err
+}
+
+// RevokeAll finishes all the sessions of the user and revokes their access tokens.
+// All the sync subscriptions of the user are canceled, including the ones
+// of the tokens issued without a session.
+func (s *sessionService) RevokeAll(ctx context.Context, username string) error {
+	err := s.revokeMany(ctx, username, bson.M{"username": username})
+	s.sync.DisconnectUser(username)
+	return err
+}
+
+// RevokeDevice finishes the sessions of the user's device and revokes their access tokens.
+func (s *sessionService) RevokeDevice(ctx context.Context, username, deviceID string) error {
+	return s.revokeMany(ctx, username, bson.M{"username": username, "device_id": deviceID})
+}
+
+// revokeMany finishes the sessions of the user matching the filter and revokes
+// their access tokens. Every session is revoked on its own, so the access token
+// issued by a concurrent refresh is revoked as well.
+func (s *sessionService) revokeMany(ctx context.Context, username string, filter bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	cur, err := s.sessions.Find(
+		ctx,
+		filter,
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	var sessions []models.Session
+	if err := cur.All(ctx, &sessions); err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		err := s.Revoke(ctx, username, session.ID)
+		if err != nil && err != errors.ErrSessionNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// Logout revokes the access token and finishes its session.
+func (s *sessionService) Logout(ctx context.Context, claims *auth.Claims) error {
+	if claims.ExpiresAt != nil {
+		if err := s.revokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+	if claims.SessionID == "" {
+		return nil
+	}
+	sessionID, err := models.ObjectIDFromString(claims.SessionID)
+	if err != nil {
+		return errors.ErrSessionNotFound
+	}
+	err = s.Revoke(ctx, claims.Username, sessionID)
+	if err == errors.ErrSessionNotFound {
+		// the session has already been revoked
+		return nil
+	}
+	return err
+}
+
+// revokeToken adds the access token id to the revocation list
+// until the token expires.
+func (s *sessionService) revokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	_, err := s.revokedTokens.InsertOne(ctx, bson.M{"_id": jti, "expires_at": expiresAt})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// IsRevoked reports whether the access token with the id was revoked.
+func (s *sessionService) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	err := s.revokedTokens.FindOne(ctx, bson.M{"_id": jti}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// EnsureIndexes creates the TTL indexes which remove the expired sessions and tokens.
+func (s *sessionService) EnsureIndexes(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	for _, collection := range []*mongo.Collection{s.sessions, s.revokedTokens} {
+		_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
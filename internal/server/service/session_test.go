@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/auth"
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+type SessionServiceTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SessionServiceTestSuite) SetupSuite()    {}
+func (suite *SessionServiceTestSuite) TearDownSuite() {}
+
+const testSigningKey = "my-secret-key"
+
+// newTestSessionService creates a session service with the test signing key.
+func newTestSessionService(mt *mtest.T) SessionService {
+	return newTestSessionServiceWithSync(mt, NewSyncService())
+}
+
+// newTestSessionServiceWithSync creates a session service with the test signing key
+// which cancels the subscriptions of the sync service.
+func newTestSessionServiceWithSync(mt *mtest.T, sync SyncService) SessionService {
+	return NewSessionService(
+		mt.DB,
+		auth.NewHMACKeySet([]byte(testSigningKey)),
+		time.Minute,
+		time.Hour,
+		sync,
+	)
+}
+
+// sessionDocument returns the stored session with the refresh token.
+func sessionDocument(id models.ObjectID, refreshToken string, expiresAt time.Time) bson.D {
+	return bson.D{
+		{Key: "_id", Value: id},
+		{Key: "username", Value: "testuser"},
+		{Key: "refresh_token_hash", Value: auth.HashToken(refreshToken)},
+		{Key: "access_token_id", Value: "old-jti"},
+		{Key: "access_token_expires_at", Value: time.Now().Add(time.Minute)},
+		{Key: "created_at", Value: time.Now()},
+		{Key: "last_used_at", Value: time.Now()},
+		{Key: "expires_at", Value: expiresAt},
+	}
+}
+
+func (suite *SessionServiceTestSuite) TestCreate() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		tokens, err := sessionService.Create(context.TODO(), "testuser", "device")
+		require.NoError(t, err)
+
+		claims, err := auth.ParseJWTToken(tokens.AccessToken, []byte(testSigningKey))
+		require.NoError(t, err)
+		assert.Equal(t, "testuser", claims.Username)
+		assert.Equal(t, tokens.SessionID, claims.SessionID)
+		assert.Equal(t, "device", claims.DeviceID)
+		assert.Equal(t, "device", tokens.DeviceID)
+		// the session keeps the device, so the refreshed tokens are bound to it as well
+		inserted := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, "device", inserted.Lookup("device_id").StringValue())
+		assert.True(t, strings.HasPrefix(tokens.RefreshToken, tokens.SessionID+"."))
+		assert.WithinDuration(t, time.Now().Add(time.Minute), tokens.ExpiresAt, time.Second)
+	})
+	mt.Run("error", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		_, err := sessionService.Create(context.TODO(), "testuser", "")
+		require.Error(t, err)
+	})
+}
+
+func (suite *SessionServiceTestSuite) TestRefresh() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	id := models.NewRandomObjectID()
+	refreshToken := id.Hex() + ".secret"
+	mt.Run("success", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "sessions.refresh", mtest.FirstBatch,
+				sessionDocument(id, refreshToken, time.Now().Add(time.Hour)),
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateSuccessResponse(),
+		)
+		tokens, err := sessionService.Refresh(context.TODO(), refreshToken, nil)
+		require.NoError(t, err)
+		assert.Equal(t, id.Hex(), tokens.SessionID)
+		assert.NotEqual(t, refreshToken, tokens.RefreshToken)
+
+		revoked := mt.GetStartedEvent()
+		for revoked != nil && revoked.CommandName != "insert" {
+			revoked = mt.GetStartedEvent()
+		}
+		require.NotNil(t, revoked)
+		assert.Equal(t, RevokedTokensCollection, revoked.Command.Lookup("insert").StringValue())
+	})
+	mt.Run("device", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		deviceID := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "sessions.refresh", mtest.FirstBatch, append(
+				sessionDocument(id, refreshToken, time.Now().Add(time.Hour)),
+				bson.E{Key: "device_id", Value: deviceID.Hex()},
+			)),
+			mtest.CreateCursorResponse(0, "devices.find", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: deviceID},
+				{Key: "public_key", Value: testDevice.PublicKey},
+			}),
+			mtest.CreateSuccessResponse(),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			mtest.CreateSuccessResponse(),
+		)
+		tokens, err := sessionService.Refresh(
+			context.TODO(),
+			refreshToken,
+			testDeviceProof(t, testDeviceKey),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, deviceID.Hex(), tokens.DeviceID)
+
+		mt.GetStartedEvent()
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, deviceID, models.ObjectID(filter.Lookup("_id").ObjectID()))
+		assert.Equal(t, "testuser", filter.Lookup("username").StringValue())
+		used := mt.GetStartedEvent().Command
+		assert.Equal(t, DeviceNoncesCollection, used.Lookup("insert").StringValue())
+	})
+	mt.Run("device_without_proof", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		deviceID := models.NewRandomObjectID()
+		_, anotherKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		for _, proof := range []*models.DeviceProof{nil, testDeviceProof(t, anotherKey)} {
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, "sessions.refresh", mtest.FirstBatch, append(
+					sessionDocument(id, refreshToken, time.Now().Add(time.Hour)),
+					bson.E{Key: "device_id", Value: deviceID.Hex()},
+				)),
+				mtest.CreateCursorResponse(0, "devices.find", mtest.FirstBatch, bson.D{
+					{Key: "_id", Value: deviceID},
+					{Key: "public_key", Value: testDevice.PublicKey},
+				}),
+			)
+			_, err = sessionService.Refresh(context.TODO(), refreshToken, proof)
+			require.ErrorIs(t, err, errors.ErrBadDeviceProof)
+		}
+		// the refresh token stays valid for the device
+		assert.Equal(t, []string{"find", "find", "find", "find"}, commandNames(mt))
+	})
+	mt.Run("deleted_device", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "sessions.refresh", mtest.FirstBatch, append(
+				sessionDocument(id, refreshToken, time.Now().Add(time.Hour)),
+				bson.E{Key: "device_id", Value: models.NewRandomObjectID().Hex()},
+			)),
+			mtest.CreateCursorResponse(0, "devices.find", mtest.FirstBatch),
+		)
+		_, err := sessionService.Refresh(context.TODO(), refreshToken, testDeviceProof(t, testDeviceKey))
+		require.ErrorIs(t, err, errors.ErrBadRefreshToken)
+	})
+	mt.Run("malformed", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		_, err := sessionService.Refresh(context.TODO(), "token", nil)
+		require.ErrorIs(t, err, errors.ErrBadRefreshToken)
+		_, err = sessionService.Refresh(context.TODO(), "id.secret", nil)
+		require.ErrorIs(t, err, errors.ErrBadRefreshToken)
+	})
+	mt.Run("not_found", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "sessions.refresh", mtest.FirstBatch))
+		_, err := sessionService.Refresh(context.TODO(), refreshToken, nil)
+		require.ErrorIs(t, err, errors.ErrBadRefreshToken)
+	})
+	mt.Run("expired", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "sessions.refresh", mtest.FirstBatch,
+			sessionDocument(id, refreshToken, time.Now().Add(-time.Hour)),
+		))
+		_, err := sessionService.Refresh(context.TODO(), refreshToken, nil)
+		require.ErrorIs(t, err, errors.ErrBadRefreshToken)
+	})
+	mt.Run("reused", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		doc := sessionDocument(id, id.Hex()+".new-secret", time.Now().Add(time.Hour))
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "sessions.refresh", mtest.FirstBatch, doc),
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: doc}},
+			mtest.CreateSuccessResponse(),
+		)
+		_, err := sessionService.Refresh(context.TODO(), refreshToken, nil)
+		require.ErrorIs(t, err, errors.ErrBadRefreshToken)
+
+		var commands []string
+		for e := mt.GetStartedEvent(); e != nil; e = mt.GetStartedEvent() {
+			commands = append(commands, e.CommandName)
+		}
+		assert.Equal(t, []string{"find", "findAndModify", "insert"}, commands)
+	})
+	mt.Run("concurrent", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "sessions.refresh", mtest.FirstBatch,
+				sessionDocument(id, refreshToken, time.Now().Add(time.Hour)),
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}},
+		)
+		_, err := sessionService.Refresh(context.TODO(), refreshToken, nil)
+		require.ErrorIs(t, err, errors.ErrBadRefreshToken)
+	})
+}
+
+func (suite *SessionServiceTestSuite) TestList() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		id := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "sessions.list", mtest.FirstBatch,
+				sessionDocument(id, "token", time.Now().Add(time.Hour)),
+			),
+		)
+		sessions, err := sessionService.List(context.TODO(), "testuser")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, id, sessions[0].ID)
+		assert.Equal(t, "testuser", sessions[0].Username)
+	})
+	mt.Run("error", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		_, err := sessionService.List(context.TODO(), "testuser")
+		require.Error(t, err)
+	})
+}
+
+func (suite *SessionServiceTestSuite) TestRevoke() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	id := models.NewRandomObjectID()
+	mt.Run("success", func(mt *mtest.T) {
+		sync := NewSyncService()
+		revoked, unsubscribeRevoked := sync.Subscribe("testuser", "", id.Hex(), time.Time{})
+		defer unsubscribeRevoked()
+		other, unsubscribeOther := sync.Subscribe("testuser", "", "other", time.Time{})
+		defer unsubscribeOther()
+		sessionService := newTestSessionServiceWithSync(mt, sync)
+		mt.AddMockResponses(
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: sessionDocument(id, "token", time.Now().Add(time.Hour))},
+			},
+			mtest.CreateSuccessResponse(),
+		)
+		err := sessionService.Revoke(context.TODO(), "testuser", id)
+		require.NoError(t, err)
+		_, ok := <-revoked
+		assert.False(t, ok, "the sync subscriptions of the session should be canceled")
+		sync.Publish("testuser", models.ChangeEvent{Op: models.OpUpdate})
+		assert.Equal(t, int64(1), (<-other).Version, "other sessions should stay subscribed")
+	})
+	mt.Run("not_found", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+		err := sessionService.Revoke(context.TODO(), "testuser", id)
+		require.ErrorIs(t, err, errors.ErrSessionNotFound)
+	})
+}
+
+func (suite *SessionServiceTestSuite) TestRevokeAll() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	first, second := models.NewRandomObjectID(), models.NewRandomObjectID()
+	mt.Run("success", func(mt *mtest.T) {
+		sync := NewSyncService()
+		events, unsubscribe := sync.Subscribe("testuser", "", "", time.Time{})
+		defer unsubscribe()
+		sessionService := newTestSessionServiceWithSync(mt, sync)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "sessions.ok", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: first}},
+				bson.D{{Key: "_id", Value: second}},
+			),
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: sessionDocument(first, "token", time.Now().Add(time.Hour))},
+			},
+			mtest.CreateSuccessResponse(),
+			// the second session has been finished concurrently
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+		)
+		err := sessionService.RevokeAll(context.TODO(), "testuser")
+		require.NoError(t, err)
+		_, ok := <-events
+		assert.False(t, ok, "all the sync subscriptions of the user should be canceled")
+	})
+	mt.Run("error", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		err := sessionService.RevokeAll(context.TODO(), "testuser")
+		require.Error(t, err)
+	})
+}
+
+func (suite *SessionServiceTestSuite) TestRevokeDevice() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	id := models.NewRandomObjectID()
+	mt.Run("success", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "sessions.ok", mtest.FirstBatch, bson.D{{Key: "_id", Value: id}}),
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: sessionDocument(id, "token", time.Now().Add(time.Hour))},
+			},
+			mtest.CreateSuccessResponse(),
+		)
+		err := sessionService.RevokeDevice(context.TODO(), "testuser", "device")
+		require.NoError(t, err)
+	})
+	mt.Run("filter", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "sessions.ok", mtest.FirstBatch))
+		err := sessionService.RevokeDevice(context.TODO(), "testuser", "device")
+		require.NoError(t, err)
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		assert.Equal(t, "testuser", filter.Lookup("username").StringValue())
+		assert.Equal(t, "device", filter.Lookup("device_id").StringValue())
+	})
+}
+
+func (suite *SessionServiceTestSuite) TestLogout() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	id := models.NewRandomObjectID()
+	claims := &auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+		Username:  "testuser",
+		SessionID: id.Hex(),
+	}
+	mt.Run("success", func(mt *mtest.T) {
+		sync := NewSyncService()
+		events, unsubscribe := sync.Subscribe("testuser", "", id.Hex(), claims.ExpiresAt.Time)
+		defer unsubscribe()
+		sessionService := newTestSessionServiceWithSync(mt, sync)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: sessionDocument(id, "token", time.Now().Add(time.Hour))},
+			},
+			mtest.CreateSuccessResponse(),
+		)
+		err := sessionService.Logout(context.TODO(), claims)
+		require.NoError(t, err)
+		_, ok := <-events
+		assert.False(t, ok, "the sync subscriptions of the session should be canceled")
+	})
+	mt.Run("already_revoked", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "dup"}),
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+		)
+		err := sessionService.Logout(context.TODO(), claims)
+		require.NoError(t, err)
+	})
+	mt.Run("without_session", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		withoutSession := *claims
+		withoutSession.SessionID = ""
+		err := sessionService.Logout(context.TODO(), &withoutSession)
+		require.NoError(t, err)
+	})
+}
+
+func (suite *SessionServiceTestSuite) TestIsRevoked() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("revoked", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "revoked.ok", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "jti"},
+		}))
+		revoked, err := sessionService.IsRevoked(context.TODO(), "jti")
+		require.NoError(t, err)
+		assert.True(t, revoked)
+	})
+	mt.Run("not_revoked", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "revoked.ok", mtest.FirstBatch))
+		revoked, err := sessionService.IsRevoked(context.TODO(), "jti")
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	})
+	mt.Run("error", func(mt *mtest.T) {
+		sessionService := newTestSessionService(mt)
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+		_, err := sessionService.IsRevoked(context.TODO(), "jti")
+		require.Error(t, err)
+	})
+}
+
+func TestSessionServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionServiceTestSuite))
+}
@@ -2,10 +2,12 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/blokhinnv/gophkeeper/internal/server/errors"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
@@ -20,13 +22,23 @@ type StorageService interface {
 		collectionName models.CollectionName,
 		record models.UntypedRecord,
 	) (string, error)
-	// GetAll retrieves all untyped records for a specified collection and username.
+	// GetAll retrieves a page of untyped records for a specified collection and username
+	// except the records in the trash. The cursor of the next page is returned
+	// if there are more records.
 	GetAll(
 		ctx context.Context,
 		collectionName models.CollectionName,
 		username string,
-	) ([]models.UntypedRecord, error)
-	// Updates the data and metadata of the document.
+		options models.GetAllOptions,
+	) ([]models.UntypedRecord, string, error)
+	// Get retrieves a single untyped record by its ID.
+	Get(
+		ctx context.Context,
+		collectionName models.CollectionName,
+		username string,
+		id models.ObjectID,
+	) (*models.UntypedRecord, error)
+	// Updates the data and metadata of the document if its version is the expected one.
 	Update(
 		ctx context.Context,
 		collectionName models.CollectionName,
@@ -34,32 +46,120 @@ type StorageService interface {
 		id models.ObjectID,
 		newData any,
 		newMetadata models.Metadata,
-	) error
-	// Deletes the document from collection.
+		expectedVersion int64,
+	) (*models.UntypedRecord, error)
+	// Deletes the document from collection: it is moved to the trash.
 	Delete(
 		ctx context.Context,
 		collectionName models.CollectionName,
 		username string,
 		id models.ObjectID,
 	) error
+	// Trash returns the deleted records which have not been purged yet.
+	Trash(
+		ctx context.Context,
+		collectionName models.CollectionName,
+		username string,
+	) ([]models.UntypedRecord, error)
+	// Undelete moves the record back from the trash.
+	Undelete(
+		ctx context.Context,
+		collectionName models.CollectionName,
+		username string,
+		id models.ObjectID,
+	) (*models.UntypedRecord, error)
+	// Purge permanently deletes the data of the record from the trash.
+	Purge(
+		ctx context.Context,
+		collectionName models.CollectionName,
+		username string,
+		id models.ObjectID,
+	) error
+	// PurgeTrash permanently deletes the records of all the users deleted before the time.
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// History returns the previous revisions of the record, the latest first.
+	History(
+		ctx context.Context,
+		collectionName models.CollectionName,
+		username string,
+		id models.ObjectID,
+	) ([]models.Revision, error)
+	// Restore replaces the record with the revision from its history. The deleted record is recreated.
+	Restore(
+		ctx context.Context,
+		collectionName models.CollectionName,
+		username string,
+		id models.ObjectID,
+		rev int64,
+	) (*models.UntypedRecord, error)
+	// Changes returns the user's records of all the collections changed after the time.
+	// The zero time returns all the records.
+	Changes(ctx context.Context, username string, since time.Time) (*models.Changes, error)
+	// Search returns the user's records of all the collections which have all the terms of the query.
+	Search(ctx context.Context, username string, query string) ([]models.SearchResult, error)
+	// Reindex replaces the search tokens of all the records of the collection in batches.
+	Reindex(ctx context.Context, collectionName models.CollectionName, batchSize int) (int64, error)
+	// EnsureIndexes creates the indexes of the collections and their history.
+	EnsureIndexes(ctx context.Context, retention time.Duration) error
+}
+
+// historyTTLIndex is the name of the index which removes the expired revisions.
+const historyTTLIndex = "archived_at_ttl"
+
+// HistoryCollectionName returns the name of the collection which keeps
+// the previous revisions of the records of the collection.
+func HistoryCollectionName(collectionName models.CollectionName) models.CollectionName {
+	return collectionName + "_history"
+}
+
+// liveFilter returns the filter of the user's record which is not in the trash.
+func liveFilter(username string, id models.ObjectID) bson.M {
+	return bson.M{"_id": id, "username": username, "deleted_at": bson.M{"$exists": false}}
+}
+
+// trashFilter returns the filter of the user's record which is in the trash.
+func trashFilter(username string, id models.ObjectID) bson.M {
+	return bson.M{
+		"_id":        id,
+		"username":   username,
+		"deleted_at": bson.M{"$exists": true},
+		"purged_at":  bson.M{"$exists": false},
+	}
+}
+
+// deletedFilter returns the filter of the user's record which is in the trash or purged.
+func deletedFilter(username string, id models.ObjectID) bson.M {
+	return bson.M{"_id": id, "username": username, "deleted_at": bson.M{"$exists": true}}
 }
 
 // storageService is a struct that implements the StorageService
 // interface and uses MongoDB for data storage.
 type storageService struct {
-	db            *mongo.Database
-	encryptionKey string
+	db      *mongo.Database
+	keyring *encrypt.Keyring
+	index   *encrypt.BlindIndex
 }
 
-// NewStorageService creates a new storageService instance.
-func NewStorageService(db *mongo.Database, encryptionKey string) StorageService {
+// NewStorageService creates a new storageService instance. The data is
+// encrypted with the active key of the keyring and decrypted with
+// the key which encrypted it. The searchable terms of the records are
+// saved as the tokens of the blind index.
+func NewStorageService(
+	db *mongo.Database,
+	keyring *encrypt.Keyring,
+	index *encrypt.BlindIndex,
+) StorageService {
 	return &storageService{
-		db:            db,
-		encryptionKey: encryptionKey,
+		db:      db,
+		keyring: keyring,
+		index:   index,
 	}
 }
 
-// Store stores a new untyped record in a specified collection.
+// Store stores a new untyped record in a specified collection. The ID of the
+// record is generated unless the client has chosen it; ErrRecordExists is
+// returned if the chosen ID is taken. The blob of a binary record must be
+// uploaded completely by the same user and is claimed by the record.
 func (t *storageService) Store(
 	ctx context.Context,
 	collectionName models.CollectionName,
@@ -69,43 +169,149 @@ func (t *storageService) Store(
 	defer cancel()
 	collection := t.db.Collection(string(collectionName))
 
-	var encryptedData any
-	var err error
-	switch collectionName {
-	case models.TextCollection:
-		encryptedData, err = encrypt.EncryptString(record.Data.(string), t.encryptionKey)
-	default:
-		encryptedData, err = encrypt.EncryptMap(record.Data.(map[string]any), t.encryptionKey)
+	// the ID is known before the insert, so the blob is claimed by the record
+	if record.RecordID.IsZero() {
+		record.RecordID = models.NewRandomObjectID()
 	}
+	err := claimBlob(ctx, t.db, collectionName, record.Username, record.RecordID, record.Data)
 	if err != nil {
 		return "", err
 	}
 
-	res, err := collection.InsertOne(ctx, bson.D{
+	encryptedData, err := t.encryptData(record.Data)
+	if err != nil {
+		return "", err
+	}
+
+	doc := bson.D{
+		{Key: "_id", Value: record.RecordID},
 		{Key: "username", Value: record.Username},
 		{Key: "data", Value: encryptedData},
 		{Key: "metadata", Value: record.Metadata},
-	})
-	stringObjectID := res.InsertedID.(models.ObjectID).Hex()
-	return stringObjectID, err
+		{
+			Key:   searchTokensField,
+			Value: t.searchTokens(record.Username, collectionName, record.Data, record.Metadata),
+		},
+		{Key: "version", Value: 1},
+		{Key: "updated_at", Value: time.Now().UTC()},
+	}
+	res, err := collection.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return "", errors.ErrRecordExists
+	} else if err != nil {
+		return "", err
+	}
+	return res.InsertedID.(models.ObjectID).Hex(), nil
 }
 
-// GetAll retrieves all untyped records for a specified collection and username.
+// GetAll retrieves a page of untyped records for a specified collection and username
+// except the records in the trash. The records are filtered by the metadata and
+// listed by the sort field, so the page starts right after the record of the cursor.
+// One extra record is requested to know if there is the next page.
 func (t *storageService) GetAll(
 	ctx context.Context,
 	collectionName models.CollectionName,
 	username string,
+	opts models.GetAllOptions,
+) ([]models.UntypedRecord, string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, "", err
+	}
+	filter := bson.M{
+		"username":   username,
+		"deleted_at": bson.M{"$exists": false},
+	}
+	for k, v := range opts.Metadata {
+		filter["metadata."+k] = v
+	}
+	after, _ := opts.After()
+	if after != nil {
+		filter["$or"] = afterFilter(opts, *after)
+	}
+	field, desc := opts.SortField()
+	order := 1
+	if desc {
+		order = -1
+	}
+	sort := bson.D{{Key: "_id", Value: order}}
+	if field == models.SortUpdated {
+		sort = bson.D{{Key: "updated_at", Value: order}, {Key: "_id", Value: order}}
+	}
+	findOptions := options.Find().SetSort(sort)
+	if opts.Limit > 0 {
+		findOptions.SetLimit(opts.Limit + 1)
+	}
+	if opts.OmitContent && collectionName == models.BinaryCollection {
+		findOptions.SetProjection(bson.M{"data.Content": 0})
+	}
+	records, err := t.find(ctx, collectionName, filter, findOptions)
+	if err != nil {
+		return nil, "", err
+	}
+	if opts.Limit == 0 || int64(len(records)) <= opts.Limit {
+		return records, "", nil
+	}
+	records = records[:opts.Limit]
+	last := records[len(records)-1]
+	return records, opts.NextCursor(
+		models.PageKey{RecordID: last.RecordID, UpdatedAt: last.UpdatedAt},
+	), nil
+}
+
+// afterFilter returns the conditions of the records listed after the key.
+// The records saved before the time of the change was kept have no
+// updated_at and are listed as the oldest ones.
+func afterFilter(opts models.GetAllOptions, key models.PageKey) bson.A {
+	field, desc := opts.SortField()
+	cmp := "$gt"
+	if desc {
+		cmp = "$lt"
+	}
+	if field == models.SortCreated {
+		return bson.A{bson.M{"_id": bson.M{cmp: key.RecordID}}}
+	}
+	if key.UpdatedAt == nil {
+		sameTime := bson.M{"updated_at": nil, "_id": bson.M{cmp: key.RecordID}}
+		if desc {
+			return bson.A{sameTime}
+		}
+		return bson.A{sameTime, bson.M{"updated_at": bson.M{"$ne": nil}}}
+	}
+	conditions := bson.A{
+		bson.M{"updated_at": bson.M{cmp: *key.UpdatedAt}},
+		bson.M{"updated_at": *key.UpdatedAt, "_id": bson.M{cmp: key.RecordID}},
+	}
+	if desc {
+		conditions = append(conditions, bson.M{"updated_at": nil})
+	}
+	return conditions
+}
+
+// Trash returns the deleted records of the user which have not been purged yet.
+func (t *storageService) Trash(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	username string,
+) ([]models.UntypedRecord, error) {
+	return t.find(ctx, collectionName, bson.M{
+		"username":   username,
+		"deleted_at": bson.M{"$exists": true},
+		"purged_at":  bson.M{"$exists": false},
+	})
+}
+
+// find returns the decrypted records of the collection which match the filter.
+func (t *storageService) find(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	filter bson.M,
+	findOptions ...*options.FindOptions,
 ) ([]models.UntypedRecord, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	result := make([]models.UntypedRecord, 0)
 	collection := t.db.Collection(string(collectionName))
-	cur, err := collection.Find(
-		ctx,
-		bson.D{
-			{Key: "username", Value: username},
-		},
-	)
+	cur, err := collection.Find(ctx, filter, findOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -117,18 +323,9 @@ func (t *storageService) GetAll(
 		if err != nil {
 			return nil, err
 		}
-		var decryptedData any
-		v, ok := r.Data.(bson.D)
-		if ok {
-			decryptedData, err = encrypt.DecryptMap(v.Map(), t.encryptionKey)
-		} else {
-			decryptedData, err = encrypt.DecryptString(r.Data.(string), t.encryptionKey)
-		}
-		r.Data = decryptedData
-		if err != nil {
+		if err := t.decryptData(&r); err != nil {
 			return nil, err
 		}
-
 		result = append(result, r)
 	}
 	if err := cur.Err(); err != nil {
@@ -137,8 +334,80 @@ func (t *storageService) GetAll(
 	return result, nil
 }
 
+// Get retrieves a single untyped record with the specified ID
+// owned by the specified user. The records in the trash are not found.
+func (t *storageService) Get(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	username string,
+	id models.ObjectID,
+) (*models.UntypedRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	filter := liveFilter(username, id)
+	collection := t.db.Collection(string(collectionName))
+	var r models.UntypedRecord
+	err := collection.FindOne(ctx, filter).Decode(&r)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.ErrRecordNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if err := t.decryptData(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// encryptData encrypts the data of the record. The data is a string for
+// the text collection and a map for the other collections. The envelope of
+// an end-to-end encrypted record is a map for any collection.
+func (t *storageService) encryptData(data any) (any, error) {
+	return encryptData(t.keyring, data)
+}
+
+// decryptData replaces the encrypted data of the record with its plain value.
+func (t *storageService) decryptData(r *models.UntypedRecord) error {
+	decryptedData, err := decryptData(t.keyring, r.Data)
+	if err != nil {
+		return err
+	}
+	r.Data = decryptedData
+	return nil
+}
+
+// encryptData encrypts the plain data with the active key of the keyring.
+func encryptData(keyring *encrypt.Keyring, data any) (any, error) {
+	switch v := data.(type) {
+	case string:
+		return keyring.EncryptString(v)
+	case map[string]any:
+		return keyring.EncryptMap(v)
+	default:
+		return nil, fmt.Errorf("unexpected record data type %T", data)
+	}
+}
+
+// decryptData decrypts the data read from the DB with the keys of the keyring.
+func decryptData(keyring *encrypt.Keyring, data any) (any, error) {
+	switch v := data.(type) {
+	case string:
+		return keyring.DecryptString(v)
+	case bson.D:
+		return keyring.DecryptMap(v.Map())
+	default:
+		return nil, fmt.Errorf("unexpected encrypted data type %T", data)
+	}
+}
+
 // Updates the data and metadata of the document with the specified ID in the
 // collection with the specified name, using the new data and metadata values.
+// The version of the document is incremented and the previous revision is
+// archived. If the expected version is not zero and the document has another
+// version, the current document is returned with ErrVersionConflict.
+// Otherwise the updated document is returned. The blob of a binary record
+// must be uploaded completely by the same user; the blob the record referred
+// to before is released.
 func (t *storageService) Update(
 	ctx context.Context,
 	collectionName models.CollectionName,
@@ -146,42 +415,78 @@ func (t *storageService) Update(
 	id models.ObjectID,
 	newData any,
 	newMetadata models.Metadata,
-) error {
+	expectedVersion int64,
+) (*models.UntypedRecord, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	var encryptedNewData any
-	var err error
-	switch collectionName {
-	case models.TextCollection:
-		encryptedNewData, err = encrypt.EncryptString(newData.(string), t.encryptionKey)
-	default:
-		encryptedNewData, err = encrypt.EncryptMap(newData.(map[string]any), t.encryptionKey)
+	if err := claimBlob(ctx, t.db, collectionName, username, id, newData); err != nil {
+		return nil, err
 	}
+	encryptedNewData, err := t.encryptData(newData)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	filter := bson.M{"_id": id, "username": username}
-	upd := bson.D{{
-		Key: "$set",
-		Value: bson.D{
-			{Key: "data", Value: encryptedNewData},
-			{Key: "metadata", Value: newMetadata},
+	filter := liveFilter(username, id)
+	if expectedVersion != 0 {
+		filter["version"] = expectedVersion
+	}
+	now := time.Now().UTC()
+	upd := bson.D{
+		{
+			Key: "$set",
+			Value: bson.D{
+				{Key: "data", Value: encryptedNewData},
+				{Key: "metadata", Value: newMetadata},
+				{
+					Key:   searchTokensField,
+					Value: t.searchTokens(username, collectionName, newData, newMetadata),
+				},
+				{Key: "updated_at", Value: now},
+			},
 		},
-	}}
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	}
 	collection := t.db.Collection(string(collectionName))
-	res, err := collection.UpdateOne(ctx, filter, upd)
-	if err != nil {
-		return err
+	var prev models.UntypedRecord
+	err = collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		upd,
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&prev)
+	if err == mongo.ErrNoDocuments {
+		if expectedVersion == 0 {
+			return nil, errors.ErrRecordNotFound
+		}
+		// the document is either missing or has another version
+		current, err := t.Get(ctx, collectionName, username, id)
+		if err != nil {
+			return nil, err
+		}
+		return current, errors.ErrVersionConflict
+	} else if err != nil {
+		return nil, err
 	}
-	if res.ModifiedCount == 0 {
-		return errors.ErrRecordNotFound
+	if err := t.archive(ctx, collectionName, username, prev, models.OpUpdate); err != nil {
+		return nil, err
 	}
-	return nil
+	if err := releaseBlobs(ctx, t.db, collectionName, id, newData); err != nil {
+		return nil, fmt.Errorf("the record is updated but its previous blob is not released: %w", err)
+	}
+	return &models.UntypedRecord{
+		UntypedRecordContent: models.UntypedRecordContent{Data: newData, Metadata: newMetadata},
+		RecordID:             id,
+		Username:             username,
+		Version:              prev.Version + 1,
+		UpdatedAt:            &now,
+	}, nil
 }
 
-// Delete removes a document from the specified collection using its ObjectID.
+// Delete moves a document of the specified collection to the trash: the time
+// of the deletion is set and the version is incremented. The previous state
+// of the document is archived.
 func (t *storageService) Delete(
 	ctx context.Context,
 	collectionName models.CollectionName,
@@ -190,15 +495,431 @@ func (t *storageService) Delete(
 ) error {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
-	filter := bson.M{"_id": id, "username": username}
+	now := time.Now().UTC()
+	upd := bson.D{
+		{
+			Key: "$set",
+			Value: bson.D{
+				{Key: "deleted_at", Value: now},
+				{Key: "updated_at", Value: now},
+			},
+		},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	}
+	collection := t.db.Collection(string(collectionName))
+	var prev models.UntypedRecord
+	err := collection.FindOneAndUpdate(ctx, liveFilter(username, id), upd).Decode(&prev)
+	if err == mongo.ErrNoDocuments {
+		return errors.ErrRecordNotFound
+	} else if err != nil {
+		return err
+	}
+	return t.archive(ctx, collectionName, username, prev, models.OpDelete)
+}
+
+// Undelete moves the document back from the trash and increments its version.
+func (t *storageService) Undelete(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	username string,
+	id models.ObjectID,
+) (*models.UntypedRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	upd := bson.D{
+		{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: ""}}},
+		{Key: "$set", Value: bson.D{{Key: "updated_at", Value: time.Now().UTC()}}},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	}
 	collection := t.db.Collection(string(collectionName))
-	res, err := collection.DeleteOne(ctx, filter)
+	var r models.UntypedRecord
+	err := collection.FindOneAndUpdate(
+		ctx,
+		trashFilter(username, id),
+		upd,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&r)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.ErrRecordNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if err := t.decryptData(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
 
+// Purge permanently deletes the data and metadata of the document from the trash
+// together with its history and the blobs of the binary record and its revisions.
+// A blob is claimed by a single record, so the blobs claimed by the record are
+// referred to by nothing else.
+// The rest of the document is kept as a tombstone for the delta sync until
+// the trash is purged by PurgeTrash.
+func (t *storageService) Purge(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	username string,
+	id models.ObjectID,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	now := time.Now().UTC()
+	upd := bson.D{
+		{
+			Key: "$unset",
+			Value: bson.D{
+				{Key: "data", Value: ""},
+				{Key: "metadata", Value: ""},
+				{Key: searchTokensField, Value: ""},
+			},
+		},
+		{
+			Key: "$set",
+			Value: bson.D{
+				{Key: "purged_at", Value: now},
+				{Key: "updated_at", Value: now},
+			},
+		},
+	}
+	collection := t.db.Collection(string(collectionName))
+	res, err := collection.UpdateOne(ctx, trashFilter(username, id), upd)
 	if err != nil {
 		return err
 	}
-	if res.DeletedCount == 0 {
+	if res.MatchedCount == 0 {
 		return errors.ErrRecordNotFound
 	}
+	if collectionName == models.BinaryCollection {
+		_, err := deleteBlobs(ctx, t.db, bson.M{"record_id": id, "username": username})
+		if err != nil {
+			return fmt.Errorf("the record is purged but its blobs are not deleted: %w", err)
+		}
+	}
+	history := t.db.Collection(string(HistoryCollectionName(collectionName)))
+	_, err = history.DeleteMany(ctx, bson.M{"record_id": id, "username": username})
+	if err != nil {
+		return fmt.Errorf("the record is purged but its history is not deleted: %w", err)
+	}
 	return nil
 }
+
+// PurgeTrash permanently deletes the documents of all the collections and
+// all the users which were moved to the trash before the specified time
+// including the tombstones of the purged ones. Their history and the blobs of
+// the binary records are deleted first, so the documents which failed to be
+// deleted are purged next time.
+// The number of the deleted documents is returned.
+func (t *storageService) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var purged int64
+	expired := bson.M{"deleted_at": bson.M{"$lt": deletedBefore}}
+	for _, collectionName := range models.AllowedCollectionNames {
+		collection := t.db.Collection(string(collectionName))
+		ids, err := collection.Distinct(ctx, "_id", expired)
+		if err != nil {
+			return purged, err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		if collectionName == models.BinaryCollection {
+			_, err := deleteBlobs(ctx, t.db, bson.M{"record_id": bson.M{"$in": ids}})
+			if err != nil {
+				return purged, err
+			}
+		}
+		history := t.db.Collection(string(HistoryCollectionName(collectionName)))
+		_, err = history.DeleteMany(ctx, bson.M{"record_id": bson.M{"$in": ids}})
+		if err != nil {
+			return purged, err
+		}
+		res, err := collection.DeleteMany(ctx, bson.M{
+			"_id":        bson.M{"$in": ids},
+			"deleted_at": bson.M{"$lt": deletedBefore},
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged += res.DeletedCount
+	}
+	return purged, nil
+}
+
+// archive saves the replaced document with the encrypted data to the history.
+func (t *storageService) archive(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	username string,
+	prev models.UntypedRecord,
+	op models.ChangeOp,
+) error {
+	history := t.db.Collection(string(HistoryCollectionName(collectionName)))
+	_, err := history.InsertOne(ctx, models.Revision{
+		UntypedRecordContent: prev.UntypedRecordContent,
+		RecordID:             prev.RecordID,
+		Username:             username,
+		Rev:                  prev.Version,
+		Op:                   op,
+		UpdatedAt:            prev.UpdatedAt,
+		ArchivedAt:           time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("the record is changed but its revision is not archived: %w", err)
+	}
+	return nil
+}
+
+// History returns the previous revisions of the record with the decrypted data, the latest first.
+func (t *storageService) History(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	username string,
+	id models.ObjectID,
+) ([]models.Revision, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	history := t.db.Collection(string(HistoryCollectionName(collectionName)))
+	cur, err := history.Find(
+		ctx,
+		bson.M{"record_id": id, "username": username},
+		options.Find().SetSort(bson.D{{Key: "rev", Value: -1}, {Key: "archived_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	result := make([]models.Revision, 0)
+	for cur.Next(ctx) {
+		var r models.Revision
+		if err := cur.Decode(&r); err != nil {
+			return nil, err
+		}
+		if r.Data, err = decryptData(t.keyring, r.Data); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Restore replaces the record with the latest revision rev from its history.
+// The current record is archived like on update. The record in the trash is
+// moved back with the data of the revision. The purged records have no history,
+// but the record whose document is missing is recreated with the same ID and
+// the version following the last revision.
+func (t *storageService) Restore(
+	ctx context.Context,
+	collectionName models.CollectionName,
+	username string,
+	id models.ObjectID,
+	rev int64,
+) (*models.UntypedRecord, error) {
+	revisions, err := t.History(ctx, collectionName, username, id)
+	if err != nil {
+		return nil, err
+	}
+	var target *models.Revision
+	for i := range revisions {
+		if revisions[i].Rev == rev {
+			target = &revisions[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, errors.ErrRevisionNotFound
+	}
+	record, err := t.Update(ctx, collectionName, username, id, target.Data, target.Metadata, 0)
+	if err != errors.ErrRecordNotFound {
+		return record, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := claimBlob(ctx, t.db, collectionName, username, id, target.Data); err != nil {
+		return nil, err
+	}
+	encryptedData, err := t.encryptData(target.Data)
+	if err != nil {
+		return nil, err
+	}
+	tokens := t.searchTokens(username, collectionName, target.Data, target.Metadata)
+	now := time.Now().UTC()
+	upd := bson.D{
+		{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: ""}, {Key: "purged_at", Value: ""}}},
+		{
+			Key: "$set",
+			Value: bson.D{
+				{Key: "data", Value: encryptedData},
+				{Key: "metadata", Value: target.Metadata},
+				{Key: searchTokensField, Value: tokens},
+				{Key: "updated_at", Value: now},
+			},
+		},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	}
+	var trashed models.UntypedRecord
+	err = t.db.Collection(string(collectionName)).FindOneAndUpdate(
+		ctx,
+		deletedFilter(username, id),
+		upd,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&trashed)
+	if err == nil {
+		if err := releaseBlobs(ctx, t.db, collectionName, id, target.Data); err != nil {
+			return nil, fmt.Errorf("the record is restored but its previous blob is not released: %w", err)
+		}
+		trashed.UntypedRecordContent = target.UntypedRecordContent
+		trashed.Username = username
+		return &trashed, nil
+	} else if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	record = &models.UntypedRecord{
+		UntypedRecordContent: target.UntypedRecordContent,
+		RecordID:             id,
+		Username:             username,
+		Version:              revisions[0].Rev + 1,
+		UpdatedAt:            &now,
+	}
+	_, err = t.db.Collection(string(collectionName)).InsertOne(ctx, bson.D{
+		{Key: "_id", Value: id},
+		{Key: "username", Value: username},
+		{Key: "data", Value: encryptedData},
+		{Key: "metadata", Value: target.Metadata},
+		{Key: searchTokensField, Value: tokens},
+		{Key: "version", Value: record.Version},
+		{Key: "updated_at", Value: now},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Changes returns the user's records of all the collections changed after
+// the time: the live records are the upserts and the records in the trash
+// or purged are the tombstones. The zero time returns all the live records
+// with the reset flag. The cursor of the changes is set by the caller.
+func (t *storageService) Changes(
+	ctx context.Context,
+	username string,
+	since time.Time,
+) (*models.Changes, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	changes := &models.Changes{
+		Upserts:    make([]models.ChangedRecord, 0),
+		Tombstones: make([]models.Tombstone, 0),
+		Reset:      since.IsZero(),
+	}
+	filter := bson.M{"username": username, "updated_at": bson.M{"$gt": since}}
+	if changes.Reset {
+		filter = bson.M{"username": username, "deleted_at": bson.M{"$exists": false}}
+	}
+	for _, collectionName := range models.AllowedCollectionNames {
+		cur, err := t.db.Collection(string(collectionName)).Find(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for cur.Next(ctx) {
+			var r models.UntypedRecord
+			if err := cur.Decode(&r); err != nil {
+				cur.Close(ctx)
+				return nil, err
+			}
+			if r.DeletedAt != nil {
+				changes.Tombstones = append(changes.Tombstones, models.Tombstone{
+					Collection: collectionName,
+					RecordID:   r.RecordID,
+					DeletedAt:  r.DeletedAt,
+				})
+				continue
+			}
+			if err := t.decryptData(&r); err != nil {
+				cur.Close(ctx)
+				return nil, err
+			}
+			changes.Upserts = append(changes.Upserts, models.ChangedRecord{
+				Collection: collectionName,
+				Record:     r,
+			})
+		}
+		err = cur.Err()
+		cur.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// EnsureIndexes creates the indexes of the changes and of the search of
+// the collections and of the history collections. The revisions are removed after the retention
+// period; zero retention keeps them forever.
+func (t *storageService) EnsureIndexes(ctx context.Context, retention time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	for _, collectionName := range models.AllowedCollectionNames {
+		_, err := t.db.Collection(string(collectionName)).Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "username", Value: 1}, {Key: "updated_at", Value: 1}}},
+			{Keys: bson.D{{Key: "username", Value: 1}, {Key: searchTokensField, Value: 1}}},
+		})
+		if err != nil {
+			return err
+		}
+		name := string(HistoryCollectionName(collectionName))
+		indexes := t.db.Collection(name).Indexes()
+		_, err = indexes.CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "username", Value: 1},
+				{Key: "record_id", Value: 1},
+				{Key: "rev", Value: -1},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if err := t.ensureTTLIndex(ctx, name, retention); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureTTLIndex creates, changes or drops the index which removes the expired revisions.
+func (t *storageService) ensureTTLIndex(
+	ctx context.Context,
+	name string,
+	retention time.Duration,
+) error {
+	indexes := t.db.Collection(name).Indexes()
+	if retention == 0 {
+		_, err := indexes.DropOne(ctx, historyTTLIndex)
+		// IndexNotFound and NamespaceNotFound
+		if cmdErr, ok := err.(mongo.CommandError); ok && (cmdErr.Code == 27 || cmdErr.Code == 26) {
+			return nil
+		}
+		return err
+	}
+	seconds := int32(retention.Seconds())
+	_, err := indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "archived_at", Value: 1}},
+		Options: options.Index().SetName(historyTTLIndex).SetExpireAfterSeconds(seconds),
+	})
+	// IndexOptionsConflict means that the retention period has been changed
+	if cmdErr, ok := err.(mongo.CommandError); ok && cmdErr.Code == 85 {
+		return t.db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: name},
+			{Key: "index", Value: bson.D{
+				{Key: "name", Value: historyTTLIndex},
+				{Key: "expireAfterSeconds", Value: seconds},
+			}},
+		}).Err()
+	}
+	return err
+}
@@ -3,12 +3,14 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
 	"github.com/blokhinnv/gophkeeper/pkg/encrypt"
 )
@@ -25,7 +27,7 @@ func (suite *StorageServiceTestSuite) TestStore() {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 	defer mt.Close()
 	mt.Run("success_text", func(mt *mtest.T) {
-		storageService := NewStorageService(mt.DB, "my-secret-key")
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
 		rec := models.UntypedRecord{
 			UntypedRecordContent: models.UntypedRecordContent{
@@ -39,7 +41,7 @@ func (suite *StorageServiceTestSuite) TestStore() {
 		require.NotEmpty(t, res)
 	})
 	mt.Run("success_not_text", func(mt *mtest.T) {
-		storageService := NewStorageService(mt.DB, "my-secret-key")
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
 		rec := models.UntypedRecord{
 			UntypedRecordContent: models.UntypedRecordContent{
@@ -55,6 +57,128 @@ func (suite *StorageServiceTestSuite) TestStore() {
 		require.NoError(t, err)
 		require.NotEmpty(t, res)
 	})
+	mt.Run("blob", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		blobID := models.NewRandomObjectID()
+		rec := models.UntypedRecord{
+			UntypedRecordContent: models.UntypedRecordContent{
+				Data: map[string]any{"FileName": "report.pdf", "BlobID": blobID.Hex()},
+			},
+			Username: "blokhinnv",
+		}
+		ns := mt.DB.Name() + "." + BlobsCollection
+		blob := func(received int64, fields ...bson.E) bson.D {
+			return append(bson.D{
+				{Key: "_id", Value: blobID},
+				{Key: "username", Value: "blokhinnv"},
+				{Key: "size", Value: int64(10)},
+				{Key: "received", Value: received},
+			}, fields...)
+		}
+		claimed := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, blob(10)),
+			claimed,
+			mtest.CreateSuccessResponse(),
+		)
+		res, err := storageService.Store(context.TODO(), models.BinaryCollection, rec)
+		require.NoError(t, err)
+		require.NotEmpty(t, res)
+		// the blob must belong to the user
+		started := mt.GetStartedEvent()
+		require.Equal(t, BlobsCollection, started.Command.Lookup("find").StringValue())
+		require.Equal(t, "blokhinnv", started.Command.Lookup("filter", "username").StringValue())
+		// and is claimed by the new record unless another record has claimed it
+		started = mt.GetStartedEvent()
+		update := started.Command.Lookup("updates").Array().Index(0).Value().Document()
+		claimedBy := update.Lookup("u", "$set", "record_id").ObjectID()
+		require.Equal(t, res, claimedBy.Hex())
+		allowed := update.Lookup("q", "record_id", "$in").Array()
+		require.Equal(t, bson.TypeNull, allowed.Index(0).Value().Type)
+		require.Equal(t, claimedBy, allowed.Index(1).Value().ObjectID())
+		require.Equal(t, res, mt.GetStartedEvent().Command.Lookup("documents").Array().
+			Index(0).Value().Document().Lookup("_id").ObjectID().Hex())
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, blob(5)))
+		_, err = storageService.Store(context.TODO(), models.BinaryCollection, rec)
+		require.ErrorIs(t, err, errors.ErrBlobIncomplete)
+
+		// two records can't share a blob
+		other := bson.E{Key: "record_id", Value: models.NewRandomObjectID()}
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, blob(10, other)))
+		_, err = storageService.Store(context.TODO(), models.BinaryCollection, rec)
+		require.ErrorIs(t, err, errors.ErrBlobInUse)
+
+		// another record has claimed the blob after it was read
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, blob(10)),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}},
+		)
+		_, err = storageService.Store(context.TODO(), models.BinaryCollection, rec)
+		require.ErrorIs(t, err, errors.ErrBlobInUse)
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+		_, err = storageService.Store(context.TODO(), models.BinaryCollection, rec)
+		require.ErrorIs(t, err, errors.ErrBlobNotFound)
+	})
+	mt.Run("success_encrypted_text", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		rec := models.UntypedRecord{
+			UntypedRecordContent: models.UntypedRecordContent{
+				Data: models.NewEncryptedData([]byte("ciphertext")),
+			},
+			Username: "blokhinnv",
+		}
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		res, err := storageService.Store(context.TODO(), models.TextCollection, rec)
+		require.NoError(t, err)
+		require.NotEmpty(t, res)
+	})
+	mt.Run("bad_data", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		rec := models.UntypedRecord{
+			UntypedRecordContent: models.UntypedRecordContent{
+				Data: 42,
+			},
+			Username: "blokhinnv",
+		}
+		_, err := storageService.Store(context.TODO(), models.TextCollection, rec)
+		require.Error(t, err)
+	})
+	mt.Run("client_id", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		id := models.NewRandomObjectID()
+		rec := models.UntypedRecord{
+			UntypedRecordContent: models.UntypedRecordContent{
+				Data: models.NewEncryptedData([]byte("ciphertext")),
+			},
+			RecordID: id,
+			Username: "blokhinnv",
+		}
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		res, err := storageService.Store(context.TODO(), models.TextCollection, rec)
+		require.NoError(t, err)
+		require.Equal(t, id.Hex(), res)
+		doc := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		require.Equal(t, id, doc.Lookup("_id").ObjectID())
+	})
+	mt.Run("client_id_taken", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		rec := models.UntypedRecord{
+			UntypedRecordContent: models.UntypedRecordContent{
+				Data: "test message",
+			},
+			RecordID: models.NewRandomObjectID(),
+			Username: "blokhinnv",
+		}
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: "duplicate key error",
+		}))
+		_, err := storageService.Store(context.TODO(), models.TextCollection, rec)
+		require.ErrorIs(t, err, errors.ErrRecordExists)
+	})
 }
 
 func (suite *StorageServiceTestSuite) TestGetAll() {
@@ -63,7 +187,7 @@ func (suite *StorageServiceTestSuite) TestGetAll() {
 	defer mt.Close()
 	mt.Run("success_text", func(mt *mtest.T) {
 		secretKey := "my-secret-key"
-		storageService := NewStorageService(mt.DB, secretKey)
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
 
 		username := "blokhinnv"
 		rawData := "some text data.."
@@ -78,14 +202,19 @@ func (suite *StorageServiceTestSuite) TestGetAll() {
 		batchEnd := mtest.CreateCursorResponse(0, "get_all.success_text", mtest.NextBatch)
 		mt.AddMockResponses(batchItem, batchEnd)
 
-		res, err := storageService.GetAll(context.TODO(), models.TextCollection, username)
+		res, _, err := storageService.GetAll(
+			context.TODO(),
+			models.TextCollection,
+			username,
+			models.GetAllOptions{},
+		)
 		require.NoError(t, err)
 		require.NotEmpty(t, res)
 		require.Equal(t, rawData, res[0].Data)
 	})
 	mt.Run("success_not_text", func(mt *mtest.T) {
 		secretKey := "my-secret-key"
-		storageService := NewStorageService(mt.DB, secretKey)
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
 
 		username := "blokhinnv"
 		rawData := map[string]any{
@@ -108,7 +237,12 @@ func (suite *StorageServiceTestSuite) TestGetAll() {
 		batchEnd := mtest.CreateCursorResponse(0, "get_all.success_not_text", mtest.NextBatch)
 		mt.AddMockResponses(batchItem, batchEnd)
 
-		res, err := storageService.GetAll(context.TODO(), models.TextCollection, username)
+		res, _, err := storageService.GetAll(
+			context.TODO(),
+			models.TextCollection,
+			username,
+			models.GetAllOptions{},
+		)
 		require.NoError(t, err)
 		require.NotEmpty(t, res)
 
@@ -119,13 +253,176 @@ func (suite *StorageServiceTestSuite) TestGetAll() {
 	})
 	mt.Run("empty_response", func(mt *mtest.T) {
 		secretKey := "my-secret-key"
-		storageService := NewStorageService(mt.DB, secretKey)
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
 
 		username := "blokhinnv"
-		res, err := storageService.GetAll(context.TODO(), models.TextCollection, username)
+		res, _, err := storageService.GetAll(
+			context.TODO(),
+			models.TextCollection,
+			username,
+			models.GetAllOptions{},
+		)
 		require.Error(t, err)
 		require.Empty(t, res)
 	})
+	mt.Run("page", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		username := "blokhinnv"
+		updatedAt := time.Date(2023, 5, 9, 12, 0, 0, 0, time.UTC)
+		ids := []models.ObjectID{
+			models.NewRandomObjectID(),
+			models.NewRandomObjectID(),
+			models.NewRandomObjectID(),
+		}
+		docs := make([]bson.D, 0, len(ids))
+		for _, id := range ids {
+			data, err := encrypt.EncryptString("text", "my-secret-key")
+			require.NoError(t, err)
+			docs = append(docs, bson.D{
+				{Key: "_id", Value: id},
+				{Key: "data", Value: data},
+				{Key: "metadata", Value: bson.M{"site": "github.com"}},
+				{Key: "updated_at", Value: updatedAt},
+			})
+		}
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.text", mtest.FirstBatch, docs...))
+
+		options := models.GetAllOptions{
+			Limit:    2,
+			Sort:     "-updated",
+			Metadata: models.Metadata{"site": "github.com"},
+		}
+		res, cursor, err := storageService.GetAll(
+			context.TODO(),
+			models.TextCollection,
+			username,
+			options,
+		)
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		require.NotEmpty(t, cursor)
+
+		cmd := mt.GetStartedEvent().Command
+		require.Equal(t, int64(3), cmd.Lookup("limit").AsInt64())
+		require.Equal(
+			t,
+			"github.com",
+			cmd.Lookup("filter", "metadata.site").StringValue(),
+		)
+		sort, err := cmd.Lookup("sort").Document().Elements()
+		require.NoError(t, err)
+		require.Equal(t, "updated_at", sort[0].Key())
+		require.Equal(t, "_id", sort[1].Key())
+		require.Equal(t, int64(-1), sort[0].Value().AsInt64())
+
+		// the next page starts after the last record of the page
+		options.Cursor = cursor
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.text", mtest.FirstBatch, docs[2]))
+		res, cursor, err = storageService.GetAll(
+			context.TODO(),
+			models.TextCollection,
+			username,
+			options,
+		)
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		require.Empty(t, cursor)
+		after, err := mt.GetStartedEvent().Command.Lookup("filter", "$or").Array().Values()
+		require.NoError(t, err)
+		require.Len(t, after, 3)
+		require.Equal(
+			t,
+			ids[1],
+			after[1].Document().Lookup("_id", "$lt").ObjectID(),
+		)
+	})
+	mt.Run("omit_content", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.binary", mtest.FirstBatch))
+
+		res, cursor, err := storageService.GetAll(
+			context.TODO(),
+			models.BinaryCollection,
+			"blokhinnv",
+			models.GetAllOptions{OmitContent: true},
+		)
+		require.NoError(t, err)
+		require.Empty(t, res)
+		require.Empty(t, cursor)
+		cmd := mt.GetStartedEvent().Command
+		require.Equal(t, int64(0), cmd.Lookup("projection", "data.Content").AsInt64())
+		require.Equal(t, int64(1), cmd.Lookup("sort", "_id").AsInt64())
+	})
+	mt.Run("bad_options", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		_, _, err := storageService.GetAll(
+			context.TODO(),
+			models.TextCollection,
+			"blokhinnv",
+			models.GetAllOptions{Sort: "name"},
+		)
+		require.ErrorIs(t, err, errors.ErrBadListOptions)
+	})
+}
+
+func (suite *StorageServiceTestSuite) TestGet() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		secretKey := "my-secret-key"
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
+
+		id := models.NewRandomObjectID()
+		rawData := map[string]any{
+			"Secret": "JBSWY3DPEHPK3PXP",
+			"Issuer": "Example",
+		}
+		data, err := encrypt.EncryptMap(rawData, secretKey)
+		require.NoError(t, err)
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "get.success", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: id},
+			{Key: "username", Value: "blokhinnv"},
+			{Key: "data", Value: data},
+		}))
+
+		res, err := storageService.Get(context.TODO(), models.OTPCollection, "blokhinnv", id)
+		require.NoError(t, err)
+		require.Equal(t, id, res.RecordID)
+		require.Equal(t, rawData, res.Data)
+	})
+	mt.Run("old_key", func(mt *mtest.T) {
+		oldKeyring, err := encrypt.NewKeyring("1", map[string]string{"1": "old-key"})
+		require.NoError(t, err)
+		keyring, err := encrypt.NewKeyring("2", map[string]string{"1": "old-key", "2": "new-key"})
+		require.NoError(t, err)
+		storageService := NewStorageService(mt.DB, keyring, newTestIndex(t))
+
+		id := models.NewRandomObjectID()
+		data, err := oldKeyring.EncryptString("some text data..")
+		require.NoError(t, err)
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "get.old_key", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: id},
+			{Key: "username", Value: "blokhinnv"},
+			{Key: "data", Value: data},
+		}))
+
+		res, err := storageService.Get(context.TODO(), models.TextCollection, "blokhinnv", id)
+		require.NoError(t, err)
+		require.Equal(t, "some text data..", res.Data)
+	})
+	mt.Run("not_found", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "get.not_found", mtest.FirstBatch))
+
+		_, err := storageService.Get(
+			context.TODO(),
+			models.OTPCollection,
+			"blokhinnv",
+			models.NewRandomObjectID(),
+		)
+		require.ErrorIs(t, err, errors.ErrRecordNotFound)
+	})
 }
 
 func (suite *StorageServiceTestSuite) TestUpdate() {
@@ -133,52 +430,187 @@ func (suite *StorageServiceTestSuite) TestUpdate() {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 	defer mt.Close()
 	mt.Run("success_text", func(mt *mtest.T) {
-		storageService := NewStorageService(mt.DB, "my-secret-key")
-		mt.AddMockResponses(bson.D{
-			{Key: "ok", Value: 1},
-			{Key: "nModified", Value: 1},
-		})
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		id := models.NewRandomObjectID()
+		mt.AddMockResponses(
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: bson.D{{Key: "_id", Value: id}, {Key: "version", Value: 2}}},
+			},
+			mtest.CreateSuccessResponse(),
+		)
 
-		err := storageService.Update(
+		res, err := storageService.Update(
 			context.TODO(),
 			models.TextCollection,
 			"blokhinnv",
-			models.NewRandomObjectID(),
+			id,
 			"test message",
 			make(models.Metadata),
+			2,
 		)
 		require.NoError(t, err)
+		require.Equal(t, int64(3), res.Version)
+		require.Equal(t, "test message", res.Data)
 	})
 	mt.Run("success_not_text", func(mt *mtest.T) {
-		storageService := NewStorageService(mt.DB, "my-secret-key")
-		mt.AddMockResponses(bson.D{
-			{Key: "ok", Value: 1},
-			{Key: "nModified", Value: 1},
-		})
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: bson.D{{Key: "_id", Value: models.NewRandomObjectID()}}},
+			},
+			mtest.CreateSuccessResponse(),
+		)
 
-		err := storageService.Update(
+		_, err := storageService.Update(
 			context.TODO(),
 			models.CredentialsCollection,
 			"blokhinnv",
 			models.NewRandomObjectID(),
 			map[string]any{"login": "blokhinnv", "password": "some-pwd"},
 			make(models.Metadata),
+			0,
 		)
 		require.NoError(t, err)
 	})
+	mt.Run("blob", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		id, blobID := models.NewRandomObjectID(), models.NewRandomObjectID()
+		modified := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.blobs", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: blobID},
+				{Key: "username", Value: "blokhinnv"},
+				{Key: "size", Value: int64(10)},
+				{Key: "received", Value: int64(10)},
+				{Key: "record_id", Value: id},
+			}),
+			modified,
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: bson.D{{Key: "_id", Value: id}, {Key: "version", Value: 2}}},
+			},
+			mtest.CreateSuccessResponse(),
+			modified,
+		)
+
+		_, err := storageService.Update(
+			context.TODO(),
+			models.BinaryCollection,
+			"blokhinnv",
+			id,
+			map[string]any{"FileName": "report.pdf", "BlobID": blobID.Hex()},
+			make(models.Metadata),
+			0,
+		)
+		require.NoError(t, err)
+		// the blobs the record referred to before are released
+		events := mt.GetAllStartedEvents()
+		release := events[len(events)-1]
+		require.Equal(t, BlobsCollection, release.Command.Lookup("update").StringValue())
+		update := release.Command.Lookup("updates").Array().Index(0).Value().Document()
+		require.True(t, update.Lookup("multi").Boolean())
+		require.Equal(t, id, update.Lookup("q", "record_id").ObjectID())
+		require.Equal(t, blobID, update.Lookup("q", "_id", "$ne").ObjectID())
+		require.False(t, update.Lookup("u", "$set", "released_at").Time().IsZero())
+	})
+	mt.Run("not_found", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+
+		_, err := storageService.Update(
+			context.TODO(),
+			models.TextCollection,
+			"blokhinnv",
+			models.NewRandomObjectID(),
+			"test message",
+			make(models.Metadata),
+			0,
+		)
+		require.ErrorIs(t, err, errors.ErrRecordNotFound)
+	})
+	mt.Run("conflict", func(mt *mtest.T) {
+		secretKey := "my-secret-key"
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
+		id := models.NewRandomObjectID()
+		data, err := encrypt.EncryptString("their message", secretKey)
+		require.NoError(t, err)
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+			mtest.CreateCursorResponse(0, "update.conflict", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: id},
+				{Key: "data", Value: data},
+				{Key: "version", Value: 5},
+			}),
+		)
+
+		current, err := storageService.Update(
+			context.TODO(),
+			models.TextCollection,
+			"blokhinnv",
+			id,
+			"my message",
+			make(models.Metadata),
+			4,
+		)
+		require.ErrorIs(t, err, errors.ErrVersionConflict)
+		require.Equal(t, int64(5), current.Version)
+		require.Equal(t, "their message", current.Data)
+	})
+	mt.Run("conflict_not_found", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+			mtest.CreateCursorResponse(0, "update.conflict_not_found", mtest.FirstBatch),
+		)
+
+		_, err := storageService.Update(
+			context.TODO(),
+			models.TextCollection,
+			"blokhinnv",
+			models.NewRandomObjectID(),
+			"my message",
+			make(models.Metadata),
+			4,
+		)
+		require.ErrorIs(t, err, errors.ErrRecordNotFound)
+	})
+	mt.Run("archive_error", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: bson.D{{Key: "_id", Value: models.NewRandomObjectID()}}},
+			},
+			bson.D{{Key: "ok", Value: 0}},
+		)
+
+		_, err := storageService.Update(
+			context.TODO(),
+			models.TextCollection,
+			"blokhinnv",
+			models.NewRandomObjectID(),
+			"test message",
+			make(models.Metadata),
+			0,
+		)
+		require.Error(t, err)
+	})
 	mt.Run("error", func(mt *mtest.T) {
-		storageService := NewStorageService(mt.DB, "my-secret-key")
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
 		mt.AddMockResponses(bson.D{
 			{Key: "ok", Value: 0},
 		})
 
-		err := storageService.Update(
+		_, err := storageService.Update(
 			context.TODO(),
 			models.CredentialsCollection,
 			"blokhinnv",
 			models.NewRandomObjectID(),
 			map[string]any{"login": "blokhinnv", "password": "some-pwd"},
 			make(models.Metadata),
+			0,
 		)
 		require.Error(t, err)
 	})
@@ -189,20 +621,33 @@ func (suite *StorageServiceTestSuite) TestDelete() {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 	defer mt.Close()
 	mt.Run("success", func(mt *mtest.T) {
-		storageService := NewStorageService(mt.DB, "my-secret-key")
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		id := models.NewRandomObjectID()
 		mt.AddMockResponses(
-			bson.D{{Key: "ok", Value: 1}, {Key: "acknowledged", Value: true}, {Key: "n", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: bson.D{{Key: "_id", Value: id}}}},
+			mtest.CreateSuccessResponse(),
 		)
 		err := storageService.Delete(
 			context.TODO(),
 			models.TextCollection,
 			"blokhinnv",
-			models.NewRandomObjectID(),
+			id,
 		)
 		require.NoError(t, err)
 	})
+	mt.Run("not_found", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+		err := storageService.Delete(
+			context.TODO(),
+			models.TextCollection,
+			"blokhinnv",
+			models.NewRandomObjectID(),
+		)
+		require.ErrorIs(t, err, errors.ErrRecordNotFound)
+	})
 	mt.Run("error", func(mt *mtest.T) {
-		storageService := NewStorageService(mt.DB, "my-secret-key")
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
 		mt.AddMockResponses(
 			bson.D{{Key: "ok", Value: 0}},
 		)
@@ -217,6 +662,433 @@ func (suite *StorageServiceTestSuite) TestDelete() {
 
 }
 
+func (suite *StorageServiceTestSuite) TestTrash() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		secretKey := "my-secret-key"
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
+		data, err := encrypt.EncryptString("deleted text", secretKey)
+		require.NoError(t, err)
+		deletedAt := time.Now().UTC().Truncate(time.Millisecond)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "trash.success", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: models.NewRandomObjectID()},
+			{Key: "data", Value: data},
+			{Key: "deleted_at", Value: deletedAt},
+		}))
+
+		records, err := storageService.Trash(context.TODO(), models.TextCollection, "blokhinnv")
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "deleted text", records[0].Data)
+		require.Equal(t, deletedAt, *records[0].DeletedAt)
+	})
+}
+
+func (suite *StorageServiceTestSuite) TestUndelete() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		secretKey := "my-secret-key"
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
+		id := models.NewRandomObjectID()
+		data, err := encrypt.EncryptString("deleted text", secretKey)
+		require.NoError(t, err)
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "value", Value: bson.D{
+				{Key: "_id", Value: id},
+				{Key: "data", Value: data},
+				{Key: "version", Value: 3},
+			}},
+		})
+
+		res, err := storageService.Undelete(context.TODO(), models.TextCollection, "blokhinnv", id)
+		require.NoError(t, err)
+		require.Equal(t, "deleted text", res.Data)
+		require.Equal(t, int64(3), res.Version)
+	})
+	mt.Run("not_found", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}})
+
+		_, err := storageService.Undelete(
+			context.TODO(),
+			models.TextCollection,
+			"blokhinnv",
+			models.NewRandomObjectID(),
+		)
+		require.ErrorIs(t, err, errors.ErrRecordNotFound)
+	})
+}
+
+func (suite *StorageServiceTestSuite) TestPurge() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}},
+		)
+
+		id := models.NewRandomObjectID()
+		err := storageService.Purge(context.TODO(), models.TextCollection, "blokhinnv", id)
+		require.NoError(t, err)
+		// the history of the record is deleted after the record is purged
+		require.Equal(t, "update", mt.GetStartedEvent().CommandName)
+		started := mt.GetStartedEvent()
+		require.Equal(t, "delete", started.CommandName)
+		require.Equal(t, "text_history", started.Command.Lookup("delete").StringValue())
+		filter := started.Command.Lookup("deletes").Array().Index(0).Value().Document().Lookup("q")
+		require.Equal(t, id, filter.Document().Lookup("record_id").ObjectID())
+	})
+	mt.Run("blobs", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		current, revision := models.NewRandomObjectID(), models.NewRandomObjectID()
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{current, revision}}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 4}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+		)
+
+		id := models.NewRandomObjectID()
+		err := storageService.Purge(context.TODO(), models.BinaryCollection, "blokhinnv", id)
+		require.NoError(t, err)
+		var deleted []string
+		for _, e := range mt.GetAllStartedEvents() {
+			if e.CommandName == "distinct" {
+				// the blobs claimed by the record, which nothing else refers to
+				query := e.Command.Lookup("query").Document()
+				require.Equal(t, id, query.Lookup("record_id").ObjectID())
+				require.Equal(t, "blokhinnv", query.Lookup("username").StringValue())
+			}
+			if e.CommandName == "delete" {
+				deleted = append(deleted, e.Command.Lookup("delete").StringValue())
+			}
+		}
+		require.Equal(t, []string{BlobChunksCollection, BlobsCollection, "binary_history"}, deleted)
+	})
+	mt.Run("history_error", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
+			bson.D{{Key: "ok", Value: 0}},
+		)
+
+		err := storageService.Purge(
+			context.TODO(),
+			models.TextCollection,
+			"blokhinnv",
+			models.NewRandomObjectID(),
+		)
+		require.Error(t, err)
+	})
+	mt.Run("not_found", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}},
+		)
+
+		err := storageService.Purge(
+			context.TODO(),
+			models.TextCollection,
+			"blokhinnv",
+			models.NewRandomObjectID(),
+		)
+		require.ErrorIs(t, err, errors.ErrRecordNotFound)
+	})
+	mt.Run("expired", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		ids := bson.A{models.NewRandomObjectID(), models.NewRandomObjectID()}
+		last := len(models.AllowedCollectionNames) - 1
+		for _, collectionName := range models.AllowedCollectionNames[:last] {
+			mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: ids}})
+			if collectionName == models.BinaryCollection {
+				// the records have claimed no blobs
+				mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{}}})
+			}
+			mt.AddMockResponses(
+				bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 3}},
+				bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}},
+			)
+		}
+		// nothing has expired in the last collection
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{}}})
+
+		purged, err := storageService.PurgeTrash(context.TODO(), time.Now())
+		require.NoError(t, err)
+		require.Equal(t, int64(2*(len(models.AllowedCollectionNames)-1)), purged)
+		// the history of the expired records is deleted before the records
+		var deleted []string
+		for _, e := range mt.GetAllStartedEvents() {
+			if e.CommandName == "delete" {
+				deleted = append(deleted, e.Command.Lookup("delete").StringValue())
+			}
+		}
+		require.Len(t, deleted, 2*(len(models.AllowedCollectionNames)-1))
+		require.Equal(t, []string{"text_history", "text"}, deleted[:2])
+	})
+	mt.Run("expired_error", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "values", Value: bson.A{models.NewRandomObjectID()}}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			bson.D{{Key: "ok", Value: 0}},
+		)
+
+		purged, err := storageService.PurgeTrash(context.TODO(), time.Now())
+		require.Error(t, err)
+		require.Equal(t, int64(1), purged)
+	})
+}
+
+func (suite *StorageServiceTestSuite) TestHistory() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		secretKey := "my-secret-key"
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
+		id := models.NewRandomObjectID()
+		second, err := encrypt.EncryptString("second", secretKey)
+		require.NoError(t, err)
+		first, err := encrypt.EncryptString("first", secretKey)
+		require.NoError(t, err)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "history.success", mtest.FirstBatch,
+			bson.D{
+				{Key: "record_id", Value: id},
+				{Key: "data", Value: second},
+				{Key: "rev", Value: 2},
+				{Key: "op", Value: models.OpDelete},
+			},
+			bson.D{
+				{Key: "record_id", Value: id},
+				{Key: "data", Value: first},
+				{Key: "rev", Value: 1},
+				{Key: "op", Value: models.OpUpdate},
+			},
+		))
+
+		revisions, err := storageService.History(context.TODO(), models.TextCollection, "blokhinnv", id)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		require.Equal(t, "second", revisions[0].Data)
+		require.Equal(t, models.OpDelete, revisions[0].Op)
+		require.Equal(t, int64(1), revisions[1].Rev)
+	})
+	mt.Run("error", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+
+		_, err := storageService.History(
+			context.TODO(),
+			models.TextCollection,
+			"blokhinnv",
+			models.NewRandomObjectID(),
+		)
+		require.Error(t, err)
+	})
+}
+
+func (suite *StorageServiceTestSuite) TestRestore() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	secretKey := "my-secret-key"
+	id := models.NewRandomObjectID()
+	history := func() bson.D {
+		second, err := encrypt.EncryptString("second", secretKey)
+		require.NoError(t, err)
+		first, err := encrypt.EncryptString("first", secretKey)
+		require.NoError(t, err)
+		return mtest.CreateCursorResponse(0, "restore.history", mtest.FirstBatch,
+			bson.D{{Key: "record_id", Value: id}, {Key: "data", Value: second}, {Key: "rev", Value: 2}},
+			bson.D{{Key: "record_id", Value: id}, {Key: "data", Value: first}, {Key: "rev", Value: 1}},
+		)
+	}
+	mt.Run("existing", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
+		mt.AddMockResponses(
+			history(),
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: bson.D{{Key: "_id", Value: id}, {Key: "version", Value: 3}}},
+			},
+			mtest.CreateSuccessResponse(),
+		)
+
+		res, err := storageService.Restore(context.TODO(), models.TextCollection, "blokhinnv", id, 1)
+		require.NoError(t, err)
+		require.Equal(t, "first", res.Data)
+		require.Equal(t, int64(4), res.Version)
+	})
+	mt.Run("trashed", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
+		mt.AddMockResponses(
+			history(),
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+			bson.D{
+				{Key: "ok", Value: 1},
+				{Key: "value", Value: bson.D{{Key: "_id", Value: id}, {Key: "version", Value: 4}}},
+			},
+		)
+
+		res, err := storageService.Restore(context.TODO(), models.TextCollection, "blokhinnv", id, 2)
+		require.NoError(t, err)
+		require.Equal(t, "second", res.Data)
+		require.Equal(t, int64(4), res.Version)
+	})
+	mt.Run("purged", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
+		mt.AddMockResponses(
+			history(),
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+			mtest.CreateSuccessResponse(),
+		)
+
+		res, err := storageService.Restore(context.TODO(), models.TextCollection, "blokhinnv", id, 2)
+		require.NoError(t, err)
+		require.Equal(t, "second", res.Data)
+		require.Equal(t, id, res.RecordID)
+		require.Equal(t, int64(3), res.Version)
+	})
+	mt.Run("revision_not_found", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
+		mt.AddMockResponses(history())
+
+		_, err := storageService.Restore(context.TODO(), models.TextCollection, "blokhinnv", id, 7)
+		require.ErrorIs(t, err, errors.ErrRevisionNotFound)
+	})
+}
+
+func (suite *StorageServiceTestSuite) TestEnsureIndexes() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("retention", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		for range models.AllowedCollectionNames {
+			mt.AddMockResponses(
+				mtest.CreateSuccessResponse(),
+				mtest.CreateSuccessResponse(),
+				mtest.CreateSuccessResponse(),
+			)
+		}
+		require.NoError(t, storageService.EnsureIndexes(context.TODO(), time.Hour))
+	})
+	mt.Run("changed_retention", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		for range models.AllowedCollectionNames {
+			mt.AddMockResponses(
+				mtest.CreateSuccessResponse(),
+				mtest.CreateSuccessResponse(),
+				mtest.CreateCommandErrorResponse(mtest.CommandError{
+					Code:    85,
+					Name:    "IndexOptionsConflict",
+					Message: "an equivalent index already exists with different options",
+				}),
+				mtest.CreateSuccessResponse(),
+			)
+		}
+		require.NoError(t, storageService.EnsureIndexes(context.TODO(), 2*time.Hour))
+	})
+	mt.Run("no_retention", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		for range models.AllowedCollectionNames {
+			mt.AddMockResponses(
+				mtest.CreateSuccessResponse(),
+				mtest.CreateSuccessResponse(),
+				mtest.CreateCommandErrorResponse(mtest.CommandError{
+					Code:    27,
+					Name:    "IndexNotFound",
+					Message: "index not found",
+				}),
+			)
+		}
+		require.NoError(t, storageService.EnsureIndexes(context.TODO(), 0))
+	})
+}
+
+func (suite *StorageServiceTestSuite) TestChanges() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		secretKey := "my-secret-key"
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, secretKey), newTestIndex(t))
+		data, err := encrypt.EncryptString("changed text", secretKey)
+		require.NoError(t, err)
+		changed, deleted := models.NewRandomObjectID(), models.NewRandomObjectID()
+		deletedAt := time.Now().UTC().Truncate(time.Millisecond)
+		mt.AddMockResponses(mtest.CreateCursorResponse(
+			0,
+			"changes.text",
+			mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: changed}, {Key: "data", Value: data}},
+			// the purged record has no data
+			bson.D{{Key: "_id", Value: deleted}, {Key: "deleted_at", Value: deletedAt}},
+		))
+		for range models.AllowedCollectionNames[1:] {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "changes.other", mtest.FirstBatch))
+		}
+
+		changes, err := storageService.Changes(context.TODO(), "blokhinnv", time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.False(t, changes.Reset)
+		require.Len(t, changes.Upserts, 1)
+		require.Equal(t, models.AllowedCollectionNames[0], changes.Upserts[0].Collection)
+		require.Equal(t, changed, changes.Upserts[0].Record.RecordID)
+		require.Equal(t, "changed text", changes.Upserts[0].Record.Data)
+		require.Equal(t, []models.Tombstone{{
+			Collection: models.AllowedCollectionNames[0],
+			RecordID:   deleted,
+			DeletedAt:  &deletedAt,
+		}}, changes.Tombstones)
+	})
+	mt.Run("reset", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		for range models.AllowedCollectionNames {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "changes.any", mtest.FirstBatch))
+		}
+
+		changes, err := storageService.Changes(context.TODO(), "blokhinnv", time.Time{})
+		require.NoError(t, err)
+		require.True(t, changes.Reset)
+		require.Empty(t, changes.Upserts)
+	})
+	mt.Run("error", func(mt *mtest.T) {
+		storageService := NewStorageService(mt.DB, newTestKeyring(t, "my-secret-key"), newTestIndex(t))
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}})
+
+		_, err := storageService.Changes(context.TODO(), "blokhinnv", time.Time{})
+		require.Error(t, err)
+	})
+}
+
 func TestStorageServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(StorageServiceTestSuite))
 }
+
+// newTestKeyring creates a keyring with the only key which also
+// decrypts the values saved without a key id.
+func newTestKeyring(t *testing.T, key string) *encrypt.Keyring {
+	keyring, err := encrypt.NewKeyring(encrypt.DefaultKeyID, map[string]string{
+		encrypt.DefaultKeyID: key,
+	})
+	require.NoError(t, err)
+	return keyring
+}
+
+func newTestIndex(t *testing.T) *encrypt.BlindIndex {
+	index, err := encrypt.NewBlindIndex("my-search-key")
+	require.NoError(t, err)
+	return index
+}
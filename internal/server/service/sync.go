@@ -1,82 +1,192 @@
 package service
 
 import (
-	"net"
 	"sync"
-
-	"golang.org/x/exp/slices"
-
-	"github.com/blokhinnv/gophkeeper/pkg/log"
-	"github.com/go-resty/resty/v2"
+	"time"
 
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
-	slicesUtils "github.com/blokhinnv/gophkeeper/pkg/slices"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
 )
 
-// SyncService is an interface for syncing clients.
+// subscriptionBufferSize is a number of events kept for a subscriber
+// which has not read them yet.
+const subscriptionBufferSize = 64
+
+// SyncService is an interface for pushing the changes to the user's clients.
 type SyncService interface {
-	Register(client *models.Client)
-	Unregister(client *models.Client)
-	Signal(client *models.Client)
+	// Subscribe returns a channel of the change events of the user's device and
+	// a function to cancel the subscription. The subscription belongs to the session
+	// of the access token and is canceled when the token expires.
+	// The channel is closed when the subscription is canceled.
+	Subscribe(
+		username, deviceID, sessionID string,
+		expiresAt time.Time,
+	) (<-chan models.ChangeEvent, func())
+	// Publish sends the event to all the user's subscriptions.
+	Publish(username string, event models.ChangeEvent)
+	// Disconnect cancels the subscriptions of the user's device.
+	Disconnect(username, deviceID string)
+	// DisconnectSession cancels the subscriptions of the user's session.
+	DisconnectSession(username, sessionID string)
+	// DisconnectUser cancels all the subscriptions of the user.
+	DisconnectUser(username string)
+	// Close cancels all the subscriptions.
+	Close()
+}
+
+// subscription is a channel of events of a single client.
+type subscription struct {
+	deviceID  string
+	sessionID string
+	events    chan models.ChangeEvent
+	expiry    *time.Timer // expiry cancels the subscription when the access token expires.
+	once      sync.Once
+}
+
+// close closes the channel of the subscription only once.
+func (s *subscription) close() {
+	s.once.Do(func() {
+		if s.expiry != nil {
+			s.expiry.Stop()
+		}
+		close(s.events)
+	})
 }
 
 // syncService implements the SyncService interface.
 type syncService struct {
-	client  *resty.Client
-	clients map[string][]string // Addr: Username
-	mu      sync.Mutex
+	subscriptions map[string]map[*subscription]struct{} // Username: subscriptions
+	versions      map[string]int64                      // Username: version of the last change
+	mu            sync.Mutex
 }
 
 // NewSyncService creates a new SyncService instance.
 func NewSyncService() SyncService {
-	s := &syncService{
-		client:  resty.New(),
-		clients: make(map[string][]string),
+	return &syncService{
+		subscriptions: make(map[string]map[*subscription]struct{}),
+		versions:      make(map[string]int64),
 	}
-	return s
 }
 
-// Register registers a new client with the server.
-func (s *syncService) Register(client *models.Client) {
+// Subscribe returns a channel of the change events of the user's device and
+// a function to cancel the subscription. The device id is empty for the clients
+// which don't register the devices, the session id is empty for the tokens
+// issued without a session. The subscription is canceled at expiresAt,
+// so the stream doesn't outlive the access token; zero time never expires.
+func (s *syncService) Subscribe(
+	username, deviceID, sessionID string,
+	expiresAt time.Time,
+) (<-chan models.ChangeEvent, func()) {
+	sub := &subscription{
+		deviceID:  deviceID,
+		sessionID: sessionID,
+		events:    make(chan models.ChangeEvent, subscriptionBufferSize),
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	addrs := s.clients[client.Username]
-	if !slices.Contains(addrs, client.SocketAddr) {
-		s.clients[client.Username] = append(addrs, client.SocketAddr)
+	if s.subscriptions[username] == nil {
+		s.subscriptions[username] = make(map[*subscription]struct{})
+	}
+	s.subscriptions[username][sub] = struct{}{}
+	if !expiresAt.IsZero() {
+		// the timer takes the lock, so it can't fire before it is set
+		sub.expiry = time.AfterFunc(time.Until(expiresAt), func() { s.unsubscribe(username, sub) })
 	}
-	log.Infof("Registered %v %v", client.Username, client.SocketAddr)
+	log.Infof("Subscribed %v", username)
+	return sub.events, func() { s.unsubscribe(username, sub) }
 }
 
-// Unregister unregisters an existing client from the server.
-func (s *syncService) Unregister(client *models.Client) {
+// unsubscribe removes the subscription and closes its channel.
+func (s *syncService) unsubscribe(username string, sub *subscription) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	oldAddrs, ok := s.clients[client.Username]
-	if !ok {
+	if _, ok := s.subscriptions[username][sub]; !ok {
 		return
 	}
-	s.clients[client.Username] = slicesUtils.Remove(oldAddrs, client.SocketAddr)
-	log.Infof("Unregistered %v %v", client.Username, client.SocketAddr)
+	delete(s.subscriptions[username], sub)
+	if len(s.subscriptions[username]) == 0 {
+		delete(s.subscriptions, username)
+	}
+	sub.close()
+	log.Infof("Unsubscribed %v", username)
 }
 
-// Signal sends a signal to all the user's registered clients.
-func (s *syncService) Signal(client *models.Client) {
-	for _, addr := range s.clients[client.Username] {
-		conn, err := net.Dial("tcp", addr)
-		if err != nil {
-			s.clients[client.Username] = slicesUtils.Remove(s.clients[client.Username], addr)
-			log.Infof("unable to reach %v; unregistered", addr)
-			continue
+// Publish sends the event to all the user's subscriptions. The version of the event
+// is set by the service. A subscriber which does not keep up with the events
+// is unsubscribed, so it has to reconnect and sync all the data.
+func (s *syncService) Publish(username string, event models.ChangeEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.versions[username]++
+	event.Version = s.versions[username]
+	for sub := range s.subscriptions[username] {
+		select {
+		case sub.events <- event:
+		default:
+			delete(s.subscriptions[username], sub)
+			sub.close()
+			log.Infof("subscriber of %v is too slow; unsubscribed", username)
 		}
-		_, err = conn.Write(nil)
-		if err != nil {
-			s.clients[client.Username] = slicesUtils.Remove(s.clients[client.Username], addr)
-			log.Infof("unable to reach %v; unregistered", addr)
+	}
+}
+
+// Disconnect cancels the subscriptions of the user's device, so the clients
+// of the deleted device stop getting the events.
+func (s *syncService) Disconnect(username, deviceID string) {
+	n := s.disconnect(username, func(sub *subscription) bool { return sub.deviceID == deviceID })
+	if n > 0 {
+		log.Infof("Disconnected device %v of %v", deviceID, username)
+	}
+}
+
+// DisconnectSession cancels the subscriptions of the user's session, so the
+// clients of the revoked session stop getting the events.
+func (s *syncService) DisconnectSession(username, sessionID string) {
+	if sessionID == "" {
+		return
+	}
+	n := s.disconnect(username, func(sub *subscription) bool { return sub.sessionID == sessionID })
+	if n > 0 {
+		log.Infof("Disconnected session %v of %v", sessionID, username)
+	}
+}
+
+// DisconnectUser cancels all the subscriptions of the user, e.g. when all
+// the sessions of the user are finished or the user is deleted.
+func (s *syncService) DisconnectUser(username string) {
+	if n := s.disconnect(username, func(*subscription) bool { return true }); n > 0 {
+		log.Infof("Disconnected %v", username)
+	}
+}
+
+// disconnect cancels the subscriptions of the user matching the function
+// and returns their number.
+func (s *syncService) disconnect(username string, match func(*subscription) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for sub := range s.subscriptions[username] {
+		if !match(sub) {
+			continue
 		}
+		delete(s.subscriptions[username], sub)
+		sub.close()
+		n++
+	}
+	if len(s.subscriptions[username]) == 0 {
+		delete(s.subscriptions, username)
 	}
+	return n
 }
 
-// getClientAddresses returns addresses for the username provided.
-func (m *syncService) getClientAddresses(username string) []string {
-	return m.clients[username]
+// Close cancels all the subscriptions.
+func (s *syncService) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for username, subs := range s.subscriptions {
+		for sub := range subs {
+			sub.close()
+		}
+		delete(s.subscriptions, username)
+	}
 }
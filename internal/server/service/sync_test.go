@@ -1,11 +1,10 @@
 package service
 
 import (
-	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 
 	"github.com/blokhinnv/gophkeeper/internal/server/models"
 )
@@ -15,135 +14,125 @@ func TestNewSyncService(t *testing.T) {
 	assert.NotNil(t, s, "NewSyncService should return a non-nil pointer.")
 }
 
-func TestSyncService_Register(t *testing.T) {
-	// Create a mock sync service instance.
-	m := syncService{
-		clients: make(map[string][]string),
-	}
-
-	// Test case 1: Registering a new client should add their address to the clients map.
-	client := &models.Client{
-		Username:   "testuser",
-		SocketAddr: "127.0.0.1:1234",
-	}
-	m.Register(client)
-	assert.Contains(
-		t,
-		m.clients["testuser"],
-		"127.0.0.1:1234",
-		"Register should add a new client address to the clients map.",
-	)
-
-	// Test case 2: Registering a client with an existing username should add their address to the existing slice.
-	client2 := &models.Client{
-		Username:   "testuser",
-		SocketAddr: "127.0.0.1:5678",
-	}
-	m.Register(client2)
-	assert.Equal(
-		t,
-		[]string{"127.0.0.1:1234", "127.0.0.1:5678"},
-		m.getClientAddresses("testuser"),
-		"Register should add a new client address to the existing slice in the clients map.",
+func TestSyncService_Publish(t *testing.T) {
+	s := NewSyncService()
+	first, unsubscribeFirst := s.Subscribe("testuser", "", "", time.Time{})
+	second, unsubscribeSecond := s.Subscribe("testuser", "", "", time.Time{})
+	defer unsubscribeSecond()
+	other, unsubscribeOther := s.Subscribe("otheruser", "", "", time.Time{})
+	defer unsubscribeOther()
+	id := models.NewRandomObjectID()
+
+	// Test case 1: the event should be delivered to all the subscriptions of the user
+	// with the version assigned.
+	s.Publish(
+		"testuser",
+		models.ChangeEvent{Collection: models.TextCollection, RecordID: id, Op: models.OpCreate},
 	)
-
-	// Test case 3: Registering a client with an existing username and address should not add a new entry to the clients map.
-	client3 := &models.Client{
-		Username:   "testuser",
-		SocketAddr: "127.0.0.1:5678",
+	want := models.ChangeEvent{
+		Collection: models.TextCollection,
+		RecordID:   id,
+		Op:         models.OpCreate,
+		Version:    1,
 	}
-	m.Register(client3)
-	assert.Equal(
-		t,
-		[]string{"127.0.0.1:1234", "127.0.0.1:5678"},
-		m.getClientAddresses("testuser"),
-		"Register should not add a new entry to the clients map if the username and address already exist.",
+	assert.Equal(t, want, <-first)
+	assert.Equal(t, want, <-second)
+	assert.Empty(t, other, "Publish should not send events to other users.")
+
+	// Test case 2: the versions should grow and a canceled subscription should be closed.
+	unsubscribeFirst()
+	unsubscribeFirst()
+	_, ok := <-first
+	assert.False(t, ok, "the channel should be closed after unsubscribe")
+	s.Publish(
+		"testuser",
+		models.ChangeEvent{Collection: models.TextCollection, RecordID: id, Op: models.OpDelete},
 	)
+	assert.Equal(t, int64(2), (<-second).Version)
 }
 
-func TestSyncService_Unregister(t *testing.T) {
-	// Create a mock sync service instance with a test client.
-	m := &syncService{
-		clients: map[string][]string{
-			"testuser": {"127.0.0.1:1234", "127.0.0.1:5678"},
-		},
-	}
+func TestSyncService_SlowSubscriber(t *testing.T) {
+	s := NewSyncService()
+	events, unsubscribe := s.Subscribe("testuser", "", "", time.Time{})
+	defer unsubscribe()
 
-	// Test case 1: Unregistering an existing client should remove their address from the clients map.
-	client := &models.Client{
-		Username:   "testuser",
-		SocketAddr: "127.0.0.1:1234",
+	// The subscriber which does not read the events should be unsubscribed.
+	for i := 0; i <= subscriptionBufferSize; i++ {
+		s.Publish("testuser", models.ChangeEvent{Op: models.OpUpdate})
 	}
-	m.Unregister(client)
-	assert.NotContains(
-		t,
-		m.clients["testuser"],
-		"127.0.0.1:1234",
-		"Unregister should remove an existing client address from the clients map.",
-	)
-
-	// Test case 2: Unregistering a client with an unknown username should not modify the clients map.
-	client2 := &models.Client{
-		Username:   "unknownuser",
-		SocketAddr: "127.0.0.1:5678",
+	n := 0
+	for range events {
+		n++
 	}
-	m.Unregister(client2)
-	assert.Equal(
-		t,
-		[]string{"127.0.0.1:5678"},
-		m.getClientAddresses("testuser"),
-		"Unregister should not modify the clients map if the username is unknown.",
-	)
+	assert.Equal(t, subscriptionBufferSize, n)
+}
 
-	// Test case 3: Unregistering a client with an unknown address for a known username should not modify the clients map.
-	client3 := &models.Client{
-		Username:   "testuser",
-		SocketAddr: "127.0.0.1:9999",
-	}
-	m.Unregister(client3)
-	assert.Equal(
-		t,
-		[]string{"127.0.0.1:5678"},
-		m.getClientAddresses("testuser"),
-		"Unregister should not modify the clients map if the address is unknown for a known username.",
-	)
+func TestSyncService_Close(t *testing.T) {
+	s := NewSyncService()
+	events, unsubscribe := s.Subscribe("testuser", "", "", time.Time{})
+	s.Close()
+	_, ok := <-events
+	assert.False(t, ok, "Close should close all the subscriptions")
+	unsubscribe()
 }
 
-func TestSyncService_Signal(t *testing.T) {
-	// Create a mock sync service instance with a test client.
-	m := &syncService{
-		clients: map[string][]string{
-			"testuser": {"127.0.0.1:1234", "127.0.0.1:5678"},
-		},
-	}
+func TestSyncService_Disconnect(t *testing.T) {
+	s := NewSyncService()
+	laptop, unsubscribeLaptop := s.Subscribe("testuser", "laptop", "", time.Time{})
+	defer unsubscribeLaptop()
+	phone, unsubscribePhone := s.Subscribe("testuser", "phone", "", time.Time{})
+	defer unsubscribePhone()
+	other, unsubscribeOther := s.Subscribe("otheruser", "laptop", "", time.Time{})
+	defer unsubscribeOther()
+
+	s.Disconnect("testuser", "laptop")
+	_, ok := <-laptop
+	assert.False(t, ok, "Disconnect should close the subscriptions of the device")
+	s.Publish("testuser", models.ChangeEvent{Op: models.OpUpdate})
+	assert.Equal(t, int64(1), (<-phone).Version)
+	s.Publish("otheruser", models.ChangeEvent{Op: models.OpUpdate})
+	assert.Equal(t, int64(1), (<-other).Version, "Disconnect should not touch other users")
+}
+
+func TestSyncService_DisconnectSession(t *testing.T) {
+	s := NewSyncService()
+	revoked, unsubscribeRevoked := s.Subscribe("testuser", "laptop", "first", time.Time{})
+	defer unsubscribeRevoked()
+	active, unsubscribeActive := s.Subscribe("testuser", "laptop", "second", time.Time{})
+	defer unsubscribeActive()
+	withoutSession, unsubscribeWithoutSession := s.Subscribe("testuser", "laptop", "", time.Time{})
+	defer unsubscribeWithoutSession()
+
+	s.DisconnectSession("testuser", "first")
+	s.DisconnectSession("testuser", "")
+	_, ok := <-revoked
+	assert.False(t, ok, "DisconnectSession should close the subscriptions of the session")
+	s.Publish("testuser", models.ChangeEvent{Op: models.OpUpdate})
+	assert.Equal(t, int64(1), (<-active).Version)
+	assert.Equal(t, int64(1), (<-withoutSession).Version)
+
+	s.DisconnectUser("testuser")
+	_, ok = <-active
+	assert.False(t, ok, "DisconnectUser should close all the subscriptions of the user")
+	_, ok = <-withoutSession
+	assert.False(t, ok, "DisconnectUser should close all the subscriptions of the user")
+}
 
-	// Test case 1: Sending a signal to all registered clients should successfully write to the connection.
-	client := &models.Client{
-		Username:   "testuser",
-		SocketAddr: "127.0.0.1:1234",
+func TestSyncService_Expiry(t *testing.T) {
+	s := NewSyncService()
+	expired, unsubscribeExpired := s.Subscribe("testuser", "", "", time.Now().Add(-time.Second))
+	defer unsubscribeExpired()
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	expiring, unsubscribeExpiring := s.Subscribe("testuser", "", "", expiresAt)
+	defer unsubscribeExpiring()
+
+	// the subscriptions are closed when the access token expires
+	_, ok := <-expired
+	assert.False(t, ok, "the subscription of the expired token should be closed")
+	select {
+	case _, ok = <-expiring:
+		assert.False(t, ok, "the subscription should be closed when the token expires")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subscription is not closed after the token expired")
 	}
-	m.Register(client)
-	// Create a mock TCP server that listens on the test client's socket address
-	listener, err := net.Listen("tcp", client.SocketAddr)
-	require.NoError(t, err)
-	go func() {
-		for {
-			// Accept incoming connections
-			conn, err := listener.Accept()
-			if err != nil {
-				return
-			}
-
-			// Close the connection immediately
-			conn.Close()
-		}
-	}()
-	// Send a signal to the test client
-	m.Signal(client)
-
-	// Verify that the test client's socket address is still registered
-	assert.Equal(t, []string{client.SocketAddr}, m.getClientAddresses(client.Username))
-
-	// Close the mock TCP server
-	require.NoError(t, listener.Close())
 }
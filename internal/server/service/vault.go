@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	srvErrors "github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// VaultService is an interface that defines the methods to keep the parameters
+// of the end-to-end encryption of the users.
+type VaultService interface {
+	// Get returns the vault parameters of the user.
+	Get(ctx context.Context, username string) (*models.VaultParams, error)
+	// Set saves the vault parameters of the user. The parameters can be set only once
+	// since the records encrypted with the old key would become unreadable.
+	Set(ctx context.Context, username string, params models.VaultParams) error
+}
+
+// vaultService is an implementation of the VaultService interface
+// which keeps the parameters in the users collection.
+type vaultService struct {
+	collection *mongo.Collection
+}
+
+// NewVaultService creates a new instance of the VaultService.
+func NewVaultService(collection *mongo.Collection) VaultService {
+	return &vaultService{collection: collection}
+}
+
+// Get returns the vault parameters of the user.
+// Returns ErrVaultNotFound if the user has not set them yet.
+func (t *vaultService) Get(ctx context.Context, username string) (*models.VaultParams, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	var user models.User
+	err := t.collection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, srvErrors.ErrVaultNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if user.Vault == nil {
+		return nil, srvErrors.ErrVaultNotFound
+	}
+	return user.Vault, nil
+}
+
+// Set saves the vault parameters of the user.
+// Returns ErrVaultExists if the parameters are already set.
+func (t *vaultService) Set(ctx context.Context, username string, params models.VaultParams) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	filter := bson.M{"username": username, "vault": bson.M{"$exists": false}}
+	upd := bson.M{"$set": bson.M{"vault": params}}
+	res, err := t.collection.UpdateOne(ctx, filter, upd)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return srvErrors.ErrVaultExists
+	}
+	return nil
+}
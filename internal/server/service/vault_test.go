@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/errors"
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+type VaultServiceTestSuite struct {
+	suite.Suite
+}
+
+func (suite *VaultServiceTestSuite) SetupSuite()    {}
+func (suite *VaultServiceTestSuite) TearDownSuite() {}
+
+// testVaultParams returns valid vault parameters.
+func testVaultParams() models.VaultParams {
+	return models.VaultParams{
+		KDF:      models.KDFArgon2id,
+		Salt:     []byte("0123456789abcdef"),
+		Time:     3,
+		Memory:   64 * 1024,
+		Threads:  4,
+		KeyCheck: []byte("key check"),
+	}
+}
+
+func (suite *VaultServiceTestSuite) TestGet() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		vaultService := NewVaultService(mt.Coll)
+		params := testVaultParams()
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "vault.success", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: models.NewRandomObjectID()},
+			{Key: "username", Value: "testuser"},
+			{Key: "vault", Value: params},
+		}))
+		res, err := vaultService.Get(context.TODO(), "testuser")
+		require.NoError(t, err)
+		assert.Equal(t, &params, res)
+	})
+	mt.Run("not_set", func(mt *mtest.T) {
+		vaultService := NewVaultService(mt.Coll)
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, "vault.not_set", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: models.NewRandomObjectID()},
+			{Key: "username", Value: "testuser"},
+		}))
+		_, err := vaultService.Get(context.TODO(), "testuser")
+		require.ErrorIs(t, err, errors.ErrVaultNotFound)
+	})
+	mt.Run("no_user", func(mt *mtest.T) {
+		vaultService := NewVaultService(mt.Coll)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "vault.no_user", mtest.FirstBatch))
+		_, err := vaultService.Get(context.TODO(), "testuser")
+		require.ErrorIs(t, err, errors.ErrVaultNotFound)
+	})
+}
+
+func (suite *VaultServiceTestSuite) TestSet() {
+	t := suite.T()
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	mt.Run("success", func(mt *mtest.T) {
+		vaultService := NewVaultService(mt.Coll)
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "n", Value: 1},
+			{Key: "nModified", Value: 1},
+		})
+		err := vaultService.Set(context.TODO(), "testuser", testVaultParams())
+		require.NoError(t, err)
+	})
+	mt.Run("exists", func(mt *mtest.T) {
+		vaultService := NewVaultService(mt.Coll)
+		mt.AddMockResponses(bson.D{
+			{Key: "ok", Value: 1},
+			{Key: "n", Value: 0},
+			{Key: "nModified", Value: 0},
+		})
+		err := vaultService.Set(context.TODO(), "testuser", testVaultParams())
+		require.ErrorIs(t, err, errors.ErrVaultExists)
+	})
+}
+
+func TestVaultServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(VaultServiceTestSuite))
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/config"
+	"github.com/blokhinnv/gophkeeper/internal/server/service"
+	"github.com/blokhinnv/gophkeeper/pkg/log"
+)
+
+// lockoutPolicy returns the policy of the login lockout set by the config.
+func lockoutPolicy(cfg *config.ServerConfig) service.LockoutPolicy {
+	return service.LockoutPolicy{
+		UserFreeAttempts: cfg.LoginFreeAttempts,
+		IPFreeAttempts:   cfg.LoginIPFreeAttempts,
+		Backoff:          cfg.LoginBackoff,
+		MaxLockout:       cfg.LoginMaxLockout,
+		Window:           cfg.LoginAttemptsWindow,
+		EventsRetention:  cfg.SecurityEventsRetention,
+	}
+}
+
+// RunUnlock removes the lockout of the logins of the username and from the address,
+// any of which may be empty.
+func RunUnlock(cfg *config.ServerConfig, username, ip string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	lockoutService := service.NewLockoutService(
+		service.NewMongoAttemptStore(client.Database(cfg.DBName)),
+		lockoutPolicy(cfg),
+	)
+	unlocked, err := lockoutService.Unlock(ctx, username, ip)
+	if err != nil {
+		return err
+	}
+	if unlocked {
+		log.Println("The logins are unlocked")
+	} else {
+		log.Println("There were no recent failed logins, nothing is unlocked")
+	}
+	return nil
+}
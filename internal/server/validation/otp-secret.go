@@ -0,0 +1,20 @@
+package validation
+
+import (
+	"github.com/go-playground/validator/v10"
+
+	"github.com/blokhinnv/gophkeeper/pkg/otp"
+)
+
+// validateOTPSecret is a custom validation function that checks if a string
+// is a valid base32 encoded one-time password secret.
+func validateOTPSecret(fl validator.FieldLevel) bool {
+	_, err := otp.DecodeSecret(fl.Field().String())
+	return err == nil
+}
+
+// init registers the validateOTPSecret function as a custom validator with
+// the Validate instance.
+func init() {
+	Validate.RegisterValidation("otp_secret", validateOTPSecret)
+}
@@ -0,0 +1,55 @@
+package validation
+
+import (
+	"testing"
+
+	// such an import was taken from the validator source...
+	. "github.com/go-playground/assert/v2"
+	"github.com/go-playground/validator/v10"
+)
+
+func TestValidateOTPSecret(t *testing.T) {
+	type Arg struct {
+		Secret string `validate:"otp_secret"`
+	}
+	validate := validator.New()
+	err := validate.RegisterValidation("otp_secret", validateOTPSecret)
+	Equal(t, err, nil)
+
+	tests := []struct {
+		name string
+		arg  Arg
+		want bool
+	}{
+		{
+			name: "valid secret",
+			arg:  Arg{"JBSWY3DPEHPK3PXP"},
+			want: true,
+		},
+		{
+			name: "valid lowercase secret with spaces",
+			arg:  Arg{"jbsw y3dp ehpk 3pxp"},
+			want: true,
+		},
+		{
+			name: "invalid secret",
+			arg:  Arg{"not-a-secret!"},
+			want: false,
+		},
+		{
+			name: "empty secret",
+			arg:  Arg{""},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err = validate.Struct(tt.arg)
+			if tt.want {
+				Equal(t, err, nil)
+			} else {
+				NotEqual(t, err, nil)
+			}
+		})
+	}
+}
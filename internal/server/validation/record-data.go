@@ -0,0 +1,29 @@
+package validation
+
+import (
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+// ValidateRecordData validates the data field of an untyped record
+// based on the collection name.
+func ValidateRecordData(data any, collectionName models.CollectionName) error {
+	var v any
+	switch collectionName {
+	case models.CredentialsCollection:
+		v = &models.CredentialInfo{}
+	case models.CardCollection:
+		v = &models.CardInfo{}
+	case models.BinaryCollection:
+		v = &models.BinaryInfo{}
+	case models.OTPCollection:
+		v = &models.OTPInfo{}
+	default:
+		return nil
+	}
+	if err := mapstructure.Decode(data, v); err != nil {
+		return err
+	}
+	return Validate.Struct(v)
+}
@@ -0,0 +1,73 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/blokhinnv/gophkeeper/internal/server/models"
+)
+
+func TestValidateRecordData(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       any
+		collection models.CollectionName
+		wantErr    bool
+	}{
+		{
+			name:       "ok_credentials",
+			data:       map[string]any{"Login": "user", "Password": "pwd"},
+			collection: models.CredentialsCollection,
+		},
+		{
+			name:       "bad_credentials",
+			data:       map[string]any{"Login": "user"},
+			collection: models.CredentialsCollection,
+			wantErr:    true,
+		},
+		{
+			name:       "ok_binary_inline",
+			data:       map[string]any{"FileName": "a.txt", "Content": "aGVsbG8="},
+			collection: models.BinaryCollection,
+		},
+		{
+			name:       "ok_binary_blob",
+			data:       map[string]any{"FileName": "a.txt", "BlobID": "6457e99ec51d35bd689f2f5b", "Size": "5"},
+			collection: models.BinaryCollection,
+		},
+		{
+			name:       "bad_binary_blob",
+			data:       map[string]any{"FileName": "a.txt", "BlobID": "qwerty"},
+			collection: models.BinaryCollection,
+			wantErr:    true,
+		},
+		{
+			name:       "binary_without_content",
+			data:       map[string]any{"FileName": "a.txt"},
+			collection: models.BinaryCollection,
+			wantErr:    true,
+		},
+		{
+			name:       "not_a_map",
+			data:       "some text",
+			collection: models.CardCollection,
+			wantErr:    true,
+		},
+		{
+			name:       "text",
+			data:       "some text",
+			collection: models.TextCollection,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRecordData(tt.data, tt.collection)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package encrypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// minPrefixLength is the length of the shortest prefix of a term
+// which is indexed, so a term can be found by its beginning.
+const minPrefixLength = 3
+
+// tokenSize is the number of bytes of the HMAC kept in a token.
+const tokenSize = 16
+
+// BlindIndex turns the searchable terms into tokens which can be
+// compared without revealing the terms: a token is an HMAC of the owner
+// and the normalized term, so equal terms of different owners have
+// different tokens and the tokens can't be checked without the key.
+type BlindIndex struct {
+	key []byte
+}
+
+// NewBlindIndex creates a blind index with the secret key.
+func NewBlindIndex(key string) (*BlindIndex, error) {
+	if key == "" {
+		return nil, errors.New("empty blind index key")
+	}
+	return &BlindIndex{key: []byte(key)}, nil
+}
+
+// Terms normalizes the text: it is lowercased and split into words
+// of letters and digits.
+func Terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Prefixes returns the terms and their prefixes which are at least
+// three characters long without duplicates.
+func Prefixes(terms []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(terms))
+	add := func(s string) {
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+	for _, term := range terms {
+		runes := []rune(term)
+		for n := minPrefixLength; n < len(runes); n++ {
+			add(string(runes[:n]))
+		}
+		add(term)
+	}
+	return result
+}
+
+// Token returns the token of the owner's term.
+func (b *BlindIndex) Token(owner, term string) string {
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(owner))
+	mac.Write([]byte{0})
+	mac.Write([]byte(term))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil)[:tokenSize])
+}
+
+// Tokens returns the tokens of the owner's terms.
+func (b *BlindIndex) Tokens(owner string, terms []string) []string {
+	tokens := make([]string, len(terms))
+	for i, term := range terms {
+		tokens[i] = b.Token(owner, term)
+	}
+	return tokens
+}
@@ -0,0 +1,46 @@
+package encrypt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBlindIndex(t *testing.T) {
+	_, err := NewBlindIndex("")
+	assert.Error(t, err)
+	index, err := NewBlindIndex("index-key")
+	require.NoError(t, err)
+	assert.NotNil(t, index)
+}
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"john", "doe", "github", "com"}, Terms("John.Doe@GitHub.com"))
+	assert.Equal(t, []string{"отчёт", "2023", "pdf"}, Terms("Отчёт 2023.pdf"))
+	assert.Empty(t, Terms(" -- "))
+}
+
+func TestPrefixes(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"go", "git", "gith", "githu", "github"},
+		Prefixes([]string{"go", "github", "git"}),
+	)
+	assert.Equal(t, []string{"сче", "счет"}, Prefixes([]string{"счет"}))
+}
+
+func TestBlindIndex_Tokens(t *testing.T) {
+	index, err := NewBlindIndex("index-key")
+	require.NoError(t, err)
+	other, err := NewBlindIndex("other-key")
+	require.NoError(t, err)
+
+	tokens := index.Tokens("user", []string{"github", "github", "gitlab"})
+	require.Len(t, tokens, 3)
+	assert.Equal(t, tokens[0], tokens[1])
+	assert.NotEqual(t, tokens[0], tokens[2])
+	assert.NotContains(t, tokens[0], "github")
+	assert.NotEqual(t, tokens[0], index.Token("another user", "github"))
+	assert.NotEqual(t, tokens[0], other.Token("user", "github"))
+}
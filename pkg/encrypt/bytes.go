@@ -2,62 +2,190 @@
 package encrypt
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 
 	"github.com/xdg-go/pbkdf2"
+	"golang.org/x/crypto/hkdf"
 )
 
-// tokenToAESKey derives an AES key using PBKDF2 with SHA256 as the hash function.
-func tokenToAESKey(key string) []byte {
-	aesKey := pbkdf2.Key([]byte(key), nil, 1000, 16, sha256.New)
-	return aesKey
-}
+// The format of the data encrypted by EncryptBytes:
+//
+//	magic (2) | version (1) | KDF id (1) | salt (16) | nonce (12) | ciphertext with the GCM tag
+//
+// The legacy (v0) blobs, the AES-128-CFB ciphertext prefixed with the IV and
+// no authentication, have no header at all. They are decrypted only by
+// DecryptLegacyBytes: the caller must know the blob is legacy from elsewhere,
+// since a blob without the magic may be a forged one.
+const (
+	// Version is the version of the format written by EncryptBytes.
+	Version byte = 2
+	// KDFArgon2id is the id of Argon2id with the parameters of argon2idParams
+	// run for every blob. It is written by the version 1 of the format.
+	KDFArgon2id byte = 1
+	// KDFHKDF is the id of HKDF-SHA256 which derives the key of every blob from
+	// the master key, derived once from the key with Argon2id.
+	KDFHKDF byte = 2
+
+	magic      = "GK"
+	saltSize   = 16
+	headerSize = len(magic) + 2 + saltSize
+)
+
+// ErrUnknownFormat is returned when the data is not in the format written by EncryptBytes.
+var ErrUnknownFormat = errors.New("unknown ciphertext format")
 
+// argon2idParams are the Argon2id parameters of the master key and of the
+// blobs of the version 1. They are lighter than the ones of NewKDFParams
+// since the key is derived by the server.
+var argon2idParams = KDFParams{Time: 2, Memory: 19 * 1024, Threads: 1}
+
+// masterSalt is the salt of the master key. The key is derived once per key,
+// so the salt of every blob is mixed in by HKDF instead.
+var masterSalt = []byte("gophkeeper/encrypt/master-key")
+
+// hkdfInfo binds the keys derived by HKDF to their purpose.
+var hkdfInfo = []byte("gophkeeper/encrypt/blob-key")
+
+// EncryptBytes encrypts the data with AES-256-GCM. The key of the blob is
+// derived from the given one with a random salt which is stored in the result.
 func EncryptBytes(data []byte, key string) ([]byte, error) {
+	return EncryptBytesWithAD(data, key, nil)
+}
+
+// EncryptBytesWithAD encrypts the data like EncryptBytes and authenticates
+// the additional data as well, so the result is decrypted only in its context.
+func EncryptBytesWithAD(data []byte, key string, additionalData []byte) ([]byte, error) {
 	if key == "" {
 		return nil, fmt.Errorf("empty key")
 	}
 	if len(data) == 0 {
 		return nil, fmt.Errorf("empty data")
 	}
-	aesKey := tokenToAESKey(key)
-	block, err := aes.NewCipher(aesKey)
+	salt, err := newSalt()
 	if err != nil {
 		return nil, err
 	}
-
-	ciphertext := make([]byte, aes.BlockSize+len(data))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+	aesKey, err := blobKey(key, salt)
+	if err != nil {
 		return nil, err
 	}
-	mode := cipher.NewCFBEncrypter(block, iv)
-	mode.XORKeyStream(ciphertext[aes.BlockSize:], data)
-	return ciphertext, nil
+	sealed, err := SealBytesWithAD(data, aesKey, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, headerSize+len(sealed))
+	header = append(header, magic...)
+	header = append(header, Version, KDFHKDF)
+	header = append(header, salt...)
+	return append(header, sealed...), nil
 }
 
+// DecryptBytes decrypts the data encrypted by EncryptBytes.
+// ErrDecryptionFailed is returned if the key is wrong or the data was modified.
+// ErrUnknownFormat is returned for the data without the header, legacy blobs included.
 func DecryptBytes(encryptedData []byte, key string) ([]byte, error) {
+	return DecryptBytesWithAD(encryptedData, key, nil)
+}
+
+// DecryptBytesWithAD decrypts the data encrypted by EncryptBytesWithAD.
+// ErrDecryptionFailed is returned if the additional data differs from the one
+// the data was encrypted with.
+func DecryptBytesWithAD(encryptedData []byte, key string, additionalData []byte) ([]byte, error) {
+	if !bytes.HasPrefix(encryptedData, []byte(magic)) {
+		return nil, ErrUnknownFormat
+	}
+	if len(encryptedData) < headerSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	version, kdf := encryptedData[len(magic)], encryptedData[len(magic)+1]
+	salt := encryptedData[len(magic)+2 : headerSize]
+	var (
+		aesKey []byte
+		err    error
+	)
+	switch {
+	case version == Version && kdf == KDFHKDF:
+		aesKey, err = blobKey(key, salt)
+	case version == 1 && kdf == KDFArgon2id:
+		aesKey, err = argon2idKey(key, salt)
+	case version != 1 && version != Version:
+		return nil, fmt.Errorf("unsupported ciphertext version: %v", version)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function: %v", kdf)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return OpenBytesWithAD(encryptedData[headerSize:], aesKey, additionalData)
+}
+
+// DecryptLegacyBytes decrypts the data in the legacy v0 format. The data is not
+// authenticated, so the result is garbage if the key is wrong or the data was modified.
+func DecryptLegacyBytes(encryptedData []byte, key string) ([]byte, error) {
 	aesKey := tokenToAESKey(key)
 	block, err := aes.NewCipher(aesKey)
 	if err != nil {
 		return nil, err
 	}
 
-	ciphertext := []byte(encryptedData)
-	if len(ciphertext) < aes.BlockSize {
+	if len(encryptedData) < aes.BlockSize {
 		return nil, fmt.Errorf("ciphertext too short")
 	}
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
+	iv := encryptedData[:aes.BlockSize]
+	ciphertext := make([]byte, len(encryptedData)-aes.BlockSize)
 
 	stream := cipher.NewCFBDecrypter(block, iv)
-
-	// XORKeyStream can work in-place if the two arguments are the same.
-	stream.XORKeyStream(ciphertext, ciphertext)
+	stream.XORKeyStream(ciphertext, encryptedData[aes.BlockSize:])
 	return ciphertext, nil
 }
+
+// blobKey derives the AES-256 key of a blob from the master key with HKDF.
+func blobKey(key string, salt []byte) ([]byte, error) {
+	master, err := argon2idKey(key, masterSalt)
+	if err != nil {
+		return nil, err
+	}
+	aesKey := make([]byte, KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, master, salt, hkdfInfo), aesKey); err != nil {
+		return nil, err
+	}
+	return aesKey, nil
+}
+
+// argon2idKey derives the AES-256 key from the key with Argon2id and the salt.
+// The derived keys are cached.
+func argon2idKey(key string, salt []byte) ([]byte, error) {
+	if aesKey, ok := keys.get(key, salt); ok {
+		return aesKey, nil
+	}
+	params := argon2idParams
+	params.Salt = salt
+	aesKey, err := DeriveKey(key, params)
+	if err != nil {
+		return nil, err
+	}
+	keys.put(key, salt, aesKey)
+	return aesKey, nil
+}
+
+// newSalt generates a random salt.
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// tokenToAESKey derives an AES key of legacy blobs using PBKDF2 with SHA256 as the hash function.
+func tokenToAESKey(key string) []byte {
+	aesKey := pbkdf2.Key([]byte(key), nil, 1000, 16, sha256.New)
+	return aesKey
+}
@@ -3,6 +3,9 @@ package encrypt
 import (
 	"bytes"
 	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -85,6 +88,38 @@ func TestDecryptBytes(t *testing.T) {
 			)
 		}
 	})
+	t.Run("incorrect_key_error", func(t *testing.T) {
+		_, err := DecryptBytes(encrypted, "wrong-key")
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+	t.Run("modified", func(t *testing.T) {
+		modified := append([]byte{}, encrypted...)
+		modified[len(modified)-1] ^= 1
+		_, err := DecryptBytes(modified, key)
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+	t.Run("modified_salt", func(t *testing.T) {
+		modified := append([]byte{}, encrypted...)
+		modified[headerSize-1] ^= 1
+		_, err := DecryptBytes(modified, key)
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+	t.Run("unknown_version", func(t *testing.T) {
+		modified := append([]byte{}, encrypted...)
+		modified[len(magic)] = Version + 1
+		_, err := DecryptBytes(modified, key)
+		assert.ErrorContains(t, err, "unsupported ciphertext version")
+	})
+	t.Run("unknown_kdf", func(t *testing.T) {
+		modified := append([]byte{}, encrypted...)
+		modified[len(magic)+1] = KDFHKDF + 1
+		_, err := DecryptBytes(modified, key)
+		assert.ErrorContains(t, err, "unsupported key derivation function")
+	})
+	t.Run("short_header", func(t *testing.T) {
+		_, err := DecryptBytes(encrypted[:headerSize-1], key)
+		assert.Error(t, err)
+	})
 	t.Run("short_cipher", func(t *testing.T) {
 		// test short ciphertext
 		shortCiphertext := make([]byte, aes.BlockSize-1)
@@ -93,4 +128,98 @@ func TestDecryptBytes(t *testing.T) {
 			t.Errorf("expected error with short ciphertext, but got nil")
 		}
 	})
+	t.Run("legacy", func(t *testing.T) {
+		legacy, err := encryptLegacy([]byte(message), key)
+		assert.NoError(t, err)
+		// the missing header doesn't make the blob legacy
+		_, err = DecryptBytes(legacy, key)
+		assert.ErrorIs(t, err, ErrUnknownFormat)
+		decrypted, err := DecryptLegacyBytes(legacy, key)
+		assert.NoError(t, err)
+		assert.Equal(t, message, string(decrypted))
+		// the legacy blob is not modified by decryption
+		again, err := DecryptLegacyBytes(legacy, key)
+		assert.NoError(t, err)
+		assert.Equal(t, message, string(again))
+	})
+	t.Run("legacy_with_magic", func(t *testing.T) {
+		// the IV of a legacy blob may start with the magic
+		legacy, err := encryptLegacy([]byte(message), key)
+		assert.NoError(t, err)
+		copy(legacy, magic)
+		decrypted, err := DecryptLegacyBytes(legacy, key)
+		assert.NoError(t, err)
+		assert.Len(t, decrypted, len(message))
+	})
+	t.Run("version_1", func(t *testing.T) {
+		v1, err := encryptV1([]byte(message), key)
+		assert.NoError(t, err)
+		decrypted, err := DecryptBytes(v1, key)
+		assert.NoError(t, err)
+		assert.Equal(t, message, string(decrypted))
+	})
+	t.Run("additional_data", func(t *testing.T) {
+		bound, err := EncryptBytesWithAD([]byte(message), key, []byte("blob|0"))
+		assert.NoError(t, err)
+		decrypted, err := DecryptBytesWithAD(bound, key, []byte("blob|0"))
+		assert.NoError(t, err)
+		assert.Equal(t, message, string(decrypted))
+		_, err = DecryptBytesWithAD(bound, key, []byte("blob|1"))
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+		_, err = DecryptBytes(bound, key)
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+	t.Run("format", func(t *testing.T) {
+		assert.Equal(t, magic, string(encrypted[:len(magic)]))
+		assert.Equal(t, Version, encrypted[len(magic)])
+		assert.Equal(t, KDFHKDF, encrypted[len(magic)+1])
+	})
+}
+
+func TestBlobKey(t *testing.T) {
+	salt := bytes.Repeat([]byte{1}, saltSize)
+	first, err := blobKey("key", salt)
+	assert.NoError(t, err)
+	assert.Len(t, first, KeySize)
+	again, err := blobKey("key", salt)
+	assert.NoError(t, err)
+	assert.Equal(t, first, again)
+	other, err := blobKey("key", bytes.Repeat([]byte{2}, saltSize))
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, other)
+}
+
+// encryptV1 encrypts the data in the version 1 format: the key of the blob
+// is derived with Argon2id.
+func encryptV1(data []byte, key string) ([]byte, error) {
+	salt, err := newSalt()
+	if err != nil {
+		return nil, err
+	}
+	aesKey, err := argon2idKey(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := SealBytes(data, aesKey)
+	if err != nil {
+		return nil, err
+	}
+	header := append([]byte(magic), 1, KDFArgon2id)
+	header = append(header, salt...)
+	return append(header, sealed...), nil
+}
+
+// encryptLegacy encrypts the data in the legacy v0 format.
+func encryptLegacy(data []byte, key string) ([]byte, error) {
+	block, err := aes.NewCipher(tokenToAESKey(key))
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := make([]byte, aes.BlockSize+len(data))
+	iv := ciphertext[:aes.BlockSize]
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(ciphertext[aes.BlockSize:], data)
+	return ciphertext, nil
 }
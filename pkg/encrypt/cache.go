@@ -0,0 +1,49 @@
+package encrypt
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// maxCachedKeys limits the number of the derived keys kept in memory.
+const maxCachedKeys = 4096
+
+// keyCache keeps the keys derived with Argon2id: the master keys and
+// the keys of the blobs of the version 1, so reading the same record
+// again doesn't run the key derivation function.
+type keyCache struct {
+	mu   sync.Mutex
+	keys map[[sha256.Size]byte][]byte
+}
+
+// keys is the cache used by argon2idKey.
+var keys = &keyCache{keys: make(map[[sha256.Size]byte][]byte)}
+
+// cacheKey identifies the key derived from the password with the salt.
+func cacheKey(password string, salt []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte{byte(len(salt))})
+	h.Write(salt)
+	h.Write([]byte(password))
+	var id [sha256.Size]byte
+	copy(id[:], h.Sum(nil))
+	return id
+}
+
+// get returns the cached key.
+func (c *keyCache) get(password string, salt []byte) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key, ok := c.keys[cacheKey(password, salt)]
+	return key, ok
+}
+
+// put saves the key. The cache is cleared when it is full.
+func (c *keyCache) put(password string, salt []byte, key []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.keys) >= maxCachedKeys {
+		c.keys = make(map[[sha256.Size]byte][]byte)
+	}
+	c.keys[cacheKey(password, salt)] = key
+}
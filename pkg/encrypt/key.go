@@ -0,0 +1,111 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// KeySize is the size of the keys derived from passwords: AES-256.
+const KeySize = 32
+
+// SealOverhead is the number of bytes SealBytes adds to the data: the nonce and the GCM tag.
+const SealOverhead = 12 + 16
+
+// ErrDecryptionFailed is returned when the ciphertext can't be authenticated:
+// it was encrypted with another key or was modified.
+var ErrDecryptionFailed = errors.New("decryption failed")
+
+// KDFParams are the parameters of the Argon2id key derivation function.
+type KDFParams struct {
+	Salt    []byte
+	Time    uint32
+	Memory  uint32 // Memory is the memory usage in KiB.
+	Threads uint8
+}
+
+// NewKDFParams returns the recommended Argon2id parameters with a new random salt.
+func NewKDFParams() (KDFParams, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return KDFParams{}, err
+	}
+	return KDFParams{Salt: salt, Time: 3, Memory: 64 * 1024, Threads: 4}, nil
+}
+
+// DeriveKey derives a 256-bit key from the password using Argon2id.
+func DeriveKey(password string, params KDFParams) ([]byte, error) {
+	if password == "" {
+		return nil, fmt.Errorf("empty password")
+	}
+	if len(params.Salt) == 0 || params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+		return nil, fmt.Errorf("bad key derivation parameters")
+	}
+	return argon2.IDKey(
+		[]byte(password),
+		params.Salt,
+		params.Time,
+		params.Memory,
+		params.Threads,
+		KeySize,
+	), nil
+}
+
+// SealBytes encrypts and authenticates the data with AES-GCM.
+// The random nonce is prepended to the result.
+func SealBytes(data, key []byte) ([]byte, error) {
+	return SealBytesWithAD(data, key, nil)
+}
+
+// SealBytesWithAD encrypts the data like SealBytes and authenticates the additional
+// data as well. The additional data isn't kept in the result: the ciphertext opens
+// only with the same additional data, which binds it to its context.
+func SealBytesWithAD(data, key, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(data)+gcm.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, additionalData), nil
+}
+
+// OpenBytes decrypts the data encrypted by SealBytes. ErrDecryptionFailed
+// is returned if the key is wrong or the data was modified.
+func OpenBytes(ciphertext, key []byte) ([]byte, error) {
+	return OpenBytesWithAD(ciphertext, key, nil)
+}
+
+// OpenBytesWithAD decrypts the data encrypted by SealBytesWithAD. ErrDecryptionFailed
+// is returned if the additional data differs from the one the data was sealed with.
+func OpenBytesWithAD(ciphertext, key, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	data, err := gcm.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return data, nil
+}
+
+// newGCM creates an AES-GCM cipher with the key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
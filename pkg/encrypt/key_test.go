@@ -0,0 +1,96 @@
+package encrypt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKDFParams are cheap parameters to keep the tests fast.
+var testKDFParams = KDFParams{Salt: []byte("0123456789abcdef"), Time: 1, Memory: 64, Threads: 1}
+
+func TestNewKDFParams(t *testing.T) {
+	first, err := NewKDFParams()
+	require.NoError(t, err)
+	second, err := NewKDFParams()
+	require.NoError(t, err)
+	assert.Len(t, first.Salt, 16)
+	assert.NotEqual(t, first.Salt, second.Salt)
+}
+
+func TestDeriveKey(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		key, err := DeriveKey("master", testKDFParams)
+		require.NoError(t, err)
+		assert.Len(t, key, KeySize)
+		same, err := DeriveKey("master", testKDFParams)
+		require.NoError(t, err)
+		assert.Equal(t, key, same)
+		other, err := DeriveKey("another", testKDFParams)
+		require.NoError(t, err)
+		assert.NotEqual(t, key, other)
+	})
+	t.Run("empty_password", func(t *testing.T) {
+		_, err := DeriveKey("", testKDFParams)
+		assert.Error(t, err)
+	})
+	t.Run("bad_params", func(t *testing.T) {
+		_, err := DeriveKey("master", KDFParams{})
+		assert.Error(t, err)
+	})
+}
+
+func TestSealBytes(t *testing.T) {
+	key, err := DeriveKey("master", testKDFParams)
+	require.NoError(t, err)
+	t.Run("ok", func(t *testing.T) {
+		sealed, err := SealBytes([]byte("secret message"), key)
+		require.NoError(t, err)
+		assert.Len(t, sealed, len("secret message")+SealOverhead)
+		opened, err := OpenBytes(sealed, key)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("secret message"), opened)
+	})
+	t.Run("random_nonce", func(t *testing.T) {
+		first, err := SealBytes([]byte("secret message"), key)
+		require.NoError(t, err)
+		second, err := SealBytes([]byte("secret message"), key)
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+	t.Run("wrong_key", func(t *testing.T) {
+		sealed, err := SealBytes([]byte("secret message"), key)
+		require.NoError(t, err)
+		other, err := DeriveKey("another", testKDFParams)
+		require.NoError(t, err)
+		_, err = OpenBytes(sealed, other)
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+	t.Run("modified", func(t *testing.T) {
+		sealed, err := SealBytes([]byte("secret message"), key)
+		require.NoError(t, err)
+		sealed[len(sealed)-1] ^= 1
+		_, err = OpenBytes(sealed, key)
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+	t.Run("too_short", func(t *testing.T) {
+		_, err := OpenBytes([]byte("short"), key)
+		assert.Error(t, err)
+	})
+	t.Run("bad_key", func(t *testing.T) {
+		_, err := SealBytes([]byte("secret message"), []byte("short"))
+		assert.Error(t, err)
+	})
+	t.Run("additional_data", func(t *testing.T) {
+		sealed, err := SealBytesWithAD([]byte("secret message"), key, []byte("text|1"))
+		require.NoError(t, err)
+		opened, err := OpenBytesWithAD(sealed, key, []byte("text|1"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("secret message"), opened)
+		_, err = OpenBytesWithAD(sealed, key, []byte("text|2"))
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+		_, err = OpenBytes(sealed, key)
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+}
@@ -0,0 +1,216 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultKeyID is the id of the key which encrypted the values saved
+// without a key id, i.e. before the keyring was introduced.
+const DefaultKeyID = "default"
+
+// keyIDSeparator separates the key id from the base64 ciphertext.
+// It is not a part of the base64 alphabet.
+const keyIDSeparator = ":"
+
+// legacyTag precedes the key id of the values in the legacy v0 format:
+// "v0:<key id>:<base64 ciphertext>". The format of the ciphertext can't be
+// recognized, so the tag is set by MarkLegacy when the data is migrated.
+const legacyTag = "v0"
+
+// ErrUnknownKey is returned when a value was encrypted with a key missing in the keyring.
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Keyring is a set of the encryption keys identified by their ids.
+// Values are encrypted with the active key and prefixed with its id,
+// so they can be decrypted after another key becomes active.
+type Keyring struct {
+	activeID string
+	keys     map[string]string
+}
+
+// NewKeyring creates a keyring. The keys must contain the active one.
+func NewKeyring(activeID string, keys map[string]string) (*Keyring, error) {
+	ring := &Keyring{activeID: activeID, keys: make(map[string]string, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, keyIDSeparator) {
+			return nil, fmt.Errorf("bad key id %q", id)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("empty key %q", id)
+		}
+		ring.keys[id] = key
+	}
+	if _, ok := ring.keys[activeID]; !ok {
+		return nil, fmt.Errorf("%w: active key %q", ErrUnknownKey, activeID)
+	}
+	return ring, nil
+}
+
+// ActiveID returns the id of the key used for encryption.
+func (k *Keyring) ActiveID() string {
+	return k.activeID
+}
+
+// parseValue splits the value into the id of the key, the base64 ciphertext
+// and the flag of the legacy format.
+func parseValue(value string) (string, string, bool) {
+	id, ciphertext, ok := strings.Cut(value, keyIDSeparator)
+	if !ok {
+		return DefaultKeyID, value, false
+	}
+	// the values of a key named like the tag have a single separator
+	if id == legacyTag {
+		if legacyID, legacyCiphertext, ok := strings.Cut(ciphertext, keyIDSeparator); ok {
+			return legacyID, legacyCiphertext, true
+		}
+	}
+	return id, ciphertext, false
+}
+
+// KeyID returns the id of the key which encrypted the value.
+func KeyID(value string) string {
+	id, _, _ := parseValue(value)
+	return id
+}
+
+// IsLegacy reports whether the value was marked as the one in the legacy v0 format.
+func IsLegacy(value string) bool {
+	_, _, legacy := parseValue(value)
+	return legacy
+}
+
+// IsActive reports whether the value was encrypted with the active key
+// in the current format.
+func (k *Keyring) IsActive(value string) bool {
+	id, _, legacy := parseValue(value)
+	return id == k.activeID && !legacy
+}
+
+// MarkLegacy returns the value marked as the one in the legacy v0 format unless
+// it is authenticated with its key. It is meant for the migration of the data
+// written before the authenticated format, which is trusted to be intact:
+// a modified value is marked as legacy as well. Returns true if the value is marked.
+func (k *Keyring) MarkLegacy(value string) (string, bool, error) {
+	id, ciphertext, legacy := parseValue(value)
+	if legacy {
+		return value, false, nil
+	}
+	key, err := k.key(value)
+	if err != nil {
+		return "", false, err
+	}
+	b, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := DecryptBytes(b, key); err == nil {
+		return value, false, nil
+	}
+	return legacyTag + keyIDSeparator + id + keyIDSeparator + ciphertext, true, nil
+}
+
+// EncryptString encrypts the text with the active key.
+func (k *Keyring) EncryptString(text string) (string, error) {
+	s, err := EncryptString(text, k.keys[k.activeID])
+	if err != nil {
+		return "", err
+	}
+	return k.activeID + keyIDSeparator + s, nil
+}
+
+// DecryptString decrypts the value with the key which encrypted it.
+// Only the values marked by MarkLegacy are decrypted as legacy ones.
+func (k *Keyring) DecryptString(value string) (string, error) {
+	key, err := k.key(value)
+	if err != nil {
+		return "", err
+	}
+	_, s, legacy := parseValue(value)
+	if !legacy {
+		return DecryptString(s, key)
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	res, err := DecryptLegacyBytes(b, key)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// EncryptMap encrypts the string values of the map with the active key.
+func (k *Keyring) EncryptMap(data map[string]any) (map[string]any, error) {
+	encrypted, err := EncryptMap(data, k.keys[k.activeID])
+	if err != nil {
+		return nil, err
+	}
+	for field, v := range encrypted {
+		encrypted[field] = k.activeID + keyIDSeparator + v.(string)
+	}
+	return encrypted, nil
+}
+
+// DecryptMap decrypts the string values of the map
+// with the keys which encrypted them.
+func (k *Keyring) DecryptMap(encryptedData map[string]any) (map[string]any, error) {
+	decrypted := make(map[string]any)
+	for field, v := range encryptedData {
+		if x, ok := v.(string); ok {
+			s, err := k.DecryptString(x)
+			if err != nil {
+				return nil, err
+			}
+			decrypted[field] = s
+		}
+	}
+	return decrypted, nil
+}
+
+// EncryptBytes encrypts the data with the active key.
+// The id of the key is returned to be saved along with the result.
+func (k *Keyring) EncryptBytes(data []byte) (string, []byte, error) {
+	return k.EncryptBytesWithAD(data, nil)
+}
+
+// EncryptBytesWithAD encrypts the data with the active key like EncryptBytes
+// and binds it to the additional data.
+func (k *Keyring) EncryptBytesWithAD(data, additionalData []byte) (string, []byte, error) {
+	encrypted, err := EncryptBytesWithAD(data, k.keys[k.activeID], additionalData)
+	if err != nil {
+		return "", nil, err
+	}
+	return k.activeID, encrypted, nil
+}
+
+// DecryptBytes decrypts the data with the key of the id.
+func (k *Keyring) DecryptBytes(id string, encryptedData []byte) ([]byte, error) {
+	return k.DecryptBytesWithAD(id, encryptedData, nil)
+}
+
+// DecryptBytesWithAD decrypts the data with the key of the id and
+// the additional data it was encrypted with.
+func (k *Keyring) DecryptBytesWithAD(
+	id string,
+	encryptedData, additionalData []byte,
+) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	return DecryptBytesWithAD(encryptedData, key, additionalData)
+}
+
+// key returns the key which encrypted the value.
+func (k *Keyring) key(value string) (string, error) {
+	id := KeyID(value)
+	key, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
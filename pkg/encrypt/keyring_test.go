@@ -0,0 +1,154 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeyring(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		ring, err := NewKeyring("2", map[string]string{"1": "old-key", "2": "new-key"})
+		require.NoError(t, err)
+		assert.Equal(t, "2", ring.ActiveID())
+	})
+	t.Run("no_active_key", func(t *testing.T) {
+		_, err := NewKeyring("3", map[string]string{"1": "old-key"})
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	})
+	t.Run("bad_id", func(t *testing.T) {
+		_, err := NewKeyring("a:b", map[string]string{"a:b": "key"})
+		assert.Error(t, err)
+	})
+	t.Run("empty_key", func(t *testing.T) {
+		_, err := NewKeyring("1", map[string]string{"1": ""})
+		assert.Error(t, err)
+	})
+}
+
+func TestKeyring(t *testing.T) {
+	old, err := NewKeyring("1", map[string]string{"1": "old-key"})
+	require.NoError(t, err)
+	ring, err := NewKeyring("2", map[string]string{"1": "old-key", "2": "new-key"})
+	require.NoError(t, err)
+
+	t.Run("string", func(t *testing.T) {
+		encrypted, err := ring.EncryptString("secret")
+		require.NoError(t, err)
+		assert.Equal(t, "2", KeyID(encrypted))
+		assert.True(t, ring.IsActive(encrypted))
+
+		decrypted, err := ring.DecryptString(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", decrypted)
+	})
+	t.Run("old_key", func(t *testing.T) {
+		encrypted, err := old.EncryptString("secret")
+		require.NoError(t, err)
+		assert.False(t, ring.IsActive(encrypted))
+
+		decrypted, err := ring.DecryptString(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", decrypted)
+	})
+	t.Run("unknown_key", func(t *testing.T) {
+		encrypted, err := ring.EncryptString("secret")
+		require.NoError(t, err)
+		_, err = old.DecryptString(encrypted)
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	})
+	t.Run("without_id", func(t *testing.T) {
+		ring, err := NewKeyring("2", map[string]string{DefaultKeyID: "old-key", "2": "new-key"})
+		require.NoError(t, err)
+		encrypted, err := EncryptString("secret", "old-key")
+		require.NoError(t, err)
+		assert.Equal(t, DefaultKeyID, KeyID(encrypted))
+		assert.False(t, ring.IsActive(encrypted))
+
+		decrypted, err := ring.DecryptString(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", decrypted)
+	})
+	t.Run("map", func(t *testing.T) {
+		encrypted, err := old.EncryptMap(map[string]any{"login": "alice", "age": 30})
+		require.NoError(t, err)
+		assert.Len(t, encrypted, 1)
+		assert.Equal(t, "1", KeyID(encrypted["login"].(string)))
+
+		decrypted, err := ring.DecryptMap(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"login": "alice"}, decrypted)
+	})
+	t.Run("legacy", func(t *testing.T) {
+		ring, err := NewKeyring("2", map[string]string{DefaultKeyID: "old-key", "2": "new-key"})
+		require.NoError(t, err)
+		b, err := encryptLegacy([]byte("secret"), "old-key")
+		require.NoError(t, err)
+		value := base64.StdEncoding.EncodeToString(b)
+
+		// an unmarked legacy value is never decrypted as legacy
+		_, err = ring.DecryptString(value)
+		assert.ErrorIs(t, err, ErrUnknownFormat)
+
+		marked, ok, err := ring.MarkLegacy(value)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, IsLegacy(marked))
+		assert.Equal(t, DefaultKeyID, KeyID(marked))
+		assert.False(t, ring.IsActive(marked))
+		decrypted, err := ring.DecryptString(marked)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", decrypted)
+
+		// the marked value is left as is
+		again, ok, err := ring.MarkLegacy(marked)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, marked, again)
+	})
+	t.Run("mark_authenticated", func(t *testing.T) {
+		encrypted, err := ring.EncryptString("secret")
+		require.NoError(t, err)
+		value, ok, err := ring.MarkLegacy(encrypted)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, encrypted, value)
+		assert.False(t, IsLegacy(value))
+	})
+	t.Run("key_named_like_tag", func(t *testing.T) {
+		ring, err := NewKeyring(legacyTag, map[string]string{legacyTag: "key"})
+		require.NoError(t, err)
+		encrypted, err := ring.EncryptString("secret")
+		require.NoError(t, err)
+		assert.False(t, IsLegacy(encrypted))
+		assert.True(t, ring.IsActive(encrypted))
+		decrypted, err := ring.DecryptString(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", decrypted)
+	})
+	t.Run("bytes", func(t *testing.T) {
+		id, encrypted, err := old.EncryptBytes([]byte("chunk"))
+		require.NoError(t, err)
+		assert.Equal(t, "1", id)
+
+		decrypted, err := ring.DecryptBytes(id, encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("chunk"), decrypted)
+
+		_, err = ring.DecryptBytes("3", encrypted)
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	})
+	t.Run("bytes_with_ad", func(t *testing.T) {
+		id, encrypted, err := old.EncryptBytesWithAD([]byte("chunk"), []byte("blob|0"))
+		require.NoError(t, err)
+
+		decrypted, err := ring.DecryptBytesWithAD(id, encrypted, []byte("blob|0"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("chunk"), decrypted)
+
+		_, err = ring.DecryptBytesWithAD(id, encrypted, []byte("blob|1"))
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+}
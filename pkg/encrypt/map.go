@@ -1,14 +1,17 @@
 package encrypt
 
+import "encoding/base64"
+
+// EncryptMap encrypts the string values of the map. Every value has its own salt.
 func EncryptMap(data map[string]any, key string) (map[string]any, error) {
 	encrypted := make(map[string]any)
 	for k, v := range data {
 		if x, ok := v.(string); ok {
-			s, err := EncryptString(x, key)
+			b, err := EncryptBytes([]byte(x), key)
 			if err != nil {
 				return nil, err
 			}
-			encrypted[k] = s
+			encrypted[k] = base64.StdEncoding.EncodeToString(b)
 		}
 	}
 	return encrypted, nil
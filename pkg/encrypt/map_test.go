@@ -1,8 +1,11 @@
 package encrypt
 
 import (
+	"encoding/base64"
 	"reflect"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestEncryptMap(t *testing.T) {
@@ -89,3 +92,28 @@ func TestDecryptMap(t *testing.T) {
 		}
 	}
 }
+
+func TestDecryptMap_Modified(t *testing.T) {
+	key := "test-key"
+	encryptedData, err := EncryptMap(map[string]any{"foo": "foo"}, key)
+	assert.NoError(t, err)
+
+	b, err := base64.StdEncoding.DecodeString(encryptedData["foo"].(string))
+	assert.NoError(t, err)
+	b[len(b)-1] ^= 1
+	encryptedData["foo"] = base64.StdEncoding.EncodeToString(b)
+
+	_, err = DecryptMap(encryptedData, key)
+	assert.ErrorIs(t, err, ErrDecryptionFailed)
+}
+
+func TestDecryptMap_Legacy(t *testing.T) {
+	key := "test-key"
+	legacy, err := encryptLegacy([]byte("foo"), key)
+	assert.NoError(t, err)
+	_, err = DecryptMap(
+		map[string]any{"foo": base64.StdEncoding.EncodeToString(legacy)},
+		key,
+	)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
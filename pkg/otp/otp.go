@@ -0,0 +1,179 @@
+// Package otp implements HMAC-based (RFC 4226) and time-based (RFC 6238)
+// one-time password generation.
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+	"strings"
+	"time"
+)
+
+// Type is a kind of one-time password generator.
+type Type string
+
+// TypeTOTP and TypeHOTP are constants representing supported generator types.
+const (
+	TypeTOTP Type = "totp"
+	TypeHOTP Type = "hotp"
+)
+
+// Algorithm is a name of a hash function used in HMAC.
+type Algorithm string
+
+// AlgorithmSHA1, AlgorithmSHA256 and AlgorithmSHA512
+// are constants representing supported hash functions.
+const (
+	AlgorithmSHA1   Algorithm = "SHA1"
+	AlgorithmSHA256 Algorithm = "SHA256"
+	AlgorithmSHA512 Algorithm = "SHA512"
+)
+
+// Default values of the generator parameters.
+const (
+	DefaultDigits    = 6
+	DefaultPeriod    = 30
+	DefaultAlgorithm = AlgorithmSHA1
+)
+
+var (
+	// ErrInvalidSecret is returned when the secret is not a valid base32 string.
+	ErrInvalidSecret = errors.New("invalid otp secret")
+	// ErrUnknownAlgorithm is returned for unsupported hash functions.
+	ErrUnknownAlgorithm = errors.New("unknown otp algorithm")
+	// ErrUnknownType is returned for unsupported generator types.
+	ErrUnknownType = errors.New("unknown otp type")
+	// ErrInvalidDigits is returned when the number of digits is out of range.
+	ErrInvalidDigits = errors.New("otp digits must be between 6 and 8")
+	// ErrInvalidPeriod is returned when the TOTP period is not positive.
+	ErrInvalidPeriod = errors.New("otp period must be positive")
+)
+
+// Key contains all the parameters required to generate one-time passwords.
+type Key struct {
+	Type      Type      // Type is a generator type: totp or hotp.
+	Secret    string    // Secret is a base32 encoded shared secret.
+	Issuer    string    // Issuer is a name of the service the key belongs to.
+	Account   string    // Account is a name of the account the key belongs to.
+	Algorithm Algorithm // Algorithm is a hash function used in HMAC.
+	Digits    int       // Digits is a length of the generated code.
+	Period    int       // Period is a TOTP time step in seconds.
+	Counter   uint64    // Counter is a HOTP moving factor.
+}
+
+// NewKey creates a key with default parameters for the given type and secret.
+func NewKey(t Type, secret string) *Key {
+	return &Key{
+		Type:      t,
+		Secret:    secret,
+		Algorithm: DefaultAlgorithm,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+	}
+}
+
+// DecodeSecret decodes the base32 secret. Spaces are ignored, the case
+// and padding are optional as authenticator apps usually omit them.
+func DecodeSecret(secret string) ([]byte, error) {
+	s := strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	s = strings.TrimRight(s, "=")
+	if s == "" {
+		return nil, ErrInvalidSecret
+	}
+	b, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSecret, err)
+	}
+	return b, nil
+}
+
+// newHash returns a hash constructor for the algorithm.
+func newHash(alg Algorithm) (func() hash.Hash, error) {
+	switch Algorithm(strings.ToUpper(string(alg))) {
+	case "", AlgorithmSHA1:
+		return sha1.New, nil
+	case AlgorithmSHA256:
+		return sha256.New, nil
+	case AlgorithmSHA512:
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownAlgorithm, alg)
+	}
+}
+
+// HOTP generates an HMAC-based one-time password as described in RFC 4226.
+func HOTP(secret []byte, counter uint64, digits int, alg Algorithm) (string, error) {
+	if digits < 6 || digits > 8 {
+		return "", ErrInvalidDigits
+	}
+	h, err := newHash(alg)
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(h, secret)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	code %= uint32(math.Pow10(digits))
+	return fmt.Sprintf("%0*d", digits, code), nil
+}
+
+// TOTP generates a time-based one-time password as described in RFC 6238.
+// It also returns the time left until the code changes.
+func TOTP(
+	secret []byte,
+	t time.Time,
+	period int,
+	digits int,
+	alg Algorithm,
+) (string, time.Duration, error) {
+	if period <= 0 {
+		return "", 0, ErrInvalidPeriod
+	}
+	unix := t.Unix()
+	counter := uint64(unix / int64(period))
+	code, err := HOTP(secret, counter, digits, alg)
+	if err != nil {
+		return "", 0, err
+	}
+	next := time.Unix(int64(counter+1)*int64(period), 0)
+	return code, next.Sub(t), nil
+}
+
+// Code generates a one-time password for the key at the moment t.
+// For HOTP keys the stored counter is used and the remaining time is zero.
+func (k *Key) Code(t time.Time) (string, time.Duration, error) {
+	secret, err := DecodeSecret(k.Secret)
+	if err != nil {
+		return "", 0, err
+	}
+	digits := k.Digits
+	if digits == 0 {
+		digits = DefaultDigits
+	}
+	switch k.Type {
+	case "", TypeTOTP:
+		period := k.Period
+		if period == 0 {
+			period = DefaultPeriod
+		}
+		return TOTP(secret, t, period, digits, k.Algorithm)
+	case TypeHOTP:
+		code, err := HOTP(secret, k.Counter, digits, k.Algorithm)
+		return code, 0, err
+	default:
+		return "", 0, fmt.Errorf("%w: %v", ErrUnknownType, k.Type)
+	}
+}
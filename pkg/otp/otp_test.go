@@ -0,0 +1,123 @@
+package otp
+
+import (
+	"encoding/base32"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHOTP(t *testing.T) {
+	// test vectors from RFC 4226, Appendix D
+	secret := []byte("12345678901234567890")
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, want := range expected {
+		got, err := HOTP(secret, uint64(counter), 6, AlgorithmSHA1)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "counter=%d", counter)
+	}
+	t.Run("bad_digits", func(t *testing.T) {
+		_, err := HOTP(secret, 0, 10, AlgorithmSHA1)
+		assert.ErrorIs(t, err, ErrInvalidDigits)
+	})
+	t.Run("bad_algorithm", func(t *testing.T) {
+		_, err := HOTP(secret, 0, 6, "MD5")
+		assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+	})
+}
+
+func TestTOTP(t *testing.T) {
+	// test vectors from RFC 6238, Appendix B
+	seeds := map[Algorithm][]byte{
+		AlgorithmSHA1:   []byte("12345678901234567890"),
+		AlgorithmSHA256: []byte("12345678901234567890123456789012"),
+		AlgorithmSHA512: []byte(
+			"1234567890123456789012345678901234567890123456789012345678901234",
+		),
+	}
+	testCases := []struct {
+		unix int64
+		alg  Algorithm
+		want string
+	}{
+		{59, AlgorithmSHA1, "94287082"},
+		{59, AlgorithmSHA256, "46119246"},
+		{59, AlgorithmSHA512, "90693936"},
+		{1111111109, AlgorithmSHA1, "07081804"},
+		{1111111109, AlgorithmSHA256, "68084774"},
+		{1111111109, AlgorithmSHA512, "25091201"},
+		{1234567890, AlgorithmSHA1, "89005924"},
+		{2000000000, AlgorithmSHA1, "69279037"},
+		{20000000000, AlgorithmSHA1, "65353130"},
+	}
+	for _, tc := range testCases {
+		got, _, err := TOTP(seeds[tc.alg], time.Unix(tc.unix, 0), 30, 8, tc.alg)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got, "time=%d alg=%v", tc.unix, tc.alg)
+	}
+	t.Run("remaining", func(t *testing.T) {
+		_, remaining, err := TOTP(seeds[AlgorithmSHA1], time.Unix(59, 0), 30, 6, AlgorithmSHA1)
+		require.NoError(t, err)
+		assert.Equal(t, time.Second, remaining)
+	})
+	t.Run("bad_period", func(t *testing.T) {
+		_, _, err := TOTP(seeds[AlgorithmSHA1], time.Unix(59, 0), 0, 6, AlgorithmSHA1)
+		assert.ErrorIs(t, err, ErrInvalidPeriod)
+	})
+}
+
+func TestDecodeSecret(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		b, err := DecodeSecret("jbsw y3dp ehpk 3pxp")
+		require.NoError(t, err)
+		assert.Equal(t, "Hello!\xde\xad\xbe\xef", string(b))
+	})
+	t.Run("padded", func(t *testing.T) {
+		b, err := DecodeSecret("MFRGG===")
+		require.NoError(t, err)
+		assert.Equal(t, "abc", string(b))
+	})
+	t.Run("bad", func(t *testing.T) {
+		_, err := DecodeSecret("not base32!")
+		assert.True(t, errors.Is(err, ErrInvalidSecret))
+	})
+	t.Run("empty", func(t *testing.T) {
+		_, err := DecodeSecret("")
+		assert.True(t, errors.Is(err, ErrInvalidSecret))
+	})
+}
+
+func TestKey_Code(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	t.Run("totp_defaults", func(t *testing.T) {
+		key := &Key{Secret: secret}
+		code, remaining, err := key.Code(time.Unix(59, 0))
+		require.NoError(t, err)
+		assert.Equal(t, "287082", code)
+		assert.Equal(t, time.Second, remaining)
+	})
+	t.Run("hotp", func(t *testing.T) {
+		key := NewKey(TypeHOTP, secret)
+		key.Counter = 3
+		code, remaining, err := key.Code(time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, "969429", code)
+		assert.Zero(t, remaining)
+	})
+	t.Run("bad_type", func(t *testing.T) {
+		key := NewKey("motp", secret)
+		_, _, err := key.Code(time.Now())
+		assert.ErrorIs(t, err, ErrUnknownType)
+	})
+	t.Run("bad_secret", func(t *testing.T) {
+		key := NewKey(TypeTOTP, "1")
+		_, _, err := key.Code(time.Now())
+		assert.ErrorIs(t, err, ErrInvalidSecret)
+	})
+}
@@ -0,0 +1,105 @@
+package otp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidURI is returned when the string is not a valid otpauth:// URI.
+var ErrInvalidURI = errors.New("invalid otpauth uri")
+
+// ParseURI parses a key from the otpauth:// URI format used by
+// authenticator apps, e.g.
+// otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example
+func ParseURI(uri string) (*Key, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURI, err)
+	}
+	if u.Scheme != "otpauth" {
+		return nil, fmt.Errorf("%w: unexpected scheme %q", ErrInvalidURI, u.Scheme)
+	}
+	t := Type(strings.ToLower(u.Host))
+	if t != TypeTOTP && t != TypeHOTP {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownType, u.Host)
+	}
+	q := u.Query()
+	key := NewKey(t, q.Get("secret"))
+	if _, err := DecodeSecret(key.Secret); err != nil {
+		return nil, err
+	}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		key.Issuer = strings.TrimSpace(issuer)
+		key.Account = strings.TrimSpace(account)
+	} else {
+		key.Account = strings.TrimSpace(label)
+	}
+	// the issuer parameter takes precedence over the label prefix
+	if issuer := q.Get("issuer"); issuer != "" {
+		key.Issuer = issuer
+	}
+	if alg := q.Get("algorithm"); alg != "" {
+		key.Algorithm = Algorithm(strings.ToUpper(alg))
+		if _, err := newHash(key.Algorithm); err != nil {
+			return nil, err
+		}
+	}
+	if digits := q.Get("digits"); digits != "" {
+		if key.Digits, err = strconv.Atoi(digits); err != nil {
+			return nil, fmt.Errorf("%w: digits: %v", ErrInvalidURI, err)
+		}
+	}
+	if period := q.Get("period"); period != "" {
+		if key.Period, err = strconv.Atoi(period); err != nil {
+			return nil, fmt.Errorf("%w: period: %v", ErrInvalidURI, err)
+		}
+	}
+	if counter := q.Get("counter"); counter != "" {
+		if key.Counter, err = strconv.ParseUint(counter, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: counter: %v", ErrInvalidURI, err)
+		}
+	} else if t == TypeHOTP {
+		return nil, fmt.Errorf("%w: counter is required for hotp", ErrInvalidURI)
+	}
+	return key, nil
+}
+
+// URI returns the key in the otpauth:// URI format.
+func (k *Key) URI() string {
+	t := k.Type
+	if t == "" {
+		t = TypeTOTP
+	}
+	label := k.Account
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + k.Account
+	}
+	q := url.Values{}
+	q.Set("secret", k.Secret)
+	if k.Issuer != "" {
+		q.Set("issuer", k.Issuer)
+	}
+	if k.Algorithm != "" {
+		q.Set("algorithm", string(k.Algorithm))
+	}
+	if k.Digits != 0 {
+		q.Set("digits", strconv.Itoa(k.Digits))
+	}
+	if t == TypeHOTP {
+		q.Set("counter", strconv.FormatUint(k.Counter, 10))
+	} else if k.Period != 0 {
+		q.Set("period", strconv.Itoa(k.Period))
+	}
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     string(t),
+		Path:     "/" + label,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
@@ -0,0 +1,74 @@
+package otp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURI(t *testing.T) {
+	t.Run("totp", func(t *testing.T) {
+		key, err := ParseURI(
+			"otpauth://totp/ACME%20Co:john.doe@email.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&issuer=ACME%20Co&algorithm=SHA256&digits=8&period=60",
+		)
+		require.NoError(t, err)
+		assert.Equal(t, &Key{
+			Type:      TypeTOTP,
+			Secret:    "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ",
+			Issuer:    "ACME Co",
+			Account:   "john.doe@email.com",
+			Algorithm: AlgorithmSHA256,
+			Digits:    8,
+			Period:    60,
+		}, key)
+	})
+	t.Run("defaults", func(t *testing.T) {
+		key, err := ParseURI("otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", key.Account)
+		assert.Equal(t, "", key.Issuer)
+		assert.Equal(t, DefaultAlgorithm, key.Algorithm)
+		assert.Equal(t, DefaultDigits, key.Digits)
+		assert.Equal(t, DefaultPeriod, key.Period)
+	})
+	t.Run("hotp", func(t *testing.T) {
+		key, err := ParseURI("otpauth://hotp/Example:bob?secret=JBSWY3DPEHPK3PXP&counter=42")
+		require.NoError(t, err)
+		assert.Equal(t, TypeHOTP, key.Type)
+		assert.Equal(t, "Example", key.Issuer)
+		assert.Equal(t, uint64(42), key.Counter)
+	})
+	testCases := []struct {
+		name string
+		uri  string
+		err  error
+	}{
+		{"bad_scheme", "https://totp/alice?secret=JBSWY3DPEHPK3PXP", ErrInvalidURI},
+		{"bad_type", "otpauth://motp/alice?secret=JBSWY3DPEHPK3PXP", ErrUnknownType},
+		{"no_secret", "otpauth://totp/alice", ErrInvalidSecret},
+		{"bad_algorithm", "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&algorithm=MD5", ErrUnknownAlgorithm},
+		{"bad_digits", "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&digits=six", ErrInvalidURI},
+		{"bad_period", "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&period=x", ErrInvalidURI},
+		{"hotp_no_counter", "otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP", ErrInvalidURI},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseURI(tc.uri)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestKey_URI(t *testing.T) {
+	for _, uri := range []string{
+		"otpauth://totp/ACME%20Co:john.doe@email.com?algorithm=SHA256&digits=8&issuer=ACME+Co&period=60&secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ",
+		"otpauth://hotp/bob?algorithm=SHA1&counter=42&digits=6&secret=JBSWY3DPEHPK3PXP",
+	} {
+		key, err := ParseURI(uri)
+		require.NoError(t, err)
+		parsed, err := ParseURI(key.URI())
+		require.NoError(t, err)
+		assert.Equal(t, key, parsed)
+	}
+}